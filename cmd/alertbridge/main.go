@@ -0,0 +1,221 @@
+// Command alertbridge subscribes to the alerts a dashboard deployment
+// publishes and fans them out to notifiers and external commands, so alert
+// handling can run, restart and scale apart from the server
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// commandList collects a repeatable -exec flag
+type commandList []string
+
+func (c *commandList) String() string { return strings.Join(*c, "; ") }
+
+func (c *commandList) Set(command string) error {
+	*c = append(*c, command)
+	return nil
+}
+
+// bridge delivers each alert to every configured destination
+type bridge struct {
+	redis     *storage.RedisClient
+	stdout    bool
+	commands  []string
+	notifiers []notify.Notifier
+	timeout   time.Duration
+
+	stdoutMu sync.Mutex
+}
+
+func main() {
+	redisAddr := flag.String("redis-addr", "localhost:6379", "Redis address of the deployment")
+	redisPassword := flag.String("redis-password", "", "Redis password")
+	redisDB := flag.Int("redis-db", 0, "Redis database number")
+	keyPrefix := flag.String("redis-key-prefix", "", "key prefix the server runs with (-redis-key-prefix)")
+	stdout := flag.Bool("stdout", false, "write every alert to stdout as a line of JSON")
+	var commands commandList
+	flag.Var(&commands, "exec", "run this shell command for every alert, with the alert as JSON on stdin; repeatable")
+	timeout := flag.Duration("timeout", 30*time.Second, "how long one delivery may take")
+	mispURL := flag.String("misp-url", "", "share alerted attacks as events on this MISP instance")
+	mispKey := flag.String("misp-key", "", "MISP API key")
+	mispSeverity := flag.String("misp-min-severity", "HIGH", "only share attacks at or above this severity with MISP")
+	mispDistribution := flag.Int("misp-distribution", 1, "MISP distribution: 0 organisation, 1 community, 2 connected communities, 3 all")
+	mispTags := flag.String("misp-tags", "tlp:amber", "comma-separated tags added to MISP events")
+	mispInsecure := flag.Bool("misp-insecure", false, "skip TLS verification for self-signed MISP instances")
+	flag.Parse()
+
+	client, err := storage.NewRedisClient(*redisAddr, *redisPassword, *redisDB)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer client.Close()
+	if err := client.SetKeyPrefix(*keyPrefix); err != nil {
+		log.Fatalf("Invalid Redis key prefix: %v", err)
+	}
+
+	b := &bridge{
+		redis:    client,
+		stdout:   *stdout,
+		commands: commands,
+		timeout:  *timeout,
+	}
+
+	if *mispURL != "" {
+		var tags []string
+		for _, tag := range strings.Split(*mispTags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		misp, err := notify.NewMISPNotifier(notify.MISPConfig{
+			URL:                *mispURL,
+			APIKey:             *mispKey,
+			MinSeverity:        *mispSeverity,
+			Distribution:       *mispDistribution,
+			Tags:               tags,
+			InsecureSkipVerify: *mispInsecure,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure MISP: %v", err)
+		}
+		b.notifiers = append(b.notifiers, misp)
+	}
+
+	if !b.stdout && len(b.commands) == 0 && len(b.notifiers) == 0 {
+		log.Fatal("Nothing to deliver alerts to; set -stdout, -exec or a notifier")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("📣 Bridging alerts to %d commands and %d notifiers (stdout: %t)", len(b.commands), len(b.notifiers), b.stdout)
+	if err := client.SubscribeAlerts(ctx, b.deliver); err != nil {
+		log.Fatalf("Alert subscription failed: %v", err)
+	}
+}
+
+// deliver fans one alert out to every destination at once and waits for
+// them, so alerts reach each destination in the order they were published
+func (b *bridge) deliver(alert models.Alert) {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Error encoding alert %s: %v", alert.ID, err)
+		return
+	}
+
+	if b.stdout {
+		b.stdoutMu.Lock()
+		os.Stdout.Write(append(data, '\n'))
+		b.stdoutMu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for _, command := range b.commands {
+		wg.Add(1)
+		go func(command string) {
+			defer wg.Done()
+			if err := b.run(command, alert, data); err != nil {
+				log.Printf("Error running %q for alert %s: %v", command, alert.ID, err)
+			}
+		}(command)
+	}
+
+	if len(b.notifiers) > 0 {
+		// Notifiers take the attack the alert was raised for; alerts such
+		// as syslog IDS events have none
+		attack, err := b.redis.GetAttack(alert.ID)
+		switch {
+		case errors.Is(err, storage.ErrAttackNotFound):
+		case err != nil:
+			log.Printf("Error loading attack for alert %s: %v", alert.ID, err)
+		default:
+			notification := notify.AttackNotification(notify.EventAttackStarted, *attack)
+			for _, notifier := range b.notifiers {
+				if !notifier.Subscribes(notification.Event) {
+					continue
+				}
+				wg.Add(1)
+				go func(notifier notify.Notifier) {
+					defer wg.Done()
+					b.notify(notifier, notification)
+				}(notifier)
+			}
+		}
+	}
+
+	wg.Wait()
+}
+
+// notify delivers an attack to one notifier with retries, recording the
+// attempts in the deployment's delivery log and dead-lettering a failure
+func (b *bridge) notify(notifier notify.Notifier, notification models.Notification) {
+	attack := notification.Attack
+	policy := notify.DefaultRetryPolicy()
+	policy.Timeout = b.timeout
+
+	final := notify.Deliver(context.Background(), notifier, notification, policy, func(delivery models.NotificationDelivery) {
+		if delivery.Error != "" {
+			log.Printf("Error notifying %s of attack %s (attempt %d): %s", delivery.Channel, attack.ID, delivery.Attempt, delivery.Error)
+		}
+		if err := b.redis.AppendDelivery(delivery); err != nil {
+			log.Printf("Error recording delivery to %s for attack %s: %v", delivery.Channel, attack.ID, err)
+		}
+	})
+	if final.Status != notify.StatusFailed {
+		return
+	}
+
+	letter := models.DeadLetter{
+		ID:           final.ID,
+		Channel:      final.Channel,
+		Notification: notification,
+		Attempts:     final.Attempt,
+		Error:        final.Error,
+		FailedAt:     final.Timestamp,
+	}
+	if err := b.redis.AddDeadLetter(letter); err != nil {
+		log.Printf("Error queueing dead letter for attack %s: %v", attack.ID, err)
+	}
+}
+
+// run executes a command through the shell with the alert on stdin and its
+// main fields in the environment
+func (b *bridge) run(command string, alert models.Alert, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Stdin = strings.NewReader(string(data))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"DDOS_ALERT_ID="+alert.ID,
+		"DDOS_ALERT_LEVEL="+alert.Level,
+		"DDOS_ALERT_TYPE="+alert.AttackType,
+		"DDOS_ALERT_TITLE="+alert.Title,
+	)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", b.timeout)
+		}
+		return err
+	}
+	return nil
+}
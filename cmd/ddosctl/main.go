@@ -0,0 +1,34 @@
+// Command ddosctl performs maintenance on a dashboard deployment's stored
+// data and prepares its configuration
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: ddosctl <command> [flags]
+
+Commands:
+  migrate         upgrade stored data to the layout of this release
+  hash-password   hash a dashboard user's password for the -users-file
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "migrate":
+		os.Exit(runMigrate(os.Args[2:]))
+	case "hash-password":
+		os.Exit(runHashPassword(os.Args[2:]))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
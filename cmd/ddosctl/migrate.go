@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// runMigrate upgrades the stored data layout, returning the exit code
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	redisAddr := fs.String("redis-addr", "localhost:6379", "Redis address of the deployment")
+	redisPassword := fs.String("redis-password", "", "Redis password")
+	redisDB := fs.Int("redis-db", 0, "Redis database number")
+	keyPrefix := fs.String("redis-key-prefix", "", "key prefix the server runs with (-redis-key-prefix)")
+	dryRun := fs.Bool("dry-run", false, "report what each pending migration would change without changing anything")
+	status := fs.Bool("status", false, "list migrations and whether they are applied, then exit")
+	fs.Parse(args)
+
+	client, err := storage.NewRedisClient(*redisAddr, *redisPassword, *redisDB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	if err := client.SetKeyPrefix(*keyPrefix); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	version, err := client.SchemaVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Reading schema version: %v\n", err)
+		return 1
+	}
+
+	if *status {
+		fmt.Printf("Schema version %d, this release writes %d\n", version, storage.LatestSchemaVersion())
+		for _, m := range storage.Migrations() {
+			state := "pending"
+			if m.Version <= version {
+				state = "applied"
+			}
+			fmt.Printf("  %3d %-24s %-8s %s\n", m.Version, m.Name, state, m.Description)
+		}
+		return 0
+	}
+
+	if version >= storage.LatestSchemaVersion() {
+		fmt.Printf("✅ Schema is up to date (version %d)\n", version)
+		return 0
+	}
+
+	mode := "Migrating"
+	if *dryRun {
+		mode = "Dry run:"
+	}
+	fmt.Printf("%s schema version %d -> %d\n", mode, version, storage.LatestSchemaVersion())
+
+	// Progress of long migrations goes on one line per migration
+	current := 0
+	results, err := client.Migrate(*dryRun, func(m storage.Migration, done int) {
+		if m.Version != current {
+			if current != 0 {
+				fmt.Println()
+			}
+			current = m.Version
+			fmt.Printf("  %d %s: ", m.Version, m.Name)
+		}
+		fmt.Printf("%d... ", done)
+	})
+	if current != 0 {
+		fmt.Println()
+	}
+
+	verb := "changed"
+	if *dryRun {
+		verb = "would change"
+	}
+	for _, result := range results {
+		fmt.Printf("  %d %s: %s %d\n", result.Version, result.Name, verb, result.Changed)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		fmt.Println("Nothing was changed; run without -dry-run to apply")
+	} else {
+		fmt.Printf("✅ Schema is at version %d\n", storage.LatestSchemaVersion())
+	}
+	return 0
+}
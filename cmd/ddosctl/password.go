@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+)
+
+// runHashPassword prints the bcrypt hash of a password read from stdin, for
+// the server's users file, returning the exit code
+func runHashPassword(args []string) int {
+	fs := flag.NewFlagSet("hash-password", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ddosctl hash-password < password.txt")
+		fmt.Fprintln(fs.Output(), "Reads the password from the first line of stdin, so it stays out of the shell history.")
+	}
+	fs.Parse(args)
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(os.Stderr, "no password on stdin")
+		return 1
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "password must not be empty")
+		return 1
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(hash)
+	return 0
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// adminAction is a parameterized operational procedure exposed over the API
+type adminAction struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Role        auth.Role     `json:"role"`
+	Params      []actionParam `json:"params"`
+
+	run func(s *Server, params map[string]string) (interface{}, error)
+}
+
+type actionParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     string `json:"default,omitempty"`
+}
+
+type runActionRequest struct {
+	Params map[string]string `json:"params"`
+}
+
+var adminActions = []adminAction{
+	{
+		Name:        "flush_current_minute",
+		Description: "Delete the real-time counters of the current (or given) minute, e.g. after ingesting bad test data",
+		Role:        auth.RoleAdmin,
+		Params: []actionParam{
+			{Name: "minute", Description: "Unix time within the minute to flush; defaults to now"},
+		},
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			t := time.Now()
+			if v := params["minute"]; v != "" {
+				unix, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid minute %q", v)
+				}
+				t = time.Unix(unix, 0)
+			}
+
+			deleted, err := s.redis.FlushMetricsMinute(t)
+			if err != nil {
+				return nil, err
+			}
+			return gin.H{"minute": t.Truncate(time.Minute), "keys_deleted": deleted}, nil
+		},
+	},
+	{
+		Name:        "run_analysis",
+		Description: "Run an analysis cycle now instead of waiting for the next tick",
+		Role:        auth.RoleOperator,
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			if !s.isLeader.Load() {
+				return nil, errors.New("this node is not the analysis leader")
+			}
+
+			attacks, err := s.runAnalysisCycle()
+			if err != nil {
+				return nil, err
+			}
+			return gin.H{"attacks_detected": attacks}, nil
+		},
+	},
+	{
+		Name:        "rebuild_baseline",
+		Description: "Rebuild an environment's request-rate baseline, and the seasonal profile of the hours covered, from its hourly history",
+		Role:        auth.RoleAdmin,
+		Params: []actionParam{
+			{Name: "environment", Description: "Environment to rebuild", Default: "default"},
+			{Name: "hours", Description: "Hours of history to learn from", Default: "24"},
+		},
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			env := params["environment"]
+			hours := 24
+			if v := params["hours"]; v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 24*7 {
+					return nil, fmt.Errorf("hours must be between 1 and %d", 24*7)
+				}
+				hours = n
+			}
+
+			history, err := s.redis.GetRecentHourlySummaries(env, hours)
+			if err != nil {
+				return nil, err
+			}
+			rates := make([]detection.HourlyRate, 0, len(history))
+			for _, hour := range history {
+				rates = append(rates, detection.HourlyRate{Start: hour.Start, Rate: float64(hour.TotalRequests) / 60})
+			}
+
+			// Don't swap the baseline out from under a running cycle
+			s.analysisMu.Lock()
+			baseline, err := s.detectors.RebuildBaseline(env, rates)
+			if err == nil {
+				s.replicateState()
+			}
+			s.analysisMu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+
+			return gin.H{"environment": env, "hours": hours, "baseline": baseline}, nil
+		},
+	},
+	{
+		Name:        "migrate_key_prefix",
+		Description: "Move data written before -redis-key-prefix was set into the prefixed namespace",
+		Role:        auth.RoleAdmin,
+		Params: []actionParam{
+			{Name: "dry_run", Description: "Only count the keys that would move", Default: "true"},
+		},
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			dryRun, err := strconv.ParseBool(params["dry_run"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid dry_run %q", params["dry_run"])
+			}
+			return s.redis.MigrateToKeyPrefix(dryRun)
+		},
+	},
+	{
+		Name:        "resend_last_alert",
+		Description: "Publish the most recent alert again to subscribers and dashboards",
+		Role:        auth.RoleOperator,
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			alert, err := s.redis.GetLastAlert()
+			if err != nil {
+				return nil, err
+			}
+			// Keep its acknowledgement
+			if stored, err := s.redis.GetAlert(alert.ID); err == nil {
+				alert = stored
+			}
+			if err := s.PublishAlert(*alert); err != nil {
+				return nil, err
+			}
+			return gin.H{"alert": alert}, nil
+		},
+	},
+	{
+		Name:        "redeliver_dead_letters",
+		Description: "Retry notifications that failed for good, e.g. after fixing a notifier's credentials",
+		Role:        auth.RoleOperator,
+		Params: []actionParam{
+			{Name: "id", Description: "Dead letter to retry; defaults to all of them"},
+		},
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			return s.redeliverDeadLetters(params["id"])
+		},
+	},
+	{
+		Name:        "send_email_digest",
+		Description: "Email the digest of the last 24 hours' attacks now, e.g. to check the SMTP settings",
+		Role:        auth.RoleOperator,
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			if s.email == nil {
+				return nil, fmt.Errorf("email is not configured; set -smtp-addr")
+			}
+			now := time.Now()
+			return s.sendEmailDigest(now.Add(-24*time.Hour), now)
+		},
+	},
+	{
+		Name:        "close_slow_connections",
+		Description: "Force-close proxied connections that send or read too slowly, e.g. during a Slowloris attack",
+		Role:        auth.RoleOperator,
+		Params: []actionParam{
+			{Name: "min_age", Description: "Age before a connection can count as slow, e.g. 10s; defaults to -proxy-slow-age"},
+			{Name: "min_write_bps", Description: "Bytes/sec below which sending is slow; defaults to -proxy-min-write-rate"},
+			{Name: "min_read_bps", Description: "Bytes/sec below which reading a pending response is slow; defaults to -proxy-min-read-rate"},
+		},
+		run: func(s *Server, params map[string]string) (interface{}, error) {
+			return s.closeSlowConnections(params)
+		},
+	},
+}
+
+// listActions describes the available admin actions
+func (s *Server) listActions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"actions": adminActions,
+	})
+}
+
+// runAction executes an admin action and records it in the audit log
+func (s *Server) runAction(c *gin.Context) {
+	action, ok := findAction(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown action", "name": c.Param("name")})
+		return
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	if !principal.Role.Allows(action.Role) {
+		s.auditAction(models.ActionAudit{
+			Action: action.Name,
+			Actor:  principal.Name,
+			Role:   string(principal.Role),
+			Error:  "forbidden",
+		})
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("action %s requires role %s", action.Name, action.Role)})
+		return
+	}
+
+	var req runActionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Params == nil {
+		req.Params = make(map[string]string)
+	}
+	for _, param := range action.Params {
+		if _, set := req.Params[param.Name]; !set && param.Default != "" {
+			req.Params[param.Name] = param.Default
+		}
+	}
+
+	result, err := action.run(s, req.Params)
+
+	entry := models.ActionAudit{
+		Action:  action.Name,
+		Params:  req.Params,
+		Actor:   principal.Name,
+		Role:    string(principal.Role),
+		Success: err == nil,
+		Result:  result,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry = s.auditAction(entry)
+	log.Printf("🛠️  %s ran %s (success: %t)", principal.Name, action.Name, err == nil)
+
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":   action.Name,
+		"result":   result,
+		"audit_id": entry.ID,
+	})
+}
+
+// auditAction stamps and records an audit entry, returning it
+func (s *Server) auditAction(entry models.ActionAudit) models.ActionAudit {
+	entry.ID = uuid.New().String()
+	entry.Timestamp = time.Now()
+
+	if err := s.redis.AppendActionAudit(entry); err != nil {
+		log.Printf("Error recording audit entry for action %s: %v", entry.Action, err)
+	}
+	return entry
+}
+
+// getActionAudit lists recent admin action invocations
+func (s *Server) getActionAudit(c *gin.Context) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	entries, err := s.redis.GetActionAudit(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
+
+func findAction(name string) (adminAction, bool) {
+	for _, action := range adminActions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return adminAction{}, false
+}
+
+// requireRole rejects principals below the given role
+func requireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := c.MustGet("principal").(auth.Principal)
+		if !principal.Role.Allows(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("requires role %s", role)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireToken authenticates the bearer token, a static API token or a
+// login session, and stores the principal on the request. Without tokens
+// or users configured the endpoints are disabled.
+func (s *Server) requireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.tokens.Empty() && s.sessions == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin actions are disabled; configure -api-tokens or -users-file"})
+			return
+		}
+
+		principal, err := s.authenticate(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if !s.limitKey(c, principal) {
+			return
+		}
+		c.Set("principal", principal)
+		c.Next()
+	}
+}
+
+// requireViewer authenticates reads when -auth-required is set, leaving
+// ingest, enforcement checks and login open to the agents and proxies
+// calling them
+func (s *Server) requireViewer() gin.HandlerFunc {
+	public := map[string]bool{
+		"/traffic/ingest":    true,
+		"/ingest/cloudflare": true,
+		"/enforce/check":     true,
+		"/auth/login":        true,
+	}
+	return func(c *gin.Context) {
+		if !s.authRequired || public[apiRoute(c.FullPath())] || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		principal, err := s.authenticate(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if !s.limitKey(c, principal) {
+			return
+		}
+		c.Set("principal", principal)
+		c.Next()
+	}
+}
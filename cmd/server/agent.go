@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ingestion"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// httpSink ships traffic to a dashboard server's ingest endpoint, as an
+// agent on another host does
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// StoreTraffic posts one request, waiting out the server's rate limit
+func (h httpSink) StoreTraffic(req models.TrafficRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			time.Sleep(time.Duration(max(wait, 1)) * time.Second)
+		case resp.StatusCode >= 300:
+			return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+		default:
+			return nil
+		}
+	}
+}
+
+// runAgent follows access logs on this host and ships their requests to a
+// dashboard server, returning the exit code
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8888", "dashboard server to ship traffic to")
+	accessLogs := fs.String("access-log", "", "nginx or Apache access logs in combined format to follow, comma-separated")
+	environment := fs.String("env", "", "environment tag for the shipped traffic")
+	destIP := fs.String("dest-ip", "", "address the web server answers on, the attacks' target")
+	caFile := fs.String("tls-ca", "", "PEM CA certificates the server's certificate must chain to, instead of the system's")
+	certFile := fs.String("tls-cert", "", "PEM client certificate presented to a server verifying agents (-tls-client-ca)")
+	keyFile := fs.String("tls-key", "", "PEM private key of -tls-cert")
+	fs.Parse(args)
+
+	paths := splitList(*accessLogs)
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "agent: -access-log is required")
+		return 2
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *caFile != "" {
+		pem, err := os.ReadFile(*caFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			fmt.Fprintf(os.Stderr, "agent: %s holds no PEM certificates\n", *caFile)
+			return 1
+		}
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		fmt.Fprintln(os.Stderr, "agent: -tls-cert and -tls-key go together")
+		return 2
+	}
+	if *certFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "agent: invalid client certificate: %v\n", err)
+			return 1
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	sink := httpSink{
+		url: strings.TrimSuffix(*server, "/") + apiV1Prefix + "/traffic/ingest",
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: config},
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, len(paths))
+	for _, path := range paths {
+		tailer := ingestion.NewAccessLogTailer(path, *environment, *destIP, sink)
+		go func() { errs <- tailer.Run(ctx) }()
+	}
+	for range paths {
+		if err := <-errs; err != nil {
+			fmt.Fprintf(os.Stderr, "agent: %v\n", err)
+			stop()
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// errAlertResolved refuses to acknowledge an alert already resolved
+var errAlertResolved = errors.New("alert is resolved")
+
+// getAlerts lists stored alerts, most recently raised first, optionally of
+// one status, level, attack type or environment, or raised since a time
+func (s *Server) getAlerts(c *gin.Context) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 time"})
+			return
+		}
+		since = t
+	}
+	status, level := strings.ToUpper(c.Query("status")), strings.ToUpper(c.Query("level"))
+	attackType, environment := strings.ToUpper(c.Query("attack_type")), c.Query("environment")
+
+	// Filters apply to every stored alert, not just the latest
+	fetch := limit
+	if status != "" || level != "" || attackType != "" || environment != "" || !since.IsZero() {
+		fetch = 0
+	}
+	alerts, err := s.redis.GetAlerts(fetch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered := make([]models.Alert, 0, limit)
+	for _, alert := range alerts {
+		if len(filtered) == limit {
+			break
+		}
+		if (status == "" || alert.Status == status) &&
+			(level == "" || alert.Level == level) &&
+			(attackType == "" || alert.AttackType == attackType) &&
+			(environment == "" || alert.Environment == environment) &&
+			!alert.Timestamp.Before(since) {
+			filtered = append(filtered, alert)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": filtered,
+		"count":  len(filtered),
+	})
+}
+
+// acknowledgeAlert marks an alert as being handled. Acknowledging it again
+// keeps who did first.
+func (s *Server) acknowledgeAlert(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	s.changeAlert(c, func(alert *models.Alert) error {
+		switch alert.Status {
+		case models.AlertResolved:
+			return errAlertResolved
+		case models.AlertAcknowledged:
+			return nil
+		}
+		now := time.Now()
+		alert.Status = models.AlertAcknowledged
+		alert.Acknowledged = true
+		alert.AcknowledgedBy = principal.Name
+		alert.AcknowledgedAt = &now
+		return nil
+	})
+}
+
+// resolveAlert closes an alert, whether or not it was acknowledged
+func (s *Server) resolveAlert(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	s.changeAlert(c, func(alert *models.Alert) error {
+		markResolved(alert, principal.Name)
+		return nil
+	})
+}
+
+// changeAlert applies an operator's change to the alert in the path and
+// broadcasts the result
+func (s *Server) changeAlert(c *gin.Context, change func(*models.Alert) error) {
+	alert, err := s.redis.UpdateAlert(c.Param("id"), change)
+	switch {
+	case errors.Is(err, storage.ErrAlertNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found", "id": c.Param("id")})
+		return
+	case errors.Is(err, errAlertResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "id": c.Param("id")})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🔔 %s marked alert %s %s", c.MustGet("principal").(auth.Principal).Name, alert.ID, strings.ToLower(alert.Status))
+	broadcastAlertUpdate(*alert)
+	c.JSON(http.StatusOK, gin.H{"alert": alert})
+}
+
+// resolveAttackAlert resolves the alert of an attack as it ends, if it
+// raised one that is still open, and reports whether it raised one
+func (s *Server) resolveAttackAlert(attack models.Attack) bool {
+	changed := false
+	alert, err := s.redis.UpdateAlert(attack.ID, func(alert *models.Alert) error {
+		if alert.Status != models.AlertResolved {
+			markResolved(alert, "detector")
+			changed = true
+		}
+		return nil
+	})
+	if errors.Is(err, storage.ErrAlertNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Error resolving alert of attack %s: %v", attack.ID, err)
+		return true
+	}
+	if changed {
+		broadcastAlertUpdate(*alert)
+	}
+	return true
+}
+
+// markResolved marks an alert resolved by actor, unless it already is
+func markResolved(alert *models.Alert, actor string) {
+	if alert.Status == models.AlertResolved {
+		return
+	}
+	now := time.Now()
+	alert.Status = models.AlertResolved
+	alert.ResolvedBy = actor
+	alert.ResolvedAt = &now
+}
+
+// broadcastAlertUpdate tells dashboard clients an alert changed status
+func broadcastAlertUpdate(alert models.Alert) {
+	broadcastMessage(map[string]interface{}{
+		"type":    "alert_updated",
+		"payload": alert,
+	})
+}
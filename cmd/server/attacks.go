@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/mitigation"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+type mergeAttacksRequest struct {
+	TargetID string `json:"target_id" binding:"required"`
+	SourceID string `json:"source_id" binding:"required"`
+	Reason   string `json:"reason"`
+}
+
+type splitAttackRequest struct {
+	SourceIPs []string `json:"source_ips" binding:"required,min=1"`
+	Reason    string   `json:"reason"`
+}
+
+// mergeAttacks folds one attack into another as a single incident
+func (s *Server) mergeAttacks(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	var req mergeAttacksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Hold the cycle off so it can't write back the attacks as they were,
+	// and take the decision only on the leader, whose correlator tracks them
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	if !s.isLeader.Load() {
+		c.JSON(http.StatusConflict, gin.H{"error": "this node is not the analysis leader"})
+		return
+	}
+
+	target, ok := s.lookupAttack(c, req.TargetID)
+	if !ok {
+		return
+	}
+	source, ok := s.lookupAttack(c, req.SourceID)
+	if !ok {
+		return
+	}
+
+	if target.ID == source.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "attacks are already the same incident"})
+		return
+	}
+
+	merged := detection.MergeAttacks(*target, *source)
+	decision := models.AttackDecision{
+		Action:    "MERGE",
+		AttackIDs: []string{target.ID, source.ID},
+		Operator:  principal.Name,
+		Reason:    req.Reason,
+		Timestamp: time.Now(),
+	}
+
+	if err := s.redis.ApplyMerge(merged, source.ID, decision); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Later detections of either attack continue the merged incident
+	s.pulses.Merge(merged, source.ID)
+	if s.autoscaler != nil {
+		s.autoscaler.Merge(merged.ID, source.ID)
+	}
+	s.replicateState()
+
+	s.recordTimeline(merged.ID, models.TimelineEvent{
+		Timestamp: decision.Timestamp,
+		Type:      "MERGED",
+		Message:   fmt.Sprintf("Merged attack %s into this incident", source.ID),
+		Actor:     principal.Name,
+	})
+
+	broadcastMessage(map[string]interface{}{
+		"type":    "attack_merged",
+		"payload": gin.H{"attack": merged, "merged_id": source.ID},
+	})
+
+	c.JSON(http.StatusOK, merged)
+}
+
+// splitAttack moves some source IPs of an attack into a separate incident
+func (s *Server) splitAttack(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	var req splitAttackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Like merges, split on the leader between cycles
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	if !s.isLeader.Load() {
+		c.JSON(http.StatusConflict, gin.H{"error": "this node is not the analysis leader"})
+		return
+	}
+
+	attack, ok := s.lookupAttack(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	remaining, split, err := detection.SplitAttack(*attack, req.SourceIPs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision := models.AttackDecision{
+		Action:    "SPLIT",
+		AttackIDs: []string{remaining.ID, split.ID},
+		Operator:  principal.Name,
+		Reason:    req.Reason,
+		Timestamp: time.Now(),
+	}
+
+	if err := s.redis.ApplySplit(remaining, split, decision); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.pulses.Split(remaining, split)
+	s.replicateState()
+
+	s.recordTimeline(remaining.ID, models.TimelineEvent{
+		Timestamp: decision.Timestamp,
+		Type:      "SPLIT",
+		Message:   fmt.Sprintf("Split %d source IPs into attack %s", len(split.SourceIPs), split.ID),
+		Actor:     principal.Name,
+	})
+
+	broadcastMessage(map[string]interface{}{
+		"type":    "attack_split",
+		"payload": gin.H{"attack": remaining, "split": split},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"attack": remaining,
+		"split":  split,
+	})
+}
+
+// mergeBlocked reports whether an operator split two attacks apart, so
+// correlation keeps their sources separate
+func (s *Server) mergeBlocked(a, b string) bool {
+	blocked, err := s.redis.IsMergeBlocked(a, b)
+	if err != nil {
+		log.Printf("Error checking split attacks %s and %s: %v", a, b, err)
+		return false
+	}
+	return blocked
+}
+
+// getAttackDecisions lists manual merge/split decisions
+func (s *Server) getAttackDecisions(c *gin.Context) {
+	decisions, err := s.redis.GetAttackDecisions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decisions": decisions,
+	})
+}
+
+// getAttackTimeline returns the incident timeline of an attack
+func (s *Server) getAttackTimeline(c *gin.Context) {
+	id, err := s.redis.ResolveAttackID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := s.redis.GetTimeline(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attack_id": id,
+		"events":    events,
+	})
+}
+
+// compareAttacks lines up two incidents (?ids=a,b) side by side
+func (s *Server) compareAttacks(c *gin.Context) {
+	ids := splitList(c.Query("ids"))
+	if len(ids) != 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must name exactly two attacks, e.g. ids=a,b"})
+		return
+	}
+
+	var attacks [2]*models.Attack
+	var timelines [2][]models.TimelineEvent
+	for i, id := range ids {
+		attack, ok := s.lookupAttack(c, id)
+		if !ok {
+			return
+		}
+
+		events, err := s.redis.GetTimeline(attack.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		attacks[i], timelines[i] = attack, events
+	}
+
+	c.JSON(http.StatusOK, detection.CompareAttacks(*attacks[0], *attacks[1], timelines[0], timelines[1]))
+}
+
+// getAttackRecommendations suggests how to block an attack, and per-path
+// rate limits for an application-layer attack. ?format=nginx|haproxy|envoy
+// returns just that proxy's rate-limit configuration as text.
+func (s *Server) getAttackRecommendations(c *gin.Context) {
+	attack, ok := s.lookupAttack(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	recommendations := mitigation.RecommendRateLimits(*attack, detection.AttackWindow(*attack))
+	allow, _ := s.detectors.IPLists()
+
+	if format := c.Query("format"); format != "" {
+		if format != "nginx" && format != "haproxy" && format != "envoy" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be nginx, haproxy or envoy"})
+			return
+		}
+
+		var config strings.Builder
+		for _, rec := range recommendations {
+			config.WriteString(rec.Snippets[format])
+			config.WriteString("\n")
+		}
+		c.String(http.StatusOK, config.String())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attack_id":       attack.ID,
+		"attack_type":     attack.Type,
+		"blocking":        mitigation.RecommendBlocking(*attack, allow),
+		"recommendations": recommendations,
+	})
+}
+
+// lookupAttack fetches an active attack, writing a 404/500 response on failure
+func (s *Server) lookupAttack(c *gin.Context, id string) (*models.Attack, bool) {
+	attack, err := s.redis.GetAttack(id)
+	if errors.Is(err, storage.ErrAttackNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attack not found", "id": id})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return attack, true
+}
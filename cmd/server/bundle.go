@@ -0,0 +1,69 @@
+//go:build bundle
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/web"
+)
+
+// Bundle builds (go build -tags bundle) run without Redis by default: an
+// embedded in-memory store speaks the Redis protocol on loopback
+const defaultRedisAddr = ""
+
+// startEmbeddedStore starts the in-process Redis-compatible store and
+// returns its address. Data lives in memory only and is lost on restart.
+func startEmbeddedStore() (string, error) {
+	store := miniredis.NewMiniRedis()
+	if err := store.StartAddr("127.0.0.1:0"); err != nil {
+		return "", fmt.Errorf("embedded store: %w", err)
+	}
+
+	// miniredis only expires keys when told time has passed
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			store.FastForward(time.Second)
+		}
+	}()
+
+	log.Printf("🗄️  Embedded store listening on %s", store.Addr())
+	return store.Addr(), nil
+}
+
+// serveUI serves the dashboard compiled into the binary
+func serveUI(router *gin.Engine) {
+	index, err := web.Files.ReadFile("index.html")
+	if err != nil {
+		log.Fatalf("Embedded UI missing: %v", err)
+	}
+
+	router.GET("/", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	})
+}
+
+// firstRunTokens lets a bundle start as shipped, with auth required but no
+// credentials configured: it makes an admin token for this run and prints
+// it, to paste into the dashboard's Mitigations card
+func firstRunTokens() (*auth.TokenStore, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(secret)
+
+	log.Printf("🔑 No -api-tokens or -users-file, admin token for this run: %s", token)
+	log.Printf("🔑 It changes on every restart; pass -api-tokens or -users-file to keep credentials")
+	return auth.ParseTokens("admin:admin:" + token)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/proxy"
+)
+
+// errNoProxy is returned when connection endpoints are used outside proxy mode
+var errNoProxy = errors.New("proxy mode is off; start the server with -proxy-listen and -proxy-backend")
+
+// slowPolicy returns the configured slow connection policy with any of
+// min_age, min_write_bps and min_read_bps overridden
+func (s *Server) slowPolicy(get func(string) string) (proxy.Policy, error) {
+	policy := s.slowConnections
+	if v := get("min_age"); v != "" {
+		age, err := time.ParseDuration(v)
+		if err != nil {
+			return policy, fmt.Errorf("invalid min_age %q", v)
+		}
+		policy.MinAge = age
+	}
+	for name, rate := range map[string]*float64{
+		"min_write_bps": &policy.MinWriteRate,
+		"min_read_bps":  &policy.MinReadRate,
+	} {
+		if v := get(name); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || n < 0 {
+				return policy, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*rate = n
+		}
+	}
+	return policy, nil
+}
+
+// getSlowConnections lists the open proxied connections that are slow
+// under the configured policy or the one given in the query
+func (s *Server) getSlowConnections(c *gin.Context) {
+	if s.proxy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errNoProxy.Error()})
+		return
+	}
+
+	policy, err := s.slowPolicy(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slow := s.proxy.Slow(policy)
+	c.JSON(http.StatusOK, gin.H{
+		"connections": slow,
+		"count":       len(slow),
+		"open":        len(s.proxy.Connections(policy)),
+		"policy": gin.H{
+			"min_age_seconds": policy.MinAge.Seconds(),
+			"min_write_bps":   policy.MinWriteRate,
+			"min_read_bps":    policy.MinReadRate,
+		},
+	})
+}
+
+// closeSlowConnections force-closes the proxied connections that are slow
+// under the configured policy or the one given in the params
+func (s *Server) closeSlowConnections(params map[string]string) (interface{}, error) {
+	if s.proxy == nil {
+		return nil, errNoProxy
+	}
+
+	policy, err := s.slowPolicy(func(name string) string { return params[name] })
+	if err != nil {
+		return nil, err
+	}
+
+	closed := s.proxy.CloseSlow(policy)
+	return gin.H{"closed": closed, "count": len(closed)}, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// trafficSince sums, per environment, the requests and bytes received since
+// the given time, each request standing for weight
+func trafficSince(requests []models.TrafficRequest, weight int, since time.Time) map[string]models.AttackTraffic {
+	traffic := make(map[string]models.AttackTraffic)
+
+	for env, scoped := range detection.PartitionByEnvironment(requests) {
+		var sum models.AttackTraffic
+		for _, req := range scoped {
+			if req.Timestamp.After(since) {
+				sum.Requests += int64(weight)
+				sum.Bytes += int64(req.BytesSent+req.BytesRecv) * int64(weight)
+			}
+		}
+		traffic[env] = sum
+	}
+
+	return traffic
+}
+
+// excessTraffic takes, per environment, the traffic received over elapsed
+// above what the environment's baseline predicts
+func (s *Server) excessTraffic(received map[string]models.AttackTraffic, elapsed time.Duration) map[string]models.AttackTraffic {
+	excess := make(map[string]models.AttackTraffic)
+
+	for env, traffic := range received {
+		count, bytes := traffic.Requests, traffic.Bytes
+		if count == 0 {
+			continue
+		}
+
+		// The baseline rate is requests per analysis window
+		baseline := s.detectors.Get(env).Snapshot().Baseline
+		expected := baseline.AverageRequestRate * elapsed.Seconds() / detection.AnalysisWindow.Seconds()
+		extra := math.Max(float64(count)-expected, 0)
+
+		excess[env] = models.AttackTraffic{
+			Requests: int64(extra),
+			Bytes:    int64(float64(bytes) * extra / float64(count)),
+		}
+	}
+
+	return excess
+}
+
+// accountAttackCost adds a share of this cycle's excess traffic to an attack
+// and re-prices it, adding the increase to the reporting rollups
+func (s *Server) accountAttackCost(attack models.Attack, share models.AttackTraffic, now time.Time) models.Attack {
+	traffic := models.AttackTraffic{}
+	if attack.Traffic != nil {
+		traffic = *attack.Traffic
+	}
+	traffic.Requests += share.Requests
+	traffic.Bytes += share.Bytes
+	attack.Traffic = &traffic
+
+	previous := models.CostEstimate{}
+	if attack.Cost != nil {
+		previous = *attack.Cost
+	}
+	attack.Cost = s.costModel.Estimate(attack, now)
+
+	attackDelta := attack.Cost.AttackCost - previous.AttackCost
+	mitigationDelta := attack.Cost.MitigationCost - previous.MitigationCost
+	if attackDelta != 0 || mitigationDelta != 0 {
+		if err := s.redis.AddRollupCost(attack.Environment, now, attackDelta, mitigationDelta); err != nil {
+			log.Printf("Error recording cost of attack %s: %v", attack.ID, err)
+		}
+	}
+
+	return attack
+}
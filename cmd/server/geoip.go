@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/geoip"
+)
+
+// maxCountryMinutes is as far back as per-minute metrics are kept
+const maxCountryMinutes = 60
+
+// metricsMinutes reads ?minutes= (default and at most 60), writing a 400
+// when it is out of range
+func metricsMinutes(c *gin.Context) (int, bool) {
+	raw := c.Query("minutes")
+	if raw == "" {
+		return maxCountryMinutes, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > maxCountryMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minutes must be between 1 and 60"})
+		return 0, false
+	}
+	return n, true
+}
+
+// getCountryMetrics reports the requests per source country over the last
+// ?minutes= minutes (default and at most 60)
+func (s *Server) getCountryMetrics(c *gin.Context) {
+	minutes, ok := metricsMinutes(c)
+	if !ok {
+		return
+	}
+
+	countries, total, err := s.redis.GetCountryMetrics(minutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	located := int64(0)
+	for _, country := range countries {
+		located += country.Requests
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"minutes":          minutes,
+		"total_requests":   total,
+		"located_requests": located,
+		"countries":        countries,
+	})
+}
+
+// getTopASNs reports the busiest source autonomous systems over the last
+// ?minutes= minutes (default and at most 60), at most ?limit= (default 20)
+// of them, marking the hosting networks of ?environment=
+func (s *Server) getTopASNs(c *gin.Context) {
+	minutes, ok := metricsMinutes(c)
+	if !ok {
+		return
+	}
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = n
+	}
+
+	asns, total, err := s.redis.GetTopASNs(minutes, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hosting := s.detectors.Thresholds(c.Query("environment")).HostingASNs
+	for i := range asns {
+		asns[i].Hosting = slices.Contains(hosting, asns[i].ASN)
+	}
+	c.JSON(http.StatusOK, gin.H{"minutes": minutes, "total_requests": total, "asns": asns})
+}
+
+// getGeoIPStatus reports the loaded GeoIP databases
+func (s *Server) getGeoIPStatus(c *gin.Context) {
+	if s.geoip == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "databases": []geoip.Database{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "databases": s.geoip.Databases()})
+}
+
+// lookupGeoIP returns the country and autonomous system of ?ip=
+func (s *Server) lookupGeoIP(c *gin.Context) {
+	ip := c.Query("ip")
+	if _, err := netip.ParseAddr(ip); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip must be an IP address"})
+		return
+	}
+	if s.geoip == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no GeoIP databases are configured"})
+		return
+	}
+
+	loc, found := s.geoip.Lookup(ip)
+	c.JSON(http.StatusOK, gin.H{"ip": ip, "found": found, "country": loc.Country, "asn": loc.ASN, "as_org": loc.ASOrg})
+}
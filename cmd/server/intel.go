@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/intel"
+)
+
+// getIntelFeeds reports the threat intelligence feeds and their last fetch
+func (s *Server) getIntelFeeds(c *gin.Context) {
+	if s.intel == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "feeds": []intel.FeedStatus{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "feeds": s.intel.Status()})
+}
+
+// lookupIntel returns what the threat intelligence feeds know about ?ip=
+func (s *Server) lookupIntel(c *gin.Context) {
+	ip := c.Query("ip")
+	if _, err := netip.ParseAddr(ip); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip must be an IP address"})
+		return
+	}
+	if s.intel == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no threat intelligence feeds are configured"})
+		return
+	}
+
+	rep, listed := s.intel.Lookup(ip)
+	c.JSON(http.StatusOK, gin.H{"ip": ip, "listed": listed, "score": rep.Score, "feeds": rep.Feeds})
+}
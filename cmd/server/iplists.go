@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/mitigation"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// maxIPListImport bounds the size of a bulk import body
+const maxIPListImport = 16 << 20
+
+// ipListEntryRequest adds one CIDR to a list
+type ipListEntryRequest struct {
+	CIDR    string `json:"cidr"`
+	Comment string `json:"comment"`
+}
+
+// loadIPLists reloads the allowlist and denylist from Redis into detection,
+// keeping the lists in force when Redis can't be read
+func (s *Server) loadIPLists() {
+	allow, err := s.redis.GetIPList(iplist.Allow)
+	if err != nil {
+		log.Printf("Error loading the allowlist: %v", err)
+		return
+	}
+	deny, err := s.redis.GetIPList(iplist.Deny)
+	if err != nil {
+		log.Printf("Error loading the denylist: %v", err)
+		return
+	}
+
+	s.detectors.SetIPLists(iplist.NewSet(allow), iplist.NewSet(deny))
+}
+
+// startIPListRefresh reloads the lists every interval until ctx is done, so
+// changes made through any node reach every node's ingest and detection
+func (s *Server) startIPListRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.loadIPLists()
+		}
+	}
+}
+
+// enforcedMitigations is the mitigation set enforcement points apply: the
+// recorded mitigations adjusted for the allowlist and denylist
+type enforcedMitigations struct {
+	server *Server
+}
+
+func (e enforcedMitigations) GetActiveMitigations() ([]models.MitigationAction, int64, error) {
+	actions, serial, err := e.server.redis.GetActiveMitigations()
+	if err != nil {
+		return nil, 0, err
+	}
+	allow, deny := e.server.detectors.IPLists()
+	return mitigation.ApplyIPLists(actions, allow, deny), serial, nil
+}
+
+// ipListParam reads the list named in the path, writing a 404 for others
+func ipListParam(c *gin.Context) (string, bool) {
+	list := c.Param("list")
+	if !iplist.Valid(list) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown list %q, use %s or %s", list, iplist.Allow, iplist.Deny)})
+		return "", false
+	}
+	return list, true
+}
+
+// getIPList lists the entries of the allowlist or denylist
+func (s *Server) getIPList(c *gin.Context) {
+	list, ok := ipListParam(c)
+	if !ok {
+		return
+	}
+
+	entries, err := s.redis.GetIPList(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"list": list, "count": len(entries), "entries": entries})
+}
+
+// addIPListEntries adds one CIDR, or an array of them, to a list
+func (s *Server) addIPListEntries(c *gin.Context) {
+	list, ok := ipListParam(c)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var requests []ipListEntryRequest
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(body, &requests)
+	} else {
+		var single ipListEntryRequest
+		err = json.Unmarshal(body, &single)
+		requests = []ipListEntryRequest{single}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	entries := make([]models.IPListEntry, 0, len(requests))
+	for _, req := range requests {
+		prefix, err := iplist.ParseCIDR(req.CIDR)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entries = append(entries, models.IPListEntry{
+			CIDR:    prefix.String(),
+			Comment: req.Comment,
+			AddedBy: principal.Name,
+			AddedAt: time.Now(),
+		})
+	}
+
+	cidrs := make([]string, len(entries))
+	for i, entry := range entries {
+		cidrs[i] = entry.CIDR
+	}
+	audit := s.storeIPList(c, "add_iplist_entries", list, entries, false,
+		map[string]string{"list": list, "cidrs": strings.Join(cidrs, ",")})
+	if audit == nil {
+		return
+	}
+	log.Printf("📋 %s added %d entries to the %slist", principal.Name, len(entries), list)
+
+	c.JSON(http.StatusOK, gin.H{"list": list, "added": entries, "audit_id": audit.ID})
+}
+
+// importIPList adds a plain-text list to a list, one address or CIDR per
+// line with an optional comment after it; lines starting with # are
+// skipped. ?replace=true replaces the whole list instead.
+func (s *Server) importIPList(c *gin.Context) {
+	list, ok := ipListParam(c)
+	if !ok {
+		return
+	}
+	replace := c.Query("replace") == "true"
+
+	principal := c.MustGet("principal").(auth.Principal)
+	now := time.Now()
+	entries := make([]models.IPListEntry, 0)
+	invalid := make([]string, 0)
+
+	scanner := bufio.NewScanner(io.LimitReader(c.Request.Body, maxIPListImport))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		prefix, err := iplist.ParseCIDR(fields[0])
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		comment := strings.TrimSpace(strings.TrimPrefix(strings.Join(fields[1:], " "), "#"))
+		entries = append(entries, models.IPListEntry{
+			CIDR:    prefix.String(),
+			Comment: comment,
+			AddedBy: principal.Name,
+			AddedAt: now,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the list has invalid lines", "invalid": invalid})
+		return
+	}
+
+	audit := s.storeIPList(c, "import_iplist", list, entries, replace,
+		map[string]string{"list": list, "entries": fmt.Sprint(len(entries)), "replace": fmt.Sprint(replace)})
+	if audit == nil {
+		return
+	}
+	log.Printf("📋 %s imported %d entries into the %slist (replace: %t)", principal.Name, len(entries), list, replace)
+
+	c.JSON(http.StatusOK, gin.H{"list": list, "imported": len(entries), "replaced": replace, "audit_id": audit.ID})
+}
+
+// storeIPList writes entries to a list, audits the change and reloads the
+// lists. On failure it writes the error response and returns nil.
+func (s *Server) storeIPList(c *gin.Context, action, list string, entries []models.IPListEntry, replace bool, params map[string]string) *models.ActionAudit {
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := models.ActionAudit{
+		Action: action,
+		Params: params,
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+
+	var err error
+	if replace {
+		err = s.redis.ReplaceIPList(list, entries)
+	} else {
+		err = s.redis.StoreIPListEntries(list, entries)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return nil
+	}
+	s.loadIPLists()
+
+	entry.Success = true
+	entry = s.auditAction(entry)
+	return &entry
+}
+
+// deleteIPListEntry removes the ?cidr= entry from a list
+func (s *Server) deleteIPListEntry(c *gin.Context) {
+	list, ok := ipListParam(c)
+	if !ok {
+		return
+	}
+	prefix, err := iplist.ParseCIDR(c.Query("cidr"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cidr := prefix.String()
+
+	removed, err := s.redis.RemoveIPListEntry(list, cidr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not on the list", "list": list, "cidr": cidr})
+		return
+	}
+	s.loadIPLists()
+
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := s.auditAction(models.ActionAudit{
+		Action:  "remove_iplist_entry",
+		Params:  map[string]string{"list": list, "cidr": cidr},
+		Actor:   principal.Name,
+		Role:    string(principal.Role),
+		Success: true,
+	})
+	log.Printf("📋 %s removed %s from the %slist", principal.Name, cidr, list)
+
+	c.JSON(http.StatusOK, gin.H{"list": list, "cidr": cidr, "audit_id": entry.ID})
+}
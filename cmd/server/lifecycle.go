@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+)
+
+// closeEndedAttacks closes the attacks that are no longer detected: the
+// final cost is priced, the record moves from the active set to history,
+// executors lifting actions with their attack lift them, dashboards
+// receive an "attack_ended" event and notifiers an attack.ended one
+func (s *Server) closeEndedAttacks(now time.Time) {
+	ended := s.pulses.End(now)
+	if len(ended) == 0 {
+		return
+	}
+	// Replicate even when no traffic arrives to analyze, so a restart
+	// doesn't end the same attacks again
+	defer s.replicateState()
+
+	for _, attack := range ended {
+		attack = s.accountAttackCost(attack, models.AttackTraffic{}, now)
+		s.pulses.Update(attack)
+
+		if err := s.redis.EndAttack(attack); err != nil {
+			log.Printf("Error ending attack %s: %v", attack.ID, err)
+			continue
+		}
+
+		if err := s.mitigations.End(attack.ID); err != nil {
+			log.Printf("Error releasing mitigations of attack %s: %v", attack.ID, err)
+		}
+		// Scale the service back down once the last absorbed attack ends
+		if s.autoscaler != nil {
+			event, err := s.autoscaler.Release(context.Background(), attack.ID)
+			if err != nil {
+				log.Printf("Error restoring capacity after attack %s: %v", attack.ID, err)
+			}
+			if event != nil {
+				log.Printf("📉 %s", event.Message)
+				s.recordTimeline(attack.ID, *event)
+			}
+		}
+
+		duration := attack.EndTime.Sub(attack.StartTime).Round(time.Second)
+		log.Printf("✅ Attack ended: %s in %s after %s", attack.Type, attack.Environment, duration)
+		s.recordTimeline(attack.ID, models.TimelineEvent{
+			Timestamp: now,
+			Type:      "ENDED",
+			Message:   fmt.Sprintf("Not detected since %s; lasted %s", attack.EndTime.Format(time.RFC3339), duration),
+			Actor:     "detector",
+		})
+
+		broadcastMessage(map[string]interface{}{
+			"type":    "attack_ended",
+			"payload": attack,
+		})
+		// An attack held back as a repeat or silenced throughout ends as
+		// quietly as it started
+		if s.resolveAttackAlert(attack) && s.alerts.Alerted(attack) {
+			s.sendNotification(notify.AttackNotification(notify.EventAttackEnded, attack))
+		}
+	}
+}
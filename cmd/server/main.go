@@ -1,16 +1,48 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/bogon"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/chaos"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/config"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/cost"
 	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/geoip"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/handoff"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ingestion"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/intel"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/mitigation"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ml"
 	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/proxy"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ratelimit"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/rules"
 	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
 )
 
 var (
@@ -21,31 +53,211 @@ var (
 	}
 
 	wsClients = make(map[*websocket.Conn]bool)
+	wsMu      sync.Mutex
+
+	// faults injects failures in chaos mode; nil (no faults) otherwise
+	faults *chaos.Injector
 )
 
 type Server struct {
-	redis    *storage.RedisClient
-	detector *detection.Detector
-	router   *gin.Engine
+	redis     *storage.RedisClient
+	detectors *detection.Pool
+	router    *gin.Engine
+
+	// Cloudflare Logpush push destination settings
+	logpushSecret      string
+	logpushEnvironment string
+
+	// Mitigation backends and the loop keeping them in line with the records
+	executors   []mitigation.Executor
+	reconciler  *mitigation.Reconciler
+	autoscaler  *mitigation.AutoscaleHook
+	mitigations *mitigation.Engine
+	// autoMitigate has detected attacks acted on by policy; manual actions
+	// are taken either way
+	autoMitigate bool
+	// enforcer answers reverse proxies asking whether to let a client through
+	enforcer *mitigation.TokenBucketExecutor
+	// mitigationChanges wakes the long-polls of the exported block list
+	mitigationChanges *serialNotifier
+
+	// Bearer tokens for admin endpoints
+	tokens *auth.TokenStore
+	// Dashboard users and their login sessions; nil without a users file
+	users    *auth.UserStore
+	sessions *auth.Sessions
+	// authRequired makes reads take at least a viewer too
+	authRequired bool
+
+	// External destinations notified of every detected attack
+	notifiers []notify.Notifier
+	// email also sends the daily digest when configured
+	email *notify.EmailNotifier
+	// alertmanager also has the active attacks' alerts resent
+	alertmanager *notify.AlertmanagerNotifier
+
+	// Optional bus consumers; nil when ingest is HTTP only
+	kafka *ingestion.KafkaConsumer
+	nats  *ingestion.NATSConsumer
+
+	// Only the node holding the leader lease runs analysis; the others stand by
+	nodeID   string
+	isLeader atomic.Bool
+
+	// Serializes scheduled and on-demand analysis cycles
+	analysisMu sync.Mutex
+
+	// Switches detection to approximate mode while the pipeline is saturated
+	load            *detection.LoadGovernor
+	backlogCapacity int64
+
+	// Folds repeated detections into one record per attack or pulse-wave campaign
+	pulses *detection.PulseCorrelator
+	// Holds back repeated alerts of the same attack
+	alerts *detection.AlertGate
+	// Silences holding back alerts and automatic mitigation, as last read
+	// from Redis
+	silencesMu sync.RWMutex
+	silences   []models.Silence
+
+	// Routes attack notifications to channels in turn, as last read from
+	// Redis; empty routes send every notification to every channel
+	routingMu sync.RWMutex
+	routing   notify.Routing
+	// Serializes changes to the routed attacks
+	routedMu sync.Mutex
+
+	// In-process window of this node's ingest; nil when analysis reads the
+	// shared raw traffic from Redis
+	stream *detection.StreamWindow
+
+	// Listening sockets and sessions passed between binaries on upgrade
+	handoff *handoff.Handoff
+
+	// TCP proxy mode and the policy its slow connections are judged by; nil
+	// when the server only observes traffic
+	proxy           *proxy.Proxy
+	slowConnections proxy.Policy
+
+	// User-defined detection rules; nil without a rules file
+	rules *rules.Engine
+
+	// Threat intelligence feeds scoring sources; nil without feeds
+	intel *intel.Intel
+
+	// GeoLite2 databases locating sources; nil without them
+	geoip *geoip.Resolver
+
+	// Thresholds file, applied at startup and over replicated state; nil without one
+	thresholdsConfig *detection.ThresholdsConfig
+
+	// Shadow mode: thresholds evaluated next to production without acting
+	// on what they detect, the file they were loaded from (nil without one),
+	// and the attacks they detected, folded like production's
+	shadowConfig       *detection.ThresholdsConfig
+	shadowPulses       *detection.PulseCorrelator
+	shadowInProduction map[string]bool
+
+	// When each ingestion source last delivered traffic to this node
+	sensors *sensorTracker
+
+	// Prices attacks by the excess traffic they cause since the previous cycle
+	costModel   cost.Model
+	lastCycleAt time.Time
+
+	// How often analysis runs
+	analysisInterval time.Duration
+	// Origins browsers may call the API from; * allows any
+	corsOrigins []string
+	// Unversioned API paths: whether they are retired, the date announced
+	// for that (zero for none), and who still calls them
+	legacyAPIOff    bool
+	legacyAPISunset time.Time
+	legacyAPIUsage  legacyAPIUsage
+
+	// ingestValidator bounds the traffic from every ingestion source
+	ingestValidator ingestion.Validator
+
+	// agentCertRequired has agents present a client certificate to ship
+	// traffic
+	agentCertRequired bool
+
+	// Rate limits per client address and per API token or user, nil when
+	// off, the client ranges exempt from them, and the requests refused
+	ipLimiter           *ratelimit.Limiter
+	keyLimiter          *ratelimit.Limiter
+	rateLimitExemptions []netip.Prefix
+	rejections          *rejectionCounter
+}
+
+// leaderLeaseTTL is how long a leader may miss renewals before a standby takes over
+const leaderLeaseTTL = 15 * time.Second
+
+// defaultAnalysisInterval is how often the analysis engine runs, and so its
+// time budget per cycle
+const defaultAnalysisInterval = 5 * time.Second
+
+// pulseBurstGap is the detection gap that ends a burst; it tolerates one
+// missed cycle
+func pulseBurstGap(analysisInterval time.Duration) time.Duration {
+	return 3 * analysisInterval
 }
 
-func NewServer() (*Server, error) {
+// wsWriteTimeout bounds one broadcast write to a dashboard; a client that
+// can't take a message within it is dropped, so it can't hold up the rest
+const wsWriteTimeout = 2 * time.Second
+
+// maxIngestBodyBytes bounds one request POSTed to the ingest endpoint
+const maxIngestBodyBytes = 64 << 10
+
+// maxLogpushBodyBytes bounds one Logpush batch, as sent
+const maxLogpushBodyBytes = 32 << 20
+
+const (
+	// An attack not detected for this long has ended. Detection lags the
+	// traffic by up to one analysis window, so this counts from the last
+	// detection, not the last attack packet.
+	defaultAttackEndAfter = time.Minute
+	// Bursts further apart than this are unrelated attacks
+	defaultPulseWindow = 10 * time.Minute
+	// An attack recurring within this of its last alert doesn't alert again
+	defaultAlertCooldown = 15 * time.Minute
+)
+
+func NewServer(redisAddr string, analysisInterval time.Duration, corsOrigins []string) (*Server, error) {
 	// Initialize Redis
-	redisClient, err := storage.NewRedisClient("localhost:6379", "", 0)
+	redisClient, err := storage.NewRedisClient(redisAddr, "", 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	// Initialize detector
-	detector := detection.NewDetector()
+	// Initialize per-environment detectors
+	detectors := detection.NewPool()
 
-	// Create Gin router
-	router := gin.Default()
+	// Create Gin router, logging requests with credentials redacted
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(redactedLogFormatter), gin.Recovery())
+
+	hostname, _ := os.Hostname()
 
 	server := &Server{
-		redis:    redisClient,
-		detector: detector,
-		router:   router,
+		redis:     redisClient,
+		detectors: detectors,
+		router:    router,
+		nodeID:    fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		load:      detection.NewLoadGovernor(analysisInterval),
+		pulses:    detection.NewPulseCorrelator(pulseBurstGap(analysisInterval), defaultAttackEndAfter, defaultPulseWindow),
+		alerts:    detection.NewAlertGate(defaultAlertCooldown),
+
+		shadowPulses:       detection.NewPulseCorrelator(pulseBurstGap(analysisInterval), defaultAttackEndAfter, defaultPulseWindow),
+		shadowInProduction: make(map[string]bool),
+		costModel:          cost.DefaultModel(),
+
+		analysisInterval:  analysisInterval,
+		corsOrigins:       corsOrigins,
+		rejections:        newRejectionCounter(),
+		sensors:           newSensorTracker(),
+		mitigationChanges: newSerialNotifier(),
 	}
 
 	server.setupRoutes()
@@ -55,44 +267,167 @@ func NewServer() (*Server, error) {
 
 func (s *Server) setupRoutes() {
 	// Enable CORS
-	s.router.Use(corsMiddleware())
+	s.router.Use(corsMiddleware(s.corsOrigins))
+
+	// API routes, versioned so that breaking changes to paths or response
+	// shapes go into a new version. The unversioned paths of earlier
+	// releases answer as v1 until they are retired.
+	s.apiRoutes(s.router.Group(apiV1Prefix, s.limitClients(), s.requireViewer()))
+	s.apiRoutes(s.router.Group(legacyAPIPrefix, s.legacyAPI(), s.limitClients(), s.requireViewer()))
+
+	// WebSocket endpoint
+	s.router.GET("/ws", s.limitClients(), s.requireViewer(), s.handleWebSocket)
 
-	// API routes
-	api := s.router.Group("/api")
-	{
-		// Traffic ingestion
-		api.POST("/traffic/ingest", s.ingestTraffic)
+	// Serve static HTML dashboard
+	serveUI(s.router)
+}
 
-		// Metrics
-		api.GET("/metrics/current", s.getCurrentMetrics)
-		api.GET("/metrics/history", s.getMetricsHistory)
+// apiRoutes registers the API's endpoints on a version's group
+func (s *Server) apiRoutes(api *gin.RouterGroup) {
+	// Login sessions
+	api.POST("/auth/login", s.login)
+	api.GET("/auth/session", s.requireToken(), s.getSession)
+	api.POST("/auth/logout", s.requireToken(), s.logout)
+	api.POST("/auth/ws-ticket", s.requireToken(), s.issueWSTicket)
 
-		// Attacks
-		api.GET("/attacks/active", s.getActiveAttacks)
-		api.GET("/attacks/history", s.getAttackHistory)
+	// Traffic ingestion
+	api.POST("/traffic/ingest", s.requireAgentCert(), s.ingestTraffic)
+	api.POST("/ingest/cloudflare", s.ingestCloudflareLogs)
+	api.GET("/ingest/status", s.getIngestStatus)
 
-		// Dashboard stats
-		api.GET("/stats/summary", s.getSummaryStats)
-	}
+	// Health of the pipeline itself
+	api.GET("/system/overview", s.getSystemOverview)
 
-	// WebSocket endpoint
-	s.router.GET("/ws", s.handleWebSocket)
+	// Metrics
+	api.GET("/metrics/current", s.getCurrentMetrics)
+	api.GET("/metrics/history", s.getMetricsHistory)
+	api.GET("/metrics/forecast", s.getMetricsForecast)
+	api.GET("/metrics/countries", s.getCountryMetrics)
+	api.GET("/metrics/top-asns", s.getTopASNs)
 
-	// Serve static HTML dashboard
-	s.router.StaticFile("/", "./web/index.html")
+	// Attacks
+	api.GET("/attacks/active", s.getActiveAttacks)
+	api.GET("/attacks/history", s.getAttackHistory)
+	api.GET("/attacks/decisions", s.getAttackDecisions)
+	api.GET("/attacks/shadow", s.getShadowAttacks)
+	api.GET("/attacks/compare", s.compareAttacks)
+	api.POST("/attacks/merge", s.requireToken(), requireRole(auth.RoleAnalyst), s.mergeAttacks)
+	api.POST("/attacks/:id/split", s.requireToken(), requireRole(auth.RoleAnalyst), s.splitAttack)
+	api.GET("/attacks/:id/timeline", s.getAttackTimeline)
+	api.GET("/attacks/:id/recommendations", s.getAttackRecommendations)
+
+	// Mitigations
+	api.GET("/mitigations/active/export", s.exportActiveMitigations)
+	api.GET("/mitigations/drift", s.getMitigationDrift)
+	api.GET("/mitigations/history", s.getMitigationHistory)
+	api.GET("/mitigations", s.listMitigations)
+	api.GET("/mitigations/:id", s.getMitigation)
+	api.GET("/mitigations/:id/effect", s.getMitigationEffect)
+	api.POST("/mitigations", s.requireToken(), requireRole(auth.RoleAdmin), s.createMitigation)
+	api.DELETE("/mitigations/:id", s.requireToken(), requireRole(auth.RoleAdmin), s.deleteMitigation)
+
+	// Alerts and their acknowledgement
+	api.GET("/alerts", s.getAlerts)
+	api.POST("/alerts/:id/ack", s.requireToken(), requireRole(auth.RoleAnalyst), s.acknowledgeAlert)
+	api.POST("/alerts/:id/resolve", s.requireToken(), requireRole(auth.RoleAnalyst), s.resolveAlert)
+
+	// Silences for load tests and maintenance
+	api.GET("/silences", s.getSilences)
+	api.POST("/silences", s.requireToken(), requireRole(auth.RoleAdmin), s.createSilence)
+	api.DELETE("/silences/:id", s.requireToken(), requireRole(auth.RoleAdmin), s.expireSilence)
+
+	// Notification delivery log
+	api.GET("/notifications/deliveries", s.getNotificationDeliveries)
+	api.GET("/notifications/dead-letters", s.getDeadLetters)
+
+	// Enforcement decisions for reverse proxies
+	api.POST("/enforce/check", s.checkEnforcement)
+
+	// Proxied connections
+	api.GET("/connections/slow", s.getSlowConnections)
+
+	// Dashboard stats
+	api.GET("/stats/summary", s.getSummaryStats)
+	api.GET("/stats/daily", s.getDailyStats)
+	api.GET("/stats/hourly", s.getHourlyStats)
+
+	// Detection scopes
+	api.GET("/environments", s.getEnvironments)
+	api.GET("/detectors", s.getDetectors)
+	api.GET("/rules", s.getRules)
+
+	// Threat intelligence
+	api.GET("/intel/feeds", s.getIntelFeeds)
+	api.GET("/intel/lookup", s.lookupIntel)
+
+	// Source geography
+	api.GET("/geoip", s.getGeoIPStatus)
+	api.GET("/geoip/lookup", s.lookupGeoIP)
+
+	// Source behavior profiles
+	api.GET("/profiles/:ip", s.getSourceProfile)
+
+	// High availability
+	api.GET("/cluster/status", s.getClusterStatus)
+
+	// Resilience testing
+	api.GET("/chaos", s.getChaosStatus)
+
+	// Runtime detection tuning
+	admin := api.Group("/admin", s.requireToken())
+	admin.GET("/thresholds", s.getThresholds)
+	admin.PUT("/thresholds", requireRole(auth.RoleAdmin), s.putThresholds)
+	admin.GET("/thresholds/shadow", s.getShadowThresholds)
+	admin.PUT("/thresholds/shadow", requireRole(auth.RoleAdmin), s.putShadowThresholds)
+	admin.DELETE("/thresholds/shadow", requireRole(auth.RoleAdmin), s.deleteShadowThresholds)
+
+	// Trusted and known-bad sources
+	admin.GET("/iplists/:list", s.getIPList)
+	admin.POST("/iplists/:list", requireRole(auth.RoleAdmin), s.addIPListEntries)
+	admin.POST("/iplists/:list/import", requireRole(auth.RoleAdmin), s.importIPList)
+	admin.DELETE("/iplists/:list", requireRole(auth.RoleAdmin), s.deleteIPListEntry)
+
+	// Automatic mitigation policy
+	admin.GET("/mitigation-policy", s.getMitigationPolicy)
+	admin.PUT("/mitigation-policy/kill-switch", requireRole(auth.RoleAdmin), s.putMitigationKillSwitch)
+
+	// Notification routing and escalation
+	admin.GET("/notification-routes", s.getNotificationRoutes)
+	admin.PUT("/notification-routes", requireRole(auth.RoleAdmin), s.putNotificationRoutes)
+
+	// Admin runbook actions
+	actions := api.Group("/actions", s.requireToken())
+	actions.GET("", s.listActions)
+	actions.GET("/audit", requireRole(auth.RoleOperator), s.getActionAudit)
+	actions.POST("/:name", s.runAction)
 }
 
 // ingestTraffic receives and processes incoming traffic data
 func (s *Server) ingestTraffic(c *gin.Context) {
 	var req models.TrafficRequest
 
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBodyBytes)
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.rejectIngest(c, http.StatusRequestEntityTooLarge, rejectPayloadTooLarge, fmt.Sprintf("request body is larger than %d bytes", maxIngestBodyBytes), nil)
+			return
+		}
+		s.rejectIngest(c, http.StatusBadRequest, rejectMalformedPayload, err.Error(), nil)
+		return
+	}
+
+	now := time.Now()
+	ingestion.Sanitize(&req, now)
+	if err := s.ingestValidator.Validate(req, now); err != nil {
+		var invalid *ingestion.ValidationError
+		errors.As(err, &invalid)
+		s.rejectIngest(c, http.StatusBadRequest, rejectInvalidTraffic, "invalid traffic", invalid.Fields)
 		return
 	}
+	ingestion.FingerprintHeaders(&req)
 
-	// Store in Redis
-	if err := s.redis.StoreTraffic(req); err != nil {
+	if err := s.sensor("http").storeChecked(req, now); err != nil {
 		log.Printf("Error storing traffic: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store traffic"})
 		return
@@ -101,6 +436,123 @@ func (s *Server) ingestTraffic(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// rejectIngest refuses ingested traffic with a structured error and counts
+// the rejection
+func (s *Server) rejectIngest(c *gin.Context, status int, reason, message string, fields []ingestion.FieldError) {
+	s.rejections.add(reason, c.FullPath(), time.Now())
+	response := gin.H{"error": message, "reason": reason}
+	if fields != nil {
+		response["fields"] = fields
+	}
+	c.JSON(status, response)
+}
+
+// ingestCloudflareLogs is a Cloudflare Logpush HTTP destination. Logpush
+// POSTs gzipped NDJSON batches of http_requests records. It is only
+// served with -cloudflare-logpush-secret set, since the secret is all that
+// authenticates Logpush.
+func (s *Server) ingestCloudflareLogs(c *gin.Context) {
+	if s.logpushSecret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Logpush ingestion is disabled; set -cloudflare-logpush-secret"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Logpush-Secret")), []byte(s.logpushSecret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid logpush secret"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxLogpushBodyBytes)
+	count, err := ingestion.ReadCloudflareLogs(c.Request.Body, s.logpushEnvironment, s.sensor("cloudflare_logpush"), s.redis)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		s.rejectIngest(c, http.StatusRequestEntityTooLarge, rejectPayloadTooLarge, fmt.Sprintf("request body is larger than %d bytes", maxLogpushBodyBytes), nil)
+		return
+	case errors.Is(err, ingestion.ErrLogpushBatchTooLarge):
+		s.rejectIngest(c, http.StatusRequestEntityTooLarge, rejectPayloadTooLarge, fmt.Sprintf("batch is larger than %d bytes decompressed", ingestion.MaxLogpushBatchBytes), nil)
+		return
+	case errors.Is(err, ingestion.ErrDuplicateLogpushBatch):
+		// Acknowledged, so Logpush stops retrying it
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "ingested": 0, "duplicate": true})
+		return
+	}
+	if err != nil {
+		log.Printf("Error ingesting Cloudflare logs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to ingest logs", "ingested": count})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "ingested": count})
+}
+
+// StoreTraffic takes one ingested request. With an in-process window it
+// feeds the window and only the Redis counters; otherwise the request is
+// stored in Redis for the analysis leader to fetch. Addresses are put in
+// canonical form first and sources from bogon space tagged with their
+// range, sources the threat intelligence feeds list with their reputation,
+// and sources the producer didn't locate with their country and AS.
+func (s *Server) StoreTraffic(req models.TrafficRequest) error {
+	ingestion.CanonicalAddresses(&req)
+	req.SourceBogon = bogon.ClassifyIP(req.SourceIP)
+	if s.intel != nil {
+		if rep, ok := s.intel.Lookup(req.SourceIP); ok {
+			req.Reputation, req.ThreatFeeds = rep.Score, rep.Feeds
+		}
+	}
+	if s.geoip != nil && (req.Country == "" || req.ASN == 0) {
+		if loc, ok := s.geoip.Lookup(req.SourceIP); ok {
+			if req.Country == "" {
+				req.Country = loc.Country
+			}
+			if req.ASN == 0 {
+				req.ASN, req.ASOrg = loc.ASN, loc.ASOrg
+			}
+		}
+	}
+
+	if s.stream == nil {
+		return s.redis.StoreTraffic(req)
+	}
+
+	// Trusted sources still count toward the traffic shown, but never
+	// toward an attack
+	if !s.detectors.Trusted(req.SourceIP) {
+		s.stream.Add(req)
+	}
+	return s.redis.CountTraffic(req)
+}
+
+// getIngestStatus reports the state of the optional ingest bus consumers
+func (s *Server) getIngestStatus(c *gin.Context) {
+	status := gin.H{
+		"detection": s.load.Status(),
+	}
+
+	if s.stream != nil {
+		status["stream"] = gin.H{"window_requests": s.stream.Requests()}
+	}
+
+	if s.kafka != nil {
+		lag, err := s.kafka.Lag(c.Request.Context())
+		if err != nil {
+			status["kafka"] = gin.H{"error": err.Error()}
+		} else {
+			status["kafka"] = gin.H{"lag": lag}
+		}
+	}
+
+	if s.nats != nil {
+		pending, err := s.nats.Pending(c.Request.Context())
+		if err != nil {
+			status["nats"] = gin.H{"error": err.Error()}
+		} else {
+			status["nats"] = gin.H{"pending": pending}
+		}
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
 // getCurrentMetrics returns current traffic metrics
 func (s *Server) getCurrentMetrics(c *gin.Context) {
 	metrics, err := s.redis.GetMetrics(time.Now())
@@ -142,21 +594,127 @@ func (s *Server) getActiveAttacks(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"attacks": attacks,
+		"attacks": inEnvironment(attacks, c.Query("environment")),
+	})
+}
+
+// getMetricsForecast returns an environment's predicted request rate and
+// anomaly band for the coming minutes
+func (s *Server) getMetricsForecast(c *gin.Context) {
+	env := c.DefaultQuery("environment", detection.DefaultEnvironment)
+	minutes := 60
+	if v := c.Query("minutes"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 24*60 {
+			minutes = n
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"environment": env,
+		"forecast":    s.detectors.Forecast(env, minutes),
+	})
+}
+
+// getEnvironments lists the environments that currently have their own detector
+func (s *Server) getEnvironments(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"environments": s.detectors.Environments(),
+	})
+}
+
+// getDetectors lists the registered detectors and whether each is enabled
+func (s *Server) getDetectors(c *gin.Context) {
+	detectors := make([]gin.H, 0)
+	for _, name := range detection.DetectorNames() {
+		detectors = append(detectors, gin.H{"name": name, "enabled": !s.detectors.Disabled(name)})
+	}
+	c.JSON(http.StatusOK, gin.H{"detectors": detectors})
+}
+
+// getRules lists the detection rules in force and whether the rules file
+// last reloaded cleanly
+func (s *Server) getRules(c *gin.Context) {
+	if s.rules == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no rules file is configured"})
+		return
+	}
+
+	response := gin.H{
+		"path":    s.rules.Path(),
+		"rules":   s.rules.Rules(),
+		"enabled": !s.detectors.Disabled(detection.RulesPlugin),
+	}
+	if err := s.rules.Err(); err != nil {
+		response["reload_error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getClusterStatus reports which node currently leads analysis
+func (s *Server) getClusterStatus(c *gin.Context) {
+	leader, err := s.redis.CurrentLeader()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node_id":   s.nodeID,
+		"is_leader": s.isLeader.Load(),
+		"leader":    leader,
+	})
+}
+
+// getChaosStatus reports the injected failure settings and counts
+func (s *Server) getChaosStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": faults != nil,
+		"config":  faults.Config(),
+		"stats":   faults.Stats(),
 	})
 }
 
-// getAttackHistory returns attack history
+// getAttackHistory returns ended attacks, most recent first
 func (s *Server) getAttackHistory(c *gin.Context) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	attacks, err := s.redis.GetAttackHistory(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"attacks": []models.Attack{},
+		"attacks": inEnvironment(attacks, c.Query("environment")),
 	})
 }
 
-// getSummaryStats returns dashboard summary statistics
+// inEnvironment keeps the attacks of one environment; an empty env keeps all
+func inEnvironment(attacks []models.Attack, env string) []models.Attack {
+	if env == "" {
+		return attacks
+	}
+
+	filtered := make([]models.Attack, 0, len(attacks))
+	for _, attack := range attacks {
+		if attack.Environment == env {
+			filtered = append(filtered, attack)
+		}
+	}
+	return filtered
+}
+
+// getSummaryStats returns dashboard summary statistics. ?environment=
+// counts only that environment's active attacks.
 func (s *Server) getSummaryStats(c *gin.Context) {
 	currentMetrics, _ := s.redis.GetMetrics(time.Now())
 	activeAttacks, _ := s.redis.GetActiveAttacks()
+	activeAttacks = inEnvironment(activeAttacks, c.Query("environment"))
 
 	status := "NORMAL"
 	if len(activeAttacks) > 0 {
@@ -185,12 +743,23 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
+	log.Println("New WebSocket client connected")
+	serveWebSocket(conn)
+}
+
+// serveWebSocket registers a dashboard session for broadcasts until the
+// client goes away
+func serveWebSocket(conn *websocket.Conn) {
 	defer conn.Close()
 
+	wsMu.Lock()
 	wsClients[conn] = true
-	defer delete(wsClients, conn)
-
-	log.Println("New WebSocket client connected")
+	wsMu.Unlock()
+	defer func() {
+		wsMu.Lock()
+		delete(wsClients, conn)
+		wsMu.Unlock()
+	}()
 
 	// Keep connection alive
 	for {
@@ -202,9 +771,25 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	}
 }
 
+// hasWebSocketClients reports whether any dashboard is connected
+func hasWebSocketClients() bool {
+	wsMu.Lock()
+	defer wsMu.Unlock()
+
+	return len(wsClients) > 0
+}
+
 // broadcastMessage sends a message to all connected WebSocket clients
 func broadcastMessage(message interface{}) {
+	wsMu.Lock()
+	defer wsMu.Unlock()
+
 	for client := range wsClients {
+		if faults.DropWSFrame() {
+			continue
+		}
+
+		client.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
 		err := client.WriteJSON(message)
 		if err != nil {
 			log.Printf("WebSocket write error: %v", err)
@@ -214,100 +799,1491 @@ func broadcastMessage(message interface{}) {
 	}
 }
 
-// startAnalysisEngine runs periodic traffic analysis
-func (s *Server) startAnalysisEngine() {
-	ticker := time.NewTicker(5 * time.Second)
+// startAnalysisEngine runs periodic traffic analysis until ctx is done
+func (s *Server) startAnalysisEngine(ctx context.Context) {
+	ticker := time.NewTicker(s.analysisInterval)
 	defer ticker.Stop()
 
 	log.Println("🔍 Analysis engine started")
 
-	for range ticker.C {
-		// Get recent traffic
-		requests, err := s.redis.GetRecentTraffic(60)
-		if err != nil {
-			log.Printf("Error getting recent traffic: %v", err)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !s.ensureLeadership() {
 			continue
 		}
 
+		if _, err := s.runAnalysisCycle(); err != nil {
+			log.Printf("Error running analysis: %v", err)
+		}
+	}
+}
+
+// stepDown hands the attacks in progress over before the process exits: it
+// waits out a running analysis cycle, replicates the detection state and
+// releases the leader lease, so a standby (or this node once restarted)
+// resumes them under the same IDs instead of raising them as new attacks
+func (s *Server) stepDown() {
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	if !s.isLeader.Swap(false) {
+		return
+	}
+
+	s.replicateState()
+	if err := s.redis.ReleaseLeadership(s.nodeID); err != nil {
+		log.Printf("Error releasing leader lease: %v", err)
+		return
+	}
+	log.Printf("%s handed over analysis leadership", s.nodeID)
+}
+
+// runAnalysisCycle analyzes recent traffic over every resolution once,
+// returning how many attacks were detected. Cycles never overlap.
+func (s *Server) runAnalysisCycle() (int, error) {
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	cycleStart := time.Now()
+
+	// Close attacks that have subsided, even when no traffic arrives at all
+	s.closeEndedAttacks(cycleStart)
+	s.closeShadowAttacks(cycleStart)
+	s.syncKillSwitch()
+	s.syncSilences()
+	s.syncRouting()
+	s.escalateNotifications(cycleStart)
+	s.expireMitigations()
+
+	// Analyze for attacks, each environment against its own detector,
+	// and total the traffic received since the last cycle
+	now := time.Now()
+	elapsed := s.analysisInterval
+	if !s.lastCycleAt.IsZero() && now.Sub(s.lastCycleAt) < detection.AnalysisWindow {
+		elapsed = now.Sub(s.lastCycleAt)
+	}
+
+	// Shadow thresholds analyze the same traffic first, from the
+	// baselines production starts the cycle with
+	var attacks, shadow []models.Attack
+	var received map[string]models.AttackTraffic
+	if s.stream != nil {
+		snapshot := s.stream.Snapshot(now)
+		shadow = s.detectors.ShadowSnapshot(snapshot)
+		attacks = s.detectors.AnalyzeSnapshot(snapshot)
+		received = s.stream.TrafficSince(now.Add(-elapsed))
+	} else {
+		// Under load only a sample is read, so the cycle stays in budget
+		// however much traffic the window holds
+		requests, weight, err := s.recentTraffic(int(detection.LongestWindow.Seconds()))
+		if err != nil {
+			return 0, fmt.Errorf("getting recent traffic: %w", err)
+		}
 		if len(requests) == 0 {
+			return 0, nil
+		}
+
+		shadow = s.detectors.ShadowSample(requests, weight)
+		attacks = s.detectors.AnalyzeSample(requests, weight)
+		received = trafficSince(requests, weight, now.Add(-elapsed))
+	}
+	s.lastCycleAt = now
+
+	// Learn what normal looks like from the environments found quiet
+	if err := s.detectors.LearnBaselines(attacks); err != nil {
+		log.Printf("Error learning baselines: %v", err)
+	}
+
+	// Share the excess traffic since the last cycle between each
+	// environment's attacks
+	excess := s.excessTraffic(received, elapsed)
+	attacksPerEnv := make(map[string]int64)
+	for _, attack := range attacks {
+		attacksPerEnv[attack.Environment]++
+	}
+
+	// Process detected attacks
+	for _, detected := range attacks {
+		attack, outcome := s.pulses.Observe(detected, now)
+
+		share := excess[attack.Environment]
+		share.Requests /= attacksPerEnv[attack.Environment]
+		share.Bytes /= attacksPerEnv[attack.Environment]
+		attack = s.accountAttackCost(attack, share, now)
+		// Show rate anomalies against the same time yesterday and last week
+		attack = s.compareRate(attack, now)
+		// Load tests and maintenance are still recorded, just not acted on
+		wasSilenced := attack.SilencedBy != ""
+		attack = s.applySilences(attack, now)
+		s.pulses.Update(attack)
+
+		if outcome != detection.PulseNew {
+			s.updateRecurringAttack(attack, outcome, wasSilenced)
 			continue
 		}
 
-		// Analyze for attacks
-		attacks := s.detector.AnalyzeTraffic(requests)
+		log.Printf("⚠️  Attack detected: %s in %s (Confidence: %.2f)", attack.Type, attack.Environment, attack.Confidence)
 
-		// Process detected attacks
-		for _, attack := range attacks {
-			log.Printf("⚠️  Attack detected: %s (Confidence: %.2f)", attack.Type, attack.Confidence)
+		// Store attack
+		if err := s.redis.StoreAttack(attack); err != nil {
+			log.Printf("Error storing attack: %v", err)
+		}
+		s.recordTimeline(attack.ID, models.TimelineEvent{
+			Timestamp: time.Now(),
+			Type:      "DETECTED",
+			Message:   attack.Description,
+			Actor:     "detector",
+		})
 
-			// Store attack
-			if err := s.redis.StoreAttack(attack); err != nil {
-				log.Printf("Error storing attack: %v", err)
+		// Absorb application-layer floods by adding capacity
+		if s.autoscaler != nil {
+			event, err := s.autoscaler.HandleAttack(context.Background(), attack)
+			if err != nil {
+				log.Printf("Error autoscaling for attack %s: %v", attack.ID, err)
 			}
-
-			// Create alert
-			alert := models.Alert{
-				ID:         attack.ID,
-				Level:      "CRITICAL",
-				Title:      fmt.Sprintf("%s Attack Detected", attack.Type),
-				Message:    attack.Description,
-				AttackType: attack.Type,
-				Timestamp:  time.Now(),
+			if event != nil {
+				log.Printf("📈 %s", event.Message)
+				s.recordTimeline(attack.ID, *event)
 			}
+		}
+
+		attack = s.mitigateAttack(attack)
+		if attack.SilencedBy == "" {
+			s.raiseAttackAlert(attack, now)
+		}
+	}
+
+	s.recordShadowAttacks(shadow, attacks, now)
+
+	// Replicate detection state so a standby can resume from here
+	s.replicateState()
+
+	s.adjustDetectionMode(time.Since(cycleStart))
 
-			// Publish alert
-			s.redis.PublishAlert(alert)
+	return len(attacks), nil
+}
+
+// startMetricsBroadcast pushes the current minute's metrics to dashboard
+// clients every interval until ctx is done. The metrics come from the
+// per-minute aggregates ingest maintains, so pushing them is cheap and
+// independent of the analysis cycle; every node pushes to its own clients.
+func (s *Server) startMetricsBroadcast(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-			// Broadcast to WebSocket clients
-			broadcastMessage(map[string]interface{}{
-				"type":    "alert",
-				"payload": alert,
-			})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 
-		// Get current metrics
+		if !hasWebSocketClients() {
+			continue
+		}
 		metrics, err := s.redis.GetMetrics(time.Now())
-		if err == nil {
-			// Broadcast metrics to WebSocket clients
-			broadcastMessage(map[string]interface{}{
-				"type":    "metrics",
-				"payload": metrics,
-			})
+		if err != nil {
+			continue
 		}
+		broadcastMessage(map[string]interface{}{
+			"type":    "metrics",
+			"payload": metrics,
+		})
 	}
 }
 
-// corsMiddleware handles CORS
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+// updateRecurringAttack refreshes the record of an attack seen in an earlier
+// cycle. Only a new burst or a rise in severity is worth a timeline entry,
+// and only those and the burst that reveals a pulse wave raise an alert.
+func (s *Server) updateRecurringAttack(attack models.Attack, outcome detection.PulseOutcome, wasSilenced bool) {
+	if err := s.redis.StoreAttack(attack); err != nil {
+		log.Printf("Error storing attack: %v", err)
+	}
+	attack = s.mitigateAttack(attack)
+	switch {
+	case attack.SilencedBy != "":
+	case wasSilenced:
+		// Alerts as new once its silence expires
+		s.raiseAttackAlert(attack, time.Now())
+	case s.alerts.Escalate(attack, time.Now()):
+		s.alertEscalation(attack)
+	}
+	if outcome != detection.PulseBurst {
+		return
+	}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	log.Printf("〰️  %s in %s pulsed again: burst %d, period %.0fs", attack.Type, attack.Environment, attack.Pulse.Bursts, attack.Pulse.PeriodSeconds)
+	s.recordTimeline(attack.ID, models.TimelineEvent{
+		Timestamp: time.Now(),
+		Type:      "BURST",
+		Message:   fmt.Sprintf("Burst %d after %.0fs", attack.Pulse.Bursts, attack.Pulse.PeriodSeconds),
+		Actor:     "detector",
+	})
 
-		c.Next()
+	if attack.Pulse.Bursts == 2 && attack.SilencedBy == "" {
+		s.PublishAlert(models.Alert{
+			ID:          attack.ID,
+			Level:       "CRITICAL",
+			Title:       fmt.Sprintf("Pulse-Wave %s Campaign Detected", attack.Type),
+			Message:     attack.Description,
+			AttackType:  attack.Type,
+			Environment: attack.Environment,
+			Timestamp:   time.Now(),
+		})
+		s.notifyAttack(attack)
 	}
 }
 
-func main() {
-	log.Println("🚀 Starting DDoS Detection Dashboard Server...")
+// raiseAttackAlert alerts a new attack, unless the same attack alerted
+// within the cooldown
+func (s *Server) raiseAttackAlert(attack models.Attack, now time.Time) {
+	if outcome, last := s.alerts.Raise(attack, now); outcome == detection.AlertSuppressed {
+		log.Printf("🔕 Not alerting %s in %s again: alerted %s ago", attack.Type, attack.Environment, now.Sub(last).Round(time.Second))
+		return
+	}
 
-	server, err := NewServer()
-	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+	s.PublishAlert(models.Alert{
+		ID:          attack.ID,
+		Level:       "CRITICAL",
+		Title:       fmt.Sprintf("%s Attack Detected", attack.Type),
+		Message:     attack.Description,
+		AttackType:  attack.Type,
+		Environment: attack.Environment,
+		Timestamp:   time.Now(),
+	})
+	s.notifyAttack(attack)
+}
+
+// alertEscalation alerts an ongoing attack again as it grows more severe
+func (s *Server) alertEscalation(attack models.Attack) {
+	log.Printf("📈 %s in %s escalated to %s", attack.Type, attack.Environment, attack.Severity)
+	s.recordTimeline(attack.ID, models.TimelineEvent{
+		Timestamp: time.Now(),
+		Type:      "ESCALATED",
+		Message:   fmt.Sprintf("Severity rose to %s", attack.Severity),
+		Actor:     "detector",
+	})
+
+	s.PublishAlert(models.Alert{
+		ID:          attack.ID,
+		Level:       "CRITICAL",
+		Title:       fmt.Sprintf("%s Attack Escalated to %s", attack.Type, attack.Severity),
+		Message:     attack.Description,
+		AttackType:  attack.Type,
+		Environment: attack.Environment,
+		Timestamp:   time.Now(),
+	})
+	s.sendNotification(notify.AttackNotification(notify.EventAttackEscalated, attack))
+}
+
+// adjustDetectionMode switches detectors to approximate mode when analysis
+// runs over budget or the ingest backlog nears capacity, and back when calm
+func (s *Server) adjustDetectionMode(cycle time.Duration) {
+	mode, changed := s.load.Observe(cycle, s.ingestBacklog())
+	if !changed {
+		return
 	}
 
-	// Start analysis engine in background
-	go server.startAnalysisEngine()
+	s.detectors.SetMode(mode)
+	status := s.load.Status()
+	log.Printf("⚙️  Detection mode now %s (cycle %dms of %dms, backlog %.0f%%)", mode, status.LastCycleMs, status.CycleBudgetMs, status.Backlog*100)
+
+	broadcastMessage(map[string]interface{}{
+		"type":    "detection_mode",
+		"payload": status,
+	})
+}
+
+// recentTraffic reads the traffic of the last seconds: all of it in full
+// mode, a sample of at most ApproximateReadSize in approximate mode. It
+// returns the requests and how many each stands for.
+func (s *Server) recentTraffic(seconds int) ([]models.TrafficRequest, int, error) {
+	if s.detectors.Mode() == detection.ModeApproximate {
+		return s.redis.GetRecentTrafficSample(seconds, detection.ApproximateReadSize)
+	}
+
+	requests, err := s.redis.GetRecentTraffic(seconds)
+	return requests, 1, err
+}
+
+// ingestBacklog returns the bus consumers' backlog as a fraction of capacity
+func (s *Server) ingestBacklog() float64 {
+	if s.backlogCapacity <= 0 {
+		return 0
+	}
+
+	var backlog int64
+	if s.kafka != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		lag, err := s.kafka.Lag(ctx)
+		cancel()
+		if err == nil {
+			backlog += lag
+		}
+	}
+	if s.nats != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		pending, err := s.nats.Pending(ctx)
+		cancel()
+		if err == nil {
+			backlog += int64(pending)
+		}
+	}
+
+	return float64(backlog) / float64(s.backlogCapacity)
+}
+
+// recordTimeline appends an event to an attack's incident timeline
+func (s *Server) recordTimeline(attackID string, event models.TimelineEvent) {
+	if err := s.redis.AppendTimelineEvent(attackID, event); err != nil {
+		log.Printf("Error recording timeline event for %s: %v", attackID, err)
+	}
+}
+
+// PublishAlert stores an alert, publishes it to Redis subscribers and
+// broadcasts it to dashboard clients. An alert published again under the
+// same ID, e.g. as its attack escalates, opens again.
+func (s *Server) PublishAlert(alert models.Alert) error {
+	if alert.Status == "" {
+		alert.Status = models.AlertOpen
+	}
+	if err := s.redis.StoreAlert(alert); err != nil {
+		log.Printf("Error storing alert %s: %v", alert.ID, err)
+	}
+
+	err := faults.NotifierFault(context.Background())
+	if err == nil {
+		err = s.redis.PublishAlert(alert)
+	}
+
+	broadcastMessage(map[string]interface{}{
+		"type":    "alert",
+		"payload": alert,
+	})
+
+	return err
+}
+
+// ensureLeadership renews this node's analysis lease. A node that has just
+// become leader restores the replicated detection state before analysing.
+func (s *Server) ensureLeadership() bool {
+	leader, err := s.redis.AcquireLeadership(s.nodeID, leaderLeaseTTL)
+	if err != nil {
+		log.Printf("Error renewing leader lease: %v", err)
+		leader = false
+	}
+
+	wasLeader := s.isLeader.Swap(leader)
+	switch {
+	case leader && !wasLeader:
+		log.Printf("👑 %s is now the analysis leader", s.nodeID)
+		s.restoreState()
+	case !leader && wasLeader:
+		log.Printf("%s lost analysis leadership, standing by", s.nodeID)
+	}
+
+	return leader
+}
+
+// checkSchema warns when stored data predates this release's layout. Data
+// that needs no migration (e.g. a fresh deployment) is stamped as current.
+func (s *Server) checkSchema() {
+	version, err := s.redis.SchemaVersion()
+	if err != nil {
+		log.Printf("Error reading schema version: %v", err)
+		return
+	}
+	if version >= storage.LatestSchemaVersion() {
+		return
+	}
+
+	pending, err := s.redis.Migrate(true, nil)
+	if err != nil {
+		log.Printf("Error checking pending migrations: %v", err)
+		return
+	}
+	for _, result := range pending {
+		if result.Changed > 0 {
+			log.Printf("⚠️  Stored data is at schema version %d, this release writes %d; run `ddosctl migrate` (migration %s would change %d entries)",
+				version, storage.LatestSchemaVersion(), result.Name, result.Changed)
+			return
+		}
+	}
+
+	if err := s.redis.SetSchemaVersion(storage.LatestSchemaVersion()); err != nil {
+		log.Printf("Error recording schema version: %v", err)
+	}
+}
+
+// leaderState is everything the analysis leader holds in memory: the
+// detectors and the attacks they track, and the server's own state around
+// them, so the next leader carries on where this one stopped
+type leaderState struct {
+	detection.PoolState
+	// Attacks the shadow thresholds are tracking, and those of them
+	// production detected too
+	ShadowCampaigns    []detection.CampaignState `json:"shadow_campaigns,omitempty"`
+	ShadowInProduction []string                  `json:"shadow_in_production,omitempty"`
+	// Detection mode and its recovery progress
+	Load *detection.LoadState `json:"load,omitempty"`
+	// Capacity raised to absorb attacks, restored when they end
+	Autoscale *mitigation.AutoscaleState `json:"autoscale,omitempty"`
+	// When traffic was last totalled for attack costs
+	LastCycleAt time.Time `json:"last_cycle_at,omitempty"`
+}
+
+// restoreState loads the detection state replicated by the previous leader,
+// resuming the attacks it was tracking
+func (s *Server) restoreState() {
+	var state leaderState
+	found, err := s.redis.LoadDetectionState(&state)
+	if err != nil {
+		log.Printf("Error loading replicated detection state: %v", err)
+		return
+	}
+	if !found {
+		return
+	}
+
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	now := time.Now()
+	// Thresholds changed at runtime were replicated; the thresholds files
+	// only seed a deployment that has no state yet
+	s.detectors.Restore(state.PoolState)
+	s.pulses.Restore(state.Campaigns, now)
+	s.alerts.Restore(state.Alerts)
+	s.shadowPulses.Restore(state.ShadowCampaigns, now)
+	s.shadowInProduction = make(map[string]bool, len(state.ShadowInProduction))
+	for _, id := range state.ShadowInProduction {
+		s.shadowInProduction[id] = true
+	}
+	if state.Load != nil {
+		s.load.Restore(*state.Load)
+	}
+	if s.autoscaler != nil && state.Autoscale != nil {
+		s.autoscaler.Restore(*state.Autoscale)
+	}
+	s.lastCycleAt = state.LastCycleAt
+	log.Printf("Resumed detection state saved by %s at %s with %d tracked attacks", state.SavedBy, state.SavedAt.Format(time.RFC3339), len(state.Campaigns))
+}
+
+// replicateState writes the leader's state to Redis. Callers hold analysisMu.
+func (s *Server) replicateState() {
+	state := leaderState{PoolState: s.detectors.Snapshot()}
+	state.SavedBy = s.nodeID
+	state.Campaigns = s.pulses.Snapshot()
+	state.Alerts = s.alerts.Snapshot()
+	state.ShadowCampaigns = s.shadowPulses.Snapshot()
+	for id := range s.shadowInProduction {
+		state.ShadowInProduction = append(state.ShadowInProduction, id)
+	}
+	load := s.load.Snapshot()
+	state.Load = &load
+	if s.autoscaler != nil {
+		autoscale := s.autoscaler.Snapshot()
+		state.Autoscale = &autoscale
+	}
+	state.LastCycleAt = s.lastCycleAt
+
+	err := s.redis.SaveDetectionState(s.nodeID, state)
+	switch {
+	case errors.Is(err, storage.ErrNotLeader):
+		// Stalled past the lease: a standby has taken over, whose state stands
+		if s.isLeader.Swap(false) {
+			log.Printf("%s lost analysis leadership, standing by", s.nodeID)
+		}
+	case err != nil:
+		log.Printf("Error replicating detection state: %v", err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// corsMiddleware handles CORS for the allowed origins; * allows any
+func corsMiddleware(origins []string) gin.HandlerFunc {
+	anyOrigin := slices.Contains(origins, "*")
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		switch {
+		case anyOrigin:
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && slices.Contains(origins, origin):
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		os.Exit(runAgent(os.Args[2:]))
+	}
+
+	configFile := flag.String("config", "", "YAML config file with storage, detection, notification, tls and other sections; command-line flags and DDOS_* environment variables override it")
+	addr := flag.String("addr", ":8888", "address the HTTP server listens on")
+	analysisEvery := flag.Duration("analysis-interval", defaultAnalysisInterval, "how often traffic is analyzed for attacks, and so each cycle's time budget")
+	corsOrigins := flag.String("cors-origins", "*", "origins browsers may call the API from, comma-separated; * allows any, without credentials")
+	tlsCert := flag.String("tls-cert", "", "serve HTTPS with this PEM certificate (chain)")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "verify client certificates against these PEM CA certificates (mTLS)")
+	tlsClientAuth := flag.String("tls-client-auth", clientAuthIngest, "with -tls-client-ca: ingest requires a certificate from agents shipping traffic, require one on every connection, optional none")
+	apiTokens := flag.String("api-tokens", "", "bearer tokens for admin endpoints as name:role:token,... (roles: viewer, analyst, operator, admin)")
+	usersFile := flag.String("users-file", "", "YAML users file (name, role, password_hash) enabling login at /api/auth/login")
+	sessionSecret := flag.String("session-secret", "", "secret of at least 32 characters signing login sessions, shared by every node; set it through DDOS_SESSION_SECRET")
+	sessionTTL := flag.Duration("session-ttl", 12*time.Hour, "how long a login session lasts")
+	rateLimitIP := flag.Int("rate-limit-ip", 0, "API requests per minute let through from each client address, ingest included; 0 for no limit")
+	rateLimitIPBurst := flag.Int("rate-limit-ip-burst", 100, "API requests a client address may send at once before it is held to -rate-limit-ip")
+	rateLimitKey := flag.Int("rate-limit-key", 0, "API requests per minute let through for each API token or logged in user; 0 for no limit")
+	rateLimitKeyBurst := flag.Int("rate-limit-key-burst", 100, "API requests a token or user may send at once before it is held to -rate-limit-key")
+	rateLimitExempt := flag.String("rate-limit-exempt", "", "client addresses and CIDRs not held to -rate-limit-ip, such as agents and reverse proxies, comma-separated")
+	trustedProxies := flag.String("trusted-proxies", "", "load balancers whose X-Forwarded-For names the client, as addresses and CIDRs, comma-separated; without, the connection's address is the client")
+	authRequired := flag.Bool("auth-required", true, "require a viewer token or session to read the API and WebSocket, not only to change things")
+	mock := flag.Bool("mock", false, "serve deterministic fixture data for front-end development; no Redis, ingestion or analysis")
+	mockSeed := flag.Int64("mock-seed", 42, "seed for the mock fixtures")
+	redisAddr := flag.String("redis-addr", defaultRedisAddr, "Redis address; empty runs the embedded store in bundle builds")
+	redisKeyPrefix := flag.String("redis-key-prefix", "", "namespace for all Redis keys and channels, e.g. ddos:prod:, to share one Redis between deployments")
+	awsRegion := flag.String("aws-region", "", "AWS region for cloud log ingestion, autoscaling and AWS WAF mitigation")
+	vpcFlowS3 := flag.String("vpc-flow-s3", "", "ingest VPC Flow Logs delivered to s3://bucket/prefix")
+	vpcFlowLogGroup := flag.String("vpc-flow-log-group", "", "ingest VPC Flow Logs from this CloudWatch Logs group")
+	vpcFlowEnv := flag.String("vpc-flow-env", "", "environment tag for ingested VPC Flow Logs")
+	vpcFlowLookback := flag.Duration("vpc-flow-lookback", 0, "read VPC Flow Logs delivered this long before startup; 0 starts at startup")
+	logpushSecret := flag.String("cloudflare-logpush-secret", "", "shared secret Logpush must send in the X-Logpush-Secret header; /api/v1/ingest/cloudflare is disabled without one")
+	logpushBucket := flag.String("cloudflare-logpush-bucket", "", "pull Cloudflare Logpush batches from s3://bucket/prefix (S3 or R2)")
+	logpushEndpoint := flag.String("cloudflare-logpush-endpoint", "", "S3-compatible endpoint for the Logpush bucket, e.g. https://<account>.r2.cloudflarestorage.com")
+	logpushEnv := flag.String("cloudflare-logpush-env", "", "environment tag for ingested Cloudflare logs")
+	logpushLookback := flag.Duration("cloudflare-logpush-lookback", 0, "pull Logpush batches delivered this long before startup; 0 starts at startup")
+	kafkaBrokers := flag.String("kafka-brokers", "", "comma-separated Kafka brokers to consume traffic from")
+	kafkaTopic := flag.String("kafka-topic", "traffic", "Kafka topic carrying TrafficRequest messages")
+	kafkaGroup := flag.String("kafka-group", "ddos-detection", "Kafka consumer group ID")
+	kafkaFormat := flag.String("kafka-format", "json", "Kafka message format: json or protobuf")
+	natsURL := flag.String("nats-url", "", "NATS server URL to consume traffic from via JetStream")
+	natsStream := flag.String("nats-stream", "TRAFFIC", "JetStream stream carrying TrafficRequest messages")
+	natsSubject := flag.String("nats-subject", "", "optional subject filter within the stream")
+	natsConsumer := flag.String("nats-consumer", "ddos-detection", "durable JetStream consumer name")
+	natsFormat := flag.String("nats-format", "json", "NATS message format: json or protobuf")
+	syslogUDP := flag.String("syslog-udp", "", "listen for firewall/IDS syslog on this UDP address, e.g. :5514")
+	syslogTCP := flag.String("syslog-tcp", "", "listen for firewall/IDS syslog on this TCP address, e.g. :5514")
+	syslogEnv := flag.String("syslog-env", "", "environment tag for traffic parsed from syslog")
+	autoscaleHPA := flag.String("autoscale-k8s-hpa", "", "raise minReplicas of this HPA (namespace/name) while absorbing application-layer attacks")
+	autoscaleASG := flag.String("autoscale-asg", "", "raise desired capacity of this EC2 Auto Scaling group while absorbing application-layer attacks")
+	autoscaleCapacity := flag.Int("autoscale-capacity", 10, "capacity to scale the protected service to during an attack")
+	autoscaleConfidence := flag.Float64("autoscale-min-confidence", 0.7, "minimum attack confidence that triggers scaling")
+	envoyALS := flag.String("envoy-als", "", "serve Envoy's gRPC Access Log Service on this address, e.g. :18090")
+	envoyEnv := flag.String("envoy-env", "", "environment tag for Envoy access logs")
+	agentAccessLogs := flag.String("agent-access-log", "", "follow these nginx or Apache access logs on this host, comma-separated; \"agent\" runs the same on other hosts")
+	agentEnv := flag.String("agent-env", "", "environment tag for traffic from -agent-access-log")
+	agentDestIP := flag.String("agent-dest-ip", "", "address the web server of -agent-access-log answers on")
+	chaosSpec := flag.String("chaos", "", "inject failures for resilience testing, e.g. redis_latency=200ms,redis_error_rate=0.05,ws_drop_rate=0.2,notifier_timeout_rate=0.5")
+	repairDrift := flag.Bool("mitigation-repair-drift", true, "repair executor state that drifts from the mitigation records")
+	reconcileInterval := flag.Duration("mitigation-reconcile-interval", time.Minute, "how often executor state is audited against the mitigation records")
+	firewall := flag.String("mitigation-firewall", "", "enforce BLOCK actions on this host: nftables or iptables (with ipset)")
+	firewallName := flag.String("mitigation-firewall-name", "ddos_dashboard", "nftables table, or prefix of the ipsets, holding the blocked sources")
+	firewallMaxEntries := flag.Int("mitigation-firewall-max-entries", mitigation.DefaultFirewallMaxEntries, "most sources the host firewall blocks at once")
+	cloudflareToken := flag.String("mitigation-cloudflare-token", "", "Cloudflare API token with Firewall Services edit permission; enforces BLOCK and RATE_LIMIT actions as IP Access Rules")
+	cloudflareZone := flag.String("mitigation-cloudflare-zone", "", "Cloudflare zone ID the access rules are created in")
+	cloudflareAccount := flag.String("mitigation-cloudflare-account", "", "Cloudflare account ID the access rules are created in, covering all its zones, when no zone is given")
+	cloudflareConfidence := flag.Float64("mitigation-cloudflare-min-confidence", 0.9, "minimum attack confidence whose actions are pushed to Cloudflare")
+	wafIPv4Set := flag.String("mitigation-aws-waf-ipset", "", "AWS WAF IPv4 IP set, as name/id, holding the blocked sources")
+	wafIPv6Set := flag.String("mitigation-aws-waf-ipset-v6", "", "AWS WAF IPv6 IP set, as name/id, holding the blocked IPv6 sources")
+	wafScope := flag.String("mitigation-aws-waf-scope", "REGIONAL", "scope of the AWS WAF IP sets: REGIONAL, or CLOUDFRONT with -aws-region us-east-1")
+	wafFlush := flag.Duration("mitigation-aws-waf-flush-interval", 10*time.Second, "how often queued changes are written to the AWS WAF IP sets")
+	gobgpAPI := flag.String("mitigation-gobgp", "", "announce mitigations upstream through the gobgpd at this gRPC address, e.g. 127.0.0.1:50051")
+	gobgpFlowspec := flag.Bool("mitigation-gobgp-flowspec", true, "announce Flowspec rules discarding the sources of volumetric floods")
+	gobgpRTBH := flag.Bool("mitigation-gobgp-rtbh", false, "blackhole the victims of attacks with likely spoofed sources; takes the victim offline upstream")
+	gobgpCommunity := flag.String("mitigation-gobgp-community", "65535:666", "community attached to every announcement, marking the dashboard's routes")
+	gobgpNextHop := flag.String("mitigation-gobgp-nexthop", "192.0.2.1", "discard next hop of IPv4 blackhole routes")
+	gobgpNextHopV6 := flag.String("mitigation-gobgp-nexthop-v6", "100::1", "discard next hop of IPv6 blackhole routes")
+	banLog := flag.String("mitigation-banlog", "", "append a BAN or UNBAN line per blocked source to this file, for a fail2ban jail to act on")
+	banList := flag.String("mitigation-banlist", "", "keep the blocked sources and their expiry in this file, for scripts to act on")
+	banDuration := flag.Duration("mitigation-ban-duration", 0, "ban time written to the ban log and list; 0 bans until the action expires")
+	enforceRate := flag.Int("mitigation-rate-limit-per-minute", mitigation.DefaultEnforceRatePerMinute, "requests per minute /api/enforce/check lets through from each rate limited client")
+	enforceBurst := flag.Int("mitigation-rate-limit-burst", mitigation.DefaultEnforceBurst, "requests a rate limited client may send at once before it is held to the rate")
+	enforceMaxClients := flag.Int("mitigation-rate-limit-max-clients", mitigation.DefaultEnforceMaxClients, "most client token buckets /api/enforce/check keeps at once")
+	autoMitigate := flag.Bool("mitigation-auto", true, "turn detected attacks into BLOCK, RATE_LIMIT or MONITOR actions by policy")
+	blockConfidence := flag.Float64("mitigation-block-confidence", mitigation.DefaultPolicy().BlockConfidence, "minimum attack confidence that blocks the sources")
+	rateLimitConfidence := flag.Float64("mitigation-rate-limit-confidence", mitigation.DefaultPolicy().RateLimitConfidence, "minimum attack confidence that rate limits the sources; below it they are monitored")
+	mitigationDuration := flag.Duration("mitigation-duration", mitigation.DefaultPolicy().Duration, "how long an action stays in force after its attack was last detected")
+	policyFile := flag.String("mitigation-policy-file", "", "YAML or JSON mitigation policies, mapping attack types, severity and confidence onto actions, tried before the confidence thresholds")
+	maxBlocked := flag.Int("mitigation-max-blocked", 0, "most targets blocked or blackholed at once; automatic actions beyond it are only monitored (0 for no cap)")
+	mispURL := flag.String("misp-url", "", "share attacks as events on this MISP instance")
+	mispKey := flag.String("misp-key", "", "MISP API key")
+	mispSeverity := flag.String("misp-min-severity", "HIGH", "only share attacks at or above this severity with MISP")
+	mispDistribution := flag.Int("misp-distribution", 1, "MISP distribution: 0 organisation, 1 community, 2 connected communities, 3 all")
+	mispTags := flag.String("misp-tags", "tlp:amber", "comma-separated tags added to MISP events")
+	mispInsecure := flag.Bool("misp-insecure", false, "skip TLS verification for self-signed MISP instances")
+	pagerDutyKey := flag.String("pagerduty-routing-key", "", "open PagerDuty incidents (Events API v2) as attacks start, resolved as they end")
+	pagerDutyURL := flag.String("pagerduty-url", notify.DefaultPagerDutyURL, "PagerDuty Events API endpoint")
+	pagerDutySeverity := flag.String("pagerduty-min-severity", "HIGH", "only page PagerDuty for attacks at or above this severity")
+	opsgenieKey := flag.String("opsgenie-api-key", "", "open Opsgenie alerts as attacks start, closed as they end")
+	opsgenieURL := flag.String("opsgenie-url", notify.DefaultOpsgenieURL, "Opsgenie API, https://api.eu.opsgenie.com for EU accounts")
+	opsgenieTeam := flag.String("opsgenie-team", "", "Opsgenie team responding to the alerts, if not the integration's")
+	opsgenieSeverity := flag.String("opsgenie-min-severity", "HIGH", "only alert Opsgenie of attacks at or above this severity")
+	alertmanagerURL := flag.String("alertmanager-url", "", "comma-separated Alertmanagers, e.g. http://alertmanager:9093, sent attacks as alerts through the v2 API")
+	alertmanagerLabels := flag.String("alertmanager-labels", "", "labels added to every Alertmanager alert, e.g. cluster=eu1,team=netops")
+	alertmanagerSeverity := flag.String("alertmanager-min-severity", "", "only send Alertmanager attacks at or above this severity (default all)")
+	alertmanagerResend := flag.Duration("alertmanager-resend-interval", notify.DefaultAlertmanagerResend, "how often the alerts of active attacks are sent again to keep them firing")
+	webhooksFile := flag.String("webhooks-file", "", "YAML or JSON file of webhooks, Slack and Discord channels told of attack and mitigation events")
+	routesFile := flag.String("notification-routes-file", "", "YAML or JSON notification routes, sending attacks by severity, type and environment to channels in turn with escalation delays; replaces the routes set through the API")
+	dashboardURL := flag.String("dashboard-url", "", "URL of this dashboard, linked from chat messages, emails and incidents")
+	smtpAddr := flag.String("smtp-addr", "", "email alerts through this SMTP server, host:port; 465 is spoken over TLS, others use STARTTLS when offered")
+	smtpUsername := flag.String("smtp-username", "", "SMTP username")
+	smtpPassword := flag.String("smtp-password", "", "SMTP password")
+	smtpFrom := flag.String("smtp-from", "", "sender of alert emails")
+	smtpToCritical := flag.String("smtp-to-critical", "", "comma-separated recipients of CRITICAL attacks")
+	smtpToHigh := flag.String("smtp-to-high", "", "comma-separated recipients of HIGH attacks")
+	smtpToMedium := flag.String("smtp-to-medium", "", "comma-separated recipients of MEDIUM attacks")
+	smtpToLow := flag.String("smtp-to-low", "", "comma-separated recipients of LOW attacks")
+	smtpAlertSeverity := flag.String("smtp-alert-severity", "CRITICAL", "email attacks at or above this severity as they start")
+	smtpDigestAt := flag.String("smtp-digest-at", "", "also email a daily digest of the attacks at this local time, e.g. 08:00")
+	legacyAPI := flag.Bool("api-legacy", true, "serve the unversioned /api/... paths as deprecated aliases of /api/v1; false answers them with 410 Gone")
+	legacyAPISunset := flag.String("api-legacy-sunset", "", "date the unversioned /api/... paths will be retired, YYYY-MM-DD, announced in their Sunset header")
+	ingestMaxAge := flag.Duration("ingest-max-age", ingestion.DefaultMaxAge, "oldest timestamp accepted from any ingestion source; 0 accepts any")
+	environments := flag.String("environments", "", "comma-separated environments traffic may be tagged with; traffic tagged with any other is rejected. Empty accepts any, up to -ingest-max-environments")
+	ingestMaxEnvironments := flag.Int("ingest-max-environments", ingestion.DefaultMaxEnvironments, "distinct environments accepted from ingestion sources; 0 for no limit")
+	ingestMaxProtocols := flag.Int("ingest-max-protocols", ingestion.DefaultMaxProtocols, "distinct protocols accepted from ingestion sources; 0 for no limit")
+	ingestMaxSkew := flag.Duration("ingest-max-clock-skew", ingestion.DefaultMaxClockSkew, "how far in the future an ingested timestamp may be; 0 accepts any")
+	backlogCapacity := flag.Int64("ingest-backlog-capacity", 100000, "bus consumer backlog (Kafka lag + NATS pending) at which detection is considered saturated")
+	timeZone := flag.String("timezone", "UTC", "deployment time zone for hourly and daily reporting windows, e.g. Europe/Berlin")
+	tenantTimeZones := flag.String("tenant-timezones", "", "per-environment reporting time zones, e.g. prod-eu=Europe/Berlin,prod-us=America/New_York")
+	natsReplay := flag.String("nats-replay-from", "", "replay the stream from an RFC3339 time, or \"all\", when the consumer is first created")
+	costCurrency := flag.String("cost-currency", "USD", "currency of the attack cost model")
+	costBandwidth := flag.Float64("cost-bandwidth-per-gb", cost.DefaultModel().BandwidthPerGB, "cost per GB of attack traffic")
+	costCompute := flag.Float64("cost-compute-per-million", cost.DefaultModel().ComputePerMillion, "cost per million attack requests served")
+	costScrubHour := flag.Float64("cost-scrubbing-per-hour", 0, "scrubbing service fee per hour an attack is mitigated")
+	costScrubGB := flag.Float64("cost-scrubbing-per-gb", 0, "scrubbing service fee per GB of mitigated attack traffic")
+	attackEndAfter := flag.Duration("attack-end-after", defaultAttackEndAfter, "how long an attack must go undetected before it is closed and moved to history")
+	alertCooldown := flag.Duration("alert-cooldown", defaultAlertCooldown, "an attack with the fingerprint of one alerted within this doesn't alert again unless it is more severe (0 alerts every attack)")
+	pulseWindow := flag.Duration("pulse-window", defaultPulseWindow, "longest quiet period between bursts that still counts as one pulse-wave campaign")
+	analysisSource := flag.String("analysis-source", "", "where analysis reads traffic: redis (raw traffic shared by every node, the default with -redis-addr, where nodes elect a leader) or stream (this node's ingest aggregated in process, the default with the embedded store)")
+	pidFile := flag.String("pid-file", "", "write the server's PID here once it serves, and again after each SIGHUP upgrade, for supervisors tracking the main process")
+	proxyListen := flag.String("proxy-listen", "", "run in proxy mode: accept TCP connections on this address, e.g. :8080, and forward them to -proxy-backend")
+	proxyBackend := flag.String("proxy-backend", "", "address of the service proxy mode protects, e.g. 127.0.0.1:8081")
+	proxyEnv := flag.String("proxy-env", "", "environment tag for proxied connections")
+	proxySlowAge := flag.Duration("proxy-slow-age", proxy.DefaultPolicy().MinAge, "age before a proxied connection can count as slow")
+	proxyMinWrite := flag.Float64("proxy-min-write-rate", proxy.DefaultPolicy().MinWriteRate, "bytes/sec below which a client sending its request is a slow write (Slowloris, slow POST)")
+	proxyMinRead := flag.Float64("proxy-min-read-rate", proxy.DefaultPolicy().MinReadRate, "bytes/sec below which a client reading a pending response is a slow read")
+	proxyCloseSlow := flag.Duration("proxy-close-slow-every", 0, "close slow proxied connections automatically at this interval; 0 leaves it to the close_slow_connections action")
+	disabledDetectors := flag.String("detectors-disabled", "", "comma-separated detectors to turn off, e.g. bot_flood,forecast; GET /api/detectors lists them")
+	thresholdsFile := flag.String("thresholds-file", "", "YAML or JSON detection thresholds: defaults for every environment and overrides per environment")
+	shadowThresholdsFile := flag.String("shadow-thresholds-file", "", "YAML or JSON thresholds, as for -thresholds-file, evaluated in shadow mode: detections are stored under attacks:shadow without alerting or mitigating")
+	serviceGroups := flag.String("service-groups", "", "destination ports counted together as one service, e.g. web=80+443+8443,mail@203.0.113.0/24=25+465+587")
+	dnsZones := flag.String("dns-zones", "", "comma-separated DNS zones query names are attributed to by water torture detection, beyond the public suffix list, e.g. corp.example.com")
+	metricsPush := flag.Duration("metrics-push-interval", time.Second, "how often dashboards are sent the current minute's metrics, independently of the 5s analysis cycle")
+	overviewPush := flag.Duration("overview-push-interval", 5*time.Second, "how often dashboards are sent the system overview")
+	intelFeeds := flag.String("intel-feeds", "", "comma-separated threat intelligence feeds: spamhaus_drop, spamhaus_dropv6, firehol_level1, or name=url")
+	abuseIPDBKey := flag.String("abuseipdb-key", "", "AbuseIPDB API key; adds its blacklist to the threat intelligence feeds")
+	abuseIPDBConfidence := flag.Int("abuseipdb-min-confidence", 90, "only take AbuseIPDB addresses reported with at least this abuse confidence")
+	intelRefresh := flag.Duration("intel-refresh-interval", 6*time.Hour, "how often threat intelligence feeds are fetched")
+	geoipDB := flag.String("geoip-db", "", "GeoLite2-Country or GeoLite2-City database locating sources by country")
+	geoipASNDB := flag.String("geoip-asn-db", "", "GeoLite2-ASN database resolving sources' autonomous systems")
+	geoipReload := flag.Duration("geoip-reload", time.Hour, "how often the GeoIP databases are checked for updates, e.g. by geoipupdate")
+	ipListRefresh := flag.Duration("iplist-refresh-interval", 10*time.Second, "how often the allowlist and denylist are reloaded from Redis")
+	rulesFile := flag.String("rules-file", "", "YAML or JSON detection rules, conditions over window metrics each raising a named attack type")
+	rulesReload := flag.Duration("rules-reload", 10*time.Second, "how often the rules file is checked for changes")
+	mlModel := flag.String("ml-model", "", "ONNX model (e.g. isolation forest or autoencoder over the detection feature vector) whose anomaly score is added to detection")
+	mlThreshold := flag.Float64("ml-threshold", 0.5, "model score at or above which a window is an ML_ANOMALY")
+	mlOutput := flag.String("ml-output", "", "model output taken as the score, e.g. scores for skl2onnx outlier detectors; empty for the first")
+	mlNegate := flag.Bool("ml-negate", false, "negate the model's score, for outputs lower for anomalies such as scikit-learn's decision_function")
+	profileSources := flag.Int("profile-sources", detection.DefaultProfiledSources, "busiest sources per environment whose behavior is profiled each cycle, flagging sharp deviations; 0 turns profiling off")
+	streamRetain := flag.Int("stream-retain", detection.DefaultStreamRetain, "requests per environment and second kept for detectors that inspect single requests; counters always see every request")
+	flag.Parse()
+
+	// Fill in what the command line left out from the config file and
+	// the environment, then check the settings the server can't start with
+	if *configFile == "" {
+		*configFile = os.Getenv(config.EnvName("config"))
+	}
+	if err := config.Apply(flag.CommandLine, *configFile); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if *addr == "" {
+		log.Fatal("-addr must not be empty")
+	}
+	if *analysisEvery < time.Second {
+		log.Fatal("-analysis-interval must be at least 1s")
+	}
+	if len(splitList(*corsOrigins)) == 0 {
+		log.Fatal("-cors-origins must not be empty; * allows any origin")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatal("-tls-cert and -tls-key go together")
+	}
+	if *tlsClientCA != "" && *tlsCert == "" {
+		log.Fatal("-tls-client-ca needs -tls-cert and -tls-key")
+	}
+	var tlsConfig *tls.Config
+	if *tlsCert != "" {
+		var err error
+		tlsConfig, err = serverTLSConfig(*tlsCert, *tlsKey, *tlsClientCA, *tlsClientAuth)
+		if err != nil {
+			log.Fatalf("Invalid TLS settings: %v", err)
+		}
+	}
+
+	log.Println("🚀 Starting DDoS Detection Dashboard Server...")
+	if *configFile != "" {
+		log.Printf("⚙️  Configuration read from %s", *configFile)
+	}
+
+	if *mock {
+		runMock(*mockSeed, *addr, *analysisEvery, splitList(*corsOrigins))
+		return
+	}
+
+	if *chaosSpec != "" {
+		cfg, err := chaos.ParseConfig(*chaosSpec)
+		if err != nil {
+			log.Fatalf("Invalid chaos settings: %v", err)
+		}
+		faults = chaos.NewInjector(cfg)
+		log.Printf("💥 Chaos mode enabled: %+v", cfg)
+	}
+
+	// Nodes sharing a Redis elect a leader that must see every node's
+	// ingest; the embedded store only ever serves this node
+	if *analysisSource == "" {
+		*analysisSource = "redis"
+		if *redisAddr == "" {
+			*analysisSource = "stream"
+		}
+	}
+	if *redisAddr == "" {
+		addr, err := startEmbeddedStore()
+		if err != nil {
+			log.Fatalf("Failed to start embedded store: %v", err)
+		}
+		*redisAddr = addr
+	}
+
+	server, err := NewServer(*redisAddr, *analysisEvery, splitList(*corsOrigins))
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+
+	server.handoff, err = handoff.New()
+	if err != nil {
+		log.Fatalf("Failed to read sockets handed over by the previous process: %v", err)
+	}
+
+	if faults != nil {
+		server.redis.AddHook(faults.RedisHook())
+	}
+
+	if err := server.redis.SetKeyPrefix(*redisKeyPrefix); err != nil {
+		log.Fatalf("Invalid Redis key prefix: %v", err)
+	}
+	server.checkSchema()
+
+	tenantZones, err := tz.ParseTenantZones(*tenantTimeZones)
+	if err != nil {
+		log.Fatalf("Invalid tenant time zones: %v", err)
+	}
+	zones, err := tz.NewZones(*timeZone, tenantZones)
+	if err != nil {
+		log.Fatalf("Invalid time zone settings: %v", err)
+	}
+	server.redis.SetZones(zones)
+	server.detectors.SetZones(zones)
+
+	if *disabledDetectors != "" {
+		if err := server.detectors.SetDisabled(strings.Split(*disabledDetectors, ",")); err != nil {
+			log.Fatalf("Invalid -detectors-disabled: %v", err)
+		}
+	}
+
+	if *thresholdsFile != "" {
+		config, err := detection.LoadThresholdsConfig(*thresholdsFile)
+		if err != nil {
+			log.Fatalf("Failed to load thresholds: %v", err)
+		}
+		server.thresholdsConfig = config
+		server.applyThresholdsFile()
+		log.Printf("🎚️  Loaded detection thresholds from %s (%d environments)", *thresholdsFile, len(config.Environments))
+	}
+	if *shadowThresholdsFile != "" {
+		config, err := detection.LoadThresholdsConfig(*shadowThresholdsFile)
+		if err != nil {
+			log.Fatalf("Failed to load shadow thresholds: %v", err)
+		}
+		server.shadowConfig = config
+		server.applyThresholdsFile()
+		log.Printf("👻 Shadowing detection with the thresholds from %s (%d environments)", *shadowThresholdsFile, len(config.Environments))
+	}
+
+	groups, err := detection.ParseServiceGroups(*serviceGroups)
+	if err != nil {
+		log.Fatalf("Invalid service groups: %v", err)
+	}
+	server.detectors.SetServiceGroups(groups)
+	server.detectors.SetDNSZones(splitList(*dnsZones))
+
+	// Add user-defined rules to detection if a rules file is configured
+	if *rulesFile != "" {
+		engine, err := rules.Load(*rulesFile, detection.RuleVariables)
+		if err != nil {
+			log.Fatalf("Failed to load detection rules: %v", err)
+		}
+		server.rules = engine
+		server.detectors.SetRules(engine)
+		log.Printf("📜 Loaded %d detection rules from %s", len(engine.Rules()), *rulesFile)
+	}
+
+	// Score sources by the threat intelligence feeds listing them
+	feeds, err := intel.ParseFeeds(*intelFeeds)
+	if err != nil {
+		log.Fatalf("Invalid threat intelligence feeds: %v", err)
+	}
+	if *abuseIPDBKey != "" {
+		feeds = append(feeds, intel.AbuseIPDB(*abuseIPDBKey, *abuseIPDBConfidence))
+	}
+	if len(feeds) > 0 {
+		if *intelRefresh <= 0 {
+			log.Fatal("-intel-refresh-interval must be positive")
+		}
+		server.intel = intel.New(feeds)
+		server.detectors.SetReputation(server.intel)
+		log.Printf("🕵️  Scoring sources with %d threat intelligence feeds", len(feeds))
+	}
+
+	// Locate sources by country and autonomous system
+	if *geoipDB != "" || *geoipASNDB != "" {
+		resolver, err := geoip.Open(*geoipDB, *geoipASNDB)
+		if err != nil {
+			log.Fatalf("Failed to load GeoIP databases: %v", err)
+		}
+		server.geoip = resolver
+		server.detectors.SetLocator(resolver)
+		log.Printf("🌍 Locating sources with %d GeoIP databases", len(resolver.Databases()))
+	}
+
+	// Profile the busiest sources and flag those breaking with their history
+	if *profileSources < 0 {
+		log.Fatal("-profile-sources must not be negative")
+	}
+	server.detectors.SetProfileStore(server.redis, *profileSources)
+
+	// Add a learned model's anomaly score to detection if one is configured
+	if *mlModel != "" {
+		model, err := ml.Load(*mlModel)
+		if err != nil {
+			log.Fatalf("Failed to load ML model: %v", err)
+		}
+		model.Output, model.Negate = *mlOutput, *mlNegate
+		if n := model.Features(); n != 0 && n != len(detection.FeatureNames) {
+			log.Fatalf("ML model %s expects %d features; detection provides %d (%s)",
+				*mlModel, n, len(detection.FeatureNames), strings.Join(detection.FeatureNames, ", "))
+		}
+		if _, err := model.Score(make([]float64, len(detection.FeatureNames))); err != nil {
+			log.Fatalf("ML model %s cannot be evaluated: %v", *mlModel, err)
+		}
+		server.detectors.SetScorer(model, *mlThreshold)
+		log.Printf("🧠 Scoring traffic with ML model %s (threshold %.3f)", *mlModel, *mlThreshold)
+	}
+
+	server.backlogCapacity = *backlogCapacity
+	switch *analysisSource {
+	case "stream":
+		server.stream = detection.NewStreamWindow(*streamRetain)
+	case "redis":
+	default:
+		log.Fatalf("Invalid analysis source %q, expected stream or redis", *analysisSource)
+	}
+	server.pulses = detection.NewPulseCorrelator(pulseBurstGap(*analysisEvery), *attackEndAfter, *pulseWindow)
+	server.pulses.MergeBlocked = server.mergeBlocked
+	if *alertCooldown < 0 {
+		log.Fatal("-alert-cooldown must not be negative")
+	}
+	server.alerts = detection.NewAlertGate(*alertCooldown)
+	server.costModel = cost.Model{
+		Currency:          *costCurrency,
+		BandwidthPerGB:    *costBandwidth,
+		ComputePerMillion: *costCompute,
+		ScrubbingPerHour:  *costScrubHour,
+		ScrubbingPerGB:    *costScrubGB,
+	}
+
+	server.tokens, err = auth.ParseTokens(*apiTokens)
+	if err != nil {
+		log.Fatalf("Invalid API tokens: %v", err)
+	}
+	if (*usersFile == "") != (*sessionSecret == "") {
+		log.Fatal("-users-file and -session-secret must be set together")
+	}
+	if *usersFile != "" {
+		server.users, err = auth.LoadUsers(*usersFile)
+		if err != nil {
+			log.Fatalf("Invalid users file: %v", err)
+		}
+		server.sessions, err = auth.NewSessions(*sessionSecret, *sessionTTL)
+		if err != nil {
+			log.Fatalf("Invalid -session-secret or -session-ttl: %v", err)
+		}
+		log.Printf("🔑 Login enabled for %d users, sessions last %s", server.users.Len(), *sessionTTL)
+	}
+	if *authRequired && server.tokens.Empty() && server.sessions == nil {
+		if server.tokens, err = firstRunTokens(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	server.authRequired = *authRequired
+	if err := server.router.SetTrustedProxies(splitList(*trustedProxies)); err != nil {
+		log.Fatalf("Invalid -trusted-proxies: %v", err)
+	}
+	if *rateLimitIP < 0 || *rateLimitKey < 0 {
+		log.Fatal("-rate-limit-ip and -rate-limit-key must not be negative")
+	}
+	if *rateLimitIP > 0 {
+		if *rateLimitIPBurst <= 0 {
+			log.Fatal("-rate-limit-ip-burst must be positive")
+		}
+		server.ipLimiter = ratelimit.New(*rateLimitIP, *rateLimitIPBurst, 0)
+	}
+	if *rateLimitKey > 0 {
+		if *rateLimitKeyBurst <= 0 {
+			log.Fatal("-rate-limit-key-burst must be positive")
+		}
+		server.keyLimiter = ratelimit.New(*rateLimitKey, *rateLimitKeyBurst, 0)
+	}
+	for _, entry := range splitList(*rateLimitExempt) {
+		prefix, err := parseSilencePrefix(entry)
+		if err != nil {
+			log.Fatalf("Invalid -rate-limit-exempt entry %q: %v", entry, err)
+		}
+		server.rateLimitExemptions = append(server.rateLimitExemptions, prefix)
+	}
+	if *ingestMaxAge < 0 || *ingestMaxSkew < 0 {
+		log.Fatal("-ingest-max-age and -ingest-max-clock-skew must not be negative")
+	}
+	if *vpcFlowLookback < 0 || *logpushLookback < 0 {
+		log.Fatal("-vpc-flow-lookback and -cloudflare-logpush-lookback must not be negative")
+	}
+	if *ingestMaxEnvironments < 0 || *ingestMaxProtocols < 0 {
+		log.Fatal("-ingest-max-environments and -ingest-max-protocols must not be negative")
+	}
+	server.legacyAPIOff = !*legacyAPI
+	if *legacyAPISunset != "" {
+		server.legacyAPISunset, err = time.Parse(time.DateOnly, *legacyAPISunset)
+		if err != nil {
+			log.Fatalf("Invalid -api-legacy-sunset: %v", err)
+		}
+	}
+	server.ingestValidator = ingestion.Validator{
+		MaxAge:       *ingestMaxAge,
+		MaxClockSkew: *ingestMaxSkew,
+		Cardinality:  ingestion.NewCardinality(*ingestMaxEnvironments, *ingestMaxProtocols),
+	}
+	if envs := splitList(*environments); len(envs) > 0 {
+		server.ingestValidator.Environments = make(map[string]bool, len(envs))
+		for _, env := range envs {
+			server.ingestValidator.Environments[env] = true
+		}
+	}
+	server.agentCertRequired = *tlsClientCA != "" && *tlsClientAuth == clientAuthIngest
+
+	if *mispURL != "" {
+		misp, err := notify.NewMISPNotifier(notify.MISPConfig{
+			URL:                *mispURL,
+			APIKey:             *mispKey,
+			MinSeverity:        *mispSeverity,
+			Distribution:       *mispDistribution,
+			Tags:               splitList(*mispTags),
+			InsecureSkipVerify: *mispInsecure,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure MISP: %v", err)
+		}
+		server.notifiers = append(server.notifiers, misp)
+	}
+	if *pagerDutyKey != "" {
+		pagerDuty, err := notify.NewPagerDutyNotifier(notify.PagerDutyConfig{
+			RoutingKey:   *pagerDutyKey,
+			URL:          *pagerDutyURL,
+			MinSeverity:  *pagerDutySeverity,
+			DashboardURL: *dashboardURL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure PagerDuty: %v", err)
+		}
+		server.notifiers = append(server.notifiers, pagerDuty)
+	}
+	if *opsgenieKey != "" {
+		opsgenie, err := notify.NewOpsgenieNotifier(notify.OpsgenieConfig{
+			APIKey:       *opsgenieKey,
+			URL:          *opsgenieURL,
+			Team:         *opsgenieTeam,
+			MinSeverity:  *opsgenieSeverity,
+			DashboardURL: *dashboardURL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure Opsgenie: %v", err)
+		}
+		server.notifiers = append(server.notifiers, opsgenie)
+	}
+	if *alertmanagerURL != "" {
+		labels, err := notify.ParseLabels(*alertmanagerLabels)
+		if err != nil {
+			log.Fatalf("Invalid -alertmanager-labels: %v", err)
+		}
+		server.alertmanager, err = notify.NewAlertmanagerNotifier(notify.AlertmanagerConfig{
+			URLs:           splitList(*alertmanagerURL),
+			Labels:         labels,
+			MinSeverity:    *alertmanagerSeverity,
+			ResendInterval: *alertmanagerResend,
+			DashboardURL:   *dashboardURL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure Alertmanager: %v", err)
+		}
+		server.notifiers = append(server.notifiers, server.alertmanager)
+	}
+	if *smtpAddr != "" {
+		recipients := make(map[string][]string)
+		for severity, to := range map[string]string{"CRITICAL": *smtpToCritical, "HIGH": *smtpToHigh, "MEDIUM": *smtpToMedium, "LOW": *smtpToLow} {
+			if list := splitList(to); len(list) > 0 {
+				recipients[severity] = list
+			}
+		}
+		server.email, err = notify.NewEmailNotifier(notify.EmailConfig{
+			Addr:          *smtpAddr,
+			Username:      *smtpUsername,
+			Password:      *smtpPassword,
+			From:          *smtpFrom,
+			Recipients:    recipients,
+			AlertSeverity: *smtpAlertSeverity,
+			DashboardURL:  *dashboardURL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure email: %v", err)
+		}
+		server.notifiers = append(server.notifiers, server.email)
+	}
+	if *webhooksFile != "" {
+		webhooks, err := notify.LoadWebhooks(*webhooksFile)
+		if err != nil {
+			log.Fatalf("Failed to load webhooks: %v", err)
+		}
+		notifiers, err := webhooks.Notifiers(*dashboardURL)
+		if err != nil {
+			log.Fatalf("Failed to configure webhooks: %v", err)
+		}
+		server.notifiers = append(server.notifiers, notifiers...)
+		log.Printf("🪝 Loaded %d webhooks from %s", len(notifiers), *webhooksFile)
+	}
+	if *routesFile != "" {
+		routing, err := notify.LoadRouting(*routesFile)
+		if err != nil {
+			log.Fatalf("Failed to load notification routes: %v", err)
+		}
+		if err := routing.Check(server.notifiers); err != nil {
+			log.Fatalf("Invalid notification routes in %s: %v", *routesFile, err)
+		}
+		if err := server.setRouting(routing); err != nil {
+			log.Fatalf("Failed to store notification routes: %v", err)
+		}
+		log.Printf("📟 Loaded %d notification routes from %s", len(routing.Routes), *routesFile)
+	} else {
+		server.syncRouting()
+	}
+	server.logpushSecret = *logpushSecret
+	server.logpushEnvironment = *logpushEnv
+
+	// Ingestion and analysis stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start VPC Flow Log ingestion if a source is configured
+	if *vpcFlowS3 != "" || *vpcFlowLogGroup != "" {
+		ingester, err := ingestion.NewFlowLogIngester(ctx, ingestion.FlowLogSourceConfig{
+			Region:      *awsRegion,
+			S3URL:       *vpcFlowS3,
+			LogGroup:    *vpcFlowLogGroup,
+			Environment: *vpcFlowEnv,
+			Lookback:    *vpcFlowLookback,
+		}, server.sensor("vpc_flow_logs"))
+		if err != nil {
+			log.Fatalf("Failed to create VPC Flow Log ingester: %v", err)
+		}
+		go ingester.Run(ctx)
+	}
+
+	// Start Cloudflare Logpush pulling if a bucket is configured
+	if *logpushBucket != "" {
+		puller, err := ingestion.NewLogpushPuller(ctx, ingestion.LogpushPullConfig{
+			S3URL:       *logpushBucket,
+			Endpoint:    *logpushEndpoint,
+			Region:      *awsRegion,
+			Environment: *logpushEnv,
+			Lookback:    *logpushLookback,
+		}, server.sensor("cloudflare_logpush"))
+		if err != nil {
+			log.Fatalf("Failed to create Cloudflare Logpush puller: %v", err)
+		}
+		go puller.Run(ctx)
+	}
+
+	// Start Kafka consumption if brokers are configured
+	if *kafkaBrokers != "" {
+		consumer, err := ingestion.NewKafkaConsumer(ingestion.KafkaConfig{
+			Brokers: strings.Split(*kafkaBrokers, ","),
+			Topic:   *kafkaTopic,
+			GroupID: *kafkaGroup,
+			Format:  *kafkaFormat,
+		}, server.sensor("kafka"))
+		if err != nil {
+			log.Fatalf("Failed to create Kafka consumer: %v", err)
+		}
+		server.kafka = consumer
+		go consumer.Run(ctx)
+	}
+
+	// Start NATS JetStream consumption if a server is configured
+	if *natsURL != "" {
+		consumer, err := ingestion.NewNATSConsumer(ctx, ingestion.NATSConfig{
+			URL:        *natsURL,
+			Stream:     *natsStream,
+			Subject:    *natsSubject,
+			Consumer:   *natsConsumer,
+			Format:     *natsFormat,
+			ReplayFrom: *natsReplay,
+		}, server.sensor("nats"))
+		if err != nil {
+			log.Fatalf("Failed to create NATS consumer: %v", err)
+		}
+		server.nats = consumer
+		go consumer.Run(ctx)
+	}
+
+	// Start the syslog listener if an address is configured
+	if *syslogUDP != "" || *syslogTCP != "" {
+		listener := ingestion.NewSyslogListener(*syslogUDP, *syslogTCP, *syslogEnv, server.sensor("syslog"), server)
+		listener.Sockets = server.handoff
+		listener.TLS = agentTLSConfig(tlsConfig, server.agentCertRequired)
+		go func() {
+			if err := listener.Run(ctx); err != nil {
+				log.Fatalf("Failed to start syslog listener: %v", err)
+			}
+		}()
+	}
+
+	// Follow this host's web server logs, the built-in agent
+	for _, path := range splitList(*agentAccessLogs) {
+		tailer := ingestion.NewAccessLogTailer(path, *agentEnv, *agentDestIP, server.sensor("agent"))
+		go func() {
+			if err := tailer.Run(ctx); err != nil {
+				log.Fatalf("Failed to follow access log: %v", err)
+			}
+		}()
+	}
+
+	// Receive access logs streamed by Envoy/Istio sidecars
+	if *envoyALS != "" {
+		receiver := ingestion.NewEnvoyALSReceiver(*envoyALS, *envoyEnv, server.sensor("envoy_als"))
+		receiver.Sockets = server.handoff
+		receiver.TLS = agentTLSConfig(tlsConfig, server.agentCertRequired)
+		go func() {
+			if err := receiver.Run(ctx); err != nil {
+				log.Fatalf("Failed to start Envoy access log service: %v", err)
+			}
+		}()
+	}
+
+	// Front the protected service in proxy mode
+	server.slowConnections = proxy.Policy{MinAge: *proxySlowAge, MinWriteRate: *proxyMinWrite, MinReadRate: *proxyMinRead}
+	if *proxyListen != "" {
+		if *proxyBackend == "" {
+			log.Fatal("-proxy-listen requires -proxy-backend")
+		}
+		server.proxy = proxy.New(*proxyListen, *proxyBackend, *proxyEnv, server.sensor("proxy"))
+		server.proxy.Sockets = server.handoff
+		go func() {
+			if err := server.proxy.Run(ctx); err != nil {
+				log.Fatalf("Failed to start proxy: %v", err)
+			}
+		}()
+		if *proxyCloseSlow > 0 {
+			go server.proxy.EnforceSlow(ctx, server.slowConnections, *proxyCloseSlow)
+		}
+	}
+
+	// Hook up capacity autoscaling if a target is configured
+	var scaler mitigation.Scaler
+	switch {
+	case *autoscaleHPA != "":
+		namespace, name, ok := strings.Cut(*autoscaleHPA, "/")
+		if !ok {
+			log.Fatalf("Invalid HPA %q, expected namespace/name", *autoscaleHPA)
+		}
+		scaler, err = mitigation.NewKubernetesHPAScaler(namespace, name)
+	case *autoscaleASG != "":
+		scaler, err = mitigation.NewASGScaler(ctx, *awsRegion, *autoscaleASG)
+	}
+	if err != nil {
+		log.Fatalf("Failed to create autoscaler: %v", err)
+	}
+	if scaler != nil {
+		server.autoscaler = mitigation.NewAutoscaleHook(scaler, mitigation.AutoscalePolicy{
+			MinConfidence: *autoscaleConfidence,
+			Capacity:      *autoscaleCapacity,
+		})
+	}
+
+	// Trusted and known-bad sources, shared through Redis
+	server.loadIPLists()
+	if *ipListRefresh <= 0 {
+		log.Fatal("-iplist-refresh-interval must be positive")
+	}
+	go server.startIPListRefresh(ctx, *ipListRefresh)
+	go server.watchMitigationSerial(ctx)
+
+	// Decide on each request for reverse proxies asking
+	if *enforceRate <= 0 || *enforceBurst <= 0 || *enforceMaxClients <= 0 {
+		log.Fatal("-mitigation-rate-limit-per-minute, -mitigation-rate-limit-burst and -mitigation-rate-limit-max-clients must be positive")
+	}
+	server.enforcer = mitigation.NewTokenBucketExecutor(*enforceRate, *enforceBurst, *enforceMaxClients)
+	server.executors = append(server.executors, server.enforcer)
+
+	// Block on this host's firewall
+	if *firewall != "" {
+		if *firewallMaxEntries <= 0 {
+			log.Fatal("-mitigation-firewall-max-entries must be positive")
+		}
+		executor, err := mitigation.NewFirewallExecutor(*firewall, *firewallName, *firewallMaxEntries)
+		if err != nil {
+			log.Fatalf("Failed to set up the host firewall: %v", err)
+		}
+		server.executors = append(server.executors, executor)
+		log.Printf("🧱 Blocking with %s, at most %d entries", executor.Name(), *firewallMaxEntries)
+	}
+
+	// Block and challenge at Cloudflare's edge
+	if *cloudflareToken != "" {
+		executor, err := mitigation.NewCloudflareExecutor(mitigation.CloudflareConfig{
+			APIToken:      *cloudflareToken,
+			ZoneID:        *cloudflareZone,
+			AccountID:     *cloudflareAccount,
+			MinConfidence: *cloudflareConfidence,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up Cloudflare mitigation: %v", err)
+		}
+		server.executors = append(server.executors, executor)
+		log.Printf("☁️  Pushing mitigations of attacks with confidence %.2f or more to Cloudflare", *cloudflareConfidence)
+	}
+
+	// Block in AWS WAF in front of cloud-hosted targets
+	if *wafIPv4Set != "" || *wafIPv6Set != "" {
+		if *wafFlush <= 0 {
+			log.Fatal("-mitigation-aws-waf-flush-interval must be positive")
+		}
+		executor, err := mitigation.NewAWSWAFExecutor(ctx, *awsRegion, *wafScope, *wafIPv4Set, *wafIPv6Set)
+		if err != nil {
+			log.Fatalf("Failed to set up AWS WAF mitigation: %v", err)
+		}
+		server.executors = append(server.executors, executor)
+		go executor.Run(ctx, *wafFlush)
+		log.Printf("🧱 Blocking in AWS WAF, flushing every %s", *wafFlush)
+	}
+
+	// Filter upstream with Flowspec and RTBH announcements
+	if *gobgpAPI != "" {
+		executor, err := mitigation.NewGoBGPExecutor(mitigation.GoBGPConfig{
+			API:       *gobgpAPI,
+			Flowspec:  *gobgpFlowspec,
+			RTBH:      *gobgpRTBH,
+			Community: *gobgpCommunity,
+			NextHop:   *gobgpNextHop,
+			NextHopV6: *gobgpNextHopV6,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up GoBGP mitigation: %v", err)
+		}
+		server.executors = append(server.executors, executor)
+		log.Printf("📡 Announcing mitigations through gobgpd at %s (Flowspec: %v, RTBH: %v)", *gobgpAPI, *gobgpFlowspec, *gobgpRTBH)
+	}
+
+	// Hand blocks to fail2ban or scripts, without firewall privileges
+	if *banLog != "" || *banList != "" {
+		if *banDuration < 0 {
+			log.Fatal("-mitigation-ban-duration must not be negative")
+		}
+		executor, err := mitigation.NewBanListExecutor(mitigation.BanListConfig{
+			LogPath:  *banLog,
+			ListPath: *banList,
+			Duration: *banDuration,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up the ban list: %v", err)
+		}
+		server.executors = append(server.executors, executor)
+		log.Printf("📝 Writing blocked sources to the ban log %q and ban list %q", *banLog, *banList)
+	}
+
+	// Act on detected attacks by policy, and on operators' manual actions
+	if *mitigationDuration <= 0 {
+		log.Fatal("-mitigation-duration must be positive")
+	}
+	if *maxBlocked < 0 {
+		log.Fatal("-mitigation-max-blocked must not be negative")
+	}
+	var policyRules []mitigation.PolicyRule
+	if *policyFile != "" {
+		policyRules, err = mitigation.LoadPolicyRules(*policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load mitigation policies: %v", err)
+		}
+		log.Printf("📜 Loaded %d mitigation policies from %s", len(policyRules), *policyFile)
+	}
+	server.mitigations = mitigation.NewEngine(server.redis, server.executors, mitigation.Policy{
+		BlockConfidence:     *blockConfidence,
+		RateLimitConfidence: *rateLimitConfidence,
+		Duration:            *mitigationDuration,
+		RTBH:                *gobgpAPI != "" && *gobgpRTBH,
+		Rules:               policyRules,
+		MaxBlocked:          *maxBlocked,
+	}, func() *iplist.Set {
+		allow, _ := server.detectors.IPLists()
+		return allow
+	})
+	server.mitigations.OnChange(server.recordMitigation)
+	server.mitigations.OnFailure(server.recordMitigationFailure)
+	server.autoMitigate = *autoMitigate
+	server.syncKillSwitch()
+
+	// Take the enforcer's limits back from the records, as its buckets do
+	// not outlive the process
+	if actions, _, err := (enforcedMitigations{server}).GetActiveMitigations(); err == nil {
+		for _, action := range actions {
+			if action.Active && server.enforcer.Handles(action) {
+				server.enforcer.Apply(action)
+			}
+		}
+	}
+
+	// Audit executor state against the mitigation records
+	server.reconciler = mitigation.NewReconciler(enforcedMitigations{server}, server.executors, *repairDrift)
+	go server.reconciler.Run(ctx, *reconcileInterval)
+
+	// Email the daily digest
+	if *smtpDigestAt != "" {
+		if server.email == nil {
+			log.Fatal("-smtp-digest-at needs -smtp-addr")
+		}
+		at, err := time.Parse("15:04", *smtpDigestAt)
+		if err != nil {
+			log.Fatal("-smtp-digest-at must be a time of day such as 08:00")
+		}
+		go server.runEmailDigest(ctx, at.Hour(), at.Minute(), zones.For(""))
+	}
+
+	// Keep the Alertmanager alerts of active attacks firing
+	if server.alertmanager != nil {
+		go server.runAlertmanagerResend(ctx)
+	}
+
+	if server.intel != nil {
+		go server.intel.Run(ctx, *intelRefresh)
+	}
+
+	if server.geoip != nil {
+		if *geoipReload <= 0 {
+			log.Fatal("-geoip-reload must be positive")
+		}
+		go server.geoip.Watch(ctx, *geoipReload)
+	}
+
+	if server.rules != nil {
+		go server.rules.Watch(ctx, *rulesReload)
+	}
+
+	if *metricsPush <= 0 {
+		log.Fatal("-metrics-push-interval must be positive")
+	}
+	go server.startMetricsBroadcast(ctx, *metricsPush)
+
+	if *overviewPush <= 0 {
+		log.Fatal("-overview-push-interval must be positive")
+	}
+	go server.startOverviewBroadcast(ctx, *overviewPush)
+
+	// SIGHUP upgrades to the binary now on disk without dropping connections
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			server.upgrade(stop)
+		}
+	}()
+
+	listener, err := server.handoff.Listen("http", "tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	if *pidFile != "" {
+		if err := os.WriteFile(*pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+			log.Fatalf("Failed to write PID file: %v", err)
+		}
+	}
+	if server.handoff.Inherited() {
+		server.adoptWebSockets()
+		if err := server.handoff.Ready(); err != nil {
+			log.Printf("Error telling the previous process to hand over: %v", err)
+		}
+	}
+
+	// Start analysis engine in background; once it stops, hand over
+	// leadership and drain the HTTP server
+	httpServer := &http.Server{Handler: server.router, TLSConfig: tlsConfig}
+	go func() {
+		server.startAnalysisEngine(ctx)
+		log.Println("Shutting down")
+		server.stepDown()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down HTTP server: %v", err)
+		}
+	}()
 
 	// Start server
-	log.Println("Server listening on :8888")
-	if err := server.router.Run(":8888"); err != nil {
+	if tlsConfig != nil {
+		if *tlsClientCA != "" {
+			log.Printf("Server listening on %s (HTTPS, client certificates: %s)", *addr, *tlsClientAuth)
+		} else {
+			log.Printf("Server listening on %s (HTTPS)", *addr)
+		}
+		err = httpServer.ServeTLS(listener, "", "")
+	} else {
+		log.Printf("Server listening on %s", *addr)
+		err = httpServer.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -0,0 +1,748 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/mitigation"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+const (
+	// exportFormatVersion changes only on breaking changes to the export payload
+	exportFormatVersion = 1
+	// maxExportWait caps how long a long-poll request may be held open
+	maxExportWait = 60 * time.Second
+	// serialPollInterval is how often the mitigation serial is checked for
+	// the long-polls waiting on it
+	serialPollInterval = time.Second
+	// effectWindow bounds the traffic compared before and after an action
+	effectWindow = 10 * time.Minute
+)
+
+// mitigationExport is the stable payload consumed by external enforcement points
+type mitigationExport struct {
+	FormatVersion int                     `json:"format_version"`
+	Serial        int64                   `json:"serial"`
+	GeneratedAt   time.Time               `json:"generated_at"`
+	Count         int                     `json:"count"`
+	Entries       []mitigationExportEntry `json:"entries"`
+}
+
+type mitigationExportEntry struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	ExpiresAt time.Time `json:"expires_at"`
+	AttackID  string    `json:"attack_id,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	// RatePerMinute and Burst are the per-client token bucket of RATE_LIMIT
+	// entries, as /api/enforce/check applies it
+	RatePerMinute int `json:"rate_per_minute,omitempty"`
+	Burst         int `json:"burst,omitempty"`
+}
+
+// exportActiveMitigations serves the active block list for edge scripts and
+// CDN workers, including the denylist and leaving out allowlisted sources. Clients send the last serial they applied via If-None-Match
+// (or ?since=) and may ask the server to hold the request open with ?wait=
+// seconds until the set changes; an unchanged set answers 304.
+func (s *Server) exportActiveMitigations(c *gin.Context) {
+	known, haveKnown := parseKnownSerial(c)
+
+	wait := time.Duration(0)
+	if secs, err := strconv.Atoi(c.Query("wait")); err == nil && secs > 0 {
+		wait = time.Duration(secs) * time.Second
+		if wait > maxExportWait {
+			wait = maxExportWait
+		}
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		// Taken before reading, so a change made meanwhile still wakes us
+		changed := s.mitigationChanges.changes()
+		actions, serial, err := enforcedMitigations{s}.GetActiveMitigations()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		etag := `"` + strconv.FormatInt(serial, 10) + `"`
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", "no-cache")
+
+		if !haveKnown || serial != known {
+			c.JSON(http.StatusOK, s.buildMitigationExport(actions, serial))
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline.C:
+			c.Status(http.StatusNotModified)
+			return
+		case <-changed:
+		}
+	}
+}
+
+// serialNotifier wakes everyone waiting for the mitigation set to change,
+// from one watch of its serial however many long-polls are open
+type serialNotifier struct {
+	mu      sync.Mutex
+	serial  int64
+	changed chan struct{} // closed and replaced when the serial changes
+}
+
+func newSerialNotifier() *serialNotifier {
+	return &serialNotifier{changed: make(chan struct{})}
+}
+
+// changes returns a channel closed the next time the serial changes
+func (n *serialNotifier) changes() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.changed
+}
+
+// set records the current serial, waking the waiters if it changed
+func (n *serialNotifier) set(serial int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if serial == n.serial {
+		return
+	}
+	n.serial = serial
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// watchMitigationSerial follows the serial, which any node may bump, for the
+// long-polls on this one
+func (s *Server) watchMitigationSerial(ctx context.Context) {
+	ticker := time.NewTicker(serialPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			serial, err := s.redis.MitigationSerial()
+			if err != nil {
+				continue
+			}
+			s.mitigationChanges.set(serial)
+		}
+	}
+}
+
+func (s *Server) buildMitigationExport(actions []models.MitigationAction, serial int64) mitigationExport {
+	ratePerMinute, burst := s.enforcer.Limits()
+	entries := make([]mitigationExportEntry, 0, len(actions))
+	for _, action := range actions {
+		if !action.Active {
+			continue
+		}
+		entry := mitigationExportEntry{
+			ID:        action.ID,
+			Action:    action.Type,
+			Target:    action.Target,
+			ExpiresAt: action.ExpiresAt,
+			AttackID:  action.AttackID,
+			Reason:    action.Reason,
+		}
+		if action.Type == mitigation.ActionRateLimit {
+			entry.RatePerMinute, entry.Burst = ratePerMinute, burst
+		}
+		entries = append(entries, entry)
+	}
+
+	return mitigationExport{
+		FormatVersion: exportFormatVersion,
+		Serial:        serial,
+		GeneratedAt:   time.Now(),
+		Count:         len(entries),
+		Entries:       entries,
+	}
+}
+
+// parseKnownSerial reads the serial the client already has from
+// If-None-Match or the since query parameter
+func parseKnownSerial(c *gin.Context) (int64, bool) {
+	value := c.GetHeader("If-None-Match")
+	if value == "" {
+		value = c.Query("since")
+	}
+
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	value = strings.Trim(value, `"`)
+	if value == "" {
+		return 0, false
+	}
+
+	serial, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return serial, true
+}
+
+// getMitigationDrift reports discrepancies between the mitigation records and
+// what each executor actually enforces. ?refresh=true runs a pass first.
+func (s *Server) getMitigationDrift(c *gin.Context) {
+	reports := s.reconciler.Reports()
+	if c.Query("refresh") == "true" {
+		reports = s.reconciler.Reconcile()
+	}
+
+	inSync := true
+	for _, report := range reports {
+		if !report.InSync() && !report.Repaired {
+			inSync = false
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"in_sync":   inSync,
+		"executors": reports,
+	})
+}
+
+// mitigateAttack has the mitigation engine act on a detected attack,
+// recording what it did on the attack's timeline. An attack whose sources
+// are now blocked or rate limited, or whose victims are blackholed, is
+// marked mitigated.
+func (s *Server) mitigateAttack(attack models.Attack) models.Attack {
+	if !s.autoMitigate || attack.SilencedBy != "" {
+		return attack
+	}
+
+	actions, err := s.mitigations.Mitigate(attack)
+	if err != nil {
+		log.Printf("Error mitigating attack %s: %v", attack.ID, err)
+	}
+	if len(actions) == 0 {
+		return attack
+	}
+
+	enforced := false
+	byType := make(map[string]int)
+	dryRuns := 0
+	for _, action := range actions {
+		byType[action.Type]++
+		switch {
+		case action.DryRun:
+			dryRuns++
+		case action.Type != mitigation.ActionMonitor:
+			enforced = true
+		}
+	}
+	parts := make([]string, 0, len(byType))
+	for _, actionType := range []string{mitigation.ActionRTBH, mitigation.ActionBlock, mitigation.ActionRateLimit, mitigation.ActionMonitor} {
+		if n := byType[actionType]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", actionType, n))
+		}
+	}
+	message := fmt.Sprintf("%s for %s", strings.Join(parts, ", "), actions[0].Duration)
+	if dryRuns > 0 {
+		message += fmt.Sprintf(" (%d dry run)", dryRuns)
+	}
+	if actions[0].Policy != "" {
+		message += ", policy " + actions[0].Policy
+	}
+	log.Printf("🛡️  Mitigating %s in %s: %s", attack.Type, attack.Environment, message)
+	s.recordTimeline(attack.ID, models.TimelineEvent{
+		Timestamp: time.Now(),
+		Type:      "MITIGATED",
+		Message:   message,
+		Actor:     "mitigation",
+	})
+
+	if enforced && !attack.Mitigated {
+		attack.Mitigated = true
+		s.pulses.Update(attack)
+		if err := s.redis.StoreAttack(attack); err != nil {
+			log.Printf("Error storing attack: %v", err)
+		}
+	}
+	return attack
+}
+
+// expireMitigations lifts the actions whose time is up
+func (s *Server) expireMitigations() {
+	expired, err := s.mitigations.Expire()
+	if err != nil {
+		log.Printf("Error expiring mitigations: %v", err)
+	}
+	for _, action := range expired {
+		log.Printf("⏱️  %s of %s expired", action.Type, action.Target)
+	}
+}
+
+// recordMitigation adds a change to the mitigations to the audit trail,
+// notifies it and pushes it to dashboard clients
+func (s *Server) recordMitigation(event string, action models.MitigationAction) {
+	recorded := s.appendMitigationEvent(models.MitigationEvent{
+		Event:  event,
+		Actor:  mitigationActor(event, action),
+		Action: action,
+	})
+	s.sendNotification(notify.MitigationNotification(recorded))
+	broadcastMessage(map[string]interface{}{
+		"type":    "mitigation",
+		"payload": gin.H{"event": event, "action": action},
+	})
+}
+
+// recordMitigationFailure adds an executor failing on an action to the
+// audit trail and notifies it
+func (s *Server) recordMitigationFailure(action models.MitigationAction, executor string, err error) {
+	recorded := s.appendMitigationEvent(models.MitigationEvent{
+		Event:    mitigation.EventFailed,
+		Actor:    "mitigation",
+		Executor: executor,
+		Error:    err.Error(),
+		Action:   action,
+	})
+	s.sendNotification(notify.MitigationNotification(recorded))
+}
+
+func (s *Server) appendMitigationEvent(event models.MitigationEvent) models.MitigationEvent {
+	event.ID = uuid.New().String()
+	event.Timestamp = time.Now()
+	if err := s.redis.AppendMitigationEvent(event); err != nil {
+		log.Printf("Error recording mitigation event: %v", err)
+	}
+	return event
+}
+
+// mitigationActor names who or what is behind a change to the mitigations:
+// the operator, the policy rule, or the confidence thresholds
+func mitigationActor(event string, action models.MitigationAction) string {
+	switch {
+	case event == mitigation.EventLifted:
+		return action.LiftedBy
+	case event == mitigation.EventExpired:
+		return "mitigation"
+	case action.AppliedBy != "":
+		return action.AppliedBy
+	case action.Policy != "":
+		return "policy " + action.Policy
+	default:
+		return "confidence thresholds"
+	}
+}
+
+// listMitigations lists the mitigations in force, optionally those of one
+// attack (?attack_id=) or of one type (?type=)
+func (s *Server) listMitigations(c *gin.Context) {
+	actions, _, err := s.redis.GetActiveMitigations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attackID, actionType := c.Query("attack_id"), strings.ToUpper(c.Query("type"))
+	matching := make([]models.MitigationAction, 0, len(actions))
+	for _, action := range actions {
+		if (attackID == "" || action.AttackID == attackID) && (actionType == "" || action.Type == actionType) {
+			matching = append(matching, action)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mitigations": matching,
+		"count":       len(matching),
+	})
+}
+
+// getMitigation returns one mitigation, in force or expired but not yet lifted
+func (s *Server) getMitigation(c *gin.Context) {
+	action, err := s.redis.GetMitigation(c.Param("id"))
+	if errors.Is(err, storage.ErrMitigationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "mitigation not found", "id": c.Param("id")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, action)
+}
+
+// mitigationRequest puts a target under an action by hand
+type mitigationRequest struct {
+	Target   string `json:"target"`
+	Type     string `json:"type"`     // BLOCK (default) or RATE_LIMIT
+	Duration string `json:"duration"` // e.g. 30m; the policy's duration when empty
+	Reason   string `json:"reason"`
+}
+
+// createMitigation blocks or rate limits an address or CIDR by hand,
+// through the same executors as the engine's own actions
+func (s *Server) createMitigation(c *gin.Context) {
+	var req mitigationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Type = strings.ToUpper(req.Type)
+	if req.Type == "" {
+		req.Type = mitigation.ActionBlock
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid duration %q", req.Duration)})
+			return
+		}
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "manual"
+	}
+	entry := models.ActionAudit{
+		Action: "create_mitigation",
+		Params: map[string]string{"target": req.Target, "type": req.Type, "duration": req.Duration, "reason": reason},
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+
+	action, err := s.mitigations.Block(req.Target, req.Type, duration, reason, principal.Name)
+	if err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, mitigation.ErrAllowlisted):
+			status = http.StatusConflict
+		case errors.Is(err, mitigation.ErrInvalidAction):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+	entry.Params["id"] = action.ID
+	entry.Success = true
+	entry = s.auditAction(entry)
+	log.Printf("🛡️  %s applied %s of %s for %s", principal.Name, action.Type, action.Target, action.Duration)
+
+	c.JSON(http.StatusCreated, gin.H{"mitigation": action, "audit_id": entry.ID})
+}
+
+// deleteMitigation lifts an action, manual or the engine's, before it expires
+func (s *Server) deleteMitigation(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := models.ActionAudit{
+		Action: "delete_mitigation",
+		Params: map[string]string{"id": c.Param("id")},
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+
+	action, err := s.mitigations.Lift(c.Param("id"), principal.Name)
+	if errors.Is(err, mitigation.ErrActionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "mitigation not found", "id": c.Param("id")})
+		return
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+	entry.Params["target"] = action.Target
+	entry.Params["type"] = action.Type
+	entry.Success = true
+	entry = s.auditAction(entry)
+	log.Printf("🛡️  %s lifted %s of %s", principal.Name, action.Type, action.Target)
+
+	c.JSON(http.StatusOK, gin.H{"mitigation": action, "audit_id": entry.ID})
+}
+
+// syncKillSwitch picks up the kill switch of automatic mitigation as
+// engaged or released through any node
+func (s *Server) syncKillSwitch() {
+	engaged, err := s.redis.MitigationKillSwitch()
+	if err != nil {
+		log.Printf("Error reading the mitigation kill switch: %v", err)
+		return
+	}
+	s.setKillSwitch(engaged, "kill switch")
+}
+
+// setKillSwitch engages or releases the kill switch on this node, returning
+// the automatic actions it lifted
+func (s *Server) setKillSwitch(engaged bool, by string) []models.MitigationAction {
+	if s.mitigations.KillSwitch() == engaged {
+		return nil
+	}
+	lifted, err := s.mitigations.SetKillSwitch(engaged, by)
+	if err != nil {
+		log.Printf("Error lifting automatic mitigations: %v", err)
+	}
+	if engaged {
+		log.Printf("🛑 Automatic mitigation stopped by the kill switch; %d actions lifted", len(lifted))
+	} else {
+		log.Printf("▶️  Automatic mitigation resumed")
+	}
+	return lifted
+}
+
+// getMitigationPolicy returns the policy detected attacks are acted on by
+func (s *Server) getMitigationPolicy(c *gin.Context) {
+	policy := s.mitigations.Policy()
+	rules := policy.Rules
+	if rules == nil {
+		rules = []mitigation.PolicyRule{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"auto":                  s.autoMitigate,
+		"kill_switch":           s.mitigations.KillSwitch(),
+		"policies":              rules,
+		"block_confidence":      policy.BlockConfidence,
+		"rate_limit_confidence": policy.RateLimitConfidence,
+		"duration":              policy.Duration.String(),
+		"rtbh":                  policy.RTBH,
+		"max_blocked":           policy.MaxBlocked,
+	})
+}
+
+// killSwitchRequest engages or releases the kill switch
+type killSwitchRequest struct {
+	Engaged *bool `json:"engaged"`
+}
+
+// putMitigationKillSwitch stops or resumes automatic mitigation on every
+// node. Stopping it lifts the actions taken for attacks; manual actions
+// stay in force and can still be taken.
+func (s *Server) putMitigationKillSwitch(c *gin.Context) {
+	var req killSwitchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Engaged == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `expected {"engaged": true} or {"engaged": false}`})
+		return
+	}
+
+	// Wait out a running cycle so it can't act after the switch is engaged
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := models.ActionAudit{
+		Action: "set_mitigation_kill_switch",
+		Params: map[string]string{"engaged": strconv.FormatBool(*req.Engaged)},
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+	if err := s.redis.SetMitigationKillSwitch(*req.Engaged); err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+	lifted := s.setKillSwitch(*req.Engaged, principal.Name)
+
+	entry.Params["lifted"] = strconv.Itoa(len(lifted))
+	entry.Success = true
+	entry = s.auditAction(entry)
+	log.Printf("🛑 %s set the mitigation kill switch to %t", principal.Name, *req.Engaged)
+
+	c.JSON(http.StatusOK, gin.H{"kill_switch": *req.Engaged, "lifted": len(lifted), "audit_id": entry.ID})
+}
+
+// getMitigationHistory returns the mitigation audit trail, newest first,
+// optionally of one action (?action_id=), attack (?attack_id=) or event
+// (?event=)
+func (s *Server) getMitigationHistory(c *gin.Context) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	actionID, attackID, event := c.Query("action_id"), c.Query("attack_id"), strings.ToLower(c.Query("event"))
+	events, err := s.redis.GetMitigationEvents(limit, func(e models.MitigationEvent) bool {
+		return (actionID == "" || e.Action.ID == actionID) &&
+			(attackID == "" || e.Action.AttackID == attackID) &&
+			(event == "" || e.Event == event)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// trafficWindow is the traffic from a mitigation's target over the minutes
+// before or after it was applied
+type trafficWindow struct {
+	From              time.Time `json:"from"`
+	To                time.Time `json:"to"`
+	Minutes           int       `json:"minutes"` // with traffic data
+	Requests          int64     `json:"requests"`
+	RequestsPerMinute float64   `json:"requests_per_minute"`
+}
+
+// sourceTraffic totals the requests from a prefix over the whole minutes
+// from..to
+func (s *Server) sourceTraffic(prefix netip.Prefix, from, to time.Time) (trafficWindow, error) {
+	window := trafficWindow{From: from, To: to.Add(time.Minute)}
+	if to.Before(from) {
+		return window, nil
+	}
+	counts, err := s.redis.GetSourceRequests(prefix, from, to)
+	if err != nil {
+		return window, err
+	}
+	for _, count := range counts {
+		window.Requests += count
+	}
+	window.Minutes = len(counts)
+	if window.Minutes > 0 {
+		window.RequestsPerMinute = math.Round(float64(window.Requests)/float64(window.Minutes)*10) / 10
+	}
+	return window, nil
+}
+
+// getMitigationEffect compares the traffic from an action's target in the
+// minutes before and after it was applied, up to effectWindow each way,
+// while the action was in force. Lifted and expired actions are found in
+// the audit trail, as long as the traffic counters are still held.
+func (s *Server) getMitigationEffect(c *gin.Context) {
+	id := c.Param("id")
+	var action models.MitigationAction
+	end := time.Now()
+	current, err := s.redis.GetMitigation(id)
+	switch {
+	case err == nil:
+		action = *current
+	case errors.Is(err, storage.ErrMitigationNotFound):
+		events, err := s.redis.GetMitigationEvents(1, func(e models.MitigationEvent) bool {
+			return e.Action.ID == id && e.Event != mitigation.EventFailed
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(events) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "mitigation not found", "id": id})
+			return
+		}
+		action = events[0].Action
+		if !action.Active {
+			end = events[0].Timestamp
+		}
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix, err := iplist.ParseCIDR(action.Target)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "id": id})
+		return
+	}
+
+	// Only whole minutes are compared, leaving out the one it was applied in
+	applied := action.AppliedAt.Truncate(time.Minute)
+	before, err := s.sourceTraffic(prefix, applied.Add(-effectWindow), applied.Add(-time.Minute))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	afterTo := end.Truncate(time.Minute).Add(-time.Minute)
+	if limit := applied.Add(effectWindow); afterTo.After(limit) {
+		afterTo = limit
+	}
+	after, err := s.sourceTraffic(prefix, applied.Add(time.Minute), afterTo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"id":         action.ID,
+		"type":       action.Type,
+		"target":     action.Target,
+		"dry_run":    action.DryRun,
+		"applied_at": action.AppliedAt,
+		"before":     before,
+		"after":      after,
+	}
+	switch {
+	case before.Minutes == 0:
+		response["summary"] = "no traffic data from before the action; counters are held for an hour"
+	case before.Requests == 0:
+		response["summary"] = "no traffic from the target before the action"
+	case after.Minutes == 0:
+		response["summary"] = "no traffic data since the action yet"
+	default:
+		reduction := (1 - after.RequestsPerMinute/before.RequestsPerMinute) * 100
+		response["reduction_percent"] = math.Round(reduction*10) / 10
+		if reduction >= 0 {
+			response["summary"] = fmt.Sprintf("attack traffic reduced by %.0f%%", reduction)
+		} else {
+			response["summary"] = fmt.Sprintf("attack traffic grew by %.0f%%", -reduction)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// enforceCheckRequest asks whether a request from a client should be let
+// through
+type enforceCheckRequest struct {
+	IP string `json:"ip"`
+}
+
+// checkEnforcement gives reverse proxies the authoritative decision on a
+// request: blocked clients are refused, rate limited ones take a token
+// from their bucket and are refused once it runs dry
+func (s *Server) checkEnforcement(c *gin.Context) {
+	var req enforceCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	verdict, err := s.enforcer.Check(req.IP)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if verdict.RateLimit != nil && verdict.RateLimit.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(verdict.RateLimit.RetryAfter))))
+	}
+
+	c.JSON(http.StatusOK, verdict)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// mockFrameInterval is how often the mock replays a fixture minute
+const mockFrameInterval = 2 * time.Second
+
+// mockFixtures is the pre-generated data served in mock mode
+type mockFixtures struct {
+	metrics     []models.Metrics // one per minute, oldest first
+	active      []models.Attack
+	history     []models.Attack
+	timelines   map[string][]models.TimelineEvent
+	mitigations []models.MitigationAction
+}
+
+// newMockFixtures generates an hour of traffic with attacks from a seed,
+// the hour starting at epoch; the same seed always yields the same data
+func newMockFixtures(seed int64, epoch time.Time) *mockFixtures {
+	rng := rand.New(rand.NewSource(seed))
+	fixtures := &mockFixtures{timelines: make(map[string][]models.TimelineEvent)}
+
+	attackTypes := []string{"HTTP_FLOOD", "SYN_FLOOD", "UDP_FLOOD", "SLOWLORIS", "DNS_AMPLIFICATION", "DNS_WATER_TORTURE"}
+	paths := []string{"/", "/api/users", "/api/products", "/login", "/search"}
+
+	for minute := 0; minute < 60; minute++ {
+		// Diurnal-looking baseline with an attack spike in minutes 40-49
+		rps := 100 + 20*math.Sin(float64(minute)/6) + rng.Float64()*10
+		uniqueIPs := 40 + rng.Intn(20)
+		underAttack := minute >= 40 && minute < 50
+		if underAttack {
+			rps *= 8 + rng.Float64()*4
+			uniqueIPs += 200
+		}
+		total := int(rps * 60)
+
+		// Ten sources make up the total, as the store reports it
+		topIPs := make([]models.IPCount, 0, 10)
+		remaining := total
+		for i := 0; i < 10; i++ {
+			count := remaining / 3
+			if i == 9 {
+				count = remaining
+			}
+			remaining -= count
+			topIPs = append(topIPs, models.IPCount{
+				IP:         mockIP(rng),
+				Count:      count,
+				Percentage: float64(count) / float64(total) * 100,
+			})
+		}
+
+		topPaths := make([]models.PathCount, 0, len(paths))
+		for i, path := range paths {
+			topPaths = append(topPaths, models.PathCount{Path: path, Count: total / (2 + i*3)})
+		}
+
+		ipEntropy := 5.2 + rng.Float64()*0.6
+		if underAttack {
+			ipEntropy = 2.1 + rng.Float64()*0.5
+		}
+
+		fixtures.metrics = append(fixtures.metrics, models.Metrics{
+			Timestamp:      epoch.Add(time.Duration(minute) * time.Minute),
+			WindowDuration: 60,
+			TotalRequests:  total,
+			UniqueIPs:      uniqueIPs,
+			RequestsPerSec: rps,
+			BytesPerSec:    rps * float64(500+rng.Intn(300)),
+			IPEntropy:      ipEntropy,
+			PathEntropy:    2.0 + rng.Float64(),
+			TopIPs:         topIPs,
+			TopPaths:       topPaths,
+			ProtocolBreakdown: map[string]int{
+				"HTTP": total * 7 / 10,
+				"TCP":  total * 2 / 10,
+				"UDP":  total / 10,
+			},
+			MethodBreakdown: map[string]int{
+				"GET":  total * 6 / 10,
+				"POST": total / 10,
+			},
+			StatusCodeDist:  map[int]int{200: total * 9 / 10, 404: total / 20, 503: total / 20},
+			AvgConnDuration: 120 + rng.Float64()*60,
+		})
+	}
+
+	for i := 0; i < 8; i++ {
+		start := epoch.Add(time.Duration(i*7) * time.Minute)
+		confidence := 0.5 + rng.Float64()*0.5
+		attack := models.Attack{
+			ID:          fmt.Sprintf("mock-attack-%02d", i),
+			Type:        attackTypes[rng.Intn(len(attackTypes))],
+			Confidence:  confidence,
+			Severity:    mockSeverity(confidence),
+			StartTime:   start,
+			SourceIPs:   []string{mockIP(rng), mockIP(rng), mockIP(rng)},
+			TargetIPs:   []string{"192.168.1.100"},
+			Environment: detection.DefaultEnvironment,
+		}
+		attack.Description = fmt.Sprintf("Mock %s at %.0f%% confidence", attack.Type, confidence*100)
+
+		fixtures.timelines[attack.ID] = []models.TimelineEvent{
+			{Timestamp: start, Type: "DETECTED", Message: attack.Description, Actor: "detector"},
+		}
+
+		// The last two attacks are still in progress, their busiest source blocked
+		if i >= 6 {
+			fixtures.active = append(fixtures.active, attack)
+			fixtures.mitigations = append(fixtures.mitigations, models.MitigationAction{
+				ID:          fmt.Sprintf("mock-mitigation-%02d", i),
+				Type:        "BLOCK",
+				Target:      attack.SourceIPs[0],
+				Duration:    time.Hour,
+				Reason:      attack.Description,
+				AttackID:    attack.ID,
+				AppliedAt:   start,
+				ExpiresAt:   start.Add(24 * time.Hour),
+				Active:      true,
+				Confidence:  attack.Confidence,
+				AttackTypes: attack.Types(),
+				Policy:      "mock",
+			})
+			continue
+		}
+
+		end := start.Add(time.Duration(2+rng.Intn(4)) * time.Minute)
+		attack.EndTime = &end
+		attack.Mitigated = true
+		fixtures.history = append(fixtures.history, attack)
+		fixtures.timelines[attack.ID] = append(fixtures.timelines[attack.ID], models.TimelineEvent{
+			Timestamp: end, Type: "ENDED", Message: "Traffic returned to baseline", Actor: "detector",
+		})
+	}
+
+	return fixtures
+}
+
+func mockIP(rng *rand.Rand) string {
+	return fmt.Sprintf("203.0.%d.%d", rng.Intn(256), 1+rng.Intn(254))
+}
+
+func mockSeverity(confidence float64) string {
+	switch {
+	case confidence >= 0.9:
+		return "CRITICAL"
+	case confidence >= 0.7:
+		return "HIGH"
+	case confidence >= 0.5:
+		return "MEDIUM"
+	}
+	return "LOW"
+}
+
+// runMock serves the dashboard API from fixtures, with no Redis, ingestion
+// or analysis, for front-end development. The fixtures are loaded into an
+// in-memory store behind the real routes, so every endpoint answers as it
+// would in production.
+func runMock(seed int64, addr string, analysisInterval time.Duration, corsOrigins []string) {
+	store := miniredis.NewMiniRedis()
+	if err := store.StartAddr("127.0.0.1:0"); err != nil {
+		log.Fatalf("Failed to start the fixture store: %v", err)
+	}
+
+	server, err := NewServer(store.Addr(), analysisInterval, corsOrigins)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+	server.detectors.Get(detection.DefaultEnvironment)
+
+	// The fixture hour is the one just gone, so endpoints reading the
+	// last hour find it
+	fixtures := newMockFixtures(seed, time.Now().Truncate(time.Minute).Add(-time.Hour))
+	if err := fixtures.load(server.redis); err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+	go fixtures.replay(server.redis)
+
+	log.Printf("🧪 Mock mode (seed %d): serving fixtures on %s, no Redis required", seed, addr)
+	if err := server.router.Run(addr); err != nil {
+		log.Fatalf("Failed to start mock server: %v", err)
+	}
+}
+
+// load stores the fixtures as the analysis engine would have
+func (f *mockFixtures) load(store *storage.RedisClient) error {
+	for _, metrics := range f.metrics {
+		if err := store.SeedMetrics(metrics); err != nil {
+			return err
+		}
+	}
+
+	for _, attack := range append(append([]models.Attack{}, f.history...), f.active...) {
+		if err := store.StoreAttack(attack); err != nil {
+			return err
+		}
+		if attack.EndTime != nil {
+			if err := store.EndAttack(attack); err != nil {
+				return err
+			}
+		}
+		for _, event := range f.timelines[attack.ID] {
+			if err := store.AppendTimelineEvent(attack.ID, event); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, action := range f.mitigations {
+		if err := store.StoreMitigation(action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replay plays the fixture hour back in a loop, a minute per frame: each
+// becomes the current minute's metrics and is broadcast, along with an
+// alert for each attack that began in it
+func (f *mockFixtures) replay(store *storage.RedisClient) {
+	epoch := f.metrics[0].Timestamp
+	attacksByMinute := make(map[int][]models.Attack)
+	for _, attack := range append(append([]models.Attack{}, f.history...), f.active...) {
+		minute := int(attack.StartTime.Sub(epoch) / time.Minute)
+		attacksByMinute[minute] = append(attacksByMinute[minute], attack)
+	}
+
+	ticker := time.NewTicker(mockFrameInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		minute := frame % len(f.metrics)
+		metrics := f.metrics[minute]
+		metrics.Timestamp = time.Now()
+		if err := store.SeedMetrics(metrics); err != nil {
+			log.Printf("Error replaying fixture minute %d: %v", minute, err)
+		}
+
+		broadcastMessage(map[string]interface{}{
+			"type":    "metrics",
+			"payload": metrics,
+		})
+		for _, attack := range attacksByMinute[minute] {
+			broadcastMessage(map[string]interface{}{
+				"type": "alert",
+				"payload": models.Alert{
+					ID:         attack.ID,
+					Level:      "CRITICAL",
+					Title:      fmt.Sprintf("%s Attack Detected", attack.Type),
+					Message:    attack.Description,
+					AttackType: attack.Type,
+					Timestamp:  metrics.Timestamp,
+				},
+			})
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !bundle
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+)
+
+const defaultRedisAddr = "localhost:6379"
+
+// startEmbeddedStore is only available in bundle builds
+func startEmbeddedStore() (string, error) {
+	return "", fmt.Errorf("no Redis address given and the embedded store requires a bundle build (go build -tags bundle)")
+}
+
+// serveUI serves the dashboard from the working directory
+func serveUI(router *gin.Engine) {
+	router.StaticFile("/", "./web/index.html")
+}
+
+// firstRunTokens only makes credentials in bundle builds
+func firstRunTokens() (*auth.TokenStore, error) {
+	return nil, errors.New("-auth-required needs -api-tokens or -users-file; set -auth-required=false to serve reads without credentials")
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+)
+
+// maxDigestAttacks bounds the ended attacks read for an email digest
+const maxDigestAttacks = 2000
+
+// faultyNotifier injects chaos-mode notifier timeouts ahead of a notifier
+type faultyNotifier struct {
+	notify.Notifier
+}
+
+func (n faultyNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if err := faults.NotifierFault(ctx); err != nil {
+		return err
+	}
+	return n.Notifier.Notify(ctx, notification)
+}
+
+// notifyAttack tells the external notifiers of a detected attack
+func (s *Server) notifyAttack(attack models.Attack) {
+	s.sendNotification(notify.AttackNotification(notify.EventAttackStarted, attack))
+}
+
+// sendNotification hands a notification to every notifier subscribed to
+// its event, or along the route its attack matches, without blocking
+// analysis
+func (s *Server) sendNotification(notification models.Notification) {
+	if s.routeNotification(notification) {
+		return
+	}
+	for _, notifier := range s.notifiers {
+		if notifier.Subscribes(notification.Event) {
+			go s.deliver(notifier, notification)
+		}
+	}
+}
+
+// deliver notifies one destination with retries, recording every attempt
+// and queueing the notification as a dead letter if it fails for good
+func (s *Server) deliver(notifier notify.Notifier, notification models.Notification) models.NotificationDelivery {
+	final := notify.Deliver(context.Background(), faultyNotifier{notifier}, notification, notify.DefaultRetryPolicy(), s.recordDelivery)
+	if final.Status != notify.StatusFailed {
+		return final
+	}
+
+	log.Printf("📪 Giving up notifying %s of %s after %d attempts: %s", final.Channel, notification.Event, final.Attempt, final.Error)
+	letter := models.DeadLetter{
+		ID:           final.ID,
+		Channel:      final.Channel,
+		Notification: notification,
+		Attempts:     final.Attempt,
+		Error:        final.Error,
+		FailedAt:     final.Timestamp,
+	}
+	if err := s.redis.AddDeadLetter(letter); err != nil {
+		log.Printf("Error queueing dead letter for %s: %v", notification.Event, err)
+	}
+	return final
+}
+
+// recordDelivery stores a notification attempt in the delivery log
+func (s *Server) recordDelivery(delivery models.NotificationDelivery) {
+	if delivery.Status == notify.StatusRetrying {
+		log.Printf("Error notifying %s of %s (attempt %d, retrying): %s", delivery.Channel, delivery.Event, delivery.Attempt, delivery.Error)
+	}
+	if err := s.redis.AppendDelivery(delivery); err != nil {
+		log.Printf("Error recording delivery to %s of %s: %v", delivery.Channel, delivery.Event, err)
+	}
+}
+
+// redeliverDeadLetters retries dead letters (all, or the one with the given
+// ID) through the notifier of their channel, removing the delivered ones
+func (s *Server) redeliverDeadLetters(id string) (gin.H, error) {
+	letters, err := s.redis.GetDeadLetters()
+	if err != nil {
+		return nil, err
+	}
+
+	notifiers := make(map[string]notify.Notifier, len(s.notifiers))
+	for _, notifier := range s.notifiers {
+		notifiers[notifier.Name()] = notifier
+	}
+
+	delivered, failed, unknown := 0, 0, 0
+	for _, letter := range letters {
+		if id != "" && letter.ID != id {
+			continue
+		}
+		notifier, ok := notifiers[letter.Channel]
+		if !ok {
+			unknown++
+			continue
+		}
+
+		// A repeated failure is queued again under the new attempt's ID
+		if err := s.redis.RemoveDeadLetter(letter.ID); err != nil {
+			return nil, err
+		}
+		if letter.Event == "" {
+			letter.Event = notify.EventAttackStarted
+		}
+		if s.deliver(notifier, letter.Notification).Status == notify.StatusFailed {
+			failed++
+		} else {
+			delivered++
+		}
+	}
+
+	return gin.H{"delivered": delivered, "failed": failed, "unconfigured_channel": unknown}, nil
+}
+
+// getNotificationDeliveries lists recent notification attempts, newest
+// first, optionally for one attack, channel or status
+func (s *Server) getNotificationDeliveries(c *gin.Context) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+	attackID, channel, status := c.Query("attack_id"), c.Query("channel"), c.Query("status")
+
+	// Filters apply to the whole log, not just the latest entries
+	fetch := limit
+	if attackID != "" || channel != "" || status != "" {
+		fetch = 0
+	}
+	deliveries, err := s.redis.GetDeliveries(fetch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered := make([]models.NotificationDelivery, 0, limit)
+	for _, delivery := range deliveries {
+		if len(filtered) == limit {
+			break
+		}
+		if (attackID == "" || delivery.AttackID == attackID) &&
+			(channel == "" || delivery.Channel == channel) &&
+			(status == "" || delivery.Status == status) {
+			filtered = append(filtered, delivery)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": filtered,
+	})
+}
+
+// getDeadLetters lists the notifications that failed for good
+func (s *Server) getDeadLetters(c *gin.Context) {
+	letters, err := s.redis.GetDeadLetters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+	})
+}
+
+// runEmailDigest emails the daily digest every day at hour:minute in loc,
+// covering the attacks started in the 24 hours before, until ctx is done
+func (s *Server) runEmailDigest(ctx context.Context, hour, minute int, loc *time.Location) {
+	for {
+		now := time.Now().In(loc)
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		if !next.After(now) {
+			next = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, loc)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		// Every node schedules the digest; the first to claim the day sends it
+		claimed, err := s.redis.ClaimDigest(tz.DayKey(next, loc))
+		if err != nil {
+			log.Printf("Error claiming the email digest: %v", err)
+			continue
+		}
+		if claimed {
+			s.sendEmailDigest(next.AddDate(0, 0, -1), next)
+		}
+	}
+}
+
+// sendEmailDigest emails the digest of the attacks started between from
+// and to, returning the delivery
+func (s *Server) sendEmailDigest(from, to time.Time) (models.NotificationDelivery, error) {
+	active, err := s.redis.GetActiveAttacks()
+	if err != nil {
+		return models.NotificationDelivery{}, err
+	}
+	history, err := s.redis.GetAttackHistory(maxDigestAttacks)
+	if err != nil {
+		return models.NotificationDelivery{}, err
+	}
+
+	attacks := make([]models.Attack, 0)
+	for _, attack := range append(active, history...) {
+		if !attack.StartTime.Before(from) && attack.StartTime.Before(to) {
+			attacks = append(attacks, attack)
+		}
+	}
+
+	notification := models.Notification{EventID: uuid.New().String(), Event: notify.EventDigest, Timestamp: time.Now()}
+	final := notify.Deliver(context.Background(), s.email.Digest(attacks, from, to), notification, notify.DefaultRetryPolicy(), s.recordDelivery)
+	if final.Status == notify.StatusFailed {
+		log.Printf("📪 Giving up emailing the digest after %d attempts: %s", final.Attempt, final.Error)
+	} else {
+		log.Printf("📧 Emailed the digest of %d attacks since %s", len(attacks), from.Format(time.RFC3339))
+	}
+	return final, nil
+}
+
+// runAlertmanagerResend sends Alertmanager the alerts of the active attacks
+// every resend interval, until ctx is done. Failures are only logged: the
+// next resend makes up for them before the alerts lapse.
+func (s *Server) runAlertmanagerResend(ctx context.Context) {
+	ticker := time.NewTicker(s.alertmanager.ResendInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		active, err := s.redis.GetActiveAttacks()
+		if err != nil {
+			log.Printf("Error loading active attacks for Alertmanager: %v", err)
+			continue
+		}
+		if err := s.alertmanager.Resend(ctx, active); err != nil {
+			log.Printf("Error resending alerts to Alertmanager: %v", err)
+		}
+	}
+}
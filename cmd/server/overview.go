@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+)
+
+const (
+	// notifierErrorWindow is how far back failed notification attempts are counted
+	notifierErrorWindow = time.Hour
+	// maxOverviewDeliveries bounds the delivery log scanned for them
+	maxOverviewDeliveries = 500
+)
+
+// systemOverview gathers the health of the detection pipeline itself:
+// whether traffic is arriving, backing up or being analyzed in time, how
+// full Redis is and whether notifications get out. Parts that can't be
+// read report an error in place of their values.
+func (s *Server) systemOverview(ctx context.Context) gin.H {
+	now := time.Now()
+	overview := gin.H{
+		"timestamp": now,
+		"node_id":   s.nodeID,
+		"leader":    s.isLeader.Load(),
+		"detection": s.load.Status(),
+	}
+
+	// Ingest rate over the last complete minute, from the shared counters;
+	// a minute without traffic has none. Sources in bogon space are either
+	// spoofed or a producer reporting its own side of a NAT or proxy.
+	var lastMinute, bogons int64
+	if metrics, err := s.redis.GetMetrics(now.Add(-time.Minute)); err == nil {
+		lastMinute = int64(metrics.TotalRequests)
+		for _, n := range metrics.BogonSources {
+			bogons += int64(n)
+		}
+	}
+	overview["ingest"] = gin.H{
+		"requests_last_minute":       lastMinute,
+		"requests_per_second":        float64(lastMinute) / 60,
+		"bogon_requests_last_minute": bogons,
+	}
+
+	queues := gin.H{}
+	if s.stream != nil {
+		queues["stream_window_requests"] = s.stream.Requests()
+	}
+	if s.kafka != nil {
+		lagCtx, cancel := context.WithTimeout(ctx, time.Second)
+		lag, err := s.kafka.Lag(lagCtx)
+		cancel()
+		if err != nil {
+			queues["kafka_error"] = err.Error()
+		} else {
+			queues["kafka_lag"] = lag
+		}
+	}
+	if s.nats != nil {
+		pendingCtx, cancel := context.WithTimeout(ctx, time.Second)
+		pending, err := s.nats.Pending(pendingCtx)
+		cancel()
+		if err != nil {
+			queues["nats_error"] = err.Error()
+		} else {
+			queues["nats_pending"] = pending
+		}
+	}
+	overview["queues"] = queues
+
+	if memory, err := s.redis.MemoryUsage(); err != nil {
+		overview["redis"] = gin.H{"error": err.Error()}
+	} else {
+		overview["redis"] = memory
+	}
+
+	overview["sensors"] = s.sensors.status(now)
+
+	// Notification failures: attempts that failed recently, and those that
+	// gave up and wait as dead letters
+	notifiers := gin.H{"configured": len(s.notifiers)}
+	if deliveries, err := s.redis.GetDeliveries(maxOverviewDeliveries); err != nil {
+		notifiers["error"] = err.Error()
+	} else {
+		failures := 0
+		for _, delivery := range deliveries {
+			if now.Sub(delivery.Timestamp) > notifierErrorWindow {
+				break
+			}
+			if delivery.Status != notify.StatusRetrying && delivery.Status != notify.StatusFailed {
+				continue
+			}
+			if failures == 0 {
+				notifiers["last_error"] = delivery.Error
+				notifiers["last_error_at"] = delivery.Timestamp
+				notifiers["last_error_channel"] = delivery.Channel
+			}
+			failures++
+		}
+		notifiers["errors_last_hour"] = failures
+	}
+	if count, err := s.redis.CountDeadLetters(); err != nil {
+		notifiers["dead_letters_error"] = err.Error()
+	} else {
+		notifiers["dead_letters"] = count
+	}
+	overview["notifiers"] = notifiers
+
+	// Requests refused to protect the API itself
+	overview["rejected_requests"] = s.rejections.status()
+	if limits := s.rateLimitStatus(); len(limits) > 0 {
+		overview["rate_limits"] = limits
+	}
+	if legacy := s.legacyAPIUsage.status(); len(legacy) > 0 {
+		overview["legacy_api_requests"] = legacy
+	}
+
+	return overview
+}
+
+// getSystemOverview reports the health of the detection pipeline itself
+func (s *Server) getSystemOverview(c *gin.Context) {
+	c.JSON(http.StatusOK, s.systemOverview(c.Request.Context()))
+}
+
+// startOverviewBroadcast pushes the system overview to dashboard clients
+// every interval until ctx is done
+func (s *Server) startOverviewBroadcast(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !hasWebSocketClients() {
+			continue
+		}
+		broadcastMessage(map[string]interface{}{
+			"type":    "system_overview",
+			"payload": s.systemOverview(ctx),
+		})
+	}
+}
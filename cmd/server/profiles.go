@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getSourceProfile returns the learned behavior of a source in ?environment=
+func (s *Server) getSourceProfile(c *gin.Context) {
+	ip := c.Param("ip")
+	if _, err := netip.ParseAddr(ip); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip must be an IP address"})
+		return
+	}
+
+	profiles, err := s.redis.SourceProfiles(c.Query("environment"), []string{ip})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	profile, ok := profiles[ip]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no profile for " + ip})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
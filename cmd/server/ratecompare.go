@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+)
+
+// comparisonHours is how many local hours the comparison sparklines cover,
+// ending with the current one
+const comparisonHours = 7
+
+// compareRate adds the same hours yesterday and a week ago to a rate
+// anomaly, from the hourly rollups, and mentions them in its description
+func (s *Server) compareRate(attack models.Attack, now time.Time) models.Attack {
+	if attack.Type != "RATE_ANOMALY" || attack.Metrics == nil {
+		return attack
+	}
+
+	loc := s.redis.Zones().For(attack.Environment)
+	current := tz.StartOfHour(now, loc)
+	starts := make([]time.Time, comparisonHours)
+	for i := range starts {
+		starts[i] = current.Add(-time.Duration(comparisonHours-1-i) * time.Hour)
+	}
+	shifted := func(days int) []time.Time {
+		hours := make([]time.Time, len(starts))
+		for i, start := range starts {
+			hours[i] = start.AddDate(0, 0, -days)
+		}
+		return hours
+	}
+
+	today, err := s.hourlyRates(attack.Environment, starts, now)
+	if err != nil {
+		log.Printf("Error comparing rate anomaly %s: %v", attack.ID, err)
+		return attack
+	}
+	comparison := &models.RateComparison{
+		CurrentRPS: attack.Metrics.RequestsPerSec,
+		HourStarts: starts,
+		Today:      today,
+	}
+	for _, period := range []struct {
+		days int
+		into *models.RatePeriod
+	}{{1, &comparison.Yesterday}, {7, &comparison.LastWeek}} {
+		hours := shifted(period.days)
+		rates, err := s.hourlyRates(attack.Environment, hours, now)
+		if err != nil {
+			log.Printf("Error comparing rate anomaly %s: %v", attack.ID, err)
+			return attack
+		}
+		*period.into = ratePeriod(comparison.CurrentRPS, hours[len(hours)-1], rates)
+	}
+
+	attack.RateComparison = comparison
+	attack.Description += fmt.Sprintf("; now %.0f req/s vs %s yesterday and %s a week ago",
+		comparison.CurrentRPS, describePeriod(comparison.Yesterday), describePeriod(comparison.LastWeek))
+	return attack
+}
+
+// hourlyRates returns the average req/s of each hour; an hour still in
+// progress is averaged over its elapsed part
+func (s *Server) hourlyRates(environment string, starts []time.Time, now time.Time) ([]float64, error) {
+	counts, err := s.redis.GetHourlyRequests(environment, starts)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]float64, len(counts))
+	for i, count := range counts {
+		seconds := time.Hour.Seconds()
+		if elapsed := now.Sub(starts[i]).Seconds(); elapsed < seconds {
+			seconds = max(elapsed, 1)
+		}
+		rates[i] = float64(count) / seconds
+	}
+	return rates, nil
+}
+
+// ratePeriod compares the current rate with the last of the period's hours
+func ratePeriod(current float64, hour time.Time, sparkline []float64) models.RatePeriod {
+	rps := sparkline[len(sparkline)-1]
+	period := models.RatePeriod{
+		HourStart: hour,
+		RPS:       rps,
+		DeltaRPS:  current - rps,
+		Sparkline: sparkline,
+	}
+	if rps > 0 {
+		percent := period.DeltaRPS / rps * 100
+		period.DeltaPercent = &percent
+	}
+	return period
+}
+
+// describePeriod summarizes a compared hour, e.g. "850 req/s (+12%)"
+func describePeriod(period models.RatePeriod) string {
+	if period.DeltaPercent == nil {
+		return "no traffic"
+	}
+	return fmt.Sprintf("%.0f req/s (%+.0f%%)", period.RPS, *period.DeltaPercent)
+}
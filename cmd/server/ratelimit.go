@@ -0,0 +1,157 @@
+package main
+
+import (
+	"log"
+	"maps"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ratelimit"
+)
+
+// Reasons API requests are rejected for
+const (
+	rejectRateLimitIP  = "rate_limit_ip"
+	rejectRateLimitKey = "rate_limit_key"
+	// Ingested traffic that doesn't parse, is too large or is out of
+	// bounds; traffic from other sources is counted under the source
+	rejectMalformedPayload = "malformed_payload"
+	rejectPayloadTooLarge  = "payload_too_large"
+	rejectInvalidTraffic   = "invalid_traffic"
+)
+
+// rejectionCounter counts the requests this node refused to protect
+// itself, by reason and by route or ingestion source, since it started
+type rejectionCounter struct {
+	mu       sync.Mutex
+	total    int64
+	byReason map[string]int64
+	byRoute  map[string]int64
+	last     time.Time
+}
+
+func newRejectionCounter() *rejectionCounter {
+	return &rejectionCounter{
+		byReason: make(map[string]int64),
+		byRoute:  make(map[string]int64),
+	}
+}
+
+// add counts one rejected request
+func (r *rejectionCounter) add(reason, route string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total++
+	r.byReason[reason]++
+	r.byRoute[route]++
+	r.last = now
+}
+
+// status reports the counts for the system overview
+func (r *rejectionCounter) status() gin.H {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := gin.H{
+		"total":     r.total,
+		"by_reason": maps.Clone(r.byReason),
+		"by_route":  maps.Clone(r.byRoute),
+	}
+	if !r.last.IsZero() {
+		status["last_rejected_at"] = r.last
+	}
+	return status
+}
+
+// limitClients holds each client address, or IPv6 /64, to -rate-limit-ip,
+// except the exempt ranges
+func (s *Server) limitClients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.ipLimiter == nil || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		ip := c.ClientIP()
+		if s.rateLimitExempt(ip) {
+			c.Next()
+			return
+		}
+		if !s.allowRequest(c, s.ipLimiter, ratelimit.ClientKey(ip), rejectRateLimitIP) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitKey holds each API token or user to -rate-limit-key, once per
+// request however often it is authenticated, reporting false when the
+// request was rejected
+func (s *Server) limitKey(c *gin.Context, principal auth.Principal) bool {
+	if s.keyLimiter == nil || c.GetBool("key_limited") {
+		return true
+	}
+	c.Set("key_limited", true)
+	return s.allowRequest(c, s.keyLimiter, principal.Name, rejectRateLimitKey)
+}
+
+// allowRequest takes a token for key, rejecting the request with 429 and a
+// Retry-After header when there is none
+func (s *Server) allowRequest(c *gin.Context, limiter *ratelimit.Limiter, key, reason string) bool {
+	now := time.Now()
+	decision := limiter.Allow(key, now)
+	if decision.Allowed {
+		return true
+	}
+
+	s.rejections.add(reason, c.FullPath(), now)
+	if decision.First {
+		log.Printf("🚦 Rate limiting %s on %s (%s)", key, c.FullPath(), reason)
+	}
+	retryAfter := max(1, int(math.Ceil(decision.RetryAfter.Seconds())))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":               "rate limit exceeded",
+		"reason":              reason,
+		"retry_after_seconds": retryAfter,
+	})
+	return false
+}
+
+// rateLimitExempt reports whether a client is in -rate-limit-exempt
+func (s *Server) rateLimitExempt(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range s.rateLimitExemptions {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitStatus reports the limits in force and the clients held to them
+func (s *Server) rateLimitStatus() gin.H {
+	status := gin.H{}
+	for name, limiter := range map[string]*ratelimit.Limiter{"ip": s.ipLimiter, "key": s.keyLimiter} {
+		if limiter == nil {
+			continue
+		}
+		perMinute, burst := limiter.Limits()
+		status[name] = gin.H{
+			"per_minute":      perMinute,
+			"burst":           burst,
+			"limited_clients": limiter.Limited(),
+		}
+	}
+	return status
+}
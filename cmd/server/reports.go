@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+)
+
+// getDailyStats returns the rollup of one local day (?date=YYYY-MM-DD,
+// default today) in the environment's time zone
+func (s *Server) getDailyStats(c *gin.Context) {
+	env := c.Query("environment")
+	day := s.reportDay(c, env)
+
+	summary, err := s.redis.GetDailySummary(env, day)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// getHourlyStats returns per-hour rollups of one local day
+func (s *Server) getHourlyStats(c *gin.Context) {
+	env := c.Query("environment")
+	day := s.reportDay(c, env)
+
+	hours, err := s.redis.GetHourlySummaries(env, day)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"environment": env,
+		"date":        day,
+		"time_zone":   s.redis.Zones().For(env).String(),
+		"hours":       hours,
+	})
+}
+
+// reportDay reads ?date, defaulting to the current day in the environment's zone
+func (s *Server) reportDay(c *gin.Context, env string) string {
+	if day := c.Query("date"); day != "" {
+		return day
+	}
+	return tz.DayKey(time.Now(), s.redis.Zones().For(env))
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// routeNotification sends an attack notification along the route its
+// attack matches, reporting false when no route applies and every
+// subscribed notifier should have it
+func (s *Server) routeNotification(notification models.Notification) bool {
+	if notification.Attack == nil {
+		return false
+	}
+	attack := *notification.Attack
+
+	s.routedMu.Lock()
+	defer s.routedMu.Unlock()
+
+	routed, err := s.redis.GetRoutedAttack(attack.ID)
+	if err != nil {
+		log.Printf("Error reading the routing of attack %s: %v", attack.ID, err)
+		return false
+	}
+
+	switch notification.Event {
+	case notify.EventAttackStarted, notify.EventAttackEscalated:
+	case notify.EventAttackEnded:
+		if routed == nil {
+			return false
+		}
+		s.notifyChannels(routed.Notified, notification)
+		if err := s.redis.RemoveRoutedAttack(attack.ID); err != nil {
+			log.Printf("Error removing the routing of attack %s: %v", attack.ID, err)
+		}
+		return true
+	default:
+		return false
+	}
+
+	route := s.currentRouting().Match(attack)
+	if route == nil && routed == nil {
+		return false
+	}
+	now := time.Now()
+	if route != nil && (routed == nil || routed.Route != route.Name) {
+		// Channels already told stay told; the new route starts over
+		var notified []string
+		if routed != nil {
+			notified = routed.Notified
+		}
+		routed = &models.RoutedAttack{AttackID: attack.ID, Route: route.Name, Since: now, Notified: notified}
+	}
+
+	routed.Notification = notification
+	s.notifyChannels(routed.Notified, notification)
+	if route != nil {
+		s.escalateRoute(routed, *route, now)
+	}
+	if err := s.redis.StoreRoutedAttack(*routed); err != nil {
+		log.Printf("Error storing the routing of attack %s: %v", attack.ID, err)
+	}
+	return true
+}
+
+// escalateNotifications moves the routed attacks whose alert is still open
+// on to the steps now due
+func (s *Server) escalateNotifications(now time.Time) {
+	s.routedMu.Lock()
+	defer s.routedMu.Unlock()
+
+	routedAttacks, err := s.redis.GetRoutedAttacks()
+	if err != nil {
+		log.Printf("Error reading routed attacks: %v", err)
+		return
+	}
+
+	routing := s.currentRouting()
+	for _, routed := range routedAttacks {
+		i := slices.IndexFunc(routing.Routes, func(r notify.Route) bool { return r.Name == routed.Route })
+		if i < 0 || routed.Steps >= len(routing.Routes[i].Steps) {
+			continue
+		}
+		// Someone acknowledging or resolving the alert stops escalation
+		alert, err := s.redis.GetAlert(routed.AttackID)
+		if err != nil && !errors.Is(err, storage.ErrAlertNotFound) {
+			log.Printf("Error reading the alert of attack %s: %v", routed.AttackID, err)
+			continue
+		}
+		if alert != nil && alert.Status != models.AlertOpen {
+			continue
+		}
+
+		if !s.escalateRoute(&routed, routing.Routes[i], now) {
+			continue
+		}
+		if err := s.redis.StoreRoutedAttack(routed); err != nil {
+			log.Printf("Error storing the routing of attack %s: %v", routed.AttackID, err)
+		}
+	}
+}
+
+// escalateRoute notifies the route's steps due by now that haven't been,
+// reporting whether there were any
+func (s *Server) escalateRoute(routed *models.RoutedAttack, route notify.Route, now time.Time) bool {
+	escalated := false
+	for routed.Steps < len(route.Steps) {
+		step := route.Steps[routed.Steps]
+		if routed.Since.Add(step.After).After(now) {
+			break
+		}
+		fresh := make([]string, 0, len(step.Channels))
+		for _, channel := range step.Channels {
+			if !slices.Contains(routed.Notified, channel) {
+				fresh = append(fresh, channel)
+			}
+		}
+		if step.After > 0 && len(fresh) > 0 {
+			log.Printf("📟 Escalating attack %s to %v: unacknowledged after %s", routed.AttackID, fresh, step.After)
+		}
+		s.notifyChannels(fresh, routed.Notification)
+		routed.Notified = append(routed.Notified, fresh...)
+		routed.Steps++
+		escalated = true
+	}
+	return escalated
+}
+
+// notifyChannels hands a notification to the named notifiers subscribed to
+// its event
+func (s *Server) notifyChannels(channels []string, notification models.Notification) {
+	for _, notifier := range s.notifiers {
+		if slices.Contains(channels, notifier.Name()) && notifier.Subscribes(notification.Event) {
+			go s.deliver(notifier, notification)
+		}
+	}
+}
+
+// currentRouting returns the notification routes in force
+func (s *Server) currentRouting() notify.Routing {
+	s.routingMu.RLock()
+	defer s.routingMu.RUnlock()
+	return s.routing
+}
+
+// syncRouting picks up the notification routes as changed through any node
+func (s *Server) syncRouting() {
+	data, err := s.redis.GetRouting()
+	if err != nil {
+		log.Printf("Error reading notification routes: %v", err)
+		return
+	}
+	routing, err := notify.ParseRouting(data)
+	if err != nil {
+		log.Printf("Error reading notification routes: %v", err)
+		return
+	}
+
+	s.routingMu.Lock()
+	s.routing = routing
+	s.routingMu.Unlock()
+}
+
+// setRouting puts routes in force on every node
+func (s *Server) setRouting(routing notify.Routing) error {
+	data, err := json.Marshal(routing)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.SetRouting(data); err != nil {
+		return err
+	}
+
+	s.routingMu.Lock()
+	s.routing = routing
+	s.routingMu.Unlock()
+	return nil
+}
+
+// getNotificationRoutes returns the notification routes and the attacks
+// being escalated along them
+func (s *Server) getNotificationRoutes(c *gin.Context) {
+	routed, err := s.redis.GetRoutedAttacks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	routes := s.currentRouting().Routes
+	if routes == nil {
+		routes = []notify.Route{}
+	}
+	escalating := make([]gin.H, 0, len(routed))
+	for _, r := range routed {
+		escalating = append(escalating, gin.H{
+			"attack_id": r.AttackID,
+			"route":     r.Route,
+			"since":     r.Since,
+			"steps":     r.Steps,
+			"notified":  r.Notified,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"routes": routes,
+		"routed": escalating,
+	})
+}
+
+// putNotificationRoutes replaces the notification routes with a YAML or
+// JSON routes document
+func (s *Server) putNotificationRoutes(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := models.ActionAudit{
+		Action: "set_notification_routes",
+		Params: map[string]string{"routes": string(body)},
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+
+	routing, err := notify.ParseRouting(body)
+	if err == nil {
+		err = routing.Check(s.notifiers)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+	if err := s.setRouting(routing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry.Success = true
+	entry = s.auditAction(entry)
+	log.Printf("📟 %s changed the notification routes: %d routes", principal.Name, len(routing.Routes))
+
+	c.JSON(http.StatusOK, gin.H{
+		"routes":   routing.Routes,
+		"audit_id": entry.ID,
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ingestion"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// sensorStaleAfter is how long a sensor may stay silent before it counts as down
+const sensorStaleAfter = time.Minute
+
+// SensorStatus is what this node has received from one ingestion source
+type SensorStatus struct {
+	Name     string    `json:"name"`
+	Requests int64     `json:"requests"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	Alive    bool      `json:"alive"`
+}
+
+// sensorTracker records when each ingestion source last delivered traffic
+type sensorTracker struct {
+	mu      sync.Mutex
+	sensors map[string]*SensorStatus
+}
+
+func newSensorTracker() *sensorTracker {
+	return &sensorTracker{sensors: make(map[string]*SensorStatus)}
+}
+
+// register lists a configured source before it has delivered anything, so
+// a sensor that never comes up shows as down rather than missing
+func (t *sensorTracker) register(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.sensors[name]; !ok {
+		t.sensors[name] = &SensorStatus{Name: name}
+	}
+}
+
+// seen records a request from a source
+func (t *sensorTracker) seen(name string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sensor, ok := t.sensors[name]
+	if !ok {
+		sensor = &SensorStatus{Name: name}
+		t.sensors[name] = sensor
+	}
+	sensor.Requests++
+	sensor.LastSeen = now
+}
+
+// status lists the sources by name, judging liveness at now
+func (t *sensorTracker) status(now time.Time) []SensorStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sensors := make([]SensorStatus, 0, len(t.sensors))
+	for _, sensor := range t.sensors {
+		status := *sensor
+		status.Alive = !status.LastSeen.IsZero() && now.Sub(status.LastSeen) < sensorStaleAfter
+		sensors = append(sensors, status)
+	}
+	sort.Slice(sensors, func(i, j int) bool { return sensors[i].Name < sensors[j].Name })
+	return sensors
+}
+
+// sensorSink is the sink one ingestion source stores through, so the
+// source's liveness is tracked on the way
+type sensorSink struct {
+	name   string
+	server *Server
+}
+
+// StoreTraffic checks a request from the source before storing it. One
+// out of bounds is counted and dropped rather than returned, so consumers
+// that retry failed writes don't stall on it.
+func (s sensorSink) StoreTraffic(req models.TrafficRequest) error {
+	now := time.Now()
+	ingestion.Sanitize(&req, now)
+	if err := s.server.ingestValidator.Validate(req, now); err != nil {
+		s.server.rejections.add(rejectInvalidTraffic, s.name, now)
+		return nil
+	}
+	return s.storeChecked(req, now)
+}
+
+// storeChecked stores a request already sanitized and validated
+func (s sensorSink) storeChecked(req models.TrafficRequest, now time.Time) error {
+	s.server.sensors.seen(s.name, now)
+	return s.server.StoreTraffic(req)
+}
+
+// sensor returns the sink for an ingestion source, registering the source
+func (s *Server) sensor(name string) sensorSink {
+	s.sensors.register(name)
+	return sensorSink{name: name, server: s}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// errNoCredentials is returned for a request without a bearer token
+var errNoCredentials = errors.New("invalid or missing bearer token")
+
+// loginRequest is the body of POST /api/auth/login
+type loginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// wsTicketTTL is how long a WebSocket ticket may wait to be used
+const wsTicketTTL = 30 * time.Second
+
+// redactedQueryParams are left out of the request log, as credentials
+var redactedQueryParams = []string{"access_token", "ticket"}
+
+// redactedLogFormatter is gin's default request log line with the
+// credentials in the query string redacted
+func redactedLogFormatter(param gin.LogFormatterParams) string {
+	if path, query, ok := strings.Cut(param.Path, "?"); ok {
+		if values, err := url.ParseQuery(query); err == nil {
+			for _, name := range redactedQueryParams {
+				if values.Has(name) {
+					values.Set(name, "REDACTED")
+				}
+			}
+			param.Path = path + "?" + values.Encode()
+		} else {
+			param.Path = path + "?REDACTED"
+		}
+	}
+
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
+// bearerToken returns the request's bearer token
+func bearerToken(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return ""
+}
+
+// authenticate returns the principal of the request's static API token or
+// login session, keeping the session's claims on the request
+func (s *Server) authenticate(c *gin.Context) (auth.Principal, error) {
+	// Browsers can't set headers on WebSocket connections, so they
+	// exchange their token for a ticket first
+	if ticket := c.Query("ticket"); ticket != "" && c.FullPath() == "/ws" {
+		return s.redeemWSTicket(ticket)
+	}
+	token := bearerToken(c)
+	if token == "" {
+		return auth.Principal{}, errNoCredentials
+	}
+	if principal, ok := s.tokens.Authenticate(token); ok {
+		return principal, nil
+	}
+	if s.sessions == nil || !auth.IsSessionToken(token) {
+		return auth.Principal{}, errNoCredentials
+	}
+
+	claims, err := s.sessions.Verify(token, time.Now())
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	revoked, err := s.redis.SessionRevoked(claims.ID)
+	if err != nil {
+		log.Printf("Error checking session %s: %v", claims.ID, err)
+		return auth.Principal{}, errors.New("session could not be checked")
+	}
+	if revoked {
+		return auth.Principal{}, errors.New("session has been logged out")
+	}
+	c.Set("session", claims)
+	return claims.Principal(), nil
+}
+
+// issueWSTicket exchanges the bearer token for a ticket that authenticates
+// one WebSocket connection as /ws?ticket=, keeping the token itself out of
+// URLs and access logs
+func (s *Server) issueWSTicket(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	data, err := json.Marshal(principal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ticket := uuid.New().String()
+	if err := s.redis.StoreWSTicket(ticket, data, wsTicketTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     ticket,
+		"expires_at": time.Now().Add(wsTicketTTL),
+	})
+}
+
+// redeemWSTicket returns the principal a WebSocket ticket was issued to,
+// which it can't be again
+func (s *Server) redeemWSTicket(ticket string) (auth.Principal, error) {
+	data, err := s.redis.TakeWSTicket(ticket)
+	if err != nil {
+		log.Printf("Error redeeming WebSocket ticket: %v", err)
+		return auth.Principal{}, errors.New("ticket could not be checked")
+	}
+	if data == nil {
+		return auth.Principal{}, errors.New("invalid, used or expired ticket")
+	}
+
+	var principal auth.Principal
+	if err := json.Unmarshal(data, &principal); err != nil {
+		return auth.Principal{}, err
+	}
+	return principal, nil
+}
+
+// login exchanges a user's name and password for a session token
+func (s *Server) login(c *gin.Context) {
+	if s.sessions == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "login is disabled; configure -users-file"})
+		return
+	}
+
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry := models.ActionAudit{
+		Action: "login",
+		Params: map[string]string{"client_ip": c.ClientIP()},
+		Actor:  req.Username,
+	}
+
+	principal, ok := s.users.Login(req.Username, req.Password)
+	if !ok {
+		entry.Error = "invalid username or password"
+		s.auditAction(entry)
+		log.Printf("🔑 Failed login as %s from %s", req.Username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entry.Error})
+		return
+	}
+
+	token, claims, err := s.sessions.Issue(principal, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry.Role = string(principal.Role)
+	entry.Success = true
+	s.auditAction(entry)
+	log.Printf("🔑 %s logged in as %s", principal.Name, principal.Role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": claims.Expires(),
+		"principal":  principal,
+	})
+}
+
+// getSession returns who the bearer token authenticates, and until when for
+// a login session
+func (s *Server) getSession(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	response := gin.H{"principal": principal}
+	if claims, ok := c.Get("session"); ok {
+		response["expires_at"] = claims.(auth.Claims).Expires()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// logout ends a login session on every node
+func (s *Server) logout(c *gin.Context) {
+	session, ok := c.Get("session")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "not a login session; API tokens are revoked by removing them from -api-tokens"})
+		return
+	}
+	claims := session.(auth.Claims)
+
+	if err := s.redis.RevokeSession(claims.ID, claims.Expires()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🔑 %s logged out", claims.Subject)
+
+	c.JSON(http.StatusOK, gin.H{"logged_out": true})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// closeShadowAttacks ends the shadow mode attacks no longer detected
+func (s *Server) closeShadowAttacks(now time.Time) {
+	for _, attack := range s.shadowPulses.End(now) {
+		s.storeShadowAttack(attack)
+		delete(s.shadowInProduction, attack.ID)
+	}
+}
+
+// recordShadowAttacks stores what the shadow thresholds detected this
+// cycle, noting whether production detected the same: every type of the
+// attack, or of its vectors, in the same cycle. Shadow detections raise no
+// alert, notify nobody and trigger no mitigation.
+func (s *Server) recordShadowAttacks(shadow, production []models.Attack, now time.Time) {
+	detected := make(map[string]bool, len(production))
+	for _, attack := range production {
+		for _, attackType := range attack.Types() {
+			detected[attack.Environment+"|"+attackType] = true
+		}
+	}
+
+	for _, observed := range shadow {
+		attack, outcome := s.shadowPulses.Observe(observed, now)
+		matched := true
+		for _, attackType := range attack.Types() {
+			matched = matched && detected[attack.Environment+"|"+attackType]
+		}
+		if matched {
+			s.shadowInProduction[attack.ID] = true
+		}
+
+		if outcome == detection.PulseNew {
+			log.Printf("👻 Shadow thresholds detected %s in %s (Confidence: %.2f, in production: %t)",
+				attack.Type, attack.Environment, attack.Confidence, s.shadowInProduction[attack.ID])
+		}
+		s.storeShadowAttack(attack)
+	}
+}
+
+// storeShadowAttack stores a shadow mode attack under attacks:shadow
+func (s *Server) storeShadowAttack(attack models.Attack) {
+	record := models.ShadowAttack{Attack: attack, InProduction: s.shadowInProduction[attack.ID]}
+	if err := s.redis.StoreShadowAttack(record); err != nil {
+		log.Printf("Error storing shadow attack: %v", err)
+	}
+}
+
+// getShadowAttacks lists what shadow mode detected, most recent first,
+// optionally for one environment
+func (s *Server) getShadowAttacks(c *gin.Context) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	attacks, err := s.redis.GetShadowAttacks(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if env := c.Query("environment"); env != "" {
+		filtered := make([]models.ShadowAttack, 0, len(attacks))
+		for _, attack := range attacks {
+			if attack.Environment == env {
+				filtered = append(filtered, attack)
+			}
+		}
+		attacks = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attacks": attacks})
+}
+
+// getShadowThresholds returns the thresholds an environment is shadowed
+// with and how they differ from its production thresholds
+func (s *Server) getShadowThresholds(c *gin.Context) {
+	env := c.DefaultQuery("environment", detection.DefaultEnvironment)
+	shadowed, defaults := s.detectors.ShadowEnvironments()
+
+	response := gin.H{
+		"environment":             env,
+		"shadowed_environments":   shadowed,
+		"shadow_all_environments": defaults,
+	}
+	thresholds, ok := s.detectors.ShadowThresholds(env)
+	response["shadowed"] = ok
+	if ok {
+		response["thresholds"] = thresholds
+		response["changes"] = s.detectors.Thresholds(env).Changes(thresholds)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// putShadowThresholds changes some of the thresholds an environment is
+// shadowed with, starting from its production thresholds if it isn't yet
+func (s *Server) putShadowThresholds(c *gin.Context) {
+	env := c.DefaultQuery("environment", detection.DefaultEnvironment)
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	if !s.isLeader.Load() {
+		c.JSON(http.StatusConflict, gin.H{"error": "this node is not the analysis leader"})
+		return
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := models.ActionAudit{
+		Action: "set_shadow_thresholds",
+		Params: map[string]string{"environment": env, "thresholds": string(body)},
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+
+	current, ok := s.detectors.ShadowThresholds(env)
+	if !ok {
+		current = s.detectors.Thresholds(env)
+	}
+	thresholds, err := current.Override(body)
+	if err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+	s.detectors.SetShadowThresholds(env, thresholds)
+	s.replicateState()
+
+	entry.Success = true
+	entry = s.auditAction(entry)
+	log.Printf("👻 %s changed the shadow thresholds of %s", principal.Name, env)
+
+	c.JSON(http.StatusOK, gin.H{
+		"environment": env,
+		"thresholds":  thresholds,
+		"changes":     s.detectors.Thresholds(env).Changes(thresholds),
+		"audit_id":    entry.ID,
+	})
+}
+
+// deleteShadowThresholds stops shadowing an environment, or every
+// environment when none is given
+func (s *Server) deleteShadowThresholds(c *gin.Context) {
+	env := c.Query("environment")
+
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	if !s.isLeader.Load() {
+		c.JSON(http.StatusConflict, gin.H{"error": "this node is not the analysis leader"})
+		return
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	s.detectors.ClearShadowThresholds(env)
+	s.replicateState()
+
+	entry := s.auditAction(models.ActionAudit{
+		Action:  "clear_shadow_thresholds",
+		Params:  map[string]string{"environment": env},
+		Actor:   principal.Name,
+		Role:    string(principal.Role),
+		Success: true,
+	})
+	if env == "" {
+		log.Printf("👻 %s turned shadow mode off", principal.Name)
+	} else {
+		log.Printf("👻 %s cleared the shadow thresholds of %s", principal.Name, env)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"environment": env, "audit_id": entry.ID})
+}
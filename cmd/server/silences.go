@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// silenceRequest creates a silence; it lasts until ends_at, or for
+// duration, from starts_at or now
+type silenceRequest struct {
+	AttackTypes []string   `json:"attack_types"`
+	SourceCIDRs []string   `json:"source_cidrs"`
+	Targets     []string   `json:"targets"`
+	Environment string     `json:"environment"`
+	Comment     string     `json:"comment"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	Duration    string     `json:"duration"`
+}
+
+// silence validates the request into a silence
+func (r silenceRequest) silence(now time.Time) (models.Silence, error) {
+	silence := models.Silence{
+		ID:          uuid.New().String(),
+		Environment: r.Environment,
+		Comment:     strings.TrimSpace(r.Comment),
+		CreatedAt:   now,
+		StartsAt:    now,
+	}
+	if silence.Comment == "" {
+		return silence, fmt.Errorf("comment is required, e.g. the load test or maintenance")
+	}
+	for _, attackType := range r.AttackTypes {
+		if attackType = strings.ToUpper(strings.TrimSpace(attackType)); attackType != "" {
+			silence.AttackTypes = append(silence.AttackTypes, attackType)
+		}
+	}
+	var err error
+	if silence.SourceCIDRs, err = silencePrefixes(r.SourceCIDRs); err != nil {
+		return silence, fmt.Errorf("source_cidrs: %w", err)
+	}
+	if silence.Targets, err = silencePrefixes(r.Targets); err != nil {
+		return silence, fmt.Errorf("targets: %w", err)
+	}
+	if len(silence.AttackTypes) == 0 && len(silence.SourceCIDRs) == 0 && len(silence.Targets) == 0 && silence.Environment == "" {
+		return silence, fmt.Errorf("a silence needs attack_types, source_cidrs, targets or environment")
+	}
+
+	if r.StartsAt != nil {
+		silence.StartsAt = *r.StartsAt
+	}
+	switch {
+	case r.EndsAt != nil && r.Duration != "":
+		return silence, fmt.Errorf("give ends_at or duration, not both")
+	case r.EndsAt != nil:
+		silence.EndsAt = *r.EndsAt
+	case r.Duration != "":
+		duration, err := time.ParseDuration(r.Duration)
+		if err != nil || duration <= 0 {
+			return silence, fmt.Errorf("invalid duration %q", r.Duration)
+		}
+		silence.EndsAt = silence.StartsAt.Add(duration)
+	default:
+		return silence, fmt.Errorf("ends_at or duration is required")
+	}
+	if !silence.EndsAt.After(silence.StartsAt) || !silence.EndsAt.After(now) {
+		return silence, fmt.Errorf("a silence must end after it starts, and in the future")
+	}
+	return silence, nil
+}
+
+// silencePrefixes normalizes IPs and CIDRs into prefixes
+func silencePrefixes(values []string) ([]string, error) {
+	prefixes := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		prefix, err := parseSilencePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", value)
+		}
+		prefixes = append(prefixes, prefix.String())
+	}
+	return prefixes, nil
+}
+
+// parseSilencePrefix reads a CIDR prefix or a single address
+func parseSilencePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// silenceMatches reports whether a silence covers an attack. Sources and
+// targets must all fall in the silence's prefixes, so that a real attack
+// mixed into a load test still alerts.
+func silenceMatches(silence models.Silence, attack models.Attack) bool {
+	if silence.Environment != "" && silence.Environment != attack.Environment {
+		return false
+	}
+	if len(silence.AttackTypes) > 0 && !containsString(silence.AttackTypes, attack.Type) {
+		return false
+	}
+	if len(silence.SourceCIDRs) > 0 {
+		sources := append([]string{}, attack.SourceIPs...)
+		for _, prefix := range attack.SourcePrefixes {
+			sources = append(sources, prefix.Prefix)
+		}
+		if !allWithin(sources, silence.SourceCIDRs) {
+			return false
+		}
+	}
+	if len(silence.Targets) > 0 && !allWithin(attack.TargetIPs, silence.Targets) {
+		return false
+	}
+	return true
+}
+
+// allWithin reports whether there are addresses or prefixes and every one
+// lies within one of the prefixes
+func allWithin(values, prefixes []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, value := range values {
+		inner, err := parseSilencePrefix(value)
+		if err != nil {
+			return false
+		}
+		within := false
+		for _, p := range prefixes {
+			outer, err := netip.ParsePrefix(p)
+			if err == nil && outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr()) {
+				within = true
+				break
+			}
+		}
+		if !within {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// syncSilences picks up the silences created or expired through any node
+func (s *Server) syncSilences() {
+	silences, err := s.redis.GetSilences()
+	if err != nil {
+		log.Printf("Error reading silences: %v", err)
+		return
+	}
+
+	s.silencesMu.Lock()
+	s.silences = silences
+	s.silencesMu.Unlock()
+}
+
+// applySilences marks an attack silenced by the first active silence that
+// matches it, or no longer silenced once none does, noting the change on
+// its timeline
+func (s *Server) applySilences(attack models.Attack, now time.Time) models.Attack {
+	var match *models.Silence
+	s.silencesMu.RLock()
+	for i := range s.silences {
+		if s.silences[i].State(now) == models.SilenceActive && silenceMatches(s.silences[i], attack) {
+			match = &s.silences[i]
+			break
+		}
+	}
+	s.silencesMu.RUnlock()
+
+	switch {
+	case match != nil && match.ID != attack.SilencedBy:
+		log.Printf("🔇 %s in %s silenced: %s", attack.Type, attack.Environment, match.Comment)
+		s.recordTimeline(attack.ID, models.TimelineEvent{
+			Timestamp: now,
+			Type:      "SILENCED",
+			Message:   fmt.Sprintf("Alerts and automatic mitigation held back by silence %s: %s", match.ID, match.Comment),
+			Actor:     match.CreatedBy,
+		})
+		attack.SilencedBy = match.ID
+	case match == nil && attack.SilencedBy != "":
+		log.Printf("🔊 %s in %s no longer silenced", attack.Type, attack.Environment)
+		s.recordTimeline(attack.ID, models.TimelineEvent{
+			Timestamp: now,
+			Type:      "UNSILENCED",
+			Message:   fmt.Sprintf("Silence %s no longer applies", attack.SilencedBy),
+			Actor:     "detector",
+		})
+		attack.SilencedBy = ""
+	}
+	return attack
+}
+
+// getSilences lists silences, most recently starting first, optionally in
+// one state: pending, active or expired
+func (s *Server) getSilences(c *gin.Context) {
+	silences, err := s.redis.GetSilences()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	state := strings.ToLower(c.Query("state"))
+	type silenceState struct {
+		models.Silence
+		State string `json:"state"`
+	}
+	filtered := make([]silenceState, 0, len(silences))
+	for _, silence := range silences {
+		if current := silence.State(now); state == "" || current == state {
+			filtered = append(filtered, silenceState{silence, current})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"silences": filtered,
+		"count":    len(filtered),
+	})
+}
+
+// createSilence starts a silence, or schedules it
+func (s *Server) createSilence(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	var req silenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	silence, err := req.silence(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	silence.CreatedBy = principal.Name
+
+	if err := s.redis.StoreSilence(silence); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🔇 %s silenced until %s: %s", principal.Name, silence.EndsAt.Format(time.RFC3339), silence.Comment)
+
+	c.JSON(http.StatusCreated, gin.H{"silence": silence})
+}
+
+// expireSilence ends a silence now, keeping it for reference
+func (s *Server) expireSilence(c *gin.Context) {
+	principal := c.MustGet("principal").(auth.Principal)
+	silence, err := s.redis.GetSilence(c.Param("id"))
+	if errors.Is(err, storage.ErrSilenceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "silence not found", "id": c.Param("id")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if silence.State(now) != models.SilenceExpired {
+		silence.EndsAt = now
+		if silence.StartsAt.After(now) {
+			silence.StartsAt = now
+		}
+		silence.ExpiredBy = principal.Name
+		if err := s.redis.StoreSilence(*silence); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("🔊 %s expired silence %s: %s", principal.Name, silence.ID, silence.Comment)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"silence": silence})
+}
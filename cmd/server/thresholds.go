@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/auth"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/detection"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// getThresholds returns the thresholds an environment is analyzed with
+func (s *Server) getThresholds(c *gin.Context) {
+	env := c.DefaultQuery("environment", detection.DefaultEnvironment)
+	c.JSON(http.StatusOK, gin.H{
+		"environment": env,
+		"thresholds":  s.detectors.Thresholds(env),
+	})
+}
+
+// putThresholds changes some of an environment's thresholds at runtime,
+// keeping its learned baseline. Only the analysis leader takes changes,
+// since it replicates them with the rest of the detection state.
+func (s *Server) putThresholds(c *gin.Context) {
+	env := c.DefaultQuery("environment", detection.DefaultEnvironment)
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Wait out a running cycle so it finishes on the thresholds it started with
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	if !s.isLeader.Load() {
+		c.JSON(http.StatusConflict, gin.H{"error": "this node is not the analysis leader"})
+		return
+	}
+
+	principal := c.MustGet("principal").(auth.Principal)
+	entry := models.ActionAudit{
+		Action: "set_thresholds",
+		Params: map[string]string{"environment": env, "thresholds": string(body)},
+		Actor:  principal.Name,
+		Role:   string(principal.Role),
+	}
+
+	thresholds, err := s.detectors.Thresholds(env).Override(body)
+	if err != nil {
+		entry.Error = err.Error()
+		entry = s.auditAction(entry)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "audit_id": entry.ID})
+		return
+	}
+	s.detectors.SetThresholds(env, thresholds)
+	s.replicateState()
+
+	entry.Success = true
+	entry = s.auditAction(entry)
+	log.Printf("🎚️  %s changed the thresholds of %s", principal.Name, env)
+
+	c.JSON(http.StatusOK, gin.H{
+		"environment": env,
+		"thresholds":  thresholds,
+		"audit_id":    entry.ID,
+	})
+}
+
+// applyThresholdsFile applies the thresholds and shadow thresholds files,
+// if configured. Replicated state restored later takes precedence.
+func (s *Server) applyThresholdsFile() {
+	if s.thresholdsConfig != nil {
+		s.detectors.Configure(s.thresholdsConfig)
+	}
+	if s.shadowConfig != nil {
+		s.detectors.ConfigureShadow(s.shadowConfig)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ways of verifying client certificates with -tls-client-ca
+const (
+	// clientAuthIngest verifies certificates clients present and requires
+	// one from agents shipping traffic; browsers and API clients connect
+	// without
+	clientAuthIngest = "ingest"
+	// clientAuthRequire requires a certificate on every connection
+	clientAuthRequire = "require"
+	// clientAuthOptional verifies certificates clients present and
+	// requires none
+	clientAuthOptional = "optional"
+)
+
+// serverTLSConfig loads the certificate the server presents and, with a
+// client CA bundle, the CAs client certificates must chain to
+func serverTLSConfig(certFile, keyFile, clientCAFile, clientAuth string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s holds no PEM certificates", clientCAFile)
+	}
+
+	switch clientAuth {
+	case clientAuthIngest, clientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case clientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client certificate mode %q; use ingest, require or optional", clientAuth)
+	}
+	return config, nil
+}
+
+// agentTLSConfig is the TLS configuration of the listeners only agents
+// connect to, syslog over TCP and the Envoy access log service: the HTTP
+// server's, requiring a client certificate where -tls-client-auth ingest
+// requires one from agents. It is nil when TLS is off.
+func agentTLSConfig(config *tls.Config, agentCertRequired bool) *tls.Config {
+	if config == nil || !agentCertRequired {
+		return config
+	}
+	config = config.Clone()
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config
+}
+
+// requireAgentCert rejects traffic shipped by an agent without a verified
+// client certificate when -tls-client-auth is ingest. Cloudflare Logpush
+// can't present one and authenticates with its own secret.
+func (s *Server) requireAgentCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.agentCertRequired {
+			c.Next()
+			return
+		}
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "agents must present a client certificate signed by -tls-client-ca"})
+			return
+		}
+		c.Next()
+	}
+}
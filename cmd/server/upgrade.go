@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsHandoffName is the name dashboard sessions are handed over under
+	wsHandoffName = "ws"
+	// upgradeReadyTimeout bounds how long the new binary may take to start
+	upgradeReadyTimeout = time.Minute
+)
+
+// upgrade replaces this process with a fresh start of the (possibly
+// replaced) binary on SIGHUP. The new process inherits the listening
+// sockets and the dashboard WebSocket sessions; once it is ready this one
+// stops, handing over analysis leadership as on SIGTERM.
+func (s *Server) upgrade(stop context.CancelFunc) {
+	log.Println("🔄 Upgrade requested, starting the new binary")
+
+	// No analysis cycle may broadcast while the sessions are in transit
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+
+	sessions := detachWebSockets()
+	err := s.handoff.Upgrade(map[string][]*os.File{wsHandoffName: sessions}, upgradeReadyTimeout)
+	for _, file := range sessions {
+		file.Close()
+	}
+	if err != nil {
+		// Detached sessions are closed along with the failed process, and
+		// dashboards reconnect to this one
+		log.Printf("Upgrade failed, carrying on: %v", err)
+		return
+	}
+
+	log.Printf("🔄 New process is ready, handing over %d dashboard sessions", len(sessions))
+	stop()
+}
+
+// detachWebSockets stops serving the dashboard sessions without closing
+// them and returns their sockets for the new process
+func detachWebSockets() []*os.File {
+	wsMu.Lock()
+	defer wsMu.Unlock()
+
+	files := make([]*os.File, 0, len(wsClients))
+	for conn := range wsClients {
+		socket, ok := conn.NetConn().(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+		file, err := socket.File()
+		if err != nil {
+			continue
+		}
+
+		files = append(files, file)
+		delete(wsClients, conn)
+		// Ends the session's read loop; the socket stays open through file
+		conn.SetReadDeadline(time.Now())
+	}
+	return files
+}
+
+// adoptWebSockets resumes the dashboard sessions handed over by the
+// previous process
+func (s *Server) adoptWebSockets() {
+	conns := s.handoff.Conns(wsHandoffName)
+	for _, netConn := range conns {
+		conn, err := adoptWebSocket(netConn)
+		if err != nil {
+			log.Printf("Error adopting WebSocket session: %v", err)
+			netConn.Close()
+			continue
+		}
+		go serveWebSocket(conn)
+	}
+
+	if len(conns) > 0 {
+		log.Printf("Resumed %d dashboard sessions", len(conns))
+	}
+}
+
+// adoptWebSocket wraps a socket whose WebSocket handshake the previous
+// process already completed. The Upgrader only builds connections from an
+// HTTP upgrade, so it is handed a synthetic one and its handshake response,
+// which the client already received, is discarded.
+func adoptWebSocket(netConn net.Conn) (*websocket.Conn, error) {
+	adopted := &adoptedConn{Conn: netConn}
+	writer := &adoptedResponse{
+		conn:   adopted,
+		rw:     bufio.NewReadWriter(bufio.NewReader(adopted), bufio.NewWriter(adopted)),
+		header: make(http.Header),
+	}
+
+	req := &http.Request{
+		Method: http.MethodGet,
+		Header: http.Header{
+			"Connection":            {"Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"ZGRvcy1oYW5kb2ZmLWtleQ=="},
+		},
+	}
+	return upgrader.Upgrade(writer, req, nil)
+}
+
+// adoptedConn drops the first write, the synthetic handshake response
+type adoptedConn struct {
+	net.Conn
+	handshakeDone bool
+}
+
+func (c *adoptedConn) Write(p []byte) (int, error) {
+	if !c.handshakeDone {
+		c.handshakeDone = true
+		return len(p), nil
+	}
+	return c.Conn.Write(p)
+}
+
+// adoptedResponse hands an adopted socket to the Upgrader
+type adoptedResponse struct {
+	conn   net.Conn
+	rw     *bufio.ReadWriter
+	header http.Header
+}
+
+func (r *adoptedResponse) Header() http.Header         { return r.header }
+func (r *adoptedResponse) Write(p []byte) (int, error) { return len(p), nil }
+func (r *adoptedResponse) WriteHeader(int)             {}
+
+func (r *adoptedResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.conn, r.rw, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// apiV1Prefix is where the current version of the API is served
+	apiV1Prefix = "/api/v1"
+	// legacyAPIPrefix serves the unversioned paths of earlier releases
+	legacyAPIPrefix = "/api"
+)
+
+// legacyAPIDeprecatedAt is when the unversioned paths were deprecated, as
+// sent in their Deprecation header
+var legacyAPIDeprecatedAt = time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+
+// apiRoute is an API route without its version prefix, e.g.
+// /traffic/ingest for both /api/v1/traffic/ingest and /api/traffic/ingest
+func apiRoute(fullPath string) string {
+	if route, ok := strings.CutPrefix(fullPath, apiV1Prefix+"/"); ok {
+		return "/" + route
+	}
+	if route, ok := strings.CutPrefix(fullPath, legacyAPIPrefix+"/"); ok {
+		return "/" + route
+	}
+	return fullPath
+}
+
+// legacyAPIUsage counts the requests to each unversioned route since this
+// node started, so operators can tell when nothing calls them any more
+type legacyAPIUsage struct {
+	mu      sync.Mutex
+	byRoute map[string]int64
+}
+
+// add counts one request, reporting whether it was the route's first
+func (u *legacyAPIUsage) add(route string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.byRoute == nil {
+		u.byRoute = make(map[string]int64)
+	}
+	u.byRoute[route]++
+	return u.byRoute[route] == 1
+}
+
+// status reports the counts for the system overview
+func (u *legacyAPIUsage) status() map[string]int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return maps.Clone(u.byRoute)
+}
+
+// legacyAPI marks responses on the unversioned paths as deprecated, pointing
+// at their v1 successor, or refuses them with 410 once -api-legacy is off
+func (s *Server) legacyAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		successor := apiV1Prefix + strings.TrimPrefix(c.Request.URL.Path, legacyAPIPrefix)
+		if s.legacyAPIOff {
+			c.AbortWithStatusJSON(http.StatusGone, gin.H{
+				"error":     "unversioned API paths have been retired",
+				"successor": successor,
+			})
+			return
+		}
+
+		c.Header("Deprecation", fmt.Sprintf("@%d", legacyAPIDeprecatedAt.Unix()))
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		if !s.legacyAPISunset.IsZero() {
+			c.Header("Sunset", s.legacyAPISunset.Format(http.TimeFormat))
+		}
+		if s.legacyAPIUsage.add(c.FullPath()) {
+			log.Printf("⚠️  Deprecated API path %s %s called by %s; use %s", c.Request.Method, c.FullPath(), c.ClientIP(), apiV1Prefix+apiRoute(c.FullPath()))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// statsSummary is the part of /api/stats/summary the feedback loop reads
+type statsSummary struct {
+	Status        string  `json:"status"`
+	ActiveAttacks int     `json:"active_attacks"`
+	CurrentRPS    float64 `json:"current_rps"`
+}
+
+// boundarySample is one poll of the server during a boundary run, and one
+// row of the generated dataset
+type boundarySample struct {
+	Time          time.Time `json:"time"`
+	Phase         string    `json:"phase"`       // search, settle, below or above
+	AttackRate    int       `json:"attack_rate"` // attack requests sent per second
+	CurrentRPS    float64   `json:"current_rps"`
+	Status        string    `json:"status"`
+	ActiveAttacks int       `json:"active_attacks"`
+}
+
+// holdResult is the outcome of holding the attack on one side of the boundary
+type holdResult struct {
+	Phase          string  `json:"phase"`
+	AttackRate     int     `json:"attack_rate"`
+	Detected       bool    `json:"detected"`
+	DetectSeconds  float64 `json:"detect_seconds,omitempty"` // from the start of the hold to the first detection
+	ClearSeconds   float64 `json:"clear_seconds,omitempty"`  // from the end of the hold until no attack was active
+	MatchesExpects bool    `json:"matches_expectation"`      // below stayed quiet, above was detected
+}
+
+// boundaryRun drives one attack type around its detection threshold
+type boundaryRun struct {
+	sim        *Simulator
+	attackType string
+	poll       time.Duration
+	settle     time.Duration
+	dataset    *json.Encoder
+}
+
+// runBoundary finds the attack rate at which an attack type starts being
+// detected by reading the server's summary while adjusting the rate, then
+// holds the attack just below and just above it, recording every poll as a
+// dataset for threshold tuning and hysteresis testing. Returns the exit code.
+func runBoundary(args []string) int {
+	fs := flag.NewFlagSet("boundary", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8888", "server to probe")
+	environment := fs.String("env", "boundary", "environment tag for the generated traffic, keeping it apart from real traffic")
+	attackType := fs.String("attack", "SYN_FLOOD", "attack type to probe: "+strings.Join(attackSequence, ", "))
+	minRate := fs.Int("min-rate", 1, "attack requests per second the search starts from")
+	maxRate := fs.Int("max-rate", 20000, "attack requests per second the search gives up at")
+	probe := fs.Duration("probe", 30*time.Second, "how long each search step sends before it counts as undetected")
+	precision := fs.Float64("precision", 0.05, "stop searching once the undetected and detected rates are within this fraction")
+	margin := fs.Float64("margin", 0.1, "hold the attack this fraction below and above the boundary")
+	hold := fs.Duration("hold", 2*time.Minute, "how long each hold below or above the boundary lasts")
+	cycles := fs.Int("cycles", 2, "below/above hold pairs to run")
+	poll := fs.Duration("poll", 5*time.Second, "how often the server's summary is read")
+	settle := fs.Duration("settle-timeout", 3*time.Minute, "how long to wait for active attacks to end between steps")
+	token := fs.String("token", os.Getenv("DDOS_API_TOKEN"), "viewer API token or session for reading the summary from a server requiring one")
+	out := fs.String("out", "boundary.jsonl", "file the dataset is written to as JSON lines, - for stdout")
+	fs.Parse(args)
+
+	*attackType = strings.ToUpper(*attackType)
+	if !knownScenario(*attackType) {
+		fmt.Fprintf(os.Stderr, "❌ Unknown attack %s; available: %s\n", *attackType, strings.Join(attackSequence, ", "))
+		return 2
+	}
+	if *minRate < 1 || *maxRate < *minRate || *precision <= 0 || *margin <= 0 || *margin >= 1 {
+		fmt.Fprintln(os.Stderr, "❌ Need 1 <= -min-rate <= -max-rate, -precision > 0 and 0 < -margin < 1")
+		return 2
+	}
+
+	var dataset io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 2
+		}
+		defer file.Close()
+		dataset = file
+	}
+
+	sim := NewSimulator(*serverURL)
+	sim.environment = *environment
+	sim.token = *token
+	run := &boundaryRun{
+		sim:        sim,
+		attackType: *attackType,
+		poll:       *poll,
+		settle:     *settle,
+		dataset:    json.NewEncoder(dataset),
+	}
+
+	if _, err := sim.fetchSummary(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Server %s is not reachable: %v\n", *serverURL, err)
+		return 2
+	}
+	if _, ok := run.waitQuiet(); !ok {
+		fmt.Fprintf(os.Stderr, "❌ Attacks in %s still active after %s; nothing to measure against\n", *environment, *settle)
+		return 2
+	}
+
+	boundary, err := run.search(*minRate, *maxRate, *probe, *precision)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("🎯 %s is detected from about %d requests/sec\n", *attackType, boundary)
+
+	below := max(int(float64(boundary)*(1-*margin)), 1)
+	above := max(int(float64(boundary)*(1+*margin)), below+1)
+	results := make([]holdResult, 0, 2**cycles)
+	for i := 0; i < *cycles; i++ {
+		results = append(results, run.hold("below", below, *hold), run.hold("above", above, *hold))
+	}
+
+	fmt.Println(boundarySummary(*attackType, boundary, results))
+	return 0
+}
+
+// search doubles the rate until the attack is detected, then bisects
+// between the last undetected and the first detected rate
+func (r *boundaryRun) search(minRate, maxRate int, probe time.Duration, precision float64) (int, error) {
+	lo, hi := 0, minRate
+	for {
+		if r.probe(hi, probe) {
+			break
+		}
+		if hi >= maxRate {
+			return 0, fmt.Errorf("%s not detected up to %d requests/sec", r.attackType, maxRate)
+		}
+		lo, hi = hi, min(hi*2, maxRate)
+	}
+	if lo == 0 {
+		return 0, fmt.Errorf("%s already detected at %d requests/sec; lower -min-rate", r.attackType, minRate)
+	}
+
+	for float64(hi) > float64(lo)*(1+precision) && hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if r.probe(mid, probe) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
+
+// probe sends the attack at rate until it is detected or the probe time is
+// up, and waits for the attack to end again if it was detected
+func (r *boundaryRun) probe(rate int, duration time.Duration) bool {
+	fmt.Printf("🔎 Probing %d requests/sec\n", rate)
+	_, detected := r.drive("search", rate, duration, func(summary statsSummary, _ time.Duration) bool {
+		return summary.ActiveAttacks > 0
+	})
+	if detected {
+		r.waitQuiet()
+	}
+	return detected
+}
+
+// hold keeps the attack at rate for the whole duration, then measures how
+// long the server takes to consider it over
+func (r *boundaryRun) hold(phase string, rate int, duration time.Duration) holdResult {
+	fmt.Printf("⏸️  Holding %s the boundary at %d requests/sec for %s\n", phase, rate, duration)
+	result := holdResult{Phase: phase, AttackRate: rate}
+
+	r.drive(phase, rate, duration, func(summary statsSummary, elapsed time.Duration) bool {
+		if summary.ActiveAttacks > 0 && !result.Detected {
+			result.Detected = true
+			result.DetectSeconds = elapsed.Round(time.Second).Seconds()
+		}
+		return false
+	})
+
+	if result.Detected {
+		cleared, _ := r.waitQuiet()
+		result.ClearSeconds = cleared.Round(time.Second).Seconds()
+	}
+	result.MatchesExpects = result.Detected == (phase == "above")
+	return result
+}
+
+// waitQuiet sends normal traffic only until no attack is active, returning
+// how long that took and whether it happened within the settle timeout
+func (r *boundaryRun) waitQuiet() (time.Duration, bool) {
+	return r.drive("settle", 0, r.settle, func(summary statsSummary, _ time.Duration) bool {
+		return summary.ActiveAttacks == 0
+	})
+}
+
+// drive sends normal traffic plus rate attack requests per second for up
+// to duration, polling the summary, and stops early once until returns
+// true. It returns the time until then and whether it happened.
+func (r *boundaryRun) drive(phase string, rate int, duration time.Duration, until func(summary statsSummary, elapsed time.Duration) bool) (time.Duration, bool) {
+	stats := newPhaseStats(phase)
+	start := time.Now()
+	end := time.After(duration)
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	polls := time.NewTicker(r.poll)
+	defer polls.Stop()
+
+	r.sim.sendAttackTick(r.attackType, rate, stats)
+	for {
+		select {
+		case <-tick.C:
+			r.sim.sendAttackTick(r.attackType, rate, stats)
+
+		case <-polls.C:
+			summary, err := r.sim.fetchSummary()
+			if err != nil {
+				// The server may still be digesting the flood
+				continue
+			}
+			r.dataset.Encode(boundarySample{
+				Time:          time.Now(),
+				Phase:         phase,
+				AttackRate:    rate,
+				CurrentRPS:    summary.CurrentRPS,
+				Status:        summary.Status,
+				ActiveAttacks: summary.ActiveAttacks,
+			})
+			if until(summary, time.Since(start)) {
+				return time.Since(start), true
+			}
+
+		case <-end:
+			return time.Since(start), false
+		}
+	}
+}
+
+// sendAttackTick sends one second of normal traffic plus rate requests of
+// an attack type
+func (s *Simulator) sendAttackTick(attackType string, rate int, phase *PhaseStats) {
+	s.sendTick("", phase)
+
+	for _, req := range s.attackTraffic(attackType, rate) {
+		s.sendAsync(req, phase)
+	}
+}
+
+// attackTraffic returns rate requests of an attack type, drawn from as many
+// generated seconds of it as needed
+func (s *Simulator) attackTraffic(attackType string, rate int) []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0, rate)
+	for len(requests) < rate {
+		batch := s.generateAttack(attackType)
+		if len(batch) == 0 {
+			break
+		}
+		requests = append(requests, batch[:min(len(batch), rate-len(requests))]...)
+	}
+	return requests
+}
+
+// fetchSummary reads the server's summary for the simulator's environment
+func (s *Simulator) fetchSummary() (statsSummary, error) {
+	var summary statsSummary
+
+	req, err := http.NewRequest(http.MethodGet, s.serverURL+"/api/v1/stats/summary?environment="+url.QueryEscape(s.environment), nil)
+	if err != nil {
+		return summary, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return summary, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return summary, &statusError{code: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return summary, err
+	}
+	if summary.Status == "" {
+		return summary, errors.New("summary has no status")
+	}
+	return summary, nil
+}
+
+// boundarySummary formats the outcome of the holds around the boundary
+func boundarySummary(attackType string, boundary int, results []holdResult) string {
+	var b strings.Builder
+
+	matched := 0
+	for _, result := range results {
+		if result.MatchesExpects {
+			matched++
+		}
+	}
+	fmt.Fprintf(&b, "📊 %s boundary ~%d requests/sec: %d of %d holds behaved as expected\n", attackType, boundary, matched, len(results))
+
+	for _, result := range results {
+		mark := "✅"
+		if !result.MatchesExpects {
+			mark = "❌"
+		}
+		switch {
+		case result.Detected:
+			fmt.Fprintf(&b, "  %s %-5s %6d/s detected after %.0fs, cleared %.0fs after it stopped\n",
+				mark, result.Phase, result.AttackRate, result.DetectSeconds, result.ClearSeconds)
+		default:
+			fmt.Fprintf(&b, "  %s %-5s %6d/s not detected\n", mark, result.Phase, result.AttackRate)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
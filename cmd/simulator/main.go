@@ -3,9 +3,17 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,16 +22,92 @@ import (
 
 type Simulator struct {
 	serverURL    string
+	environment  string
+	token        string  // bearer token for the reads verify and boundary make
+	ipv6Share    float64 // share of generated sources that are IPv6
 	normalRate   int
 	attackActive bool
 	attackType   string
+	client       *http.Client
+	maxRetries   int
+	baseBackoff  time.Duration
+	phase        *PhaseStats
+	// summaries tracks the summaries of ended phases still waiting on sends
+	summaries sync.WaitGroup
+}
+
+// PhaseStats counts delivery outcomes for one scenario phase, so a missed
+// detection can be told apart from traffic that never reached the server
+type PhaseStats struct {
+	Name      string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Attempted atomic.Int64
+	Delivered atomic.Int64
+	Retries   atomic.Int64
+	Failed    atomic.Int64
+
+	// sends tracks the phase's requests still being sent or retried
+	sends sync.WaitGroup
 }
 
 func NewSimulator(serverURL string) *Simulator {
 	return &Simulator{
-		serverURL:  serverURL,
-		normalRate: 100,
+		serverURL:   serverURL,
+		normalRate:  100,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		maxRetries:  3,
+		baseBackoff: 100 * time.Millisecond,
+		phase:       newPhaseStats("NORMAL"),
+	}
+}
+
+func newPhaseStats(name string) *PhaseStats {
+	return &PhaseStats{
+		Name:      name,
+		StartedAt: time.Now(),
+	}
+}
+
+// Summary formats the delivery outcome of the phase
+func (p *PhaseStats) Summary() string {
+	attempted := p.Attempted.Load()
+	failed := p.Failed.Load()
+
+	failRate := 0.0
+	if attempted > 0 {
+		failRate = float64(failed) / float64(attempted) * 100
+	}
+
+	ended := p.EndedAt
+	if ended.IsZero() {
+		ended = time.Now()
 	}
+
+	return fmt.Sprintf("📊 Phase %s (%s): %d attempted, %d delivered, %d failed (%.1f%%), %d retries",
+		p.Name, ended.Sub(p.StartedAt).Round(time.Second), attempted, p.Delivered.Load(), failed, failRate, p.Retries.Load())
+}
+
+// finish ends the phase and waits for its sends, retries included, to
+// settle, so its summary counts every request it generated
+func (p *PhaseStats) finish() {
+	p.EndedAt = time.Now()
+	p.sends.Wait()
+}
+
+// startPhase begins counting a new phase, printing the summary of the
+// current one once its sends have settled
+func (s *Simulator) startPhase(name string) {
+	ended := s.phase
+	s.phase = newPhaseStats(name)
+
+	s.summaries.Add(1)
+	ended.EndedAt = time.Now()
+	go func() {
+		defer s.summaries.Done()
+		ended.sends.Wait()
+		fmt.Println(ended.Summary())
+	}()
 }
 
 // GenerateNormalTraffic creates realistic user traffic
@@ -40,8 +124,11 @@ func (s *Simulator) GenerateNormalTraffic() models.TrafficRequest {
 		"/profile", "/search", "/checkout", "/api/orders", "/help",
 	}
 
-	sourceIP := fmt.Sprintf("%d.%d.%d.%d",
-		rand.Intn(256), rand.Intn(256), rand.Intn(256), rand.Intn(256))
+	sourceIP := s.randomIP()
+
+	// Each browser sends its own consistent header stack
+	ua := rand.Intn(len(userAgents))
+	stack := browserStacks[ua%len(browserStacks)]
 
 	return models.TrafficRequest{
 		ID:          uuid.New().String(),
@@ -52,14 +139,37 @@ func (s *Simulator) GenerateNormalTraffic() models.TrafficRequest {
 		DestPort:    443,
 		Protocol:    "HTTP",
 		RequestPath: paths[rand.Intn(len(paths))],
-		UserAgent:   userAgents[rand.Intn(len(userAgents))],
+		UserAgent:   userAgents[ua],
 		BytesSent:   rand.Intn(1000) + 100,
 		BytesRecv:   rand.Intn(5000) + 200,
 		StatusCode:  200,
 		Duration:    rand.Intn(200) + 50,
+
+		HTTPVersion:    stack.version,
+		HeaderOrder:    stack.headerOrder,
+		Accept:         stack.accept,
+		AcceptLanguage: stack.acceptLanguage,
 	}
 }
 
+// clientStack is what an HTTP client sends regardless of the User-Agent it claims
+type clientStack struct {
+	version        string
+	headerOrder    []string
+	accept         string
+	acceptLanguage string
+}
+
+var browserStacks = []clientStack{
+	{"HTTP/2", []string{":method", ":authority", ":scheme", ":path", "user-agent", "accept", "accept-encoding", "accept-language"}, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "en-US,en;q=0.9"},
+	{"HTTP/2", []string{":method", ":path", ":authority", ":scheme", "user-agent", "accept", "accept-language", "accept-encoding"}, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "en-US,en;q=0.5"},
+	{"HTTP/2", []string{":method", ":scheme", ":path", ":authority", "accept", "user-agent", "accept-language", "accept-encoding"}, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "en-GB,en;q=0.9"},
+	{"HTTP/2", []string{":method", ":scheme", ":authority", ":path", "accept", "accept-encoding", "user-agent", "accept-language"}, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "en-US"},
+}
+
+// botStack is the HTTP library behind the flood, whatever User-Agent it sends
+var botStack = clientStack{"HTTP/1.1", []string{"host", "user-agent", "accept", "connection"}, "*/*", ""}
+
 // GenerateSYNFlood simulates SYN flood attack
 func (s *Simulator) GenerateSYNFlood() []models.TrafficRequest {
 	requests := make([]models.TrafficRequest, 0)
@@ -79,6 +189,7 @@ func (s *Simulator) GenerateSYNFlood() []models.TrafficRequest {
 			SourcePort: rand.Intn(65535),
 			DestPort:   80,
 			Protocol:   "TCP_SYN",
+			TCPFlags:   models.TCPFlagSYN,
 			BytesSent:  64,
 			Duration:   0,
 		}
@@ -92,9 +203,20 @@ func (s *Simulator) GenerateSYNFlood() []models.TrafficRequest {
 func (s *Simulator) GenerateHTTPFlood() []models.TrafficRequest {
 	requests := make([]models.TrafficRequest, 0)
 
-	attackIPs := generateBotnet(50)
+	attackIPs := s.generateBotnet(50)
 	targetPaths := []string{"/api/search", "/login"}
 
+	// Rotated to look like ordinary browsers
+	userAgents := []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X)",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/115.0",
+		"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36",
+		"curl/7.68.0",
+	}
+
 	count := rand.Intn(3000) + 2000
 
 	for i := 0; i < count; i++ {
@@ -107,11 +229,15 @@ func (s *Simulator) GenerateHTTPFlood() []models.TrafficRequest {
 			DestPort:    443,
 			Protocol:    "HTTP",
 			RequestPath: targetPaths[rand.Intn(len(targetPaths))],
-			UserAgent:   "curl/7.68.0",
+			UserAgent:   userAgents[rand.Intn(len(userAgents))],
 			BytesSent:   rand.Intn(500) + 100,
 			BytesRecv:   rand.Intn(1000) + 200,
 			StatusCode:  200,
 			Duration:    rand.Intn(100) + 20,
+
+			HTTPVersion: botStack.version,
+			HeaderOrder: botStack.headerOrder,
+			Accept:      botStack.accept,
 		}
 		requests = append(requests, req)
 	}
@@ -147,11 +273,42 @@ func (s *Simulator) GenerateSlowloris() []models.TrafficRequest {
 	return requests
 }
 
+// GenerateSlowPOST simulates a RUDY attack: long-lived POSTs whose bodies
+// trickle in a few bytes per second
+func (s *Simulator) GenerateSlowPOST() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	attackIPs := s.generateBotnet(30)
+
+	count := rand.Intn(200) + 150
+
+	for i := 0; i < count; i++ {
+		duration := rand.Intn(40000) + 20000
+		bodyBytes := rand.Intn(2000) + 100
+		req := models.TrafficRequest{
+			ID:              uuid.New().String(),
+			Timestamp:       time.Now(),
+			SourceIP:        attackIPs[rand.Intn(len(attackIPs))],
+			DestIP:          "192.168.1.100",
+			SourcePort:      rand.Intn(65535-1024) + 1024,
+			DestPort:        80,
+			Protocol:        "HTTP",
+			RequestPath:     "/api/upload",
+			BytesSent:       bodyBytes,
+			Duration:        duration,
+			RequestBodyRate: float64(bodyBytes) / (float64(duration) / 1000),
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
 // GenerateUDPFlood simulates UDP flood attack
 func (s *Simulator) GenerateUDPFlood() []models.TrafficRequest {
 	requests := make([]models.TrafficRequest, 0)
 
-	attackIPs := generateBotnet(30)
+	attackIPs := s.generateBotnet(30)
 
 	count := rand.Intn(5000) + 3000
 
@@ -164,6 +321,7 @@ func (s *Simulator) GenerateUDPFlood() []models.TrafficRequest {
 			SourcePort: rand.Intn(65535),
 			DestPort:   rand.Intn(65535),
 			Protocol:   "UDP",
+			TTL:        rand.Intn(200) + 32, // forged per packet, as raw-socket flood tools do
 			BytesSent:  rand.Intn(1400) + 100,
 			Duration:   0,
 		}
@@ -173,37 +331,364 @@ func (s *Simulator) GenerateUDPFlood() []models.TrafficRequest {
 	return requests
 }
 
-// Helper function to generate botnet IPs
-func generateBotnet(size int) []string {
+// GenerateDNSAmplification simulates DNS reflection: open resolvers answering
+// spoofed queries with large responses aimed at the victim
+func (s *Simulator) GenerateDNSAmplification() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	resolvers := s.generateBotnet(200)
+
+	count := rand.Intn(2000) + 1000
+
+	for i := 0; i < count; i++ {
+		req := models.TrafficRequest{
+			ID:         uuid.New().String(),
+			Timestamp:  time.Now(),
+			SourceIP:   resolvers[rand.Intn(len(resolvers))],
+			DestIP:     "192.168.1.100",
+			SourcePort: 53,
+			DestPort:   rand.Intn(65535-1024) + 1024,
+			Protocol:   "UDP",
+			BytesSent:  rand.Intn(2500) + 1500,
+			Duration:   0,
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// GenerateACKFlood simulates a flood of bare ACK segments from a spoofed botnet
+func (s *Simulator) GenerateACKFlood() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	attackIPs := s.generateBotnet(500)
+
+	count := rand.Intn(3000) + 2500
+
+	for i := 0; i < count; i++ {
+		req := models.TrafficRequest{
+			ID:         uuid.New().String(),
+			Timestamp:  time.Now(),
+			SourceIP:   attackIPs[rand.Intn(len(attackIPs))],
+			DestIP:     "192.168.1.100",
+			SourcePort: rand.Intn(65535),
+			DestPort:   443,
+			Protocol:   "TCP",
+			TCPFlags:   models.TCPFlagACK,
+			BytesSent:  40,
+			Duration:   0,
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// GenerateDNSWaterTorture simulates a random-subdomain flood: resolvers
+// forwarding queries for never-seen names to the zone's authoritative server
+func (s *Simulator) GenerateDNSWaterTorture() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	resolvers := s.generateBotnet(100)
+
+	count := rand.Intn(1500) + 1000
+
+	for i := 0; i < count; i++ {
+		req := models.TrafficRequest{
+			ID:           uuid.New().String(),
+			Timestamp:    time.Now(),
+			SourceIP:     resolvers[rand.Intn(len(resolvers))],
+			DestIP:       "192.168.1.53",
+			SourcePort:   rand.Intn(65535-1024) + 1024,
+			DestPort:     53,
+			Protocol:     "UDP",
+			BytesSent:    rand.Intn(30) + 40,
+			DNSQueryName: randomLabel(12) + ".example.com",
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// GenerateCarpetBombing simulates an attack spread across every address of
+// a /24 so no single host sees enough traffic to stand out
+func (s *Simulator) GenerateCarpetBombing() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	attackIPs := s.generateBotnet(300)
+
+	count := rand.Intn(2000) + 3000
+
+	for i := 0; i < count; i++ {
+		req := models.TrafficRequest{
+			ID:         uuid.New().String(),
+			Timestamp:  time.Now(),
+			SourceIP:   attackIPs[rand.Intn(len(attackIPs))],
+			DestIP:     fmt.Sprintf("192.168.2.%d", rand.Intn(254)+1),
+			SourcePort: rand.Intn(65535),
+			DestPort:   rand.Intn(65535),
+			Protocol:   "UDP",
+			BytesSent:  rand.Intn(800) + 200,
+			Duration:   0,
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// GenerateCredentialStuffing simulates a botnet replaying leaked credentials
+// against the login endpoint, a few attempts per IP, nearly all rejected
+func (s *Simulator) GenerateCredentialStuffing() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	attackIPs := s.generateBotnet(500)
+
+	count := rand.Intn(50) + 80
+
+	for i := 0; i < count; i++ {
+		status := 401
+		if rand.Float64() < 0.02 {
+			status = 200 // the occasional valid credential pair
+		}
+		req := models.TrafficRequest{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			SourceIP:    attackIPs[rand.Intn(len(attackIPs))],
+			DestIP:      "192.168.1.100",
+			SourcePort:  rand.Intn(65535-1024) + 1024,
+			DestPort:    443,
+			Protocol:    "HTTP",
+			RequestPath: "/login",
+			UserAgent:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
+			BytesSent:   rand.Intn(100) + 250,
+			BytesRecv:   rand.Intn(200) + 300,
+			StatusCode:  status,
+			Duration:    rand.Intn(300) + 100,
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// GenerateBotFlood simulates a scraping script hammering the catalogue with
+// a stock HTTP library User-Agent
+func (s *Simulator) GenerateBotFlood() []models.TrafficRequest {
+	requests := make([]models.TrafficRequest, 0)
+
+	attackIPs := s.generateBotnet(20)
+	paths := []string{"/api/products", "/search", "/api/users", "/help"}
+
+	count := rand.Intn(300) + 300
+
+	for i := 0; i < count; i++ {
+		req := models.TrafficRequest{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			SourceIP:    attackIPs[rand.Intn(len(attackIPs))],
+			DestIP:      "192.168.1.100",
+			SourcePort:  rand.Intn(65535-1024) + 1024,
+			DestPort:    443,
+			Protocol:    "HTTP",
+			RequestPath: paths[rand.Intn(len(paths))],
+			UserAgent:   "python-requests/2.31.0",
+			BytesSent:   rand.Intn(200) + 100,
+			BytesRecv:   rand.Intn(5000) + 500,
+			StatusCode:  200,
+			Duration:    rand.Intn(150) + 30,
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// randomLabel returns a random lowercase alphanumeric DNS label
+func randomLabel(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	label := make([]byte, n)
+	for i := range label {
+		label[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(label)
+}
+
+// randomIP returns a random source address, IPv6 for ipv6Share of them
+func (s *Simulator) randomIP() string {
+	if rand.Float64() < s.ipv6Share {
+		return fmt.Sprintf("%s:%s", randomIPv6Prefix(), randomInterfaceID())
+	}
+	return fmt.Sprintf("%d.%d.%d.%d",
+		rand.Intn(256), rand.Intn(256), rand.Intn(256), rand.Intn(256))
+}
+
+// generateBotnet returns size bot addresses, ipv6Share of them IPv6. IPv6
+// bots are spread over a few /64s, as attackers handed a /64 rotate through it.
+func (s *Simulator) generateBotnet(size int) []string {
+	subnets := make([]string, max(size/8, 1))
+	for i := range subnets {
+		subnets[i] = randomIPv6Prefix()
+	}
+
 	ips := make([]string, size)
 	for i := 0; i < size; i++ {
+		if rand.Float64() < s.ipv6Share {
+			ips[i] = fmt.Sprintf("%s:%s", subnets[rand.Intn(len(subnets))], randomInterfaceID())
+			continue
+		}
 		ips[i] = fmt.Sprintf("%d.%d.%d.%d",
 			rand.Intn(256), rand.Intn(256), rand.Intn(256), rand.Intn(256))
 	}
 	return ips
 }
 
-// SendTraffic sends generated traffic to the server
+// randomIPv6Prefix returns a random /64 in 2a00::/8, global unicast space
+// clear of the documentation and other reserved ranges
+func randomIPv6Prefix() string {
+	return fmt.Sprintf("2a%02x:%x:%x:%x", rand.Intn(256), rand.Intn(65536), rand.Intn(65536), rand.Intn(65536))
+}
+
+// randomInterfaceID returns the low 64 bits of an IPv6 address
+func randomInterfaceID() string {
+	return fmt.Sprintf("%x:%x:%x:%x", rand.Intn(65536), rand.Intn(65536), rand.Intn(65536), rand.Intn(65536))
+}
+
+// SendTraffic sends generated traffic to the server, retrying transient
+// failures with exponential backoff. The outcome is recorded on the phase
+// that was active when the request was generated.
 func (s *Simulator) SendTraffic(req models.TrafficRequest) error {
+	return s.sendWithRetry(req, s.phase)
+}
+
+// sendAsync sends a request in the background, counted on the phase until
+// it settles
+func (s *Simulator) sendAsync(req models.TrafficRequest, phase *PhaseStats) {
+	phase.sends.Add(1)
+	go func() {
+		defer phase.sends.Done()
+		s.sendWithRetry(req, phase)
+	}()
+}
+
+func (s *Simulator) sendWithRetry(req models.TrafficRequest, phase *PhaseStats) error {
+	req.Environment = s.environment
+
 	data, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.Post(s.serverURL+"/api/traffic/ingest", "application/json",
+	phase.Attempted.Add(1)
+
+	backoff := s.baseBackoff
+	for attempt := 0; ; attempt++ {
+		err = s.post(data)
+		if err == nil {
+			phase.Delivered.Add(1)
+			return nil
+		}
+
+		if attempt >= s.maxRetries || !isRetryable(err) {
+			phase.Failed.Add(1)
+			return err
+		}
+
+		phase.Retries.Add(1)
+		// Jitter keeps thousands of concurrent senders from retrying in lockstep
+		time.Sleep(backoff + time.Duration(rand.Int63n(int64(backoff))))
+		backoff *= 2
+	}
+}
+
+// statusError is returned when the server answers with a non-2xx status
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server returned status %d", e.code)
+}
+
+func (s *Simulator) post(data []byte) error {
+	resp, err := s.client.Post(s.serverURL+"/api/v1/traffic/ingest", "application/json",
 		bytes.NewBuffer(data))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
 
 	return nil
 }
 
+// isRetryable reports whether a failed send is worth retrying: network
+// errors, throttling and server errors are; rejected payloads are not
+func isRetryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	return true
+}
+
+// attackSequence is every scenario the simulator can generate, in demo order
+var attackSequence = []string{"HTTP_FLOOD", "SYN_FLOOD", "SLOWLORIS", "UDP_FLOOD", "DNS_AMPLIFICATION", "ACK_FLOOD", "SLOW_POST", "DNS_WATER_TORTURE", "CARPET_BOMBING", "CREDENTIAL_STUFFING", "BOT_FLOOD"}
+
+// generateAttack returns one second of traffic for an attack type
+func (s *Simulator) generateAttack(attackType string) []models.TrafficRequest {
+	switch attackType {
+	case "SYN_FLOOD":
+		return s.GenerateSYNFlood()
+	case "HTTP_FLOOD":
+		return s.GenerateHTTPFlood()
+	case "SLOWLORIS":
+		return s.GenerateSlowloris()
+	case "UDP_FLOOD":
+		return s.GenerateUDPFlood()
+	case "DNS_AMPLIFICATION":
+		return s.GenerateDNSAmplification()
+	case "ACK_FLOOD":
+		return s.GenerateACKFlood()
+	case "SLOW_POST":
+		return s.GenerateSlowPOST()
+	case "DNS_WATER_TORTURE":
+		return s.GenerateDNSWaterTorture()
+	case "CARPET_BOMBING":
+		return s.GenerateCarpetBombing()
+	case "CREDENTIAL_STUFFING":
+		return s.GenerateCredentialStuffing()
+	case "BOT_FLOOD":
+		return s.GenerateBotFlood()
+	}
+	return nil
+}
+
+// sendTick sends one second of normal traffic, plus attack traffic unless
+// attackType is empty
+func (s *Simulator) sendTick(attackType string, phase *PhaseStats) {
+	for i := 0; i < s.normalRate; i++ {
+		s.sendAsync(s.GenerateNormalTraffic(), phase)
+	}
+
+	for _, req := range s.generateAttack(attackType) {
+		s.sendAsync(req, phase)
+	}
+}
+
 // Run starts the simulator
 func (s *Simulator) Run() {
 	fmt.Println("🚀 Starting Traffic Simulator...")
 	fmt.Println("Generating normal traffic at", s.normalRate, "req/sec")
+	if s.environment != "" {
+		fmt.Println("Tagging traffic with environment", s.environment)
+	}
 	fmt.Println("🎯 DEMO MODE: Will cycle through all attack types")
 
 	ticker := time.NewTicker(time.Second)
@@ -212,53 +697,43 @@ func (s *Simulator) Run() {
 	attackTicker := time.NewTicker(10 * time.Second) // Attack every 10 seconds
 	defer attackTicker.Stop()
 
-	// Attack sequence for demo
-	attackSequence := []string{"HTTP_FLOOD", "SYN_FLOOD", "SLOWLORIS", "UDP_FLOOD"}
 	currentAttackIndex := 0
 
 	// Start first attack immediately
 	s.attackActive = true
 	s.attackType = attackSequence[0]
+	s.phase = newPhaseStats(s.attackType)
 	fmt.Printf("⚠️  Starting %s attack\n", s.attackType)
 
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
 	for {
 		select {
-		case <-ticker.C:
-			// Generate normal traffic
-			for i := 0; i < s.normalRate; i++ {
-				req := s.GenerateNormalTraffic()
-				go s.SendTraffic(req)
-			}
+		case <-interrupt:
+			s.phase.finish()
+			s.summaries.Wait()
+			fmt.Println(s.phase.Summary())
+			return
 
-			// Generate attack traffic if active
+		case <-ticker.C:
+			attackType := ""
 			if s.attackActive {
-				var attackRequests []models.TrafficRequest
-
-				switch s.attackType {
-				case "SYN_FLOOD":
-					attackRequests = s.GenerateSYNFlood()
-				case "HTTP_FLOOD":
-					attackRequests = s.GenerateHTTPFlood()
-				case "SLOWLORIS":
-					attackRequests = s.GenerateSlowloris()
-				case "UDP_FLOOD":
-					attackRequests = s.GenerateUDPFlood()
-				}
-
-				for _, req := range attackRequests {
-					go s.SendTraffic(req)
-				}
+				attackType = s.attackType
 			}
+			s.sendTick(attackType, s.phase)
 
 		case <-attackTicker.C:
 			// Cycle to next attack type
 			if s.attackActive {
 				fmt.Println("✅ Attack stopped")
 				s.attackActive = false
+				s.startPhase("NORMAL")
 			} else {
 				currentAttackIndex = (currentAttackIndex + 1) % len(attackSequence)
 				s.attackActive = true
 				s.attackType = attackSequence[currentAttackIndex]
+				s.startPhase(s.attackType)
 				fmt.Printf("⚠️  Starting %s attack\n", s.attackType)
 			}
 		}
@@ -266,10 +741,27 @@ func (s *Simulator) Run() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "boundary" {
+		os.Exit(runBoundary(os.Args[2:]))
+	}
+
+	environment := flag.String("env", "", "environment tag attached to generated traffic (e.g. staging)")
+	ipv6Share := flag.Float64("ipv6-share", 0.2, "share of generated source addresses that are IPv6, from 0 to 1")
+	flag.Parse()
+	if *ipv6Share < 0 || *ipv6Share > 1 {
+		fmt.Fprintln(os.Stderr, "-ipv6-share must be between 0 and 1")
+		os.Exit(2)
+	}
+
 	rand.Seed(time.Now().UnixNano())
 
 	serverURL := "http://localhost:8888"
 	simulator := NewSimulator(serverURL)
+	simulator.environment = *environment
+	simulator.ipv6Share = *ipv6Share
 
 	fmt.Println("DDoS Detection - Traffic Simulator")
 	fmt.Println("===================================")
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/notify"
+)
+
+// Exit codes of verify mode, so cron and Kubernetes CronJobs can tell a
+// detection regression from a broken run
+const (
+	verifyPassed = 0
+	verifyFailed = 1
+	verifyError  = 2
+)
+
+// clockSlack tolerates clock skew between simulator and server when
+// matching attacks to scenarios
+const clockSlack = 5 * time.Second
+
+// scenarioResult is the outcome of one verification scenario
+type scenarioResult struct {
+	Scenario         string   `json:"scenario"`
+	Detected         bool     `json:"detected"`
+	DetectionSeconds float64  `json:"detection_seconds,omitempty"` // from attack start to the attack being reported
+	OtherTypes       []string `json:"other_types,omitempty"`       // other attack types reported during the scenario
+	Delivered        int64    `json:"delivered"`
+	Failed           int64    `json:"failed"`
+}
+
+// verifyReport is the accuracy evaluation of a whole verification run
+type verifyReport struct {
+	Environment    string           `json:"environment"`
+	Scenarios      []scenarioResult `json:"scenarios"`
+	DetectionRate  float64          `json:"detection_rate"`
+	FalsePositives []string         `json:"false_positives"` // attack types reported during the baseline
+	Passed         bool             `json:"passed"`
+}
+
+// runVerify replays a fixed battery of scenarios against a server and
+// checks each one is detected, returning the process exit code
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8888", "server to validate")
+	environment := fs.String("env", "verify", "environment tag for verification traffic, keeping it apart from real traffic")
+	scenarios := fs.String("scenarios", strings.Join(attackSequence, ","), "comma-separated attack scenarios to run")
+	baseline := fs.Duration("baseline", time.Minute, "normal-only traffic sent first; any attack reported during it is a false positive")
+	attackDuration := fs.Duration("attack-duration", 20*time.Second, "how long each attack scenario runs")
+	detectTimeout := fs.Duration("detect-timeout", 45*time.Second, "how long after a scenario ends to wait for its detection")
+	cooldown := fs.Duration("cooldown", 70*time.Second, "quiet time between scenarios so the analysis window drains")
+	minRate := fs.Float64("min-detection-rate", 1.0, "share of scenarios that must be detected for the run to pass")
+	webhooksFile := fs.String("webhooks-file", "", "webhooks file, as the server's -webhooks-file, whose webhooks and channels subscribed to verify.passed or verify.failed are sent the summary")
+	dashboardURL := fs.String("dashboard-url", "", "URL of the dashboard, linked from chat messages")
+	token := fs.String("token", os.Getenv("DDOS_API_TOKEN"), "viewer API token or session for reading attacks from a server requiring one")
+	jsonOut := fs.Bool("json", false, "print the report as JSON instead of text")
+	fs.Parse(args)
+
+	var notifiers []notify.Notifier
+	if *webhooksFile != "" {
+		webhooks, err := notify.LoadWebhooks(*webhooksFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Webhooks: %v\n", err)
+			return verifyError
+		}
+		notifiers, err = webhooks.Notifiers(*dashboardURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Webhooks: %v\n", err)
+			return verifyError
+		}
+	}
+
+	battery := splitScenarios(*scenarios)
+	for _, scenario := range battery {
+		if !knownScenario(scenario) {
+			fmt.Fprintf(os.Stderr, "❌ Unknown scenario %s; available: %s\n", scenario, strings.Join(attackSequence, ", "))
+			return verifyError
+		}
+	}
+
+	s := NewSimulator(*serverURL)
+	s.environment = *environment
+	s.token = *token
+
+	if _, err := s.fetchAttacks("active", url.Values{}); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Server %s is not reachable: %v\n", *serverURL, err)
+		return verifyError
+	}
+
+	report := verifyReport{Environment: *environment}
+
+	fmt.Printf("🧪 Baseline: %s of normal traffic\n", *baseline)
+	start := time.Now()
+	s.sendFor("", *baseline)
+	time.Sleep(*detectTimeout)
+	raised, err := s.attacksSince(start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Fetching attacks: %v\n", err)
+		return verifyError
+	}
+	report.FalsePositives = attackTypes(raised)
+	time.Sleep(*cooldown)
+
+	for _, scenario := range battery {
+		fmt.Printf("⚠️  Scenario %s for %s\n", scenario, *attackDuration)
+		result, err := s.verifyScenario(scenario, *attackDuration, *detectTimeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Scenario %s: %v\n", scenario, err)
+			return verifyError
+		}
+		report.Scenarios = append(report.Scenarios, result)
+		time.Sleep(*cooldown)
+	}
+
+	detected := 0
+	for _, result := range report.Scenarios {
+		if result.Detected {
+			detected++
+		}
+	}
+	if len(report.Scenarios) > 0 {
+		report.DetectionRate = float64(detected) / float64(len(report.Scenarios))
+	}
+	report.Passed = report.DetectionRate >= *minRate && len(report.FalsePositives) == 0
+
+	summary := report.Summary()
+	if *jsonOut {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Println(summary)
+	}
+
+	status := "PASS"
+	if !report.Passed {
+		status = "FAIL"
+	}
+	notification := notify.ReportNotification(models.ValidationReport{
+		Title:   fmt.Sprintf("Detection regression run (%s): %s", *environment, status),
+		Passed:  report.Passed,
+		Summary: summary,
+		Details: report,
+	})
+	if !notifyReport(notifiers, notification) {
+		return verifyError
+	}
+
+	if !report.Passed {
+		return verifyFailed
+	}
+	return verifyPassed
+}
+
+// notifyReport delivers the run's outcome to every notifier subscribed to
+// it, with the server's retries, and reports whether none failed
+func notifyReport(notifiers []notify.Notifier, notification models.Notification) bool {
+	delivered := true
+	for _, notifier := range notifiers {
+		if !notifier.Subscribes(notification.Event) {
+			continue
+		}
+		final := notify.Deliver(context.Background(), notifier, notification, notify.DefaultRetryPolicy(), func(models.NotificationDelivery) {})
+		if final.Status == notify.StatusFailed {
+			fmt.Fprintf(os.Stderr, "❌ Notifying %s: %s\n", notifier.Name(), final.Error)
+			delivered = false
+		}
+	}
+	return delivered
+}
+
+// verifyScenario runs one attack and polls the server until it reports an
+// attack of the same type or the timeout expires
+func (s *Simulator) verifyScenario(scenario string, duration, timeout time.Duration) (scenarioResult, error) {
+	result := scenarioResult{Scenario: scenario}
+
+	start := time.Now()
+	phase := s.sendFor(scenario, duration)
+	result.Delivered = phase.Delivered.Load()
+	result.Failed = phase.Failed.Load()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		raised, err := s.attacksSince(start)
+		if err != nil {
+			// The server may still be digesting the flood; keep polling
+			if time.Now().After(deadline) {
+				return result, err
+			}
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, attack := range raised {
+			if slices.Contains(attack.Types(), scenario) && !result.Detected {
+				result.Detected = true
+				result.DetectionSeconds = time.Since(start).Round(time.Second).Seconds()
+			}
+		}
+
+		if result.Detected || time.Now().After(deadline) {
+			for _, attackType := range attackTypes(raised) {
+				if attackType != scenario {
+					result.OtherTypes = append(result.OtherTypes, attackType)
+				}
+			}
+			return result, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// sendFor sends traffic for a scenario ("" for normal traffic only) at one
+// batch per second and returns its delivery stats
+func (s *Simulator) sendFor(attackType string, duration time.Duration) *PhaseStats {
+	name := attackType
+	if name == "" {
+		name = "NORMAL"
+	}
+	phase := newPhaseStats(name)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	end := time.After(duration)
+
+	s.sendTick(attackType, phase)
+	for {
+		select {
+		case <-ticker.C:
+			s.sendTick(attackType, phase)
+		case <-end:
+			phase.finish()
+			fmt.Println(phase.Summary())
+			return phase
+		}
+	}
+}
+
+// fetchAttacks lists the attacks of the simulator's environment from an
+// attacks endpoint, active or history
+func (s *Simulator) fetchAttacks(endpoint string, query url.Values) ([]models.Attack, error) {
+	query.Set("environment", s.environment)
+	req, err := http.NewRequest(http.MethodGet, s.serverURL+"/api/v1/attacks/"+endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode}
+	}
+
+	var body struct {
+		Attacks []models.Attack `json:"attacks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Attacks, nil
+}
+
+// attacksSince returns attacks that started, or began a new pulse-wave
+// burst, after since. Ended attacks are read from history as well, so an
+// attack that was detected and ended between two polls still counts.
+func (s *Simulator) attacksSince(since time.Time) ([]models.Attack, error) {
+	attacks, err := s.fetchAttacks("active", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	ended, err := s.fetchAttacks("history", url.Values{"limit": {"1000"}})
+	if err != nil {
+		return nil, err
+	}
+	for _, attack := range ended {
+		if !slices.ContainsFunc(attacks, func(a models.Attack) bool { return a.ID == attack.ID }) {
+			attacks = append(attacks, attack)
+		}
+	}
+
+	since = since.Add(-clockSlack)
+	raised := make([]models.Attack, 0)
+	for _, attack := range attacks {
+		if attack.StartTime.After(since) || (attack.Pulse != nil && attack.Pulse.LastBurst.After(since)) {
+			raised = append(raised, attack)
+		}
+	}
+	return raised, nil
+}
+
+// Summary formats the report for logs and notifications
+func (r verifyReport) Summary() string {
+	var b strings.Builder
+
+	status := "✅ PASS"
+	if !r.Passed {
+		status = "❌ FAIL"
+	}
+	fmt.Fprintf(&b, "%s: detected %.0f%% of %d scenarios in %s, %d false positives\n",
+		status, r.DetectionRate*100, len(r.Scenarios), r.Environment, len(r.FalsePositives))
+
+	for _, result := range r.Scenarios {
+		if result.Detected {
+			fmt.Fprintf(&b, "  ✅ %-20s detected after %.0fs\n", result.Scenario, result.DetectionSeconds)
+		} else {
+			fmt.Fprintf(&b, "  ❌ %-20s missed (%d delivered, %d failed)\n", result.Scenario, result.Delivered, result.Failed)
+		}
+	}
+	if len(r.FalsePositives) > 0 {
+		fmt.Fprintf(&b, "  ❌ baseline raised %s\n", strings.Join(r.FalsePositives, ", "))
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func splitScenarios(spec string) []string {
+	scenarios := make([]string, 0)
+	for _, scenario := range strings.Split(spec, ",") {
+		if scenario = strings.ToUpper(strings.TrimSpace(scenario)); scenario != "" {
+			scenarios = append(scenarios, scenario)
+		}
+	}
+	return scenarios
+}
+
+func knownScenario(name string) bool {
+	for _, scenario := range attackSequence {
+		if scenario == name {
+			return true
+		}
+	}
+	return false
+}
+
+// attackTypes lists the distinct types of the given attacks, and of the
+// vectors of multi-vector ones, sorted
+func attackTypes(attacks []models.Attack) []string {
+	seen := make(map[string]bool)
+	types := make([]string, 0)
+	for _, attack := range attacks {
+		for _, attackType := range attack.Types() {
+			if !seen[attackType] {
+				seen[attackType] = true
+				types = append(types, attackType)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,53 @@
+// Command alert-consumer is a minimal program reacting to dashboard alerts
+// through package alerts. It prints each alert and keeps a running count
+// per attack type; replace handle with whatever should happen on an alert.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/nshruti113/ddos-detection-dashboard/pkg/alerts"
+)
+
+func main() {
+	addr := flag.String("redis-addr", "localhost:6379", "Redis address of the deployment")
+	prefix := flag.String("redis-key-prefix", "", "key prefix the server runs with")
+	flag.Parse()
+
+	subscriber, err := alerts.NewSubscriber(alerts.Config{Addr: *addr, KeyPrefix: *prefix})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer subscriber.Close()
+
+	// Show where things stand before waiting for new alerts
+	last, err := subscriber.Last()
+	switch {
+	case errors.Is(err, alerts.ErrNoAlert):
+		fmt.Println("No alerts published yet")
+	case err != nil:
+		log.Fatal(err)
+	default:
+		fmt.Printf("Last alert: %s at %s\n", last.Title, last.Timestamp.Format("15:04:05"))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	counts := make(map[string]int)
+	handle := func(alert alerts.Alert) {
+		counts[alert.AttackType]++
+		fmt.Printf("[%s] %s: %s (%d %s alerts so far)\n",
+			alert.Level, alert.Title, alert.Message, counts[alert.AttackType], alert.AttackType)
+	}
+
+	if err := subscriber.Run(ctx, handle); err != nil {
+		log.Fatal(err)
+	}
+}
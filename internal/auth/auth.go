@@ -0,0 +1,106 @@
+// Package auth maps API tokens and login sessions onto principals with roles
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+// Role grants a level of access; each role includes the ones below it
+type Role string
+
+const (
+	// RoleViewer reads metrics, attacks and alerts
+	RoleViewer Role = "viewer"
+	// RoleAnalyst also triages: acknowledges alerts, merges and splits attacks
+	RoleAnalyst Role = "analyst"
+	// RoleOperator also runs operational actions and reads the action audit
+	RoleOperator Role = "operator"
+	// RoleAdmin also changes thresholds, lists and policies, mitigates by
+	// hand, silences attacks and engages the kill switch
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleAnalyst:  2,
+	RoleOperator: 3,
+	RoleAdmin:    4,
+}
+
+// Valid reports whether the role is known
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether the role includes the required one
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] > 0 && roleRank[r] >= roleRank[required]
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+// TokenStore authenticates static bearer tokens
+type TokenStore struct {
+	tokens []tokenEntry
+}
+
+type tokenEntry struct {
+	token     []byte
+	principal Principal
+}
+
+// ParseTokens reads "name:role:token,..." e.g. "alice:admin:s3cret,ci:operator:t0ken"
+func ParseTokens(spec string) (*TokenStore, error) {
+	store := &TokenStore{}
+
+	for i, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.SplitN(part, ":", 3)
+		if len(fields) != 3 || fields[0] == "" || fields[2] == "" {
+			return nil, fmt.Errorf("token entry %d is not name:role:token", i+1)
+		}
+		role := Role(fields[1])
+		if !role.Valid() {
+			return nil, fmt.Errorf("token entry %q has unknown role %q", fields[0], role)
+		}
+
+		store.tokens = append(store.tokens, tokenEntry{
+			token:     []byte(fields[2]),
+			principal: Principal{Name: fields[0], Role: role},
+		})
+	}
+
+	return store, nil
+}
+
+// Empty reports whether no tokens are configured
+func (s *TokenStore) Empty() bool {
+	return s == nil || len(s.tokens) == 0
+}
+
+// Authenticate returns the principal a token belongs to
+func (s *TokenStore) Authenticate(token string) (Principal, bool) {
+	if s == nil || token == "" {
+		return Principal{}, false
+	}
+
+	// Compare against every entry so timing doesn't reveal which one matched
+	var found Principal
+	ok := false
+	for _, entry := range s.tokens {
+		if subtle.ConstantTimeCompare(entry.token, []byte(token)) == 1 {
+			found, ok = entry.principal, true
+		}
+	}
+	return found, ok
+}
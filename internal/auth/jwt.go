@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jwtIssuer marks the tokens this dashboard issued
+const jwtIssuer = "ddos-dashboard"
+
+// MinSecretLength is the shortest signing secret accepted, 256 bits
+const MinSecretLength = 32
+
+var (
+	// ErrInvalidToken is returned for a token that is malformed, forged or
+	// not a session token of this dashboard
+	ErrInvalidToken = errors.New("invalid session token")
+	// ErrExpiredToken is returned for a session token past its expiry
+	ErrExpiredToken = errors.New("session expired")
+)
+
+// jwtHeader is the only header issued and accepted; other algorithms,
+// "none" among them, are refused
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are the contents of a session token
+type Claims struct {
+	ID        string `json:"jti"`
+	Subject   string `json:"sub"`
+	Role      Role   `json:"role"`
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Principal is who the session is for
+func (c Claims) Principal() Principal {
+	return Principal{Name: c.Subject, Role: c.Role}
+}
+
+// Expires is when the session ends
+func (c Claims) Expires() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Sessions issues and verifies HS256 JSON Web Tokens for logged in users
+type Sessions struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSessions signs sessions lasting ttl with secret, which every node of
+// a cluster must share
+func NewSessions(secret string, ttl time.Duration) (*Sessions, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("session secret must be at least %d characters", MinSecretLength)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("session lifetime must be positive")
+	}
+	return &Sessions{secret: []byte(secret), ttl: ttl}, nil
+}
+
+// Issue starts a session for a principal
+func (s *Sessions) Issue(principal Principal, now time.Time) (string, Claims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", Claims{}, err
+	}
+	claims := Claims{
+		ID:        hex.EncodeToString(id),
+		Subject:   principal.Name,
+		Role:      principal.Role,
+		Issuer:    jwtIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + s.sign(signed), claims, nil
+}
+
+// Verify checks a session token's signature and expiry and returns its
+// claims
+func (s *Sessions) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return Claims{}, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if claims.Issuer != jwtIssuer || claims.ID == "" || claims.Subject == "" || !claims.Role.Valid() {
+		return Claims{}, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// IsSessionToken reports whether a bearer token looks like a session token
+// rather than a static API token
+func IsSessionToken(token string) bool {
+	return strings.HasPrefix(token, jwtHeader+".")
+}
+
+func (s *Sessions) sign(signed string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// forge builds a token with any header and claims, signed with the
+// sessions' own secret so only the field under test is wrong
+func forge(t *testing.T, s *Sessions, header string, claims Claims) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + s.sign(signed)
+}
+
+func TestVerify(t *testing.T) {
+	s, err := NewSessions(testSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	token, issued, err := s.Issue(Principal{Name: "alice", Role: RoleAnalyst}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs256 := `{"alg":"HS256","typ":"JWT"}`
+	with := func(change func(*Claims)) Claims {
+		c := issued
+		change(&c)
+		return c
+	}
+	parts := strings.Split(token, ".")
+	other, _ := NewSessions(strings.Repeat("x", MinSecretLength), time.Hour)
+	forged, _, _ := other.Issue(Principal{Name: "alice", Role: RoleAdmin}, now)
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		at    time.Time
+		want  error
+	}{
+		{"valid", token, now, nil},
+		{"valid until expiry", token, issued.Expires().Add(-time.Second), nil},
+		{"bad signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), now, ErrInvalidToken},
+		{"signed with another secret", forged, now, ErrInvalidToken},
+		{"tampered claims", parts[0] + "." + strings.Split(forge(t, s, hs256, with(func(c *Claims) { c.Role = RoleAdmin })), ".")[1] + "." + parts[2], now, ErrInvalidToken},
+		{"alg none", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + ".", now, ErrInvalidToken},
+		{"alg HS512", forge(t, s, `{"alg":"HS512","typ":"JWT"}`, issued), now, ErrInvalidToken},
+		{"alg RS256", forge(t, s, `{"alg":"RS256","typ":"JWT"}`, issued), now, ErrInvalidToken},
+		{"expired", token, issued.Expires(), ErrExpiredToken},
+		{"expired claims", forge(t, s, hs256, with(func(c *Claims) { c.ExpiresAt = now.Unix() - 1 })), now, ErrExpiredToken},
+		{"wrong issuer", forge(t, s, hs256, with(func(c *Claims) { c.Issuer = "someone-else" })), now, ErrInvalidToken},
+		{"no issuer", forge(t, s, hs256, with(func(c *Claims) { c.Issuer = "" })), now, ErrInvalidToken},
+		{"unknown role", forge(t, s, hs256, with(func(c *Claims) { c.Role = "root" })), now, ErrInvalidToken},
+		{"no subject", forge(t, s, hs256, with(func(c *Claims) { c.Subject = "" })), now, ErrInvalidToken},
+		{"empty", "", now, ErrInvalidToken},
+		{"one segment", parts[0], now, ErrInvalidToken},
+		{"two segments", parts[0] + "." + parts[1], now, ErrInvalidToken},
+		{"four segments", token + "." + parts[2], now, ErrInvalidToken},
+		{"payload not base64", parts[0] + ".!!!." + s.sign(parts[0]+".!!!"), now, ErrInvalidToken},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := s.Verify(tc.token, tc.at)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tc.want)
+			}
+			if tc.want == nil && claims != issued {
+				t.Errorf("Verify() claims = %+v, want %+v", claims, issued)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is someone who logs in to the dashboard
+type User struct {
+	Name string `yaml:"name"`
+	Role Role   `yaml:"role"`
+	// PasswordHash is a bcrypt hash, as printed by ddosctl hash-password
+	PasswordHash string `yaml:"password_hash"`
+}
+
+// usersFile is the layout of a users file
+type usersFile struct {
+	Users []User `yaml:"users"`
+}
+
+// UserStore checks the passwords of dashboard users
+type UserStore struct {
+	users map[string]User
+}
+
+// dummyHash is compared against for unknown users, so that a failed login
+// takes as long whether or not the user exists
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("no such user"), 12)
+	return hash
+})
+
+// LoadUsers reads a YAML or JSON users file
+func LoadUsers(path string) (*UserStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f usersFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	store := &UserStore{users: make(map[string]User, len(f.Users))}
+	for i, user := range f.Users {
+		if user.Name == "" {
+			return nil, fmt.Errorf("%s: user %d has no name", path, i+1)
+		}
+		if _, ok := store.users[user.Name]; ok {
+			return nil, fmt.Errorf("%s: user %s is defined twice", path, user.Name)
+		}
+		if !user.Role.Valid() {
+			return nil, fmt.Errorf("%s: user %s has unknown role %q", path, user.Name, user.Role)
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("%s: user %s: password_hash is not a bcrypt hash", path, user.Name)
+		}
+		store.users[user.Name] = user
+	}
+	return store, nil
+}
+
+// Len returns how many users there are
+func (s *UserStore) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.users)
+}
+
+// Login returns the principal of a user whose password matches
+func (s *UserStore) Login(name, password string) (Principal, bool) {
+	if s == nil {
+		return Principal{}, false
+	}
+	user, ok := s.users[name]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return Principal{}, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return Principal{}, false
+	}
+	return Principal{Name: user.Name, Role: user.Role}, true
+}
+
+// HashPassword hashes a password for a users file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	return string(hash), err
+}
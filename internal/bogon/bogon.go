@@ -0,0 +1,85 @@
+// Package bogon classifies addresses no internet traffic legitimately comes
+// from: private, reserved, multicast and unallocated space
+package bogon
+
+import "net/netip"
+
+// Range classes
+const (
+	Unspecified   = "unspecified"
+	Loopback      = "loopback"
+	Private       = "private" // RFC 1918 and IPv6 unique local
+	Shared        = "shared"  // RFC 6598 carrier-grade NAT
+	LinkLocal     = "link_local"
+	Documentation = "documentation"
+	Benchmarking  = "benchmarking"
+	Reserved      = "reserved" // IETF protocol assignments and the old class E
+	Multicast     = "multicast"
+	Unallocated   = "unallocated" // IPv6 outside the global unicast 2000::/3
+)
+
+type class struct {
+	name     string
+	prefixes []netip.Prefix
+}
+
+// classes are checked in order, so the catch-all IPv6 global unicast test
+// comes after the special ranges carved out of it
+var classes = []class{
+	{Unspecified, mustParsePrefixes("0.0.0.0/8", "::/128")},
+	{Loopback, mustParsePrefixes("127.0.0.0/8", "::1/128")},
+	{Private, mustParsePrefixes("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")},
+	{Shared, mustParsePrefixes("100.64.0.0/10")},
+	{LinkLocal, mustParsePrefixes("169.254.0.0/16", "fe80::/10")},
+	{Documentation, mustParsePrefixes("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32")},
+	{Benchmarking, mustParsePrefixes("198.18.0.0/15", "2001:2::/48")},
+	{Reserved, mustParsePrefixes("192.0.0.0/24", "240.0.0.0/4")},
+	{Multicast, mustParsePrefixes("224.0.0.0/4", "ff00::/8")},
+}
+
+// globalUnicastV6 is the only IPv6 space the registries allocate from
+var globalUnicastV6 = netip.MustParsePrefix("2000::/3")
+
+// Classify returns the class of range addr lies in, or "" when it is
+// ordinary internet space
+func Classify(addr netip.Addr) string {
+	addr = addr.Unmap()
+	if !addr.IsValid() {
+		return ""
+	}
+	for _, c := range classes {
+		for _, prefix := range c.prefixes {
+			if prefix.Contains(addr) {
+				return c.name
+			}
+		}
+	}
+	if addr.Is6() && !globalUnicastV6.Contains(addr) {
+		return Unallocated
+	}
+	return ""
+}
+
+// ClassifyIP is Classify for an address in text form; malformed addresses
+// are ""
+func ClassifyIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	return Classify(addr)
+}
+
+// Internal reports whether a class is space networks use internally,
+// legitimate on links that don't face the internet
+func Internal(name string) bool {
+	return name == Private || name == Shared || name == LinkLocal
+}
+
+func mustParsePrefixes(prefixes ...string) []netip.Prefix {
+	parsed := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		parsed = append(parsed, netip.MustParsePrefix(prefix))
+	}
+	return parsed
+}
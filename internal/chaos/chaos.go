@@ -0,0 +1,204 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInjected marks failures produced by chaos mode rather than real faults
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config selects which failures to inject. Rates are probabilities in [0, 1].
+type Config struct {
+	RedisLatency        time.Duration `json:"redis_latency"`
+	RedisErrorRate      float64       `json:"redis_error_rate"`
+	WSDropRate          float64       `json:"ws_drop_rate"`
+	NotifierTimeoutRate float64       `json:"notifier_timeout_rate"`
+	NotifierTimeout     time.Duration `json:"notifier_timeout"`
+}
+
+// ParseConfig reads a comma-separated spec such as
+// "redis_latency=200ms,redis_error_rate=0.05,ws_drop_rate=0.2"
+func ParseConfig(spec string) (Config, error) {
+	cfg := Config{NotifierTimeout: 10 * time.Second}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return cfg, fmt.Errorf("chaos setting %q is not key=value", part)
+		}
+
+		var err error
+		switch key {
+		case "redis_latency":
+			cfg.RedisLatency, err = time.ParseDuration(value)
+		case "redis_error_rate":
+			cfg.RedisErrorRate, err = parseRate(value)
+		case "ws_drop_rate":
+			cfg.WSDropRate, err = parseRate(value)
+		case "notifier_timeout_rate":
+			cfg.NotifierTimeoutRate, err = parseRate(value)
+		case "notifier_timeout":
+			cfg.NotifierTimeout, err = time.ParseDuration(value)
+		default:
+			err = fmt.Errorf("unknown setting")
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("chaos setting %q: %w", key, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate must be between 0 and 1")
+	}
+	return rate, nil
+}
+
+// Stats counts the faults injected so far
+type Stats struct {
+	RedisDelayed     int64 `json:"redis_delayed"`
+	RedisFailed      int64 `json:"redis_failed"`
+	WSDropped        int64 `json:"ws_dropped"`
+	NotifierTimeouts int64 `json:"notifier_timeouts"`
+}
+
+// Injector decides, per call site, whether to inject a fault. A nil
+// *Injector is valid and never injects anything.
+type Injector struct {
+	cfg Config
+
+	redisDelayed     atomic.Int64
+	redisFailed      atomic.Int64
+	wsDropped        atomic.Int64
+	notifierTimeouts atomic.Int64
+}
+
+func NewInjector(cfg Config) *Injector {
+	return &Injector{cfg: cfg}
+}
+
+// Config returns the active chaos settings
+func (i *Injector) Config() Config {
+	if i == nil {
+		return Config{}
+	}
+	return i.cfg
+}
+
+// Stats returns how many faults have been injected
+func (i *Injector) Stats() Stats {
+	if i == nil {
+		return Stats{}
+	}
+	return Stats{
+		RedisDelayed:     i.redisDelayed.Load(),
+		RedisFailed:      i.redisFailed.Load(),
+		WSDropped:        i.wsDropped.Load(),
+		NotifierTimeouts: i.notifierTimeouts.Load(),
+	}
+}
+
+// DropWSFrame reports whether a WebSocket frame should be silently dropped
+func (i *Injector) DropWSFrame() bool {
+	if i == nil || !roll(i.cfg.WSDropRate) {
+		return false
+	}
+	i.wsDropped.Add(1)
+	return true
+}
+
+// NotifierFault simulates an outbound notification hanging until its
+// timeout. It returns ErrInjected when a fault was injected.
+func (i *Injector) NotifierFault(ctx context.Context) error {
+	if i == nil || !roll(i.cfg.NotifierTimeoutRate) {
+		return nil
+	}
+	i.notifierTimeouts.Add(1)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(i.cfg.NotifierTimeout):
+	}
+	return fmt.Errorf("%w: notifier timed out", ErrInjected)
+}
+
+// RedisHook returns a go-redis hook that delays and fails commands
+func (i *Injector) RedisHook() redis.Hook {
+	return redisHook{i}
+}
+
+type redisHook struct {
+	injector *Injector
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.injector.redisFault(ctx); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := h.injector.redisFault(ctx); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (i *Injector) redisFault(ctx context.Context) error {
+	if i.cfg.RedisLatency > 0 {
+		i.redisDelayed.Add(1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(i.cfg.RedisLatency):
+		}
+	}
+
+	if roll(i.cfg.RedisErrorRate) {
+		i.redisFailed.Add(1)
+		return fmt.Errorf("%w: redis command failed", ErrInjected)
+	}
+
+	return nil
+}
+
+func roll(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
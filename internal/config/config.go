@@ -0,0 +1,151 @@
+// Package config fills in command-line flags from a YAML config file and
+// from environment variables. Flags given on the command line win over the
+// environment, which wins over the file, which wins over the defaults.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// EnvPrefix starts the environment variable of every flag: -redis-addr is
+// DDOS_REDIS_ADDR
+const EnvPrefix = "DDOS_"
+
+// EnvName is the environment variable setting a flag
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Apply sets the flags of fs not given on its command line, which must have
+// been parsed, from the config file at path, if any, and then from the
+// environment.
+//
+// The file groups settings into sections, such as storage, detection,
+// notification and tls. A key is the name of a flag, or the rest of it
+// after the section's name, so tls: {cert: ...} sets -tls-cert. Keys
+// outside any section name flags directly. Lists are joined with commas.
+func Apply(fs *flag.FlagSet, path string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if path != "" {
+		settings, err := load(fs, path)
+		if err != nil {
+			return err
+		}
+		for name, setting := range settings {
+			if explicit[name] {
+				continue
+			}
+			if err := fs.Set(name, setting.value); err != nil {
+				return fmt.Errorf("%s: %s: invalid value %q: %w", path, setting.key, setting.value, err)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		if value, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("%s: invalid value %q: %w", EnvName(f.Name), value, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// setting is a flag value from the config file, under key
+type setting struct {
+	key   string
+	value string
+}
+
+// load reads the config file into flag values by flag name
+func load(fs *flag.FlagSet, path string) (map[string]setting, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]interface{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := make(map[string]setting)
+	set := func(key, name string, value interface{}) error {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: %s: no such setting", path, key)
+		}
+		if _, ok := settings[name]; ok {
+			return fmt.Errorf("%s: %s: -%s is set twice", path, key, name)
+		}
+		s, err := scalar(value)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+		settings[name] = setting{key: key, value: s}
+		return nil
+	}
+
+	// Sorted so that errors are the same from run to run
+	for _, key := range sortedKeys(file) {
+		section, ok := file[key].(map[string]interface{})
+		if !ok {
+			if err := set(key, key, file[key]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, name := range sortedKeys(section) {
+			flagName := name
+			if fs.Lookup(flagName) == nil {
+				flagName = key + "-" + name
+			}
+			if err := set(key+"."+name, flagName, section[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return settings, nil
+}
+
+// scalar renders a setting as a flag value
+func scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		return "", fmt.Errorf("expected a value or a list, not a section")
+	}
+	return fmt.Sprint(value), nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
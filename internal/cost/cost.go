@@ -0,0 +1,65 @@
+// Package cost estimates what an attack costs in infrastructure and
+// mitigation spend, from the excess traffic it caused
+package cost
+
+import (
+	"math"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const bytesPerGB = 1 << 30
+
+// Model prices the resources an attack consumes. Zero prices leave that
+// component out of the estimate.
+type Model struct {
+	Currency string
+	// Egress and ingress bandwidth, per GB of attack traffic
+	BandwidthPerGB float64
+	// Serving requests: load balancer, compute and WAF request charges
+	ComputePerMillion float64
+	// Scrubbing service fees while an attack is mitigated
+	ScrubbingPerHour float64
+	ScrubbingPerGB   float64
+}
+
+// DefaultModel uses typical public-cloud list prices
+func DefaultModel() Model {
+	return Model{
+		Currency:          "USD",
+		BandwidthPerGB:    0.09,
+		ComputePerMillion: 0.60,
+	}
+}
+
+// Estimate prices an attack's accumulated excess traffic. Ongoing attacks
+// are priced up to now, so the estimate grows until the attack ends.
+func (m Model) Estimate(attack models.Attack, now time.Time) *models.CostEstimate {
+	estimate := &models.CostEstimate{Currency: m.Currency}
+	if attack.Traffic != nil {
+		estimate.Requests = attack.Traffic.Requests
+		estimate.Bytes = attack.Traffic.Bytes
+	}
+
+	gb := float64(estimate.Bytes) / bytesPerGB
+	estimate.Bandwidth = roundCents(gb * m.BandwidthPerGB)
+	estimate.Compute = roundCents(float64(estimate.Requests) / 1e6 * m.ComputePerMillion)
+	estimate.AttackCost = roundCents(estimate.Bandwidth + estimate.Compute)
+
+	if attack.Mitigated {
+		end := now
+		if attack.EndTime != nil {
+			end = *attack.EndTime
+		}
+		hours := math.Max(end.Sub(attack.StartTime).Hours(), 0)
+		estimate.MitigationCost = roundCents(hours*m.ScrubbingPerHour + gb*m.ScrubbingPerGB)
+	}
+
+	estimate.Total = roundCents(estimate.AttackCost + estimate.MitigationCost)
+	return estimate
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
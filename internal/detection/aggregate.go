@@ -0,0 +1,93 @@
+package detection
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// maxSourcePrefixes caps the prefixes reported per attack
+const maxSourcePrefixes = 20
+
+// Aggregation levels: narrow prefixes are reported on their own, wide ones
+// when they hold several populated narrow prefixes
+const (
+	narrowPrefixV4 = 24
+	widePrefixV4   = 16
+	narrowPrefixV6 = 64
+	widePrefixV6   = 48
+)
+
+type prefixSources struct {
+	sources  int
+	requests int
+	subnets  map[netip.Prefix]bool // populated narrow prefixes, for wide prefixes
+}
+
+// aggregateSources collapses attacking sources into /24 and /16 prefixes
+// (/64 and /48 for IPv6). Nil unless most sources share reported prefixes,
+// in which case the prefixes describe the attack better than sample IPs.
+func (d *Detector) aggregateSources(ipCounts map[IPKey]int) []models.SourcePrefix {
+	narrow := make(map[netip.Prefix]*prefixSources)
+	wide := make(map[netip.Prefix]*prefixSources)
+	total := 0
+
+	for ip, count := range ipCounts {
+		if ip.IsZero() {
+			continue
+		}
+		addr := ip.Addr()
+		narrowBits, wideBits := narrowPrefixV6, widePrefixV6
+		if addr.Is4() {
+			narrowBits, wideBits = narrowPrefixV4, widePrefixV4
+		}
+		n, _ := addr.Prefix(narrowBits)
+		w, _ := addr.Prefix(wideBits)
+		total++
+
+		if narrow[n] == nil {
+			narrow[n] = &prefixSources{}
+		}
+		narrow[n].sources++
+		narrow[n].requests += count
+
+		if wide[w] == nil {
+			wide[w] = &prefixSources{subnets: make(map[netip.Prefix]bool)}
+		}
+		wide[w].sources++
+		wide[w].requests += count
+		wide[w].subnets[n] = true
+	}
+
+	prefixes := make([]models.SourcePrefix, 0)
+	covered := 0
+	for w, load := range wide {
+		if len(load.subnets) >= d.thresholds.SourcePrefixMinSubnets && load.sources >= d.thresholds.SourcePrefixMinSources {
+			prefixes = append(prefixes, models.SourcePrefix{Prefix: w.String(), Sources: load.sources, Requests: load.requests})
+			covered += load.sources
+			continue
+		}
+		for n := range load.subnets {
+			if narrow[n].sources >= d.thresholds.SourcePrefixMinSources {
+				prefixes = append(prefixes, models.SourcePrefix{Prefix: n.String(), Sources: narrow[n].sources, Requests: narrow[n].requests})
+				covered += narrow[n].sources
+			}
+		}
+	}
+
+	if total == 0 || float64(covered)/float64(total) < d.thresholds.SourcePrefixShareMin {
+		return nil
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Requests != prefixes[j].Requests {
+			return prefixes[i].Requests > prefixes[j].Requests
+		}
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+	if len(prefixes) > maxSourcePrefixes {
+		prefixes = prefixes[:maxSourcePrefixes]
+	}
+	return prefixes
+}
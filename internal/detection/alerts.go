@@ -0,0 +1,171 @@
+package detection
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// alertRetention is how long an attack fingerprint's alert is remembered
+// after it was last seen, when that is longer than the cooldown
+const alertRetention = time.Hour
+
+// AlertOutcome says whether a detection raises an alert
+type AlertOutcome int
+
+const (
+	// AlertSuppressed repeats an alert already raised
+	AlertSuppressed AlertOutcome = iota
+	// AlertRaised is the first alert for a fingerprint, or the first
+	// since the cooldown passed
+	AlertRaised
+	// AlertEscalated re-alerts an attack that grew more severe
+	AlertEscalated
+)
+
+// AlertGate keeps attacks from alerting over and over. Attacks are told
+// apart by fingerprint, so an attack that ends and comes straight back
+// from the same sources against the same targets only alerts again once
+// the cooldown since its last alert has passed. Within it, and for as long
+// as an attack goes on, only an increase in severity alerts again.
+type AlertGate struct {
+	mu       sync.Mutex
+	cooldown time.Duration
+	alerted  map[string]*alertRecord // by fingerprint
+}
+
+type alertRecord struct {
+	attackID  string // last alerted
+	severity  string // highest alerted
+	alertedAt time.Time
+	seenAt    time.Time
+}
+
+// AlertState is the replicable state of a fingerprint an AlertGate has
+// alerted
+type AlertState struct {
+	Fingerprint string    `json:"fingerprint"`
+	AttackID    string    `json:"attack_id"`
+	Severity    string    `json:"severity"`
+	AlertedAt   time.Time `json:"alerted_at"`
+	SeenAt      time.Time `json:"seen_at"`
+}
+
+// NewAlertGate creates a gate; a cooldown of 0 alerts every new attack
+func NewAlertGate(cooldown time.Duration) *AlertGate {
+	return &AlertGate{
+		cooldown: cooldown,
+		alerted:  make(map[string]*alertRecord),
+	}
+}
+
+// Raise decides whether a newly detected attack alerts, returning when its
+// fingerprint last alerted if it is suppressed
+func (g *AlertGate) Raise(attack models.Attack, now time.Time) (AlertOutcome, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prune(now)
+
+	key := alertKey(attack)
+	record, ok := g.alerted[key]
+	if !ok || now.Sub(record.alertedAt) >= g.cooldown {
+		g.alerted[key] = &alertRecord{attackID: attack.ID, severity: attack.Severity, alertedAt: now, seenAt: now}
+		return AlertRaised, time.Time{}
+	}
+
+	record.seenAt = now
+	if g.escalated(record, attack, now) {
+		return AlertEscalated, time.Time{}
+	}
+	return AlertSuppressed, record.alertedAt
+}
+
+// Escalate reports whether an attack already alerted grew more severe than
+// it last alerted
+func (g *AlertGate) Escalate(attack models.Attack, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := alertKey(attack)
+	record, ok := g.alerted[key]
+	if !ok {
+		// Alerted before the gate's memory; only later changes count
+		g.alerted[key] = &alertRecord{attackID: attack.ID, severity: attack.Severity, alertedAt: now, seenAt: now}
+		return false
+	}
+	record.seenAt = now
+	return g.escalated(record, attack, now)
+}
+
+func (g *AlertGate) escalated(record *alertRecord, attack models.Attack, now time.Time) bool {
+	if models.SeverityRank(attack.Severity) <= models.SeverityRank(record.severity) {
+		return false
+	}
+	record.attackID = attack.ID
+	record.severity = attack.Severity
+	record.alertedAt = now
+	return true
+}
+
+// Alerted reports whether an attack raised its own alert rather than being
+// held back as a repeat, so that its end is worth telling
+func (g *AlertGate) Alerted(attack models.Attack) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	record, ok := g.alerted[alertKey(attack)]
+	return !ok || record.attackID == attack.ID
+}
+
+// prune forgets fingerprints neither alerted nor seen for the retention
+func (g *AlertGate) prune(now time.Time) {
+	retention := max(g.cooldown, alertRetention)
+	for key, record := range g.alerted {
+		if now.Sub(record.seenAt) > retention && now.Sub(record.alertedAt) > retention {
+			delete(g.alerted, key)
+		}
+	}
+}
+
+// Snapshot copies the alerted fingerprints for replication
+func (g *AlertGate) Snapshot() []AlertState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	alerts := make([]AlertState, 0, len(g.alerted))
+	for key, record := range g.alerted {
+		alerts = append(alerts, AlertState{
+			Fingerprint: key,
+			AttackID:    record.attackID,
+			Severity:    record.severity,
+			AlertedAt:   record.alertedAt,
+			SeenAt:      record.seenAt,
+		})
+	}
+	return alerts
+}
+
+// Restore replaces the alerted fingerprints with a snapshot
+func (g *AlertGate) Restore(alerts []AlertState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.alerted = make(map[string]*alertRecord, len(alerts))
+	for _, state := range alerts {
+		g.alerted[state.Fingerprint] = &alertRecord{
+			attackID:  state.AttackID,
+			severity:  state.Severity,
+			alertedAt: state.AlertedAt,
+			seenAt:    state.SeenAt,
+		}
+	}
+}
+
+// alertKey is the fingerprint alerts are deduplicated on
+func alertKey(attack models.Attack) string {
+	if attack.Fingerprint != "" {
+		return attack.Fingerprint
+	}
+	return Fingerprint(attack)
+}
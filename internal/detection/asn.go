@@ -0,0 +1,188 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/geoip"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// maxSourceASNs caps the autonomous systems reported per attack
+const maxSourceASNs = 10
+
+// DefaultHostingASNs are the networks of large hosting and cloud providers,
+// where floods come from rented servers rather than compromised devices:
+// Amazon, Google Cloud, Microsoft, DigitalOcean, OVH, Hetzner, Linode,
+// Vultr, Contabo, Alibaba, Tencent, Oracle, Scaleway, Leaseweb and M247
+var DefaultHostingASNs = []uint32{
+	16509, 14618, 396982, 8075, 14061, 16276, 24940, 63949,
+	20473, 51167, 45102, 132203, 31898, 12876, 60781, 9009,
+}
+
+// Locator resolves the autonomous system of a source
+type Locator interface {
+	LookupAddr(addr netip.Addr) (geoip.Location, bool)
+}
+
+// SetLocator adds GeoIP to every environment's detection: attacks are
+// broken down by the autonomous systems of their sources. Nil removes it.
+func (p *Pool) SetLocator(locator Locator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.locator = locator
+	for _, detector := range p.detectors {
+		detector.locator = locator
+	}
+}
+
+// hostingASN reports whether an AS is one of the environment's hosting networks
+func (d *Detector) hostingASN(asn uint32) bool {
+	return slices.Contains(d.thresholds.HostingASNs, asn)
+}
+
+// aggregateASNs groups attacking sources by autonomous system, busiest
+// first. Nil without GeoIP or when no source resolves.
+func (d *Detector) aggregateASNs(ipCounts map[IPKey]int) []models.ASNCount {
+	if d.locator == nil {
+		return nil
+	}
+
+	byASN := make(map[uint32]*models.ASNCount)
+	total := 0
+	for ip, count := range ipCounts {
+		total += count
+		if ip.IsZero() {
+			continue
+		}
+		loc, ok := d.locator.LookupAddr(ip.Addr())
+		if !ok || loc.ASN == 0 {
+			continue
+		}
+		as := byASN[loc.ASN]
+		if as == nil {
+			as = &models.ASNCount{ASN: loc.ASN, Org: loc.ASOrg, Hosting: d.hostingASN(loc.ASN)}
+			byASN[loc.ASN] = as
+		}
+		as.Sources++
+		as.Requests += int64(count)
+	}
+	if len(byASN) == 0 {
+		return nil
+	}
+
+	asns := make([]models.ASNCount, 0, len(byASN))
+	for _, as := range byASN {
+		as.Percentage = float64(as.Requests) / float64(total) * 100
+		asns = append(asns, *as)
+	}
+	sortASNs(asns)
+	if len(asns) > maxSourceASNs {
+		asns = asns[:maxSourceASNs]
+	}
+	return asns
+}
+
+// sortASNs orders autonomous systems busiest first
+func sortASNs(asns []models.ASNCount) {
+	sort.Slice(asns, func(i, j int) bool {
+		if asns[i].Requests != asns[j].Requests {
+			return asns[i].Requests > asns[j].Requests
+		}
+		return asns[i].ASN < asns[j].ASN
+	})
+}
+
+// detectHostingASNFlood detects a flood sent overwhelmingly from a handful
+// of hosting networks: rented servers, which upstream providers and the
+// hosts' abuse desks can filter at the source
+func (d *Detector) detectHostingASNFlood(w *Window) *models.Attack {
+	if w.Metrics.TotalRequests < w.Thresholds.HostingASNFloodThreshold {
+		return nil
+	}
+
+	hosting := make([]models.ASNCount, 0)
+	for asn, count := range w.Metrics.ASNCounts {
+		if d.hostingASN(asn) {
+			hosting = append(hosting, models.ASNCount{ASN: asn, Requests: int64(count), Hosting: true})
+		}
+	}
+	sortASNs(hosting)
+	if len(hosting) > w.Thresholds.HostingASNMaxASNs {
+		hosting = hosting[:w.Thresholds.HostingASNMaxASNs]
+	}
+
+	requests := int64(0)
+	flooding := make(map[uint32]*models.ASNCount, len(hosting))
+	for i := range hosting {
+		requests += hosting[i].Requests
+		hosting[i].Percentage = float64(hosting[i].Requests) / float64(w.Metrics.TotalRequests) * 100
+		flooding[hosting[i].ASN] = &hosting[i]
+	}
+	share := float64(requests) / float64(w.Metrics.TotalRequests)
+	if share < w.Thresholds.HostingASNShareMin {
+		return nil
+	}
+
+	sources := make(map[IPKey]int)
+	seen := make(map[uint32]map[IPKey]bool)
+	for _, req := range w.Requests {
+		as := flooding[req.ASN]
+		if as == nil {
+			continue
+		}
+		ip := ParseIPKey(req.SourceIP)
+		sources[ip] += w.Weight
+		if seen[req.ASN] == nil {
+			seen[req.ASN] = make(map[IPKey]bool)
+		}
+		if !seen[req.ASN][ip] {
+			seen[req.ASN][ip] = true
+			as.Sources++
+		}
+		if as.Org == "" {
+			as.Org = req.ASOrg
+		}
+	}
+
+	names := make([]string, 0, len(hosting))
+	for _, as := range hosting {
+		name := fmt.Sprintf("AS%d", as.ASN)
+		if as.Org != "" {
+			name += " " + as.Org
+		}
+		names = append(names, fmt.Sprintf("%s (%.0f%%)", name, as.Percentage))
+	}
+
+	confidence := math.Min(0.5*math.Min(float64(requests)/float64(w.Thresholds.HostingASNFloodThreshold*2), 1.0)+0.5*share, 1.0)
+
+	ev := withSources(evidence(
+		atLeast("requests", float64(w.Metrics.TotalRequests), "HostingASNFloodThreshold", float64(w.Thresholds.HostingASNFloodThreshold)),
+		atLeast("hosting_asn_share", share, "HostingASNShareMin", w.Thresholds.HostingASNShareMin),
+	), sources)
+	ev.Features["hosting_asns"] = float64(len(hosting))
+	ev.Features["hosting_requests"] = float64(requests)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "HOSTING_ASN_FLOOD",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(sources, 20),
+		SourcePrefixes: d.aggregateSources(sources),
+		SourceASNs:     hosting,
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("Flood from hosting networks: %.1f%% of %d requests from %d hosting ASNs: %s",
+			share*100, w.Metrics.TotalRequests, len(hosting), strings.Join(names, ", ")),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
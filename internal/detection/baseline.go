@@ -0,0 +1,113 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// minBaselineSamples is how many observed periods a rebuild needs
+	minBaselineSamples = 3
+	// baselineAlpha is the weight of each newly learned window
+	baselineAlpha = 0.1
+)
+
+// minStandardDeviation keeps the request-rate deviation from collapsing on
+// very steady traffic, which would make every fluctuation a huge Z-score
+func minStandardDeviation(mean float64) float64 {
+	return math.Max(mean*0.1, 1)
+}
+
+// RebuildBaseline replaces the learned request-rate baseline with the mean
+// and standard deviation of historical hourly rates, and the seasonal
+// buckets those hours fall into with theirs. Hours without traffic (e.g.
+// while the server was down) are ignored.
+func (d *Detector) RebuildBaseline(history []HourlyRate) (Baseline, error) {
+	samples := make([]float64, 0, len(history))
+	for _, hour := range history {
+		if hour.Rate > 0 {
+			samples = append(samples, hour.Rate)
+		}
+	}
+	if len(samples) < minBaselineSamples {
+		return *d.baseline, fmt.Errorf("need at least %d periods with traffic, have %d", minBaselineSamples, len(samples))
+	}
+
+	mean, stddev := meanAndDeviation(samples)
+	stddev = math.Max(stddev, minStandardDeviation(mean))
+
+	baseline := *d.baseline
+	baseline.AverageRequestRate = mean
+	baseline.StandardDeviation = stddev
+	baseline.Samples = max(baseline.Samples, len(samples))
+	baseline.rebuildSeasonal(history, d.localNow().Location())
+	d.baseline = &baseline
+
+	return baseline, nil
+}
+
+// RebuildBaseline rebuilds one environment's baseline from history
+func (p *Pool) RebuildBaseline(env string, history []HourlyRate) (Baseline, error) {
+	detector := p.Get(env)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return detector.RebuildBaseline(history)
+}
+
+// meanAndDeviation returns the mean and population standard deviation
+func meanAndDeviation(samples []float64) (float64, float64) {
+	mean := 0.0
+	for _, sample := range samples {
+		mean += sample
+	}
+	mean /= float64(len(samples))
+
+	variance := 0.0
+	for _, sample := range samples {
+		variance += (sample - mean) * (sample - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(samples)))
+}
+
+// LearnBaselines feeds the window each environment's detector last analyzed
+// into its baseline, unless an attack was detected in that environment, so
+// attack traffic never becomes the norm. Call it once per analysis cycle
+// with everything the cycle detected. The sources of quiet environments are
+// learned into their profiles too; the error is that of storing them.
+func (p *Pool) LearnBaselines(attacks []models.Attack) error {
+	attacked := make(map[string]bool)
+	for _, attack := range attacks {
+		attacked[normalizeEnvironment(attack.Environment)] = true
+	}
+
+	p.mu.Lock()
+	store := p.profiles
+	profiles := make(map[string][]models.SourceProfile)
+	for env, detector := range p.detectors {
+		if detector.observed != nil && !attacked[env] {
+			detector.UpdateBaseline(detector.observed)
+			detector.learnChangePoints(detector.observed)
+			detector.forecast.learn(detector.localNow(), float64(detector.observed.TotalRequests))
+		}
+		if !attacked[env] {
+			if learned := detector.learnSourceProfiles(); len(learned) > 0 {
+				profiles[env] = learned
+			}
+		}
+		detector.observed, detector.observedPrefixes = nil, nil
+		detector.observedSources, detector.observedProfiles = nil, nil
+	}
+	p.mu.Unlock()
+
+	// Profiles are stored outside the lock ingest checks the allowlist under
+	for env, learned := range profiles {
+		if err := store.StoreSourceProfiles(env, learned); err != nil {
+			return fmt.Errorf("storing source profiles of %s: %w", env, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// carpetBombMaxTargetShare is the largest share of a prefix's traffic a
+	// single address may take for the load to count as spread thinly
+	carpetBombMaxTargetShare = 0.2
+	// carpetBombMinSamples is how many windows the per-prefix traffic needs
+	// before prefixes are held to it
+	carpetBombMinSamples = 10
+	// minPrefixRequests is the learned traffic below which a prefix is
+	// forgotten, keeping the baseline to the prefixes traffic goes to
+	minPrefixRequests = 1.0
+)
+
+// subnetLoad tallies traffic into one destination prefix
+type subnetLoad struct {
+	total   int
+	targets map[IPKey]int
+	sources map[IPKey]int
+}
+
+// detectCarpetBombing detects attacks spread thinly across many addresses in
+// one destination prefix. Per-host volume stays under every per-IP threshold,
+// but the prefix as a whole (and its shared uplink) is flooded: well beyond
+// what it normally receives, once that is learned, so a busy prefix of many
+// servers isn't mistaken for one under attack.
+func (d *Detector) detectCarpetBombing(win *Window) *models.Attack {
+	w := d.sampleWeight()
+	subnets := make(map[netip.Prefix]*subnetLoad)
+
+	for _, req := range win.Requests {
+		prefix, ok := d.destinationPrefix(req.DestIP)
+		if !ok {
+			continue
+		}
+
+		load, ok := subnets[prefix]
+		if !ok {
+			load = &subnetLoad{
+				targets: make(map[IPKey]int),
+				sources: make(map[IPKey]int),
+			}
+			subnets[prefix] = load
+		}
+		load.total += w
+		load.targets[ParseIPKey(req.DestIP)] += w
+		load.sources[ParseIPKey(req.SourceIP)] += w
+	}
+
+	if win.Resolution.Window == AnalysisWindow {
+		d.observedPrefixes = make(map[string]int, len(subnets))
+		for prefix, load := range subnets {
+			d.observedPrefixes[prefix.String()] = load.total
+		}
+	}
+
+	var target *subnetLoad
+	var targetPrefix netip.Prefix
+	for prefix, load := range subnets {
+		if load.total < d.thresholds.CarpetBombThreshold || len(load.targets) < d.thresholds.CarpetBombMinTargets {
+			continue
+		}
+		if maxShare(load.targets, load.total) > carpetBombMaxTargetShare {
+			continue
+		}
+		if learned, ok := d.prefixBaseline(prefix); ok && float64(load.total) < learned*d.thresholds.CarpetBombBaselineFactor {
+			continue
+		}
+		if target == nil || load.total > target.total {
+			target, targetPrefix = load, prefix
+		}
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	volumeScore := math.Min(float64(target.total)/float64(d.thresholds.CarpetBombThreshold*3), 1.0)
+	spreadScore := math.Min(float64(len(target.targets))/float64(d.thresholds.CarpetBombMinTargets*4), 1.0)
+	confidence := math.Min(0.6*volumeScore+0.4*spreadScore, 1.0)
+
+	ev := withSources(evidence(
+		atLeast("prefix_requests", float64(target.total), "CarpetBombThreshold", float64(d.thresholds.CarpetBombThreshold)),
+		atLeast("prefix_targets", float64(len(target.targets)), "CarpetBombMinTargets", float64(d.thresholds.CarpetBombMinTargets)),
+	), target.sources)
+	ev.Features["max_target_share"] = maxShare(target.targets, target.total)
+	if learned, ok := d.prefixBaseline(targetPrefix); ok {
+		ev.Checks = append(ev.Checks, atLeast("prefix_requests_vs_baseline", float64(target.total)/math.Max(learned, minPrefixRequests), "CarpetBombBaselineFactor", d.thresholds.CarpetBombBaselineFactor))
+		ev.Features["prefix_baseline_requests"] = learned
+	}
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "CARPET_BOMBING",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(target.sources, 20),
+		SourcePrefixes: d.aggregateSources(target.sources),
+		SourceASNs:     d.aggregateASNs(target.sources),
+		TargetIPs:      []string{targetPrefix.String()},
+		Description:    fmt.Sprintf("Carpet bombing on %s: %d requests spread across %d addresses (max %.0f%% per address) from %d IPs", targetPrefix, target.total, len(target.targets), maxShare(target.targets, target.total)*100, len(target.sources)),
+		Mitigated:      false,
+		Evidence:       ev,
+	}
+}
+
+// prefixBaseline returns the requests a destination prefix normally
+// receives per window, once enough windows are learned. A prefix not
+// learned normally receives none.
+func (d *Detector) prefixBaseline(prefix netip.Prefix) (float64, bool) {
+	if d.baseline.PrefixSamples < carpetBombMinSamples {
+		return 0, false
+	}
+	return d.baseline.PrefixRequests[prefix.String()], true
+}
+
+// learnPrefixes moves the learned requests per destination prefix toward a
+// window's. Nil totals, from a window carpet bombing wasn't checked in,
+// leave them be.
+func (b *Baseline) learnPrefixes(totals map[string]int) {
+	if totals == nil {
+		return
+	}
+
+	alpha := math.Max(baselineAlpha, 1/float64(b.PrefixSamples+1))
+	learned := make(map[string]float64, len(b.PrefixRequests)+len(totals))
+	for prefix, requests := range b.PrefixRequests {
+		learned[prefix] = (1 - alpha) * requests
+	}
+	for prefix, total := range totals {
+		learned[prefix] += alpha * float64(total)
+	}
+	for prefix, requests := range learned {
+		if requests < minPrefixRequests {
+			delete(learned, prefix)
+		}
+	}
+
+	b.PrefixRequests = learned
+	b.PrefixSamples++
+}
+
+// destinationPrefix returns the prefix an address is aggregated under
+func (d *Detector) destinationPrefix(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+
+	bits := d.thresholds.CarpetBombPrefixV6
+	if addr.Is4() {
+		bits = d.thresholds.CarpetBombPrefixV4
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// maxShare returns the largest count's share of total
+func maxShare(counts map[IPKey]int, total int) float64 {
+	max := 0
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+	return float64(max) / float64(total)
+}
@@ -0,0 +1,144 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// changePointAlpha is the weight of each learned window in a series'
+	// reference, far slower than the baseline so a ramp can't pull the
+	// reference along with it
+	changePointAlpha = 0.01
+	// minChangePointSamples is how many quiet windows a series learns
+	// before its statistics count, a minute of cycles
+	minChangePointSamples = 12
+)
+
+// ChangePointSeries is the CUSUM state of one traffic series: its
+// reference level and spread, and the accumulated evidence of a shift up
+// and down, in deviations beyond the allowed drift
+type ChangePointSeries struct {
+	Mean      float64
+	Deviation float64
+	Samples   int
+	High      float64
+	Low       float64
+}
+
+// ChangePoints tracks the series whose sustained shifts are flagged: the
+// request rate and unique sources rising, and the source entropy moving
+// either way, from traffic concentrating or a botnet spreading it out
+type ChangePoints struct {
+	RequestRate ChangePointSeries
+	UniqueIPs   ChangePointSeries
+	IPEntropy   ChangePointSeries
+}
+
+// changePointValues returns the series' values in a window
+func changePointValues(metrics *TrafficMetrics) (rate, uniqueIPs, entropy float64) {
+	return float64(metrics.TotalRequests), float64(metrics.UniqueIPs), metrics.IPEntropy
+}
+
+// floor keeps a series' deviation from collapsing on steady traffic
+func (s *ChangePointSeries) floor(min float64) float64 {
+	return math.Max(s.Deviation, min)
+}
+
+// accumulate adds one window's value to the series' CUSUM statistics,
+// capped at twice the limit so they decay soon after the shift ends
+func (s *ChangePointSeries) accumulate(value, deviation, drift, limit float64) {
+	if s.Samples < minChangePointSamples {
+		return
+	}
+	z := (value - s.Mean) / deviation
+	s.High = math.Min(math.Max(0, s.High+z-drift), 2*limit)
+	s.Low = math.Min(math.Max(0, s.Low-z-drift), 2*limit)
+}
+
+// learn feeds a quiet window into the reference, unless evidence of a
+// shift is building up, which would teach the series the shift itself
+func (s *ChangePointSeries) learn(value float64) {
+	if s.High > 0 || s.Low > 0 {
+		return
+	}
+
+	alpha := math.Max(changePointAlpha, 1/float64(s.Samples+1))
+	diff := value - s.Mean
+	variance := s.Deviation * s.Deviation
+	if s.Samples == 0 {
+		diff, variance = 0, 0
+		s.Mean = value
+	}
+
+	s.Mean += alpha * diff
+	s.Deviation = math.Sqrt((1 - alpha) * (variance + alpha*diff*diff))
+	s.Samples++
+}
+
+// detectChangePoint runs the CUSUM check over one cycle's AnalysisWindow,
+// flagging gradual ramps that never stand out from the baseline in a
+// single window. Call it once per cycle: its statistics build up over
+// consecutive windows.
+func (d *Detector) detectChangePoint(metrics *TrafficMetrics) *models.Attack {
+	rate, uniqueIPs, entropy := changePointValues(metrics)
+	drift, limit := d.thresholds.ChangePointDrift, d.thresholds.ChangePointLimit
+
+	cp := &d.changePoints
+	cp.RequestRate.accumulate(rate, cp.RequestRate.floor(minStandardDeviation(cp.RequestRate.Mean)), drift, limit)
+	cp.UniqueIPs.accumulate(uniqueIPs, cp.UniqueIPs.floor(minStandardDeviation(cp.UniqueIPs.Mean)), drift, limit)
+	cp.IPEntropy.accumulate(entropy, cp.IPEntropy.floor(0.1), drift, limit)
+
+	shifts := make([]string, 0, 3)
+	strongest := 0.0
+	ev := evidence()
+	report := func(name, feature string, statistic, value, reference float64, direction string) {
+		if statistic < limit {
+			return
+		}
+		shifts = append(shifts, fmt.Sprintf("%s %s to %.2f from %.2f (CUSUM %.1f)", name, direction, value, reference, statistic))
+		strongest = math.Max(strongest, statistic)
+		ev.Checks = append(ev.Checks, atLeast("cusum_"+feature+"_"+direction, statistic, "ChangePointLimit", limit))
+		ev.Features[feature] = value
+		ev.Features[feature+"_reference"] = reference
+	}
+	report("request rate", "request_rate", cp.RequestRate.High, rate, cp.RequestRate.Mean, "up")
+	report("unique IPs", "unique_ips", cp.UniqueIPs.High, uniqueIPs, cp.UniqueIPs.Mean, "up")
+	report("IP entropy", "ip_entropy", cp.IPEntropy.High, entropy, cp.IPEntropy.Mean, "up")
+	report("IP entropy", "ip_entropy", cp.IPEntropy.Low, entropy, cp.IPEntropy.Mean, "down")
+	if len(shifts) == 0 {
+		return nil
+	}
+
+	// At the limit a shift is as likely as not; at the cap it is certain
+	confidence := math.Min(strongest/(2*limit), 1.0)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "CHANGE_POINT",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(metrics.IPCounts, 20),
+		SourcePrefixes: d.aggregateSources(metrics.IPCounts),
+		SourceASNs:     d.aggregateASNs(metrics.IPCounts),
+		Description:    "Sustained traffic shift detected: " + strings.Join(shifts, ", "),
+		Evidence:       ev,
+		Window:         "medium",
+		WindowSeconds:  int(AnalysisWindow / time.Second),
+		Mitigated:      false,
+	}
+}
+
+// learnChangePoints feeds an attack-free window into the series' references
+func (d *Detector) learnChangePoints(metrics *TrafficMetrics) {
+	rate, uniqueIPs, entropy := changePointValues(metrics)
+	d.changePoints.RequestRate.learn(rate)
+	d.changePoints.UniqueIPs.learn(uniqueIPs)
+	d.changePoints.IPEntropy.learn(entropy)
+}
@@ -0,0 +1,209 @@
+package detection
+
+import (
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Prefix lengths sources are grouped by when comparing attacks. Botnets
+// rotate addresses within the same networks, so prefixes reveal a shared
+// actor better than exact IPs.
+const (
+	compareSourcePrefixV4 = 24
+	compareSourcePrefixV6 = 48
+)
+
+// AttackComparison summarizes how similar two incidents are
+type AttackComparison struct {
+	Attacks   [2]models.Attack   `json:"attacks"`
+	Timelines [2][]AlignedEvent  `json:"timelines"`
+	Sources   SourceOverlap      `json:"sources"`
+	Vectors   VectorDiff         `json:"vectors"`
+	Intensity [2]AttackIntensity `json:"intensity"`
+	Ratios    IntensityRatios    `json:"ratios"`
+}
+
+// AlignedEvent is a timeline event positioned relative to its attack's start
+type AlignedEvent struct {
+	models.TimelineEvent
+	OffsetSeconds float64 `json:"offset_sec"`
+}
+
+// SourceOverlap compares the attacking sources
+type SourceOverlap struct {
+	PrefixJaccard  float64  `json:"prefix_jaccard"`
+	IPJaccard      float64  `json:"ip_jaccard"`
+	SharedPrefixes []string `json:"shared_prefixes"`
+	SharedIPs      []string `json:"shared_ips"`
+}
+
+// VectorDiff compares attack vectors and targets
+type VectorDiff struct {
+	Shared        []string `json:"shared"`
+	OnlyFirst     []string `json:"only_first"`
+	OnlySecond    []string `json:"only_second"`
+	SharedTargets []string `json:"shared_targets"`
+}
+
+// AttackIntensity is how hard one attack hit
+type AttackIntensity struct {
+	Confidence      float64 `json:"confidence"`
+	Severity        string  `json:"severity"`
+	DurationSeconds float64 `json:"duration_sec"`
+	SourceCount     int     `json:"source_count"`
+	RequestsPerSec  float64 `json:"requests_per_sec,omitempty"`
+	Ongoing         bool    `json:"ongoing"`
+}
+
+// IntensityRatios divide the second attack's intensity by the first's
+type IntensityRatios struct {
+	Confidence     float64 `json:"confidence"`
+	Duration       float64 `json:"duration"`
+	SourceCount    float64 `json:"source_count"`
+	RequestsPerSec float64 `json:"requests_per_sec,omitempty"`
+}
+
+// CompareAttacks lines two incidents up side by side, e.g. to judge whether
+// tonight's attack comes from the same actor as last week's
+func CompareAttacks(first, second models.Attack, firstTimeline, secondTimeline []models.TimelineEvent) AttackComparison {
+	now := time.Now()
+	intensity := [2]AttackIntensity{attackIntensity(first, now), attackIntensity(second, now)}
+
+	return AttackComparison{
+		Attacks:   [2]models.Attack{first, second},
+		Timelines: [2][]AlignedEvent{alignTimeline(first, firstTimeline), alignTimeline(second, secondTimeline)},
+		Sources:   compareSources(first.SourceIPs, second.SourceIPs),
+		Vectors: VectorDiff{
+			Shared:        intersect(first.Types(), second.Types()),
+			OnlyFirst:     difference(first.Types(), second.Types()),
+			OnlySecond:    difference(second.Types(), first.Types()),
+			SharedTargets: intersect(first.TargetIPs, second.TargetIPs),
+		},
+		Intensity: intensity,
+		Ratios: IntensityRatios{
+			Confidence:     ratio(intensity[1].Confidence, intensity[0].Confidence),
+			Duration:       ratio(intensity[1].DurationSeconds, intensity[0].DurationSeconds),
+			SourceCount:    ratio(float64(intensity[1].SourceCount), float64(intensity[0].SourceCount)),
+			RequestsPerSec: ratio(intensity[1].RequestsPerSec, intensity[0].RequestsPerSec),
+		},
+	}
+}
+
+func alignTimeline(attack models.Attack, events []models.TimelineEvent) []AlignedEvent {
+	aligned := make([]AlignedEvent, 0, len(events))
+	for _, event := range events {
+		aligned = append(aligned, AlignedEvent{
+			TimelineEvent: event,
+			OffsetSeconds: event.Timestamp.Sub(attack.StartTime).Seconds(),
+		})
+	}
+	sort.SliceStable(aligned, func(i, j int) bool {
+		return aligned[i].OffsetSeconds < aligned[j].OffsetSeconds
+	})
+	return aligned
+}
+
+func compareSources(first, second []string) SourceOverlap {
+	firstPrefixes, secondPrefixes := sourcePrefixes(first), sourcePrefixes(second)
+	sharedPrefixes := intersect(firstPrefixes, secondPrefixes)
+	sharedIPs := intersect(first, second)
+
+	return SourceOverlap{
+		PrefixJaccard:  jaccard(len(sharedPrefixes), len(firstPrefixes), len(secondPrefixes)),
+		IPJaccard:      jaccard(len(sharedIPs), len(unionStrings(first, nil)), len(unionStrings(second, nil))),
+		SharedPrefixes: sharedPrefixes,
+		SharedIPs:      sharedIPs,
+	}
+}
+
+// sourcePrefixes maps source addresses onto their deduplicated prefixes
+func sourcePrefixes(ips []string) []string {
+	prefixes := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+
+		bits := compareSourcePrefixV6
+		if addr.Is4() {
+			bits = compareSourcePrefixV4
+		}
+		if prefix, err := addr.Prefix(bits); err == nil {
+			prefixes = append(prefixes, prefix.String())
+		}
+	}
+	return unionStrings(prefixes, nil)
+}
+
+func attackIntensity(attack models.Attack, now time.Time) AttackIntensity {
+	end := now
+	if attack.EndTime != nil {
+		end = *attack.EndTime
+	}
+
+	intensity := AttackIntensity{
+		Confidence:      attack.Confidence,
+		Severity:        attack.Severity,
+		DurationSeconds: end.Sub(attack.StartTime).Seconds(),
+		SourceCount:     len(unionStrings(attack.SourceIPs, nil)),
+		Ongoing:         attack.EndTime == nil,
+	}
+	if attack.Metrics != nil {
+		intensity.RequestsPerSec = attack.Metrics.RequestsPerSec
+	}
+	return intensity
+}
+
+func jaccard(shared, a, b int) float64 {
+	union := a + b - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// intersect returns the sorted items present in both lists
+func intersect(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, item := range b {
+		inB[item] = true
+	}
+
+	result := make([]string, 0)
+	for _, item := range unionStrings(a, nil) {
+		if inB[item] {
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// difference returns the sorted items of a missing from b
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, item := range b {
+		inB[item] = true
+	}
+
+	result := make([]string, 0)
+	for _, item := range unionStrings(a, nil) {
+		if !inB[item] {
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,89 @@
+package detection
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// fingerprintSources is how many top sources go into an attack fingerprint
+const fingerprintSources = 5
+
+// Fingerprint identifies an attack by its type, environment, top sources
+// and targets, e.g. for deduplicating notifications downstream
+func Fingerprint(attack models.Attack) string {
+	sources := attackSources(attack)
+	if len(sources) > fingerprintSources {
+		sources = sources[:fingerprintSources]
+	}
+	sort.Strings(sources)
+	targets := attackTargets(attack)
+	sort.Strings(targets)
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		attack.Environment,
+		attack.Type,
+		strings.Join(sources, ","),
+		strings.Join(targets, ","),
+	}, "|")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// continues reports whether a detection can be folded into a tracked attack
+// of the same type and environment, and how strongly it matches. Attacks on
+// different targets are different attacks, and attacks without targets
+// can't be told to be the same one; sources only rank the candidates, since
+// botnets rotate them between windows.
+func continues(tracked, detected models.Attack) (int, bool) {
+	if tracked.Environment != detected.Environment || tracked.Type != detected.Type {
+		return 0, false
+	}
+
+	if overlap(attackTargets(tracked), attackTargets(detected)) == 0 {
+		return 0, false
+	}
+
+	return overlap(attackSources(tracked), attackSources(detected)), true
+}
+
+// attackSources lists an attack's source prefixes, or its top source IPs
+// when its sources aren't aggregated
+func attackSources(attack models.Attack) []string {
+	if len(attack.SourcePrefixes) == 0 {
+		return append([]string(nil), attack.SourceIPs...)
+	}
+
+	sources := make([]string, 0, len(attack.SourcePrefixes))
+	for _, prefix := range attack.SourcePrefixes {
+		sources = append(sources, prefix.Prefix)
+	}
+	return sources
+}
+
+// attackTargets lists an attack's victim addresses and targeted path
+func attackTargets(attack models.Attack) []string {
+	targets := append([]string(nil), attack.TargetIPs...)
+	if attack.TargetEndpoint != "" {
+		targets = append(targets, attack.TargetEndpoint)
+	}
+	return targets
+}
+
+// overlap counts the entries of b also in a
+func overlap(a, b []string) int {
+	seen := make(map[string]bool, len(a))
+	for _, s := range a {
+		seen[s] = true
+	}
+
+	shared := 0
+	for _, s := range b {
+		if seen[s] {
+			shared++
+		}
+	}
+	return shared
+}
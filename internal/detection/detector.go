@@ -3,15 +3,74 @@ package detection
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
 	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/rules"
 )
 
+// AnalysisWindow is how much recent traffic each analysis cycle inspects
+const AnalysisWindow = time.Minute
+
 type Detector struct {
 	baseline   *Baseline
 	thresholds *Thresholds
+
+	// weight is how many real requests each analyzed request stands for
+	// while running on a sample in approximate mode
+	weight int
+
+	// observed is the last analyzed AnalysisWindow, learned into the
+	// baseline once the cycle turns out to be free of attacks, and
+	// observedPrefixes its requests per destination prefix
+	observed         *TrafficMetrics
+	observedPrefixes map[string]int
+
+	// loc is the time zone of the seasonal baseline's hours; nil is UTC
+	loc *time.Location
+
+	changePoints ChangePoints
+	forecast     HoltWinters
+
+	// ml is the optional learned scorer shared by the pool; nil without a model
+	ml *mlScoring
+
+	// disabled are the plugins and per-cycle checks turned off, shared by the pool
+	disabled map[string]bool
+
+	// services are the port groups counted together per destination, shared by the pool
+	services []ServiceGroup
+
+	// dnsZones are the configured zones query names are attributed to, longest first, shared by the pool
+	dnsZones []string
+
+	// ruleEngine holds the user-defined rules shared by the pool; nil without a rules file
+	ruleEngine *rules.Engine
+
+	// denylist holds the known-bad sources shared by the pool
+	denylist *iplist.Set
+
+	// reputation is the threat intelligence shared by the pool; nil without feeds
+	reputation Reputation
+
+	// locator resolves sources' autonomous systems, shared by the pool; nil without GeoIP
+	locator Locator
+
+	// env is the environment the detector analyzes
+	env string
+
+	// profiles keeps the per-source profiles of the busiest profiledSources
+	// sources, shared by the pool; nil without profiling. observedSources
+	// and observedProfiles are the last reference window's, learned once
+	// the cycle turns out to be free of attacks.
+	profiles         ProfileStore
+	profiledSources  int
+	observedSources  map[string]*sourceActivity
+	observedProfiles map[string]models.SourceProfile
 }
 
 type Baseline struct {
@@ -21,6 +80,19 @@ type Baseline struct {
 	StandardDeviation    float64
 	NormalIPRatio        float64
 	AvgConnectionDuration float64
+	AverageErrorRate     float64 // share of responses with a 4xx/5xx status
+	Samples              int     // attack-free windows learned from
+	// Request rate per local hour of weekdays, then of weekend days, so
+	// regular peaks aren't anomalies and quiet hours are held to less
+	Seasonal []SeasonalBucket `json:",omitempty"`
+	// Share of located requests per source country, and the windows with
+	// located traffic it was learned from
+	CountryShares  map[string]float64 `json:",omitempty"`
+	CountrySamples int                `json:",omitempty"`
+	// Requests per AnalysisWindow into each destination prefix, and the
+	// windows it was learned from
+	PrefixRequests map[string]float64 `json:",omitempty"`
+	PrefixSamples  int                `json:",omitempty"`
 }
 
 type Thresholds struct {
@@ -30,108 +102,406 @@ type Thresholds struct {
 	ConnectionsPerIP     int
 	SlowConnectionTime   int
 	SYNFloodThreshold    int
+	SYNFloodMaxSources   int
 	HTTPFloodThreshold   int
+	HTTPFloodPathEntropyMax float64
+	UDPFloodThreshold    int
+	SlowlorisThreshold   int
+	SlowlorisMaxSources  int
+	DNSResponseThreshold int
+	DNSAmplificationMin  float64
+	DNSVictimShareMin    float64
+	ACKFloodThreshold    int
+	RSTFloodThreshold    int
+	FINFloodThreshold    int
+	InvalidFlagThreshold int
+	SlowTransferMinDuration int
+	SlowTransferMaxRate     float64
+	SlowTransferThreshold   int
+	SlowTransferPerIP       int
+	DNSZoneQueryThreshold   int
+	DNSSubdomainEntropyMin  float64
+	CarpetBombThreshold     int
+	CarpetBombMinTargets    int
+	CarpetBombPrefixV4      int
+	CarpetBombPrefixV6      int
+	CarpetBombBaselineFactor float64
+	AuthFailureThreshold    int
+	AuthFailureMinSources   int
+	AuthFailureRatioMin     float64
+	ErrorRateMinResponses   int
+	ErrorRateMin            float64
+	ErrorRateSpikeFactor    float64
+	FingerprintFloodThreshold int
+	FingerprintShareMin       float64
+	FingerprintMinSources     int
+	FingerprintMinUserAgents  int
+	BotFloodThreshold         int
+	BotUserAgentShareMin      float64
+	// User-Agent substrings of legitimate crawlers never flagged as bot floods
+	CrawlerAllowlist []string
+	SpoofMinPackets         int
+	SpoofPortEntropyMin     float64
+	SpoofLowPortShareMin    float64
+	SpoofTTLDistinctMin     int
+	SpoofTTLVaryingShareMin float64
+	SpoofBogonShareMin      float64
+	SourcePrefixMinSources  int
+	SourcePrefixMinSubnets  int
+	SourcePrefixShareMin    float64
+	ChangePointDrift        float64
+	ChangePointLimit        float64
+	ForecastBandWidth       float64
+	RegularClientMinSources  int
+	RegularClientMinRequests int
+	RegularClientMaxRequests int
+	RegularClientMaxJitter   float64
+	ServiceFloodThreshold    int
+	MultiVectorMinVectors    int
+	GeoShiftMinRequests      int
+	GeoShiftShareMin         float64
+	GeoShiftDeltaMin         float64
+	HostingASNFloodThreshold int
+	HostingASNShareMin       float64
+	HostingASNMaxASNs        int
+	// Autonomous systems of hosting and cloud providers
+	HostingASNs []uint32
+	ProfileMinRequests      int
+	ProfileMinDeviations    int
+	ProfileRateFactor       float64
+	ProfileDiversityRiseMin float64
+	ProfileErrorRiseMin     float64
+	ProfileRareHourShare    float64
+	JA3FloodThreshold       int
+	JA3ShareMin             float64
+	JA3MinSources           int
+	// JA3 fingerprints of known clients never flagged as JA3 floods
+	JA3Allowlist []string
+	MethodFloodThreshold     int
+	MethodShareMin           float64
+	// HTTP methods that are a flood when they carry most requests
+	FloodMethods []string
+	OversizedHeaderBytes     int
+	OversizedHeaderThreshold int
+	OversizedHeaderShareMin  float64
+	// Count RFC 1918, CGNAT and link-local sources as bogons. Only correct
+	// where the monitored links face the internet.
+	SpoofPrivateIsBogon bool
 }
 
 func NewDetector() *Detector {
+	return NewDetectorWithThresholds(DefaultThresholds())
+}
+
+// NewDetectorWithThresholds creates a detector with a fresh baseline and the given thresholds
+func NewDetectorWithThresholds(thresholds Thresholds) *Detector {
+	baseline := DefaultBaseline()
 	return &Detector{
-		baseline: &Baseline{
-			AverageRequestRate:    100.0,
-			AverageUniqueIPs:      50,
-			AverageIPEntropy:      5.5,
-			StandardDeviation:     15.0,
-			NormalIPRatio:         2.0,
-			AvgConnectionDuration: 150.0,
-		},
-		thresholds: &Thresholds{
-			RequestsPerSecond:  500,
-			RequestRateZScore:  3.0,
-			IPEntropyMin:       3.0,
-			ConnectionsPerIP:   100,
-			SlowConnectionTime: 30000,
-			SYNFloodThreshold:  1000,
-			HTTPFloodThreshold: 2000,
+		baseline:   &baseline,
+		thresholds: &thresholds,
+	}
+}
+
+// DefaultBaseline returns the baseline a detector starts from before it
+// has learned any traffic
+func DefaultBaseline() Baseline {
+	return Baseline{
+		AverageRequestRate:    100.0,
+		AverageUniqueIPs:      50,
+		AverageIPEntropy:      5.5,
+		StandardDeviation:     15.0,
+		NormalIPRatio:         2.0,
+		AvgConnectionDuration: 150.0,
+		AverageErrorRate:      0.02,
+	}
+}
+
+// DefaultThresholds returns the stock detection thresholds
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		RequestsPerSecond:  500,
+		RequestRateZScore:  3.0,
+		IPEntropyMin:       3.0,
+		ConnectionsPerIP:   100,
+		SlowConnectionTime: 30000,
+		// SYN packets per window, sent by fewer than this many IPs unless
+		// the sources look spoofed
+		SYNFloodThreshold:  1000,
+		SYNFloodMaxSources: 10,
+		// HTTP requests per window, concentrated on few paths (path entropy
+		// in bits below this)
+		HTTPFloodThreshold:      2000,
+		HTTPFloodPathEntropyMax: 2.0,
+		UDPFloodThreshold:       2000,
+		// Connections open longer than SlowConnectionTime, from fewer than
+		// this many IPs, for Slowloris
+		SlowlorisThreshold:  100,
+		SlowlorisMaxSources: 10,
+		// DNS responses per window before amplification is considered
+		DNSResponseThreshold: 500,
+		// Response/query size ratio that indicates amplification, or the
+		// share of responses converging on one victim that does
+		DNSAmplificationMin: 10.0,
+		DNSVictimShareMin:   0.8,
+		// Bare ACK / RST / FIN segments per window, and packets with impossible flag combinations
+		ACKFloodThreshold:    2000,
+		RSTFloodThreshold:    1000,
+		FINFloodThreshold:    1000,
+		InvalidFlagThreshold: 50,
+		// Connections open at least this long (ms) moving under this many
+		// bytes/sec, in total and per IP, for slow POST / slow-read detection
+		SlowTransferMinDuration: 10000,
+		SlowTransferMaxRate:     100,
+		SlowTransferThreshold:   50,
+		SlowTransferPerIP:       3,
+		// Queries per zone, and entropy (bits) of the queried subdomains,
+		// above which random-subdomain (water torture) floods are flagged
+		DNSZoneQueryThreshold:  500,
+		DNSSubdomainEntropyMin: 7.0,
+		// Requests into one destination prefix, spread over at least this
+		// many addresses, for carpet-bombing detection
+		CarpetBombThreshold:  2000,
+		CarpetBombMinTargets: 32,
+		CarpetBombPrefixV4:   24,
+		CarpetBombPrefixV6:   64,
+		// ...and this many times the prefix's learned traffic, once learned
+		CarpetBombBaselineFactor: 3.0,
+		// 401/403 responses on one path, from at least this many IPs and
+		// making up this share of the path's requests, for credential stuffing
+		AuthFailureThreshold:  200,
+		AuthFailureMinSources: 20,
+		AuthFailureRatioMin:   0.5,
+		// Responses per window before the error rate is judged, and the
+		// error share (absolute, and relative to baseline) that is a spike
+		ErrorRateMinResponses: 500,
+		ErrorRateMin:          0.2,
+		ErrorRateSpikeFactor:  3.0,
+		// Fingerprinted requests per window, and the share, source IPs and
+		// distinct User-Agents of one header fingerprint that make a
+		// fingerprint-uniform flood
+		FingerprintFloodThreshold: 2000,
+		FingerprintShareMin:       0.5,
+		FingerprintMinSources:     20,
+		FingerprintMinUserAgents:  5,
+		// HTTP requests per window, and the share a single non-browser
+		// User-Agent must carry, for a bot flood
+		BotFloodThreshold:    1000,
+		BotUserAgentShareMin: 0.5,
+		CrawlerAllowlist: []string{
+			"Googlebot", "bingbot", "DuckDuckBot", "Applebot", "YandexBot", "Baiduspider",
+			"Slackbot", "facebookexternalhit", "Twitterbot", "LinkedInBot",
 		},
+		// Packets an L3/L4 flood needs before its sources are judged; the
+		// normalized source port entropy and sub-1024 port share of random
+		// ports; distinct TTLs per repeat source, and the share of repeat
+		// sources showing them; and the share of bogon-sourced packets
+		SpoofMinPackets:         1000,
+		SpoofPortEntropyMin:     0.95,
+		SpoofLowPortShareMin:    0.005,
+		SpoofTTLDistinctMin:     4,
+		SpoofTTLVaryingShareMin: 0.3,
+		SpoofBogonShareMin:      0.01,
+		// Sources a prefix needs to be reported, populated /24s that
+		// collapse into their /16, and the share of all sources the
+		// prefixes must cover before an attack is reported by prefix
+		SourcePrefixMinSources: 4,
+		SourcePrefixMinSubnets: 4,
+		SourcePrefixShareMin:   0.5,
+		// Deviations per cycle a series may wander from its reference
+		// unnoticed, and the accumulated deviations beyond that which make
+		// a sustained shift; consecutive windows overlap, so both are
+		// larger than for independent samples
+		ChangePointDrift: 1.0,
+		ChangePointLimit: 10.0,
+		// Forecast errors (standard deviations) a window may exceed the
+		// Holt-Winters forecast by before it is a rate anomaly
+		ForecastBandWidth: 3.0,
+		// Scripted sources a low-and-slow botnet needs; the requests per
+		// window a source needs before its timing is judged and above which
+		// it isn't low-rate; and the variation of its request intervals
+		// (standard deviation over mean) below which it runs like clockwork
+		RegularClientMinSources:  50,
+		RegularClientMinRequests: 6,
+		RegularClientMaxRequests: 120,
+		RegularClientMaxJitter:   0.1,
+		// Requests per window to one service on one destination: a port,
+		// or every port of a declared service group
+		ServiceFloodThreshold: 2000,
+		// Attack types detected in one environment in the same cycle that
+		// are reported together as a single multi-vector attack
+		MultiVectorMinVectors: 2,
+		// Located requests per window before its geography is judged; the
+		// share one source country must carry, and the rise over its
+		// baseline share, for a sudden geographic shift
+		GeoShiftMinRequests: 1000,
+		GeoShiftShareMin:    0.8,
+		GeoShiftDeltaMin:    0.5,
+		// Requests per window, and the share of them at most
+		// HostingASNMaxASNs hosting networks must send, for a flood from
+		// rented servers
+		HostingASNFloodThreshold: 2000,
+		HostingASNShareMin:       0.8,
+		HostingASNMaxASNs:        5,
+		HostingASNs:              append([]uint32(nil), DefaultHostingASNs...),
+		// Requests per minute a profiled source needs before it is judged,
+		// and how many ways it must break with its profile at once: a rate
+		// this many times its usual, path diversity (distinct paths per
+		// request) or error ratio risen this far, or activity in an hour
+		// carrying under this share of its history
+		ProfileMinRequests:      60,
+		ProfileMinDeviations:    2,
+		ProfileRateFactor:       5.0,
+		ProfileDiversityRiseMin: 0.5,
+		ProfileErrorRiseMin:     0.4,
+		ProfileRareHourShare:    0.02,
+		// Requests with a JA3 fingerprint per window, and the share and
+		// source IPs one fingerprint must carry, for a flood from one TLS
+		// client stack. Browsers shuffle their ClientHello extensions, so a
+		// single JA3 on this many IPs is a bot's.
+		JA3FloodThreshold: 2000,
+		JA3ShareMin:       0.6,
+		JA3MinSources:     500,
+		// Requests per window of one of the FloodMethods, and the share of
+		// the requests reporting a method it must carry, for a method flood
+		MethodFloodThreshold: 1000,
+		MethodShareMin:       0.5,
+		FloodMethods:         []string{"OPTIONS", "HEAD", "TRACE", "CONNECT", "PROPFIND"},
+		// Request header size that is oversized, and the requests per window
+		// and share of the sized ones over it, for an oversized-header attack
+		OversizedHeaderBytes:     8192,
+		OversizedHeaderThreshold: 200,
+		OversizedHeaderShareMin:  0.3,
 	}
 }
 
 // AnalyzeTraffic performs comprehensive analysis on traffic data
 func (d *Detector) AnalyzeTraffic(requests []models.TrafficRequest) []models.Attack {
+	return d.AnalyzeTrafficMode(requests, ModeFull)
+}
+
+// AnalyzeTrafficMode analyzes traffic in the given mode over every
+// resolution and records the mode on every detected attack. Each attack
+// type is reported once, from the window that detected it most confidently.
+func (d *Detector) AnalyzeTrafficMode(requests []models.TrafficRequest, mode Mode) []models.Attack {
+	return d.AnalyzeSampleMode(requests, 1, mode)
+}
+
+// AnalyzeSampleMode is AnalyzeTrafficMode for a sample already taken as the
+// traffic was read, each request standing for weight
+func (d *Detector) AnalyzeSampleMode(requests []models.TrafficRequest, weight int, mode Mode) []models.Attack {
 	if len(requests) == 0 {
 		return nil
 	}
 
+	now := time.Now()
 	attacks := make([]models.Attack, 0)
+	for _, res := range Resolutions {
+		windowed := requestsSince(requests, now.Add(-res.Window))
+		if len(windowed) == 0 {
+			continue
+		}
 
-	// Calculate metrics
-	metrics := d.calculateMetrics(requests)
+		d.weight = weight
+		if mode == ModeApproximate {
+			var k int
+			windowed, k = sampleRequests(windowed, approximateSampleSize)
+			d.weight *= k
+		}
+		metrics := d.calculateMetrics(windowed)
+		if res.Window == AnalysisWindow {
+			d.observed = metrics
+			attacks = append(attacks, d.detectPerCycle(metrics)...)
+		}
+		attacks = append(attacks, d.analyzeAt(res, windowed, metrics)...)
+	}
+	d.weight = 1
 
-	// Run detection algorithms
-	if attack := d.detectSYNFlood(requests, metrics); attack != nil {
-		attacks = append(attacks, *attack)
+	attacks = d.correlateVectors(strongestPerType(attacks))
+	d.scoreReputation(attacks)
+	for i := range attacks {
+		attacks[i].DetectionMode = string(mode)
 	}
 
-	if attack := d.detectHTTPFlood(requests, metrics); attack != nil {
-		attacks = append(attacks, *attack)
+	return attacks
+}
+
+// AnalyzeWindowMode analyzes one environment's share of a StreamWindow, a
+// slice per resolution. Window metrics come from its exact counters;
+// detectors that inspect single requests run on its sample.
+func (d *Detector) AnalyzeWindowMode(slices []WindowSlice, mode Mode) []models.Attack {
+	attacks := make([]models.Attack, 0)
+	for _, slice := range slices {
+		if slice.counts == nil || slice.counts.requests == 0 {
+			continue
+		}
+
+		requests, weight := slice.Requests, slice.Weight
+		if mode == ModeApproximate {
+			var k int
+			requests, k = sampleRequests(requests, approximateSampleSize)
+			weight *= k
+		}
+		d.weight = weight
+		metrics := slice.counts.metrics()
+		if slice.Resolution.Window == AnalysisWindow {
+			d.observed = metrics
+			attacks = append(attacks, d.detectPerCycle(metrics)...)
+		}
+		attacks = append(attacks, d.analyzeAt(slice.Resolution, requests, metrics)...)
 	}
+	d.weight = 1
 
-	if attack := d.detectSlowloris(requests, metrics); attack != nil {
-		attacks = append(attacks, *attack)
+	attacks = d.correlateVectors(strongestPerType(attacks))
+	d.scoreReputation(attacks)
+	for i := range attacks {
+		attacks[i].DetectionMode = string(mode)
 	}
 
-	if attack := d.detectUDPFlood(requests, metrics); attack != nil {
-		attacks = append(attacks, *attack)
+	return attacks
+}
+
+// analyze runs every enabled plugin over one resolution's window
+func (d *Detector) analyze(res Resolution, requests []models.TrafficRequest, metrics *TrafficMetrics) []models.Attack {
+	window := &Window{
+		Resolution: res,
+		Requests:   requests,
+		Weight:     d.sampleWeight(),
+		Metrics:    metrics,
+		Thresholds: *d.thresholds,
+		detector:   d,
 	}
 
-	if attack := d.detectRateAnomaly(metrics); attack != nil {
-		attacks = append(attacks, *attack)
+	attacks := make([]models.Attack, 0)
+	for _, plugin := range registeredPlugins() {
+		if !d.enabled(plugin.Name()) {
+			continue
+		}
+		for _, attack := range plugin.Detect(window) {
+			completeEvidence(&attack, plugin.Name(), metrics)
+			attacks = append(attacks, attack)
+		}
 	}
 
+	d.tagSpoofedSources(requests, attacks)
+
 	return attacks
 }
 
 // calculateMetrics computes various metrics from traffic data
 func (d *Detector) calculateMetrics(requests []models.TrafficRequest) *TrafficMetrics {
-	ipCounts := make(map[string]int)
-	protocolCounts := make(map[string]int)
-	pathCounts := make(map[string]int)
-	totalDuration := 0
-	synCount := 0
-
+	w := d.sampleWeight()
+	counts := newWindowCounts()
 	for _, req := range requests {
-		ipCounts[req.SourceIP]++
-		protocolCounts[req.Protocol]++
-		pathCounts[req.RequestPath]++
-		totalDuration += req.Duration
-
-		if req.Protocol == "TCP_SYN" {
-			synCount++
-		}
-	}
-
-	avgDuration := 0.0
-	if len(requests) > 0 {
-		avgDuration = float64(totalDuration) / float64(len(requests))
+		counts.add(req, w)
 	}
 
-	return &TrafficMetrics{
-		TotalRequests:      len(requests),
-		UniqueIPs:          len(ipCounts),
-		IPCounts:           ipCounts,
-		ProtocolCounts:     protocolCounts,
-		PathCounts:         pathCounts,
-		IPEntropy:          calculateEntropy(ipCounts),
-		PathEntropy:        calculateEntropy(pathCounts),
-		AvgConnDuration:    avgDuration,
-		RequestsPerIP:      float64(len(requests)) / float64(len(ipCounts)),
-		SYNPacketCount:     synCount,
-	}
+	return counts.metrics()
 }
 
 type TrafficMetrics struct {
 	TotalRequests      int
 	UniqueIPs          int
-	IPCounts           map[string]int
+	IPCounts           map[IPKey]int
 	ProtocolCounts     map[string]int
 	PathCounts         map[string]int
 	IPEntropy          float64
@@ -139,6 +509,28 @@ type TrafficMetrics struct {
 	AvgConnDuration    float64
 	RequestsPerIP      float64
 	SYNPacketCount     int
+	UserAgentCounts    map[string]int // HTTP requests per User-Agent
+	UserAgentEntropy   float64
+	TopUserAgents      []UserAgentCount
+	MethodCounts       map[string]int // HTTP requests per method, of those reporting one
+	BogonCounts        map[string]int // requests per bogon range class of their source
+	ToolRequests       int // HTTP requests from known tools and libraries
+	SourceCounts       SourcePercentiles // requests per source
+	CountryCounts      map[string]int    // requests per source country, of those located
+	ASNCounts          map[uint32]int    // requests per source autonomous system, of those resolved
+}
+
+// attackMetrics summarizes the window an attack was detected in; the
+// window's length and rate are filled in once the window is known
+func attackMetrics(metrics *TrafficMetrics) *models.Metrics {
+	return &models.Metrics{
+		Timestamp:     time.Now(),
+		TotalRequests: metrics.TotalRequests,
+		UniqueIPs:     metrics.UniqueIPs,
+		IPEntropy:     metrics.IPEntropy,
+		PathEntropy:   metrics.PathEntropy,
+		AvgConnDuration: metrics.AvgConnDuration,
+	}
 }
 
 // detectSYNFlood detects SYN flood attacks
@@ -148,31 +540,46 @@ func (d *Detector) detectSYNFlood(requests []models.TrafficRequest, metrics *Tra
 	}
 
 	// Check if many SYN packets from few IPs
-	synIPs := make(map[string]bool)
+	w := d.sampleWeight()
+	synIPs := make(map[IPKey]int)
 	for _, req := range requests {
-		if req.Protocol == "TCP_SYN" {
-			synIPs[req.SourceIP] = true
+		if isSYN(req) {
+			synIPs[ParseIPKey(req.SourceIP)] += w
 		}
 	}
+	synSources := estimateSources(synIPs, w)
 
-	// SYN flood: High SYN count, low IP diversity
-	if metrics.SYNPacketCount > d.thresholds.SYNFloodThreshold && len(synIPs) < 10 {
-		sourceIPs := make([]string, 0, len(synIPs))
-		for ip := range synIPs {
-			sourceIPs = append(sourceIPs, ip)
-		}
+	// Floods from many sources are caught when those sources are forged
+	var spoofing *models.SpoofingEvidence
+	var victims []string
+	if synSources >= d.thresholds.SYNFloodMaxSources {
+		spoofing, victims = d.assessSpoofing(requests, isSYN)
+	}
 
+	// SYN flood: High SYN count, low IP diversity or spoofed sources
+	if metrics.SYNPacketCount > d.thresholds.SYNFloodThreshold && (synSources < d.thresholds.SYNFloodMaxSources || spoofing != nil) {
 		confidence := math.Min(float64(metrics.SYNPacketCount)/float64(d.thresholds.SYNFloodThreshold*2), 1.0)
 
+		ev := withSources(evidence(above("syn_packets", float64(metrics.SYNPacketCount), "SYNFloodThreshold", float64(d.thresholds.SYNFloodThreshold))), synIPs)
+		if spoofing == nil {
+			ev.Checks = append(ev.Checks, below("syn_sources", float64(synSources), "SYNFloodMaxSources", float64(d.thresholds.SYNFloodMaxSources)))
+		}
+		ev.Features["syn_sources"] = float64(synSources)
+
 		return &models.Attack{
 			ID:          uuid.New().String(),
 			Type:        "SYN_FLOOD",
 			Severity:    getSeverity(confidence),
 			Confidence:  confidence,
 			StartTime:   time.Now(),
-			SourceIPs:   sourceIPs,
-			Description: fmt.Sprintf("SYN flood detected: %d SYN packets from %d IPs", metrics.SYNPacketCount, len(synIPs)),
+			SourceIPs:   getTopIPs(synIPs, 20),
+			SourcePrefixes: d.aggregateSources(synIPs),
+			SourceASNs:     d.aggregateASNs(synIPs),
+			TargetIPs:   victims,
+			Description: fmt.Sprintf("SYN flood detected: %d SYN packets from %d IPs", metrics.SYNPacketCount, synSources),
 			Mitigated:   false,
+			Spoofing:    spoofing,
+			Evidence:    ev,
 		}
 	}
 
@@ -181,13 +588,21 @@ func (d *Detector) detectSYNFlood(requests []models.TrafficRequest, metrics *Tra
 
 // detectHTTPFlood detects HTTP flood attacks
 func (d *Detector) detectHTTPFlood(requests []models.TrafficRequest, metrics *TrafficMetrics) *models.Attack {
+	w := d.sampleWeight()
 	httpCount := 0
-	httpIPs := make(map[string]int)
+	httpIPs := make(map[IPKey]int)
+	paths := make(map[string]map[IPKey]int)
 
 	for _, req := range requests {
 		if req.Protocol == "HTTP" {
-			httpCount++
-			httpIPs[req.SourceIP]++
+			httpCount += w
+			httpIPs[ParseIPKey(req.SourceIP)] += w
+
+			path, _, _ := strings.Cut(req.RequestPath, "?")
+			if paths[path] == nil {
+				paths[path] = make(map[IPKey]int)
+			}
+			paths[path][ParseIPKey(req.SourceIP)] += w
 		}
 	}
 
@@ -197,7 +612,7 @@ func (d *Detector) detectHTTPFlood(requests []models.TrafficRequest, metrics *Tr
 	}
 
 	// Check for repetitive patterns (same path, low entropy)
-	if metrics.PathEntropy < 2.0 {
+	if metrics.PathEntropy < d.thresholds.HTTPFloodPathEntropyMax {
 		// Get top attacking IPs
 		sourceIPs := getTopIPs(httpIPs, 20)
 		
@@ -210,8 +625,15 @@ func (d *Detector) detectHTTPFlood(requests []models.TrafficRequest, metrics *Tr
 			Confidence:  confidence,
 			StartTime:   time.Now(),
 			SourceIPs:   sourceIPs,
+			SourcePrefixes: d.aggregateSources(httpIPs),
+			SourceASNs:     d.aggregateASNs(httpIPs),
+			TargetPaths: topPathLoads(paths, 3),
 			Description: fmt.Sprintf("HTTP flood detected: %d requests with low path diversity (entropy: %.2f)", httpCount, metrics.PathEntropy),
 			Mitigated:   false,
+			Evidence: withSources(evidence(
+				atLeast("http_requests", float64(httpCount), "HTTPFloodThreshold", float64(d.thresholds.HTTPFloodThreshold)),
+				below("path_entropy", metrics.PathEntropy, "HTTPFloodPathEntropyMax", d.thresholds.HTTPFloodPathEntropyMax),
+			), httpIPs),
 		}
 	}
 
@@ -220,24 +642,29 @@ func (d *Detector) detectHTTPFlood(requests []models.TrafficRequest, metrics *Tr
 
 // detectSlowloris detects Slowloris attacks
 func (d *Detector) detectSlowloris(requests []models.TrafficRequest, metrics *TrafficMetrics) *models.Attack {
+	w := d.sampleWeight()
 	slowConnections := 0
-	slowIPs := make(map[string]int)
+	slowIPs := make(map[IPKey]int)
 
 	for _, req := range requests {
 		if req.Protocol == "HTTP" && req.Duration > d.thresholds.SlowConnectionTime {
-			slowConnections++
-			slowIPs[req.SourceIP]++
+			slowConnections += w
+			slowIPs[ParseIPKey(req.SourceIP)] += w
 		}
 	}
+	slowSources := estimateSources(slowIPs, w)
 
 	// Slowloris: Many slow connections from few IPs
-	if slowConnections > 100 && len(slowIPs) < 10 {
+	if slowConnections > d.thresholds.SlowlorisThreshold && slowSources < d.thresholds.SlowlorisMaxSources {
 		sourceIPs := make([]string, 0, len(slowIPs))
 		for ip := range slowIPs {
-			sourceIPs = append(sourceIPs, ip)
+			if ip.IsZero() {
+				continue
+			}
+			sourceIPs = append(sourceIPs, ip.String())
 		}
 
-		confidence := math.Min(float64(slowConnections)/300.0, 1.0)
+		confidence := math.Min(float64(slowConnections)/float64(d.thresholds.SlowlorisThreshold*3), 1.0)
 
 		return &models.Attack{
 			ID:          uuid.New().String(),
@@ -246,8 +673,12 @@ func (d *Detector) detectSlowloris(requests []models.TrafficRequest, metrics *Tr
 			Confidence:  confidence,
 			StartTime:   time.Now(),
 			SourceIPs:   sourceIPs,
-			Description: fmt.Sprintf("Slowloris detected: %d slow connections from %d IPs", slowConnections, len(slowIPs)),
+			Description: fmt.Sprintf("Slowloris detected: %d slow connections from %d IPs", slowConnections, slowSources),
 			Mitigated:   false,
+			Evidence: withSources(evidence(
+				above("slow_connections", float64(slowConnections), "SlowlorisThreshold", float64(d.thresholds.SlowlorisThreshold)),
+				below("slow_sources", float64(slowSources), "SlowlorisMaxSources", float64(d.thresholds.SlowlorisMaxSources)),
+			), slowIPs),
 		}
 	}
 
@@ -258,19 +689,20 @@ func (d *Detector) detectSlowloris(requests []models.TrafficRequest, metrics *Tr
 func (d *Detector) detectUDPFlood(requests []models.TrafficRequest, metrics *TrafficMetrics) *models.Attack {
 	udpCount := metrics.ProtocolCounts["UDP"]
 	
-	if udpCount < 2000 {
+	if udpCount < d.thresholds.UDPFloodThreshold {
 		return nil
 	}
 
-	udpIPs := make(map[string]int)
+	w := d.sampleWeight()
+	udpIPs := make(map[IPKey]int)
 	for _, req := range requests {
 		if req.Protocol == "UDP" {
-			udpIPs[req.SourceIP]++
+			udpIPs[ParseIPKey(req.SourceIP)] += w
 		}
 	}
 
 	sourceIPs := getTopIPs(udpIPs, 20)
-	confidence := math.Min(float64(udpCount)/5000.0, 1.0)
+	confidence := math.Min(float64(udpCount)/(float64(d.thresholds.UDPFloodThreshold)*2.5), 1.0)
 
 	return &models.Attack{
 		ID:          uuid.New().String(),
@@ -279,8 +711,98 @@ func (d *Detector) detectUDPFlood(requests []models.TrafficRequest, metrics *Tra
 		Confidence:  confidence,
 		StartTime:   time.Now(),
 		SourceIPs:   sourceIPs,
-		Description: fmt.Sprintf("UDP flood detected: %d UDP packets from %d IPs", udpCount, len(udpIPs)),
+		SourcePrefixes: d.aggregateSources(udpIPs),
+		SourceASNs:     d.aggregateASNs(udpIPs),
+		Description: fmt.Sprintf("UDP flood detected: %d UDP packets from %d IPs", udpCount, estimateSources(udpIPs, w)),
 		Mitigated:   false,
+		Evidence:    withSources(evidence(atLeast("udp_packets", float64(udpCount), "UDPFloodThreshold", float64(d.thresholds.UDPFloodThreshold))), udpIPs),
+	}
+}
+
+// typicalDNSQueryBytes is used to estimate amplification when the queries
+// themselves are not visible (they were sent by the attacker with a spoofed source)
+const typicalDNSQueryBytes = 64.0
+
+// detectDNSAmplification detects DNS reflection/amplification attacks: large
+// UDP responses from port 53 that dwarf the queries, or a flood of DNS
+// responses converging on a single destination
+func (d *Detector) detectDNSAmplification(requests []models.TrafficRequest) *models.Attack {
+	w := d.sampleWeight()
+	responseCount, responseBytes := 0, 0
+	queryCount, queryBytes := 0, 0
+	reflectors := make(map[IPKey]int)
+	victims := make(map[IPKey]int)
+
+	for _, req := range requests {
+		if req.Protocol != "UDP" {
+			continue
+		}
+		if req.SourcePort == 53 {
+			responseCount += w
+			responseBytes += req.BytesSent * w
+			reflectors[ParseIPKey(req.SourceIP)] += w
+			victims[ParseIPKey(req.DestIP)] += w
+		} else if req.DestPort == 53 {
+			queryCount += w
+			queryBytes += req.BytesSent * w
+		}
+	}
+
+	if responseCount < d.thresholds.DNSResponseThreshold {
+		return nil
+	}
+
+	avgResponse := float64(responseBytes) / float64(responseCount)
+	avgQuery := typicalDNSQueryBytes
+	if queryCount > 0 && queryBytes > 0 {
+		avgQuery = float64(queryBytes) / float64(queryCount)
+	}
+	amplification := avgResponse / avgQuery
+
+	var victimKey IPKey
+	victimCount := 0
+	for ip, count := range victims {
+		if count > victimCount {
+			victimKey, victimCount = ip, count
+		}
+	}
+	victim := victimKey.String()
+	victimShare := float64(victimCount) / float64(responseCount)
+
+	// Either the responses are amplified or they converge on one target
+	if amplification < d.thresholds.DNSAmplificationMin && victimShare < d.thresholds.DNSVictimShareMin {
+		return nil
+	}
+
+	volumeScore := math.Min(float64(responseCount)/float64(d.thresholds.DNSResponseThreshold*4), 1.0)
+	ampScore := math.Min(amplification/(d.thresholds.DNSAmplificationMin*3), 1.0)
+	confidence := math.Min(0.5*volumeScore+0.3*ampScore+0.2*victimShare, 1.0)
+
+	ev := withSources(evidence(atLeast("dns_responses", float64(responseCount), "DNSResponseThreshold", float64(d.thresholds.DNSResponseThreshold))), reflectors)
+	if amplification >= d.thresholds.DNSAmplificationMin {
+		ev.Checks = append(ev.Checks, atLeast("amplification", amplification, "DNSAmplificationMin", d.thresholds.DNSAmplificationMin))
+	}
+	if victimShare >= d.thresholds.DNSVictimShareMin {
+		ev.Checks = append(ev.Checks, atLeast("victim_share", victimShare, "DNSVictimShareMin", d.thresholds.DNSVictimShareMin))
+	}
+	ev.Features["amplification"] = amplification
+	ev.Features["victim_share"] = victimShare
+	ev.Features["avg_response_bytes"] = avgResponse
+	ev.Features["reflectors"] = float64(len(reflectors))
+
+	return &models.Attack{
+		ID:          uuid.New().String(),
+		Type:        "DNS_AMPLIFICATION",
+		Severity:    getSeverity(confidence),
+		Confidence:  confidence,
+		StartTime:   time.Now(),
+		SourceIPs:   getTopIPs(reflectors, 20),
+		SourcePrefixes: d.aggregateSources(reflectors),
+		SourceASNs:     d.aggregateASNs(reflectors),
+		TargetIPs:   []string{victim},
+		Description: fmt.Sprintf("DNS amplification detected: %d responses from %d reflectors, avg %.0f bytes (amplification ~%.1fx), %.0f%% targeting %s", responseCount, len(reflectors), avgResponse, amplification, victimShare*100, victim),
+		Mitigated:   false,
+		Evidence:    ev,
 	}
 }
 
@@ -288,8 +810,10 @@ func (d *Detector) detectUDPFlood(requests []models.TrafficRequest, metrics *Tra
 func (d *Detector) detectRateAnomaly(metrics *TrafficMetrics) *models.Attack {
 	requestRate := float64(metrics.TotalRequests)
 	
-	// Calculate Z-score
-	zScore := (requestRate - d.baseline.AverageRequestRate) / d.baseline.StandardDeviation
+	// Calculate Z-score against the rate learned for this time of the week and its spread
+	mean, stddev, profile := d.baseline.rateAt(d.localNow())
+	stddev = math.Max(stddev, minStandardDeviation(mean))
+	zScore := (requestRate - mean) / stddev
 
 	if zScore > d.thresholds.RequestRateZScore {
 		// Also check IP entropy
@@ -297,6 +821,13 @@ func (d *Detector) detectRateAnomaly(metrics *TrafficMetrics) *models.Attack {
 			sourceIPs := getTopIPs(metrics.IPCounts, 20)
 			confidence := math.Min(zScore/6.0, 1.0)
 
+			ev := withSources(evidence(
+				above("rate_z_score", zScore, "RequestRateZScore", d.thresholds.RequestRateZScore),
+				below("ip_entropy", metrics.IPEntropy, "IPEntropyMin", d.thresholds.IPEntropyMin),
+			), metrics.IPCounts)
+			ev.Features["baseline_mean"] = mean
+			ev.Features["baseline_stddev"] = stddev
+
 			return &models.Attack{
 				ID:          uuid.New().String(),
 				Type:        "RATE_ANOMALY",
@@ -304,8 +835,12 @@ func (d *Detector) detectRateAnomaly(metrics *TrafficMetrics) *models.Attack {
 				Confidence:  confidence,
 				StartTime:   time.Now(),
 				SourceIPs:   sourceIPs,
-				Description: fmt.Sprintf("Rate anomaly detected: %.0f req/s (Z-score: %.2f), low IP entropy: %.2f", requestRate, zScore, metrics.IPEntropy),
+				SourcePrefixes: d.aggregateSources(metrics.IPCounts),
+				SourceASNs:     d.aggregateASNs(metrics.IPCounts),
+				Metrics:     attackMetrics(metrics),
+				Description: fmt.Sprintf("Rate anomaly detected: %.0f req/s (Z-score: %.2f against the %s baseline of %.0f), low IP entropy: %.2f, %d sources at p95 %d requests each (busiest %d)", requestRate, zScore, profile, mean, metrics.IPEntropy, metrics.UniqueIPs, metrics.SourceCounts.P95, metrics.SourceCounts.Max),
 				Mitigated:   false,
+				Evidence:    ev,
 			}
 		}
 	}
@@ -314,7 +849,7 @@ func (d *Detector) detectRateAnomaly(metrics *TrafficMetrics) *models.Attack {
 }
 
 // calculateEntropy calculates Shannon entropy for a distribution
-func calculateEntropy(counts map[string]int) float64 {
+func calculateEntropy[K comparable](counts map[K]int) float64 {
 	total := 0
 	for _, count := range counts {
 		total += count
@@ -336,14 +871,17 @@ func calculateEntropy(counts map[string]int) float64 {
 }
 
 // getTopIPs returns the top N IPs by request count
-func getTopIPs(ipCounts map[string]int, n int) []string {
+func getTopIPs(ipCounts map[IPKey]int, n int) []string {
 	type ipCount struct {
-		ip    string
+		ip    IPKey
 		count int
 	}
 
 	ips := make([]ipCount, 0, len(ipCounts))
 	for ip, count := range ipCounts {
+		if ip.IsZero() {
+			continue
+		}
 		ips = append(ips, ipCount{ip, count})
 	}
 
@@ -363,12 +901,42 @@ func getTopIPs(ipCounts map[string]int, n int) []string {
 	}
 
 	for i := 0; i < limit; i++ {
-		result = append(result, ips[i].ip)
+		result = append(result, ips[i].ip.String())
 	}
 
 	return result
 }
 
+// topPathLoads summarizes the n busiest paths from per-path, per-IP counts
+func topPathLoads(paths map[string]map[IPKey]int, n int) []models.PathLoad {
+	loads := make([]models.PathLoad, 0, len(paths))
+	for path, sources := range paths {
+		loads = append(loads, pathLoad(path, sources))
+	}
+
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].Requests != loads[j].Requests {
+			return loads[i].Requests > loads[j].Requests
+		}
+		return loads[i].Path < loads[j].Path
+	})
+	if len(loads) > n {
+		loads = loads[:n]
+	}
+	return loads
+}
+
+func pathLoad(path string, sources map[IPKey]int) models.PathLoad {
+	load := models.PathLoad{Path: path, Sources: len(sources)}
+	for _, count := range sources {
+		load.Requests += count
+		if count > load.PeakPerIP {
+			load.PeakPerIP = count
+		}
+	}
+	return load
+}
+
 // getSeverity determines attack severity based on confidence
 func getSeverity(confidence float64) string {
 	if confidence >= 0.9 {
@@ -381,13 +949,31 @@ func getSeverity(confidence float64) string {
 	return "LOW"
 }
 
-// UpdateBaseline updates the baseline metrics from normal traffic
+// UpdateBaseline learns a window of normal traffic into the baseline as
+// exponentially weighted moving averages, including the request rate's
+// variance. Until 1/baselineAlpha windows are learned each counts equally,
+// so the stock defaults are replaced within the first few cycles. The rate
+// is also learned into the seasonal bucket of the current local hour.
 func (d *Detector) UpdateBaseline(metrics *TrafficMetrics) {
-	// Exponential moving average
-	alpha := 0.1
-	
-	d.baseline.AverageRequestRate = alpha*float64(metrics.TotalRequests) + (1-alpha)*d.baseline.AverageRequestRate
-	d.baseline.AverageUniqueIPs = int(alpha*float64(metrics.UniqueIPs) + (1-alpha)*float64(d.baseline.AverageUniqueIPs))
-	d.baseline.AverageIPEntropy = alpha*metrics.IPEntropy + (1-alpha)*d.baseline.AverageIPEntropy
-	d.baseline.AvgConnectionDuration = alpha*metrics.AvgConnDuration + (1-alpha)*d.baseline.AvgConnectionDuration
-}
\ No newline at end of file
+	alpha := math.Max(baselineAlpha, 1/float64(d.baseline.Samples+1))
+
+	baseline := *d.baseline
+	rate := float64(metrics.TotalRequests)
+	diff := rate - baseline.AverageRequestRate
+	variance := baseline.StandardDeviation * baseline.StandardDeviation
+	if baseline.Samples == 0 {
+		diff, variance = 0, 0
+		baseline.AverageRequestRate = rate
+	}
+
+	baseline.AverageRequestRate += alpha * diff
+	baseline.StandardDeviation = math.Sqrt((1 - alpha) * (variance + alpha*diff*diff))
+	baseline.AverageUniqueIPs = int(alpha*float64(metrics.UniqueIPs) + (1-alpha)*float64(baseline.AverageUniqueIPs))
+	baseline.AverageIPEntropy = alpha*metrics.IPEntropy + (1-alpha)*baseline.AverageIPEntropy
+	baseline.AvgConnectionDuration = alpha*metrics.AvgConnDuration + (1-alpha)*baseline.AvgConnectionDuration
+	baseline.Samples++
+	baseline.learnSeasonal(d.localNow(), rate)
+	baseline.learnCountries(metrics.CountryCounts)
+	baseline.learnPrefixes(d.observedPrefixes)
+	d.baseline = &baseline
+}
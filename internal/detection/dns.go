@@ -0,0 +1,142 @@
+package detection
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// zoneQueries tallies the subdomains queried under one zone
+type zoneQueries struct {
+	total      int
+	subdomains map[string]int
+	sources    map[IPKey]int
+}
+
+// detectDNSWaterTorture detects random-subdomain floods: huge numbers of
+// queries for never-repeating names under one zone, which miss every cache
+// and exhaust the zone's authoritative servers
+func (d *Detector) detectDNSWaterTorture(requests []models.TrafficRequest) *models.Attack {
+	w := d.sampleWeight()
+	zones := make(map[string]*zoneQueries)
+
+	for _, req := range requests {
+		if req.DNSQueryName == "" {
+			continue
+		}
+		subdomain, zone := d.splitQueryName(req.DNSQueryName)
+		if subdomain == "" {
+			continue
+		}
+
+		z, ok := zones[zone]
+		if !ok {
+			z = &zoneQueries{
+				subdomains: make(map[string]int),
+				sources:    make(map[IPKey]int),
+			}
+			zones[zone] = z
+		}
+		z.total += w
+		z.subdomains[subdomain] += w
+		z.sources[ParseIPKey(req.SourceIP)] += w
+	}
+
+	// Report the zone under the heaviest random-subdomain load
+	var target *zoneQueries
+	targetZone := ""
+	targetEntropy := 0.0
+	for zone, z := range zones {
+		if z.total < d.thresholds.DNSZoneQueryThreshold {
+			continue
+		}
+		entropy := calculateEntropy(z.subdomains)
+		if entropy < d.thresholds.DNSSubdomainEntropyMin {
+			continue
+		}
+		if target == nil || z.total > target.total {
+			target, targetZone, targetEntropy = z, zone, entropy
+		}
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	volumeScore := math.Min(float64(target.total)/float64(d.thresholds.DNSZoneQueryThreshold*4), 1.0)
+	entropyScore := math.Min(targetEntropy/(d.thresholds.DNSSubdomainEntropyMin*1.5), 1.0)
+	confidence := math.Min(0.6*volumeScore+0.4*entropyScore, 1.0)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "DNS_WATER_TORTURE",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(target.sources, 20),
+		SourcePrefixes: d.aggregateSources(target.sources),
+		SourceASNs:     d.aggregateASNs(target.sources),
+		Description:    fmt.Sprintf("DNS water torture on %s: %d queries for %d distinct subdomains (entropy: %.2f) from %d resolvers", targetZone, target.total, len(target.subdomains), targetEntropy, len(target.sources)),
+		Mitigated:      false,
+		Evidence: withSources(evidence(
+			atLeast("zone_queries", float64(target.total), "DNSZoneQueryThreshold", float64(d.thresholds.DNSZoneQueryThreshold)),
+			atLeast("subdomain_entropy", targetEntropy, "DNSSubdomainEntropyMin", d.thresholds.DNSSubdomainEntropyMin),
+		), target.sources),
+	}
+}
+
+// SetDNSZones sets the zones query names are attributed to, in every
+// environment, for zones delegated below a registrable domain
+// ("corp.example.com") or under suffixes missing from the public suffix list
+func (p *Pool) SetDNSZones(zones []string) {
+	normalized := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		if zone = normalizeQueryName(zone); zone != "" {
+			normalized = append(normalized, zone)
+		}
+	}
+	// Longest first, so the most specific zone matches
+	slices.SortFunc(normalized, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dnsZones = normalized
+	for _, detector := range p.detectors {
+		detector.dnsZones = normalized
+	}
+}
+
+func normalizeQueryName(name string) string {
+	return strings.ToLower(strings.Trim(name, "."))
+}
+
+// splitQueryName splits a QNAME into the labels below the zone and the zone
+// itself: the longest configured zone it falls under, or else its
+// registrable domain by the public suffix list ("a1b2.www.example.co.uk" ->
+// "a1b2.www", "example.co.uk")
+func (d *Detector) splitQueryName(name string) (string, string) {
+	name = normalizeQueryName(name)
+
+	for _, zone := range d.dnsZones {
+		if subdomain, ok := strings.CutSuffix(name, "."+zone); ok {
+			return subdomain, zone
+		}
+		if name == zone {
+			return "", zone
+		}
+	}
+
+	zone, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil || zone == name {
+		return "", name
+	}
+	return strings.TrimSuffix(name, "."+zone), zone
+}
@@ -0,0 +1,94 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// errorRateAlpha is the weight of each quiet window in the learned error rate
+	errorRateAlpha = 0.05
+	// errorRateFloor keeps a near-zero baseline from making every error a spike
+	errorRateFloor = 0.01
+)
+
+// detectErrorRateSpike detects a sudden surge in the share of 4xx/5xx
+// responses against the learned baseline. Floods that exhaust the backend
+// show up as 5xx, while attacks probing or hammering endpoints the app
+// rejects show up as 4xx. Quiet windows feed the baseline.
+func (d *Detector) detectErrorRateSpike(requests []models.TrafficRequest) *models.Attack {
+	w := d.sampleWeight()
+	responses, clientErrors, serverErrors := 0, 0, 0
+	sources := make(map[IPKey]int)
+	paths := make(map[string]int)
+
+	for _, req := range requests {
+		if req.StatusCode == 0 {
+			continue
+		}
+		responses += w
+		if req.StatusCode < 400 {
+			continue
+		}
+		if req.StatusCode >= 500 {
+			serverErrors += w
+		} else {
+			clientErrors += w
+		}
+		sources[ParseIPKey(req.SourceIP)] += w
+		path, _, _ := strings.Cut(req.RequestPath, "?")
+		paths[path] += w
+	}
+
+	if responses < d.thresholds.ErrorRateMinResponses {
+		return nil
+	}
+
+	rate := float64(clientErrors+serverErrors) / float64(responses)
+	baseline := math.Max(d.baseline.AverageErrorRate, errorRateFloor)
+	factor := rate / baseline
+
+	if rate < d.thresholds.ErrorRateMin || factor < d.thresholds.ErrorRateSpikeFactor {
+		d.baseline.AverageErrorRate = errorRateAlpha*rate + (1-errorRateAlpha)*d.baseline.AverageErrorRate
+		return nil
+	}
+
+	topPath, topCount := "", 0
+	for path, count := range paths {
+		if count > topCount || (count == topCount && path < topPath) {
+			topPath, topCount = path, count
+		}
+	}
+
+	confidence := math.Min(0.5*math.Min(factor/(d.thresholds.ErrorRateSpikeFactor*3), 1.0)+0.5*rate, 1.0)
+
+	ev := withPaths(withSources(evidence(
+		atLeast("responses", float64(responses), "ErrorRateMinResponses", float64(d.thresholds.ErrorRateMinResponses)),
+		atLeast("error_rate", rate, "ErrorRateMin", d.thresholds.ErrorRateMin),
+		atLeast("error_rate_factor", factor, "ErrorRateSpikeFactor", d.thresholds.ErrorRateSpikeFactor),
+	), sources), paths)
+	ev.Features["baseline_error_rate"] = baseline
+	ev.Features["client_errors"] = float64(clientErrors)
+	ev.Features["server_errors"] = float64(serverErrors)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "ERROR_RATE_SPIKE",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(sources, 20),
+		SourcePrefixes: d.aggregateSources(sources),
+		SourceASNs:     d.aggregateASNs(sources),
+		TargetEndpoint: topPath,
+		Description: fmt.Sprintf("Error rate spike: %.1f%% of %d responses failed (%d 4xx, %d 5xx), %.1fx the %.1f%% baseline; most on %s",
+			rate*100, responses, clientErrors, serverErrors, factor, baseline*100, topPath),
+		Mitigated: false,
+		Evidence:  ev,
+	}
+}
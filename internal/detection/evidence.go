@@ -0,0 +1,138 @@
+package detection
+
+import (
+	"sort"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// evidenceTopN is how many contributing sources and paths evidence lists
+const evidenceTopN = 10
+
+// evidence starts an attack's evidence from the checks that flagged it
+func evidence(checks ...models.ThresholdCheck) *models.Evidence {
+	return &models.Evidence{
+		Features: make(map[string]float64),
+		Checks:   checks,
+	}
+}
+
+// above records that a measurement exceeded a threshold
+func above(feature string, value float64, threshold string, limit float64) models.ThresholdCheck {
+	return models.ThresholdCheck{Feature: feature, Value: value, Operator: ">", Threshold: threshold, Limit: limit}
+}
+
+// atLeast records that a measurement reached a threshold
+func atLeast(feature string, value float64, threshold string, limit float64) models.ThresholdCheck {
+	return models.ThresholdCheck{Feature: feature, Value: value, Operator: ">=", Threshold: threshold, Limit: limit}
+}
+
+// below records that a measurement stayed under a threshold
+func below(feature string, value float64, threshold string, limit float64) models.ThresholdCheck {
+	return models.ThresholdCheck{Feature: feature, Value: value, Operator: "<", Threshold: threshold, Limit: limit}
+}
+
+// withSources sets the sources contributing most to an attack
+func withSources(e *models.Evidence, counts map[IPKey]int) *models.Evidence {
+	e.TopSources = topSourceCounts(counts, evidenceTopN)
+	return e
+}
+
+// withPaths sets the paths contributing most to an attack
+func withPaths(e *models.Evidence, counts map[string]int) *models.Evidence {
+	e.TopPaths = topPathCounts(counts, evidenceTopN)
+	return e
+}
+
+// completeEvidence fills in what a detector left out of an attack's
+// evidence: the detector's name, the window's own measurements, and the
+// window's busiest sources and paths
+func completeEvidence(attack *models.Attack, detector string, metrics *TrafficMetrics) {
+	if attack.Evidence == nil {
+		attack.Evidence = evidence()
+	}
+	e := attack.Evidence
+	if e.Detector == "" {
+		e.Detector = detector
+	}
+	if e.Features == nil {
+		e.Features = make(map[string]float64)
+	}
+	if metrics == nil {
+		return
+	}
+
+	for name, value := range windowFeatures(metrics) {
+		if _, ok := e.Features[name]; !ok {
+			e.Features[name] = value
+		}
+	}
+	if e.TopSources == nil {
+		e.TopSources = topSourceCounts(metrics.IPCounts, evidenceTopN)
+	}
+	if e.TopPaths == nil && len(metrics.PathCounts) > 0 {
+		e.TopPaths = topPathCounts(metrics.PathCounts, evidenceTopN)
+	}
+}
+
+// windowFeatures are the measurements of a whole window every attack's
+// evidence includes
+func windowFeatures(metrics *TrafficMetrics) map[string]float64 {
+	return map[string]float64{
+		"total_requests":       float64(metrics.TotalRequests),
+		"unique_ips":           float64(metrics.UniqueIPs),
+		"ip_entropy":           metrics.IPEntropy,
+		"path_entropy":         metrics.PathEntropy,
+		"requests_per_ip":      metrics.RequestsPerIP,
+		"syn_packets":          float64(metrics.SYNPacketCount),
+		"avg_conn_duration_ms": metrics.AvgConnDuration,
+		"source_requests_p95":  float64(metrics.SourceCounts.P95),
+		"source_requests_max":  float64(metrics.SourceCounts.Max),
+	}
+}
+
+// topSourceCounts returns the n busiest sources with their share of the total
+func topSourceCounts(counts map[IPKey]int, n int) []models.IPCount {
+	total := 0
+	sources := make([]models.IPCount, 0, len(counts))
+	for ip, count := range counts {
+		total += count
+		if ip.IsZero() {
+			continue
+		}
+		sources = append(sources, models.IPCount{IP: ip.String(), Count: count})
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Count != sources[j].Count {
+			return sources[i].Count > sources[j].Count
+		}
+		return sources[i].IP < sources[j].IP
+	})
+	if len(sources) > n {
+		sources = sources[:n]
+	}
+	for i := range sources {
+		sources[i].Percentage = 100 * float64(sources[i].Count) / float64(total)
+	}
+	return sources
+}
+
+// topPathCounts returns the n busiest paths
+func topPathCounts(counts map[string]int, n int) []models.PathCount {
+	paths := make([]models.PathCount, 0, len(counts))
+	for path, count := range counts {
+		paths = append(paths, models.PathCount{Path: path, Count: count})
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].Count != paths[j].Count {
+			return paths[i].Count > paths[j].Count
+		}
+		return paths[i].Path < paths[j].Path
+	})
+	if len(paths) > n {
+		paths = paths[:n]
+	}
+	return paths
+}
@@ -0,0 +1,94 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// fingerprintUsage tallies the traffic sharing one header fingerprint
+type fingerprintUsage struct {
+	total      int
+	sources    map[IPKey]int
+	userAgents map[string]int
+}
+
+// detectFingerprintFlood detects floods from a botnet that rotates source
+// IPs and User-Agents but runs one HTTP client stack. A real browser stack
+// is shared by many users too, but they all report the same few
+// User-Agents; one fingerprint behind many different claimed browsers
+// carrying most of the traffic is the tell.
+func (d *Detector) detectFingerprintFlood(requests []models.TrafficRequest) *models.Attack {
+	w := d.sampleWeight()
+	fingerprints := make(map[string]*fingerprintUsage)
+	counts := make(map[string]int)
+	total := 0
+
+	for _, req := range requests {
+		if req.HeaderFingerprint == "" {
+			continue
+		}
+
+		usage, ok := fingerprints[req.HeaderFingerprint]
+		if !ok {
+			usage = &fingerprintUsage{
+				sources:    make(map[IPKey]int),
+				userAgents: make(map[string]int),
+			}
+			fingerprints[req.HeaderFingerprint] = usage
+		}
+		usage.total += w
+		usage.sources[ParseIPKey(req.SourceIP)] += w
+		usage.userAgents[req.UserAgent] += w
+		counts[req.HeaderFingerprint] += w
+		total += w
+	}
+
+	if total < d.thresholds.FingerprintFloodThreshold {
+		return nil
+	}
+
+	var target *fingerprintUsage
+	targetFingerprint := ""
+	for fingerprint, usage := range fingerprints {
+		if target == nil || usage.total > target.total {
+			target, targetFingerprint = usage, fingerprint
+		}
+	}
+
+	share := float64(target.total) / float64(total)
+	if share < d.thresholds.FingerprintShareMin ||
+		len(target.sources) < d.thresholds.FingerprintMinSources ||
+		len(target.userAgents) < d.thresholds.FingerprintMinUserAgents {
+		return nil
+	}
+
+	entropy := calculateEntropy(counts)
+	uaScore := math.Min(float64(len(target.userAgents))/float64(d.thresholds.FingerprintMinUserAgents*4), 1.0)
+	confidence := math.Min(0.6*share+0.4*uaScore, 1.0)
+
+	ev := withSources(evidence(
+		atLeast("fingerprinted_requests", float64(total), "FingerprintFloodThreshold", float64(d.thresholds.FingerprintFloodThreshold)),
+		atLeast("fingerprint_share", share, "FingerprintShareMin", d.thresholds.FingerprintShareMin),
+		atLeast("fingerprint_sources", float64(len(target.sources)), "FingerprintMinSources", float64(d.thresholds.FingerprintMinSources)),
+		atLeast("fingerprint_user_agents", float64(len(target.userAgents)), "FingerprintMinUserAgents", float64(d.thresholds.FingerprintMinUserAgents)),
+	), target.sources)
+	ev.Features["fingerprint_entropy"] = entropy
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "FINGERPRINT_FLOOD",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(target.sources, 20),
+		SourcePrefixes: d.aggregateSources(target.sources),
+		SourceASNs:     d.aggregateASNs(target.sources),
+		Description:    fmt.Sprintf("Fingerprint-uniform flood: client stack %s sent %.0f%% of %d fingerprinted requests from %d IPs claiming %d User-Agents (fingerprint entropy: %.2f)", targetFingerprint, share*100, total, len(target.sources), len(target.userAgents), entropy),
+		Mitigated:      false,
+		Evidence:       ev,
+	}
+}
@@ -0,0 +1,200 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// forecastSlots is one seasonal slot per local minute of the day
+	forecastSlots = 24 * 60
+	// Smoothing of the level and trend per learned minute, and of a
+	// seasonal slot per day it is learned. The level moves over a day or
+	// two so the daily shape ends up in the season, not in the level.
+	forecastAlpha = 0.0005
+	forecastBeta  = 0.01
+	forecastGamma = 0.3
+	// forecastDamping fades the trend out over the hour ahead, so a
+	// forecast far out doesn't run off along the latest slope
+	forecastDamping = 0.98
+	// forecastResidualAlpha smooths the squared forecast errors the bands
+	// are derived from
+	forecastResidualAlpha = 0.05
+	// minForecastMinutes is how many minutes a forecaster learns before
+	// traffic outside its band is an anomaly: a day, so the season has
+	// seen every time of day once
+	minForecastMinutes = forecastSlots
+)
+
+// HoltWinters forecasts the request count per AnalysisWindow with additive
+// triple exponential smoothing: a level, a trend and a daily season of one
+// slot per minute. It learns one attack-free window per minute.
+type HoltWinters struct {
+	Level    float64
+	Trend    float64
+	Season   []float64 `json:",omitempty"`
+	Residual float64   // standard deviation of the one-step forecast error
+	Minutes  int       // minutes learned
+	Last     time.Time // start of the last learned minute
+}
+
+// ForecastPoint is the predicted traffic of one minute and the band
+// traffic is expected to stay in, in requests per second
+type ForecastPoint struct {
+	Time         time.Time `json:"time"`
+	PredictedRPS float64   `json:"predicted_rps"`
+	LowerRPS     float64   `json:"lower_rps"`
+	UpperRPS     float64   `json:"upper_rps"`
+}
+
+// forecastSlot returns the seasonal slot of a local time
+func forecastSlot(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// predict returns the expected count in the window ending in the minute
+// steps minutes after the last learned one
+func (h *HoltWinters) predict(t time.Time, steps float64) float64 {
+	predicted := h.Level + dampedSteps(steps)*h.Trend
+	if len(h.Season) == forecastSlots {
+		predicted += h.Season[forecastSlot(t)]
+	}
+	return math.Max(predicted, 0)
+}
+
+// dampedSteps is how many minutes of trend apply steps minutes ahead
+func dampedSteps(steps float64) float64 {
+	return forecastDamping * (1 - math.Pow(forecastDamping, steps)) / (1 - forecastDamping)
+}
+
+// band returns the half-width of the band at the given number of
+// deviations, never narrower than the floor the baseline uses
+func (h *HoltWinters) band(width, predicted float64) float64 {
+	return width * math.Max(h.Residual, minStandardDeviation(predicted))
+}
+
+// learn feeds the window ending at local time t into the forecaster, once
+// per minute; later windows of an already learned minute are ignored
+func (h *HoltWinters) learn(t time.Time, count float64) {
+	minute := t.Truncate(time.Minute)
+	if !minute.After(h.Last) {
+		return
+	}
+	if len(h.Season) != forecastSlots {
+		h.Season = make([]float64, forecastSlots)
+	}
+	slot := forecastSlot(t)
+
+	if h.Minutes == 0 {
+		h.Level, h.Trend, h.Residual = count, 0, 0
+	} else {
+		// Minutes without an attack-free window count as unobserved
+		steps := math.Max(minute.Sub(h.Last).Minutes(), 1)
+		predicted := h.predict(t, steps)
+		err := count - predicted
+		h.Residual = math.Sqrt((1-forecastResidualAlpha)*h.Residual*h.Residual + forecastResidualAlpha*err*err)
+
+		previous := h.Level
+		h.Level = forecastAlpha*(count-h.Season[slot]) + (1-forecastAlpha)*(h.Level+dampedSteps(steps)*h.Trend)
+		h.Trend = forecastBeta*(h.Level-previous)/steps + (1-forecastBeta)*forecastDamping*h.Trend
+
+		// The first day fills the season in; later days refine it
+		gamma := forecastGamma
+		if h.Minutes < forecastSlots {
+			gamma = 1
+		}
+		h.Season[slot] = gamma*(count-h.Level) + (1-gamma)*h.Season[slot]
+	}
+
+	h.Minutes++
+	h.Last = minute
+}
+
+// clone copies the forecaster, including its season
+func (h HoltWinters) clone() HoltWinters {
+	h.Season = append([]float64(nil), h.Season...)
+	return h
+}
+
+// detectForecastAnomaly flags a window whose request count rises above the
+// band the forecast for the current minute allows
+func (d *Detector) detectForecastAnomaly(metrics *TrafficMetrics) *models.Attack {
+	h := &d.forecast
+	if h.Minutes < minForecastMinutes {
+		return nil
+	}
+
+	now := d.localNow()
+	steps := math.Max(now.Truncate(time.Minute).Sub(h.Last).Minutes(), 1)
+	predicted := h.predict(now, steps)
+	band := h.band(d.thresholds.ForecastBandWidth, predicted)
+	observed := float64(metrics.TotalRequests)
+	if observed <= predicted+band {
+		return nil
+	}
+
+	// At the band's edge a flood is as likely as not; twice as far out it
+	// is certain
+	confidence := math.Min(0.5+(observed-predicted-band)/(2*band), 1.0)
+	perSecond := AnalysisWindow.Seconds()
+	summary := attackMetrics(metrics)
+	summary.WindowDuration = int(perSecond)
+	summary.RequestsPerSec = observed / perSecond
+
+	ev := evidence(above("requests", observed, "ForecastBandWidth", predicted+band))
+	ev.Features["predicted_requests"] = predicted
+	ev.Features["forecast_band"] = band
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "RATE_ANOMALY",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(metrics.IPCounts, 20),
+		SourcePrefixes: d.aggregateSources(metrics.IPCounts),
+		SourceASNs:     d.aggregateASNs(metrics.IPCounts),
+		Metrics:        summary,
+		Description: fmt.Sprintf("Rate anomaly detected: %.0f req/s above the forecast band (%.0f ± %.0f req/s)",
+			observed/perSecond, predicted/perSecond, band/perSecond),
+		Evidence:      ev,
+		Window:        "medium",
+		WindowSeconds: int(AnalysisWindow / time.Second),
+		Mitigated:     false,
+	}
+}
+
+// Forecast predicts an environment's traffic for the given number of
+// minutes from now, with bands at the environment's ForecastBandWidth. It
+// returns nothing until the environment's forecaster has learned a minute.
+func (p *Pool) Forecast(env string, minutes int) []ForecastPoint {
+	detector := p.Get(env)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := &detector.forecast
+	if h.Minutes == 0 {
+		return []ForecastPoint{}
+	}
+
+	now := detector.localNow().Truncate(time.Minute)
+	perSecond := AnalysisWindow.Seconds()
+	points := make([]ForecastPoint, 0, minutes)
+	for i := 0; i < minutes; i++ {
+		t := now.Add(time.Duration(i) * time.Minute)
+		predicted := h.predict(t, math.Max(t.Sub(h.Last).Minutes(), 1))
+		band := h.band(detector.thresholds.ForecastBandWidth, predicted)
+		points = append(points, ForecastPoint{
+			Time:         t,
+			PredictedRPS: predicted / perSecond,
+			LowerRPS:     math.Max(predicted-band, 0) / perSecond,
+			UpperRPS:     (predicted + band) / perSecond,
+		})
+	}
+	return points
+}
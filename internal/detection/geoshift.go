@@ -0,0 +1,107 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// geoShiftMinSamples is how many windows with located traffic the
+	// country shares need before shifts away from them are judged
+	geoShiftMinSamples = 10
+	// minCountryShare is the learned share below which a country is
+	// forgotten, keeping the baseline to the countries traffic comes from
+	minCountryShare = 0.001
+)
+
+// learnCountries moves the learned country shares toward a window's
+func (b *Baseline) learnCountries(counts map[string]int) {
+	located := 0
+	for _, count := range counts {
+		located += count
+	}
+	if located == 0 {
+		return
+	}
+
+	alpha := math.Max(baselineAlpha, 1/float64(b.CountrySamples+1))
+	shares := make(map[string]float64, len(b.CountryShares)+len(counts))
+	for country, share := range b.CountryShares {
+		shares[country] = (1 - alpha) * share
+	}
+	for country, count := range counts {
+		shares[country] += alpha * float64(count) / float64(located)
+	}
+	for country, share := range shares {
+		if share < minCountryShare {
+			delete(shares, country)
+		}
+	}
+
+	b.CountryShares = shares
+	b.CountrySamples++
+}
+
+// detectGeoShift detects traffic abruptly concentrating on one source
+// country it doesn't normally come from, as when a botnet concentrated in
+// one region joins in. Shares are of the located requests only.
+func (d *Detector) detectGeoShift(w *Window) *models.Attack {
+	if d.baseline.CountrySamples < geoShiftMinSamples {
+		return nil
+	}
+
+	located := 0
+	top, topCount := "", 0
+	for country, count := range w.Metrics.CountryCounts {
+		located += count
+		if count > topCount || (count == topCount && country < top) {
+			top, topCount = country, count
+		}
+	}
+	if located < w.Thresholds.GeoShiftMinRequests {
+		return nil
+	}
+
+	share := float64(topCount) / float64(located)
+	baseline := d.baseline.CountryShares[top]
+	if share < w.Thresholds.GeoShiftShareMin || share-baseline < w.Thresholds.GeoShiftDeltaMin {
+		return nil
+	}
+
+	sources := make(map[IPKey]int)
+	for _, req := range w.Requests {
+		if req.Country == top {
+			sources[ParseIPKey(req.SourceIP)] += w.Weight
+		}
+	}
+
+	confidence := math.Min((share-baseline)/(2*w.Thresholds.GeoShiftDeltaMin), 1.0)
+
+	ev := withSources(evidence(
+		atLeast("located_requests", float64(located), "GeoShiftMinRequests", float64(w.Thresholds.GeoShiftMinRequests)),
+		atLeast("country_share", share, "GeoShiftShareMin", w.Thresholds.GeoShiftShareMin),
+		atLeast("country_share_increase", share-baseline, "GeoShiftDeltaMin", w.Thresholds.GeoShiftDeltaMin),
+	), sources)
+	ev.Features["baseline_country_share"] = baseline
+	ev.Features["countries"] = float64(len(w.Metrics.CountryCounts))
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "GEO_SHIFT",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(sources, 20),
+		SourcePrefixes: d.aggregateSources(sources),
+		SourceASNs:     d.aggregateASNs(sources),
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("Geographic shift: %.1f%% of located traffic (%d of %d requests) from %s, against a %.1f%% baseline",
+			share*100, topCount, located, top, baseline*100),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
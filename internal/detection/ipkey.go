@@ -0,0 +1,62 @@
+package detection
+
+import (
+	"hash/fnv"
+	"net/netip"
+)
+
+// IPKey is an address packed into 16 bytes, IPv4 as v4-mapped IPv6. Unlike a
+// string it holds no pointer, so maps keyed by millions of botnet sources
+// need no per-entry allocation and are never scanned by the garbage collector.
+type IPKey struct {
+	addr [16]byte
+	kind ipKeyKind
+}
+
+type ipKeyKind uint8
+
+const (
+	ipKeyNone ipKeyKind = iota
+	ipKeyAddr
+	// ipKeyMalformed keys hold a 128-bit hash of the text, so distinct
+	// malformed sources still count as distinct
+	ipKeyMalformed
+)
+
+// ParseIPKey packs an address. An empty address gives the zero key.
+func ParseIPKey(s string) IPKey {
+	if s == "" {
+		return IPKey{}
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		h := fnv.New128a()
+		h.Write([]byte(s))
+		k := IPKey{kind: ipKeyMalformed}
+		h.Sum(k.addr[:0])
+		return k
+	}
+	return IPKey{addr: addr.As16(), kind: ipKeyAddr}
+}
+
+// IsZero reports whether the key holds no usable address: the source was
+// missing or malformed
+func (k IPKey) IsZero() bool {
+	return k.kind != ipKeyAddr
+}
+
+// Addr unpacks the key, IPv4 unmapped; the zero Addr if IsZero
+func (k IPKey) Addr() netip.Addr {
+	if k.IsZero() {
+		return netip.Addr{}
+	}
+	return netip.AddrFrom16(k.addr).Unmap()
+}
+
+// String unpacks the key into its canonical text form, "" if IsZero
+func (k IPKey) String() string {
+	if k.IsZero() {
+		return ""
+	}
+	return k.Addr().String()
+}
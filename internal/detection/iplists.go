@@ -0,0 +1,133 @@
+package detection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// DenylistCheck is the plugin flagging traffic from denylisted sources
+const DenylistCheck = "denylisted_source"
+
+// SetIPLists sets the trusted and known-bad sources of every environment.
+// Trusted sources are dropped before analysis, so they never count toward
+// an attack; traffic from known-bad ones is always flagged. A source on
+// both lists is trusted.
+func (p *Pool) SetIPLists(allow, deny *iplist.Set) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.allowlist, p.denylist = allow, deny
+	for _, detector := range p.detectors {
+		detector.denylist = deny
+	}
+}
+
+// IPLists returns the trusted and known-bad sources
+func (p *Pool) IPLists() (allow, deny *iplist.Set) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.allowlist, p.denylist
+}
+
+// Trusted reports whether a source is on the allowlist
+func (p *Pool) Trusted(ip string) bool {
+	p.mu.Lock()
+	allow := p.allowlist
+	p.mu.Unlock()
+
+	return allow.ContainsIP(ip)
+}
+
+// untrusted drops the requests of allowlisted sources
+func (p *Pool) untrusted(requests []models.TrafficRequest) []models.TrafficRequest {
+	p.mu.Lock()
+	allow := p.allowlist
+	p.mu.Unlock()
+
+	if allow.Len() == 0 {
+		return requests
+	}
+	kept := make([]models.TrafficRequest, 0, len(requests))
+	for _, req := range requests {
+		if !allow.ContainsIP(req.SourceIP) {
+			kept = append(kept, req)
+		}
+	}
+	return kept
+}
+
+// detectDenylisted flags any traffic from denylisted sources. It runs over
+// the reference window only, so its counts are per minute.
+func (d *Detector) detectDenylisted(w *Window) *models.Attack {
+	if d.denylist.Len() == 0 || w.Resolution.Window != AnalysisWindow {
+		return nil
+	}
+
+	counts := make(map[IPKey]int)
+	listed := make(map[string]models.IPListEntry)
+	requests := 0
+	for ip, count := range w.Metrics.IPCounts {
+		if ip.IsZero() {
+			continue
+		}
+		entry, ok := d.denylist.Lookup(ip.Addr())
+		if !ok {
+			continue
+		}
+		counts[ip] = count
+		listed[entry.CIDR] = entry
+		requests += count
+	}
+	if requests == 0 {
+		return nil
+	}
+
+	share := float64(requests) / float64(w.Metrics.TotalRequests)
+	severity := "MEDIUM"
+	switch {
+	case share >= 0.5:
+		severity = "CRITICAL"
+	case share >= 0.1:
+		severity = "HIGH"
+	}
+
+	cidrs := make([]string, 0, len(listed))
+	for cidr := range listed {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+	matched := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if comment := listed[cidr].Comment; comment != "" {
+			cidr = fmt.Sprintf("%s (%s)", cidr, comment)
+		}
+		matched = append(matched, cidr)
+	}
+
+	ev := withSources(evidence(atLeast("denylisted_requests", float64(requests), "denylist", 1)), counts)
+	ev.Features["denylisted_sources"] = float64(len(counts))
+	ev.Features["denylisted_share"] = share
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "DENYLISTED_SOURCE",
+		Severity:       severity,
+		Confidence:     1.0,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(counts, 20),
+		SourcePrefixes: d.aggregateSources(counts),
+		SourceASNs:     d.aggregateASNs(counts),
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("Traffic from denylisted sources: %d requests (%.1f%% of the window) from %d IPs in %s",
+			requests, share*100, len(counts), strings.Join(matched, ", ")),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
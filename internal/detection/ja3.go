@@ -0,0 +1,99 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// ja3Usage tallies the traffic of one JA3 fingerprint
+type ja3Usage struct {
+	total   int
+	sources map[IPKey]int
+	snis    map[string]int
+}
+
+// detectJA3Flood detects floods from thousands of IPs all completing the
+// same TLS handshake: one client stack, as many modern botnets run,
+// however they vary their addresses, User-Agents and headers. Only
+// requests whose producer reported a JA3 are judged.
+func (d *Detector) detectJA3Flood(w *Window) *models.Attack {
+	fingerprints := make(map[string]*ja3Usage)
+	total := 0
+	for _, req := range w.Requests {
+		if req.JA3 == "" {
+			continue
+		}
+		usage, ok := fingerprints[req.JA3]
+		if !ok {
+			usage = &ja3Usage{sources: make(map[IPKey]int), snis: make(map[string]int)}
+			fingerprints[req.JA3] = usage
+		}
+		usage.total += w.Weight
+		usage.sources[ParseIPKey(req.SourceIP)] += w.Weight
+		if req.TLSServerName != "" {
+			usage.snis[req.TLSServerName] += w.Weight
+		}
+		total += w.Weight
+	}
+	if total < w.Thresholds.JA3FloodThreshold {
+		return nil
+	}
+
+	var target *ja3Usage
+	ja3 := ""
+	for fingerprint, usage := range fingerprints {
+		if target == nil || usage.total > target.total || (usage.total == target.total && fingerprint < ja3) {
+			target, ja3 = usage, fingerprint
+		}
+	}
+	if slices.Contains(w.Thresholds.JA3Allowlist, ja3) {
+		return nil
+	}
+
+	share := float64(target.total) / float64(total)
+	if share < w.Thresholds.JA3ShareMin || len(target.sources) < w.Thresholds.JA3MinSources {
+		return nil
+	}
+
+	sni, sniCount := "", 0
+	for name, count := range target.snis {
+		if count > sniCount || (count == sniCount && name < sni) {
+			sni, sniCount = name, count
+		}
+	}
+	against := "its targets"
+	if sni != "" {
+		against = sni
+	}
+
+	sourceScore := math.Min(float64(len(target.sources))/float64(w.Thresholds.JA3MinSources*4), 1.0)
+	confidence := math.Min(0.6*share+0.4*sourceScore, 1.0)
+
+	ev := withSources(evidence(
+		atLeast("ja3_requests", float64(total), "JA3FloodThreshold", float64(w.Thresholds.JA3FloodThreshold)),
+		atLeast("ja3_share", share, "JA3ShareMin", w.Thresholds.JA3ShareMin),
+		atLeast("ja3_sources", float64(len(target.sources)), "JA3MinSources", float64(w.Thresholds.JA3MinSources)),
+	), target.sources)
+	ev.Features["ja3_fingerprints"] = float64(len(fingerprints))
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "JA3_FLOOD",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(target.sources, 20),
+		SourcePrefixes: d.aggregateSources(target.sources),
+		SourceASNs:     d.aggregateASNs(target.sources),
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("JA3 flood: TLS fingerprint %s carried %.0f%% of %d TLS requests from %d IPs against %s",
+			ja3, share*100, total, len(target.sources), against),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
@@ -0,0 +1,155 @@
+package detection
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Mode is how thoroughly detectors analyze a window
+type Mode string
+
+const (
+	// ModeFull analyzes every request in the window
+	ModeFull Mode = "full"
+	// ModeApproximate analyzes a uniform sample with counts scaled back up,
+	// so per-IP maps and top-K stay bounded when the pipeline is saturated
+	ModeApproximate Mode = "approximate"
+)
+
+// approximateSampleSize caps the requests analyzed per environment in approximate mode
+const approximateSampleSize = 20000
+
+// ApproximateReadSize caps the requests read from storage per cycle in
+// approximate mode, across environments
+const ApproximateReadSize = 100000
+
+// sampleRequests keeps every k-th request so at most max remain, returning
+// the sample and k
+func sampleRequests(requests []models.TrafficRequest, max int) ([]models.TrafficRequest, int) {
+	if len(requests) <= max {
+		return requests, 1
+	}
+
+	k := (len(requests) + max - 1) / max
+	sample := make([]models.TrafficRequest, 0, len(requests)/k+1)
+	for i := 0; i < len(requests); i += k {
+		sample = append(sample, requests[i])
+	}
+
+	return sample, k
+}
+
+// estimateSources estimates how many distinct sources sampled counts stand
+// for when each sampled request stands for w. A source sampled more than
+// once counts once, but one sampled once stands for about w sources, most
+// of which the sample missed, so a botnet of one-request sources isn't
+// mistaken for a few.
+func estimateSources(counts map[IPKey]int, w int) int {
+	if w <= 1 {
+		return len(counts)
+	}
+	sources := 0
+	for _, count := range counts {
+		if count <= w {
+			sources += w
+		} else {
+			sources++
+		}
+	}
+	return sources
+}
+
+func (d *Detector) sampleWeight() int {
+	if d.weight < 1 {
+		return 1
+	}
+	return d.weight
+}
+
+// LoadGovernor picks the analysis mode from how loaded the pipeline is.
+// It degrades as soon as a cycle runs over budget or the ingest backlog
+// nears capacity, and only recovers after several calm cycles so the mode
+// doesn't flap.
+type LoadGovernor struct {
+	mu          sync.Mutex
+	budget      time.Duration
+	mode        Mode
+	calmCycles  int
+	lastCycle   time.Duration
+	lastBacklog float64
+	since       time.Time
+}
+
+const (
+	// Degrade when a cycle uses this share of its budget or the backlog this share of capacity
+	loadHighWater = 0.8
+	// Recover once both stay under this share for recoveryCycles cycles in a row
+	loadLowWater   = 0.5
+	recoveryCycles = 3
+)
+
+func NewLoadGovernor(budget time.Duration) *LoadGovernor {
+	return &LoadGovernor{
+		budget: budget,
+		mode:   ModeFull,
+		since:  time.Now(),
+	}
+}
+
+// Observe records one analysis cycle and the ingest backlog as a fraction
+// of capacity. It returns the mode for the next cycle and whether it changed.
+func (g *LoadGovernor) Observe(cycle time.Duration, backlog float64) (Mode, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastCycle = cycle
+	g.lastBacklog = backlog
+	usage := float64(cycle) / float64(g.budget)
+
+	next := g.mode
+	switch {
+	case usage >= loadHighWater || backlog >= loadHighWater:
+		g.calmCycles = 0
+		next = ModeApproximate
+	case usage < loadLowWater && backlog < loadLowWater:
+		g.calmCycles++
+		if g.calmCycles >= recoveryCycles {
+			next = ModeFull
+		}
+	default:
+		g.calmCycles = 0
+	}
+
+	if next == g.mode {
+		return next, false
+	}
+
+	g.mode = next
+	g.since = time.Now()
+	return next, true
+}
+
+// LoadStatus describes the governor's current view of the pipeline
+type LoadStatus struct {
+	Mode          Mode      `json:"mode"`
+	Since         time.Time `json:"since"`
+	LastCycleMs   int64     `json:"last_cycle_ms"`
+	CycleBudgetMs int64     `json:"cycle_budget_ms"`
+	Backlog       float64   `json:"backlog"`
+}
+
+// Status returns the current mode and the load that led to it
+func (g *LoadGovernor) Status() LoadStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return LoadStatus{
+		Mode:          g.mode,
+		Since:         g.since,
+		LastCycleMs:   g.lastCycle.Milliseconds(),
+		CycleBudgetMs: g.budget.Milliseconds(),
+		Backlog:       g.lastBacklog,
+	}
+}
@@ -0,0 +1,164 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// maxSourcePaths caps the distinct paths remembered per source; a source
+// visiting more counts as visiting this many
+const maxSourcePaths = 32
+
+// sourceCadence is what the low-and-slow detector keeps per source: its
+// request times and distinct paths, bounded because sources sending more
+// than a low rate are dropped and paths are capped
+type sourceCadence struct {
+	requests int // weighted
+	times    []time.Time
+	paths    map[string]struct{}
+	busy     bool // sent too much to be low-rate; nothing else is kept
+}
+
+// regularSource is a source whose requests arrive like clockwork
+type regularSource struct {
+	ip        IPKey
+	requests  int
+	interval  time.Duration // mean time between requests
+	jitter    float64       // coefficient of variation of the intervals
+	diversity float64       // distinct paths per request
+}
+
+// cadence returns a source's mean interval between requests and their
+// coefficient of variation, or false with too few distinct times to tell
+func (c *sourceCadence) cadence() (time.Duration, float64, bool) {
+	sort.Slice(c.times, func(i, j int) bool { return c.times[i].Before(c.times[j]) })
+
+	intervals := make([]float64, 0, len(c.times)-1)
+	for i := 1; i < len(c.times); i++ {
+		intervals = append(intervals, c.times[i].Sub(c.times[i-1]).Seconds())
+	}
+	mean, stddev := meanAndDeviation(intervals)
+	if mean <= 0 {
+		return 0, 0, false
+	}
+	return time.Duration(mean * float64(time.Second)), stddev / mean, true
+}
+
+// detectLowAndSlow detects scripted clients spread over a large botnet:
+// many sources that each stay far below any per-IP or volume threshold but
+// send HTTP requests at near-perfectly regular intervals, which people
+// browsing never do. Together they drain application resources without a
+// volume spike. Sources are judged from the analyzed requests; while they
+// are sampled, intervals look less regular and fewer sources qualify.
+func (d *Detector) detectLowAndSlow(requests []models.TrafficRequest) *models.Attack {
+	w := d.sampleWeight()
+	maxRequests := d.thresholds.RegularClientMaxRequests
+	sources := make(map[IPKey]*sourceCadence)
+	httpRequests := 0
+
+	for _, req := range requests {
+		if req.Protocol != "HTTP" {
+			continue
+		}
+		httpRequests += w
+
+		ip := ParseIPKey(req.SourceIP)
+		source, ok := sources[ip]
+		if !ok {
+			source = &sourceCadence{paths: make(map[string]struct{})}
+			sources[ip] = source
+		}
+		if source.busy {
+			continue
+		}
+		if source.requests += w; source.requests > maxRequests {
+			*source = sourceCadence{busy: true}
+			continue
+		}
+		source.times = append(source.times, req.Timestamp)
+		if len(source.paths) < maxSourcePaths {
+			source.paths[req.RequestPath] = struct{}{}
+		}
+	}
+
+	regular := make([]regularSource, 0)
+	regularRequests := 0
+	for ip, source := range sources {
+		if source.busy || len(source.times) < d.thresholds.RegularClientMinRequests {
+			continue
+		}
+		interval, jitter, ok := source.cadence()
+		if !ok || jitter > d.thresholds.RegularClientMaxJitter {
+			continue
+		}
+		regular = append(regular, regularSource{
+			ip:        ip,
+			requests:  source.requests,
+			interval:  interval,
+			jitter:    jitter,
+			diversity: float64(len(source.paths)) / float64(len(source.times)),
+		})
+		regularRequests += source.requests
+	}
+
+	if len(regular) < d.thresholds.RegularClientMinSources {
+		return nil
+	}
+
+	// Report the typical regular source
+	median := func(value func(regularSource) float64) float64 {
+		values := make([]float64, len(regular))
+		for i, source := range regular {
+			values[i] = value(source)
+		}
+		sort.Float64s(values)
+		return values[len(values)/2]
+	}
+	interval := time.Duration(median(func(s regularSource) float64 { return float64(s.interval) })).Round(time.Second / 10)
+	jitter := median(func(s regularSource) float64 { return s.jitter })
+	diversity := median(func(s regularSource) float64 { return s.diversity })
+
+	pattern := "repeating the same few paths"
+	if diversity >= 0.8 {
+		pattern = "requesting a different path almost every time"
+	}
+
+	counts := make(map[IPKey]int, len(regular))
+	for _, source := range regular {
+		counts[source.ip] = source.requests
+	}
+
+	// Twice the minimum botnet size, and clockwork-perfect timing, make
+	// scripted abuse certain
+	sizeScore := math.Min(float64(len(regular))/float64(2*d.thresholds.RegularClientMinSources), 1.0)
+	timingScore := 1 - jitter/math.Max(d.thresholds.RegularClientMaxJitter, 1e-9)
+	confidence := math.Min(0.3+0.4*sizeScore+0.3*timingScore, 1.0)
+
+	ev := withSources(evidence(
+		atLeast("regular_sources", float64(len(regular)), "RegularClientMinSources", float64(d.thresholds.RegularClientMinSources)),
+	), counts)
+	ev.Features["median_interval_sec"] = interval.Seconds()
+	ev.Features["median_jitter"] = jitter
+	ev.Features["median_path_diversity"] = diversity
+	ev.Features["regular_requests"] = float64(regularRequests)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "LOW_AND_SLOW",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(counts, 20),
+		SourcePrefixes: d.aggregateSources(counts),
+		SourceASNs:     d.aggregateASNs(counts),
+		Description: fmt.Sprintf("Low-and-slow scripted clients: %d IPs each sending a request every ~%s (jitter %.0f%%), %s (%.2f distinct paths per request), %d requests (%.0f%% of HTTP traffic)",
+			len(regular), interval, jitter*100, pattern, diversity, regularRequests, 100*float64(regularRequests)/float64(max(httpRequests, 1))),
+		Mitigated: false,
+		Evidence:  ev,
+	}
+}
@@ -0,0 +1,117 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// MergeAttacks folds source into target as a single incident. The target
+// keeps its ID; the source ID is recorded in MergedFrom.
+func MergeAttacks(target, source models.Attack) models.Attack {
+	merged := target
+
+	if source.StartTime.Before(merged.StartTime) {
+		merged.StartTime = source.StartTime
+	}
+	merged.EndTime = laterEndTime(target.EndTime, source.EndTime)
+
+	merged.Confidence = math.Max(target.Confidence, source.Confidence)
+	if models.SeverityRank(source.Severity) > models.SeverityRank(merged.Severity) {
+		merged.Severity = source.Severity
+	}
+
+	merged.SourceIPs = unionStrings(target.SourceIPs, source.SourceIPs)
+	merged.SourceReputation = sourceReputation(merged.SourceIPs, target.SourceReputation, source.SourceReputation)
+	merged.TargetIPs = unionStrings(target.TargetIPs, source.TargetIPs)
+	if merged.SourcePrefixes == nil {
+		merged.SourcePrefixes = source.SourcePrefixes
+	}
+	if merged.SourceASNs == nil {
+		merged.SourceASNs = source.SourceASNs
+	}
+	if merged.Spoofing == nil {
+		merged.Spoofing = source.Spoofing
+	}
+
+	if source.Type != target.Type {
+		merged.Description = fmt.Sprintf("%s; merged %s: %s", target.Description, source.Type, source.Description)
+	} else {
+		merged.Description = fmt.Sprintf("%s; merged: %s", target.Description, source.Description)
+	}
+
+	merged.MergedFrom = unionStrings(target.MergedFrom, append([]string{source.ID}, source.MergedFrom...))
+	merged.Mitigated = target.Mitigated && source.Mitigated
+
+	return merged
+}
+
+// SplitAttack moves the given source IPs out of an attack into a new one of
+// the same type. It returns the remainder and the split-off attack.
+func SplitAttack(attack models.Attack, sourceIPs []string) (models.Attack, models.Attack, error) {
+	moving := make(map[string]bool, len(sourceIPs))
+	for _, ip := range sourceIPs {
+		moving[ip] = true
+	}
+
+	remaining := make([]string, 0, len(attack.SourceIPs))
+	moved := make([]string, 0, len(sourceIPs))
+	for _, ip := range attack.SourceIPs {
+		if moving[ip] {
+			moved = append(moved, ip)
+		} else {
+			remaining = append(remaining, ip)
+		}
+	}
+
+	if len(moved) == 0 {
+		return attack, models.Attack{}, fmt.Errorf("none of the given source IPs belong to attack %s", attack.ID)
+	}
+	if len(remaining) == 0 {
+		return attack, models.Attack{}, fmt.Errorf("cannot split off every source IP of attack %s", attack.ID)
+	}
+
+	split := attack
+	split.ID = uuid.New().String()
+	split.StartTime = time.Now()
+	split.SourceIPs = moved
+	split.SourceReputation = sourceReputation(moved, attack.SourceReputation)
+	split.MergedFrom = nil
+	split.SplitFrom = attack.ID
+	split.Description = fmt.Sprintf("Split from %s: %d source IPs", attack.ID, len(moved))
+
+	attack.SourceIPs = remaining
+	attack.SourceReputation = sourceReputation(remaining, attack.SourceReputation)
+
+	return attack, split, nil
+}
+
+func laterEndTime(a, b *time.Time) *time.Time {
+	// An incident is still open if either part is
+	if a == nil || b == nil {
+		return nil
+	}
+	if b.After(*a) {
+		return b
+	}
+	return a
+}
+
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	result := make([]string, 0, len(a)+len(b))
+
+	for _, list := range [][]string{a, b} {
+		for _, s := range list {
+			if !seen[s] {
+				seen[s] = true
+				result = append(result, s)
+			}
+		}
+	}
+
+	return result
+}
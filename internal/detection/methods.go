@@ -0,0 +1,123 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// detectMethodFlood detects HTTP floods of a method real clients send
+// sparingly, such as OPTIONS or HEAD: cheap for bots to send, often
+// answered without caching, and easily overlooked by GET-centric limits
+func (d *Detector) detectMethodFlood(w *Window) *models.Attack {
+	total := 0
+	method, count := "", 0
+	for m, n := range w.Metrics.MethodCounts {
+		total += n
+		flood := slices.ContainsFunc(w.Thresholds.FloodMethods, func(f string) bool { return strings.EqualFold(f, m) })
+		if flood && (n > count || (n == count && m < method)) {
+			method, count = m, n
+		}
+	}
+	if count < w.Thresholds.MethodFloodThreshold {
+		return nil
+	}
+	share := float64(count) / float64(total)
+	if share < w.Thresholds.MethodShareMin {
+		return nil
+	}
+
+	sources := make(map[IPKey]int)
+	paths := make(map[string]int)
+	for _, req := range w.Requests {
+		if strings.EqualFold(req.Method, method) {
+			sources[ParseIPKey(req.SourceIP)] += w.Weight
+			paths[req.RequestPath] += w.Weight
+		}
+	}
+
+	confidence := math.Min(0.5*math.Min(float64(count)/float64(w.Thresholds.MethodFloodThreshold*2), 1.0)+0.5*share, 1.0)
+
+	ev := withPaths(withSources(evidence(
+		atLeast("method_requests", float64(count), "MethodFloodThreshold", float64(w.Thresholds.MethodFloodThreshold)),
+		atLeast("method_share", share, "MethodShareMin", w.Thresholds.MethodShareMin),
+	), sources), paths)
+	ev.Features["methods"] = float64(len(w.Metrics.MethodCounts))
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "METHOD_FLOOD",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(sources, 20),
+		SourcePrefixes: d.aggregateSources(sources),
+		SourceASNs:     d.aggregateASNs(sources),
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("%s flood: %d %s requests, %.1f%% of HTTP requests, from %d IPs",
+			method, count, method, share*100, len(sources)),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
+
+// detectOversizedHeaders detects floods of requests with huge headers:
+// giant cookies or stuffed custom headers that tie up the parsing and
+// memory of proxies and servers at little cost to the sender. Only requests
+// whose producer reported their header size are judged.
+func (d *Detector) detectOversizedHeaders(w *Window) *models.Attack {
+	sized, oversized := 0, 0
+	largest := 0
+	sources := make(map[IPKey]int)
+	paths := make(map[string]int)
+	for _, req := range w.Requests {
+		if req.HeaderBytes <= 0 {
+			continue
+		}
+		sized += w.Weight
+		if req.HeaderBytes < w.Thresholds.OversizedHeaderBytes {
+			continue
+		}
+		oversized += w.Weight
+		largest = max(largest, req.HeaderBytes)
+		sources[ParseIPKey(req.SourceIP)] += w.Weight
+		paths[req.RequestPath] += w.Weight
+	}
+	if oversized < w.Thresholds.OversizedHeaderThreshold {
+		return nil
+	}
+	share := float64(oversized) / float64(sized)
+	if share < w.Thresholds.OversizedHeaderShareMin {
+		return nil
+	}
+
+	confidence := math.Min(0.5*math.Min(float64(oversized)/float64(w.Thresholds.OversizedHeaderThreshold*2), 1.0)+0.5*math.Min(share/(2*w.Thresholds.OversizedHeaderShareMin), 1.0), 1.0)
+
+	ev := withPaths(withSources(evidence(
+		atLeast("oversized_header_requests", float64(oversized), "OversizedHeaderThreshold", float64(w.Thresholds.OversizedHeaderThreshold)),
+		atLeast("oversized_header_share", share, "OversizedHeaderShareMin", w.Thresholds.OversizedHeaderShareMin),
+	), sources), paths)
+	ev.Features["oversized_header_bytes"] = float64(w.Thresholds.OversizedHeaderBytes)
+	ev.Features["largest_header_bytes"] = float64(largest)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "OVERSIZED_HEADERS",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(sources, 20),
+		SourcePrefixes: d.aggregateSources(sources),
+		SourceASNs:     d.aggregateASNs(sources),
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("Oversized-header attack: %d requests (%.1f%%) with headers of %d bytes or more, up to %d, from %d IPs",
+			oversized, share*100, w.Thresholds.OversizedHeaderBytes, largest, len(sources)),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
@@ -0,0 +1,132 @@
+package detection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// correlateVectors folds the attacks one environment's cycle detected into
+// a single MULTI_VECTOR attack when at least MultiVectorMinVectors
+// detectors fired: a SYN flood, a UDP flood and the rate anomaly they cause
+// are one attack on the site, not three. The vectors are kept on the
+// attack; each is evidence for the others, so the combined confidence is
+// the chance that at least one of them is right. Each vector keeps its own
+// evidence; the attack's lists every vector's confidence and the sources
+// and paths of the most confident vector.
+func (d *Detector) correlateVectors(attacks []models.Attack) []models.Attack {
+	minVectors := max(d.thresholds.MultiVectorMinVectors, 2)
+	if !d.enabled(MultiVectorCheck) || len(attacks) < minVectors {
+		return attacks
+	}
+
+	vectors := append([]models.Attack(nil), attacks...)
+	sort.SliceStable(vectors, func(i, j int) bool { return vectors[i].Confidence > vectors[j].Confidence })
+	strongest := vectors[0]
+
+	multi := models.Attack{
+		ID:            uuid.New().String(),
+		Type:          "MULTI_VECTOR",
+		StartTime:     strongest.StartTime,
+		Mitigated:     false,
+		Window:        strongest.Window,
+		WindowSeconds: strongest.WindowSeconds,
+		Vectors:       make([]models.AttackVector, 0, len(vectors)),
+	}
+
+	ev := evidence(atLeast("vectors", float64(len(vectors)), "MultiVectorMinVectors", float64(minVectors)))
+	ev.Detector = MultiVectorCheck
+	if strongest.Evidence != nil {
+		ev.TopSources, ev.TopPaths = strongest.Evidence.TopSources, strongest.Evidence.TopPaths
+	}
+	multi.Evidence = ev
+
+	doubt := 1.0
+	names := make([]string, 0, len(vectors))
+	sources := make(map[string]bool)
+	prefixes := make(map[string]bool)
+	targets := make(map[string]bool)
+	for _, vector := range vectors {
+		doubt *= 1 - vector.Confidence
+		if models.SeverityRank(vector.Severity) > models.SeverityRank(multi.Severity) {
+			multi.Severity = vector.Severity
+		}
+		if vector.StartTime.Before(multi.StartTime) {
+			multi.StartTime = vector.StartTime
+		}
+		names = append(names, fmt.Sprintf("%s (%.0f%%)", vector.Type, vector.Confidence*100))
+		ev.Features[strings.ToLower(vector.Type)+"_confidence"] = vector.Confidence
+
+		for _, ip := range vector.SourceIPs {
+			if !sources[ip] && len(multi.SourceIPs) < 20 {
+				sources[ip] = true
+				multi.SourceIPs = append(multi.SourceIPs, ip)
+			}
+		}
+		for _, prefix := range vector.SourcePrefixes {
+			if !prefixes[prefix.Prefix] {
+				prefixes[prefix.Prefix] = true
+				multi.SourcePrefixes = append(multi.SourcePrefixes, prefix)
+			}
+		}
+		for _, ip := range vector.TargetIPs {
+			if !targets[ip] {
+				targets[ip] = true
+				multi.TargetIPs = append(multi.TargetIPs, ip)
+			}
+		}
+
+		// Details only one kind of vector has are taken from the most
+		// confident vector that has them
+		if multi.TargetEndpoint == "" {
+			multi.TargetEndpoint = vector.TargetEndpoint
+		}
+		if multi.TargetPaths == nil {
+			multi.TargetPaths = vector.TargetPaths
+		}
+		if multi.TargetService == "" {
+			multi.TargetService, multi.TargetPorts = vector.TargetService, vector.TargetPorts
+		}
+		if multi.Metrics == nil {
+			multi.Metrics = vector.Metrics
+		}
+		if multi.Spoofing == nil {
+			multi.Spoofing = vector.Spoofing
+		}
+		if multi.SourceASNs == nil {
+			multi.SourceASNs = vector.SourceASNs
+		}
+
+		multi.Vectors = append(multi.Vectors, models.AttackVector{
+			Type:        vector.Type,
+			Severity:    vector.Severity,
+			Confidence:  vector.Confidence,
+			Description: vector.Description,
+			Window:      vector.Window,
+			Evidence:    vector.Evidence,
+		})
+	}
+
+	multi.Confidence = 1 - doubt
+	if severity := getSeverity(multi.Confidence); models.SeverityRank(severity) > models.SeverityRank(multi.Severity) {
+		multi.Severity = severity
+	}
+	multi.Description = fmt.Sprintf("Multi-vector attack with %d vectors: %s", len(vectors), joinAnd(names))
+	if multi.StartTime.IsZero() {
+		multi.StartTime = time.Now()
+	}
+
+	return []models.Attack{multi}
+}
+
+// joinAnd lists items as "a, b and c"
+func joinAnd(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
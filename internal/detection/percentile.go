@@ -0,0 +1,66 @@
+package detection
+
+import "math"
+
+// percentileGrowth is the ratio between the bounds of neighbouring
+// histogram buckets, which keeps percentiles within about 2.5%
+const percentileGrowth = 1.05
+
+// SourcePercentiles summarizes how requests spread over sources: the
+// approximate requests per source at the 50th, 95th and 99th percentile of
+// sources, and the exact count of the busiest one. Thousands of bots at a
+// low rate have a low p95; a handful of aggressive clients a high one.
+type SourcePercentiles struct {
+	P50 int
+	P95 int
+	P99 int
+	Max int
+}
+
+// sourcePercentiles computes the per-source percentiles of a window from a
+// log-scale histogram of the sources' request counts, which takes one pass
+// and no sorting however many sources the window has
+func sourcePercentiles(counts map[IPKey]int) SourcePercentiles {
+	if len(counts) == 0 {
+		return SourcePercentiles{}
+	}
+
+	var histogram []int
+	top := 0
+	for _, count := range counts {
+		if count <= 0 {
+			continue
+		}
+		i := percentileBucket(count)
+		for len(histogram) <= i {
+			histogram = append(histogram, 0)
+		}
+		histogram[i]++
+		top = max(top, count)
+	}
+
+	sources := 0
+	for _, n := range histogram {
+		sources += n
+	}
+
+	// at returns the representative count of the bucket holding the source
+	// ranked at fraction p, never above the busiest source
+	at := func(p float64) int {
+		rank := int(math.Ceil(p * float64(sources)))
+		seen := 0
+		for i, n := range histogram {
+			if seen += n; seen >= rank {
+				return min(int(math.Round(math.Pow(percentileGrowth, float64(i)+0.5))), top)
+			}
+		}
+		return top
+	}
+
+	return SourcePercentiles{P50: at(0.5), P95: at(0.95), P99: at(0.99), Max: top}
+}
+
+// percentileBucket returns the histogram bucket of a positive count
+func percentileBucket(count int) int {
+	return int(math.Log(float64(count)) / math.Log(percentileGrowth))
+}
@@ -0,0 +1,219 @@
+package detection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Plugin is a detector run over every resolution's window of every
+// environment. The built-in detectors are plugins; Register adds custom ones.
+type Plugin interface {
+	// Name identifies the plugin, e.g. "syn_flood", for enabling and disabling it
+	Name() string
+	// Detect inspects one window and returns the attacks it finds
+	Detect(window *Window) []models.Attack
+}
+
+// Window is one environment's traffic over one resolution, as a Plugin sees it
+type Window struct {
+	Resolution Resolution
+	// Requests are the window's requests, or a uniform sample of them in
+	// which each stands for Weight requests
+	Requests []models.TrafficRequest
+	Weight   int
+	// Metrics are exact for the whole window, sampled or not
+	Metrics *TrafficMetrics
+	// Thresholds are the environment's, with counts scaled to the resolution
+	Thresholds Thresholds
+
+	detector *Detector
+}
+
+// Per-cycle checks: stateful detectors run once per analysis cycle over the
+// AnalysisWindow rather than as plugins, and the correlation of the cycle's
+// attacks into one multi-vector attack. They can be disabled by name too.
+const (
+	ChangePointCheck = "change_point"
+	ForecastCheck    = "forecast"
+	MLCheck          = "ml_anomaly"
+	MultiVectorCheck = "multi_vector"
+)
+
+// cycleChecks are the per-cycle checks in the order they run
+var cycleChecks = []string{ChangePointCheck, ForecastCheck, MLCheck, MultiVectorCheck}
+
+// builtin adapts one of the Detector's own checks to a Plugin
+type builtin struct {
+	name   string
+	detect func(d *Detector, w *Window) *models.Attack
+}
+
+func (b builtin) Name() string {
+	return b.name
+}
+
+func (b builtin) Detect(w *Window) []models.Attack {
+	if w.detector == nil {
+		return nil
+	}
+	if attack := b.detect(w.detector, w); attack != nil {
+		return []models.Attack{*attack}
+	}
+	return nil
+}
+
+var (
+	pluginsMu sync.RWMutex
+	// plugins run in registration order: the built-ins, then custom ones
+	plugins = []Plugin{
+		builtin{"syn_flood", func(d *Detector, w *Window) *models.Attack { return d.detectSYNFlood(w.Requests, w.Metrics) }},
+		builtin{"http_flood", func(d *Detector, w *Window) *models.Attack { return d.detectHTTPFlood(w.Requests, w.Metrics) }},
+		builtin{"slowloris", func(d *Detector, w *Window) *models.Attack { return d.detectSlowloris(w.Requests, w.Metrics) }},
+		builtin{"udp_flood", func(d *Detector, w *Window) *models.Attack { return d.detectUDPFlood(w.Requests, w.Metrics) }},
+		builtin{"dns_amplification", func(d *Detector, w *Window) *models.Attack { return d.detectDNSAmplification(w.Requests) }},
+		builtin{"carpet_bombing", func(d *Detector, w *Window) *models.Attack { return d.detectCarpetBombing(w) }},
+		builtin{"dns_water_torture", func(d *Detector, w *Window) *models.Attack { return d.detectDNSWaterTorture(w.Requests) }},
+		builtin{"bot_flood", func(d *Detector, w *Window) *models.Attack { return d.detectBotFlood(w.Requests, w.Metrics) }},
+		builtin{"fingerprint_flood", func(d *Detector, w *Window) *models.Attack { return d.detectFingerprintFlood(w.Requests) }},
+		builtin{"ja3_flood", func(d *Detector, w *Window) *models.Attack { return d.detectJA3Flood(w) }},
+		builtin{"method_flood", func(d *Detector, w *Window) *models.Attack { return d.detectMethodFlood(w) }},
+		builtin{"oversized_headers", func(d *Detector, w *Window) *models.Attack { return d.detectOversizedHeaders(w) }},
+		builtin{"error_rate_spike", func(d *Detector, w *Window) *models.Attack { return d.detectErrorRateSpike(w.Requests) }},
+		builtin{"credential_stuffing", func(d *Detector, w *Window) *models.Attack { return d.detectCredentialStuffing(w.Requests) }},
+		builtin{"slow_post", func(d *Detector, w *Window) *models.Attack { return d.detectSlowTransfer(w.Requests, slowPOST) }},
+		builtin{"slow_read", func(d *Detector, w *Window) *models.Attack { return d.detectSlowTransfer(w.Requests, slowRead) }},
+		builtin{"ack_flood", func(d *Detector, w *Window) *models.Attack { return d.detectACKFlood(w.Requests) }},
+		builtin{"rst_flood", func(d *Detector, w *Window) *models.Attack { return d.detectRSTFlood(w.Requests) }},
+		builtin{"fin_flood", func(d *Detector, w *Window) *models.Attack { return d.detectFINFlood(w.Requests) }},
+		builtin{"invalid_tcp_flags", func(d *Detector, w *Window) *models.Attack { return d.detectInvalidTCPFlags(w.Requests) }},
+		builtin{"service_flood", func(d *Detector, w *Window) *models.Attack { return d.detectServiceFlood(w.Requests) }},
+		builtin{"low_and_slow", func(d *Detector, w *Window) *models.Attack { return d.detectLowAndSlow(w.Requests) }},
+		builtin{"rate_anomaly", func(d *Detector, w *Window) *models.Attack { return d.detectRateAnomaly(w.Metrics) }},
+		builtin{"geo_shift", func(d *Detector, w *Window) *models.Attack { return d.detectGeoShift(w) }},
+		builtin{"hosting_asn_flood", func(d *Detector, w *Window) *models.Attack { return d.detectHostingASNFlood(w) }},
+		builtin{ProfileCheck, func(d *Detector, w *Window) *models.Attack { return d.detectProfileDeviation(w) }},
+		builtin{DenylistCheck, func(d *Detector, w *Window) *models.Attack { return d.detectDenylisted(w) }},
+		rulesPlugin{},
+	}
+)
+
+// Register adds a custom detector to every environment's analysis. Call it
+// before analysis starts, typically from an init function. Names must be
+// unique, including those of the built-ins and per-cycle checks.
+func Register(plugin Plugin) error {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	name := plugin.Name()
+	if name == "" {
+		return fmt.Errorf("detector plugin has no name")
+	}
+	for _, existing := range append(registered(), cycleChecks...) {
+		if existing == name {
+			return fmt.Errorf("detector %q is already registered", name)
+		}
+	}
+	plugins = append(plugins, plugin)
+	return nil
+}
+
+// registered returns the names of the registered plugins; the caller holds pluginsMu
+func registered() []string {
+	names := make([]string, len(plugins))
+	for i, plugin := range plugins {
+		names[i] = plugin.Name()
+	}
+	return names
+}
+
+// DetectorNames lists every detector that can be enabled and disabled: the
+// registered plugins in the order they run, then the per-cycle checks
+func DetectorNames() []string {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	return append(registered(), cycleChecks...)
+}
+
+// registeredPlugins returns the plugins to run
+func registeredPlugins() []Plugin {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	return append([]Plugin(nil), plugins...)
+}
+
+// SetDisabled turns off the named detectors in every environment, and the
+// rest back on. Unknown names are rejected so a typo doesn't go unnoticed.
+func (p *Pool) SetDisabled(names []string) error {
+	known := make(map[string]bool)
+	for _, name := range DetectorNames() {
+		known[name] = true
+	}
+	disabled := make(map[string]bool, len(names))
+	var unknown []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+		disabled[name] = true
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown detectors %s; known: %s", strings.Join(unknown, ", "), strings.Join(DetectorNames(), ", "))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.disabled = disabled
+	for _, detector := range p.detectors {
+		detector.disabled = disabled
+	}
+	return nil
+}
+
+// Disabled reports whether the named detector is turned off
+func (p *Pool) Disabled(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.disabled[name]
+}
+
+// enabled reports whether the detector runs the named check
+func (d *Detector) enabled(name string) bool {
+	return !d.disabled[name]
+}
+
+// detectPerCycle runs the enabled per-cycle checks over the cycle's
+// AnalysisWindow
+func (d *Detector) detectPerCycle(metrics *TrafficMetrics) []models.Attack {
+	checks := []struct {
+		name   string
+		detect func(*TrafficMetrics) *models.Attack
+	}{
+		{ChangePointCheck, d.detectChangePoint},
+		{ForecastCheck, d.detectForecastAnomaly},
+		{MLCheck, d.detectMLAnomaly},
+	}
+
+	attacks := make([]models.Attack, 0)
+	for _, check := range checks {
+		if !d.enabled(check.name) {
+			continue
+		}
+		if attack := check.detect(metrics); attack != nil {
+			completeEvidence(attack, check.name, metrics)
+			attacks = append(attacks, *attack)
+		}
+	}
+	return attacks
+}
@@ -0,0 +1,267 @@
+package detection
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// ProfileCheck is the plugin flagging sources that break with their own history
+const ProfileCheck = "profile_deviation"
+
+const (
+	// DefaultProfiledSources is how many of each environment's busiest
+	// sources are profiled per cycle
+	DefaultProfiledSources = 1000
+	// profileMinSamples is how many windows a profile needs before the
+	// source is judged against it: half an hour of activity
+	profileMinSamples = 30
+	// profileHourDecay fades the activity of each hour every learned
+	// window, so a client that changed its schedule isn't held to the old one
+	profileHourDecay = 0.995
+)
+
+// ProfileStore keeps per-source profiles, per environment
+type ProfileStore interface {
+	SourceProfiles(env string, ips []string) (map[string]models.SourceProfile, error)
+	StoreSourceProfiles(env string, profiles []models.SourceProfile) error
+}
+
+// SetProfileStore adds per-source profiling to every environment's
+// detection: the busiest limit sources of each quiet window are learned
+// into their profiles, and sources breaking sharply with theirs are
+// flagged. A nil store or a limit of 0 turns it off.
+func (p *Pool) SetProfileStore(store ProfileStore, limit int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if limit <= 0 {
+		store = nil
+	}
+	p.profiles, p.profiledSources = store, limit
+	for _, detector := range p.detectors {
+		detector.profiles, detector.profiledSources = store, limit
+	}
+}
+
+// sourceActivity is one source's traffic over the reference window
+type sourceActivity struct {
+	requests  int // exact
+	sampled   int // in the window's requests, which may be a sample
+	paths     map[string]bool
+	responses int
+	errors    int
+}
+
+func (a *sourceActivity) pathDiversity() float64 {
+	if a.sampled == 0 {
+		return 0
+	}
+	return float64(len(a.paths)) / float64(a.sampled)
+}
+
+func (a *sourceActivity) errorRatio() (float64, bool) {
+	if a.responses == 0 {
+		return 0, false
+	}
+	return float64(a.errors) / float64(a.responses), true
+}
+
+// observeSources measures the busiest sources of the reference window and
+// fetches their profiles, keeping both for learning at the end of the cycle
+func (d *Detector) observeSources(w *Window) (map[string]*sourceActivity, map[string]models.SourceProfile) {
+	type source struct {
+		ip    IPKey
+		count int
+	}
+	busiest := make([]source, 0, len(w.Metrics.IPCounts))
+	for ip, count := range w.Metrics.IPCounts {
+		if !ip.IsZero() {
+			busiest = append(busiest, source{ip, count})
+		}
+	}
+	slices.SortFunc(busiest, func(a, b source) int { return cmp.Compare(b.count, a.count) })
+	if len(busiest) > d.profiledSources {
+		busiest = busiest[:d.profiledSources]
+	}
+
+	byKey := make(map[IPKey]*sourceActivity, len(busiest))
+	activity := make(map[string]*sourceActivity, len(busiest))
+	ips := make([]string, 0, len(busiest))
+	for _, s := range busiest {
+		a := &sourceActivity{requests: s.count, paths: make(map[string]bool)}
+		byKey[s.ip] = a
+		activity[s.ip.String()] = a
+		ips = append(ips, s.ip.String())
+	}
+	for _, req := range w.Requests {
+		a := byKey[ParseIPKey(req.SourceIP)]
+		if a == nil {
+			continue
+		}
+		a.sampled++
+		path, _, _ := strings.Cut(req.RequestPath, "?")
+		a.paths[path] = true
+		if req.StatusCode != 0 {
+			a.responses++
+			if req.StatusCode >= 400 {
+				a.errors++
+			}
+		}
+	}
+
+	profiles, err := d.profiles.SourceProfiles(d.env, ips)
+	if err != nil {
+		// Without the profiles there's nothing to judge against, and
+		// learning would start them over
+		return nil, nil
+	}
+	d.observedSources, d.observedProfiles = activity, profiles
+	return activity, profiles
+}
+
+// profileDeviation is how one source breaks with its profile
+type profileDeviation struct {
+	ip         string
+	requests   int
+	signals    []string
+	rateFactor float64
+}
+
+// deviations lists how a source's window breaks with its profile
+func (d *Detector) deviations(a *sourceActivity, profile models.SourceProfile, hour int, t *Thresholds) ([]string, float64) {
+	signals := make([]string, 0, 4)
+
+	rateFactor := float64(a.requests) / math.Max(profile.RequestRate, 1)
+	if rateFactor >= t.ProfileRateFactor {
+		signals = append(signals, fmt.Sprintf("%.0fx its usual rate", rateFactor))
+	}
+	if rise := a.pathDiversity() - profile.PathDiversity; a.sampled >= t.ProfileMinRequests && rise >= t.ProfileDiversityRiseMin {
+		signals = append(signals, fmt.Sprintf("path diversity %.2f against %.2f", a.pathDiversity(), profile.PathDiversity))
+	}
+	if ratio, ok := a.errorRatio(); ok && ratio-profile.ErrorRatio >= t.ProfileErrorRiseMin {
+		signals = append(signals, fmt.Sprintf("%.0f%% errors against %.0f%%", ratio*100, profile.ErrorRatio*100))
+	}
+
+	activity := 0.0
+	for _, h := range profile.ActiveHours {
+		activity += h
+	}
+	if activity > 0 && profile.ActiveHours[hour]/activity < t.ProfileRareHourShare {
+		signals = append(signals, fmt.Sprintf("active at %02d:00, when it rarely is", hour))
+	}
+	return signals, rateFactor
+}
+
+// detectProfileDeviation detects sources whose behavior breaks sharply with
+// their own history on several counts at once, e.g. a long-standing API
+// client suddenly sending ten times its usual rate at an hour it is never
+// active, as compromised clients and credentials do. It runs over the
+// reference window only.
+func (d *Detector) detectProfileDeviation(w *Window) *models.Attack {
+	if d.profiles == nil || w.Resolution.Window != AnalysisWindow {
+		return nil
+	}
+
+	activity, profiles := d.observeSources(w)
+	hour := d.localNow().Hour()
+
+	deviating := make([]profileDeviation, 0)
+	counts := make(map[IPKey]int)
+	for ip, a := range activity {
+		profile, ok := profiles[ip]
+		if !ok || profile.Samples < profileMinSamples || a.requests < w.Thresholds.ProfileMinRequests {
+			continue
+		}
+		signals, rateFactor := d.deviations(a, profile, hour, &w.Thresholds)
+		if len(signals) < w.Thresholds.ProfileMinDeviations {
+			continue
+		}
+		deviating = append(deviating, profileDeviation{ip: ip, requests: a.requests, signals: signals, rateFactor: rateFactor})
+		counts[ParseIPKey(ip)] = a.requests
+	}
+	if len(deviating) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(deviating, func(a, b profileDeviation) int {
+		if c := cmp.Compare(len(b.signals), len(a.signals)); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.requests, a.requests)
+	})
+	strongest := deviating[0]
+
+	details := make([]string, 0, 3)
+	for _, dev := range deviating[:min(len(deviating), 3)] {
+		details = append(details, fmt.Sprintf("%s (%s)", dev.ip, strings.Join(dev.signals, ", ")))
+	}
+
+	confidence := math.Min(0.4+0.15*float64(len(strongest.signals)), 1.0)
+
+	ev := withSources(evidence(
+		atLeast("profile_deviations", float64(len(strongest.signals)), "ProfileMinDeviations", float64(w.Thresholds.ProfileMinDeviations)),
+	), counts)
+	ev.Features["deviating_sources"] = float64(len(deviating))
+	ev.Features["rate_factor"] = strongest.rateFactor
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "PROFILE_DEVIATION",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(counts, 20),
+		SourcePrefixes: d.aggregateSources(counts),
+		SourceASNs:     d.aggregateASNs(counts),
+		Metrics:        attackMetrics(w.Metrics),
+		Description: fmt.Sprintf("%d sources breaking with their usual behavior: %s",
+			len(deviating), strings.Join(details, "; ")),
+		Evidence:  ev,
+		Mitigated: false,
+	}
+}
+
+// learnSourceProfiles folds the sources observed in the last reference
+// window into their profiles, returning the updated profiles
+func (d *Detector) learnSourceProfiles() []models.SourceProfile {
+	if len(d.observedSources) == 0 {
+		return nil
+	}
+
+	now := d.localNow()
+	learned := make([]models.SourceProfile, 0, len(d.observedSources))
+	for ip, a := range d.observedSources {
+		profile, ok := d.observedProfiles[ip]
+		if !ok {
+			profile = models.SourceProfile{IP: ip, FirstSeen: now}
+		} else if now.Sub(profile.LastSeen) < AnalysisWindow {
+			// Consecutive windows overlap; learn each minute once
+			continue
+		}
+		alpha := math.Max(baselineAlpha, 1/float64(profile.Samples+1))
+
+		profile.RequestRate += alpha * (float64(a.requests) - profile.RequestRate)
+		if a.sampled > 0 {
+			profile.PathDiversity += alpha * (a.pathDiversity() - profile.PathDiversity)
+		}
+		if ratio, ok := a.errorRatio(); ok {
+			profile.ErrorRatio += alpha * (ratio - profile.ErrorRatio)
+		}
+		for h := range profile.ActiveHours {
+			profile.ActiveHours[h] *= profileHourDecay
+		}
+		profile.ActiveHours[now.Hour()]++
+		profile.Samples++
+		profile.LastSeen = now
+
+		learned = append(learned, profile)
+	}
+	return learned
+}
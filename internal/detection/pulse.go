@@ -0,0 +1,291 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// PulseOutcome says how a detection relates to attacks already seen
+type PulseOutcome int
+
+const (
+	// PulseNew is a first sighting and starts a new attack record
+	PulseNew PulseOutcome = iota
+	// PulseContinued is the burst already in progress
+	PulseContinued
+	// PulseBurst is a new burst of an attack that had gone quiet
+	PulseBurst
+)
+
+// maxCampaignSources caps the source IPs accumulated across bursts
+const maxCampaignSources = 100
+
+// PulseCorrelator folds repeated detections of the same attack into one
+// record and tracks it until it ends. A detection continues the tracked
+// attack with its fingerprint, or else one of the same type and environment
+// sharing a target; among several candidates the one sharing the most
+// sources wins. Attacks without targets only continue on their fingerprint.
+// Detections closer together than burstGap belong to the same burst; a
+// longer silence followed by a new detection within window starts another
+// burst of the same campaign, which makes it a pulse wave instead of a
+// fresh attack every few seconds. An attack not detected for endAfter has
+// ended; a burst within window reopens it.
+type PulseCorrelator struct {
+	mu        sync.Mutex
+	burstGap  time.Duration
+	endAfter  time.Duration
+	window    time.Duration
+	campaigns map[string]*pulseCampaign // by attack ID
+
+	// MergeBlocked reports whether an operator split two attacks apart;
+	// a detection folded into one keeps the other's sources out of it
+	MergeBlocked func(a, b string) bool
+}
+
+type pulseCampaign struct {
+	attack      models.Attack
+	firstBurst  time.Time
+	burstStart  time.Time
+	lastSeen    time.Time
+	bursts      int
+	completed   int
+	burstLength time.Duration // total length of completed bursts
+	ended       bool
+	resumedAt   time.Time // when tracking resumed from a snapshot
+}
+
+// quietSince is when the campaign was last detected, or when tracking
+// resumed if that is later
+func (c *pulseCampaign) quietSince() time.Time {
+	if c.resumedAt.After(c.lastSeen) {
+		return c.resumedAt
+	}
+	return c.lastSeen
+}
+
+// NewPulseCorrelator creates a correlator. endAfter is raised to burstGap
+// and window to endAfter, so an attack always ends before it is forgotten.
+func NewPulseCorrelator(burstGap, endAfter, window time.Duration) *PulseCorrelator {
+	endAfter = max(endAfter, burstGap)
+	return &PulseCorrelator{
+		burstGap:  burstGap,
+		endAfter:  endAfter,
+		window:    max(window, endAfter),
+		campaigns: make(map[string]*pulseCampaign),
+	}
+}
+
+// Observe correlates a detection made at now with earlier ones. It returns
+// the attack record to store, which keeps the campaign's ID and start time
+// once the detection is folded in.
+func (p *PulseCorrelator) Observe(attack models.Attack, now time.Time) (models.Attack, PulseOutcome) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, campaign := range p.campaigns {
+		if now.Sub(campaign.quietSince()) > p.window {
+			delete(p.campaigns, id)
+		}
+	}
+
+	campaign := p.match(attack)
+	if campaign == nil {
+		attack.Fingerprint = Fingerprint(attack)
+		attack.Detections = 1
+		attack.LastDetected = &now
+		p.campaigns[attack.ID] = &pulseCampaign{
+			attack:     attack,
+			firstBurst: now,
+			burstStart: now,
+			lastSeen:   now,
+			bursts:     1,
+		}
+		return attack, PulseNew
+	}
+
+	outcome := PulseContinued
+	if now.Sub(campaign.quietSince()) > p.burstGap {
+		campaign.burstLength += campaign.lastSeen.Sub(campaign.burstStart)
+		campaign.completed++
+		campaign.bursts++
+		campaign.burstStart = now
+		outcome = PulseBurst
+	}
+	campaign.lastSeen = now
+
+	merged := campaign.attack
+	if campaign.ended {
+		campaign.ended = false
+		merged.EndTime = nil
+	}
+	merged.Confidence = math.Max(merged.Confidence, attack.Confidence)
+	if models.SeverityRank(attack.Severity) > models.SeverityRank(merged.Severity) {
+		merged.Severity = attack.Severity
+	}
+	merged.SourceIPs = unionStrings(merged.SourceIPs, p.withoutSplitSources(campaign.attack.ID, attack.SourceIPs))
+	if len(merged.SourceIPs) > maxCampaignSources {
+		merged.SourceIPs = merged.SourceIPs[:maxCampaignSources]
+	}
+	merged.SourceReputation = sourceReputation(merged.SourceIPs, attack.SourceReputation, merged.SourceReputation)
+	merged.TargetIPs = unionStrings(merged.TargetIPs, attack.TargetIPs)
+	merged.SourcePrefixes = attack.SourcePrefixes
+	merged.SourceASNs = attack.SourceASNs
+	merged.Metrics = attack.Metrics
+	merged.DetectionMode = attack.DetectionMode
+	merged.Spoofing = attack.Spoofing
+	merged.Evidence = attack.Evidence
+	merged.Description = attack.Description
+	merged.Detections++
+	merged.LastDetected = &now
+	merged.DurationSeconds = now.Sub(merged.StartTime).Round(time.Second).Seconds()
+
+	if campaign.bursts > 1 {
+		pulse := &models.PulseWave{
+			Bursts:        campaign.bursts,
+			PeriodSeconds: campaign.burstStart.Sub(campaign.firstBurst).Seconds() / float64(campaign.bursts-1),
+			LastBurst:     campaign.burstStart,
+		}
+		if campaign.completed > 0 {
+			pulse.BurstSeconds = campaign.burstLength.Seconds() / float64(campaign.completed)
+		}
+		merged.Pulse = pulse
+		merged.Description = fmt.Sprintf("Pulse-wave campaign: %d bursts every ~%.0fs; latest: %s",
+			pulse.Bursts, pulse.PeriodSeconds, attack.Description)
+	}
+
+	campaign.attack = merged
+	return merged, outcome
+}
+
+// match finds the tracked attack a detection continues: the one with its
+// fingerprint, or else the one sharing the most sources and then the most
+// recently detected
+func (p *PulseCorrelator) match(attack models.Attack) *pulseCampaign {
+	fingerprint := Fingerprint(attack)
+	var best *pulseCampaign
+	bestShared := -1
+	for _, campaign := range p.campaigns {
+		if campaign.attack.Fingerprint == fingerprint {
+			return campaign
+		}
+		shared, ok := continues(campaign.attack, attack)
+		if !ok {
+			continue
+		}
+		if shared > bestShared || (shared == bestShared && campaign.lastSeen.After(best.lastSeen)) {
+			best, bestShared = campaign, shared
+		}
+	}
+	return best
+}
+
+// withoutSplitSources drops the source IPs that belong to an attack an
+// operator split away from attack id
+func (p *PulseCorrelator) withoutSplitSources(id string, sourceIPs []string) []string {
+	if p.MergeBlocked == nil {
+		return sourceIPs
+	}
+
+	split := make(map[string]bool)
+	for otherID, other := range p.campaigns {
+		if otherID == id || !p.MergeBlocked(id, otherID) {
+			continue
+		}
+		for _, ip := range other.attack.SourceIPs {
+			split[ip] = true
+		}
+	}
+	if len(split) == 0 {
+		return sourceIPs
+	}
+
+	kept := make([]string, 0, len(sourceIPs))
+	for _, ip := range sourceIPs {
+		if !split[ip] {
+			kept = append(kept, ip)
+		}
+	}
+	return kept
+}
+
+// Merge follows an operator folding attack sourceID into merged: the two
+// are tracked as one campaign under merged's ID from now on
+func (p *PulseCorrelator) Merge(merged models.Attack, sourceID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, source := p.campaigns[merged.ID], p.campaigns[sourceID]
+	delete(p.campaigns, sourceID)
+	switch {
+	case target == nil && source == nil:
+		return
+	case target == nil:
+		target = source
+		p.campaigns[merged.ID] = target
+	case source != nil:
+		if source.firstBurst.Before(target.firstBurst) {
+			target.firstBurst = source.firstBurst
+		}
+		if source.lastSeen.After(target.lastSeen) {
+			target.lastSeen = source.lastSeen
+			target.burstStart = source.burstStart
+		}
+		if source.resumedAt.After(target.resumedAt) {
+			target.resumedAt = source.resumedAt
+		}
+		target.ended = target.ended && source.ended
+	}
+	target.attack = merged
+}
+
+// Split follows an operator splitting split off remaining: split is tracked
+// as a campaign of its own, continuing the bursts seen so far
+func (p *PulseCorrelator) Split(remaining, split models.Attack) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	campaign, ok := p.campaigns[remaining.ID]
+	if !ok {
+		return
+	}
+	campaign.attack = remaining
+
+	splitCampaign := *campaign
+	splitCampaign.attack = split
+	p.campaigns[split.ID] = &splitCampaign
+}
+
+// Update replaces the tracked record of an attack after the caller
+// annotated it, so the annotations carry over to later detections
+func (p *PulseCorrelator) Update(attack models.Attack) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if campaign, ok := p.campaigns[attack.ID]; ok {
+		campaign.attack = attack
+	}
+}
+
+// End closes the attacks not detected for endAfter as of now, setting their
+// EndTime to their last detection, and returns them
+func (p *PulseCorrelator) End(now time.Time) []models.Attack {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ended := make([]models.Attack, 0)
+	for _, campaign := range p.campaigns {
+		if campaign.ended || now.Sub(campaign.quietSince()) <= p.endAfter {
+			continue
+		}
+
+		end := campaign.lastSeen
+		campaign.ended = true
+		campaign.attack.EndTime = &end
+		ended = append(ended, campaign.attack)
+	}
+	return ended
+}
@@ -0,0 +1,81 @@
+package detection
+
+import "github.com/nshruti113/ddos-detection-dashboard/internal/models"
+
+// reputationBoost is how far an attack's confidence moves toward certainty
+// when threat intelligence lists every one of its sources with score 100
+const reputationBoost = 0.5
+
+// Reputation scores sources by what threat intelligence feeds know about them
+type Reputation interface {
+	Lookup(ip string) (models.SourceReputation, bool)
+}
+
+// SetReputation adds threat intelligence to every environment's detection:
+// attacks list the reputation of their sources, and attacks from sources
+// the feeds know are reported with more confidence. Nil removes it.
+func (p *Pool) SetReputation(reputation Reputation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.reputation = reputation
+	for _, detector := range p.detectors {
+		detector.reputation = reputation
+	}
+}
+
+// scoreReputation annotates attacks with the reputation of their sources
+// and raises the confidence of those whose sources the feeds list, in
+// proportion to how many of them are listed and how badly
+func (d *Detector) scoreReputation(attacks []models.Attack) {
+	if d.reputation == nil {
+		return
+	}
+
+	for i := range attacks {
+		attack := &attacks[i]
+		known := 0.0
+		for _, ip := range attack.SourceIPs {
+			if rep, ok := d.reputation.Lookup(ip); ok {
+				attack.SourceReputation = append(attack.SourceReputation, rep)
+				known += float64(rep.Score) / 100
+			}
+		}
+		if len(attack.SourceReputation) == 0 {
+			continue
+		}
+
+		boost := reputationBoost * known / float64(len(attack.SourceIPs))
+		attack.Confidence += (1 - attack.Confidence) * boost
+		if severity := getSeverity(attack.Confidence); models.SeverityRank(severity) > models.SeverityRank(attack.Severity) {
+			attack.Severity = severity
+		}
+		if attack.Evidence != nil {
+			attack.Evidence.Features["known_bad_sources"] = float64(len(attack.SourceReputation))
+			attack.Evidence.Features["reputation_boost"] = boost
+		}
+	}
+}
+
+// sourceReputation keeps the reputations of the given sources, taking each
+// from the first list that has it
+func sourceReputation(sources []string, lists ...[]models.SourceReputation) []models.SourceReputation {
+	wanted := make(map[string]bool, len(sources))
+	for _, ip := range sources {
+		wanted[ip] = true
+	}
+
+	kept := make([]models.SourceReputation, 0)
+	for _, list := range lists {
+		for _, rep := range list {
+			if wanted[rep.IP] {
+				kept = append(kept, rep)
+				delete(wanted, rep.IP)
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
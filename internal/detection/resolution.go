@@ -0,0 +1,144 @@
+package detection
+
+import (
+	"math"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// LongestWindow is the longest window analysis inspects, and so how much
+// recent traffic each cycle reads
+const LongestWindow = 5 * time.Minute
+
+// Resolution is one of the windows every analysis cycle runs the detectors
+// over. Thresholds and the request-rate baseline are set for
+// AnalysisWindow; Scale multiplies the count thresholds and the baseline
+// for this window, while ratios, shares and entropies apply as they are.
+type Resolution struct {
+	Name   string
+	Window time.Duration
+	Scale  float64
+}
+
+// Resolutions are the windows analyzed each cycle, shortest first. The
+// short window catches bursts that are over before they weigh on a minute;
+// the long one catches low-and-slow attacks that stay under the per-minute
+// thresholds. Both scale thresholds by less than their length relative to
+// a minute, which is what makes them more sensitive to their kind of attack.
+var Resolutions = []Resolution{
+	{Name: "short", Window: 10 * time.Second, Scale: 0.25},
+	{Name: "medium", Window: AnalysisWindow, Scale: 1},
+	{Name: "long", Window: LongestWindow, Scale: 4},
+}
+
+// AttackWindow is the window an attack was detected over. Attacks stored
+// before windows were recorded were detected over AnalysisWindow.
+func AttackWindow(attack models.Attack) time.Duration {
+	if attack.WindowSeconds <= 0 {
+		return AnalysisWindow
+	}
+	return time.Duration(attack.WindowSeconds) * time.Second
+}
+
+// analyzeAt runs the detectors over one resolution's traffic with the
+// thresholds and baseline scaled to its window. The scaled baseline is a
+// copy, so only the reference window feeds what the detector learns.
+func (d *Detector) analyzeAt(res Resolution, requests []models.TrafficRequest, metrics *TrafficMetrics) []models.Attack {
+	if res.Scale != 1 {
+		thresholds, baseline := d.thresholds.scaled(res.Scale), d.baseline.scaled(res.Scale)
+		defer func(t *Thresholds, b *Baseline) { d.thresholds, d.baseline = t, b }(d.thresholds, d.baseline)
+		d.thresholds, d.baseline = &thresholds, &baseline
+	}
+
+	attacks := d.analyze(res, requests, metrics)
+	for i := range attacks {
+		attacks[i].Window = res.Name
+		attacks[i].WindowSeconds = int(res.Window / time.Second)
+		if m := attacks[i].Metrics; m != nil {
+			m.WindowDuration = attacks[i].WindowSeconds
+			m.RequestsPerSec = float64(m.TotalRequests) / res.Window.Seconds()
+		}
+	}
+	return attacks
+}
+
+// strongestPerType keeps one detection per attack type, the most confident;
+// on a tie the earlier one, which is the shorter window
+func strongestPerType(attacks []models.Attack) []models.Attack {
+	kept := make([]models.Attack, 0, len(attacks))
+	index := make(map[string]int)
+	for _, attack := range attacks {
+		i, ok := index[attack.Type]
+		if !ok {
+			index[attack.Type] = len(kept)
+			kept = append(kept, attack)
+			continue
+		}
+		if attack.Confidence > kept[i].Confidence {
+			kept[i] = attack
+		}
+	}
+	return kept
+}
+
+// requestsSince returns the requests made after since
+func requestsSince(requests []models.TrafficRequest, since time.Time) []models.TrafficRequest {
+	windowed := make([]models.TrafficRequest, 0, len(requests))
+	for _, req := range requests {
+		if req.Timestamp.After(since) {
+			windowed = append(windowed, req)
+		}
+	}
+	return windowed
+}
+
+// scaled returns a copy of the thresholds with every count multiplied by scale
+func (t *Thresholds) scaled(scale float64) Thresholds {
+	s := *t
+	for _, count := range []*int{
+		&s.SYNFloodThreshold,
+		&s.HTTPFloodThreshold,
+		&s.UDPFloodThreshold,
+		&s.SlowlorisThreshold,
+		&s.DNSResponseThreshold,
+		&s.ACKFloodThreshold,
+		&s.RSTFloodThreshold,
+		&s.FINFloodThreshold,
+		&s.InvalidFlagThreshold,
+		&s.SlowTransferThreshold,
+		&s.DNSZoneQueryThreshold,
+		&s.CarpetBombThreshold,
+		&s.AuthFailureThreshold,
+		&s.ErrorRateMinResponses,
+		&s.FingerprintFloodThreshold,
+		&s.BotFloodThreshold,
+		&s.SpoofMinPackets,
+		&s.RegularClientMaxRequests,
+		&s.ServiceFloodThreshold,
+		&s.GeoShiftMinRequests,
+		&s.HostingASNFloodThreshold,
+		&s.JA3FloodThreshold,
+		&s.MethodFloodThreshold,
+		&s.OversizedHeaderThreshold,
+	} {
+		*count = max(int(math.Round(float64(*count)*scale)), 1)
+	}
+	return s
+}
+
+// scaled returns a copy of the baseline with the request rates and
+// per-prefix requests, counts per AnalysisWindow, multiplied by scale
+func (b *Baseline) scaled(scale float64) Baseline {
+	s := *b
+	s.AverageRequestRate *= scale
+	s.StandardDeviation *= scale
+	s.Seasonal = scaledSeasonal(b.Seasonal, scale)
+	if b.PrefixRequests != nil {
+		s.PrefixRequests = make(map[string]float64, len(b.PrefixRequests))
+		for prefix, requests := range b.PrefixRequests {
+			s.PrefixRequests[prefix] = requests * scale
+		}
+	}
+	return s
+}
@@ -0,0 +1,144 @@
+package detection
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/rules"
+)
+
+// RulesPlugin is the name rule-based detections are enabled and disabled under
+const RulesPlugin = "rules"
+
+// RuleVariables are the window metrics rule conditions can use; map
+// variables take a key, e.g. protocol_counts.UDP or path_counts["/login"]
+var RuleVariables = rules.Vars{
+	"total_requests":     false,
+	"unique_ips":         false,
+	"requests_per_sec":   false,
+	"requests_per_ip":    false,
+	"ip_entropy":         false,
+	"path_entropy":       false,
+	"user_agent_entropy": false,
+	"avg_conn_duration":  false,
+	"syn_packets":        false,
+	"tool_requests":      false,
+	"source_p50":         false,
+	"source_p95":         false,
+	"source_p99":         false,
+	"source_max":         false,
+	"baseline_requests":  false,
+	"protocol_counts":    true,
+	"path_counts":        true,
+	"user_agent_counts":  true,
+	"method_counts":      true,
+	"bogon_counts":       true,
+}
+
+// rulesPlugin raises an attack for every rule whose condition holds over
+// an AnalysisWindow
+type rulesPlugin struct{}
+
+func (rulesPlugin) Name() string {
+	return RulesPlugin
+}
+
+// Detect evaluates the rules over the reference window only, so a rule's
+// counts are per minute whichever resolutions run
+func (rulesPlugin) Detect(w *Window) []models.Attack {
+	d := w.detector
+	if d == nil || d.ruleEngine == nil || w.Resolution.Window != AnalysisWindow {
+		return nil
+	}
+
+	attacks := make([]models.Attack, 0)
+	for _, match := range d.ruleEngine.Match(d.ruleLookup(w.Metrics)) {
+		severity := match.Severity
+		if severity == "" {
+			severity = getSeverity(match.Confidence)
+		}
+		description := match.Description
+		if description == "" {
+			description = fmt.Sprintf("Detection rule %s matched", match.Name)
+		}
+
+		attacks = append(attacks, models.Attack{
+			ID:             uuid.New().String(),
+			Type:           match.Type,
+			Severity:       severity,
+			Confidence:     match.Confidence,
+			StartTime:      time.Now(),
+			SourceIPs:      getTopIPs(w.Metrics.IPCounts, 20),
+			SourcePrefixes: d.aggregateSources(w.Metrics.IPCounts),
+			SourceASNs:     d.aggregateASNs(w.Metrics.IPCounts),
+			Description:    fmt.Sprintf("%s: %s", description, match.Values),
+			Evidence:       &models.Evidence{Features: match.Variables, Condition: match.Condition},
+			Metrics:        attackMetrics(w.Metrics),
+			Mitigated:      false,
+		})
+	}
+	return attacks
+}
+
+// ruleLookup resolves RuleVariables over a window's metrics
+func (d *Detector) ruleLookup(metrics *TrafficMetrics) rules.Lookup {
+	return func(name, key string) float64 {
+		switch name {
+		case "total_requests":
+			return float64(metrics.TotalRequests)
+		case "unique_ips":
+			return float64(metrics.UniqueIPs)
+		case "requests_per_sec":
+			return float64(metrics.TotalRequests) / AnalysisWindow.Seconds()
+		case "requests_per_ip":
+			return metrics.RequestsPerIP
+		case "ip_entropy":
+			return metrics.IPEntropy
+		case "path_entropy":
+			return metrics.PathEntropy
+		case "user_agent_entropy":
+			return metrics.UserAgentEntropy
+		case "avg_conn_duration":
+			return metrics.AvgConnDuration
+		case "syn_packets":
+			return float64(metrics.SYNPacketCount)
+		case "tool_requests":
+			return float64(metrics.ToolRequests)
+		case "source_p50":
+			return float64(metrics.SourceCounts.P50)
+		case "source_p95":
+			return float64(metrics.SourceCounts.P95)
+		case "source_p99":
+			return float64(metrics.SourceCounts.P99)
+		case "source_max":
+			return float64(metrics.SourceCounts.Max)
+		case "baseline_requests":
+			return d.baseline.AverageRequestRate
+		case "protocol_counts":
+			return float64(metrics.ProtocolCounts[key])
+		case "path_counts":
+			return float64(metrics.PathCounts[key])
+		case "user_agent_counts":
+			return float64(metrics.UserAgentCounts[key])
+		case "method_counts":
+			return float64(metrics.MethodCounts[key])
+		case "bogon_counts":
+			return float64(metrics.BogonCounts[key])
+		}
+		return 0
+	}
+}
+
+// SetRules adds the rules of an engine to every environment's detection;
+// nil removes them
+func (p *Pool) SetRules(engine *rules.Engine) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ruleEngine = engine
+	for _, detector := range p.detectors {
+		detector.ruleEngine = engine
+	}
+}
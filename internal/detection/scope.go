@@ -0,0 +1,195 @@
+package detection
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/rules"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+)
+
+// DefaultEnvironment is the scope used for traffic that carries no environment tag
+const DefaultEnvironment = "default"
+
+// Pool keeps an independent Detector per environment so that each site
+// learns its own baseline and a load test in one scope can't trip another
+type Pool struct {
+	mu         sync.Mutex
+	detectors  map[string]*Detector
+	thresholds map[string]Thresholds
+	defaults   *Thresholds // for environments without their own; nil is DefaultThresholds
+	mode       Mode
+	zones      *tz.Zones
+	ml         *mlScoring
+	disabled   map[string]bool
+	services   []ServiceGroup
+	dnsZones   []string
+	ruleEngine *rules.Engine
+	allowlist  *iplist.Set
+	denylist   *iplist.Set
+	reputation Reputation
+	locator    Locator
+
+	// Per-source profiles and how many sources per environment are profiled
+	profiles        ProfileStore
+	profiledSources int
+
+	// Thresholds evaluated in shadow mode, for every environment and per
+	// environment; environments with neither aren't shadowed
+	shadowDefaults *Thresholds
+	shadow         map[string]Thresholds
+}
+
+func NewPool() *Pool {
+	return &Pool{
+		detectors:  make(map[string]*Detector),
+		thresholds: make(map[string]Thresholds),
+		shadow:     make(map[string]Thresholds),
+		mode:       ModeFull,
+	}
+}
+
+// SetMode switches every environment between full and approximate analysis
+func (p *Pool) SetMode(mode Mode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.mode = mode
+}
+
+// Mode returns the current analysis mode
+func (p *Pool) Mode() Mode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.mode
+}
+
+// SetThresholds overrides the thresholds for an environment. An existing
+// detector for that environment keeps its learned baseline; like the other
+// settings, call it between analysis cycles.
+func (p *Pool) SetThresholds(env string, thresholds Thresholds) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.setThresholds(normalizeEnvironment(env), thresholds)
+}
+
+// setThresholds overrides an environment's thresholds; the caller holds p.mu
+func (p *Pool) setThresholds(env string, thresholds Thresholds) {
+	p.thresholds[env] = thresholds
+	if detector, ok := p.detectors[env]; ok {
+		detector.thresholds = &thresholds
+	}
+}
+
+// Get returns the detector for an environment, creating it on first use
+func (p *Pool) Get(env string) *Detector {
+	env = normalizeEnvironment(env)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if detector, ok := p.detectors[env]; ok {
+		return detector
+	}
+
+	detector := p.newDetector(env, p.thresholdsFor(env))
+	p.detectors[env] = detector
+	return detector
+}
+
+// newDetector creates a detector for an environment in its time zone
+func (p *Pool) newDetector(env string, thresholds Thresholds) *Detector {
+	detector := NewDetectorWithThresholds(thresholds)
+	detector.loc = p.zones.For(env)
+	detector.ml = p.ml
+	detector.disabled = p.disabled
+	detector.services = p.services
+	detector.dnsZones = p.dnsZones
+	detector.ruleEngine = p.ruleEngine
+	detector.denylist = p.denylist
+	detector.reputation = p.reputation
+	detector.locator = p.locator
+	detector.env = env
+	detector.profiles, detector.profiledSources = p.profiles, p.profiledSources
+	return detector
+}
+
+// Environments returns the environments that have seen traffic, sorted by name
+func (p *Pool) Environments() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	envs := make([]string, 0, len(p.detectors))
+	for env := range p.detectors {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	return envs
+}
+
+// AnalyzeTraffic splits traffic by environment and runs each slice through
+// that environment's detector. Detected attacks are tagged with their scope.
+// Allowlisted sources are left out; a StreamWindow should never be given them.
+func (p *Pool) AnalyzeTraffic(requests []models.TrafficRequest) []models.Attack {
+	return p.AnalyzeSample(requests, 1)
+}
+
+// AnalyzeSample is AnalyzeTraffic for a sample taken as the traffic was
+// read, each request standing for weight
+func (p *Pool) AnalyzeSample(requests []models.TrafficRequest, weight int) []models.Attack {
+	attacks := make([]models.Attack, 0)
+	mode := p.Mode()
+
+	for env, scoped := range PartitionByEnvironment(p.untrusted(requests)) {
+		for _, attack := range p.Get(env).AnalyzeSampleMode(scoped, weight, mode) {
+			attack.Environment = env
+			attacks = append(attacks, attack)
+		}
+	}
+
+	return attacks
+}
+
+// AnalyzeWindow runs each environment's share of a stream window through
+// that environment's detector, as AnalyzeTraffic does for fetched traffic
+func (p *Pool) AnalyzeWindow(window *StreamWindow, now time.Time) []models.Attack {
+	return p.AnalyzeSnapshot(window.Snapshot(now))
+}
+
+// AnalyzeSnapshot is AnalyzeWindow over a window snapshot already taken
+func (p *Pool) AnalyzeSnapshot(snapshot map[string][]WindowSlice) []models.Attack {
+	attacks := make([]models.Attack, 0)
+	mode := p.Mode()
+
+	for env, slices := range snapshot {
+		for _, attack := range p.Get(env).AnalyzeWindowMode(slices, mode) {
+			attack.Environment = env
+			attacks = append(attacks, attack)
+		}
+	}
+
+	return attacks
+}
+
+// PartitionByEnvironment groups requests by their environment tag
+func PartitionByEnvironment(requests []models.TrafficRequest) map[string][]models.TrafficRequest {
+	partitions := make(map[string][]models.TrafficRequest)
+	for _, req := range requests {
+		env := normalizeEnvironment(req.Environment)
+		partitions[env] = append(partitions[env], req)
+	}
+	return partitions
+}
+
+func normalizeEnvironment(env string) string {
+	if env == "" {
+		return DefaultEnvironment
+	}
+	return env
+}
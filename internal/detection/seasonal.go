@@ -0,0 +1,152 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+)
+
+const (
+	// seasonalBuckets is one bucket per local hour of a weekday, then one
+	// per local hour of a weekend day
+	seasonalBuckets = 48
+	// seasonalAlpha is the weight of each learned window in its bucket,
+	// about three days' worth of one hour's analysis cycles, so a bucket
+	// remembers previous days instead of just the last few minutes
+	seasonalAlpha = 1.0 / 2000
+	// minSeasonalSamples is how many windows a bucket learns before it
+	// replaces the overall baseline, about half an hour of cycles
+	minSeasonalSamples = 360
+)
+
+// SeasonalBucket is the learned request rate of one local hour of the day,
+// on weekdays or on weekends
+type SeasonalBucket struct {
+	AverageRequestRate float64
+	StandardDeviation  float64
+	Samples            int
+}
+
+// HourlyRate is the average per-AnalysisWindow request count over one
+// hour of history
+type HourlyRate struct {
+	Start time.Time
+	Rate  float64
+}
+
+// seasonalIndex returns the bucket for a local time
+func seasonalIndex(t time.Time) int {
+	if day := t.Weekday(); day == time.Saturday || day == time.Sunday {
+		return 24 + t.Hour()
+	}
+	return t.Hour()
+}
+
+// seasonalName describes a bucket, e.g. "weekday 09:00"
+func seasonalName(index int) string {
+	if index >= 24 {
+		return fmt.Sprintf("weekend %02d:00", index-24)
+	}
+	return fmt.Sprintf("weekday %02d:00", index)
+}
+
+// rateAt returns the request rate expected at local time t and its spread:
+// the seasonal bucket once it has learned enough, the overall baseline
+// otherwise. The name says which one it was.
+func (b *Baseline) rateAt(t time.Time) (mean, stddev float64, name string) {
+	if len(b.Seasonal) == seasonalBuckets {
+		i := seasonalIndex(t)
+		if bucket := b.Seasonal[i]; bucket.Samples >= minSeasonalSamples {
+			return bucket.AverageRequestRate, bucket.StandardDeviation, seasonalName(i)
+		}
+	}
+	return b.AverageRequestRate, b.StandardDeviation, "overall"
+}
+
+// learnSeasonal learns a window's request rate into the bucket of local
+// time t the way UpdateBaseline learns the overall rate
+func (b *Baseline) learnSeasonal(t time.Time, rate float64) {
+	if len(b.Seasonal) != seasonalBuckets {
+		b.Seasonal = make([]SeasonalBucket, seasonalBuckets)
+	} else {
+		// The previous baseline shares the slice and must stay unchanged
+		b.Seasonal = append([]SeasonalBucket(nil), b.Seasonal...)
+	}
+
+	bucket := &b.Seasonal[seasonalIndex(t)]
+	alpha := math.Max(seasonalAlpha, 1/float64(bucket.Samples+1))
+	diff := rate - bucket.AverageRequestRate
+	variance := bucket.StandardDeviation * bucket.StandardDeviation
+	if bucket.Samples == 0 {
+		diff, variance = 0, 0
+		bucket.AverageRequestRate = rate
+	}
+
+	bucket.AverageRequestRate += alpha * diff
+	bucket.StandardDeviation = math.Sqrt((1 - alpha) * (variance + alpha*diff*diff))
+	bucket.Samples++
+}
+
+// rebuildSeasonal replaces the buckets that hourly history covers with the
+// mean and spread of those hours. History counts as enough for a bucket to
+// be trusted right away; buckets without history keep what they learned.
+func (b *Baseline) rebuildSeasonal(history []HourlyRate, loc *time.Location) {
+	rates := make(map[int][]float64)
+	for _, hour := range history {
+		if hour.Rate > 0 {
+			i := seasonalIndex(hour.Start.In(loc))
+			rates[i] = append(rates[i], hour.Rate)
+		}
+	}
+	if len(rates) == 0 {
+		return
+	}
+
+	seasonal := make([]SeasonalBucket, seasonalBuckets)
+	copy(seasonal, b.Seasonal)
+	for i, samples := range rates {
+		mean, stddev := meanAndDeviation(samples)
+		seasonal[i] = SeasonalBucket{
+			AverageRequestRate: mean,
+			StandardDeviation:  math.Max(stddev, minStandardDeviation(mean)),
+			Samples:            max(seasonal[i].Samples, minSeasonalSamples),
+		}
+	}
+	b.Seasonal = seasonal
+}
+
+// SetZones sets the time zones whose local hours and weekends the seasonal
+// baselines follow, per environment. Without zones they follow UTC.
+func (p *Pool) SetZones(zones *tz.Zones) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.zones = zones
+	for env, detector := range p.detectors {
+		detector.loc = zones.For(env)
+	}
+}
+
+// localNow is the current time in the detector's zone
+func (d *Detector) localNow() time.Time {
+	if d.loc == nil {
+		return time.Now().UTC()
+	}
+	return time.Now().In(d.loc)
+}
+
+// scaledSeasonal returns a copy of the buckets with every rate multiplied by scale
+func scaledSeasonal(seasonal []SeasonalBucket, scale float64) []SeasonalBucket {
+	if seasonal == nil {
+		return nil
+	}
+	s := make([]SeasonalBucket, len(seasonal))
+	for i, bucket := range seasonal {
+		bucket.AverageRequestRate *= scale
+		bucket.StandardDeviation *= scale
+		s[i] = bucket
+	}
+	return s
+}
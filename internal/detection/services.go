@@ -0,0 +1,229 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// ServiceGroup is a set of destination ports serving one service, e.g. 80,
+// 443 and 8443 for "web". Traffic to any of the ports counts toward the
+// service, so a flood spread across them is judged as a whole.
+type ServiceGroup struct {
+	Name  string
+	Ports []int
+	// Target limits the group to destinations in a prefix; the zero prefix
+	// matches every destination
+	Target netip.Prefix
+}
+
+// matches reports whether traffic to the address and port belongs to the group
+func (g ServiceGroup) matches(addr netip.Addr, port int) bool {
+	if g.Target.IsValid() && !g.Target.Contains(addr) {
+		return false
+	}
+	for _, p := range g.Ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseServiceGroups reads "web=80+443+8443,mail@203.0.113.0/24=25+465+587".
+// A group may be limited to a destination address or prefix after an @.
+func ParseServiceGroups(spec string) ([]ServiceGroup, error) {
+	groups := make([]ServiceGroup, 0)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, ports, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("service group %q is not name=port+port", part)
+		}
+
+		group := ServiceGroup{Name: name}
+		if name, target, ok := strings.Cut(name, "@"); ok {
+			group.Name = name
+			prefix, err := parsePrefix(target)
+			if err != nil {
+				return nil, fmt.Errorf("service group %q: %w", part, err)
+			}
+			group.Target = prefix
+		}
+		if group.Name == "" {
+			return nil, fmt.Errorf("service group %q has no name", part)
+		}
+
+		for _, p := range strings.Split(ports, "+") {
+			port, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("service group %q: invalid port %q", part, p)
+			}
+			group.Ports = append(group.Ports, port)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// parsePrefix reads a CIDR prefix or a single address
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// SetServiceGroups sets the port groups traffic is aggregated over per
+// destination, in every environment. Ports outside every group are each
+// a service of their own.
+func (p *Pool) SetServiceGroups(groups []ServiceGroup) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.services = groups
+	for _, detector := range p.detectors {
+		detector.services = groups
+	}
+}
+
+// serviceKey identifies one service on one destination address
+type serviceKey struct {
+	dest    netip.Addr
+	service string
+}
+
+// serviceLoad tallies the traffic to one service on one destination
+type serviceLoad struct {
+	total   int
+	ports   map[int]int
+	sources map[IPKey]int
+}
+
+// service returns the service traffic to a destination port belongs to:
+// the first group listing the port, or the port on its own
+func (d *Detector) service(addr netip.Addr, port int) string {
+	for _, group := range d.services {
+		if group.matches(addr, port) {
+			return group.Name
+		}
+	}
+	return strconv.Itoa(port)
+}
+
+// detectServiceFlood detects a flood of one service on one destination.
+// Ports declared as a service group are counted together, so an attacker
+// spreading a flood evenly across a service's ports stays visible even
+// though no single port carries enough to stand out.
+func (d *Detector) detectServiceFlood(requests []models.TrafficRequest) *models.Attack {
+	w := d.sampleWeight()
+	services := make(map[serviceKey]*serviceLoad)
+
+	for _, req := range requests {
+		if req.DestPort == 0 {
+			continue
+		}
+		addr, err := netip.ParseAddr(req.DestIP)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+
+		key := serviceKey{dest: addr, service: d.service(addr, req.DestPort)}
+		load, ok := services[key]
+		if !ok {
+			load = &serviceLoad{
+				ports:   make(map[int]int),
+				sources: make(map[IPKey]int),
+			}
+			services[key] = load
+		}
+		load.total += w
+		load.ports[req.DestPort] += w
+		load.sources[ParseIPKey(req.SourceIP)] += w
+	}
+
+	var target *serviceLoad
+	var targetKey serviceKey
+	for key, load := range services {
+		if load.total < d.thresholds.ServiceFloodThreshold {
+			continue
+		}
+		if target == nil || load.total > target.total {
+			target, targetKey = load, key
+		}
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	ports := make([]int, 0, len(target.ports))
+	busiest := 0
+	for port, count := range target.ports {
+		ports = append(ports, port)
+		busiest = max(busiest, count)
+	}
+	sort.Ints(ports)
+
+	description := fmt.Sprintf("Service flood on %s port %d: %d requests from %d IPs", targetKey.dest, ports[0], target.total, len(target.sources))
+	if len(ports) > 1 {
+		description = fmt.Sprintf("Service flood on %s service %q: %d requests across ports %s (max %.0f%% per port) from %d IPs",
+			targetKey.dest, targetKey.service, target.total, joinPorts(ports), 100*float64(busiest)/float64(target.total), len(target.sources))
+		if busiest < d.thresholds.ServiceFloodThreshold {
+			description += ", with no single port over the threshold"
+		}
+	}
+
+	confidence := math.Min(float64(target.total)/float64(d.thresholds.ServiceFloodThreshold*2), 1.0)
+
+	ev := withSources(evidence(
+		atLeast("service_requests", float64(target.total), "ServiceFloodThreshold", float64(d.thresholds.ServiceFloodThreshold)),
+	), target.sources)
+	ev.Features["busiest_port_requests"] = float64(busiest)
+	ev.Features["ports"] = float64(len(ports))
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "SERVICE_FLOOD",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(target.sources, 20),
+		SourcePrefixes: d.aggregateSources(target.sources),
+		SourceASNs:     d.aggregateASNs(target.sources),
+		TargetIPs:      []string{targetKey.dest.String()},
+		TargetService:  targetKey.service,
+		TargetPorts:    ports,
+		Description:    description,
+		Mitigated:      false,
+		Evidence:       ev,
+	}
+}
+
+// joinPorts lists ports as "80, 443 and 8443"
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return joinAnd(parts)
+}
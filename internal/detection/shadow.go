@@ -0,0 +1,153 @@
+package detection
+
+import (
+	"sort"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Shadow mode runs candidate thresholds next to the production ones. Each
+// cycle, every shadowed environment is analyzed a second time by a copy of
+// its detector that has the same learned baseline but the shadow
+// thresholds; what the copy detects is reported separately and the copy is
+// thrown away, so shadow analysis never changes what production learns.
+
+// ConfigureShadow replaces the shadow thresholds with a thresholds file's:
+// its defaults shadow every environment, and its environments get their own
+func (p *Pool) ConfigureShadow(config *ThresholdsConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	defaults := config.Defaults
+	p.shadowDefaults = &defaults
+	p.shadow = make(map[string]Thresholds, len(config.Environments))
+	for env, thresholds := range config.Environments {
+		p.shadow[env] = thresholds
+	}
+}
+
+// SetShadowThresholds shadows an environment with the given thresholds
+func (p *Pool) SetShadowThresholds(env string, thresholds Thresholds) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.shadow[normalizeEnvironment(env)] = thresholds
+}
+
+// ClearShadowThresholds drops an environment's own shadow thresholds; it
+// stays shadowed by the shadow defaults if there are any. An empty
+// environment turns shadow mode off everywhere.
+func (p *Pool) ClearShadowThresholds(env string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if env == "" {
+		p.shadowDefaults = nil
+		p.shadow = make(map[string]Thresholds)
+		return
+	}
+	delete(p.shadow, env)
+}
+
+// ShadowThresholds returns the thresholds an environment is shadowed with,
+// and whether it is shadowed at all
+func (p *Pool) ShadowThresholds(env string) (Thresholds, bool) {
+	env = normalizeEnvironment(env)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.shadowFor(env)
+}
+
+// ShadowEnvironments returns the environments with shadow thresholds of
+// their own, sorted by name, and whether shadow defaults cover the rest
+func (p *Pool) ShadowEnvironments() ([]string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	envs := make([]string, 0, len(p.shadow))
+	for env := range p.shadow {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs, p.shadowDefaults != nil
+}
+
+// shadowFor returns an environment's shadow thresholds; the caller holds p.mu
+func (p *Pool) shadowFor(env string) (Thresholds, bool) {
+	if thresholds, ok := p.shadow[env]; ok {
+		return thresholds, true
+	}
+	if p.shadowDefaults != nil {
+		return *p.shadowDefaults, true
+	}
+	return Thresholds{}, false
+}
+
+// shadowDetector returns a throwaway copy of an environment's detector
+// that analyzes with its shadow thresholds, or nil if it isn't shadowed
+func (p *Pool) shadowDetector(env string) *Detector {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	thresholds, ok := p.shadowFor(env)
+	if !ok {
+		return nil
+	}
+
+	// An environment without a detector yet starts from the same fresh
+	// baseline production is about to
+	detector := p.newDetector(env, thresholds)
+	if production, ok := p.detectors[env]; ok {
+		detector.Restore(production.Snapshot())
+		detector.thresholds = &thresholds
+	}
+	return detector
+}
+
+// ShadowTraffic analyzes traffic with the shadow thresholds, as
+// AnalyzeTraffic does with the production ones. Run it before AnalyzeTraffic
+// so both see the baselines as they were before the cycle.
+func (p *Pool) ShadowTraffic(requests []models.TrafficRequest) []models.Attack {
+	return p.ShadowSample(requests, 1)
+}
+
+// ShadowSample is ShadowTraffic for a sample, as AnalyzeSample
+func (p *Pool) ShadowSample(requests []models.TrafficRequest, weight int) []models.Attack {
+	attacks := make([]models.Attack, 0)
+	mode := p.Mode()
+
+	for env, scoped := range PartitionByEnvironment(p.untrusted(requests)) {
+		detector := p.shadowDetector(env)
+		if detector == nil {
+			continue
+		}
+		for _, attack := range detector.AnalyzeSampleMode(scoped, weight, mode) {
+			attack.Environment = env
+			attacks = append(attacks, attack)
+		}
+	}
+
+	return attacks
+}
+
+// ShadowSnapshot is ShadowTraffic over a window snapshot; pass the snapshot
+// AnalyzeSnapshot analyzes afterwards
+func (p *Pool) ShadowSnapshot(snapshot map[string][]WindowSlice) []models.Attack {
+	attacks := make([]models.Attack, 0)
+	mode := p.Mode()
+
+	for env, slices := range snapshot {
+		detector := p.shadowDetector(env)
+		if detector == nil {
+			continue
+		}
+		for _, attack := range detector.AnalyzeWindowMode(slices, mode) {
+			attack.Environment = env
+			attacks = append(attacks, attack)
+		}
+	}
+
+	return attacks
+}
@@ -0,0 +1,90 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// slowTransfer selects which direction of a connection is being starved
+type slowTransfer struct {
+	attackType string
+	label      string
+	what       string
+	rate       func(models.TrafficRequest) float64
+}
+
+var (
+	// slowPOST (RUDY) trickles the request body in to hold a worker
+	slowPOST = slowTransfer{
+		attackType: "SLOW_POST",
+		label:      "Slow POST (RUDY)",
+		what:       "request bodies",
+		rate:       func(req models.TrafficRequest) float64 { return req.RequestBodyRate },
+	}
+	// slowRead advertises a tiny receive window so responses drain slowly
+	slowRead = slowTransfer{
+		attackType: "SLOW_READ",
+		label:      "Slow read",
+		what:       "responses",
+		rate:       func(req models.TrafficRequest) float64 { return req.ResponseReadRate },
+	}
+)
+
+// detectSlowTransfer detects low-and-slow attacks: many long-lived HTTP
+// connections per IP whose body transfer rate is barely above zero
+func (d *Detector) detectSlowTransfer(requests []models.TrafficRequest, kind slowTransfer) *models.Attack {
+	w := d.sampleWeight()
+	slowCount := 0
+	slowIPs := make(map[IPKey]int)
+	totalRate := 0.0
+
+	for _, req := range requests {
+		rate := kind.rate(req)
+		if rate <= 0 || rate > d.thresholds.SlowTransferMaxRate {
+			continue
+		}
+		if req.Protocol != "HTTP" || req.Duration < d.thresholds.SlowTransferMinDuration {
+			continue
+		}
+		slowCount += w
+		slowIPs[ParseIPKey(req.SourceIP)] += w
+		totalRate += rate * float64(w)
+	}
+
+	if slowCount < d.thresholds.SlowTransferThreshold {
+		return nil
+	}
+
+	// Real slow clients hold one or two connections; attackers hold many each
+	slowSources := estimateSources(slowIPs, w)
+	perIP := float64(slowCount) / float64(slowSources)
+	if perIP < float64(d.thresholds.SlowTransferPerIP) {
+		return nil
+	}
+
+	confidence := math.Min(float64(slowCount)/float64(d.thresholds.SlowTransferThreshold*4), 1.0)
+
+	ev := withSources(evidence(
+		atLeast("slow_connections", float64(slowCount), "SlowTransferThreshold", float64(d.thresholds.SlowTransferThreshold)),
+		atLeast("connections_per_ip", perIP, "SlowTransferPerIP", float64(d.thresholds.SlowTransferPerIP)),
+	), slowIPs)
+	ev.Features["avg_rate_bytes_per_sec"] = totalRate / float64(slowCount)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           kind.attackType,
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(slowIPs, 20),
+		SourcePrefixes: d.aggregateSources(slowIPs),
+		SourceASNs:     d.aggregateASNs(slowIPs),
+		Description:    fmt.Sprintf("%s detected: %d connections transferring %s at avg %.0f B/s from %d IPs (%.1f per IP)", kind.label, slowCount, kind.what, totalRate/float64(slowCount), slowSources, perIP),
+		Mitigated:      false,
+		Evidence:       ev,
+	}
+}
@@ -0,0 +1,168 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/bogon"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// portBuckets splits the source port space into 1024-port buckets. Real
+// stacks pick ports from an ephemeral range covering at most half of them;
+// flood tools forging packets draw from all of them.
+const portBuckets = 64
+
+// spoofableTraffic selects, per attack type, the packets whose sources could
+// be forged. HTTP needs a completed handshake and reflection attacks come from
+// real reflectors, so neither is assessed.
+var spoofableTraffic = map[string]func(models.TrafficRequest) bool{
+	"SYN_FLOOD": isSYN,
+	"UDP_FLOOD": func(req models.TrafficRequest) bool {
+		return req.Protocol == "UDP" && req.SourcePort != 53
+	},
+	"ACK_FLOOD": isBareACK,
+	"RST_FLOOD": isRST,
+	"FIN_FLOOD": isFIN,
+	"INVALID_TCP_FLAGS": func(req models.TrafficRequest) bool {
+		return invalidSegment(req) != ""
+	},
+	"CARPET_BOMBING": func(req models.TrafficRequest) bool {
+		return req.Protocol != "HTTP"
+	},
+}
+
+// tagSpoofedSources marks L3/L4 floods whose sources look forged, and names
+// their victims so they can be blackholed upstream
+func (d *Detector) tagSpoofedSources(requests []models.TrafficRequest, attacks []models.Attack) {
+	for i := range attacks {
+		match, ok := spoofableTraffic[attacks[i].Type]
+		if !ok || attacks[i].Spoofing != nil {
+			continue
+		}
+
+		evidence, victims := d.assessSpoofing(requests, match)
+		if evidence == nil {
+			continue
+		}
+		attacks[i].Spoofing = evidence
+		if len(attacks[i].TargetIPs) == 0 {
+			attacks[i].TargetIPs = victims
+		}
+		attacks[i].Description += "; sources likely spoofed"
+	}
+}
+
+// assessSpoofing applies the spoofing heuristics to the matching packets,
+// returning nil evidence unless at least one fires, and the top destinations
+func (d *Detector) assessSpoofing(requests []models.TrafficRequest, match func(models.TrafficRequest) bool) (*models.SpoofingEvidence, []string) {
+	w := d.sampleWeight()
+	packets, lowPorts := 0, 0
+	buckets := make(map[int]int)
+	sources := make(map[IPKey]int)
+	victims := make(map[IPKey]int)
+	ttls := make(map[IPKey]map[int]int)
+	bogons := make(map[string]int)
+
+	for _, req := range requests {
+		if !match(req) {
+			continue
+		}
+		packets += w
+		buckets[req.SourcePort*portBuckets/65536] += w
+		if req.SourcePort < 1024 {
+			lowPorts += w
+		}
+
+		source := ParseIPKey(req.SourceIP)
+		sources[source] += w
+		victims[ParseIPKey(req.DestIP)] += w
+		if class := sourceBogon(req, source); d.isBogon(class) {
+			bogons[class] += w
+		}
+
+		if req.TTL > 0 {
+			if ttls[source] == nil {
+				ttls[source] = make(map[int]int)
+			}
+			ttls[source][req.TTL]++
+		}
+	}
+
+	if packets < d.thresholds.SpoofMinPackets {
+		return nil, nil
+	}
+
+	evidence := &models.SpoofingEvidence{
+		PortEntropy:  calculateEntropy(buckets) / math.Log2(portBuckets),
+		LowPortShare: float64(lowPorts) / float64(packets),
+	}
+
+	// Uniform ports: spread over the whole port space, privileged ports included
+	if evidence.PortEntropy >= d.thresholds.SpoofPortEntropyMin && evidence.LowPortShare >= d.thresholds.SpoofLowPortShareMin {
+		evidence.Indicators = append(evidence.Indicators, fmt.Sprintf("source ports uniformly random (entropy %.2f, %.1f%% below 1024)", evidence.PortEntropy, evidence.LowPortShare*100))
+	}
+
+	// TTL: a real host sits a fixed number of hops away, so its packets
+	// arrive with one or two TTLs; forged packets carry whatever the tool picks
+	repeat, varying := 0, 0
+	for _, seen := range ttls {
+		total := 0
+		for _, count := range seen {
+			total += count
+		}
+		if total < d.thresholds.SpoofTTLDistinctMin {
+			continue
+		}
+		repeat++
+		if len(seen) >= d.thresholds.SpoofTTLDistinctMin {
+			varying++
+		}
+	}
+	if repeat >= 10 {
+		evidence.TTLVaryingShare = float64(varying) / float64(repeat)
+		if evidence.TTLVaryingShare >= d.thresholds.SpoofTTLVaryingShareMin {
+			evidence.Indicators = append(evidence.Indicators, fmt.Sprintf("TTL varies per source (%d of %d repeat sources)", varying, repeat))
+		}
+	}
+
+	bogonPackets := 0
+	for _, count := range bogons {
+		bogonPackets += count
+	}
+	evidence.BogonShare = float64(bogonPackets) / float64(packets)
+	if len(bogons) > 0 {
+		evidence.BogonRanges = bogons
+	}
+	if evidence.BogonShare >= d.thresholds.SpoofBogonShareMin {
+		ranges := make([]string, 0, len(bogons))
+		for class := range bogons {
+			ranges = append(ranges, class)
+		}
+		sort.Strings(ranges)
+		evidence.Indicators = append(evidence.Indicators, fmt.Sprintf("%.1f%% of packets from bogon ranges (%s)", evidence.BogonShare*100, strings.Join(ranges, ", ")))
+	}
+
+	if len(evidence.Indicators) == 0 {
+		return nil, nil
+	}
+	return evidence, getTopIPs(victims, 5)
+}
+
+// sourceBogon returns the bogon range class of a request's source: as
+// tagged on ingest, or classified here for requests that weren't
+func sourceBogon(req models.TrafficRequest, source IPKey) string {
+	if req.SourceBogon != "" || source.IsZero() {
+		return req.SourceBogon
+	}
+	return bogon.Classify(source.Addr())
+}
+
+// isBogon reports whether a source in a bogon range class can't
+// legitimately send here. Internal ranges can, unless
+// Thresholds.SpoofPrivateIsBogon says the links face the internet.
+func (d *Detector) isBogon(class string) bool {
+	return class != "" && (d.thresholds.SpoofPrivateIsBogon || !bogon.Internal(class))
+}
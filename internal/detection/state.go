@@ -0,0 +1,210 @@
+package detection
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// DetectorState is the replicable state of a single detector
+type DetectorState struct {
+	Baseline     Baseline     `json:"baseline"`
+	Thresholds   Thresholds   `json:"thresholds"`
+	ChangePoints ChangePoints `json:"change_points"`
+	Forecast     HoltWinters  `json:"forecast"`
+}
+
+// PoolState is a point-in-time copy of every environment's detector, written
+// to shared storage so a standby can take over without relearning baselines
+type PoolState struct {
+	SavedAt      time.Time                `json:"saved_at"`
+	SavedBy      string                   `json:"saved_by"`
+	Environments map[string]DetectorState `json:"environments"`
+	// Attacks being tracked, so the next leader (or this node after a
+	// restart) continues them under the same IDs
+	Campaigns []CampaignState `json:"campaigns,omitempty"`
+	// Attack fingerprints alerted recently, so the next leader doesn't
+	// alert them again
+	Alerts []AlertState `json:"alerts,omitempty"`
+	// Shadow mode thresholds, so the next leader keeps evaluating them
+	ShadowDefaults *Thresholds           `json:"shadow_defaults,omitempty"`
+	Shadow         map[string]Thresholds `json:"shadow,omitempty"`
+}
+
+// CampaignState is the replicable state of an attack a PulseCorrelator is
+// tracking, including its burst counters
+type CampaignState struct {
+	Attack      models.Attack `json:"attack"`
+	FirstBurst  time.Time     `json:"first_burst"`
+	BurstStart  time.Time     `json:"burst_start"`
+	LastSeen    time.Time     `json:"last_seen"`
+	Bursts      int           `json:"bursts"`
+	Completed   int           `json:"completed"`
+	BurstLength time.Duration `json:"burst_length"`
+	Ended       bool          `json:"ended"`
+}
+
+// UnmarshalJSON starts from the default thresholds, so detectors added
+// after a snapshot was written get sane values instead of zeros
+func (t *Thresholds) UnmarshalJSON(data []byte) error {
+	type plain Thresholds
+	thresholds := plain(DefaultThresholds())
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		return err
+	}
+	*t = Thresholds(thresholds)
+	return nil
+}
+
+// UnmarshalJSON starts from the default baseline for the same reason
+func (b *Baseline) UnmarshalJSON(data []byte) error {
+	type plain Baseline
+	baseline := plain(DefaultBaseline())
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return err
+	}
+	*b = Baseline(baseline)
+	return nil
+}
+
+// Snapshot copies the detector's learned state
+func (d *Detector) Snapshot() DetectorState {
+	return DetectorState{
+		Baseline:     *d.baseline,
+		Thresholds:   *d.thresholds,
+		ChangePoints: d.changePoints,
+		Forecast:     d.forecast.clone(),
+	}
+}
+
+// Restore replaces the detector's state with a previously taken snapshot
+func (d *Detector) Restore(state DetectorState) {
+	baseline := state.Baseline
+	thresholds := state.Thresholds
+	d.baseline = &baseline
+	d.thresholds = &thresholds
+	d.changePoints = state.ChangePoints
+	d.forecast = state.Forecast.clone()
+}
+
+// Snapshot copies the state of every environment's detector
+func (p *Pool) Snapshot() PoolState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := PoolState{
+		SavedAt:      time.Now(),
+		Environments: make(map[string]DetectorState, len(p.detectors)),
+	}
+	for env, detector := range p.detectors {
+		state.Environments[env] = detector.Snapshot()
+	}
+	if p.shadowDefaults != nil {
+		defaults := *p.shadowDefaults
+		state.ShadowDefaults = &defaults
+	}
+	if len(p.shadow) > 0 {
+		state.Shadow = make(map[string]Thresholds, len(p.shadow))
+		for env, thresholds := range p.shadow {
+			state.Shadow[env] = thresholds
+		}
+	}
+
+	return state
+}
+
+// Restore rebuilds the pool's detectors from a snapshot. Environments not in
+// the snapshot keep their current detector.
+func (p *Pool) Restore(state PoolState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for env, detectorState := range state.Environments {
+		detector := p.newDetector(env, detectorState.Thresholds)
+		detector.Restore(detectorState)
+		p.detectors[env] = detector
+		p.thresholds[env] = detectorState.Thresholds
+	}
+
+	p.shadowDefaults = state.ShadowDefaults
+	p.shadow = make(map[string]Thresholds, len(state.Shadow))
+	for env, thresholds := range state.Shadow {
+		p.shadow[env] = thresholds
+	}
+}
+
+// Snapshot copies the attacks the correlator is tracking
+func (p *PulseCorrelator) Snapshot() []CampaignState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	campaigns := make([]CampaignState, 0, len(p.campaigns))
+	for _, campaign := range p.campaigns {
+		campaigns = append(campaigns, CampaignState{
+			Attack:      campaign.attack,
+			FirstBurst:  campaign.firstBurst,
+			BurstStart:  campaign.burstStart,
+			LastSeen:    campaign.lastSeen,
+			Bursts:      campaign.bursts,
+			Completed:   campaign.completed,
+			BurstLength: campaign.burstLength,
+			Ended:       campaign.ended,
+		})
+	}
+	return campaigns
+}
+
+// Restore replaces the tracked attacks with a snapshot taken before a
+// restart or leader change. Nobody was detecting while the snapshot sat in
+// storage, so the gap up to now counts as neither a silence between bursts
+// nor the attack ending.
+func (p *PulseCorrelator) Restore(campaigns []CampaignState, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.campaigns = make(map[string]*pulseCampaign, len(campaigns))
+	for _, state := range campaigns {
+		p.campaigns[state.Attack.ID] = &pulseCampaign{
+			attack:      state.Attack,
+			firstBurst:  state.FirstBurst,
+			burstStart:  state.BurstStart,
+			lastSeen:    state.LastSeen,
+			bursts:      state.Bursts,
+			completed:   state.Completed,
+			burstLength: state.BurstLength,
+			ended:       state.Ended,
+			resumedAt:   now,
+		}
+	}
+}
+
+// LoadState is the replicable state of a LoadGovernor: its mode and the
+// calm cycles counted so far towards leaving approximate mode
+type LoadState struct {
+	Mode       Mode      `json:"mode"`
+	Since      time.Time `json:"since"`
+	CalmCycles int       `json:"calm_cycles"`
+}
+
+// Snapshot copies the governor's mode and recovery progress
+func (g *LoadGovernor) Snapshot() LoadState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return LoadState{Mode: g.mode, Since: g.since, CalmCycles: g.calmCycles}
+}
+
+// Restore resumes the mode of the previous leader, so a takeover in the
+// middle of a flood stays approximate instead of starting a full cycle
+func (g *LoadGovernor) Restore(state LoadState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if state.Mode != ModeFull && state.Mode != ModeApproximate {
+		return
+	}
+	g.mode = state.Mode
+	g.since = state.Since
+	g.calmCycles = state.CalmCycles
+}
@@ -0,0 +1,384 @@
+package detection
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// ringSeconds is the number of one-second buckets in a StreamWindow
+	ringSeconds = int(LongestWindow / time.Second)
+	// DefaultStreamRetain is how many requests a StreamWindow keeps per
+	// environment per second for the detectors that inspect single requests
+	DefaultStreamRetain = 2000
+	// sliceRetainSeconds caps a slice's sample at this many seconds of
+	// retained requests; samples of longer windows are thinned to it
+	sliceRetainSeconds = int(AnalysisWindow / time.Second)
+)
+
+// windowCounts are the counters behind TrafficMetrics. Counts are weighted,
+// so a sample counts as the traffic it stands for.
+type windowCounts struct {
+	requests int
+	bytes    int64
+	duration int64
+	syn      int
+	tools    int
+	// weight is the most any counted request stood for, 1 unless sampled
+	weight     int
+	ips        map[IPKey]int
+	paths      map[string]int
+	protocols  map[string]int
+	userAgents map[string]int
+	methods    map[string]int
+	bogons     map[string]int
+	countries  map[string]int
+	asns       map[uint32]int
+}
+
+func newWindowCounts() *windowCounts {
+	return &windowCounts{
+		ips:        make(map[IPKey]int),
+		paths:      make(map[string]int),
+		protocols:  make(map[string]int),
+		userAgents: make(map[string]int),
+		methods:    make(map[string]int),
+		bogons:     make(map[string]int),
+		countries:  make(map[string]int),
+		asns:       make(map[uint32]int),
+	}
+}
+
+// add counts one request standing for w requests
+func (c *windowCounts) add(req models.TrafficRequest, w int) {
+	c.requests += w
+	c.weight = max(c.weight, w)
+	c.bytes += int64(req.BytesSent+req.BytesRecv) * int64(w)
+	c.duration += int64(req.Duration) * int64(w)
+	c.ips[ParseIPKey(req.SourceIP)] += w
+	c.paths[req.RequestPath] += w
+	c.protocols[req.Protocol] += w
+	if req.Method != "" {
+		c.methods[strings.ToUpper(req.Method)] += w
+	}
+	if req.SourceBogon != "" {
+		c.bogons[req.SourceBogon] += w
+	}
+	if req.Country != "" {
+		c.countries[req.Country] += w
+	}
+	if req.ASN != 0 {
+		c.asns[req.ASN] += w
+	}
+
+	if isSYN(req) {
+		c.syn += w
+	}
+	if req.Protocol == "HTTP" {
+		c.userAgents[req.UserAgent] += w
+		if toolSignature(req.UserAgent) != "" {
+			c.tools += w
+		}
+	}
+}
+
+// merge adds (sign 1) or removes (sign -1) other's counts
+func (c *windowCounts) merge(other *windowCounts, sign int) {
+	c.requests += sign * other.requests
+	c.bytes += int64(sign) * other.bytes
+	c.duration += int64(sign) * other.duration
+	c.syn += sign * other.syn
+	c.tools += sign * other.tools
+	c.weight = max(c.weight, other.weight)
+	mergeCounts(c.ips, other.ips, sign)
+	mergeCounts(c.paths, other.paths, sign)
+	mergeCounts(c.protocols, other.protocols, sign)
+	mergeCounts(c.userAgents, other.userAgents, sign)
+	mergeCounts(c.methods, other.methods, sign)
+	mergeCounts(c.bogons, other.bogons, sign)
+	mergeCounts(c.countries, other.countries, sign)
+	mergeCounts(c.asns, other.asns, sign)
+}
+
+func mergeCounts[K comparable](into, from map[K]int, sign int) {
+	for key, count := range from {
+		if into[key] += sign * count; into[key] <= 0 {
+			delete(into, key)
+		}
+	}
+}
+
+// metrics derives window metrics from the counters
+func (c *windowCounts) metrics() *TrafficMetrics {
+	metrics := &TrafficMetrics{
+		TotalRequests:    c.requests,
+		UniqueIPs:        estimateSources(c.ips, c.weight),
+		IPCounts:         c.ips,
+		ProtocolCounts:   c.protocols,
+		PathCounts:       c.paths,
+		IPEntropy:        calculateEntropy(c.ips),
+		PathEntropy:      calculateEntropy(c.paths),
+		SYNPacketCount:   c.syn,
+		UserAgentCounts:  c.userAgents,
+		UserAgentEntropy: calculateEntropy(c.userAgents),
+		TopUserAgents:    topUserAgents(c.userAgents, 5),
+		MethodCounts:     c.methods,
+		BogonCounts:      c.bogons,
+		ToolRequests:     c.tools,
+		SourceCounts:     sourcePercentiles(c.ips),
+		CountryCounts:    c.countries,
+		ASNCounts:        c.asns,
+	}
+	if c.requests > 0 {
+		metrics.AvgConnDuration = float64(c.duration) / float64(c.requests)
+		metrics.RequestsPerIP = float64(c.requests) / float64(metrics.UniqueIPs)
+	}
+	return metrics
+}
+
+// clone copies the counters so they can be read without holding the window's lock
+func (c *windowCounts) clone() *windowCounts {
+	copied := newWindowCounts()
+	copied.merge(c, 1)
+	return copied
+}
+
+// streamBucket is one environment's traffic in one second
+type streamBucket struct {
+	counts *windowCounts
+	seen   int
+	sample []models.TrafficRequest
+}
+
+// WindowSlice is one environment's share of a StreamWindow over one
+// resolution
+type WindowSlice struct {
+	Resolution Resolution
+	// Requests is a uniform sample of the window's traffic; each stands
+	// for Weight requests
+	Requests []models.TrafficRequest
+	Weight   int
+	counts   *windowCounts
+}
+
+// StreamWindow aggregates the last LongestWindow of traffic as it is
+// ingested, so analysis reads it from memory instead of fetching and
+// decoding every request from Redis. Rolling per-environment counters (per
+// source IP, path, protocol and User-Agent) are kept for every resolution
+// and updated per request and per one-second bucket sliding out of the
+// resolution's window, which keeps window metrics exact at any volume.
+// Detectors that inspect single requests get a uniform sample of up to
+// retain requests per environment and second.
+type StreamWindow struct {
+	mu      sync.Mutex
+	retain  int
+	rng     *rand.Rand
+	seconds [ringSeconds]int64
+	buckets [ringSeconds]map[string]*streamBucket
+	// Per resolution, the counters of the buckets after its edge second
+	totals []map[string]*windowCounts
+	edges  []int64
+}
+
+// NewStreamWindow creates an empty window keeping up to retain requests per
+// environment and second; retain below 1 means DefaultStreamRetain
+func NewStreamWindow(retain int) *StreamWindow {
+	if retain < 1 {
+		retain = DefaultStreamRetain
+	}
+	w := &StreamWindow{
+		retain: retain,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		totals: make([]map[string]*windowCounts, len(Resolutions)),
+		edges:  make([]int64, len(Resolutions)),
+	}
+	now := time.Now()
+	for i, res := range Resolutions {
+		w.totals[i] = make(map[string]*windowCounts)
+		w.edges[i] = now.Add(-res.Window).Unix()
+	}
+	return w
+}
+
+// Add counts a request into the window at its timestamp. Requests older
+// than the window are dropped and future timestamps count as now.
+func (w *StreamWindow) Add(req models.TrafficRequest) {
+	now := time.Now()
+	second := req.Timestamp.Unix()
+	if second > now.Unix() {
+		second = now.Unix()
+	}
+	if second <= now.Add(-LongestWindow).Unix() {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	slot := int(second % int64(ringSeconds))
+	if w.seconds[slot] != second {
+		if w.seconds[slot] > second {
+			return
+		}
+		w.expire(slot)
+		w.seconds[slot] = second
+	}
+
+	env := normalizeEnvironment(req.Environment)
+	bucket, ok := w.buckets[slot][env]
+	if !ok {
+		if w.buckets[slot] == nil {
+			w.buckets[slot] = make(map[string]*streamBucket)
+		}
+		bucket = &streamBucket{counts: newWindowCounts()}
+		w.buckets[slot][env] = bucket
+	}
+
+	bucket.counts.add(req, 1)
+	for i, totals := range w.totals {
+		if second <= w.edges[i] {
+			continue
+		}
+		total, ok := totals[env]
+		if !ok {
+			total = newWindowCounts()
+			totals[env] = total
+		}
+		total.add(req, 1)
+	}
+
+	// Reservoir sampling keeps the retained requests a uniform sample of
+	// the second however many arrive
+	bucket.seen++
+	if len(bucket.sample) < w.retain {
+		bucket.sample = append(bucket.sample, req)
+	} else if i := w.rng.Intn(bucket.seen); i < w.retain {
+		bucket.sample[i] = req
+	}
+}
+
+// expire removes a bucket's counts from the rolling totals still holding
+// them and empties it
+func (w *StreamWindow) expire(slot int) {
+	for i := range w.totals {
+		if w.seconds[slot] > w.edges[i] {
+			w.subtract(i, slot)
+		}
+	}
+	w.buckets[slot] = nil
+	w.seconds[slot] = 0
+}
+
+// subtract removes a bucket's counts from one resolution's totals
+func (w *StreamWindow) subtract(i, slot int) {
+	for env, bucket := range w.buckets[slot] {
+		if total, ok := w.totals[i][env]; ok {
+			total.merge(bucket.counts, -1)
+			if total.requests <= 0 {
+				delete(w.totals[i], env)
+			}
+		}
+	}
+}
+
+// advance moves every resolution's edge up to now, subtracting the buckets
+// that slid out of its window, and expires the buckets that left them all.
+// Each step is one pass over the ring however long analysis was idle.
+func (w *StreamWindow) advance(now time.Time) {
+	for i, res := range Resolutions {
+		edge := now.Add(-res.Window).Unix()
+		if edge <= w.edges[i] {
+			continue
+		}
+		for slot, second := range w.seconds {
+			if second > w.edges[i] && second <= edge {
+				w.subtract(i, slot)
+			}
+		}
+		w.edges[i] = edge
+	}
+
+	oldest := now.Add(-LongestWindow).Unix()
+	for slot, second := range w.seconds {
+		if second != 0 && second <= oldest {
+			w.expire(slot)
+		}
+	}
+}
+
+// Snapshot returns each environment's share of the window as of now, one
+// slice per resolution in the order of Resolutions
+func (w *StreamWindow) Snapshot(now time.Time) map[string][]WindowSlice {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.advance(now)
+
+	slices := make(map[string][]WindowSlice)
+	for i, res := range Resolutions {
+		samples := make(map[string][]models.TrafficRequest, len(w.totals[i]))
+		seen := make(map[string]int, len(w.totals[i]))
+		for slot, second := range w.seconds {
+			if second <= w.edges[i] {
+				continue
+			}
+			for env, bucket := range w.buckets[slot] {
+				samples[env] = append(samples[env], bucket.sample...)
+				seen[env] += bucket.seen
+			}
+		}
+
+		for env, total := range w.totals[i] {
+			requests, _ := sampleRequests(samples[env], w.retain*sliceRetainSeconds)
+			slice := WindowSlice{Resolution: res, Requests: requests, Weight: 1, counts: total.clone()}
+			if len(requests) > 0 && seen[env] > len(requests) {
+				slice.Weight = (seen[env] + len(requests) - 1) / len(requests)
+			}
+			slices[env] = append(slices[env], slice)
+		}
+	}
+	return slices
+}
+
+// TrafficSince sums each environment's requests and bytes received since
+// the given time, at one-second resolution
+func (w *StreamWindow) TrafficSince(since time.Time) map[string]models.AttackTraffic {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	traffic := make(map[string]models.AttackTraffic)
+	for slot, second := range w.seconds {
+		if second == 0 || second < since.Unix() {
+			continue
+		}
+		for env, bucket := range w.buckets[slot] {
+			sum := traffic[env]
+			sum.Requests += int64(bucket.counts.requests)
+			sum.Bytes += bucket.counts.bytes
+			traffic[env] = sum
+		}
+	}
+	return traffic
+}
+
+// Requests counts the requests in the last AnalysisWindow across environments
+func (w *StreamWindow) Requests() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.advance(time.Now())
+	total := 0
+	for i, res := range Resolutions {
+		if res.Window != AnalysisWindow {
+			continue
+		}
+		for _, counts := range w.totals[i] {
+			total += counts.requests
+		}
+	}
+	return total
+}
@@ -0,0 +1,182 @@
+package detection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/goccy/go-yaml"
+)
+
+// ThresholdsConfig is a thresholds file: overrides of the stock thresholds
+// for every environment, and further overrides per environment. Fields are
+// named as in Thresholds, e.g.
+//
+//	defaults:
+//	  SYNFloodThreshold: 1500
+//	environments:
+//	  prod-eu:
+//	    UDPFloodThreshold: 5000
+type ThresholdsConfig struct {
+	Defaults     Thresholds
+	Environments map[string]Thresholds
+}
+
+// LoadThresholdsConfig reads a YAML or JSON thresholds file. Unknown fields
+// and invalid values are rejected.
+func LoadThresholdsConfig(path string) (*ThresholdsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var file struct {
+		Defaults     json.RawMessage            `json:"defaults"`
+		Environments map[string]json.RawMessage `json:"environments"`
+	}
+	if err := decodeStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	config := &ThresholdsConfig{
+		Defaults:     DefaultThresholds(),
+		Environments: make(map[string]Thresholds, len(file.Environments)),
+	}
+	if file.Defaults != nil {
+		if config.Defaults, err = config.Defaults.Override(file.Defaults); err != nil {
+			return nil, fmt.Errorf("%s: defaults: %w", path, err)
+		}
+	}
+	for env, overrides := range file.Environments {
+		thresholds, err := config.Defaults.Override(overrides)
+		if err != nil {
+			return nil, fmt.Errorf("%s: environment %s: %w", path, env, err)
+		}
+		config.Environments[normalizeEnvironment(env)] = thresholds
+	}
+	return config, nil
+}
+
+// Override returns a copy of the thresholds with the fields set in a JSON
+// object replaced, e.g. {"SYNFloodThreshold": 1500}
+func (t Thresholds) Override(data []byte) (Thresholds, error) {
+	// Decode over the current values rather than the defaults UnmarshalJSON
+	// starts from
+	type plain Thresholds
+	overridden := plain(t)
+	if err := decodeStrict(data, &overridden); err != nil {
+		return t, err
+	}
+
+	thresholds := Thresholds(overridden)
+	if err := thresholds.Validate(); err != nil {
+		return t, err
+	}
+	return thresholds, nil
+}
+
+// Validate rejects negative counts, ratios and limits, and prefix lengths
+// that don't fit their address family
+func (t Thresholds) Validate() error {
+	v := reflect.ValueOf(t)
+	var negative []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Int:
+			if field.Int() < 0 {
+				negative = append(negative, v.Type().Field(i).Name)
+			}
+		case reflect.Float64:
+			if field.Float() < 0 {
+				negative = append(negative, v.Type().Field(i).Name)
+			}
+		}
+	}
+	if len(negative) > 0 {
+		sort.Strings(negative)
+		return fmt.Errorf("thresholds must not be negative: %v", negative)
+	}
+
+	if t.CarpetBombPrefixV4 < 1 || t.CarpetBombPrefixV4 > 32 {
+		return fmt.Errorf("CarpetBombPrefixV4 must be 1-32, not %d", t.CarpetBombPrefixV4)
+	}
+	if t.CarpetBombPrefixV6 < 1 || t.CarpetBombPrefixV6 > 128 {
+		return fmt.Errorf("CarpetBombPrefixV6 must be 1-128, not %d", t.CarpetBombPrefixV6)
+	}
+	return nil
+}
+
+// ThresholdChange is one threshold set differently in two sets of thresholds
+type ThresholdChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Changes lists the thresholds other sets differently from t, by field name
+func (t Thresholds) Changes(other Thresholds) map[string]ThresholdChange {
+	from, to := reflect.ValueOf(t), reflect.ValueOf(other)
+	changes := make(map[string]ThresholdChange)
+	for i := 0; i < from.NumField(); i++ {
+		a, b := from.Field(i).Interface(), to.Field(i).Interface()
+		if !reflect.DeepEqual(a, b) {
+			changes[from.Type().Field(i).Name] = ThresholdChange{From: a, To: b}
+		}
+	}
+	return changes
+}
+
+// decodeStrict decodes JSON, rejecting fields the target doesn't have
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// Configure applies a thresholds file: its defaults to every environment
+// it doesn't list, and its per-environment thresholds to those it does
+func (p *Pool) Configure(config *ThresholdsConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	defaults := config.Defaults
+	p.defaults = &defaults
+	for env, detector := range p.detectors {
+		if _, ok := config.Environments[env]; !ok {
+			delete(p.thresholds, env)
+			thresholds := defaults
+			detector.thresholds = &thresholds
+		}
+	}
+	for env, thresholds := range config.Environments {
+		p.setThresholds(env, thresholds)
+	}
+}
+
+// Thresholds returns the thresholds an environment is analyzed with
+func (p *Pool) Thresholds(env string) Thresholds {
+	env = normalizeEnvironment(env)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.thresholdsFor(env)
+}
+
+// thresholdsFor returns an environment's thresholds; the caller holds p.mu
+func (p *Pool) thresholdsFor(env string) Thresholds {
+	if thresholds, ok := p.thresholds[env]; ok {
+		return thresholds
+	}
+	if p.defaults != nil {
+		return *p.defaults
+	}
+	return DefaultThresholds()
+}
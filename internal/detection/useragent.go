@@ -0,0 +1,152 @@
+package detection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// UserAgentCount is a User-Agent and how many HTTP requests carried it
+type UserAgentCount struct {
+	UserAgent string `json:"user_agent"`
+	Count     int    `json:"count"`
+	Tool      string `json:"tool,omitempty"` // known tool or library, if recognized
+}
+
+// toolSignatures maps User-Agent prefixes (lowercased) of HTTP tools and
+// libraries to a display name. Browsers never send these.
+var toolSignatures = []struct {
+	prefix string
+	name   string
+}{
+	{"curl/", "curl"},
+	{"wget/", "wget"},
+	{"python-requests/", "python-requests"},
+	{"python-urllib/", "python-urllib"},
+	{"python-httpx/", "httpx"},
+	{"aiohttp/", "aiohttp"},
+	{"scrapy/", "Scrapy"},
+	{"go-http-client/", "Go-http-client"},
+	{"okhttp/", "okhttp"},
+	{"java/", "Java"},
+	{"apache-httpclient/", "Apache-HttpClient"},
+	{"libwww-perl/", "libwww-perl"},
+	{"node-fetch/", "node-fetch"},
+	{"axios/", "axios"},
+	{"postmanruntime/", "PostmanRuntime"},
+	{"masscan/", "masscan"},
+	{"zgrab/", "zgrab"},
+}
+
+// toolSignature names the tool a User-Agent belongs to, or "" for anything else
+func toolSignature(userAgent string) string {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	for _, sig := range toolSignatures {
+		if strings.HasPrefix(ua, sig.prefix) {
+			return sig.name
+		}
+	}
+	return ""
+}
+
+// isBrowser reports whether a User-Agent claims to be a browser. Every
+// mainstream browser still starts with the historical "Mozilla/" token.
+func isBrowser(userAgent string) bool {
+	return strings.HasPrefix(userAgent, "Mozilla/")
+}
+
+func (d *Detector) isAllowlistedCrawler(userAgent string) bool {
+	for _, crawler := range d.thresholds.CrawlerAllowlist {
+		if crawler != "" && strings.Contains(userAgent, crawler) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectBotFlood detects HTTP floods dominated by a single non-browser
+// User-Agent, such as a script hammering the site with python-requests.
+// Known crawlers are allowlisted: they identify as bots but are welcome.
+func (d *Detector) detectBotFlood(requests []models.TrafficRequest, metrics *TrafficMetrics) *models.Attack {
+	httpTotal := 0
+	for _, count := range metrics.UserAgentCounts {
+		httpTotal += count
+	}
+	if httpTotal < d.thresholds.BotFloodThreshold || len(metrics.TopUserAgents) == 0 {
+		return nil
+	}
+
+	top := metrics.TopUserAgents[0]
+	share := float64(top.Count) / float64(httpTotal)
+	if share < d.thresholds.BotUserAgentShareMin || isBrowser(top.UserAgent) || d.isAllowlistedCrawler(top.UserAgent) {
+		return nil
+	}
+
+	w := d.sampleWeight()
+	sources := make(map[IPKey]int)
+	for _, req := range requests {
+		if req.Protocol == "HTTP" && req.UserAgent == top.UserAgent {
+			sources[ParseIPKey(req.SourceIP)] += w
+		}
+	}
+
+	client := top.Tool
+	if client == "" {
+		client = fmt.Sprintf("non-browser client %q", top.UserAgent)
+		if top.UserAgent == "" {
+			client = "clients without a User-Agent"
+		}
+	}
+
+	volumeScore := math.Min(float64(top.Count)/float64(d.thresholds.BotFloodThreshold*4), 1.0)
+	confidence := math.Min(0.5*share+0.5*volumeScore, 1.0)
+
+	ev := withSources(evidence(
+		atLeast("http_requests", float64(httpTotal), "BotFloodThreshold", float64(d.thresholds.BotFloodThreshold)),
+		atLeast("user_agent_share", share, "BotUserAgentShareMin", d.thresholds.BotUserAgentShareMin),
+	), sources)
+	ev.Features["user_agent_entropy"] = metrics.UserAgentEntropy
+	ev.Features["tool_requests"] = float64(metrics.ToolRequests)
+
+	return &models.Attack{
+		ID:             uuid.New().String(),
+		Type:           "BOT_FLOOD",
+		Severity:       getSeverity(confidence),
+		Confidence:     confidence,
+		StartTime:      time.Now(),
+		SourceIPs:      getTopIPs(sources, 20),
+		SourcePrefixes: d.aggregateSources(sources),
+		SourceASNs:     d.aggregateASNs(sources),
+		Description:    fmt.Sprintf("Bot flood: %s sent %d of %d HTTP requests (%.0f%%) from %d IPs (User-Agent entropy: %.2f)", client, top.Count, httpTotal, share*100, len(sources), metrics.UserAgentEntropy),
+		Mitigated:      false,
+		Evidence:       ev,
+	}
+}
+
+// topUserAgents returns the n most frequent User-Agents, busiest first
+func topUserAgents(counts map[string]int, n int) []UserAgentCount {
+	agents := make([]UserAgentCount, 0, len(counts))
+	for ua, count := range counts {
+		agents = append(agents, UserAgentCount{UserAgent: ua, Count: count})
+	}
+
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].Count != agents[j].Count {
+			return agents[i].Count > agents[j].Count
+		}
+		return agents[i].UserAgent < agents[j].UserAgent
+	})
+	if len(agents) > n {
+		agents = agents[:n]
+	}
+	for i := range agents {
+		agents[i].Tool = toolSignature(agents[i].UserAgent)
+	}
+
+	return agents
+}
@@ -0,0 +1,233 @@
+// Package geoip resolves source addresses to their country and autonomous
+// system with MaxMind GeoLite2 (or GeoIP2) databases
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxCached bounds the decoded records kept per database; GeoLite2 leaves
+// share records, so a few thousand cover most traffic
+const maxCached = 1 << 16
+
+// Location is what the databases know about an address
+type Location struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	ASN     uint32 `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+}
+
+// source is one database file and the records decoded from it, keyed by
+// their offset in the data section
+type source struct {
+	path    string
+	modTime time.Time
+	db      *database
+
+	mu    sync.Mutex
+	cache map[uint]Location
+}
+
+func openSource(path string) (*source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	return &source{path: path, modTime: info.ModTime(), db: db, cache: make(map[uint]Location)}, nil
+}
+
+// lookup resolves an address with decodeRecord, caching what it decodes
+func (s *source) lookup(addr netip.Addr, decodeRecord func(map[string]interface{}) Location) Location {
+	offset, ok := s.db.lookup(addr)
+	if !ok {
+		return Location{}
+	}
+
+	s.mu.Lock()
+	loc, ok := s.cache[offset]
+	s.mu.Unlock()
+	if ok {
+		return loc
+	}
+
+	value, _, err := decode(s.db.data, offset)
+	if err != nil {
+		return Location{}
+	}
+	if record, ok := value.(map[string]interface{}); ok {
+		loc = decodeRecord(record)
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= maxCached {
+		s.cache = make(map[uint]Location)
+	}
+	s.cache[offset] = loc
+	s.mu.Unlock()
+	return loc
+}
+
+// Resolver answers lookups from a country database (GeoLite2-Country or
+// GeoLite2-City) and an ASN database (GeoLite2-ASN), either optional
+type Resolver struct {
+	countryPath string
+	asnPath     string
+
+	mu      sync.RWMutex
+	country *source
+	asn     *source
+}
+
+// Open loads the databases at the given paths; an empty path leaves that
+// part of the Location unresolved
+func Open(countryPath, asnPath string) (*Resolver, error) {
+	if countryPath == "" && asnPath == "" {
+		return nil, fmt.Errorf("no GeoIP database given")
+	}
+	r := &Resolver{countryPath: countryPath, asnPath: asnPath}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reopens the databases whose files have changed since they were
+// loaded, as geoipupdate replaces them, and reports whether any did. A file
+// that fails to open leaves the previous database in force.
+func (r *Resolver) Reload() (bool, error) {
+	r.mu.RLock()
+	country, asn := r.country, r.asn
+	r.mu.RUnlock()
+
+	country, countryChanged, err := reopen(r.countryPath, country)
+	if err != nil {
+		return false, err
+	}
+	asn, asnChanged, err := reopen(r.asnPath, asn)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.country, r.asn = country, asn
+	return countryChanged || asnChanged, nil
+}
+
+// reopen opens path unless current was loaded from the file as it is
+func reopen(path string, current *source) (*source, bool, error) {
+	if path == "" {
+		return nil, false, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return current, false, err
+	}
+	if current != nil && info.ModTime().Equal(current.modTime) {
+		return current, false, nil
+	}
+	s, err := openSource(path)
+	if err != nil {
+		return current, false, err
+	}
+	return s, true, nil
+}
+
+// Watch reloads changed databases every interval until the context is done
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				log.Printf("⚠️  Keeping previous GeoIP databases: %v", err)
+			} else if reloaded {
+				log.Printf("🌍 Reloaded GeoIP databases")
+			}
+		}
+	}
+}
+
+// Lookup resolves an address's country and autonomous system, reporting
+// whether either is known
+func (r *Resolver) Lookup(ip string) (Location, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}, false
+	}
+	return r.LookupAddr(addr)
+}
+
+// LookupAddr is Lookup for a parsed address
+func (r *Resolver) LookupAddr(addr netip.Addr) (Location, bool) {
+	r.mu.RLock()
+	country, asn := r.country, r.asn
+	r.mu.RUnlock()
+
+	var loc Location
+	if country != nil {
+		loc.Country = country.lookup(addr, countryRecord).Country
+	}
+	if asn != nil {
+		as := asn.lookup(addr, asnRecord)
+		loc.ASN, loc.ASOrg = as.ASN, as.ASOrg
+	}
+	return loc, loc.Country != "" || loc.ASN != 0
+}
+
+// Database describes a loaded database
+type Database struct {
+	Path       string    `json:"path"`
+	Type       string    `json:"type"` // e.g. GeoLite2-Country
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// Databases lists the loaded databases
+func (r *Resolver) Databases() []Database {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	databases := make([]Database, 0, 2)
+	for _, s := range []*source{r.country, r.asn} {
+		if s != nil {
+			databases = append(databases, Database{Path: s.path, Type: s.db.dbType, ModifiedAt: s.modTime})
+		}
+	}
+	return databases
+}
+
+// countryRecord reads the country of a Country or City record: where the
+// address is in use, else where its block is registered
+func countryRecord(record map[string]interface{}) Location {
+	for _, key := range []string{"country", "registered_country"} {
+		if country, ok := record[key].(map[string]interface{}); ok {
+			if code, ok := country["iso_code"].(string); ok && code != "" {
+				return Location{Country: code}
+			}
+		}
+	}
+	return Location{}
+}
+
+// asnRecord reads an ASN record
+func asnRecord(record map[string]interface{}) Location {
+	number, _ := record["autonomous_system_number"].(uint64)
+	org, _ := record["autonomous_system_organization"].(string)
+	return Location{ASN: uint32(number), ASOrg: org}
+}
@@ -0,0 +1,309 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSeparator is the run of zero bytes between the search tree and the data section
+const dataSeparator = 16
+
+// MaxMind DB data section types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// database is a MaxMind DB file (GeoLite2, GeoIP2 or compatible), read
+// whole into memory: a binary search tree over address bits whose leaves
+// point into a data section of typed records
+type database struct {
+	buf        []byte
+	data       []byte // the data section
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dbType     string
+	ipv4Start  uint // node reached after the 96 zero bits of an IPv4 address in an IPv6 tree
+}
+
+// openDatabase reads and validates a MaxMind DB file
+func openDatabase(path string) (*database, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	at := bytes.LastIndex(buf, metadataMarker)
+	if at < 0 {
+		return nil, fmt.Errorf("%s is not a MaxMind DB file", path)
+	}
+	metaSection := buf[at+len(metadataMarker):]
+	value, _, err := decode(metaSection, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading metadata: %w", path, err)
+	}
+	meta, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: metadata is not a map", path)
+	}
+
+	db := &database{buf: buf}
+	db.nodeCount = uint(metaUint(meta, "node_count"))
+	db.recordSize = uint(metaUint(meta, "record_size"))
+	db.ipVersion = uint(metaUint(meta, "ip_version"))
+	db.dbType, _ = meta["database_type"].(string)
+
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%s: unsupported record size %d", path, db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("%s: unsupported IP version %d", path, db.ipVersion)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+dataSeparator > uint(at) {
+		return nil, fmt.Errorf("%s: search tree runs past the data section", path)
+	}
+	db.data = buf[treeSize+dataSeparator : at]
+
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+func metaUint(meta map[string]interface{}, key string) uint64 {
+	v, _ := meta[key].(uint64)
+	return v
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a tree node
+func (db *database) record(node uint, bit uint) uint {
+	switch db.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		b := db.buf[off : off+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		off := node * 7
+		b := db.buf[off : off+7]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(db.buf[off : off+4]))
+	}
+}
+
+// lookup walks the tree for an address, returning the offset of its record
+// in the data section and whether it has one
+func (db *database) lookup(addr netip.Addr) (uint, bool) {
+	addr = addr.Unmap()
+	var bits []byte
+	node := uint(0)
+	switch {
+	case addr.Is4():
+		a := addr.As4()
+		bits = a[:]
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	case db.ipVersion == 4:
+		return 0, false
+	default:
+		a := addr.As16()
+		bits = a[:]
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+
+	if node <= db.nodeCount {
+		return 0, false
+	}
+	offset := node - db.nodeCount - dataSeparator
+	if offset >= uint(len(db.data)) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// decode decodes the value at offset in a data section, returning it and
+// the offset after it: maps as map[string]interface{}, arrays as
+// []interface{}, unsigned integers as uint64 and signed ones as int64
+func decode(data []byte, offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(data)) {
+		return nil, 0, fmt.Errorf("offset %d is past the data section", offset)
+	}
+	ctrl := data[offset]
+	offset++
+
+	typ := uint(ctrl >> 5)
+	if typ == typePointer {
+		pointer, next, err := decodePointer(data, ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := decode(data, pointer)
+		return value, next, err
+	}
+	if typ == typeExtended {
+		if offset >= uint(len(data)) {
+			return nil, 0, fmt.Errorf("truncated extended type")
+		}
+		typ = 7 + uint(data[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(data)) {
+			return nil, 0, fmt.Errorf("truncated size")
+		}
+		n := uint(0)
+		for _, b := range data[offset : offset+extra] {
+			n = n<<8 | uint(b)
+		}
+		offset += extra
+		switch size {
+		case 29:
+			size = 29 + n
+		case 30:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := decode(data, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, after, err := decode(data, next)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			}
+			m[name] = value
+			offset = after
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := decode(data, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeEndMarker, typeContainer:
+		return nil, offset, nil
+	}
+
+	if offset+size > uint(len(data)) {
+		return nil, 0, fmt.Errorf("value of %d bytes runs past the data section", size)
+	}
+	raw := data[offset : offset+size]
+	offset += size
+
+	switch typ {
+	case typeString:
+		return string(raw), offset, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), raw...), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of %d bytes", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of %d bytes", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), offset, nil
+	case typeUint16, typeUint32, typeUint64:
+		n := uint64(0)
+		for _, b := range raw {
+			n = n<<8 | uint64(b)
+		}
+		return n, offset, nil
+	case typeInt32:
+		n := uint32(0)
+		for _, b := range raw {
+			n = n<<8 | uint32(b)
+		}
+		return int64(int32(n)), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unknown data type %d", typ)
+}
+
+// decodePointer reads a pointer's target offset and the offset after it
+func decodePointer(data []byte, ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl>>3)&0x3 + 1
+	if offset+size > uint(len(data)) {
+		return 0, 0, fmt.Errorf("truncated pointer")
+	}
+	n := uint(0)
+	for _, b := range data[offset : offset+size] {
+		n = n<<8 | uint(b)
+	}
+	value := uint(ctrl & 0x7)
+
+	var pointer uint
+	switch size {
+	case 1:
+		pointer = value<<8 | n
+	case 2:
+		pointer = (value<<16 | n) + 2048
+	case 3:
+		pointer = (value<<24 | n) + 526336
+	default:
+		pointer = n
+	}
+	return pointer, offset + size, nil
+}
@@ -0,0 +1,280 @@
+// Package handoff replaces a running server binary without refusing a
+// connection, in the manner of cloudflare/tableflip. The old process starts
+// the new binary with its listening sockets (and any connections it wants
+// to pass on) as inherited file descriptors, both accept on the same
+// sockets until the new process reports ready, and the old one then drains
+// and exits.
+package handoff
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// envFiles lists the inherited descriptors as name=fd pairs
+	envFiles = "DDOS_HANDOFF_FILES"
+	// readyName is the pipe the new process writes to once it serves
+	readyName = "ready"
+)
+
+// ErrInProgress is returned when an upgrade is requested while one is running
+var ErrInProgress = errors.New("handoff: upgrade already in progress")
+
+// filer is implemented by the listeners and connections of package net
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Handoff tracks the sockets a process can pass on to its successor and
+// the ones it inherited from its predecessor
+type Handoff struct {
+	mu        sync.Mutex
+	inherited map[string][]*os.File
+	sockets   map[string]*os.File // listening sockets, by name
+	ready     *os.File            // write end of the predecessor's ready pipe
+	upgrading bool
+}
+
+// New reads the descriptors inherited from a predecessor, if any
+func New() (*Handoff, error) {
+	h := &Handoff{
+		inherited: make(map[string][]*os.File),
+		sockets:   make(map[string]*os.File),
+	}
+
+	spec := os.Getenv(envFiles)
+	os.Unsetenv(envFiles)
+	for _, entry := range strings.Split(spec, ",") {
+		if entry == "" {
+			continue
+		}
+		name, fdText, ok := strings.Cut(entry, "=")
+		fd, err := strconv.Atoi(fdText)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("handoff: invalid inherited descriptor %q", entry)
+		}
+
+		file := os.NewFile(uintptr(fd), name)
+		if name == readyName {
+			h.ready = file
+		} else {
+			h.inherited[name] = append(h.inherited[name], file)
+		}
+	}
+
+	return h, nil
+}
+
+// Inherited reports whether this process was started by an upgrade
+func (h *Handoff) Inherited() bool {
+	return h.ready != nil
+}
+
+// Listen returns the stream listener called name, inherited from the
+// predecessor when it had one, and remembers it for the next upgrade
+func (h *Handoff) Listen(name, network, addr string) (net.Listener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if file := h.take(name); file != nil {
+		listener, err := net.FileListener(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("handoff: inherited %s: %w", name, err)
+		}
+		h.sockets[name] = file
+		return listener, nil
+	}
+
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.remember(name, listener); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ListenPacket is Listen for datagram sockets
+func (h *Handoff) ListenPacket(name, network, addr string) (net.PacketConn, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if file := h.take(name); file != nil {
+		conn, err := net.FilePacketConn(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("handoff: inherited %s: %w", name, err)
+		}
+		h.sockets[name] = file
+		return conn, nil
+	}
+
+	conn, err := net.ListenPacket(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.remember(name, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Conns returns the connections the predecessor passed on under name
+func (h *Handoff) Conns(name string) []net.Conn {
+	h.mu.Lock()
+	files := h.inherited[name]
+	delete(h.inherited, name)
+	h.mu.Unlock()
+
+	conns := make([]net.Conn, 0, len(files))
+	for _, file := range files {
+		conn, err := net.FileConn(file)
+		file.Close()
+		if err == nil {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
+
+// Ready tells the predecessor this process is serving, so it can drain and
+// exit
+func (h *Handoff) Ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ready == nil {
+		return nil
+	}
+	_, err := h.ready.Write([]byte{1})
+	h.ready.Close()
+	h.ready = nil
+	return err
+}
+
+// Upgrade starts the current executable with the same arguments, passing
+// it every listening socket plus the given connection files, and waits up
+// to timeout for it to become ready. On success the caller should stop
+// accepting, drain and exit; on failure it carries on serving.
+func (h *Handoff) Upgrade(conns map[string][]*os.File, timeout time.Duration) error {
+	h.mu.Lock()
+	if h.upgrading {
+		h.mu.Unlock()
+		return ErrInProgress
+	}
+	h.upgrading = true
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		h.upgrading = false
+		h.mu.Unlock()
+	}()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("handoff: locating executable: %w", err)
+	}
+
+	readyRead, readyWrite, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("handoff: ready pipe: %w", err)
+	}
+	defer readyRead.Close()
+
+	// ExtraFiles[i] becomes descriptor 3+i in the new process
+	extra := []*os.File{readyWrite}
+	entries := []string{fmt.Sprintf("%s=%d", readyName, 3)}
+	h.mu.Lock()
+	for name, file := range h.sockets {
+		entries = append(entries, fmt.Sprintf("%s=%d", name, 3+len(extra)))
+		extra = append(extra, file)
+	}
+	h.mu.Unlock()
+	for name, files := range conns {
+		for _, file := range files {
+			entries = append(entries, fmt.Sprintf("%s=%d", name, 3+len(extra)))
+			extra = append(extra, file)
+		}
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), envFiles+"="+strings.Join(entries, ","))
+	cmd.ExtraFiles = extra
+
+	err = cmd.Start()
+	readyWrite.Close()
+	if err != nil {
+		return fmt.Errorf("handoff: starting %s: %w", executable, err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyRead.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			cmd.Process.Kill()
+			return errors.New("handoff: new process closed its ready pipe without becoming ready")
+		}
+		// The new process outlives this one and is adopted by init
+		return nil
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("exit status 0")
+		}
+		return fmt.Errorf("handoff: new process exited before becoming ready: %w", err)
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("handoff: new process not ready after %s", timeout)
+	}
+}
+
+// take removes and returns an inherited socket, or nil
+func (h *Handoff) take(name string) *os.File {
+	files := h.inherited[name]
+	if len(files) == 0 {
+		return nil
+	}
+	delete(h.inherited, name)
+	for _, extra := range files[1:] {
+		extra.Close()
+	}
+	return files[0]
+}
+
+// remember keeps a duplicate of a new socket's descriptor for the next upgrade
+func (h *Handoff) remember(name string, socket interface{}) error {
+	f, ok := socket.(filer)
+	if !ok {
+		return fmt.Errorf("handoff: %s can't be passed on", name)
+	}
+	file, err := f.File()
+	if err != nil {
+		return fmt.Errorf("handoff: %s: %w", name, err)
+	}
+	h.sockets[name] = file
+	return nil
+}
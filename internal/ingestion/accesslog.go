@@ -0,0 +1,160 @@
+package ingestion
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// combinedLogPattern matches the NCSA combined log format nginx and Apache
+// write by default; the referer and user agent are optional, so the common
+// format matches too
+var combinedLogPattern = regexp.MustCompile(`^(\S+) \S+ \S+ \[([^\]]+)\] "([^"]*)" (\d{3}) (\d+|-)(?: "([^"]*)" "([^"]*)")?`)
+
+// accessLogTime is the layout of $time_local
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogPoll is how often a tailed log is checked for new lines
+const accessLogPoll = 250 * time.Millisecond
+
+// ParseAccessLog turns one combined-format access log line into a traffic
+// request to destIP, which the log doesn't record
+func ParseAccessLog(line, environment, destIP string) (models.TrafficRequest, bool) {
+	m := combinedLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return models.TrafficRequest{}, false
+	}
+
+	ts, err := time.Parse(accessLogTime, m[2])
+	if err != nil {
+		ts = time.Now()
+	}
+	status, _ := strconv.Atoi(m[4])
+	bytesSent, _ := strconv.Atoi(m[5])
+
+	req := models.TrafficRequest{
+		ID:          uuid.New().String(),
+		Timestamp:   ts,
+		SourceIP:    m[1],
+		DestIP:      destIP,
+		Protocol:    "HTTP",
+		StatusCode:  status,
+		BytesSent:   bytesSent,
+		Environment: environment,
+	}
+	// "GET /path HTTP/1.1"; malformed requests are logged as they came
+	if parts := strings.Fields(m[3]); len(parts) == 3 {
+		req.Method, req.RequestPath, req.HTTPVersion = parts[0], parts[1], parts[2]
+	} else {
+		req.RequestPath = m[3]
+	}
+	if m[6] != "-" {
+		req.Referer = m[6]
+	}
+	if m[7] != "-" {
+		req.UserAgent = m[7]
+	}
+	return req, true
+}
+
+// AccessLogTailer is the optional agent: it follows a web server's access
+// log like tail -F, from its end, through rotation and truncation, and
+// stores each request it logs
+type AccessLogTailer struct {
+	Path        string
+	Environment string
+	// DestIP is the address the web server answers on
+	DestIP string
+
+	sink Sink
+}
+
+func NewAccessLogTailer(path, environment, destIP string, sink Sink) *AccessLogTailer {
+	return &AccessLogTailer{Path: path, Environment: environment, DestIP: destIP, sink: sink}
+}
+
+// Run follows the log until ctx is done
+func (t *AccessLogTailer) Run(ctx context.Context) error {
+	file, err := os.Open(t.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	log.Printf("📥 Following access log %s", t.Path)
+
+	ticker := time.NewTicker(accessLogPoll)
+	defer ticker.Stop()
+
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			t.store(partial + line)
+			partial = ""
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		// A line still being written is finished on a later read
+		partial += line
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Rotated: the path names a new file, read from its start.
+		// Truncated: the same file shrank below what was read.
+		info, err := os.Stat(t.Path)
+		if err != nil {
+			continue
+		}
+		current, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(info, current) {
+			next, err := os.Open(t.Path)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file, offset, partial = next, 0, ""
+			reader.Reset(file)
+		} else if info.Size() < offset {
+			if offset, err = file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			partial = ""
+			reader.Reset(file)
+		}
+	}
+}
+
+func (t *AccessLogTailer) store(line string) {
+	req, ok := ParseAccessLog(strings.TrimRight(line, "\r\n"), t.Environment, t.DestIP)
+	if !ok {
+		return
+	}
+	if err := t.sink.StoreTraffic(req); err != nil {
+		log.Printf("Error storing access log traffic: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package ingestion
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// CanonicalAddresses rewrites a request's source and destination in
+// canonical form, so one host is counted, keyed and matched as one
+// whatever its producer wrote: IPv6 compressed and lower-case, without
+// brackets or zone, and IPv4-mapped IPv6 as plain IPv4. Addresses that
+// don't parse are left as they are.
+func CanonicalAddresses(req *models.TrafficRequest) {
+	req.SourceIP = CanonicalIP(req.SourceIP)
+	req.DestIP = CanonicalIP(req.DestIP)
+}
+
+// CanonicalIP returns an address in canonical form, or s if it isn't one
+func CanonicalIP(s string) string {
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return s
+	}
+	return addr.WithZone("").Unmap().String()
+}
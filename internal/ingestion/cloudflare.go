@@ -0,0 +1,338 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// CloudflareRecord holds the Logpush http_requests fields we map into the traffic model
+type CloudflareRecord struct {
+	RayID                  string          `json:"RayID"`
+	ClientIP               string          `json:"ClientIP"`
+	ClientSrcPort          int             `json:"ClientSrcPort"`
+	ClientRequestHost      string          `json:"ClientRequestHost"`
+	ClientRequestMethod    string          `json:"ClientRequestMethod"`
+	ClientRequestURI       string          `json:"ClientRequestURI"`
+	ClientRequestScheme    string          `json:"ClientRequestScheme"`
+	ClientRequestUserAgent string          `json:"ClientRequestUserAgent"`
+	ClientRequestReferer   string          `json:"ClientRequestReferer"`
+	ClientRequestBytes     int             `json:"ClientRequestBytes"`
+	ClientCountry          string          `json:"ClientCountry"`
+	ClientASN              uint32          `json:"ClientASN"`
+	ClientSSLCipher        string          `json:"ClientSSLCipher"`
+	JA3Hash                string          `json:"JA3Hash"` // Bot Management customers only
+	EdgeResponseStatus     int             `json:"EdgeResponseStatus"`
+	EdgeResponseBytes      int             `json:"EdgeResponseBytes"`
+	EdgeServerIP           string          `json:"EdgeServerIP"`
+	OriginIP               string          `json:"OriginIP"`
+	EdgeStartTimestamp     json.RawMessage `json:"EdgeStartTimestamp"`
+	EdgeEndTimestamp       json.RawMessage `json:"EdgeEndTimestamp"`
+}
+
+// ToTrafficRequest normalizes a Logpush record
+func (r CloudflareRecord) ToTrafficRequest(environment string) models.TrafficRequest {
+	start, startOK := parseLogpushTimestamp(r.EdgeStartTimestamp)
+	end, endOK := parseLogpushTimestamp(r.EdgeEndTimestamp)
+
+	timestamp := time.Now()
+	if startOK {
+		timestamp = start
+	}
+
+	duration := 0
+	if startOK && endOK && end.After(start) {
+		duration = int(end.Sub(start).Milliseconds())
+	}
+
+	destIP := r.OriginIP
+	if destIP == "" {
+		destIP = r.EdgeServerIP
+	}
+
+	destPort := 443
+	if r.ClientRequestScheme == "http" {
+		destPort = 80
+	}
+
+	id := r.RayID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	return models.TrafficRequest{
+		ID:          id,
+		Timestamp:   timestamp,
+		SourceIP:    r.ClientIP,
+		DestIP:      destIP,
+		SourcePort:  r.ClientSrcPort,
+		DestPort:    destPort,
+		Protocol:    "HTTP",
+		RequestPath: r.ClientRequestURI,
+		Method:      r.ClientRequestMethod,
+		UserAgent:   r.ClientRequestUserAgent,
+		Referer:     r.ClientRequestReferer,
+		BytesSent:   r.ClientRequestBytes,
+		BytesRecv:   r.EdgeResponseBytes,
+		StatusCode:  r.EdgeResponseStatus,
+		Duration:    duration,
+		Environment: environment,
+		Country:     strings.ToUpper(r.ClientCountry),
+		ASN:         r.ClientASN,
+		JA3:         r.JA3Hash,
+		TLSCipher:   tlsCipher(r.ClientSSLCipher),
+	}
+}
+
+// tlsCipher drops the NONE Logpush records for plain HTTP
+func tlsCipher(cipher string) string {
+	if cipher == "NONE" {
+		return ""
+	}
+	return cipher
+}
+
+// parseLogpushTimestamp accepts every Logpush timestamp_format: rfc3339
+// strings, unix seconds and unix nanoseconds
+func parseLogpushTimestamp(raw json.RawMessage) (time.Time, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, false
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			return t, true
+		}
+		raw = json.RawMessage(text)
+	}
+
+	n, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Anything past year ~5000 in seconds is really nanoseconds
+	if n > 1e11 {
+		return time.Unix(0, n), true
+	}
+	return time.Unix(n, 0), true
+}
+
+// MaxLogpushBatchBytes bounds a Logpush batch once decompressed, so a
+// small gzip bomb can't keep a node decompressing
+const MaxLogpushBatchBytes = 256 << 20
+
+var (
+	// ErrLogpushBatchTooLarge is returned for a batch that decompresses
+	// to more than MaxLogpushBatchBytes
+	ErrLogpushBatchTooLarge = errors.New("logpush batch is too large once decompressed")
+	// ErrDuplicateLogpushBatch is returned for a batch already ingested
+	ErrDuplicateLogpushBatch = errors.New("logpush batch was already ingested")
+)
+
+// LogpushBatches remembers the Logpush batches ingested, so one Logpush
+// retries after a timeout isn't counted twice
+type LogpushBatches interface {
+	// ClaimLogpushBatch claims a batch, returning how many of its records
+	// an earlier delivery stored before failing; false if the batch was
+	// ingested already or is being ingested
+	ClaimLogpushBatch(id string) (int, bool, error)
+	// ReleaseLogpushBatch hands back a batch that failed after storing
+	// stored of its records
+	ReleaseLogpushBatch(id string, stored int) error
+}
+
+// ReadCloudflareLogs parses a Logpush batch (NDJSON, optionally gzipped) and
+// writes each request to the sink. Lines without a client IP, such as the
+// destination validation payload Logpush sends on setup, are skipped.
+//
+// With batches set, a batch is identified by the RayID of its first
+// request, which no other batch holds, and one already ingested returns
+// ErrDuplicateLogpushBatch without storing anything. A batch that fails
+// part way is released with how many of its records were stored, and
+// Logpush's retry of it stores only the rest.
+func ReadCloudflareLogs(body io.Reader, environment string, sink Sink, batches LogpushBatches) (count int, err error) {
+	reader := bufio.NewReader(body)
+
+	magic, err := reader.Peek(2)
+	if err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	scanner := bufio.NewScanner(&cappedReader{r: reader, n: MaxLogpushBatchBytes})
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	// Records of the batch stored by this and earlier deliveries, and by
+	// earlier deliveries alone
+	batchID := ""
+	stored, skip := 0, 0
+	defer func() {
+		if err != nil && batchID != "" {
+			if releaseErr := batches.ReleaseLogpushBatch(batchID, stored); releaseErr != nil {
+				log.Printf("Error releasing Logpush batch %s: %v", batchID, releaseErr)
+			}
+		}
+	}()
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record CloudflareRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			log.Printf("Skipping malformed Logpush record: %v", err)
+			continue
+		}
+		if record.ClientIP == "" {
+			continue
+		}
+
+		if batches != nil && stored == 0 && batchID == "" && record.RayID != "" {
+			done, ok, err := batches.ClaimLogpushBatch(record.RayID)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				return 0, ErrDuplicateLogpushBatch
+			}
+			batchID, skip = record.RayID, done
+		}
+
+		if stored < skip {
+			stored++
+			continue
+		}
+		if err := sink.StoreTraffic(record.ToTrafficRequest(environment)); err != nil {
+			return count, err
+		}
+		stored++
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// cappedReader reads at most n bytes, failing once there are more
+type cappedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		// Reaching the cap is only an error if the batch goes on
+		n, err := c.r.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, ErrLogpushBatchTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
+
+// LogpushPullConfig describes a Logpush destination bucket on S3 or R2
+type LogpushPullConfig struct {
+	S3URL        string // s3://bucket/prefix
+	Endpoint     string // e.g. https://<account>.r2.cloudflarestorage.com for R2
+	Region       string
+	Environment  string
+	PollInterval time.Duration
+	// Lookback is how far before startup batches are read from; 0 reads
+	// what is delivered from startup on
+	Lookback time.Duration
+}
+
+// LogpushPuller polls a Logpush bucket for new batches
+type LogpushPuller struct {
+	cfg    LogpushPullConfig
+	sink   Sink
+	poller *s3Poller
+}
+
+func NewLogpushPuller(ctx context.Context, cfg LogpushPullConfig, sink Sink) (*LogpushPuller, error) {
+	if cfg.S3URL == "" {
+		return nil, fmt.Errorf("logpush bucket URL is required")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 30 * time.Second
+	}
+	if cfg.Region == "" && cfg.Endpoint != "" {
+		// R2 ignores the region but the SDK insists on one
+		cfg.Region = "auto"
+	}
+
+	opts := make([]func(*config.LoadOptions) error, 0)
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load S3 config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	return &LogpushPuller{
+		cfg:    cfg,
+		sink:   sink,
+		poller: newS3Poller(client, cfg.S3URL, time.Now().Add(-cfg.Lookback)),
+	}, nil
+}
+
+// Run polls the bucket until the context is cancelled
+func (l *LogpushPuller) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("📥 Cloudflare Logpush puller started")
+
+	for {
+		count, err := l.poller.poll(ctx, func(key string, body io.Reader) (int, error) {
+			return ReadCloudflareLogs(body, l.cfg.Environment, l.sink, nil)
+		})
+		if err != nil {
+			log.Printf("Error ingesting Cloudflare logs: %v", err)
+		} else if count > 0 {
+			log.Printf("Ingested %d Cloudflare log records", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
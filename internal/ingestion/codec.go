@@ -0,0 +1,168 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Message formats accepted on the ingest bus
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+)
+
+// DecodeTrafficRequest decodes a bus message in the given format. Missing IDs
+// and timestamps are filled in the same way the HTTP ingest path would see them.
+func DecodeTrafficRequest(data []byte, format string) (models.TrafficRequest, error) {
+	var (
+		req models.TrafficRequest
+		err error
+	)
+
+	switch format {
+	case FormatJSON, "":
+		err = json.Unmarshal(data, &req)
+	case FormatProtobuf:
+		req, err = decodeTrafficProto(data)
+	default:
+		return req, fmt.Errorf("unsupported message format %q", format)
+	}
+	if err != nil {
+		return req, err
+	}
+
+	if req.ID == "" {
+		req.ID = uuid.New().String()
+	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	FingerprintHeaders(&req)
+
+	return req, nil
+}
+
+// decodeTrafficProto decodes the TrafficRequest message defined in traffic.proto
+func decodeTrafficProto(data []byte) (models.TrafficRequest, error) {
+	var req models.TrafficRequest
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return req, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return req, protowire.ParseError(n)
+			}
+			data = data[n:]
+
+			switch num {
+			case 1:
+				req.ID = string(v)
+			case 3:
+				req.SourceIP = string(v)
+			case 4:
+				req.DestIP = string(v)
+			case 7:
+				req.Protocol = string(v)
+			case 8:
+				req.RequestPath = string(v)
+			case 9:
+				req.UserAgent = string(v)
+			case 14:
+				req.Environment = string(v)
+			case 18:
+				req.DNSQueryName = string(v)
+			case 19:
+				req.HTTPVersion = string(v)
+			case 20:
+				req.HeaderOrder = append(req.HeaderOrder, string(v))
+			case 21:
+				req.Accept = string(v)
+			case 22:
+				req.AcceptLanguage = string(v)
+			case 23:
+				req.HeaderFingerprint = string(v)
+			case 25:
+				req.TLSServerName = string(v)
+			case 26:
+				req.JA3 = string(v)
+			case 27:
+				req.TLSCipher = string(v)
+			case 28:
+				req.Method = string(v)
+			case 29:
+				req.Referer = string(v)
+			}
+
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return req, protowire.ParseError(n)
+			}
+			data = data[n:]
+
+			switch num {
+			case 2:
+				req.Timestamp = time.UnixMilli(int64(v))
+			case 5:
+				req.SourcePort = int(int32(v))
+			case 6:
+				req.DestPort = int(int32(v))
+			case 10:
+				req.BytesSent = int(int64(v))
+			case 11:
+				req.BytesRecv = int(int64(v))
+			case 12:
+				req.StatusCode = int(int32(v))
+			case 13:
+				req.Duration = int(int64(v))
+			case 15:
+				req.TCPFlags = uint8(v)
+			case 24:
+				req.TTL = int(uint32(v))
+			case 30:
+				req.HeaderBytes = int(int64(v))
+			case 31:
+				req.TCPFlagsAggregated = v != 0
+			case 32:
+				req.Packets = int(int64(v))
+			}
+
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return req, protowire.ParseError(n)
+			}
+			data = data[n:]
+
+			switch num {
+			case 16:
+				req.RequestBodyRate = math.Float64frombits(v)
+			case 17:
+				req.ResponseReadRate = math.Float64frombits(v)
+			}
+
+		default:
+			// Skip fields from newer schema versions
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return req, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,212 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	accesslogdatav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	alsv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// EnvoyALSReceiver implements Envoy's gRPC Access Log Service so Envoy and
+// Istio sidecars can stream access logs straight into detection
+type EnvoyALSReceiver struct {
+	alsv3.UnimplementedAccessLogServiceServer
+
+	addr        string
+	environment string
+	sink        Sink
+
+	// Sockets opens the listener; nil means plain net.Listen
+	Sockets Sockets
+	// TLS serves the endpoint over TLS; nil serves it in cleartext
+	TLS *tls.Config
+}
+
+func NewEnvoyALSReceiver(addr, environment string, sink Sink) *EnvoyALSReceiver {
+	return &EnvoyALSReceiver{
+		addr:        addr,
+		environment: environment,
+		sink:        sink,
+	}
+}
+
+// Run serves the gRPC endpoint until the context is cancelled
+func (e *EnvoyALSReceiver) Run(ctx context.Context) error {
+	listener, err := listen(e.Sockets, "envoy-als", "tcp", e.addr)
+	if err != nil {
+		return fmt.Errorf("envoy als listen: %w", err)
+	}
+
+	var options []grpc.ServerOption
+	if e.TLS != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(e.TLS)))
+	}
+	server := grpc.NewServer(options...)
+	alsv3.RegisterAccessLogServiceServer(server, e)
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Printf("📥 Envoy access log service listening on %s", e.addr)
+	return server.Serve(listener)
+}
+
+// StreamAccessLogs receives the never-ending stream of log batches from one Envoy
+func (e *EnvoyALSReceiver) StreamAccessLogs(stream alsv3.AccessLogService_StreamAccessLogsServer) error {
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range msg.GetHttpLogs().GetLogEntry() {
+			e.store(httpEntryToTraffic(entry, e.environment))
+		}
+		for _, entry := range msg.GetTcpLogs().GetLogEntry() {
+			e.store(tcpEntryToTraffic(entry, e.environment))
+		}
+	}
+}
+
+func (e *EnvoyALSReceiver) store(req models.TrafficRequest) {
+	if req.SourceIP == "" {
+		return
+	}
+	if err := e.sink.StoreTraffic(req); err != nil {
+		log.Printf("Error storing Envoy access log: %v", err)
+	}
+}
+
+func httpEntryToTraffic(entry *accesslogdatav3.HTTPAccessLogEntry, environment string) models.TrafficRequest {
+	req := commonToTraffic(entry.GetCommonProperties(), environment)
+	req.Protocol = "HTTP"
+
+	request := entry.GetRequest()
+	req.RequestPath = request.GetPath()
+	req.UserAgent = request.GetUserAgent()
+	req.Referer = request.GetReferer()
+	req.HeaderBytes = int(request.GetRequestHeadersBytes())
+	if method := request.GetRequestMethod(); method != corev3.RequestMethod_METHOD_UNSPECIFIED {
+		req.Method = method.String()
+	}
+	req.BytesSent = int(request.GetRequestHeadersBytes() + request.GetRequestBodyBytes())
+	if id := request.GetRequestId(); id != "" {
+		req.ID = id
+	}
+
+	// Header order isn't logged by Envoy, so the fingerprint rests on the
+	// protocol and whichever Accept headers the access log is configured to keep
+	req.HTTPVersion = envoyHTTPVersion(entry.GetProtocolVersion())
+	headers := request.GetRequestHeaders()
+	req.Accept = headers["accept"]
+	req.AcceptLanguage = headers["accept-language"]
+	FingerprintHeaders(&req)
+
+	response := entry.GetResponse()
+	req.StatusCode = int(response.GetResponseCode().GetValue())
+	req.BytesRecv = int(response.GetResponseHeadersBytes() + response.GetResponseBodyBytes())
+
+	// Body transfer rates expose slow POST and slow-read clients
+	common := entry.GetCommonProperties()
+	if body := request.GetRequestBodyBytes(); body > 0 {
+		req.RequestBodyRate = transferRate(body, common.GetTimeToLastRxByte().AsDuration())
+	}
+	if body := response.GetResponseBodyBytes(); body > 0 {
+		span := common.GetTimeToLastDownstreamTxByte().AsDuration() - common.GetTimeToFirstDownstreamTxByte().AsDuration()
+		req.ResponseReadRate = transferRate(body, span)
+	}
+
+	return req
+}
+
+func tcpEntryToTraffic(entry *accesslogdatav3.TCPAccessLogEntry, environment string) models.TrafficRequest {
+	req := commonToTraffic(entry.GetCommonProperties(), environment)
+	req.Protocol = "TCP"
+
+	conn := entry.GetConnectionProperties()
+	req.BytesSent = int(conn.GetReceivedBytes())
+	req.BytesRecv = int(conn.GetSentBytes())
+
+	return req
+}
+
+func commonToTraffic(common *accesslogdatav3.AccessLogCommon, environment string) models.TrafficRequest {
+	source := socketAddress(common.GetDownstreamRemoteAddress())
+	dest := socketAddress(common.GetDownstreamLocalAddress())
+
+	timestamp := time.Now()
+	if start := common.GetStartTime(); start != nil {
+		timestamp = start.AsTime()
+	}
+
+	duration := 0
+	if d := common.GetDuration(); d != nil {
+		duration = int(d.AsDuration().Milliseconds())
+	} else if d := common.GetTimeToLastDownstreamTxByte(); d != nil {
+		duration = int(d.AsDuration().Milliseconds())
+	}
+
+	req := models.TrafficRequest{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		SourceIP:    source.GetAddress(),
+		SourcePort:  int(source.GetPortValue()),
+		DestIP:      dest.GetAddress(),
+		DestPort:    int(dest.GetPortValue()),
+		Duration:    duration,
+		Environment: environment,
+	}
+
+	// Set when Envoy terminated TLS for the downstream connection
+	if tlsProps := common.GetTlsProperties(); tlsProps != nil {
+		req.TLSServerName = tlsProps.GetTlsSniHostname()
+		req.JA3 = tlsProps.GetJa3Fingerprint()
+		if suite := tlsProps.GetTlsCipherSuite(); suite != nil {
+			req.TLSCipher = tls.CipherSuiteName(uint16(suite.GetValue()))
+		}
+	}
+
+	return req
+}
+
+func envoyHTTPVersion(version accesslogdatav3.HTTPAccessLogEntry_HTTPVersion) string {
+	switch version {
+	case accesslogdatav3.HTTPAccessLogEntry_HTTP10:
+		return "HTTP/1.0"
+	case accesslogdatav3.HTTPAccessLogEntry_HTTP11:
+		return "HTTP/1.1"
+	case accesslogdatav3.HTTPAccessLogEntry_HTTP2:
+		return "HTTP/2"
+	case accesslogdatav3.HTTPAccessLogEntry_HTTP3:
+		return "HTTP/3"
+	}
+	return ""
+}
+
+// transferRate returns bytes/sec, or 0 when the duration is unknown
+func transferRate(bytes uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds()
+}
+
+func socketAddress(addr *corev3.Address) *corev3.SocketAddress {
+	return addr.GetSocketAddress()
+}
@@ -0,0 +1,42 @@
+package ingestion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// FingerprintHeaders fills HeaderFingerprint from the request's client stack
+// signals unless the producer already computed one. Requests without any
+// of those signals are left unfingerprinted.
+func FingerprintHeaders(req *models.TrafficRequest) {
+	if req.HeaderFingerprint != "" {
+		return
+	}
+	if req.HTTPVersion == "" && len(req.HeaderOrder) == 0 && req.Accept == "" && req.AcceptLanguage == "" {
+		return
+	}
+	req.HeaderFingerprint = HeaderFingerprint(req.HTTPVersion, req.HeaderOrder, req.Accept, req.AcceptLanguage)
+}
+
+// HeaderFingerprint hashes what a client stack sends regardless of who uses
+// it: protocol version, header order and the Accept/Accept-Language pair.
+// Bots that rotate IPs and User-Agents but share one HTTP library keep the
+// same fingerprint. Header names are case-insensitive, values are trimmed.
+func HeaderFingerprint(version string, headerOrder []string, accept, acceptLanguage string) string {
+	names := make([]string, len(headerOrder))
+	for i, name := range headerOrder {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.ToUpper(strings.TrimSpace(version)),
+		strings.Join(names, ","),
+		strings.TrimSpace(accept),
+		strings.TrimSpace(acceptLanguage),
+	}, "|")))
+
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,185 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaConfig describes the topic traffic producers publish to
+type KafkaConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	Format  string // json or protobuf
+}
+
+// KafkaConsumer reads TrafficRequests from a topic as part of a consumer
+// group and writes them to storage. Offsets are committed only after a
+// message is stored, so delivery is at-least-once and a slow analysis
+// engine shows up as consumer lag rather than dropped requests.
+type KafkaConsumer struct {
+	cfg    KafkaConfig
+	reader *kafka.Reader
+	client *kafka.Client
+	sink   Sink
+}
+
+func NewKafkaConsumer(cfg KafkaConfig, sink Sink) (*KafkaConsumer, error) {
+	if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka brokers and topic are required")
+	}
+	if cfg.GroupID == "" {
+		cfg.GroupID = "ddos-detection"
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatProtobuf {
+		return nil, fmt.Errorf("unsupported kafka message format %q", cfg.Format)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  cfg.Brokers,
+		Topic:    cfg.Topic,
+		GroupID:  cfg.GroupID,
+		MinBytes: 1,
+		MaxBytes: 10e6,
+		MaxWait:  500 * time.Millisecond,
+	})
+
+	return &KafkaConsumer{
+		cfg:    cfg,
+		reader: reader,
+		client: &kafka.Client{Addr: kafka.TCP(cfg.Brokers...)},
+		sink:   sink,
+	}, nil
+}
+
+// Run consumes until the context is cancelled
+func (k *KafkaConsumer) Run(ctx context.Context) {
+	defer k.reader.Close()
+
+	log.Printf("📥 Kafka consumer started (topic %s, group %s)", k.cfg.Topic, k.cfg.GroupID)
+
+	for {
+		msg, err := k.reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return
+			}
+			log.Printf("Error fetching Kafka message: %v", err)
+			continue
+		}
+
+		req, err := DecodeTrafficRequest(msg.Value, k.cfg.Format)
+		if err != nil {
+			// Poison messages are skipped rather than blocking the partition
+			log.Printf("Skipping undecodable Kafka message at %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		} else if !k.store(ctx, req) {
+			return
+		}
+
+		if err := k.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("Error committing Kafka offset: %v", err)
+		}
+	}
+}
+
+// store retries a failed write with backoff until it succeeds or the
+// context ends; the offset is never committed for an unstored message
+func (k *KafkaConsumer) store(ctx context.Context, req models.TrafficRequest) bool {
+	backoff := 100 * time.Millisecond
+
+	for {
+		err := k.sink.StoreTraffic(req)
+		if err == nil {
+			return true
+		}
+
+		log.Printf("Error storing Kafka traffic, retrying in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		if backoff < 5*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Lag returns how many messages the group is behind the end of the topic,
+// from its committed offsets against each partition's high watermark. The
+// reader's own statistics only cover the partitions it is assigned, and
+// none with a consumer group.
+func (k *KafkaConsumer) Lag(ctx context.Context) (int64, error) {
+	metadata, err := k.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{k.cfg.Topic}})
+	if err != nil {
+		return 0, err
+	}
+	var partitions []int
+	for _, topic := range metadata.Topics {
+		if topic.Name != k.cfg.Topic {
+			continue
+		}
+		if topic.Error != nil {
+			return 0, topic.Error
+		}
+		for _, partition := range topic.Partitions {
+			partitions = append(partitions, partition.ID)
+		}
+	}
+	if len(partitions) == 0 {
+		return 0, fmt.Errorf("kafka topic %s has no partitions", k.cfg.Topic)
+	}
+
+	committed, err := k.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: k.cfg.GroupID,
+		Topics:  map[string][]int{k.cfg.Topic: partitions},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if committed.Error != nil {
+		return 0, committed.Error
+	}
+
+	requests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	for _, partition := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(partition), kafka.LastOffsetOf(partition))
+	}
+	watermarks, err := k.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{k.cfg.Topic: requests},
+	})
+	if err != nil {
+		return 0, err
+	}
+	bounds := make(map[int]kafka.PartitionOffsets, len(partitions))
+	for _, offsets := range watermarks.Topics[k.cfg.Topic] {
+		if offsets.Error != nil {
+			return 0, offsets.Error
+		}
+		bounds[offsets.Partition] = offsets
+	}
+
+	var lag int64
+	for _, partition := range committed.Topics[k.cfg.Topic] {
+		if partition.Error != nil {
+			return 0, partition.Error
+		}
+		offsets := bounds[partition.Partition]
+		// Without a commit the group starts from the oldest message
+		// retained, and retention may have deleted what it committed
+		next := max(partition.CommittedOffset, offsets.FirstOffset)
+		lag += max(offsets.LastOffset-next, 0)
+	}
+	return lag, nil
+}
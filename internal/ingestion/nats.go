@@ -0,0 +1,147 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSConfig describes the JetStream stream traffic producers publish to
+type NATSConfig struct {
+	URL      string
+	Stream   string
+	Subject  string // optional filter within the stream
+	Consumer string // durable consumer name, shared by all server replicas
+	Format   string // json or protobuf
+	// ReplayFrom makes a newly created consumer start at this time instead
+	// of at new messages; "all" replays the whole stream
+	ReplayFrom string
+}
+
+// NATSConsumer reads TrafficRequests from a JetStream durable consumer and
+// writes them to storage. Messages are acked only once stored, so delivery
+// is at-least-once; failed writes are redelivered after a delay.
+type NATSConsumer struct {
+	cfg  NATSConfig
+	conn *nats.Conn
+	cons jetstream.Consumer
+	sink Sink
+}
+
+func NewNATSConsumer(ctx context.Context, cfg NATSConfig, sink Sink) (*NATSConsumer, error) {
+	if cfg.URL == "" || cfg.Stream == "" {
+		return nil, fmt.Errorf("nats url and stream are required")
+	}
+	if cfg.Consumer == "" {
+		cfg.Consumer = "ddos-detection"
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatProtobuf {
+		return nil, fmt.Errorf("unsupported nats message format %q", cfg.Format)
+	}
+
+	consumerCfg := jetstream.ConsumerConfig{
+		Durable:       cfg.Consumer,
+		FilterSubject: cfg.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       30 * time.Second,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+	}
+
+	switch cfg.ReplayFrom {
+	case "":
+	case "all":
+		consumerCfg.DeliverPolicy = jetstream.DeliverAllPolicy
+	default:
+		start, err := time.Parse(time.RFC3339, cfg.ReplayFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nats replay start %q: %w", cfg.ReplayFrom, err)
+		}
+		consumerCfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		consumerCfg.OptStartTime = &start
+	}
+
+	conn, err := nats.Connect(cfg.URL, nats.Name("ddos-detection-server"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	stream, err := js.Stream(ctx, cfg.Stream)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open stream %s: %w", cfg.Stream, err)
+	}
+
+	// An existing durable keeps its position; the deliver policy only
+	// applies the first time it is created
+	cons, err := stream.Consumer(ctx, cfg.Consumer)
+	if err != nil {
+		cons, err = stream.CreateOrUpdateConsumer(ctx, consumerCfg)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create consumer %s: %w", cfg.Consumer, err)
+	}
+
+	return &NATSConsumer{
+		cfg:  cfg,
+		conn: conn,
+		cons: cons,
+		sink: sink,
+	}, nil
+}
+
+// Run consumes until the context is cancelled
+func (n *NATSConsumer) Run(ctx context.Context) {
+	defer n.conn.Close()
+
+	consumeCtx, err := n.cons.Consume(n.handle)
+	if err != nil {
+		log.Printf("Error starting NATS consumer: %v", err)
+		return
+	}
+	defer consumeCtx.Stop()
+
+	log.Printf("📥 NATS JetStream consumer started (stream %s, consumer %s)", n.cfg.Stream, n.cfg.Consumer)
+
+	<-ctx.Done()
+}
+
+func (n *NATSConsumer) handle(msg jetstream.Msg) {
+	req, err := DecodeTrafficRequest(msg.Data(), n.cfg.Format)
+	if err != nil {
+		// Poison messages are terminated rather than redelivered forever
+		log.Printf("Dropping undecodable NATS message on %s: %v", msg.Subject(), err)
+		msg.Term()
+		return
+	}
+
+	if err := n.sink.StoreTraffic(req); err != nil {
+		log.Printf("Error storing NATS traffic, will be redelivered: %v", err)
+		msg.NakWithDelay(time.Second)
+		return
+	}
+
+	msg.Ack()
+}
+
+// Pending returns how many stream messages the consumer has yet to receive
+func (n *NATSConsumer) Pending(ctx context.Context) (uint64, error) {
+	info, err := n.cons.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return info.NumPending, nil
+}
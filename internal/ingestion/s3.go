@@ -0,0 +1,170 @@
+package ingestion
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3DeliveryGrace is how long after an object's LastModified time a later
+// one of the same directory may still be listed. Writers upload in
+// parallel, so objects don't become visible in the order they're stamped.
+const s3DeliveryGrace = 10 * time.Minute
+
+// s3MaxAttempts is how many polls an object that fails to read is retried
+// on before it is skipped
+const s3MaxAttempts = 3
+
+// s3Poller hands out the objects delivered under a prefix, from a start
+// time on. It keeps a cursor per directory: delivery services write
+// several streams side by side (one per account, region or day), whose
+// keys don't sort in delivery order across directories.
+type s3Poller struct {
+	client  *s3.Client
+	bucket  string
+	prefix  string
+	since   time.Time
+	cursors map[string]*s3Cursor
+	// Failed reads per object still to be retried
+	attempts map[string]int
+}
+
+// s3Cursor is one directory's progress: the newest object read and the
+// objects read within s3DeliveryGrace of it
+type s3Cursor struct {
+	newest time.Time
+	read   map[string]time.Time
+}
+
+// newS3Poller reads the objects delivered from since on
+func newS3Poller(client *s3.Client, url string, since time.Time) *s3Poller {
+	bucket, prefix := parseS3URL(url)
+	return &s3Poller{
+		client:   client,
+		bucket:   bucket,
+		prefix:   prefix,
+		since:    since,
+		cursors:  make(map[string]*s3Cursor),
+		attempts: make(map[string]int),
+	}
+}
+
+// poll calls handle with the (decompressed) body of every object delivered
+// since the previous poll. An object that fails before anything of it is
+// stored is retried on the next polls, up to s3MaxAttempts; one that fails
+// after storing records, or keeps failing, is logged and skipped, so one
+// bad object never holds up the objects after it.
+func (p *s3Poller) poll(ctx context.Context, handle func(key string, body io.Reader) (int, error)) (int, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(p.bucket),
+		Prefix: aws.String(p.prefix),
+	}
+
+	total := 0
+	paginator := s3.NewListObjectsV2Paginator(p.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			modified := aws.ToTime(object.LastModified)
+			cursor := p.cursor(key)
+			if !cursor.unread(key, modified, p.since) {
+				continue
+			}
+
+			count, err := p.read(ctx, key, handle)
+			total += count
+			if err != nil {
+				if ctx.Err() != nil {
+					return total, ctx.Err()
+				}
+				p.attempts[key]++
+				if count == 0 && p.attempts[key] < s3MaxAttempts {
+					log.Printf("Error reading s3://%s/%s, retrying on the next poll: %v", p.bucket, key, err)
+					continue
+				}
+				// Reading it again would store its first records twice
+				log.Printf("Skipping s3://%s/%s after %d attempts with %d records stored: %v", p.bucket, key, p.attempts[key], count, err)
+			}
+			delete(p.attempts, key)
+			cursor.markRead(key, modified)
+		}
+	}
+
+	return total, nil
+}
+
+// cursor returns the cursor of an object's directory
+func (p *s3Poller) cursor(key string) *s3Cursor {
+	dir, _ := path.Split(key)
+	cursor, ok := p.cursors[dir]
+	if !ok {
+		cursor = &s3Cursor{read: make(map[string]time.Time)}
+		p.cursors[dir] = cursor
+	}
+	return cursor
+}
+
+// unread reports whether an object is still to be read: delivered from
+// since on, not long before the newest read and not read yet
+func (c *s3Cursor) unread(key string, modified, since time.Time) bool {
+	if modified.Before(since) || modified.Before(c.newest.Add(-s3DeliveryGrace)) {
+		return false
+	}
+	_, read := c.read[key]
+	return !read
+}
+
+// markRead records an object as read, forgetting those too old to list
+// again
+func (c *s3Cursor) markRead(key string, modified time.Time) {
+	c.read[key] = modified
+	if modified.After(c.newest) {
+		c.newest = modified
+	}
+	for key, modified := range c.read {
+		if modified.Before(c.newest.Add(-s3DeliveryGrace)) {
+			delete(c.read, key)
+		}
+	}
+}
+
+func (p *s3Poller) read(ctx context.Context, key string, handle func(key string, body io.Reader) (int, error)) (int, error) {
+	out, err := p.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer out.Body.Close()
+
+	var body io.Reader = out.Body
+	if strings.HasSuffix(key, ".gz") {
+		gz, err := gzip.NewReader(out.Body)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	return handle(key, body)
+}
+
+func parseS3URL(url string) (bucket, prefix string) {
+	path := strings.TrimPrefix(url, "s3://")
+	bucket, prefix, _ = strings.Cut(path, "/")
+	return bucket, prefix
+}
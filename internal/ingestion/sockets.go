@@ -0,0 +1,24 @@
+package ingestion
+
+import "net"
+
+// Sockets opens named listening sockets, e.g. ones handed over by a
+// previous server process during a binary upgrade
+type Sockets interface {
+	Listen(name, network, addr string) (net.Listener, error)
+	ListenPacket(name, network, addr string) (net.PacketConn, error)
+}
+
+func listen(sockets Sockets, name, network, addr string) (net.Listener, error) {
+	if sockets == nil {
+		return net.Listen(network, addr)
+	}
+	return sockets.Listen(name, network, addr)
+}
+
+func listenPacket(sockets Sockets, name, network, addr string) (net.PacketConn, error) {
+	if sockets == nil {
+		return net.ListenPacket(network, addr)
+	}
+	return sockets.ListenPacket(name, network, addr)
+}
@@ -0,0 +1,334 @@
+package ingestion
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// AlertSink receives alerts parsed from IDS events
+type AlertSink interface {
+	PublishAlert(alert models.Alert) error
+}
+
+// SyslogMessage is a parsed RFC3164 or RFC5424 message
+type SyslogMessage struct {
+	Priority  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	Message   string
+}
+
+var (
+	rfc5424Pattern = regexp.MustCompile(`^<(\d{1,3})>1 (\S+) (\S+) (\S+) (\S+) (\S+) (-|\[.*?\](?:\[.*?\])*)\s?(.*)$`)
+	rfc3164Pattern = regexp.MustCompile(`^<(\d{1,3})>([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^:\[\s]+)(?:\[\d+\])?:\s?(.*)$`)
+
+	// netfilter LOG target: "... SRC=1.2.3.4 DST=5.6.7.8 LEN=60 ... PROTO=TCP SPT=123 DPT=80 ... SYN"
+	netfilterPattern = regexp.MustCompile(`\bSRC=(\S+) DST=(\S+) LEN=(\d+)`)
+	netfilterField   = regexp.MustCompile(`\b(PROTO|SPT|DPT)=(\S+)`)
+	// Cisco ASA: "%ASA-4-106023: Deny tcp src outside:1.2.3.4/1234 dst inside:5.6.7.8/80 ..."
+	asaDenyPattern = regexp.MustCompile(`%ASA-\d-\d+: Deny (\w+) src \S+?:([\d.:a-fA-F]+)/(\d+) dst \S+?:([\d.:a-fA-F]+)/(\d+)`)
+	// Snort/Suricata fast alert: "[1:2000001:1] msg [Classification: x] [Priority: 2] {TCP} 1.2.3.4:123 -> 5.6.7.8:80"
+	idsAlertPattern = regexp.MustCompile(`\[\d+:(\d+):\d+\]\s+(.*?)\s+\[\*\*\]|\[\d+:(\d+):\d+\]\s+(.*?)\s+\[Classification`)
+	idsPriority     = regexp.MustCompile(`\[Priority: (\d)\]`)
+	idsFlow         = regexp.MustCompile(`\{(\w+)\} ([\d.a-fA-F:]+?):?(\d*) -> ([\d.a-fA-F:]+?):?(\d*)$`)
+)
+
+// ParseSyslog parses a single syslog line in either RFC5424 or RFC3164 format
+func ParseSyslog(line string) (SyslogMessage, error) {
+	line = strings.TrimRight(line, "\r\n\x00")
+
+	if m := rfc5424Pattern.FindStringSubmatch(line); m != nil {
+		pri, _ := strconv.Atoi(m[1])
+		ts, err := time.Parse(time.RFC3339Nano, m[2])
+		if err != nil {
+			ts = time.Now()
+		}
+		return SyslogMessage{
+			Priority:  pri,
+			Timestamp: ts,
+			Hostname:  m[3],
+			AppName:   m[4],
+			Message:   strings.TrimPrefix(m[8], "\ufeff"),
+		}, nil
+	}
+
+	if m := rfc3164Pattern.FindStringSubmatch(line); m != nil {
+		pri, _ := strconv.Atoi(m[1])
+		// RFC3164 timestamps carry no year or zone
+		ts, err := time.ParseInLocation(time.Stamp, m[2], time.Local)
+		if err != nil {
+			ts = time.Now()
+		} else {
+			ts = ts.AddDate(time.Now().Year(), 0, 0)
+		}
+		return SyslogMessage{
+			Priority:  pri,
+			Timestamp: ts,
+			Hostname:  m[3],
+			AppName:   m[4],
+			Message:   m[5],
+		}, nil
+	}
+
+	return SyslogMessage{}, fmt.Errorf("unrecognized syslog format")
+}
+
+// FirewallTraffic extracts a denied connection from netfilter or Cisco ASA
+// log messages
+func FirewallTraffic(msg SyslogMessage, environment string) (models.TrafficRequest, bool) {
+	req := models.TrafficRequest{
+		ID:          uuid.New().String(),
+		Timestamp:   msg.Timestamp,
+		StatusCode:  403,
+		Environment: environment,
+	}
+
+	if m := netfilterPattern.FindStringSubmatch(msg.Message); m != nil {
+		req.SourceIP = m[1]
+		req.DestIP = m[2]
+		req.BytesSent, _ = strconv.Atoi(m[3])
+
+		for _, f := range netfilterField.FindAllStringSubmatch(msg.Message, -1) {
+			switch f[1] {
+			case "PROTO":
+				req.Protocol = f[2]
+			case "SPT":
+				req.SourcePort, _ = strconv.Atoi(f[2])
+			case "DPT":
+				req.DestPort, _ = strconv.Atoi(f[2])
+			}
+		}
+
+		if req.Protocol == "TCP" {
+			req.TCPFlags = netfilterFlags(msg.Message)
+			if req.TCPFlags&(models.TCPFlagSYN|models.TCPFlagACK) == models.TCPFlagSYN {
+				req.Protocol = "TCP_SYN"
+			}
+		}
+		return req, true
+	}
+
+	if m := asaDenyPattern.FindStringSubmatch(msg.Message); m != nil {
+		req.Protocol = strings.ToUpper(m[1])
+		req.SourceIP = m[2]
+		req.SourcePort, _ = strconv.Atoi(m[3])
+		req.DestIP = m[4]
+		req.DestPort, _ = strconv.Atoi(m[5])
+		return req, true
+	}
+
+	return req, false
+}
+
+// IDSAlert converts a Snort/Suricata fast-format alert into an Alert
+func IDSAlert(msg SyslogMessage) (models.Alert, bool) {
+	m := idsAlertPattern.FindStringSubmatch(msg.Message)
+	if m == nil {
+		return models.Alert{}, false
+	}
+
+	sid, title := m[1], m[2]
+	if sid == "" {
+		sid, title = m[3], m[4]
+	}
+
+	level := "INFO"
+	if p := idsPriority.FindStringSubmatch(msg.Message); p != nil {
+		switch p[1] {
+		case "1":
+			level = "CRITICAL"
+		case "2":
+			level = "WARNING"
+		}
+	}
+
+	alert := models.Alert{
+		ID:        uuid.New().String(),
+		Level:     level,
+		Title:     strings.TrimSpace(title),
+		Message:   fmt.Sprintf("%s (sid %s) reported by %s", msg.Message, sid, msg.Hostname),
+		Timestamp: msg.Timestamp,
+	}
+	if f := idsFlow.FindStringSubmatch(msg.Message); f != nil {
+		alert.SourceIP = f[2]
+	}
+
+	return alert, true
+}
+
+// SyslogListener receives syslog over UDP and/or TCP and feeds firewall
+// denies into traffic storage and IDS events into alerts
+type SyslogListener struct {
+	UDPAddr     string
+	TCPAddr     string
+	Environment string
+
+	// Sockets opens the listeners; nil means plain net.Listen
+	Sockets Sockets
+	// TLS serves syslog over TLS on TCPAddr (RFC 5425); nil serves it in
+	// cleartext
+	TLS *tls.Config
+
+	sink   Sink
+	alerts AlertSink
+}
+
+func NewSyslogListener(udpAddr, tcpAddr, environment string, sink Sink, alerts AlertSink) *SyslogListener {
+	return &SyslogListener{
+		UDPAddr:     udpAddr,
+		TCPAddr:     tcpAddr,
+		Environment: environment,
+		sink:        sink,
+		alerts:      alerts,
+	}
+}
+
+// Run starts the configured listeners and blocks until the context is cancelled
+func (l *SyslogListener) Run(ctx context.Context) error {
+	if l.UDPAddr != "" {
+		conn, err := listenPacket(l.Sockets, "syslog-udp", "udp", l.UDPAddr)
+		if err != nil {
+			return fmt.Errorf("syslog udp listen: %w", err)
+		}
+		go func() {
+			<-ctx.Done()
+			conn.Close()
+		}()
+		go l.serveUDP(conn)
+		log.Printf("📥 Syslog listening on udp %s", l.UDPAddr)
+	}
+
+	if l.TCPAddr != "" {
+		listener, err := listen(l.Sockets, "syslog-tcp", "tcp", l.TCPAddr)
+		if err != nil {
+			return fmt.Errorf("syslog tcp listen: %w", err)
+		}
+		if l.TLS != nil {
+			listener = tls.NewListener(listener, l.TLS)
+		}
+		go func() {
+			<-ctx.Done()
+			listener.Close()
+		}()
+		go l.serveTCP(listener)
+		log.Printf("📥 Syslog listening on tcp %s", l.TCPAddr)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func (l *SyslogListener) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		l.handle(string(buf[:n]))
+	}
+}
+
+func (l *SyslogListener) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go l.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn reads both RFC6587 framings: octet counting ("123 <34>1 ...")
+// and newline-delimited messages
+func (l *SyslogListener) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		first, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+
+		if first[0] >= '0' && first[0] <= '9' {
+			lengthText, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			length, err := strconv.Atoi(strings.TrimSpace(lengthText))
+			if err != nil || length <= 0 || length > 64*1024 {
+				return
+			}
+			frame := make([]byte, length)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+			l.handle(string(frame))
+			continue
+		}
+
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			l.handle(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (l *SyslogListener) handle(line string) {
+	msg, err := ParseSyslog(line)
+	if err != nil {
+		return
+	}
+
+	if req, ok := FirewallTraffic(msg, l.Environment); ok {
+		if err := l.sink.StoreTraffic(req); err != nil {
+			log.Printf("Error storing syslog traffic: %v", err)
+		}
+		return
+	}
+
+	if alert, ok := IDSAlert(msg); ok && l.alerts != nil {
+		if err := l.alerts.PublishAlert(alert); err != nil {
+			log.Printf("Error publishing IDS alert: %v", err)
+		}
+	}
+}
+
+// netfilterFlags reads the TCP flag words netfilter LOG prints, e.g. "ACK PSH FIN"
+func netfilterFlags(message string) uint8 {
+	var flags uint8
+	for _, word := range strings.Fields(message) {
+		switch word {
+		case "FIN":
+			flags |= models.TCPFlagFIN
+		case "SYN":
+			flags |= models.TCPFlagSYN
+		case "RST":
+			flags |= models.TCPFlagRST
+		case "PSH":
+			flags |= models.TCPFlagPSH
+		case "ACK":
+			flags |= models.TCPFlagACK
+		case "URG":
+			flags |= models.TCPFlagURG
+		}
+	}
+	return flags
+}
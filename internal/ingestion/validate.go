@@ -0,0 +1,286 @@
+package ingestion
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// DefaultMaxAge is how old a request's timestamp may be when it is
+	// ingested over HTTP; older samples would land in minutes already
+	// analyzed
+	DefaultMaxAge = time.Hour
+	// DefaultMaxClockSkew is how far ahead of the server's clock a
+	// producer's may run
+	DefaultMaxClockSkew = time.Minute
+	// DefaultMaxEnvironments and DefaultMaxProtocols bound the distinct
+	// environments and protocols ingested
+	DefaultMaxEnvironments = 100
+	DefaultMaxProtocols    = 32
+)
+
+// Length limits on the strings of an ingested request, in bytes. They keep
+// Redis hash fields and the per-source state of detectors bounded whatever
+// a producer sends.
+var maxLengths = map[string]int{
+	"id":                 128,
+	"protocol":           16,
+	"request_path":       2048,
+	"method":             16,
+	"user_agent":         1024,
+	"referer":            2048,
+	"environment":        64,
+	"dns_query_name":     255,
+	"http_version":       16,
+	"accept":             1024,
+	"accept_language":    256,
+	"header_fingerprint": 128,
+	"tls_sni":            255,
+	"ja3":                64,
+	"tls_cipher":         128,
+	"country":            2,
+	"as_org":             256,
+}
+
+const (
+	// maxHeaderOrder bounds the header names listed in header_order
+	maxHeaderOrder = 100
+	// maxHeaderName bounds each of them
+	maxHeaderName = 128
+)
+
+// FieldError is what is wrong with one field of an ingested request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found with an ingested request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		problems[i] = f.Field + ": " + f.Message
+	}
+	return "invalid traffic: " + strings.Join(problems, "; ")
+}
+
+// Validator checks ingested requests before they reach the counters and
+// the detectors
+type Validator struct {
+	// MaxAge and MaxClockSkew bound a request's timestamp around the time
+	// it is ingested; 0 leaves that side unchecked
+	MaxAge       time.Duration
+	MaxClockSkew time.Duration
+	// Environments, when set, are the only environments traffic may be
+	// tagged with; untagged traffic is always accepted
+	Environments map[string]bool
+	// Cardinality caps the distinct environments and protocols; nil
+	// leaves them uncapped
+	Cardinality *Cardinality
+}
+
+// Cardinality caps the distinct environments and protocols this node
+// ingests. Each names Redis keys and hash fields and gets detectors and
+// baselines of its own, so a producer inventing a new one per request
+// would exhaust memory. A value first seen once its cap is reached is
+// refused; values already seen are always accepted.
+type Cardinality struct {
+	maxEnvironments int
+	maxProtocols    int
+
+	mu           sync.Mutex
+	environments map[string]bool
+	protocols    map[string]bool
+}
+
+// NewCardinality allows up to maxEnvironments environments and
+// maxProtocols protocols; 0 leaves one uncapped
+func NewCardinality(maxEnvironments, maxProtocols int) *Cardinality {
+	return &Cardinality{
+		maxEnvironments: maxEnvironments,
+		maxProtocols:    maxProtocols,
+		environments:    make(map[string]bool),
+		protocols:       make(map[string]bool),
+	}
+}
+
+// admit records a request's environment and protocol, returning what is
+// wrong with those over their cap
+func (c *Cardinality) admit(req models.TrafficRequest) []FieldError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []FieldError
+	if !admitValue(c.environments, req.Environment, c.maxEnvironments) {
+		errs = append(errs, FieldError{Field: "environment", Message: fmt.Sprintf("%q is new and %d environments are already ingested", req.Environment, c.maxEnvironments)})
+	}
+	if !admitValue(c.protocols, req.Protocol, c.maxProtocols) {
+		errs = append(errs, FieldError{Field: "protocol", Message: fmt.Sprintf("%q is new and %d protocols are already ingested", req.Protocol, c.maxProtocols)})
+	}
+	if len(errs) == 0 {
+		c.environments[req.Environment] = true
+		c.protocols[req.Protocol] = true
+	}
+	return errs
+}
+
+// admitValue reports whether value is known or fits under limit
+func admitValue(seen map[string]bool, value string, limit int) bool {
+	return value == "" || limit <= 0 || seen[value] || len(seen) < limit
+}
+
+// Sanitize fills in what a producer may leave out and clears what only
+// ingest itself sets, so a producer can't vouch for its own reputation
+func Sanitize(req *models.TrafficRequest, now time.Time) {
+	if req.Timestamp.IsZero() {
+		req.Timestamp = now
+	}
+	req.Protocol = strings.ToUpper(strings.TrimSpace(req.Protocol))
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	req.Environment = strings.TrimSpace(req.Environment)
+	req.Reputation = 0
+	req.ThreatFeeds = nil
+}
+
+// Validate returns a *ValidationError listing every field of req out of
+// bounds, or nil
+func (v Validator) Validate(req models.TrafficRequest, now time.Time) error {
+	var errs []FieldError
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if req.SourceIP == "" {
+		fail("source_ip", "is required")
+	} else if !validIP(req.SourceIP) {
+		fail("source_ip", "%.64q is not an IP address", req.SourceIP)
+	}
+	if req.DestIP != "" && !validIP(req.DestIP) {
+		fail("dest_ip", "%.64q is not an IP address", req.DestIP)
+	}
+	if req.SourcePort < 0 || req.SourcePort > 65535 {
+		fail("source_port", "%d is outside 0-65535", req.SourcePort)
+	}
+	if req.DestPort < 0 || req.DestPort > 65535 {
+		fail("dest_port", "%d is outside 0-65535", req.DestPort)
+	}
+	if req.StatusCode != 0 && (req.StatusCode < 100 || req.StatusCode > 599) {
+		fail("status_code", "%d is not an HTTP status", req.StatusCode)
+	}
+	if req.TTL < 0 || req.TTL > 255 {
+		fail("ttl", "%d is outside 0-255", req.TTL)
+	}
+	for field, n := range map[string]int{
+		"bytes_sent":   req.BytesSent,
+		"bytes_recv":   req.BytesRecv,
+		"header_bytes": req.HeaderBytes,
+		"duration_ms":  req.Duration,
+		"packets":      req.Packets,
+	} {
+		if n < 0 {
+			fail(field, "must not be negative")
+		}
+	}
+	for field, rate := range map[string]float64{
+		"request_body_bps":  req.RequestBodyRate,
+		"response_read_bps": req.ResponseReadRate,
+	} {
+		if rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			fail(field, "must be a non-negative number")
+		}
+	}
+
+	for field, value := range map[string]string{
+		"id":                 req.ID,
+		"protocol":           req.Protocol,
+		"request_path":       req.RequestPath,
+		"method":             req.Method,
+		"user_agent":         req.UserAgent,
+		"referer":            req.Referer,
+		"environment":        req.Environment,
+		"dns_query_name":     req.DNSQueryName,
+		"http_version":       req.HTTPVersion,
+		"accept":             req.Accept,
+		"accept_language":    req.AcceptLanguage,
+		"header_fingerprint": req.HeaderFingerprint,
+		"tls_sni":            req.TLSServerName,
+		"ja3":                req.JA3,
+		"tls_cipher":         req.TLSCipher,
+		"country":            req.Country,
+		"as_org":             req.ASOrg,
+	} {
+		if msg := checkString(value, maxLengths[field]); msg != "" {
+			fail(field, "%s", msg)
+		}
+	}
+	// Environments and protocols name Redis keys and hash fields
+	if strings.IndexFunc(req.Environment, unicode.IsSpace) >= 0 {
+		fail("environment", "must not contain spaces")
+	} else if v.Environments != nil && req.Environment != "" && !v.Environments[req.Environment] {
+		fail("environment", "%.64q is not a configured environment", req.Environment)
+	}
+	if strings.IndexFunc(req.Protocol, unicode.IsSpace) >= 0 {
+		fail("protocol", "must not contain spaces")
+	}
+	if len(req.HeaderOrder) > maxHeaderOrder {
+		fail("header_order", "lists %d headers, more than %d", len(req.HeaderOrder), maxHeaderOrder)
+	} else {
+		for i, name := range req.HeaderOrder {
+			if msg := checkString(name, maxHeaderName); msg != "" {
+				fail(fmt.Sprintf("header_order[%d]", i), "%s", msg)
+			}
+		}
+	}
+
+	if v.MaxAge > 0 && req.Timestamp.Before(now.Add(-v.MaxAge)) {
+		fail("timestamp", "%s is more than %s ago", req.Timestamp.Format(time.RFC3339), v.MaxAge)
+	}
+	if v.MaxClockSkew > 0 && req.Timestamp.After(now.Add(v.MaxClockSkew)) {
+		fail("timestamp", "%s is more than %s in the future", req.Timestamp.Format(time.RFC3339), v.MaxClockSkew)
+	}
+
+	// Only a request otherwise valid takes up one of the capped values
+	if len(errs) == 0 && v.Cardinality != nil {
+		errs = v.Cardinality.admit(req)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	// Sorted so that a client sees the same error for the same request
+	slices.SortStableFunc(errs, func(a, b FieldError) int { return strings.Compare(a.Field, b.Field) })
+	return &ValidationError{Fields: errs}
+}
+
+// validIP reports whether s is an address in any form CanonicalIP accepts
+func validIP(s string) bool {
+	_, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	return err == nil
+}
+
+// checkString describes what is wrong with a string field, or returns ""
+func checkString(s string, maxLen int) string {
+	if len(s) > maxLen {
+		return fmt.Sprintf("is %d bytes, longer than %d", len(s), maxLen)
+	}
+	if !utf8.ValidString(s) {
+		return "is not valid UTF-8"
+	}
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		return "contains control characters"
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package ingestion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Sink receives normalized traffic records. *storage.RedisClient satisfies it.
+type Sink interface {
+	StoreTraffic(req models.TrafficRequest) error
+}
+
+// DefaultFlowLogFields is the field order of the default (version 2) VPC Flow Log format
+var DefaultFlowLogFields = []string{
+	"version", "account-id", "interface-id", "srcaddr", "dstaddr",
+	"srcport", "dstport", "protocol", "packets", "bytes",
+	"start", "end", "action", "log-status",
+}
+
+// tcpFlagSYN is the SYN bit as reported in the tcp-flags field of custom formats
+const tcpFlagSYN = 2
+
+// FlowLogParser turns VPC Flow Log lines into TrafficRequests
+type FlowLogParser struct {
+	fields      []string
+	environment string
+}
+
+func NewFlowLogParser(fields []string, environment string) *FlowLogParser {
+	if len(fields) == 0 {
+		fields = DefaultFlowLogFields
+	}
+	return &FlowLogParser{
+		fields:      fields,
+		environment: environment,
+	}
+}
+
+// IsHeader reports whether a line is the field header S3 delivery writes at the top of each file
+func IsHeader(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "version ")
+}
+
+// HeaderFields returns the field names declared by a header line
+func HeaderFields(line string) []string {
+	return strings.Fields(line)
+}
+
+// Parse normalizes a single flow log record. Records that carry no traffic
+// (NODATA/SKIPDATA) return ok=false and no error.
+func (p *FlowLogParser) Parse(line string) (req models.TrafficRequest, ok bool, err error) {
+	values := strings.Fields(line)
+	if len(values) != len(p.fields) {
+		return req, false, fmt.Errorf("expected %d fields, got %d", len(p.fields), len(values))
+	}
+
+	record := make(map[string]string, len(values))
+	for i, field := range p.fields {
+		record[field] = values[i]
+	}
+
+	if status := record["log-status"]; status == "NODATA" || status == "SKIPDATA" {
+		return req, false, nil
+	}
+
+	srcPort, _ := strconv.Atoi(record["srcport"])
+	dstPort, _ := strconv.Atoi(record["dstport"])
+	bytes, _ := strconv.Atoi(record["bytes"])
+	packets, _ := strconv.Atoi(record["packets"])
+	start, _ := strconv.ParseInt(record["start"], 10, 64)
+	end, _ := strconv.ParseInt(record["end"], 10, 64)
+
+	timestamp := time.Now()
+	if start > 0 {
+		timestamp = time.Unix(start, 0)
+	}
+
+	duration := 0
+	if end > start {
+		duration = int(end-start) * 1000
+	}
+
+	statusCode := 0
+	if record["action"] == "REJECT" {
+		statusCode = 403
+	}
+
+	req = models.TrafficRequest{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		SourceIP:    record["srcaddr"],
+		DestIP:      record["dstaddr"],
+		SourcePort:  srcPort,
+		DestPort:    dstPort,
+		Protocol:    flowProtocol(record["protocol"], record["tcp-flags"]),
+		BytesSent:   bytes,
+		Packets:     packets,
+		StatusCode:  statusCode,
+		Duration:    duration,
+		Environment: p.environment,
+	}
+
+	// tcp-flags is the OR of every flag seen in the aggregation interval
+	if flags, err := strconv.ParseUint(record["tcp-flags"], 10, 8); err == nil {
+		req.TCPFlags = uint8(flags)
+		req.TCPFlagsAggregated = true
+	}
+
+	return req, true, nil
+}
+
+// flowProtocol maps IANA protocol numbers onto the protocol names used by the detector
+func flowProtocol(number string, tcpFlags string) string {
+	switch number {
+	case "6":
+		if flags, err := strconv.Atoi(tcpFlags); err == nil && flags == tcpFlagSYN {
+			return "TCP_SYN"
+		}
+		return "TCP"
+	case "17":
+		return "UDP"
+	case "1", "58":
+		return "ICMP"
+	}
+	return "IP_" + number
+}
@@ -0,0 +1,212 @@
+package ingestion
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// cloudWatchOverlap is how far before the newest event seen each poll
+// reads again. CloudWatch Logs doesn't deliver events in timestamp order, so
+// an event stamped earlier, or in the same millisecond, may show up later.
+const cloudWatchOverlap = 5 * time.Minute
+
+// FlowLogSourceConfig describes where VPC Flow Logs are delivered. Exactly one
+// of S3URL ("s3://bucket/prefix") or LogGroup should be set.
+type FlowLogSourceConfig struct {
+	Region       string
+	S3URL        string
+	LogGroup     string
+	Fields       []string // custom log format; defaults to the v2 format
+	Environment  string
+	PollInterval time.Duration
+	// Lookback is how far before startup records are read from; 0 reads
+	// what is delivered from startup on
+	Lookback time.Duration
+}
+
+// FlowLogIngester polls S3 or CloudWatch Logs for new flow log records and
+// writes them to the sink
+type FlowLogIngester struct {
+	cfg    FlowLogSourceConfig
+	sink   Sink
+	s3     *s3Poller
+	cwl    *cloudwatchlogs.Client
+	parser *FlowLogParser
+
+	// CloudWatch progress in epoch milliseconds: where reading started and
+	// the newest event seen
+	startTime     int64
+	lastEventTime int64
+	// Events read within the overlap, by ID with their timestamp
+	seenEvents map[string]int64
+}
+
+func NewFlowLogIngester(ctx context.Context, cfg FlowLogSourceConfig, sink Sink) (*FlowLogIngester, error) {
+	if (cfg.S3URL == "") == (cfg.LogGroup == "") {
+		return nil, fmt.Errorf("exactly one of S3 URL or log group must be configured")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+
+	opts := make([]func(*config.LoadOptions) error, 0)
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	start := time.Now().Add(-max(cfg.Lookback, cfg.PollInterval)).UnixMilli()
+	ingester := &FlowLogIngester{
+		cfg:           cfg,
+		sink:          sink,
+		parser:        NewFlowLogParser(cfg.Fields, cfg.Environment),
+		startTime:     start,
+		lastEventTime: start,
+		seenEvents:    make(map[string]int64),
+	}
+
+	if cfg.S3URL != "" {
+		ingester.s3 = newS3Poller(s3.NewFromConfig(awsCfg), cfg.S3URL, time.Now().Add(-cfg.Lookback))
+	} else {
+		ingester.cwl = cloudwatchlogs.NewFromConfig(awsCfg)
+	}
+
+	return ingester, nil
+}
+
+// Run polls the configured source until the context is cancelled
+func (f *FlowLogIngester) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.cfg.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("📥 VPC Flow Log ingester started")
+
+	for {
+		var (
+			count int
+			err   error
+		)
+		if f.s3 != nil {
+			count, err = f.pollS3(ctx)
+		} else {
+			count, err = f.pollCloudWatch(ctx)
+		}
+
+		if err != nil {
+			log.Printf("Error ingesting VPC Flow Logs: %v", err)
+		} else if count > 0 {
+			log.Printf("Ingested %d VPC Flow Log records", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollS3 reads every object delivered since the last poll
+func (f *FlowLogIngester) pollS3(ctx context.Context) (int, error) {
+	return f.s3.poll(ctx, func(key string, body io.Reader) (int, error) {
+		return f.readFlowLogFile(body)
+	})
+}
+
+func (f *FlowLogIngester) readFlowLogFile(body io.Reader) (int, error) {
+	// Each delivered file starts with its own header, which may differ from
+	// the configured format if the flow log was recreated
+	parser := f.parser
+	count := 0
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if IsHeader(line) {
+			parser = NewFlowLogParser(HeaderFields(line), f.cfg.Environment)
+			continue
+		}
+		if f.ingestLine(parser, line) {
+			count++
+		}
+	}
+
+	return count, scanner.Err()
+}
+
+// pollCloudWatch reads log events from cloudWatchOverlap before the newest
+// one seen, skipping those already read
+func (f *FlowLogIngester) pollCloudWatch(ctx context.Context) (int, error) {
+	start := max(f.lastEventTime-cloudWatchOverlap.Milliseconds(), f.startTime)
+	for id, ts := range f.seenEvents {
+		if ts < start {
+			delete(f.seenEvents, id)
+		}
+	}
+
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(f.cfg.LogGroup),
+		StartTime:    aws.Int64(start),
+	}
+
+	total := 0
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(f.cwl, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		for _, event := range page.Events {
+			id, ts := aws.ToString(event.EventId), aws.ToInt64(event.Timestamp)
+			if _, seen := f.seenEvents[id]; seen {
+				continue
+			}
+			f.seenEvents[id] = ts
+
+			if f.ingestLine(f.parser, aws.ToString(event.Message)) {
+				total++
+			}
+			if ts > f.lastEventTime {
+				f.lastEventTime = ts
+			}
+		}
+	}
+
+	return total, nil
+}
+
+func (f *FlowLogIngester) ingestLine(parser *FlowLogParser, line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+
+	req, ok, err := parser.Parse(line)
+	if err != nil {
+		log.Printf("Skipping malformed flow log record: %v", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	if err := f.sink.StoreTraffic(req); err != nil {
+		log.Printf("Error storing flow log record: %v", err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,323 @@
+// Package intel fetches threat intelligence blocklists in the background
+// and scores source addresses by the feeds that list them
+package intel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// customFeedScore is the reputation of the addresses other lists name
+	customFeedScore = 80
+	// maxFeedSize bounds a downloaded feed
+	maxFeedSize = 64 << 20
+
+	kindList      = "list"
+	kindAbuseIPDB = "abuseipdb"
+)
+
+// Feed is a source of known-bad addresses
+type Feed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Score is the reputation of every address the feed lists; AbuseIPDB
+	// scores each address itself
+	Score int `json:"score"`
+
+	kind string
+	key  string
+}
+
+// builtinFeeds are the public lists -intel-feeds knows by name
+var builtinFeeds = map[string]Feed{
+	// Netblocks hijacked or leased by professional spam and cyber-crime operations
+	"spamhaus_drop":   {Name: "spamhaus_drop", URL: "https://www.spamhaus.org/drop/drop_v4.json", Score: 100, kind: kindList},
+	"spamhaus_dropv6": {Name: "spamhaus_dropv6", URL: "https://www.spamhaus.org/drop/drop_v6.json", Score: 100, kind: kindList},
+	// Attacks, malware and abuse seen over the last days, with few false positives
+	"firehol_level1": {Name: "firehol_level1", URL: "https://raw.githubusercontent.com/firehol/blocklist-ipsets/master/firehol_level1.netset", Score: 90, kind: kindList},
+}
+
+// ParseFeeds reads a comma-separated list of built-in feed names, e.g.
+// "spamhaus_drop,firehol_level1", or name=url for other lists in the same
+// formats: one address or CIDR per line, or Spamhaus JSON lines
+func ParseFeeds(spec string) ([]Feed, error) {
+	feeds := make([]Feed, 0)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if name, url, ok := strings.Cut(part, "="); ok {
+			if name == "" || !strings.HasPrefix(url, "http") {
+				return nil, fmt.Errorf("intel feed %q is not name=url", part)
+			}
+			feeds = append(feeds, Feed{Name: name, URL: url, Score: customFeedScore, kind: kindList})
+			continue
+		}
+
+		feed, ok := builtinFeeds[part]
+		if !ok {
+			names := make([]string, 0, len(builtinFeeds))
+			for name := range builtinFeeds {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown intel feed %q, use %s or name=url", part, strings.Join(names, ", "))
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
+
+// AbuseIPDB is the feed of AbuseIPDB's blacklist: the addresses reported
+// with at least minConfidence abuse confidence, scored by it
+func AbuseIPDB(key string, minConfidence int) Feed {
+	return Feed{
+		Name: "abuseipdb",
+		URL:  fmt.Sprintf("https://api.abuseipdb.com/api/v2/blacklist?confidenceMinimum=%d&limit=10000", minConfidence),
+		kind: kindAbuseIPDB,
+		key:  key,
+	}
+}
+
+// FeedStatus is the outcome of a feed's last fetch
+type FeedStatus struct {
+	Name      string    `json:"name"`
+	Entries   int       `json:"entries"`
+	FetchedAt time.Time `json:"fetched_at,omitempty"` // last successful fetch
+	CheckedAt time.Time `json:"checked_at,omitempty"` // last attempt
+	Error     string    `json:"error,omitempty"`
+}
+
+// feedList is what a feed listed at its last successful fetch
+type feedList struct {
+	set    *iplist.Set
+	scores map[string]int // CIDR -> score, for feeds that score each entry
+}
+
+// Intel keeps the feeds' lists and answers reputation lookups. A feed
+// whose refresh fails keeps the list it fetched last.
+type Intel struct {
+	feeds  []Feed
+	client *http.Client
+
+	mu     sync.RWMutex
+	lists  map[string]feedList
+	status map[string]FeedStatus
+}
+
+func New(feeds []Feed) *Intel {
+	status := make(map[string]FeedStatus, len(feeds))
+	for _, feed := range feeds {
+		status[feed.Name] = FeedStatus{Name: feed.Name}
+	}
+	return &Intel{
+		feeds:  feeds,
+		client: &http.Client{Timeout: time.Minute},
+		lists:  make(map[string]feedList),
+		status: status,
+	}
+}
+
+// Run refreshes the feeds now and then every interval until ctx is done
+func (i *Intel) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		i.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches every feed once
+func (i *Intel) Refresh(ctx context.Context) {
+	for _, feed := range i.feeds {
+		entries, scores, err := i.fetch(ctx, feed)
+
+		i.mu.Lock()
+		status := i.status[feed.Name]
+		status.CheckedAt = time.Now()
+		if err != nil {
+			status.Error = err.Error()
+			log.Printf("Error fetching intel feed %s: %v", feed.Name, err)
+		} else {
+			status.Error = ""
+			status.FetchedAt = status.CheckedAt
+			status.Entries = len(entries)
+			i.lists[feed.Name] = feedList{set: iplist.NewSet(entries), scores: scores}
+		}
+		i.status[feed.Name] = status
+		i.mu.Unlock()
+	}
+}
+
+// Lookup returns the reputation of an address: the highest score of the
+// feeds listing it, and their names
+func (i *Intel) Lookup(ip string) (models.SourceReputation, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return models.SourceReputation{}, false
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	rep := models.SourceReputation{IP: ip}
+	for _, feed := range i.feeds {
+		list, ok := i.lists[feed.Name]
+		if !ok {
+			continue
+		}
+		entry, ok := list.set.Lookup(addr)
+		if !ok {
+			continue
+		}
+		score, ok := list.scores[entry.CIDR]
+		if !ok {
+			score = feed.Score
+		}
+		rep.Score = max(rep.Score, score)
+		rep.Feeds = append(rep.Feeds, feed.Name)
+	}
+	return rep, len(rep.Feeds) > 0
+}
+
+// Status reports every feed's last fetch, in configuration order
+func (i *Intel) Status() []FeedStatus {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	status := make([]FeedStatus, 0, len(i.feeds))
+	for _, feed := range i.feeds {
+		status = append(status, i.status[feed.Name])
+	}
+	return status
+}
+
+// fetch downloads and parses one feed
+func (i *Intel) fetch(ctx context.Context, feed Feed) ([]models.IPListEntry, map[string]int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if feed.kind == kindAbuseIPDB {
+		req.Header.Set("Key", feed.key)
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	body := io.LimitReader(resp.Body, maxFeedSize)
+	if feed.kind == kindAbuseIPDB {
+		return parseAbuseIPDB(body)
+	}
+	entries, err := parseList(body, feed.Name)
+	return entries, nil, err
+}
+
+// parseList reads a list of one address or CIDR per line, with # or ;
+// comments, or Spamhaus's JSON lines of {"cidr": ...}
+func parseList(r io.Reader, name string) ([]models.IPListEntry, error) {
+	entries := make([]models.IPListEntry, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		var cidr string
+		if strings.HasPrefix(line, "{") {
+			var record struct {
+				CIDR string `json:"cidr"`
+			}
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				continue
+			}
+			cidr = record.CIDR
+		} else {
+			if i := strings.IndexAny(line, "#;"); i >= 0 {
+				line = line[:i]
+			}
+			if fields := strings.Fields(line); len(fields) > 0 {
+				cidr = fields[0]
+			}
+		}
+
+		if entry, ok := listEntry(cidr, name); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parseAbuseIPDB reads the blacklist endpoint's JSON response
+func parseAbuseIPDB(r io.Reader) ([]models.IPListEntry, map[string]int, error) {
+	var response struct {
+		Data []struct {
+			IPAddress            string `json:"ipAddress"`
+			AbuseConfidenceScore int    `json:"abuseConfidenceScore"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]models.IPListEntry, 0, len(response.Data))
+	scores := make(map[string]int, len(response.Data))
+	for _, record := range response.Data {
+		entry, ok := listEntry(record.IPAddress, "abuseipdb")
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry)
+		scores[entry.CIDR] = record.AbuseConfidenceScore
+	}
+	return entries, scores, nil
+}
+
+// listEntry turns a listed address or CIDR into an entry. Reserved ranges
+// (private, loopback, link-local, multicast), which lists such as
+// FireHOL's include as bogons, are skipped: they would brand internal
+// clients as attackers.
+func listEntry(cidr, feed string) (models.IPListEntry, bool) {
+	if cidr == "" {
+		return models.IPListEntry{}, false
+	}
+	prefix, err := iplist.ParseCIDR(cidr)
+	if err != nil {
+		return models.IPListEntry{}, false
+	}
+	addr := prefix.Addr()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return models.IPListEntry{}, false
+	}
+	return models.IPListEntry{CIDR: prefix.String(), Comment: feed}, true
+}
@@ -0,0 +1,166 @@
+// Package iplist matches addresses against the allowlist of trusted
+// sources and the denylist of known-bad ones
+package iplist
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// The lists
+const (
+	// Allow holds trusted sources: never counted toward attacks, never blocked
+	Allow = "allow"
+	// Deny holds known-bad sources: always flagged and always blocked
+	Deny = "deny"
+)
+
+// Valid reports whether name is one of the lists
+func Valid(name string) bool {
+	return name == Allow || name == Deny
+}
+
+// ParseCIDR parses a CIDR, or a single address as its host prefix, with
+// the host bits masked and IPv4-mapped addresses unmapped
+func ParseCIDR(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address or CIDR %q", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Set is one list's CIDRs, indexed by prefix length so a lookup costs one
+// map probe per distinct length however long the list. A nil Set is empty.
+type Set struct {
+	entries []models.IPListEntry
+	byBits  map[int]map[netip.Prefix]int // prefix length -> prefix -> entry index
+	lengths []int                        // distinct prefix lengths, longest first
+}
+
+// NewSet indexes a list's entries; entries that don't parse are skipped
+func NewSet(entries []models.IPListEntry) *Set {
+	s := &Set{byBits: make(map[int]map[netip.Prefix]int)}
+	for _, entry := range entries {
+		prefix, err := ParseCIDR(entry.CIDR)
+		if err != nil {
+			continue
+		}
+		entry.CIDR = prefix.String()
+
+		bits := prefix.Bits()
+		if prefix.Addr().Is4() {
+			bits += 96
+		}
+		if s.byBits[bits] == nil {
+			s.byBits[bits] = make(map[netip.Prefix]int)
+			s.lengths = append(s.lengths, bits)
+		}
+		s.byBits[bits][prefix] = len(s.entries)
+		s.entries = append(s.entries, entry)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(s.lengths)))
+	return s
+}
+
+// Len returns the number of entries
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.entries)
+}
+
+// Entries returns the list's entries in the order they were given
+func (s *Set) Entries() []models.IPListEntry {
+	if s == nil {
+		return nil
+	}
+	return s.entries
+}
+
+// Lookup returns the most specific entry containing an address
+func (s *Set) Lookup(addr netip.Addr) (models.IPListEntry, bool) {
+	if s.Len() == 0 || !addr.IsValid() {
+		return models.IPListEntry{}, false
+	}
+
+	addr = addr.Unmap()
+	for _, bits := range s.lengths {
+		width := bits
+		if addr.Is4() {
+			if width < 96 {
+				continue
+			}
+			width -= 96
+		}
+		if width > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(width)
+		if err != nil {
+			continue
+		}
+		if i, ok := s.byBits[bits][prefix]; ok {
+			return s.entries[i], true
+		}
+	}
+	return models.IPListEntry{}, false
+}
+
+// Contains reports whether an address is on the list
+func (s *Set) Contains(addr netip.Addr) bool {
+	_, ok := s.Lookup(addr)
+	return ok
+}
+
+// ContainsIP is Contains for an address in text form; malformed addresses
+// are never on the list
+func (s *Set) ContainsIP(ip string) bool {
+	if s.Len() == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return s.Contains(addr)
+}
+
+// Overlaps reports whether any address of a target, an address or a CIDR,
+// is on the list
+func (s *Set) Overlaps(target string) bool {
+	if s.Len() == 0 {
+		return false
+	}
+	prefix, err := ParseCIDR(target)
+	if err != nil {
+		return false
+	}
+	if s.Contains(prefix.Addr()) {
+		return true
+	}
+	for _, entry := range s.entries {
+		if listed, err := netip.ParsePrefix(entry.CIDR); err == nil && prefix.Overlaps(listed) {
+			return true
+		}
+	}
+	return false
+}
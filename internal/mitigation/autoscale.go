@@ -0,0 +1,351 @@
+package mitigation
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Scaler changes the capacity of the protected service
+type Scaler interface {
+	Name() string
+	// Capacity returns the current capacity floor of the service
+	Capacity(ctx context.Context) (int, error)
+	// SetCapacity changes the capacity floor of the service
+	SetCapacity(ctx context.Context, capacity int) error
+}
+
+// ApplicationLayerAttacks are the attack types that can be absorbed by adding
+// capacity instead of blocking sources
+var ApplicationLayerAttacks = map[string]bool{
+	"HTTP_FLOOD": true,
+	"SLOWLORIS":  true,
+}
+
+// isApplicationLayer reports whether any of an attack's types can be absorbed
+func isApplicationLayer(attack models.Attack) bool {
+	for _, attackType := range attack.Types() {
+		if ApplicationLayerAttacks[attackType] {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoscalePolicy decides when an attack warrants scaling
+type AutoscalePolicy struct {
+	MinConfidence float64
+	// Capacity is the floor the service is raised to while absorbing an attack
+	Capacity int
+	// Cooldown stops repeated detections of the same attack from rescaling
+	Cooldown time.Duration
+}
+
+// AutoscaleHook scales the protected service up while application-layer
+// attacks are being absorbed rather than blocked, and restores the previous
+// capacity once the last of them has ended
+type AutoscaleHook struct {
+	scaler Scaler
+	policy AutoscalePolicy
+
+	mu         sync.Mutex
+	previous   int
+	scaled     bool
+	lastScaled time.Time
+	absorbing  map[string]bool // IDs of the attacks the capacity is held for
+}
+
+func NewAutoscaleHook(scaler Scaler, policy AutoscalePolicy) *AutoscaleHook {
+	if policy.Cooldown <= 0 {
+		policy.Cooldown = 5 * time.Minute
+	}
+	return &AutoscaleHook{
+		scaler:    scaler,
+		policy:    policy,
+		absorbing: make(map[string]bool),
+	}
+}
+
+// HandleAttack scales up for an unmitigated application-layer attack, or
+// a multi-vector attack with an application-layer vector. It returns a
+// timeline event when a scaling action was taken.
+func (h *AutoscaleHook) HandleAttack(ctx context.Context, attack models.Attack) (*models.TimelineEvent, error) {
+	if !isApplicationLayer(attack) || attack.Mitigated || attack.Confidence < h.policy.MinConfidence {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Capacity raised for an earlier attack is held until this one ends too
+	if h.scaled {
+		h.absorbing[attack.ID] = true
+	}
+	if h.scaled && time.Since(h.lastScaled) < h.policy.Cooldown {
+		return nil, nil
+	}
+
+	current, err := h.scaler.Capacity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading capacity: %w", h.scaler.Name(), err)
+	}
+	if current >= h.policy.Capacity {
+		return nil, nil
+	}
+
+	if err := h.scaler.SetCapacity(ctx, h.policy.Capacity); err != nil {
+		return nil, fmt.Errorf("%s: scaling to %d: %w", h.scaler.Name(), h.policy.Capacity, err)
+	}
+
+	if !h.scaled {
+		h.previous = current
+	}
+	h.scaled = true
+	h.lastScaled = time.Now()
+	h.absorbing[attack.ID] = true
+
+	return &models.TimelineEvent{
+		Timestamp: h.lastScaled,
+		Type:      "SCALED",
+		Message:   fmt.Sprintf("Scaled %s from %d to %d to absorb %s", h.scaler.Name(), current, h.policy.Capacity, attack.Type),
+		Actor:     "autoscaler",
+	}, nil
+}
+
+// Merge carries the capacity held for attack sourceID over to targetID,
+// which an operator merged it into
+func (h *AutoscaleHook) Merge(targetID, sourceID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.absorbing[sourceID] {
+		delete(h.absorbing, sourceID)
+		h.absorbing[targetID] = true
+	}
+}
+
+// Release is called when an attack ends. Once no attack the capacity is
+// held for is left, it restores the capacity the service had before the
+// first scale-up.
+func (h *AutoscaleHook) Release(ctx context.Context, attackID string) (*models.TimelineEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.scaled || !h.absorbing[attackID] {
+		return nil, nil
+	}
+	delete(h.absorbing, attackID)
+	if len(h.absorbing) > 0 {
+		return nil, nil
+	}
+
+	if err := h.scaler.SetCapacity(ctx, h.previous); err != nil {
+		return nil, fmt.Errorf("%s: restoring capacity %d: %w", h.scaler.Name(), h.previous, err)
+	}
+	h.scaled = false
+
+	return &models.TimelineEvent{
+		Timestamp: time.Now(),
+		Type:      "SCALED",
+		Message:   fmt.Sprintf("Restored %s capacity to %d", h.scaler.Name(), h.previous),
+		Actor:     "autoscaler",
+	}, nil
+}
+
+// AutoscaleState is the replicable state of an AutoscaleHook, so the next
+// leader restores the capacity once the attacks it was raised for end
+type AutoscaleState struct {
+	Previous   int       `json:"previous"`
+	Scaled     bool      `json:"scaled"`
+	LastScaled time.Time `json:"last_scaled"`
+	Absorbing  []string  `json:"absorbing,omitempty"`
+}
+
+// Snapshot copies the capacity held and the attacks it is held for
+func (h *AutoscaleHook) Snapshot() AutoscaleState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := AutoscaleState{Previous: h.previous, Scaled: h.scaled, LastScaled: h.lastScaled}
+	for id := range h.absorbing {
+		state.Absorbing = append(state.Absorbing, id)
+	}
+	return state
+}
+
+// Restore replaces the hook's state with a snapshot taken by the previous leader
+func (h *AutoscaleHook) Restore(state AutoscaleState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.previous = state.Previous
+	h.scaled = state.Scaled
+	h.lastScaled = state.LastScaled
+	h.absorbing = make(map[string]bool, len(state.Absorbing))
+	for _, id := range state.Absorbing {
+		h.absorbing[id] = true
+	}
+}
+
+// KubernetesHPAScaler raises a HorizontalPodAutoscaler's minReplicas using the
+// in-cluster service account
+type KubernetesHPAScaler struct {
+	namespace string
+	name      string
+	apiServer string
+	token     string
+	client    *http.Client
+}
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+func NewKubernetesHPAScaler(namespace, name string) (*KubernetesHPAScaler, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running inside Kubernetes")
+	}
+
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("reading service account token: %w", err)
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return &KubernetesHPAScaler{
+		namespace: namespace,
+		name:      name,
+		apiServer: fmt.Sprintf("https://%s:%s", host, port),
+		token:     string(token),
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+func (k *KubernetesHPAScaler) Name() string {
+	return fmt.Sprintf("hpa/%s/%s", k.namespace, k.name)
+}
+
+func (k *KubernetesHPAScaler) url() string {
+	return fmt.Sprintf("%s/apis/autoscaling/v2/namespaces/%s/horizontalpodautoscalers/%s", k.apiServer, k.namespace, k.name)
+}
+
+func (k *KubernetesHPAScaler) Capacity(ctx context.Context) (int, error) {
+	var hpa struct {
+		Spec struct {
+			MinReplicas *int `json:"minReplicas"`
+		} `json:"spec"`
+	}
+	if err := k.do(ctx, http.MethodGet, "", nil, &hpa); err != nil {
+		return 0, err
+	}
+	if hpa.Spec.MinReplicas == nil {
+		// The API default when unset
+		return 1, nil
+	}
+	return *hpa.Spec.MinReplicas, nil
+}
+
+func (k *KubernetesHPAScaler) SetCapacity(ctx context.Context, capacity int) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"minReplicas": capacity},
+	})
+	if err != nil {
+		return err
+	}
+	return k.do(ctx, http.MethodPatch, "application/merge-patch+json", patch, nil)
+}
+
+func (k *KubernetesHPAScaler) do(ctx context.Context, method, contentType string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, k.url(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kubernetes API returned %d: %s", resp.StatusCode, msg)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// ASGScaler raises an EC2 Auto Scaling group's desired capacity
+type ASGScaler struct {
+	group  string
+	client *autoscaling.Client
+}
+
+func NewASGScaler(ctx context.Context, region, group string) (*ASGScaler, error) {
+	opts := make([]func(*config.LoadOptions) error, 0)
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &ASGScaler{
+		group:  group,
+		client: autoscaling.NewFromConfig(awsCfg),
+	}, nil
+}
+
+func (a *ASGScaler) Name() string {
+	return "asg/" + a.group
+}
+
+func (a *ASGScaler) Capacity(ctx context.Context) (int, error) {
+	out, err := a.client.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{a.group},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(out.AutoScalingGroups) == 0 {
+		return 0, fmt.Errorf("auto scaling group %s not found", a.group)
+	}
+	return int(aws.ToInt32(out.AutoScalingGroups[0].DesiredCapacity)), nil
+}
+
+func (a *ASGScaler) SetCapacity(ctx context.Context, capacity int) error {
+	_, err := a.client.SetDesiredCapacity(ctx, &autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String(a.group),
+		DesiredCapacity:      aws.Int32(int32(capacity)),
+		HonorCooldown:        aws.Bool(false),
+	})
+	return err
+}
@@ -0,0 +1,321 @@
+package mitigation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/wafv2"
+	"github.com/aws/aws-sdk-go-v2/service/wafv2/types"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// wafMaxAddresses is the most addresses an AWS WAF IP set holds
+	wafMaxAddresses = 10000
+	// wafLockRetries bounds the retries of an update racing another writer
+	wafLockRetries = 3
+)
+
+// wafIPSet is one IP set, of one address family
+type wafIPSet struct {
+	name string
+	id   string
+}
+
+// AWSWAFExecutor enforces BLOCK actions by keeping attacker CIDRs in AWS
+// WAF IP sets, which a web ACL rule blocks. IP sets can only be replaced
+// whole, within tight API rate limits, so changes are queued and written
+// in one update per set every flush. Entries are dropped once their action
+// expires, whether or not the engine lifts them.
+type AWSWAFExecutor struct {
+	client *wafv2.Client
+	scope  types.Scope
+	ipv4   *wafIPSet
+	ipv6   *wafIPSet
+
+	mu      sync.Mutex
+	pending map[string]bool // target -> add (true) or remove (false)
+	expires map[string]time.Time
+}
+
+// NewAWSWAFExecutor checks the IP sets, each given as name/id and either
+// optional, exist in the scope (REGIONAL or CLOUDFRONT) and hold their
+// address family
+func NewAWSWAFExecutor(ctx context.Context, region, scope, ipv4Set, ipv6Set string) (*AWSWAFExecutor, error) {
+	opts := make([]func(*config.LoadOptions) error, 0)
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	w := &AWSWAFExecutor{
+		client:  wafv2.NewFromConfig(awsCfg),
+		scope:   types.Scope(strings.ToUpper(scope)),
+		pending: make(map[string]bool),
+		expires: make(map[string]time.Time),
+	}
+	if w.scope != types.ScopeRegional && w.scope != types.ScopeCloudfront {
+		return nil, fmt.Errorf("aws-waf: unknown scope %q, expected REGIONAL or CLOUDFRONT", scope)
+	}
+
+	for _, set := range []struct {
+		spec    string
+		version types.IPAddressVersion
+		into    **wafIPSet
+	}{
+		{ipv4Set, types.IPAddressVersionIpv4, &w.ipv4},
+		{ipv6Set, types.IPAddressVersionIpv6, &w.ipv6},
+	} {
+		if set.spec == "" {
+			continue
+		}
+		name, id, ok := strings.Cut(set.spec, "/")
+		if !ok || name == "" || id == "" {
+			return nil, fmt.Errorf("aws-waf: invalid IP set %q, expected name/id", set.spec)
+		}
+		ipSet := &wafIPSet{name: name, id: id}
+		out, err := w.getIPSet(ctx, ipSet)
+		if err != nil {
+			return nil, err
+		}
+		if out.IPSet.IPAddressVersion != set.version {
+			return nil, fmt.Errorf("aws-waf: IP set %s holds %s addresses, expected %s", name, out.IPSet.IPAddressVersion, set.version)
+		}
+		*set.into = ipSet
+	}
+	if w.ipv4 == nil && w.ipv6 == nil {
+		return nil, fmt.Errorf("aws-waf: no IP set given")
+	}
+	return w, nil
+}
+
+func (w *AWSWAFExecutor) Name() string {
+	return "aws-waf"
+}
+
+// Handles accepts BLOCK actions on the address families with an IP set
+func (w *AWSWAFExecutor) Handles(action models.MitigationAction) bool {
+	if action.Type != ActionBlock {
+		return false
+	}
+	prefix, ok := firewallTarget(action.Target)
+	return ok && w.setFor(prefix) != nil
+}
+
+// Apply queues the target to be added at the next flush
+func (w *AWSWAFExecutor) Apply(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok || w.setFor(prefix) == nil {
+		return fmt.Errorf("aws-waf: no IP set for %q", action.Target)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	target := formatTarget(prefix)
+	w.pending[target] = true
+	if action.ExpiresAt.IsZero() {
+		delete(w.expires, target)
+	} else {
+		w.expires[target] = action.ExpiresAt
+	}
+	return nil
+}
+
+// Remove queues the target to be removed at the next flush
+func (w *AWSWAFExecutor) Remove(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok || w.setFor(prefix) == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	target := formatTarget(prefix)
+	w.pending[target] = false
+	delete(w.expires, target)
+	return nil
+}
+
+// List returns the addresses in the IP sets as AWS has them, without the
+// changes still queued. The sets belong to the dashboard, so everything in
+// them is its own.
+func (w *AWSWAFExecutor) List() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	targets := make([]string, 0)
+	for _, set := range []*wafIPSet{w.ipv4, w.ipv6} {
+		if set == nil {
+			continue
+		}
+		out, err := w.getIPSet(ctx, set)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range out.IPSet.Addresses {
+			if prefix, ok := firewallTarget(address); ok {
+				targets = append(targets, formatTarget(prefix))
+			}
+		}
+	}
+	return targets, nil
+}
+
+// Run flushes the queued changes every interval until the context is done
+func (w *AWSWAFExecutor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := w.Flush(ctx); err != nil {
+			log.Printf("Error updating AWS WAF IP sets: %v", err)
+		}
+	}
+}
+
+// Flush writes the queued changes, and drops expired entries, in one
+// update per IP set. Changes that fail stay queued for the next flush.
+func (w *AWSWAFExecutor) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]bool)
+	now := time.Now()
+	for target, expiresAt := range w.expires {
+		if now.After(expiresAt) {
+			pending[target] = false
+			delete(w.expires, target)
+		}
+	}
+	w.mu.Unlock()
+
+	var errs []error
+	for _, set := range []*wafIPSet{w.ipv4, w.ipv6} {
+		if set == nil {
+			continue
+		}
+		changes := make(map[string]bool)
+		for target, add := range pending {
+			if prefix, _ := firewallTarget(target); w.setFor(prefix) == set {
+				changes[target] = add
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		if err := w.update(ctx, set, changes); err != nil {
+			errs = append(errs, err)
+			w.requeue(changes)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// update applies changes to one IP set, retrying when another writer
+// changed it in between
+func (w *AWSWAFExecutor) update(ctx context.Context, set *wafIPSet, changes map[string]bool) error {
+	for attempt := 0; ; attempt++ {
+		out, err := w.getIPSet(ctx, set)
+		if err != nil {
+			return err
+		}
+
+		addresses := make(map[string]bool, len(out.IPSet.Addresses)+len(changes))
+		for _, address := range out.IPSet.Addresses {
+			addresses[address] = true
+		}
+		added, removed := 0, 0
+		for target, add := range changes {
+			prefix, _ := firewallTarget(target)
+			address := prefix.String()
+			switch {
+			case add && !addresses[address]:
+				if len(addresses) >= wafMaxAddresses {
+					log.Printf("⚠️  AWS WAF IP set %s is full at %d addresses; not blocking %s", set.name, wafMaxAddresses, target)
+					continue
+				}
+				addresses[address] = true
+				added++
+			case !add && addresses[address]:
+				delete(addresses, address)
+				removed++
+			}
+		}
+		if added == 0 && removed == 0 {
+			return nil
+		}
+
+		list := make([]string, 0, len(addresses))
+		for address := range addresses {
+			list = append(list, address)
+		}
+		sort.Strings(list)
+
+		_, err = w.client.UpdateIPSet(ctx, &wafv2.UpdateIPSetInput{
+			Name:      aws.String(set.name),
+			Id:        aws.String(set.id),
+			Scope:     w.scope,
+			Addresses: list,
+			LockToken: out.LockToken,
+		})
+		var conflict *types.WAFOptimisticLockException
+		if errors.As(err, &conflict) && attempt < wafLockRetries {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("aws-waf: updating IP set %s: %w", set.name, err)
+		}
+		log.Printf("🧱 AWS WAF IP set %s: %d added, %d removed, %d blocked", set.name, added, removed, len(list))
+		return nil
+	}
+}
+
+// requeue puts back changes that failed, unless newer ones replaced them
+func (w *AWSWAFExecutor) requeue(changes map[string]bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for target, add := range changes {
+		if _, ok := w.pending[target]; !ok {
+			w.pending[target] = add
+		}
+	}
+}
+
+func (w *AWSWAFExecutor) getIPSet(ctx context.Context, set *wafIPSet) (*wafv2.GetIPSetOutput, error) {
+	out, err := w.client.GetIPSet(ctx, &wafv2.GetIPSetInput{
+		Name:  aws.String(set.name),
+		Id:    aws.String(set.id),
+		Scope: w.scope,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws-waf: reading IP set %s: %w", set.name, err)
+	}
+	return out, nil
+}
+
+// setFor returns the IP set holding a prefix's address family, if any
+func (w *AWSWAFExecutor) setFor(prefix netip.Prefix) *wafIPSet {
+	if prefix.Addr().Is4() {
+		return w.ipv4
+	}
+	return w.ipv6
+}
@@ -0,0 +1,245 @@
+package mitigation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// BanListConfig configures the ban outputs; either path may be empty
+type BanListConfig struct {
+	// LogPath is appended a BAN or UNBAN line per change, for fail2ban
+	LogPath string
+	// ListPath is rewritten with every ban in force, for scripts
+	ListPath string
+	// Duration is the ban time handed out; 0 bans until the action expires
+	Duration time.Duration
+}
+
+// banEntry is one ban in force
+type banEntry struct {
+	expires time.Time // zero for never
+}
+
+// BanListExecutor enforces BLOCK actions without any privileges, by
+// writing them out for fail2ban or scripts to act on: a log of BAN and
+// UNBAN lines, and a list of the bans in force, one per line with its
+// expiry, replaced atomically on every change
+type BanListExecutor struct {
+	cfg BanListConfig
+
+	mu   sync.Mutex
+	bans map[string]banEntry
+}
+
+// NewBanListExecutor picks up the bans of an existing ban list, so they
+// survive restarts
+func NewBanListExecutor(cfg BanListConfig) (*BanListExecutor, error) {
+	if cfg.LogPath == "" && cfg.ListPath == "" {
+		return nil, fmt.Errorf("banlist: no ban log or ban list path given")
+	}
+	b := &BanListExecutor{cfg: cfg, bans: make(map[string]banEntry)}
+	if cfg.ListPath != "" {
+		if err := b.load(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.LogPath != "" {
+		// Fail early on a path that can't be written
+		f, err := os.OpenFile(cfg.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("banlist: %w", err)
+		}
+		f.Close()
+	}
+	return b, nil
+}
+
+func (b *BanListExecutor) Name() string {
+	return "banlist"
+}
+
+// Handles accepts BLOCK actions on addresses and prefixes
+func (b *BanListExecutor) Handles(action models.MitigationAction) bool {
+	if action.Type != ActionBlock {
+		return false
+	}
+	_, ok := firewallTarget(action.Target)
+	return ok
+}
+
+// Apply bans the target for the configured duration, or until the action
+// expires. Banning a target again pushes its expiry back.
+func (b *BanListExecutor) Apply(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return fmt.Errorf("banlist: invalid target %q", action.Target)
+	}
+	target := formatTarget(prefix)
+
+	now := time.Now()
+	expires := action.ExpiresAt
+	if b.cfg.Duration > 0 {
+		expires = now.Add(b.cfg.Duration)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bans[target] = banEntry{expires: expires}
+	banTime := -1 // fail2ban's permanent ban
+	if !expires.IsZero() {
+		banTime = int(math.Ceil(expires.Sub(now).Seconds()))
+	}
+	line := fmt.Sprintf("BAN %s bantime=%d", target, banTime)
+	if action.AttackID != "" {
+		line += " attack=" + action.AttackID
+	}
+	if err := b.logLine(now, line); err != nil {
+		return err
+	}
+	return b.writeList(now)
+}
+
+// Remove lifts the target's ban; a target not banned is left alone
+func (b *BanListExecutor) Remove(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return nil
+	}
+	target := formatTarget(prefix)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.bans[target]; !ok {
+		return nil
+	}
+	delete(b.bans, target)
+	now := time.Now()
+	if err := b.logLine(now, "UNBAN "+target); err != nil {
+		return err
+	}
+	return b.writeList(now)
+}
+
+// List returns the banned targets. Bans handed out for a shorter time
+// than their action are still listed once over, so they aren't renewed.
+func (b *BanListExecutor) List() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	targets := make([]string, 0, len(b.bans))
+	for target := range b.bans {
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// logLine appends a timestamped line to the ban log
+func (b *BanListExecutor) logLine(now time.Time, line string) error {
+	if b.cfg.LogPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(b.cfg.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("banlist: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%s ddos-dashboard: %s\n", now.UTC().Format(time.RFC3339), line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("banlist: %w", err)
+	}
+	return nil
+}
+
+// writeList replaces the ban list with the bans not yet expired, so
+// readers never see it half written
+func (b *BanListExecutor) writeList(now time.Time) error {
+	if b.cfg.ListPath == "" {
+		return nil
+	}
+
+	lines := make([]string, 0, len(b.bans))
+	for target, ban := range b.bans {
+		switch {
+		case ban.expires.IsZero():
+			lines = append(lines, target+" never")
+		case ban.expires.After(now):
+			lines = append(lines, target+" "+ban.expires.UTC().Format(time.RFC3339))
+		}
+	}
+	sort.Strings(lines)
+
+	f, err := os.CreateTemp(filepath.Dir(b.cfg.ListPath), ".banlist-*")
+	if err != nil {
+		return fmt.Errorf("banlist: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# ddos-dashboard ban list, updated %s\n# target expires\n", now.UTC().Format(time.RFC3339))
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), b.cfg.ListPath)
+	}
+	if err != nil {
+		return fmt.Errorf("banlist: %w", err)
+	}
+	return nil
+}
+
+// load reads the bans not yet expired from the ban list
+func (b *BanListExecutor) load() error {
+	f, err := os.Open(b.cfg.ListPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("banlist: %w", err)
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		prefix, ok := firewallTarget(fields[0])
+		if !ok {
+			continue
+		}
+		var ban banEntry
+		if fields[1] != "never" {
+			expires, err := time.Parse(time.RFC3339, fields[1])
+			if err != nil || !expires.After(now) {
+				continue
+			}
+			ban.expires = expires
+		}
+		b.bans[formatTarget(prefix)] = ban
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,85 @@
+package mitigation
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Blocking strategies
+const (
+	// StrategyBlock drops the attacking source IPs
+	StrategyBlock = "BLOCK"
+	// StrategyRTBH announces the victim for remotely triggered black hole
+	// routing, dropping its traffic in the upstream network. Forged sources
+	// are new on every packet, so blocking them achieves nothing.
+	StrategyRTBH = "RTBH"
+)
+
+// RecommendBlocking chooses between blocking the sources (by prefix when
+// they cluster) and blackholing the victim, depending on whether the
+// attack's sources are likely spoofed. Sources and prefixes overlapping
+// the allowlist are never recommended for blocking.
+func RecommendBlocking(attack models.Attack, allow *iplist.Set) models.BlockingRecommendation {
+	if attack.Spoofing == nil {
+		rec := models.BlockingRecommendation{
+			Strategy: StrategyBlock,
+			Targets:  attack.SourceIPs,
+			Reason:   "no sign of spoofing; block the sources individually",
+		}
+		if len(attack.SourcePrefixes) > 0 {
+			prefixes := make([]string, 0, len(attack.SourcePrefixes))
+			for _, prefix := range attack.SourcePrefixes {
+				prefixes = append(prefixes, prefix.Prefix)
+			}
+			rec.Targets = prefixes
+			rec.Reason = "no sign of spoofing; most sources share these prefixes, block them"
+		}
+
+		targets := make([]string, 0, len(rec.Targets))
+		for _, target := range rec.Targets {
+			if !allow.Overlaps(target) {
+				targets = append(targets, target)
+			}
+		}
+		if trusted := len(rec.Targets) - len(targets); trusted > 0 {
+			rec.Reason += fmt.Sprintf("; %d allowlisted left out", trusted)
+		}
+		rec.Targets = targets
+		return rec
+	}
+
+	targets := make([]string, 0, len(attack.TargetIPs))
+	for _, target := range attack.TargetIPs {
+		if prefix, ok := hostPrefix(target); ok {
+			targets = append(targets, prefix)
+		}
+	}
+
+	rec := models.BlockingRecommendation{
+		Strategy: StrategyRTBH,
+		Targets:  targets,
+		Reason:   fmt.Sprintf("sources likely spoofed: %s", strings.Join(attack.Spoofing.Indicators, "; ")),
+	}
+	if len(targets) == 0 {
+		rec.Reason += "; victim unknown, identify it before announcing a blackhole"
+	}
+	return rec
+}
+
+// hostPrefix turns a victim address into the host route announced for
+// RTBH; prefixes (carpet bombing victims) are kept as they are
+func hostPrefix(target string) (string, bool) {
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		return prefix.Masked().String(), true
+	}
+	addr, err := netip.ParseAddr(target)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()).String(), true
+}
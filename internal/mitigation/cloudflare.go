@@ -0,0 +1,292 @@
+package mitigation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	cloudflareAPI = "https://api.cloudflare.com/client/v4"
+	// cloudflareNotes marks the access rules the dashboard owns
+	cloudflareNotes = "ddos-dashboard"
+	// cloudflarePageSize is the most rules Cloudflare lists per page
+	cloudflarePageSize = 1000
+)
+
+// CloudflareConfig configures IP Access Rules on a zone or, without a zone,
+// on every zone of an account
+type CloudflareConfig struct {
+	APIToken  string
+	ZoneID    string
+	AccountID string
+	// MinConfidence is the attack confidence from which its actions are
+	// pushed to Cloudflare
+	MinConfidence float64
+	// BaseURL overrides the Cloudflare API endpoint
+	BaseURL string
+}
+
+// CloudflareExecutor enforces BLOCK and RATE_LIMIT actions at Cloudflare's
+// edge as IP Access Rules: blocked sources are blocked, rate limited ones
+// get a managed challenge. Actions are only pushed for attacks detected
+// with enough confidence, and lifted as soon as the attack ends.
+type CloudflareExecutor struct {
+	cfg    CloudflareConfig
+	rules  string // the access rules endpoint
+	client *http.Client
+}
+
+func NewCloudflareExecutor(cfg CloudflareConfig) (*CloudflareExecutor, error) {
+	if cfg.APIToken == "" {
+		return nil, fmt.Errorf("cloudflare: api token is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = cloudflareAPI
+	}
+
+	var scope string
+	switch {
+	case cfg.ZoneID != "":
+		scope = "zones/" + url.PathEscape(cfg.ZoneID)
+	case cfg.AccountID != "":
+		scope = "accounts/" + url.PathEscape(cfg.AccountID)
+	default:
+		return nil, fmt.Errorf("cloudflare: a zone or account ID is required")
+	}
+
+	return &CloudflareExecutor{
+		cfg:    cfg,
+		rules:  strings.TrimSuffix(cfg.BaseURL, "/") + "/" + scope + "/firewall/access_rules/rules",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (c *CloudflareExecutor) Name() string {
+	return "cloudflare"
+}
+
+// Handles accepts the BLOCK and RATE_LIMIT actions of ongoing attacks
+// detected with enough confidence, on targets an access rule can hold
+func (c *CloudflareExecutor) Handles(action models.MitigationAction) bool {
+	if action.Type != ActionBlock && action.Type != ActionRateLimit {
+		return false
+	}
+	if action.AttackEndedAt != nil || action.Confidence < c.cfg.MinConfidence {
+		return false
+	}
+	_, _, ok := cloudflareTarget(action.Target)
+	return ok
+}
+
+// cloudflareRule is an IP Access Rule
+type cloudflareRule struct {
+	ID            string `json:"id,omitempty"`
+	Mode          string `json:"mode"`
+	Notes         string `json:"notes,omitempty"`
+	Configuration struct {
+		Target string `json:"target"`
+		Value  string `json:"value"`
+	} `json:"configuration"`
+}
+
+// Apply creates the rule, or changes the mode of the one already there
+func (c *CloudflareExecutor) Apply(action models.MitigationAction) error {
+	target, value, ok := cloudflareTarget(action.Target)
+	if !ok {
+		return fmt.Errorf("cloudflare: %q can't be an access rule", action.Target)
+	}
+	mode := cloudflareMode(action.Type)
+
+	owned, err := c.owned()
+	if err != nil {
+		return err
+	}
+	if rule, ok := owned[value]; ok {
+		if rule.Mode == mode {
+			return nil
+		}
+		return c.do(http.MethodPatch, c.rules+"/"+url.PathEscape(rule.ID), map[string]string{"mode": mode}, nil)
+	}
+
+	var rule cloudflareRule
+	rule.Mode = mode
+	rule.Notes = cloudflareNotes
+	if action.AttackID != "" {
+		rule.Notes += " attack " + action.AttackID
+	}
+	rule.Configuration.Target, rule.Configuration.Value = target, value
+	return c.do(http.MethodPost, c.rules, rule, nil)
+}
+
+// Remove deletes the dashboard's rule for the target
+func (c *CloudflareExecutor) Remove(action models.MitigationAction) error {
+	_, value, ok := cloudflareTarget(action.Target)
+	if !ok {
+		return nil
+	}
+	owned, err := c.owned()
+	if err != nil {
+		return err
+	}
+	rule, ok := owned[value]
+	if !ok {
+		return nil
+	}
+	return c.do(http.MethodDelete, c.rules+"/"+url.PathEscape(rule.ID), nil, nil)
+}
+
+// List returns the targets of the dashboard's rules; rules made by hand
+// or by other tools are left out
+func (c *CloudflareExecutor) List() ([]string, error) {
+	owned, err := c.owned()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0, len(owned))
+	for value := range owned {
+		targets = append(targets, value)
+	}
+	return targets, nil
+}
+
+// owned fetches the dashboard's rules by value
+func (c *CloudflareExecutor) owned() (map[string]cloudflareRule, error) {
+	owned := make(map[string]cloudflareRule)
+	for page := 1; ; page++ {
+		query := url.Values{
+			"notes":    {cloudflareNotes},
+			"per_page": {strconv.Itoa(cloudflarePageSize)},
+			"page":     {strconv.Itoa(page)},
+		}
+		var rules []cloudflareRule
+		totalPages := 0
+		err := c.do(http.MethodGet, c.rules+"?"+query.Encode(), nil, func(resp cloudflareResponse) error {
+			if resp.ResultInfo != nil {
+				totalPages = resp.ResultInfo.TotalPages
+			}
+			return json.Unmarshal(resp.Result, &rules)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			// The notes filter matches anywhere in the notes
+			if strings.HasPrefix(rule.Notes, cloudflareNotes) {
+				owned[normalizeCloudflareValue(rule.Configuration.Value)] = rule
+			}
+		}
+		if page >= totalPages {
+			return owned, nil
+		}
+	}
+}
+
+// cloudflareResponse is the envelope of every API response
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo *struct {
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
+}
+
+// do calls the API, handing a successful response to handle when given
+func (c *CloudflareExecutor) do(method, endpoint string, body interface{}, handle func(cloudflareResponse) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudflare: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded cloudflareResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&decoded); err != nil {
+		return fmt.Errorf("cloudflare: %s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	if !decoded.Success || resp.StatusCode >= 300 {
+		messages := make([]string, 0, len(decoded.Errors))
+		for _, e := range decoded.Errors {
+			messages = append(messages, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("cloudflare: %s %s: %s: %s", method, req.URL.Path, resp.Status, strings.Join(messages, "; "))
+	}
+	if handle != nil {
+		return handle(decoded)
+	}
+	return nil
+}
+
+// cloudflareMode is the access rule mode enforcing an action
+func cloudflareMode(actionType string) string {
+	if actionType == ActionRateLimit {
+		return "managed_challenge"
+	}
+	return "block"
+}
+
+// cloudflareTarget maps an address or prefix onto an access rule target.
+// Ranges can only be /16 or /24 for IPv4 and /32, /48 or /64 for IPv6.
+func cloudflareTarget(target string) (string, string, bool) {
+	prefix, ok := firewallTarget(target)
+	if !ok {
+		return "", "", false
+	}
+	value := formatTarget(prefix)
+	switch bits := prefix.Bits(); {
+	case prefix.IsSingleIP() && prefix.Addr().Is4():
+		return "ip", value, true
+	case prefix.IsSingleIP():
+		return "ip6", value, true
+	case prefix.Addr().Is4() && (bits == 16 || bits == 24):
+		return "ip_range", value, true
+	case prefix.Addr().Is6() && (bits == 32 || bits == 48 || bits == 64):
+		return "ip_range", value, true
+	}
+	return "", "", false
+}
+
+// normalizeCloudflareValue writes a rule's value as List reports targets
+func normalizeCloudflareValue(value string) string {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return formatTarget(prefix.Masked())
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.String()
+	}
+	return value
+}
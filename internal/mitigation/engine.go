@@ -0,0 +1,532 @@
+package mitigation
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Action types the engine decides between
+const (
+	ActionBlock     = StrategyBlock
+	ActionRateLimit = "RATE_LIMIT"
+	ActionMonitor   = "MONITOR"
+	ActionRTBH      = StrategyRTBH
+)
+
+// Changes reported to the engine's listener
+const (
+	EventApplied   = "applied"
+	EventEscalated = "escalated"
+	EventExpired   = "expired"
+	EventLifted    = "lifted"
+	EventFailed    = "failed"
+)
+
+var (
+	// ErrActionNotFound is returned for an action that isn't on record
+	ErrActionNotFound = errors.New("mitigation not found")
+	// ErrInvalidAction is returned for a manual action that can't be taken
+	ErrInvalidAction = errors.New("invalid mitigation")
+	// ErrAllowlisted is returned for a manual action on a trusted source
+	ErrAllowlisted = errors.New("target is on the allowlist")
+)
+
+// actionRank orders action types by how hard they act on the traffic, so a
+// re-detection only ever tightens the action already in force
+var actionRank = map[string]int{
+	ActionMonitor:   0,
+	ActionRateLimit: 1,
+	ActionBlock:     2,
+	ActionRTBH:      2,
+}
+
+// ActionStore keeps the mitigation records
+type ActionStore interface {
+	GetMitigations() ([]models.MitigationAction, error)
+	StoreMitigation(action models.MitigationAction) error
+	RemoveMitigation(id string) error
+}
+
+// Policy decides which action an attack warrants
+type Policy struct {
+	// BlockConfidence is the confidence from which sources are blocked
+	BlockConfidence float64
+	// RateLimitConfidence is the confidence from which sources are rate
+	// limited; below it they are only monitored
+	RateLimitConfidence float64
+	// Duration is how long an action stays in force after the attack was
+	// last detected
+	Duration time.Duration
+	// RTBH blackholes the victims of attacks whose sources look spoofed,
+	// from BlockConfidence, instead of only monitoring them
+	RTBH bool
+	// Rules are tried in order before the confidence thresholds; the first
+	// matching an attack decides its action
+	Rules []PolicyRule
+	// MaxBlocked caps the targets blocked or blackholed at once, manual
+	// actions included; automatic actions beyond it are only monitored.
+	// 0 leaves it uncapped.
+	MaxBlocked int
+}
+
+// Decision is the action an attack warrants
+type Decision struct {
+	Action   string
+	Duration time.Duration
+	DryRun   bool
+	// Rule names the policy rule that decided; empty for the thresholds
+	Rule string
+}
+
+// DefaultPolicy blocks only attacks detected with high confidence, for an hour
+func DefaultPolicy() Policy {
+	return Policy{
+		BlockConfidence:     0.9,
+		RateLimitConfidence: 0.7,
+		Duration:            time.Hour,
+	}
+}
+
+// Decide picks the action for an attack given its blocking recommendation,
+// by the first matching rule or else by confidence. Spoofed sources are
+// new on every packet, so blocking them achieves nothing: their victims
+// are blackholed when the policy allows, else only monitored. Sources that
+// can be blocked are never blackholed.
+func (p Policy) Decide(attack models.Attack, rec models.BlockingRecommendation) Decision {
+	decision := Decision{Duration: p.Duration}
+	for _, rule := range p.Rules {
+		if !rule.Matches(attack) {
+			continue
+		}
+		decision.Action, decision.DryRun, decision.Rule = rule.Action, rule.DryRun, rule.Name
+		if rule.Duration > 0 {
+			decision.Duration = rule.Duration
+		}
+		switch {
+		case rec.Strategy == StrategyRTBH && (rule.Action != ActionRTBH || !p.RTBH):
+			decision.Action = ActionMonitor
+		case rec.Strategy != StrategyRTBH && rule.Action == ActionRTBH:
+			decision.Action = ActionBlock
+		}
+		return decision
+	}
+
+	switch {
+	case rec.Strategy == StrategyRTBH:
+		decision.Action = ActionMonitor
+		if p.RTBH && attack.Confidence >= p.BlockConfidence {
+			decision.Action = ActionRTBH
+		}
+	case attack.Confidence >= p.BlockConfidence:
+		decision.Action = ActionBlock
+	case attack.Confidence >= p.RateLimitConfidence:
+		decision.Action = ActionRateLimit
+	default:
+		decision.Action = ActionMonitor
+	}
+	return decision
+}
+
+// blocks reports whether an action type counts toward MaxBlocked
+func blocks(actionType string) bool {
+	return actionType == ActionBlock || actionType == ActionRTBH
+}
+
+// Engine turns detected attacks into mitigation actions: it records them,
+// has the executors enforce them and lifts them once they expire
+type Engine struct {
+	store     ActionStore
+	executors []Executor
+	policy    Policy
+	// allow returns the allowlist in force, whose sources are never acted on
+	allow func() *iplist.Set
+
+	// killed stops automatic mitigation; manual actions are still taken
+	killed atomic.Bool
+
+	mu       sync.Mutex
+	listener func(event string, action models.MitigationAction)
+	failure  func(action models.MitigationAction, executor string, err error)
+}
+
+func NewEngine(store ActionStore, executors []Executor, policy Policy, allow func() *iplist.Set) *Engine {
+	if policy.Duration <= 0 {
+		policy.Duration = DefaultPolicy().Duration
+	}
+	return &Engine{
+		store:     store,
+		executors: executors,
+		policy:    policy,
+		allow:     allow,
+	}
+}
+
+// Policy returns the policy the engine acts on
+func (e *Engine) Policy() Policy {
+	return e.policy
+}
+
+// OnChange registers a function called with every action applied,
+// escalated, expired or lifted
+func (e *Engine) OnChange(listener func(event string, action models.MitigationAction)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.listener = listener
+}
+
+// OnFailure registers a function called with every action an executor
+// failed to apply or lift
+func (e *Engine) OnFailure(failure func(action models.MitigationAction, executor string, err error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failure = failure
+}
+
+func (e *Engine) notify(event string, action models.MitigationAction) {
+	e.mu.Lock()
+	listener := e.listener
+	e.mu.Unlock()
+	if listener != nil {
+		listener(event, action)
+	}
+}
+
+// fail logs and reports an executor failing on an action
+func (e *Engine) fail(verb string, action models.MitigationAction, executor Executor, err error) {
+	log.Printf("Error %s %s of %s on %s: %v", verb, action.Type, action.Target, executor.Name(), err)
+	e.mu.Lock()
+	failure := e.failure
+	e.mu.Unlock()
+	if failure != nil {
+		failure(action, executor.Name(), err)
+	}
+}
+
+// KillSwitch reports whether automatic mitigation is stopped
+func (e *Engine) KillSwitch() bool {
+	return e.killed.Load()
+}
+
+// SetKillSwitch stops or resumes automatic mitigation. Engaging it lifts
+// and removes the actions taken for attacks, on behalf of by, returning
+// them; manual actions stay in force.
+func (e *Engine) SetKillSwitch(engaged bool, by string) ([]models.MitigationAction, error) {
+	if e.killed.Swap(engaged) == engaged || !engaged {
+		return nil, nil
+	}
+
+	records, err := e.store.GetMitigations()
+	if err != nil {
+		return nil, fmt.Errorf("reading mitigations: %w", err)
+	}
+	lifted := make([]models.MitigationAction, 0)
+	for _, action := range records {
+		if action.AttackID == "" {
+			continue
+		}
+		e.lift(action, records)
+		if err := e.store.RemoveMitigation(action.ID); err != nil {
+			return lifted, fmt.Errorf("removing mitigation %s: %w", action.ID, err)
+		}
+		action.Active = false
+		action.LiftedBy = by
+		lifted = append(lifted, action)
+		e.notify(EventLifted, action)
+	}
+	return lifted, nil
+}
+
+// Mitigate acts on a detected attack, unless the kill switch is engaged.
+// New targets get an action; targets already acted on for the attack have
+// their expiry pushed back and their action escalated when the attack now
+// warrants a harder one. It returns the actions applied or escalated.
+func (e *Engine) Mitigate(attack models.Attack) ([]models.MitigationAction, error) {
+	if e.killed.Load() {
+		return nil, nil
+	}
+	rec := RecommendBlocking(attack, e.allow())
+	if len(rec.Targets) == 0 {
+		return nil, nil
+	}
+	decision := e.policy.Decide(attack, rec)
+	reason := fmt.Sprintf("%s (confidence %.2f): %s", attack.Type, attack.Confidence, rec.Reason)
+	if decision.Rule != "" {
+		reason = fmt.Sprintf("policy %s, %s", decision.Rule, reason)
+	}
+
+	records, err := e.store.GetMitigations()
+	if err != nil {
+		return nil, fmt.Errorf("reading mitigations: %w", err)
+	}
+	now := time.Now()
+	existing := make(map[string]models.MitigationAction)
+	blocked := make(map[string]bool)
+	for _, action := range records {
+		if action.AttackID == attack.ID {
+			existing[action.Target] = action
+		}
+		if blocks(action.Type) && !action.DryRun && (action.ExpiresAt.IsZero() || now.Before(action.ExpiresAt)) {
+			blocked[action.Target] = true
+		}
+	}
+
+	changed := make([]models.MitigationAction, 0, len(rec.Targets))
+	capped := 0
+	for _, target := range rec.Targets {
+		actionType := decision.Action
+		if blocks(actionType) && !decision.DryRun && e.policy.MaxBlocked > 0 && !blocked[target] {
+			if len(blocked) >= e.policy.MaxBlocked {
+				actionType = ActionMonitor
+				if _, ok := existing[target]; !ok {
+					capped++
+				}
+			} else {
+				blocked[target] = true
+			}
+		}
+
+		action, ok := existing[target]
+		before := action
+		event := ""
+		switch {
+		case !ok:
+			action = models.MitigationAction{
+				ID:        uuid.New().String(),
+				Type:      actionType,
+				Target:    target,
+				Duration:  decision.Duration,
+				Reason:    reason,
+				AttackID:  attack.ID,
+				AppliedAt: now,
+				DryRun:    decision.DryRun,
+			}
+			event = EventApplied
+		case actionRank[actionType] > actionRank[action.Type] && (action.DryRun || !decision.DryRun),
+			action.DryRun && !decision.DryRun && actionType != ActionMonitor:
+			// A dry run becomes enforced once a rule enforcing it matches,
+			// but never the other way round
+			action.Type = actionType
+			action.Reason = reason
+			action.DryRun = decision.DryRun
+			event = EventEscalated
+		}
+		if event != "" {
+			action.Policy = decision.Rule
+		}
+
+		// Executors expiring entries themselves hear of a pushed back
+		// expiry once half the action's time has run out
+		refresh := event == "" && action.ExpiresAt.Sub(now) < action.Duration/2
+		action.Duration = max(action.Duration, decision.Duration)
+		action.ExpiresAt = now.Add(action.Duration)
+		action.Active = true
+		action.Confidence = max(action.Confidence, attack.Confidence)
+		action.AttackTypes = attack.Types()
+		action.AttackEndedAt = nil
+
+		var previous *models.MitigationAction
+		if ok {
+			previous = &before
+		}
+		e.sync(previous, action, event != "" || refresh)
+		if err := e.store.StoreMitigation(action); err != nil {
+			return changed, fmt.Errorf("storing mitigation for %s: %w", target, err)
+		}
+		if event != "" {
+			changed = append(changed, action)
+			e.notify(event, action)
+		}
+	}
+
+	if capped > 0 {
+		log.Printf("⚠️  %d targets of attack %s only monitored: %d blocked, at the cap of %d", capped, attack.ID, len(blocked), e.policy.MaxBlocked)
+	}
+	return changed, nil
+}
+
+// Block puts a target under an action by hand, for duration or the
+// policy's when 0. Blocking a target already under a manual action
+// replaces it. Unlike attack actions, manual ones never escalate or end
+// with an attack; they stay in force until they expire or are lifted.
+func (e *Engine) Block(target, actionType string, duration time.Duration, reason, appliedBy string) (models.MitigationAction, error) {
+	if actionType != ActionBlock && actionType != ActionRateLimit {
+		return models.MitigationAction{}, fmt.Errorf("%w: unknown action type %q, expected %s or %s", ErrInvalidAction, actionType, ActionBlock, ActionRateLimit)
+	}
+	prefix, ok := firewallTarget(target)
+	if !ok {
+		return models.MitigationAction{}, fmt.Errorf("%w: invalid target %q, expected an address or CIDR", ErrInvalidAction, target)
+	}
+	target = formatTarget(prefix)
+	if e.allow().Overlaps(target) {
+		return models.MitigationAction{}, ErrAllowlisted
+	}
+	if duration <= 0 {
+		duration = e.policy.Duration
+	}
+
+	records, err := e.store.GetMitigations()
+	if err != nil {
+		return models.MitigationAction{}, fmt.Errorf("reading mitigations: %w", err)
+	}
+	var previous *models.MitigationAction
+	for i, action := range records {
+		if action.AttackID == "" && action.Target == target {
+			previous = &records[i]
+			break
+		}
+	}
+
+	now := time.Now()
+	action := models.MitigationAction{
+		ID:         uuid.New().String(),
+		Type:       actionType,
+		Target:     target,
+		Duration:   duration,
+		Reason:     reason,
+		AppliedAt:  now,
+		ExpiresAt:  now.Add(duration),
+		Active:     true,
+		Confidence: 1,
+		AppliedBy:  appliedBy,
+	}
+	if previous != nil {
+		action.ID = previous.ID
+	}
+
+	e.sync(previous, action, true)
+	if err := e.store.StoreMitigation(action); err != nil {
+		return action, fmt.Errorf("storing mitigation for %s: %w", target, err)
+	}
+	e.notify(EventApplied, action)
+	return action, nil
+}
+
+// Lift lifts an action before it expires, on behalf of by, and removes it
+func (e *Engine) Lift(id, by string) (models.MitigationAction, error) {
+	records, err := e.store.GetMitigations()
+	if err != nil {
+		return models.MitigationAction{}, fmt.Errorf("reading mitigations: %w", err)
+	}
+	for _, action := range records {
+		if action.ID != id {
+			continue
+		}
+		e.lift(action, records)
+		if err := e.store.RemoveMitigation(action.ID); err != nil {
+			return action, fmt.Errorf("removing mitigation %s: %w", action.ID, err)
+		}
+		action.Active = false
+		action.LiftedBy = by
+		e.notify(EventLifted, action)
+		return action, nil
+	}
+	return models.MitigationAction{}, ErrActionNotFound
+}
+
+// End records that an attack stopped being detected, lifting its actions
+// from the executors that only enforce them while it lasts. The actions
+// themselves stay in force until they expire.
+func (e *Engine) End(attackID string) error {
+	records, err := e.store.GetMitigations()
+	if err != nil {
+		return fmt.Errorf("reading mitigations: %w", err)
+	}
+
+	now := time.Now()
+	for _, action := range records {
+		if action.AttackID != attackID || action.AttackEndedAt != nil {
+			continue
+		}
+		before := action
+		action.AttackEndedAt = &now
+		e.sync(&before, action, false)
+		if err := e.store.StoreMitigation(action); err != nil {
+			return fmt.Errorf("storing mitigation for %s: %w", action.Target, err)
+		}
+	}
+	return nil
+}
+
+// Expire lifts and removes the actions whose time is up, returning them
+func (e *Engine) Expire() ([]models.MitigationAction, error) {
+	records, err := e.store.GetMitigations()
+	if err != nil {
+		return nil, fmt.Errorf("reading mitigations: %w", err)
+	}
+
+	now := time.Now()
+	expired := make([]models.MitigationAction, 0)
+	for _, action := range records {
+		if action.ExpiresAt.IsZero() || now.Before(action.ExpiresAt) {
+			continue
+		}
+		e.lift(action, records)
+		if err := e.store.RemoveMitigation(action.ID); err != nil {
+			return expired, fmt.Errorf("removing mitigation %s: %w", action.ID, err)
+		}
+		action.Active = false
+		expired = append(expired, action)
+		e.notify(EventExpired, action)
+	}
+
+	return expired, nil
+}
+
+// sync brings the executors in line with a changed action: those that
+// stopped handling it lift the previous one, those that started handling it
+// apply it, and with reapply so do those that already did
+func (e *Engine) sync(previous *models.MitigationAction, action models.MitigationAction, reapply bool) {
+	for _, executor := range e.executors {
+		was := previous != nil && enforces(executor, *previous)
+		switch handles := enforces(executor, action); {
+		case was && !handles:
+			if err := executor.Remove(*previous); err != nil {
+				e.fail("lifting", *previous, executor, err)
+			}
+		case handles && (!was || reapply):
+			if err := executor.Apply(action); err != nil {
+				e.fail("applying", action, executor, err)
+			}
+		}
+	}
+}
+
+// lift has every executor handling an action stop enforcing it. Executors
+// hold a target once however many actions name it, so other actions in
+// force on the same target are applied again.
+func (e *Engine) lift(action models.MitigationAction, records []models.MitigationAction) {
+	now := time.Now()
+	for _, executor := range e.executors {
+		if !enforces(executor, action) {
+			continue
+		}
+		if err := executor.Remove(action); err != nil {
+			e.fail("lifting", action, executor, err)
+		}
+		for _, other := range records {
+			if other.ID == action.ID || other.Target != action.Target || !enforces(executor, other) {
+				continue
+			}
+			if !other.ExpiresAt.IsZero() && !now.Before(other.ExpiresAt) {
+				continue
+			}
+			if err := executor.Apply(other); err != nil {
+				e.fail("applying", other, executor, err)
+			}
+		}
+	}
+}
+
+// enforces reports whether an executor enforces an action; dry runs are
+// enforced by none
+func enforces(executor Executor, action models.MitigationAction) bool {
+	return !action.DryRun && executor.Handles(action)
+}
@@ -0,0 +1,25 @@
+package mitigation
+
+import "github.com/nshruti113/ddos-detection-dashboard/internal/models"
+
+// Executor enforces mitigation actions on a backend (host firewall, CDN, cloud WAF)
+type Executor interface {
+	// Name identifies the backend in logs and drift reports
+	Name() string
+
+	// Handles reports whether this backend enforces the given action type
+	Handles(action models.MitigationAction) bool
+
+	// Apply enforces an action. Applying an already enforced target is a
+	// no-op, except that backends expiring entries themselves take on the
+	// action's new ExpiresAt.
+	Apply(action models.MitigationAction) error
+
+	// Remove lifts an action. Removing an unknown target is a no-op.
+	Remove(action models.MitigationAction) error
+
+	// List returns the targets the backend currently enforces. Only entries
+	// owned by this system are returned, so manual rules elsewhere in the
+	// backend are never reported as drift.
+	List() ([]string, error)
+}
@@ -0,0 +1,343 @@
+package mitigation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/netip"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Host firewall backends
+const (
+	FirewallNftables = "nftables"
+	FirewallIPSet    = "iptables"
+)
+
+// DefaultFirewallMaxEntries caps the sources a host firewall blocks, so a
+// runaway detection can't fill its sets
+const DefaultFirewallMaxEntries = 10000
+
+// commandTimeout bounds each firewall or routing command
+const commandTimeout = 10 * time.Second
+
+// FirewallExecutor enforces BLOCK actions on the local host by adding the
+// targets to nftables sets, or to ipsets matched by iptables rules, which
+// drop their incoming traffic. Entries carry the action's expiry as their
+// kernel timeout, so blocks are lifted at ExpiresAt even if the dashboard
+// is down by then.
+type FirewallExecutor struct {
+	backend string
+	// name is the nftables table, or the prefix of the ipsets
+	name       string
+	maxEntries int
+
+	// mu serializes changes, so the cap check and the add can't interleave
+	mu sync.Mutex
+}
+
+// NewFirewallExecutor sets up the backend's table or sets and the rules
+// dropping traffic from them, adding at most maxEntries blocks (0 for
+// DefaultFirewallMaxEntries). Setting up again is harmless.
+func NewFirewallExecutor(backend, name string, maxEntries int) (*FirewallExecutor, error) {
+	if backend != FirewallNftables && backend != FirewallIPSet {
+		return nil, fmt.Errorf("unknown firewall backend %q, expected %s or %s", backend, FirewallNftables, FirewallIPSet)
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultFirewallMaxEntries
+	}
+
+	f := &FirewallExecutor{
+		backend:    backend,
+		name:       name,
+		maxEntries: maxEntries,
+	}
+	if err := f.setup(); err != nil {
+		return nil, fmt.Errorf("%s: setting up: %w", f.Name(), err)
+	}
+	return f, nil
+}
+
+func (f *FirewallExecutor) Name() string {
+	return f.backend
+}
+
+// Handles accepts BLOCK actions on addresses and prefixes
+func (f *FirewallExecutor) Handles(action models.MitigationAction) bool {
+	if action.Type != ActionBlock {
+		return false
+	}
+	_, ok := firewallTarget(action.Target)
+	return ok
+}
+
+// Apply adds the target with the time left until the action expires, or
+// for good when it never does. An entry already there takes on the new
+// expiry.
+func (f *FirewallExecutor) Apply(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return fmt.Errorf("%s: invalid target %q", f.Name(), action.Target)
+	}
+	timeout := 0
+	if !action.ExpiresAt.IsZero() {
+		timeout = int(math.Ceil(time.Until(action.ExpiresAt).Seconds()))
+		if timeout <= 0 {
+			return nil
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.List()
+	if err != nil {
+		return err
+	}
+	target := formatTarget(prefix)
+	if len(entries) >= f.maxEntries && !slices.Contains(entries, target) {
+		return fmt.Errorf("%s: %d entries blocked, at the cap of %d; not blocking %s", f.Name(), len(entries), f.maxEntries, target)
+	}
+
+	if f.backend == FirewallNftables {
+		element := target
+		if timeout > 0 {
+			element += fmt.Sprintf(" timeout %ds", timeout)
+		}
+		// Adding, deleting and adding again in one transaction replaces
+		// an entry already there, whose timeout can't be changed in place
+		set := f.setName(prefix)
+		return f.nft(fmt.Sprintf("add element inet %[1]s %[2]s { %[3]s }\ndelete element inet %[1]s %[2]s { %[3]s }\nadd element inet %[1]s %[2]s { %[4]s }\n",
+			f.name, set, target, element))
+	}
+	_, err = runCommand("ipset", "", "add", f.setName(prefix), target, "timeout", fmt.Sprint(timeout), "-exist")
+	return err
+}
+
+// Remove deletes the target; one not blocked is left alone
+func (f *FirewallExecutor) Remove(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return nil
+	}
+	target := formatTarget(prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.backend == FirewallNftables {
+		// Adding first makes deleting an absent entry succeed
+		set := f.setName(prefix)
+		return f.nft(fmt.Sprintf("add element inet %[1]s %[2]s { %[3]s }\ndelete element inet %[1]s %[2]s { %[3]s }\n",
+			f.name, set, target))
+	}
+	_, err := runCommand("ipset", "", "del", f.setName(prefix), target, "-exist")
+	return err
+}
+
+// List returns the blocked targets of both address families. The sets
+// belong to the dashboard, so everything in them is its own.
+func (f *FirewallExecutor) List() ([]string, error) {
+	targets := make([]string, 0)
+	for _, set := range []string{f.setName(netip.MustParsePrefix("0.0.0.0/0")), f.setName(netip.MustParsePrefix("::/0"))} {
+		var entries []string
+		var err error
+		if f.backend == FirewallNftables {
+			entries, err = f.listNftSet(set)
+		} else {
+			entries, err = f.listIPSet(set)
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, entries...)
+	}
+	return targets, nil
+}
+
+// setName names the set holding a prefix's address family
+func (f *FirewallExecutor) setName(prefix netip.Prefix) string {
+	family := "4"
+	if prefix.Addr().Is6() {
+		family = "6"
+	}
+	if f.backend == FirewallNftables {
+		return "blocked" + family
+	}
+	return f.name + "-blocked" + family
+}
+
+// setup creates the table or sets and the rules dropping their traffic
+func (f *FirewallExecutor) setup() error {
+	if f.backend == FirewallNftables {
+		// The chain is flushed and refilled, so its rules are never doubled
+		return f.nft(fmt.Sprintf(`add table inet %[1]s
+add set inet %[1]s blocked4 { type ipv4_addr; flags interval, timeout; }
+add set inet %[1]s blocked6 { type ipv6_addr; flags interval, timeout; }
+add chain inet %[1]s input { type filter hook input priority -10; policy accept; }
+flush chain inet %[1]s input
+add rule inet %[1]s input ip saddr @blocked4 drop
+add rule inet %[1]s input ip6 saddr @blocked6 drop
+`, f.name))
+	}
+
+	for _, family := range []struct{ set, ipsetFamily, iptables string }{
+		{f.name + "-blocked4", "inet", "iptables"},
+		{f.name + "-blocked6", "inet6", "ip6tables"},
+	} {
+		if _, err := runCommand("ipset", "", "create", family.set, "hash:net", "family", family.ipsetFamily, "timeout", "0", "-exist"); err != nil {
+			return err
+		}
+		rule := []string{"INPUT", "-m", "set", "--match-set", family.set, "src", "-j", "DROP"}
+		if _, err := runCommand(family.iptables, "", append([]string{"-C"}, rule...)...); err == nil {
+			continue
+		}
+		if _, err := runCommand(family.iptables, "", append([]string{"-I"}, rule...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nft runs an nftables script as one transaction
+func (f *FirewallExecutor) nft(script string) error {
+	_, err := runCommand("nft", script, "-f", "-")
+	return err
+}
+
+// listNftSet reads the elements of an nftables set
+func (f *FirewallExecutor) listNftSet(set string) ([]string, error) {
+	out, err := runCommand("nft", "", "-j", "list", "set", "inet", f.name, set)
+	if err != nil {
+		return nil, err
+	}
+
+	var listing struct {
+		Nftables []struct {
+			Set *struct {
+				Elem []json.RawMessage `json:"elem"`
+			} `json:"set"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(out, &listing); err != nil {
+		return nil, fmt.Errorf("%s: reading set %s: %w", f.Name(), set, err)
+	}
+
+	targets := make([]string, 0)
+	for _, object := range listing.Nftables {
+		if object.Set == nil {
+			continue
+		}
+		for _, raw := range object.Set.Elem {
+			if target, ok := nftElement(raw); ok {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// nftElement reads a set element, an address or a prefix, wrapped in an
+// "elem" object when it carries a timeout
+func nftElement(raw json.RawMessage) (string, bool) {
+	var wrapped struct {
+		Elem *struct {
+			Val json.RawMessage `json:"val"`
+		} `json:"elem"`
+	}
+	if json.Unmarshal(raw, &wrapped) == nil && wrapped.Elem != nil {
+		raw = wrapped.Elem.Val
+	}
+
+	var addr string
+	if json.Unmarshal(raw, &addr) == nil {
+		prefix, ok := firewallTarget(addr)
+		return formatTarget(prefix), ok
+	}
+	var value struct {
+		Prefix *struct {
+			Addr string `json:"addr"`
+			Len  int    `json:"len"`
+		} `json:"prefix"`
+	}
+	if json.Unmarshal(raw, &value) != nil || value.Prefix == nil {
+		return "", false
+	}
+	prefix, ok := firewallTarget(fmt.Sprintf("%s/%d", value.Prefix.Addr, value.Prefix.Len))
+	return formatTarget(prefix), ok
+}
+
+// listIPSet reads the members of an ipset
+func (f *FirewallExecutor) listIPSet(set string) ([]string, error) {
+	out, err := runCommand("ipset", "", "list", set, "-output", "save")
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "add" {
+			continue
+		}
+		if prefix, ok := firewallTarget(fields[2]); ok {
+			targets = append(targets, formatTarget(prefix))
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// runCommand executes a firewall or routing command, with stdin when given
+func runCommand(name, stdin string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// firewallTarget parses an address or prefix to block
+func firewallTarget(target string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		prefix = prefix.Masked()
+		if prefix.Addr().Is4In6() {
+			return netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0)), true
+		}
+		return prefix, true
+	}
+	addr, err := netip.ParseAddr(target)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// formatTarget writes a prefix as the firewall lists it: single addresses
+// without a length
+func formatTarget(prefix netip.Prefix) string {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
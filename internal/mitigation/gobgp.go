@@ -0,0 +1,301 @@
+package mitigation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// VolumetricAttacks are the attack types whose sources are worth filtering
+// upstream with Flowspec, before their traffic fills the links
+var VolumetricAttacks = map[string]bool{
+	"SYN_FLOOD":         true,
+	"ACK_FLOOD":         true,
+	"RST_FLOOD":         true,
+	"FIN_FLOOD":         true,
+	"INVALID_TCP_FLAGS": true,
+	"UDP_FLOOD":         true,
+	"DNS_AMPLIFICATION": true,
+	"CARPET_BOMBING":    true,
+}
+
+// isVolumetric reports whether any of an action's attack types is volumetric
+func isVolumetric(action models.MitigationAction) bool {
+	for _, attackType := range action.AttackTypes {
+		if VolumetricAttacks[attackType] {
+			return true
+		}
+	}
+	return false
+}
+
+// GoBGPConfig configures announcements through a local gobgpd
+type GoBGPConfig struct {
+	// API is gobgpd's gRPC address, host:port
+	API string
+	// Flowspec announces discard rules for the sources of volumetric floods
+	Flowspec bool
+	// RTBH announces blackhole routes for the victims of RTBH actions
+	RTBH bool
+	// Community is attached to every announcement and tells the
+	// dashboard's routes apart, e.g. 65535:666 (BLACKHOLE, RFC 7999)
+	Community string
+	// NextHop and NextHopV6 are the discard next hops of blackhole routes
+	NextHop   string
+	NextHopV6 string
+}
+
+// GoBGPExecutor announces mitigations to upstream routers through gobgpd,
+// driven by its gobgp command: BLOCK actions of volumetric floods become
+// Flowspec rules discarding the sources' traffic, and RTBH actions
+// blackhole routes for the victims. Withdrawing a route lifts it.
+type GoBGPExecutor struct {
+	cfg       GoBGPConfig
+	host      string
+	port      string
+	community uint32
+}
+
+func NewGoBGPExecutor(cfg GoBGPConfig) (*GoBGPExecutor, error) {
+	host, port, err := net.SplitHostPort(cfg.API)
+	if err != nil {
+		return nil, fmt.Errorf("gobgp: invalid API address %q: %w", cfg.API, err)
+	}
+	community, err := parseCommunity(cfg.Community)
+	if err != nil {
+		return nil, fmt.Errorf("gobgp: %w", err)
+	}
+	if cfg.RTBH {
+		for _, hop := range []string{cfg.NextHop, cfg.NextHopV6} {
+			if _, err := netip.ParseAddr(hop); err != nil {
+				return nil, fmt.Errorf("gobgp: invalid next hop %q", hop)
+			}
+		}
+	}
+
+	g := &GoBGPExecutor{cfg: cfg, host: host, port: port, community: community}
+	if _, err := g.gobgp("global"); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *GoBGPExecutor) Name() string {
+	return "gobgp"
+}
+
+// Handles accepts RTBH actions and, with Flowspec, the BLOCK actions of
+// volumetric floods
+func (g *GoBGPExecutor) Handles(action models.MitigationAction) bool {
+	if _, ok := firewallTarget(action.Target); !ok {
+		return false
+	}
+	switch action.Type {
+	case ActionRTBH:
+		return g.cfg.RTBH
+	case ActionBlock:
+		return g.cfg.Flowspec && isVolumetric(action)
+	}
+	return false
+}
+
+// Apply announces the action's route; announcing it again replaces it
+func (g *GoBGPExecutor) Apply(action models.MitigationAction) error {
+	args, err := g.routeArgs(action, "add")
+	if err != nil {
+		return err
+	}
+	_, err = g.gobgp(args...)
+	return err
+}
+
+// Remove withdraws the routes the dashboard announced for the target;
+// routes announced by others for the same prefix are left alone
+func (g *GoBGPExecutor) Remove(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return nil
+	}
+	for _, kind := range []struct {
+		actionType string
+		enabled    bool
+		flowspec   bool
+	}{{ActionRTBH, g.cfg.RTBH, false}, {ActionBlock, g.cfg.Flowspec, true}} {
+		if !kind.enabled {
+			continue
+		}
+		owned, err := g.ownedTargets(gobgpFamily(prefix, kind.flowspec), kind.flowspec)
+		if err != nil {
+			return err
+		}
+		if !owned[ribTarget(prefix, kind.flowspec)] {
+			continue
+		}
+		action.Type = kind.actionType
+		args, err := g.routeArgs(action, "del")
+		if err != nil {
+			return err
+		}
+		if _, err := g.gobgp(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeArgs builds the gobgp command announcing or withdrawing an action
+func (g *GoBGPExecutor) routeArgs(action models.MitigationAction, verb string) ([]string, error) {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return nil, fmt.Errorf("gobgp: invalid target %q", action.Target)
+	}
+	nextHop := g.cfg.NextHop
+	if prefix.Addr().Is6() {
+		nextHop = g.cfg.NextHopV6
+	}
+	community := []string{"community", g.cfg.Community}
+
+	if action.Type == ActionRTBH {
+		args := []string{"global", "rib", verb, "-a", gobgpFamily(prefix, false), prefix.String()}
+		if verb == "add" {
+			args = append(args, "nexthop", nextHop)
+			args = append(args, community...)
+		}
+		return args, nil
+	}
+
+	args := []string{"global", "rib", "-a", gobgpFamily(prefix, true), verb, "match", "source", prefix.String()}
+	if verb == "add" {
+		args = append(args, "then", "discard")
+		args = append(args, community...)
+	}
+	return args, nil
+}
+
+// gobgpPath is a route as gobgp lists it in JSON
+type gobgpPath struct {
+	Attrs []struct {
+		Communities []uint32 `json:"communities"`
+	} `json:"attrs"`
+}
+
+// owned reports whether the dashboard announced any of a prefix's routes
+func (g *GoBGPExecutor) owned(paths []gobgpPath) bool {
+	for _, path := range paths {
+		for _, attr := range path.Attrs {
+			if slices.Contains(attr.Communities, g.community) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flowspecSource finds the source prefix in a Flowspec rule's listing
+// (IPv6 ones carry an offset after the length, e.g. 2001:db8::/64/0)
+var flowspecSource = regexp.MustCompile(`\[source: ?([0-9A-Fa-f.:]+/\d+)`)
+
+// List returns the targets of the routes the dashboard announced: victims
+// as host routes, Flowspec sources as the actions name them
+func (g *GoBGPExecutor) List() ([]string, error) {
+	targets := make([]string, 0)
+	for _, family := range []struct {
+		name     string
+		flowspec bool
+	}{{"ipv4", false}, {"ipv6", false}, {"ipv4-flowspec", true}, {"ipv6-flowspec", true}} {
+		if family.flowspec && !g.cfg.Flowspec || !family.flowspec && !g.cfg.RTBH {
+			continue
+		}
+		owned, err := g.ownedTargets(family.name, family.flowspec)
+		if err != nil {
+			return nil, err
+		}
+		for target := range owned {
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// ownedTargets reads the targets of the dashboard's routes in one family
+func (g *GoBGPExecutor) ownedTargets(family string, flowspec bool) (map[string]bool, error) {
+	out, err := g.gobgp("global", "rib", "-a", family, "-j")
+	if err != nil {
+		return nil, err
+	}
+	var rib map[string][]gobgpPath
+	if len(bytes.TrimSpace(out)) > 0 {
+		if err := json.Unmarshal(out, &rib); err != nil {
+			return nil, fmt.Errorf("gobgp: reading %s routes: %w", family, err)
+		}
+	}
+
+	targets := make(map[string]bool)
+	for key, paths := range rib {
+		if !g.owned(paths) {
+			continue
+		}
+		if flowspec {
+			match := flowspecSource.FindStringSubmatch(key)
+			if match == nil {
+				continue
+			}
+			key = match[1]
+		}
+		if prefix, ok := firewallTarget(key); ok {
+			targets[ribTarget(prefix, flowspec)] = true
+		}
+	}
+	return targets, nil
+}
+
+// gobgpFamily names the address family a prefix is announced in
+func gobgpFamily(prefix netip.Prefix, flowspec bool) string {
+	family := "ipv4"
+	if prefix.Addr().Is6() {
+		family = "ipv6"
+	}
+	if flowspec {
+		family += "-flowspec"
+	}
+	return family
+}
+
+// ribTarget writes a route's prefix as actions name it: blackholed victims
+// as host routes, Flowspec sources as addresses or prefixes
+func ribTarget(prefix netip.Prefix, flowspec bool) string {
+	if flowspec {
+		return formatTarget(prefix)
+	}
+	return prefix.String()
+}
+
+// gobgp runs the gobgp command against the configured gobgpd
+func (g *GoBGPExecutor) gobgp(args ...string) ([]byte, error) {
+	return runCommand("gobgp", "", append([]string{"-u", g.host, "-p", g.port}, args...)...)
+}
+
+// parseCommunity parses a standard community, ASN:value
+func parseCommunity(s string) (uint32, error) {
+	high, low, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid community %q, expected ASN:value", s)
+	}
+	asn, err := strconv.ParseUint(high, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid community %q: %w", s, err)
+	}
+	value, err := strconv.ParseUint(low, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid community %q: %w", s, err)
+	}
+	return uint32(asn)<<16 | uint32(value), nil
+}
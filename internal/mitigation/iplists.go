@@ -0,0 +1,53 @@
+package mitigation
+
+import (
+	"sort"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/iplist"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// ApplyIPLists returns the mitigations enforcement points should apply:
+// the recorded ones less dry runs and those that would block an
+// allowlisted source, plus a BLOCK, with full confidence, of every denylisted CIDR not already
+// covered. RTBH targets are victims rather than sources, so the allowlist
+// doesn't apply to them; a denylisted CIDR overlapping the allowlist isn't
+// blocked.
+func ApplyIPLists(actions []models.MitigationAction, allow, deny *iplist.Set) []models.MitigationAction {
+	applied := make([]models.MitigationAction, 0, len(actions)+deny.Len())
+	targets := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		if action.DryRun || action.Type != StrategyRTBH && allow.Overlaps(action.Target) {
+			continue
+		}
+		applied = append(applied, action)
+		targets[action.Target] = true
+	}
+
+	for _, entry := range deny.Entries() {
+		if targets[entry.CIDR] || allow.Overlaps(entry.CIDR) {
+			continue
+		}
+		reason := "denylisted"
+		if entry.Comment != "" {
+			reason += ": " + entry.Comment
+		}
+		applied = append(applied, models.MitigationAction{
+			ID:         "denylist:" + entry.CIDR,
+			Type:       StrategyBlock,
+			Target:     entry.CIDR,
+			Reason:     reason,
+			AppliedAt:  entry.AddedAt,
+			Active:     true,
+			Confidence: 1,
+		})
+	}
+
+	sort.Slice(applied, func(i, j int) bool {
+		if applied[i].Target != applied[j].Target {
+			return applied[i].Target < applied[j].Target
+		}
+		return applied[i].ID < applied[j].ID
+	})
+	return applied
+}
@@ -0,0 +1,91 @@
+package mitigation
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// PolicyRule maps the attacks it matches onto an action. Every condition
+// given must hold; a rule without conditions matches every attack.
+type PolicyRule struct {
+	Name string `yaml:"name" json:"name"`
+	// AttackTypes matches attacks of any of these types, or with a vector
+	// of them
+	AttackTypes   []string `yaml:"attack_types" json:"attack_types,omitempty"`
+	MinSeverity   string   `yaml:"min_severity" json:"min_severity,omitempty"`
+	MinConfidence float64  `yaml:"min_confidence" json:"min_confidence,omitempty"`
+	// Action is BLOCK, RATE_LIMIT, MONITOR or RTBH
+	Action string `yaml:"action" json:"action"`
+	// Duration overrides the policy's
+	Duration time.Duration `yaml:"duration" json:"duration,omitempty"`
+	// DryRun records and reports the rule's actions without enforcing them
+	DryRun bool `yaml:"dry_run" json:"dry_run,omitempty"`
+}
+
+// Matches reports whether the rule applies to an attack
+func (r PolicyRule) Matches(attack models.Attack) bool {
+	if len(r.AttackTypes) > 0 && !slices.ContainsFunc(attack.Types(), func(t string) bool {
+		return slices.Contains(r.AttackTypes, t)
+	}) {
+		return false
+	}
+	if r.MinSeverity != "" && models.SeverityRank(attack.Severity) < models.SeverityRank(r.MinSeverity) {
+		return false
+	}
+	return attack.Confidence >= r.MinConfidence
+}
+
+// policyFile is the layout of a policy file
+type policyFile struct {
+	Policies []PolicyRule `yaml:"policies"`
+}
+
+// LoadPolicyRules reads the rules of a YAML or JSON policy file, in the
+// order they are tried
+func LoadPolicyRules(path string) ([]PolicyRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f policyFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(f.Policies))
+	for i := range f.Policies {
+		rule := &f.Policies[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("%s: policy %d has no name", path, i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("%s: policy %s is defined twice", path, rule.Name)
+		}
+		names[rule.Name] = true
+
+		rule.Action = strings.ToUpper(rule.Action)
+		if _, ok := actionRank[rule.Action]; !ok {
+			return nil, fmt.Errorf("%s: policy %s: unknown action %q", path, rule.Name, rule.Action)
+		}
+		rule.MinSeverity = strings.ToUpper(rule.MinSeverity)
+		if rule.MinSeverity != "" && models.SeverityRank(rule.MinSeverity) == 0 {
+			return nil, fmt.Errorf("%s: policy %s: unknown severity %s", path, rule.Name, rule.MinSeverity)
+		}
+		if rule.MinConfidence < 0 || rule.MinConfidence > 1 {
+			return nil, fmt.Errorf("%s: policy %s: confidence %.2f is not between 0 and 1", path, rule.Name, rule.MinConfidence)
+		}
+		if rule.Duration < 0 {
+			return nil, fmt.Errorf("%s: policy %s: negative duration", path, rule.Name)
+		}
+		for j, attackType := range rule.AttackTypes {
+			rule.AttackTypes[j] = strings.ToUpper(attackType)
+		}
+	}
+	return f.Policies, nil
+}
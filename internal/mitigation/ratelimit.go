@@ -0,0 +1,119 @@
+package mitigation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+const (
+	// minRatePerMinute is the lowest limit recommended, so legitimate users
+	// retrying or paging quickly are never throttled
+	minRatePerMinute = 30
+	// rateLimitDivisor sets the limit to this fraction of the attackers' rate
+	rateLimitDivisor = 5
+)
+
+var (
+	zoneNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+	// Paths come from attacker-controlled traffic; only plain ones are
+	// pasted into configuration
+	safePath = regexp.MustCompile(`^/[A-Za-z0-9._~%/:@+-]*$`)
+)
+
+// RecommendRateLimits derives per-client rate limits for the paths an
+// application-layer attack targeted, counted over the given detection
+// window. Attacks without per-path data get no recommendations.
+func RecommendRateLimits(attack models.Attack, window time.Duration) []models.RateLimitRecommendation {
+	recommendations := make([]models.RateLimitRecommendation, 0, len(attack.TargetPaths))
+	minutes := window.Minutes()
+	if minutes <= 0 {
+		return recommendations
+	}
+
+	for _, load := range attack.TargetPaths {
+		if !safePath.MatchString(load.Path) || load.Sources == 0 {
+			continue
+		}
+
+		observed := float64(load.Requests) / float64(load.Sources) / minutes
+		rate := int(math.Max(minRatePerMinute, observed/rateLimitDivisor))
+		burst := int(math.Max(5, float64(rate)/2))
+
+		rec := models.RateLimitRecommendation{
+			Path:          load.Path,
+			Key:           "client_ip",
+			RatePerMinute: rate,
+			Burst:         burst,
+			ObservedPerIP: math.Round(observed*10) / 10,
+			Effective:     observed > float64(rate+burst),
+		}
+		if !rec.Effective {
+			rec.Note = fmt.Sprintf("attackers average %.1f requests/minute per IP, under this limit; per-IP limiting won't stop this attack, consider a challenge or a path-wide limit", observed)
+		}
+
+		zone := zoneName(attack.Type, load.Path)
+		rec.Snippets = map[string]string{
+			"nginx":   nginxSnippet(zone, rec),
+			"haproxy": haproxySnippet(zone, rec),
+			"envoy":   envoySnippet(zone, rec),
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations
+}
+
+// zoneName builds a config-safe identifier such as "ddos_http_flood_api_search"
+func zoneName(attackType, path string) string {
+	name := zoneNameUnsafe.ReplaceAllString(strings.ToLower(attackType+"_"+path), "_")
+	return "ddos_" + strings.Trim(name, "_")
+}
+
+func nginxSnippet(zone string, rec models.RateLimitRecommendation) string {
+	return fmt.Sprintf(`# http {} block
+limit_req_zone $binary_remote_addr zone=%[1]s:10m rate=%[2]dr/m;
+
+# server {} block
+location = %[3]s {
+    limit_req zone=%[1]s burst=%[4]d nodelay;
+    limit_req_status 429;
+    # proxy_pass ... as for the existing location
+}
+`, zone, rec.RatePerMinute, rec.Path, rec.Burst)
+}
+
+func haproxySnippet(zone string, rec models.RateLimitRecommendation) string {
+	return fmt.Sprintf(`backend %[1]s
+    stick-table type ip size 1m expire 10m store http_req_rate(1m)
+
+# in the frontend receiving the traffic
+    http-request track-sc1 src table %[1]s if { path %[2]s }
+    http-request deny deny_status 429 if { path %[2]s } { sc_http_req_rate(1,%[1]s) gt %[3]d }
+`, zone, rec.Path, rec.RatePerMinute+rec.Burst)
+}
+
+func envoySnippet(zone string, rec models.RateLimitRecommendation) string {
+	return fmt.Sprintf(`# Route: send a per-client descriptor for the path to the rate limit service
+- match: { path: "%[2]s" }
+  route:
+    cluster: backend
+    rate_limits:
+    - actions:
+      - generic_key: { descriptor_value: %[1]s }
+      - remote_address: {}
+
+# envoyproxy/ratelimit service configuration
+domain: edge
+descriptors:
+- key: generic_key
+  value: %[1]s
+  descriptors:
+  - key: remote_address
+    rate_limit: { unit: minute, requests_per_unit: %[3]d }
+`, zone, rec.Path, rec.RatePerMinute)
+}
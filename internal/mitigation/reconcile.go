@@ -0,0 +1,159 @@
+package mitigation
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Store is the source of truth for which mitigations should be in force
+type Store interface {
+	GetActiveMitigations() ([]models.MitigationAction, int64, error)
+}
+
+// DriftReport describes how one executor's applied state differs from the
+// mitigation records
+type DriftReport struct {
+	Executor   string    `json:"executor"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Missing    []string  `json:"missing"`    // recorded but not enforced
+	Unexpected []string  `json:"unexpected"` // enforced but not recorded
+	Repaired   bool      `json:"repaired"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// InSync reports whether the executor matched the records
+func (d DriftReport) InSync() bool {
+	return d.Error == "" && len(d.Missing) == 0 && len(d.Unexpected) == 0
+}
+
+// Reconciler periodically compares mitigation records with each executor's
+// actual state and, when repair is enabled, re-applies missing entries and
+// removes ones that were added or left behind outside the dashboard
+type Reconciler struct {
+	store     Store
+	executors []Executor
+	repair    bool
+
+	mu      sync.RWMutex
+	reports []DriftReport
+}
+
+func NewReconciler(store Store, executors []Executor, repair bool) *Reconciler {
+	return &Reconciler{
+		store:     store,
+		executors: executors,
+		repair:    repair,
+	}
+}
+
+// Run reconciles on every interval until the context is cancelled
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Reconcile()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile performs one comparison pass across all executors
+func (r *Reconciler) Reconcile() []DriftReport {
+	actions, _, err := r.store.GetActiveMitigations()
+	if err != nil {
+		log.Printf("Error reading mitigations for reconciliation: %v", err)
+		return r.Reports()
+	}
+
+	reports := make([]DriftReport, 0, len(r.executors))
+	for _, executor := range r.executors {
+		report := r.reconcileExecutor(executor, actions)
+		if !report.InSync() {
+			log.Printf("⚠️  Mitigation drift on %s: %d missing, %d unexpected (repaired: %v) %s",
+				report.Executor, len(report.Missing), len(report.Unexpected), report.Repaired, report.Error)
+		}
+		reports = append(reports, report)
+	}
+
+	r.mu.Lock()
+	r.reports = reports
+	r.mu.Unlock()
+
+	return reports
+}
+
+// Reports returns the result of the last reconciliation pass
+func (r *Reconciler) Reports() []DriftReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reports := make([]DriftReport, len(r.reports))
+	copy(reports, r.reports)
+	return reports
+}
+
+func (r *Reconciler) reconcileExecutor(executor Executor, actions []models.MitigationAction) DriftReport {
+	report := DriftReport{
+		Executor:   executor.Name(),
+		CheckedAt:  time.Now(),
+		Missing:    make([]string, 0),
+		Unexpected: make([]string, 0),
+	}
+
+	desired := make(map[string]models.MitigationAction)
+	for _, action := range actions {
+		if action.Active && executor.Handles(action) {
+			desired[action.Target] = action
+		}
+	}
+
+	applied, err := executor.List()
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	appliedSet := make(map[string]bool, len(applied))
+	for _, target := range applied {
+		appliedSet[target] = true
+		if _, ok := desired[target]; !ok {
+			report.Unexpected = append(report.Unexpected, target)
+		}
+	}
+	for target := range desired {
+		if !appliedSet[target] {
+			report.Missing = append(report.Missing, target)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unexpected)
+
+	if !r.repair || (len(report.Missing) == 0 && len(report.Unexpected) == 0) {
+		return report
+	}
+
+	for _, target := range report.Missing {
+		if err := executor.Apply(desired[target]); err != nil {
+			report.Error = err.Error()
+		}
+	}
+	for _, target := range report.Unexpected {
+		if err := executor.Remove(models.MitigationAction{Target: target}); err != nil {
+			report.Error = err.Error()
+		}
+	}
+	report.Repaired = report.Error == ""
+
+	return report
+}
@@ -0,0 +1,214 @@
+package mitigation
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ratelimit"
+)
+
+const (
+	// DefaultEnforceRatePerMinute is the rate a rate limited client is held to
+	DefaultEnforceRatePerMinute = 60
+	// DefaultEnforceBurst is the requests a rate limited client may send at once
+	DefaultEnforceBurst = 20
+	// DefaultEnforceMaxClients bounds the token buckets kept at once
+	DefaultEnforceMaxClients = 100000
+)
+
+// enforceLimit is an action the enforcer holds for a target
+type enforceLimit struct {
+	id         string
+	actionType string
+	expires    time.Time // zero for never
+}
+
+// Verdict is the enforcer's decision on a request from a client
+type Verdict struct {
+	IP           string `json:"ip"`
+	ShouldAllow  bool   `json:"should_allow"`
+	Action       string `json:"action,omitempty"` // BLOCK or RATE_LIMIT
+	Target       string `json:"target,omitempty"`
+	MitigationID string `json:"mitigation_id,omitempty"`
+	// RateLimit is the state of the client's bucket, when rate limited
+	RateLimit *RateLimitState `json:"rate_limit,omitempty"`
+}
+
+// RateLimitState is a client's token bucket after a request
+type RateLimitState struct {
+	RatePerMinute int     `json:"rate_per_minute"`
+	Burst         int     `json:"burst"`
+	Remaining     int     `json:"remaining"`
+	RetryAfter    float64 `json:"retry_after_seconds,omitempty"`
+}
+
+// TokenBucketExecutor decides, for reverse proxies asking about each
+// request, whether a client should be let through: sources under a BLOCK
+// action never are, and sources under a RATE_LIMIT action are held to a
+// token bucket per client address, so each keeps a trickle of traffic
+// instead of being cut off. Buckets live in memory, per node.
+type TokenBucketExecutor struct {
+	ratePerMinute int
+	burst         int
+	buckets       *ratelimit.Limiter
+
+	mu      sync.Mutex
+	limits  map[netip.Prefix]enforceLimit
+	lengths []int // prefix lengths in limits, longest first
+}
+
+// NewTokenBucketExecutor holds rate limited clients to ratePerMinute with
+// bursts of burst, keeping at most maxClients buckets (0 for the defaults)
+func NewTokenBucketExecutor(ratePerMinute, burst, maxClients int) *TokenBucketExecutor {
+	if ratePerMinute <= 0 {
+		ratePerMinute = DefaultEnforceRatePerMinute
+	}
+	if burst <= 0 {
+		burst = DefaultEnforceBurst
+	}
+	if maxClients <= 0 {
+		maxClients = DefaultEnforceMaxClients
+	}
+	return &TokenBucketExecutor{
+		ratePerMinute: ratePerMinute,
+		burst:         burst,
+		buckets:       ratelimit.New(ratePerMinute, burst, maxClients),
+		limits:        make(map[netip.Prefix]enforceLimit),
+	}
+}
+
+func (t *TokenBucketExecutor) Name() string {
+	return "enforcer"
+}
+
+// Handles accepts BLOCK and RATE_LIMIT actions on addresses and prefixes
+func (t *TokenBucketExecutor) Handles(action models.MitigationAction) bool {
+	if action.Type != ActionBlock && action.Type != ActionRateLimit {
+		return false
+	}
+	_, ok := firewallTarget(action.Target)
+	return ok
+}
+
+// Apply holds the target to the action until it expires
+func (t *TokenBucketExecutor) Apply(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return fmt.Errorf("enforcer: invalid target %q", action.Target)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.limits[prefix] = enforceLimit{id: action.ID, actionType: action.Type, expires: action.ExpiresAt}
+	t.index()
+	return nil
+}
+
+// Remove lets the target through again
+func (t *TokenBucketExecutor) Remove(action models.MitigationAction) error {
+	prefix, ok := firewallTarget(action.Target)
+	if !ok {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.limits[prefix]; ok {
+		delete(t.limits, prefix)
+		t.index()
+	}
+	return nil
+}
+
+// List returns the targets held, leaving out those past their expiry
+func (t *TokenBucketExecutor) List() ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	targets := make([]string, 0, len(t.limits))
+	for prefix, limit := range t.limits {
+		if limit.expires.IsZero() || now.Before(limit.expires) {
+			targets = append(targets, formatTarget(prefix))
+		}
+	}
+	return targets, nil
+}
+
+// Limits returns the rate and burst rate limited clients are held to
+func (t *TokenBucketExecutor) Limits() (ratePerMinute, burst int) {
+	return t.ratePerMinute, t.burst
+}
+
+// Check decides on one request from a client, taking a token from its
+// bucket when it is rate limited. A client under several actions is held
+// to the one on its most specific prefix, and a BLOCK on any of them wins.
+func (t *TokenBucketExecutor) Check(ip string) (Verdict, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("invalid IP address %q", ip)
+	}
+	addr = addr.Unmap().WithZone("")
+	verdict := Verdict{IP: addr.String(), ShouldAllow: true}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var matched *netip.Prefix
+	for _, bits := range t.lengths {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix := netip.PrefixFrom(addr, bits).Masked()
+		limit, ok := t.limits[prefix]
+		if !ok || !limit.expires.IsZero() && !now.Before(limit.expires) {
+			continue
+		}
+		if limit.actionType == ActionBlock {
+			verdict.ShouldAllow = false
+			verdict.Action, verdict.Target, verdict.MitigationID = limit.actionType, formatTarget(prefix), limit.id
+			return verdict, nil
+		}
+		if matched == nil {
+			matched = &prefix
+		}
+	}
+	if matched == nil {
+		return verdict, nil
+	}
+
+	limit := t.limits[*matched]
+	verdict.Action, verdict.Target, verdict.MitigationID = limit.actionType, formatTarget(*matched), limit.id
+	state := &RateLimitState{RatePerMinute: t.ratePerMinute, Burst: t.burst}
+	verdict.RateLimit = state
+
+	decision := t.buckets.Allow(addr.String(), now)
+	if !decision.Allowed {
+		verdict.ShouldAllow = false
+		state.RetryAfter = math.Ceil(decision.RetryAfter.Seconds()*10) / 10
+	}
+	state.Remaining = decision.Remaining
+	return verdict, nil
+}
+
+// index lists the prefix lengths of the limits, longest first, so Check
+// looks up one prefix per length rather than scanning every limit
+func (t *TokenBucketExecutor) index() {
+	seen := make(map[int]bool)
+	t.lengths = t.lengths[:0]
+	for prefix := range t.limits {
+		if bits := prefix.Bits(); !seen[bits] {
+			seen[bits] = true
+			t.lengths = append(t.lengths, bits)
+		}
+	}
+	slices.SortFunc(t.lengths, func(a, b int) int { return b - a })
+}
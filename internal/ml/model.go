@@ -0,0 +1,159 @@
+// Package ml scores traffic windows with a pre-trained ONNX model, such as
+// an isolation forest or an autoencoder over the detection feature vector.
+// Models are evaluated in process by a small interpreter covering the
+// operators those models are exported with, so no ONNX runtime or cgo is
+// needed; a model using anything else is rejected when it is loaded.
+package ml
+
+import (
+	"fmt"
+	"os"
+)
+
+// Model is a loaded ONNX model. It is safe for concurrent use.
+type Model struct {
+	// Output names the graph output scored; empty for the first. Outlier
+	// detectors exported by skl2onnx output their label first and their
+	// decision function as "scores".
+	Output string
+	// Negate flips the score's sign, for outputs lower for anomalies, such
+	// as scikit-learn's decision functions
+	Negate bool
+
+	graph *graph
+	input string
+	width int // features per row; 0 when the model doesn't declare it
+	path  string
+}
+
+// Load reads an ONNX model and checks that it can be evaluated: one
+// float input of shape [N, features] or [features], and operators this
+// package implements
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := decodeModel(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	m := &Model{graph: g, path: path}
+	for _, input := range g.inputs {
+		if _, ok := g.initializers[input.name]; ok {
+			continue
+		}
+		if m.input != "" {
+			return nil, fmt.Errorf("%s: model has more than one input", path)
+		}
+		m.input = input.name
+		if len(input.shape) > 0 {
+			m.width = max(input.shape[len(input.shape)-1], 0)
+		}
+	}
+	if m.input == "" {
+		return nil, fmt.Errorf("%s: model has no input", path)
+	}
+	if len(g.outputs) == 0 {
+		return nil, fmt.Errorf("%s: model has no output", path)
+	}
+	for _, n := range g.nodes {
+		if _, ok := ops[n.opType]; !ok {
+			return nil, fmt.Errorf("%s: unsupported operator %s", path, n.opType)
+		}
+	}
+
+	return m, nil
+}
+
+// Features is how many features the model expects per row, or 0 if the
+// model doesn't say
+func (m *Model) Features() int {
+	return m.width
+}
+
+// Path is the file the model was loaded from
+func (m *Model) Path() string {
+	return m.path
+}
+
+// Score runs the model over one feature vector and returns its anomaly
+// score. A model whose first output has one value returns that value; one
+// whose output is as wide as its input is an autoencoder, scored by the
+// mean squared error of its reconstruction.
+func (m *Model) Score(features []float64) (float64, error) {
+	if m.width > 0 && len(features) != m.width {
+		return 0, fmt.Errorf("model expects %d features, got %d", m.width, len(features))
+	}
+
+	input := &tensor{shape: []int{1, len(features)}, data: features}
+	output, err := m.run(input)
+	if err != nil {
+		return 0, err
+	}
+
+	var score float64
+	switch len(output.data) {
+	case 1:
+		score = output.data[0]
+	case len(features):
+		for i, v := range output.data {
+			diff := v - features[i]
+			score += diff * diff
+		}
+		score /= float64(len(features))
+	default:
+		return 0, fmt.Errorf("model output has %d values; expected 1 or %d", len(output.data), len(features))
+	}
+	if m.Negate {
+		score = -score
+	}
+	return score, nil
+}
+
+// run evaluates the graph's nodes in order, as ONNX requires them to be
+// topologically sorted, and returns the scored graph output
+func (m *Model) run(input *tensor) (*tensor, error) {
+	values := make(map[string]*tensor, len(m.graph.initializers)+len(m.graph.nodes)+1)
+	for name, t := range m.graph.initializers {
+		values[name] = t
+	}
+	values[m.input] = input
+
+	for _, n := range m.graph.nodes {
+		inputs := make([]*tensor, len(n.inputs))
+		for i, name := range n.inputs {
+			if name == "" {
+				continue // omitted optional input
+			}
+			t, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: input %s is not computed before it is used", n.opType, name)
+			}
+			inputs[i] = t
+		}
+		if n.opType != "Constant" && (len(inputs) == 0 || inputs[0] == nil) {
+			return nil, fmt.Errorf("%s: missing input", n.opType)
+		}
+
+		out, err := ops[n.opType](n, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.opType, err)
+		}
+		// Secondary outputs aren't used by the supported operators
+		if len(n.outputs) > 0 {
+			values[n.outputs[0]] = out
+		}
+	}
+
+	name := m.graph.outputs[0].name
+	if m.Output != "" {
+		name = m.Output
+	}
+	output, ok := values[name]
+	if !ok {
+		return nil, fmt.Errorf("output %s is never computed", name)
+	}
+	return output, nil
+}
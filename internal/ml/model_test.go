@@ -0,0 +1,336 @@
+package ml
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The helpers below encode ONNX protobuf messages field by field, as the
+// onnx package would serialize them
+
+func message(fields ...[]byte) []byte {
+	var out []byte
+	for _, f := range fields {
+		out = append(out, f...)
+	}
+	return out
+}
+
+func bytesField(num protowire.Number, value []byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func stringField(num protowire.Number, value string) []byte {
+	return bytesField(num, []byte(value))
+}
+
+func varintField(num protowire.Number, value int64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+func packedInts(num protowire.Number, values []int64) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = protowire.AppendVarint(packed, uint64(v))
+	}
+	return bytesField(num, packed)
+}
+
+func packedFloats(num protowire.Number, values []float64) []byte {
+	packed := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		packed = binary.LittleEndian.AppendUint32(packed, math.Float32bits(float32(v)))
+	}
+	return bytesField(num, packed)
+}
+
+// floatTensor is a TensorProto of floats in float_data
+func floatTensor(name string, dims []int64, values ...float64) []byte {
+	return message(packedInts(1, dims), varintField(2, onnxFloat), packedFloats(4, values), stringField(8, name))
+}
+
+// int64Tensor is a TensorProto of int64s in raw_data, as skl2onnx writes them
+func int64Tensor(name string, dims []int64, values ...int64) []byte {
+	raw := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		raw = binary.LittleEndian.AppendUint64(raw, uint64(v))
+	}
+	return message(packedInts(1, dims), varintField(2, onnxInt64), stringField(8, name), bytesField(9, raw))
+}
+
+func intAttr(name string, value int64) []byte {
+	return bytesField(5, message(stringField(1, name), varintField(3, value), varintField(20, 2)))
+}
+
+func intsAttr(name string, values ...int64) []byte {
+	return bytesField(5, message(stringField(1, name), packedInts(8, values), varintField(20, 7)))
+}
+
+func floatsAttr(name string, values ...float64) []byte {
+	return bytesField(5, message(stringField(1, name), packedFloats(7, values), varintField(20, 6)))
+}
+
+func stringsAttr(name string, values ...string) []byte {
+	fields := [][]byte{stringField(1, name)}
+	for _, v := range values {
+		fields = append(fields, stringField(9, v))
+	}
+	fields = append(fields, varintField(20, 8))
+	return bytesField(5, message(fields...))
+}
+
+func nodeProto(opType, domain string, inputs, outputs []string, attrs ...[]byte) []byte {
+	var fields [][]byte
+	for _, in := range inputs {
+		fields = append(fields, stringField(1, in))
+	}
+	for _, out := range outputs {
+		fields = append(fields, stringField(2, out))
+	}
+	fields = append(fields, stringField(4, opType))
+	fields = append(fields, attrs...)
+	if domain != "" {
+		fields = append(fields, stringField(7, domain))
+	}
+	return bytesField(1, message(fields...))
+}
+
+// valueInfoProto is a ValueInfoProto of a tensor whose unknown dimensions are
+// named, as exporters declare the batch dimension
+func valueInfoProto(num protowire.Number, name string, elemType int64, dims ...int64) []byte {
+	var shape [][]byte
+	for _, d := range dims {
+		if d < 0 {
+			shape = append(shape, bytesField(1, stringField(2, "N")))
+		} else {
+			shape = append(shape, bytesField(1, varintField(1, d)))
+		}
+	}
+	tensorType := message(varintField(1, elemType), bytesField(2, message(shape...)))
+	return bytesField(num, message(stringField(1, name), bytesField(2, bytesField(1, tensorType))))
+}
+
+// isolationForest is laid out as skl2onnx exports an IsolationForest: each
+// tree sees its own feature subset through Gather, a TreeEnsembleRegressor
+// yields its path length, the lengths are concatenated and summed, and the
+// score 0.5 - 2^(-E(h)/c) is output as "scores" after the label. Its trees
+// isolate high request rates (feature 0) and SYN shares (feature 7).
+func isolationForest(branchFeature int64) []byte {
+	tree := func(i int, threshold float64) [][]byte {
+		in, out := "X"+string(rune('0'+i)), "path"+string(rune('0'+i))
+		return [][]byte{
+			nodeProto("Gather", "", []string{"X", "features" + string(rune('0'+i))}, []string{in}, intAttr("axis", 1)),
+			nodeProto("TreeEnsembleRegressor", "ai.onnx.ml", []string{in}, []string{out + "_raw"},
+				intsAttr("nodes_treeids", 0, 0, 0),
+				intsAttr("nodes_nodeids", 0, 1, 2),
+				// Leaves keep skl2onnx's placeholder feature IDs, which
+				// needn't be columns of the input
+				intsAttr("nodes_featureids", branchFeature, 99, 99),
+				stringsAttr("nodes_modes", "BRANCH_LEQ", "LEAF", "LEAF"),
+				floatsAttr("nodes_values", threshold, 0, 0),
+				intsAttr("nodes_truenodeids", 1, 0, 0),
+				intsAttr("nodes_falsenodeids", 2, 0, 0),
+				intsAttr("target_treeids", 0, 0),
+				intsAttr("target_nodeids", 1, 2),
+				intsAttr("target_ids", 0, 0),
+				// Normal traffic takes the long path
+				floatsAttr("target_weights", 3, 1),
+				intAttr("n_targets", 1),
+			),
+			nodeProto("Reshape", "", []string{out + "_raw", "column"}, []string{out}),
+		}
+	}
+
+	var graph [][]byte
+	graph = append(graph, tree(0, 100)...)
+	graph = append(graph, tree(1, 0.5)...)
+	graph = append(graph,
+		nodeProto("Concat", "", []string{"path0", "path1"}, []string{"paths"}, intAttr("axis", 1)),
+		nodeProto("ReduceSum", "", []string{"paths"}, []string{"depth"}, intsAttr("axes", 1), intAttr("keepdims", 1)),
+		nodeProto("Div", "", []string{"depth", "norm"}, []string{"ratio"}),
+		nodeProto("Neg", "", []string{"ratio"}, []string{"exponent"}),
+		nodeProto("Pow", "", []string{"two", "exponent"}, []string{"power"}),
+		nodeProto("Sub", "", []string{"offset", "power"}, []string{"scores"}),
+		nodeProto("Less", "", []string{"scores", "zero"}, []string{"outlier"}),
+		nodeProto("Where", "", []string{"outlier", "minus_one", "one"}, []string{"label_float"}),
+		nodeProto("Cast", "", []string{"label_float"}, []string{"label_column"}, intAttr("to", onnxInt64)),
+		nodeProto("Squeeze", "", []string{"label_column", "squeeze_axes"}, []string{"label"}),
+		bytesField(5, int64Tensor("features0", []int64{2}, 0, 5)),
+		bytesField(5, int64Tensor("features1", []int64{2}, 7, 3)),
+		bytesField(5, int64Tensor("column", []int64{2}, -1, 1)),
+		bytesField(5, int64Tensor("squeeze_axes", []int64{1}, 1)),
+		// n_trees * c(max_samples), with c(max_samples) = 2
+		bytesField(5, floatTensor("norm", []int64{1}, 4)),
+		bytesField(5, floatTensor("two", []int64{1}, 2)),
+		bytesField(5, floatTensor("offset", []int64{1}, 0.5)),
+		bytesField(5, floatTensor("zero", []int64{1}, 0)),
+		bytesField(5, int64Tensor("one", []int64{1}, 1)),
+		bytesField(5, int64Tensor("minus_one", []int64{1}, -1)),
+		valueInfoProto(11, "X", onnxFloat, -1, 12),
+		valueInfoProto(12, "label", onnxInt64, -1),
+		valueInfoProto(12, "scores", onnxFloat, -1, 1),
+	)
+	return message(varintField(1, 8), bytesField(7, message(graph...)))
+}
+
+func loadModel(t *testing.T, data []byte) *Model {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return m
+}
+
+func features(rps, synShare float64) []float64 {
+	f := make([]float64, 12)
+	f[0], f[7] = rps, synShare
+	return f
+}
+
+func TestIsolationForestScores(t *testing.T) {
+	m := loadModel(t, isolationForest(0))
+	if m.Features() != 12 {
+		t.Fatalf("Features() = %d, want 12", m.Features())
+	}
+	m.Output, m.Negate = "scores", true
+
+	tests := []struct {
+		name          string
+		rps, synShare float64
+		want          float64
+	}{
+		// Both trees take the long path: E(h) = 6, 0.5 - 2^-1.5
+		{"normal", 50, 0.1, -(0.5 - math.Pow(2, -1.5))},
+		// One short path: E(h) = 4, 0.5 - 2^-1
+		{"syn heavy", 50, 0.9, 0},
+		// Both short: E(h) = 2, 0.5 - 2^-0.5
+		{"flood", 5000, 0.9, -(0.5 - math.Pow(2, -0.5))},
+	}
+	for _, tt := range tests {
+		got, err := m.Score(features(tt.rps, tt.synShare))
+		if err != nil {
+			t.Fatalf("%s: Score: %v", tt.name, err)
+		}
+		// float32 initializers and casts
+		if math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: score = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsolationForestLabel(t *testing.T) {
+	m := loadModel(t, isolationForest(0))
+
+	for _, tt := range []struct {
+		rps  float64
+		want float64
+	}{{50, 1}, {5000, -1}} {
+		got, err := m.Score(features(tt.rps, 0.9))
+		if err != nil {
+			t.Fatalf("Score: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("label for %v rps = %v, want %v", tt.rps, got, tt.want)
+		}
+	}
+}
+
+func TestTreeBranchOnMissingFeature(t *testing.T) {
+	// Each tree sees two columns, so feature 2 doesn't exist
+	m := loadModel(t, isolationForest(2))
+	if _, err := m.Score(features(50, 0.1)); err == nil {
+		t.Fatal("Score succeeded with a branch on a missing feature")
+	}
+}
+
+func TestLoadRejectsUnsupportedOperator(t *testing.T) {
+	data := message(bytesField(7, message(
+		nodeProto("Softmax", "", []string{"X"}, []string{"Y"}),
+		valueInfoProto(11, "X", onnxFloat, -1, 12),
+		valueInfoProto(12, "Y", onnxFloat, -1, 12),
+	)))
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load accepted a model using Softmax")
+	}
+}
+
+func TestShapeOps(t *testing.T) {
+	x := &tensor{shape: []int{2, 3}, data: []float64{1, 2, 3, 4, 5, 6}}
+	shape := func(values ...float64) *tensor { return &tensor{shape: []int{len(values)}, data: values} }
+
+	tests := []struct {
+		name      string
+		op        string
+		n         node
+		inputs    []*tensor
+		wantShape []int
+		wantData  []float64
+	}{
+		{"reshape inferred", "Reshape", node{}, []*tensor{x, shape(-1)}, []int{6}, []float64{1, 2, 3, 4, 5, 6}},
+		{"reshape copied", "Reshape", node{}, []*tensor{x, shape(0, 3, 1)}, []int{2, 3, 1}, []float64{1, 2, 3, 4, 5, 6}},
+		{"concat rows", "Concat", nodeWith("axis", 0), []*tensor{x, x}, []int{4, 3}, []float64{1, 2, 3, 4, 5, 6, 1, 2, 3, 4, 5, 6}},
+		{"concat columns", "Concat", nodeWith("axis", -1), []*tensor{x, x}, []int{2, 6}, []float64{1, 2, 3, 1, 2, 3, 4, 5, 6, 4, 5, 6}},
+		{"gather columns", "Gather", nodeWith("axis", 1), []*tensor{x, shape(2, -3)}, []int{2, 2}, []float64{3, 1, 6, 4}},
+		{"gather rows", "Gather", node{}, []*tensor{x, shape(1)}, []int{1, 3}, []float64{4, 5, 6}},
+		{"feature extractor", "ArrayFeatureExtractor", node{}, []*tensor{x, shape(1)}, []int{2, 1}, []float64{2, 5}},
+		{"flatten", "Flatten", node{}, []*tensor{{shape: []int{2, 1, 3}, data: x.data}}, []int{2, 3}, []float64{1, 2, 3, 4, 5, 6}},
+		{"unsqueeze", "Unsqueeze", node{}, []*tensor{x, shape(0)}, []int{1, 2, 3}, []float64{1, 2, 3, 4, 5, 6}},
+		{"where", "Where", node{}, []*tensor{shape(1, 0, 1), x, shape(0)}, []int{2, 3}, []float64{1, 0, 3, 4, 0, 6}},
+		{"max", "Max", node{}, []*tensor{x, shape(2), shape(0, 0, 5)}, []int{2, 3}, []float64{2, 2, 5, 4, 5, 6}},
+		{"cast to int", "Cast", nodeWith("to", onnxInt64), []*tensor{shape(-1.5, 2.7)}, []int{2}, []float64{-1, 2}},
+		{"cast to bool", "Cast", nodeWith("to", onnxBool), []*tensor{shape(0, -3)}, []int{2}, []float64{0, 1}},
+	}
+	for _, tt := range tests {
+		got, err := ops[tt.op](tt.n, tt.inputs)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got.shape, tt.wantShape) || !slices.Equal(got.data, tt.wantData) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, got.shape, got.data, tt.wantShape, tt.wantData)
+		}
+	}
+}
+
+func TestShapeOpErrors(t *testing.T) {
+	x := &tensor{shape: []int{2, 3}, data: []float64{1, 2, 3, 4, 5, 6}}
+	shape := func(values ...float64) *tensor { return &tensor{shape: []int{len(values)}, data: values} }
+
+	for _, tt := range []struct {
+		name   string
+		op     string
+		n      node
+		inputs []*tensor
+	}{
+		{"reshape size", "Reshape", node{}, []*tensor{x, shape(4)}},
+		{"gather index", "Gather", nodeWith("axis", 1), []*tensor{x, shape(3)}},
+		{"concat shapes", "Concat", node{}, []*tensor{x, shape(1, 2)}},
+		{"squeeze wide", "Squeeze", node{}, []*tensor{x, shape(0)}},
+		{"cast string", "Cast", nodeWith("to", 8), []*tensor{x}},
+	} {
+		if _, err := ops[tt.op](tt.n, tt.inputs); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func nodeWith(name string, value int64) node {
+	return node{attributes: map[string]attribute{name: {i: value}}}
+}
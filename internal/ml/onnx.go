@@ -0,0 +1,396 @@
+package ml
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ONNX tensor element types
+const (
+	onnxFloat  = 1
+	onnxUint8  = 2
+	onnxInt8   = 3
+	onnxUint16 = 4
+	onnxInt16  = 5
+	onnxInt32  = 6
+	onnxInt64  = 7
+	onnxBool   = 9
+	onnxDouble = 11
+	onnxUint32 = 12
+	onnxUint64 = 13
+)
+
+// graph is the part of an ONNX GraphProto inference needs
+type graph struct {
+	nodes        []node
+	initializers map[string]*tensor
+	inputs       []valueInfo
+	outputs      []valueInfo
+}
+
+// node is an ONNX NodeProto
+type node struct {
+	opType     string
+	domain     string
+	inputs     []string
+	outputs    []string
+	attributes map[string]attribute
+}
+
+// attribute is an ONNX AttributeProto; only the field its type uses is set
+type attribute struct {
+	f       float64
+	i       int64
+	s       string
+	t       *tensor
+	floats  []float64
+	ints    []int64
+	strings []string
+}
+
+// valueInfo is a graph input or output and its shape; unknown dimensions are -1
+type valueInfo struct {
+	name  string
+	shape []int
+}
+
+// fields walks the fields of a protobuf message, calling fn with each
+// field's number, wire type and encoded value
+func fields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, typ, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// bytesValue decodes a length-delimited field value
+func bytesValue(value []byte) []byte {
+	v, _ := protowire.ConsumeBytes(value)
+	return v
+}
+
+// varints decodes a repeated varint field, packed or not
+func varints(typ protowire.Type, value []byte) ([]int64, error) {
+	if typ == protowire.VarintType {
+		v, _ := protowire.ConsumeVarint(value)
+		return []int64{int64(v)}, nil
+	}
+
+	packed := bytesValue(value)
+	var out []int64
+	for len(packed) > 0 {
+		v, n := protowire.ConsumeVarint(packed)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		out = append(out, int64(v))
+		packed = packed[n:]
+	}
+	return out, nil
+}
+
+// float32s decodes a repeated float field, packed or not
+func float32s(typ protowire.Type, value []byte) []float64 {
+	if typ == protowire.Fixed32Type {
+		v, _ := protowire.ConsumeFixed32(value)
+		return []float64{float64(math.Float32frombits(v))}
+	}
+
+	packed := bytesValue(value)
+	out := make([]float64, 0, len(packed)/4)
+	for ; len(packed) >= 4; packed = packed[4:] {
+		out = append(out, float64(math.Float32frombits(binary.LittleEndian.Uint32(packed))))
+	}
+	return out
+}
+
+// float64s decodes a repeated double field, packed or not
+func float64s(typ protowire.Type, value []byte) []float64 {
+	if typ == protowire.Fixed64Type {
+		v, _ := protowire.ConsumeFixed64(value)
+		return []float64{math.Float64frombits(v)}
+	}
+
+	packed := bytesValue(value)
+	out := make([]float64, 0, len(packed)/8)
+	for ; len(packed) >= 8; packed = packed[8:] {
+		out = append(out, math.Float64frombits(binary.LittleEndian.Uint64(packed)))
+	}
+	return out
+}
+
+// decodeModel decodes a ModelProto and returns its graph
+func decodeModel(data []byte) (*graph, error) {
+	var g *graph
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num == 7 && typ == protowire.BytesType {
+			var err error
+			g, err = decodeGraph(bytesValue(value))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	return g, nil
+}
+
+// decodeGraph decodes a GraphProto
+func decodeGraph(data []byte) (*graph, error) {
+	g := &graph{initializers: make(map[string]*tensor)}
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			n, err := decodeNode(bytesValue(value))
+			if err != nil {
+				return err
+			}
+			g.nodes = append(g.nodes, n)
+		case 5:
+			name, t, err := decodeTensor(bytesValue(value))
+			if err != nil {
+				return fmt.Errorf("initializer %s: %w", name, err)
+			}
+			g.initializers[name] = t
+		case 11, 12:
+			info, err := decodeValueInfo(bytesValue(value))
+			if err != nil {
+				return err
+			}
+			if num == 11 {
+				g.inputs = append(g.inputs, info)
+			} else {
+				g.outputs = append(g.outputs, info)
+			}
+		}
+		return nil
+	})
+	return g, err
+}
+
+// decodeNode decodes a NodeProto
+func decodeNode(data []byte) (node, error) {
+	n := node{attributes: make(map[string]attribute)}
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			n.inputs = append(n.inputs, string(bytesValue(value)))
+		case 2:
+			n.outputs = append(n.outputs, string(bytesValue(value)))
+		case 4:
+			n.opType = string(bytesValue(value))
+		case 7:
+			n.domain = string(bytesValue(value))
+		case 5:
+			name, attr, err := decodeAttribute(bytesValue(value))
+			if err != nil {
+				return fmt.Errorf("attribute %s: %w", name, err)
+			}
+			n.attributes[name] = attr
+		}
+		return nil
+	})
+	return n, err
+}
+
+// decodeAttribute decodes an AttributeProto
+func decodeAttribute(data []byte) (string, attribute, error) {
+	var name string
+	var attr attribute
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 1:
+			name = string(bytesValue(value))
+		case 2:
+			attr.f = float32s(typ, value)[0]
+		case 3:
+			v, _ := protowire.ConsumeVarint(value)
+			attr.i = int64(v)
+		case 4:
+			attr.s = string(bytesValue(value))
+		case 5:
+			_, t, err := decodeTensor(bytesValue(value))
+			if err != nil {
+				return err
+			}
+			attr.t = t
+		case 7:
+			attr.floats = append(attr.floats, float32s(typ, value)...)
+		case 8:
+			ints, err := varints(typ, value)
+			if err != nil {
+				return err
+			}
+			attr.ints = append(attr.ints, ints...)
+		case 9:
+			attr.strings = append(attr.strings, string(bytesValue(value)))
+		}
+		return nil
+	})
+	return name, attr, err
+}
+
+// decodeTensor decodes a TensorProto of floats, doubles or integers into
+// float64 values
+func decodeTensor(data []byte) (string, *tensor, error) {
+	var (
+		name     string
+		dims     []int64
+		dataType int64
+		values   []float64
+		raw      []byte
+	)
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 1:
+			d, err := varints(typ, value)
+			if err != nil {
+				return err
+			}
+			dims = append(dims, d...)
+		case 2:
+			v, _ := protowire.ConsumeVarint(value)
+			dataType = int64(v)
+		case 4:
+			values = append(values, float32s(typ, value)...)
+		case 5, 7:
+			ints, err := varints(typ, value)
+			if err != nil {
+				return err
+			}
+			for _, v := range ints {
+				if num == 5 {
+					v = int64(int32(v))
+				}
+				values = append(values, float64(v))
+			}
+		case 8:
+			name = string(bytesValue(value))
+		case 9:
+			raw = bytesValue(value)
+		case 10:
+			values = append(values, float64s(typ, value)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return name, nil, err
+	}
+
+	if raw != nil {
+		values, err = decodeRaw(raw, dataType)
+		if err != nil {
+			return name, nil, err
+		}
+	}
+
+	shape := make([]int, len(dims))
+	for i, d := range dims {
+		shape[i] = int(d)
+	}
+	t := &tensor{shape: shape, data: values}
+	if t.size() != len(values) {
+		return name, nil, fmt.Errorf("shape %v holds %d values, got %d", shape, t.size(), len(values))
+	}
+	return name, t, nil
+}
+
+// decodeRaw decodes a tensor's little-endian raw_data
+func decodeRaw(raw []byte, dataType int64) ([]float64, error) {
+	var width int
+	switch dataType {
+	case onnxBool, onnxUint8, onnxInt8:
+		width = 1
+	case onnxFloat, onnxInt32:
+		width = 4
+	case onnxDouble, onnxInt64:
+		width = 8
+	default:
+		return nil, fmt.Errorf("unsupported tensor data type %d", dataType)
+	}
+
+	values := make([]float64, 0, len(raw)/width)
+	for ; len(raw) >= width; raw = raw[width:] {
+		switch dataType {
+		case onnxBool, onnxUint8:
+			values = append(values, float64(raw[0]))
+		case onnxInt8:
+			values = append(values, float64(int8(raw[0])))
+		case onnxFloat:
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))))
+		case onnxInt32:
+			values = append(values, float64(int32(binary.LittleEndian.Uint32(raw))))
+		case onnxDouble:
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(raw)))
+		case onnxInt64:
+			values = append(values, float64(int64(binary.LittleEndian.Uint64(raw))))
+		}
+	}
+	return values, nil
+}
+
+// decodeValueInfo decodes a ValueInfoProto's name and tensor shape
+func decodeValueInfo(data []byte) (valueInfo, error) {
+	var info valueInfo
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 1:
+			info.name = string(bytesValue(value))
+		case 2:
+			// TypeProto.tensor_type.shape.dim
+			return fields(bytesValue(value), func(num protowire.Number, _ protowire.Type, value []byte) error {
+				if num != 1 {
+					return nil
+				}
+				return fields(bytesValue(value), func(num protowire.Number, _ protowire.Type, value []byte) error {
+					if num != 2 {
+						return nil
+					}
+					return fields(bytesValue(value), func(num protowire.Number, _ protowire.Type, value []byte) error {
+						if num != 1 {
+							return nil
+						}
+						size := -1
+						fields(bytesValue(value), func(num protowire.Number, typ protowire.Type, value []byte) error {
+							if num == 1 && typ == protowire.VarintType {
+								v, _ := protowire.ConsumeVarint(value)
+								size = int(v)
+							}
+							return nil
+						})
+						info.shape = append(info.shape, size)
+						return nil
+					})
+				})
+			})
+		}
+		return nil
+	})
+	return info, err
+}
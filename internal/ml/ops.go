@@ -0,0 +1,801 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// tensor is a dense row-major tensor of float64 values
+type tensor struct {
+	shape []int
+	data  []float64
+}
+
+// size is the number of values a tensor of its shape holds
+func (t *tensor) size() int {
+	n := 1
+	for _, d := range t.shape {
+		n *= d
+	}
+	return n
+}
+
+// matrix returns the tensor's shape as rows and columns, treating a
+// vector as one row
+func (t *tensor) matrix() (rows, cols int, err error) {
+	switch len(t.shape) {
+	case 1:
+		return 1, t.shape[0], nil
+	case 2:
+		return t.shape[0], t.shape[1], nil
+	}
+	return 0, 0, fmt.Errorf("expected a matrix, got shape %v", t.shape)
+}
+
+// op evaluates one node given its input tensors
+type op func(n node, inputs []*tensor) (*tensor, error)
+
+// ops are the operators a model may use: enough for dense autoencoders
+// and scoring networks, and tree ensembles such as isolation forests with
+// the path length arithmetic skl2onnx exports around them
+var ops = map[string]op{
+	"MatMul":                gemmOp(false),
+	"Gemm":                  gemmOp(true),
+	"Add":                   binaryOp(func(a, b float64) float64 { return a + b }),
+	"Sub":                   binaryOp(func(a, b float64) float64 { return a - b }),
+	"Mul":                   binaryOp(func(a, b float64) float64 { return a * b }),
+	"Div":                   binaryOp(func(a, b float64) float64 { return a / b }),
+	"Pow":                   binaryOp(math.Pow),
+	"Relu":                  unaryOp(func(n node, x float64) float64 { return math.Max(x, 0) }),
+	"Sigmoid":               unaryOp(func(n node, x float64) float64 { return 1 / (1 + math.Exp(-x)) }),
+	"Tanh":                  unaryOp(func(n node, x float64) float64 { return math.Tanh(x) }),
+	"Exp":                   unaryOp(func(n node, x float64) float64 { return math.Exp(x) }),
+	"Log":                   unaryOp(func(n node, x float64) float64 { return math.Log(x) }),
+	"Abs":                   unaryOp(func(n node, x float64) float64 { return math.Abs(x) }),
+	"Neg":                   unaryOp(func(n node, x float64) float64 { return -x }),
+	"Sqrt":                  unaryOp(func(n node, x float64) float64 { return math.Sqrt(x) }),
+	"Identity":              unaryOp(func(n node, x float64) float64 { return x }),
+	"LeakyRelu":             unaryOp(leakyRelu),
+	"ReduceMean":            reduceOp(true),
+	"ReduceSum":             reduceOp(false),
+	"Max":                   variadicOp(math.Max),
+	"Min":                   variadicOp(math.Min),
+	"Equal":                 binaryOp(func(a, b float64) float64 { return boolValue(a == b) }),
+	"Less":                  binaryOp(func(a, b float64) float64 { return boolValue(a < b) }),
+	"LessOrEqual":           binaryOp(func(a, b float64) float64 { return boolValue(a <= b) }),
+	"Greater":               binaryOp(func(a, b float64) float64 { return boolValue(a > b) }),
+	"GreaterOrEqual":        binaryOp(func(a, b float64) float64 { return boolValue(a >= b) }),
+	"And":                   binaryOp(func(a, b float64) float64 { return boolValue(a != 0 && b != 0) }),
+	"Or":                    binaryOp(func(a, b float64) float64 { return boolValue(a != 0 || b != 0) }),
+	"Not":                   unaryOp(func(n node, x float64) float64 { return boolValue(x == 0) }),
+	"Where":                 whereOp,
+	"Constant":              constantOp,
+	"Cast":                  castOp,
+	"Reshape":               reshapeOp,
+	"Flatten":               flattenOp,
+	"Squeeze":               squeezeOp(false),
+	"Unsqueeze":             squeezeOp(true),
+	"Concat":                concatOp,
+	"Gather":                gatherOp,
+	"ArrayFeatureExtractor": arrayFeatureExtractor,
+	"TreeEnsembleRegressor": treeEnsemble,
+}
+
+// boolValue is how booleans are held: 1 for true, 0 for false
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// attrFloat returns a float attribute or its default
+func (n node) attrFloat(name string, def float64) float64 {
+	if a, ok := n.attributes[name]; ok {
+		return a.f
+	}
+	return def
+}
+
+// attrInt returns an integer attribute or its default
+func (n node) attrInt(name string, def int64) int64 {
+	if a, ok := n.attributes[name]; ok {
+		return a.i
+	}
+	return def
+}
+
+func leakyRelu(n node, x float64) float64 {
+	if x < 0 {
+		return n.attrFloat("alpha", 0.01) * x
+	}
+	return x
+}
+
+func unaryOp(fn func(n node, x float64) float64) op {
+	return func(n node, inputs []*tensor) (*tensor, error) {
+		x := inputs[0]
+		out := &tensor{shape: x.shape, data: make([]float64, len(x.data))}
+		for i, v := range x.data {
+			out.data[i] = fn(n, v)
+		}
+		return out, nil
+	}
+}
+
+// binaryOp applies fn elementwise with numpy-style broadcasting
+func binaryOp(fn func(a, b float64) float64) op {
+	return func(n node, inputs []*tensor) (*tensor, error) {
+		if len(inputs) != 2 || inputs[1] == nil {
+			return nil, fmt.Errorf("expected 2 inputs, got %d", len(inputs))
+		}
+		return broadcast(inputs[0], inputs[1], fn)
+	}
+}
+
+func broadcast(a, b *tensor, fn func(a, b float64) float64) (*tensor, error) {
+	return broadcastAll([]*tensor{a, b}, func(v []float64) float64 { return fn(v[0], v[1]) })
+}
+
+// broadcastAll applies fn elementwise over any number of tensors with
+// numpy-style broadcasting
+func broadcastAll(ts []*tensor, fn func(values []float64) float64) (*tensor, error) {
+	rank := 0
+	for _, t := range ts {
+		rank = max(rank, len(t.shape))
+	}
+	padded := make([][]int, len(ts))
+	for i, t := range ts {
+		padded[i] = make([]int, rank)
+		for d := range padded[i] {
+			padded[i][d] = 1
+		}
+		copy(padded[i][rank-len(t.shape):], t.shape)
+	}
+
+	shape := make([]int, rank)
+	for d := range shape {
+		shape[d] = 1
+		for i := range ts {
+			switch size := padded[i][d]; {
+			case size == shape[d], size == 1:
+			case shape[d] == 1:
+				shape[d] = size
+			default:
+				return nil, fmt.Errorf("cannot broadcast shapes %v and %v", ts[0].shape, ts[i].shape)
+			}
+		}
+	}
+
+	out := &tensor{shape: shape}
+	out.data = make([]float64, out.size())
+	index := make([]int, rank)
+	values := make([]float64, len(ts))
+	for o := range out.data {
+		for i, t := range ts {
+			at := 0
+			for d := 0; d < rank; d++ {
+				at = at*padded[i][d] + min(index[d], padded[i][d]-1)
+			}
+			values[i] = t.data[at]
+		}
+		out.data[o] = fn(values)
+
+		for d := rank - 1; d >= 0; d-- {
+			if index[d]++; index[d] < shape[d] {
+				break
+			}
+			index[d] = 0
+		}
+	}
+	return out, nil
+}
+
+// variadicOp folds fn elementwise over every input, broadcasting them
+func variadicOp(fn func(a, b float64) float64) op {
+	return func(n node, inputs []*tensor) (*tensor, error) {
+		for _, t := range inputs {
+			if t == nil {
+				return nil, fmt.Errorf("missing input")
+			}
+		}
+		return broadcastAll(inputs, func(v []float64) float64 {
+			acc := v[0]
+			for _, x := range v[1:] {
+				acc = fn(acc, x)
+			}
+			return acc
+		})
+	}
+}
+
+// whereOp picks from the second input where the condition holds and from
+// the third elsewhere
+func whereOp(n node, inputs []*tensor) (*tensor, error) {
+	if len(inputs) != 3 || inputs[1] == nil || inputs[2] == nil {
+		return nil, fmt.Errorf("expected 3 inputs, got %d", len(inputs))
+	}
+	return broadcastAll(inputs, func(v []float64) float64 {
+		if v[0] != 0 {
+			return v[1]
+		}
+		return v[2]
+	})
+}
+
+// constantOp produces the tensor of its value attribute, or of one of the
+// value_float(s) and value_int(s) shorthands
+func constantOp(n node, inputs []*tensor) (*tensor, error) {
+	if a, ok := n.attributes["value"]; ok && a.t != nil {
+		return a.t, nil
+	}
+	if a, ok := n.attributes["value_float"]; ok {
+		return &tensor{shape: []int{}, data: []float64{a.f}}, nil
+	}
+	if a, ok := n.attributes["value_int"]; ok {
+		return &tensor{shape: []int{}, data: []float64{float64(a.i)}}, nil
+	}
+	if a, ok := n.attributes["value_floats"]; ok {
+		return &tensor{shape: []int{len(a.floats)}, data: a.floats}, nil
+	}
+	if a, ok := n.attributes["value_ints"]; ok {
+		data := make([]float64, len(a.ints))
+		for i, v := range a.ints {
+			data[i] = float64(v)
+		}
+		return &tensor{shape: []int{len(data)}, data: data}, nil
+	}
+	return nil, fmt.Errorf("no supported value attribute")
+}
+
+// castOp converts to the ONNX element type in the to attribute. Values are
+// held as float64, so this rounds them as the target type would.
+func castOp(n node, inputs []*tensor) (*tensor, error) {
+	var convert func(x float64) float64
+	switch to := n.attrInt("to", 0); to {
+	case onnxFloat:
+		convert = func(x float64) float64 { return float64(float32(x)) }
+	case onnxDouble:
+		convert = func(x float64) float64 { return x }
+	case onnxBool:
+		convert = func(x float64) float64 { return boolValue(x != 0) }
+	case onnxUint8, onnxInt8, onnxUint16, onnxInt16, onnxInt32, onnxInt64, onnxUint32, onnxUint64:
+		convert = math.Trunc
+	default:
+		return nil, fmt.Errorf("unsupported cast to element type %d", to)
+	}
+
+	x := inputs[0]
+	out := &tensor{shape: x.shape, data: make([]float64, len(x.data))}
+	for i, v := range x.data {
+		out.data[i] = convert(v)
+	}
+	return out, nil
+}
+
+// axis resolves a possibly negative axis attribute against a rank
+func axis(n node, name string, def int64, rank int) (int, error) {
+	a := n.attrInt(name, def)
+	if a < 0 {
+		a += int64(rank)
+	}
+	if a < 0 || int(a) >= rank {
+		return 0, fmt.Errorf("%s %d out of range for rank %d", name, n.attrInt(name, def), rank)
+	}
+	return int(a), nil
+}
+
+// reshaped returns x's values under a new shape, which must hold as many
+func reshaped(x *tensor, shape []int) (*tensor, error) {
+	out := &tensor{shape: shape, data: x.data}
+	if out.size() != len(x.data) {
+		return nil, fmt.Errorf("cannot reshape %v to %v", x.shape, shape)
+	}
+	return out, nil
+}
+
+// reshapeOp takes the shape from its second input, where 0 keeps the
+// input's dimension and -1 is inferred
+func reshapeOp(n node, inputs []*tensor) (*tensor, error) {
+	if len(inputs) != 2 || inputs[1] == nil {
+		return nil, fmt.Errorf("expected 2 inputs, got %d", len(inputs))
+	}
+	x := inputs[0]
+	allowZero := n.attrInt("allowzero", 0) != 0
+
+	shape := make([]int, len(inputs[1].data))
+	inferred, known := -1, 1
+	for i, v := range inputs[1].data {
+		d := int(v)
+		switch {
+		case d == -1 && inferred < 0:
+			inferred = i
+			continue
+		case d == 0 && !allowZero:
+			if i >= len(x.shape) {
+				return nil, fmt.Errorf("shape %v copies a dimension %v doesn't have", inputs[1].data, x.shape)
+			}
+			d = x.shape[i]
+		case d < 0:
+			return nil, fmt.Errorf("invalid shape %v", inputs[1].data)
+		}
+		shape[i] = d
+		known *= d
+	}
+	if inferred >= 0 {
+		if known == 0 || len(x.data)%known != 0 {
+			return nil, fmt.Errorf("cannot reshape %v to %v", x.shape, inputs[1].data)
+		}
+		shape[inferred] = len(x.data) / known
+	}
+	return reshaped(x, shape)
+}
+
+// flattenOp reshapes to a matrix, the dimensions before axis making rows
+func flattenOp(n node, inputs []*tensor) (*tensor, error) {
+	x := inputs[0]
+	// axis may equal the rank, flattening everything into one row
+	at, err := axis(n, "axis", 1, len(x.shape)+1)
+	if err != nil {
+		return nil, err
+	}
+	rows := 1
+	for _, d := range x.shape[:at] {
+		rows *= d
+	}
+	cols := 1
+	for _, d := range x.shape[at:] {
+		cols *= d
+	}
+	return reshaped(x, []int{rows, cols})
+}
+
+// squeezeOp drops (Squeeze) or inserts (Unsqueeze) dimensions of size 1
+// at the axes given as attribute or second input; Squeeze without axes
+// drops every dimension of size 1
+func squeezeOp(insert bool) op {
+	return func(n node, inputs []*tensor) (*tensor, error) {
+		x := inputs[0]
+		var axes []int64
+		if a, ok := n.attributes["axes"]; ok {
+			axes = a.ints
+		} else if len(inputs) > 1 && inputs[1] != nil {
+			for _, v := range inputs[1].data {
+				axes = append(axes, int64(v))
+			}
+		}
+
+		rank := len(x.shape)
+		if insert {
+			rank += len(axes)
+		}
+		marked := make([]bool, rank)
+		for _, a := range axes {
+			if a < 0 {
+				a += int64(rank)
+			}
+			if a < 0 || int(a) >= rank {
+				return nil, fmt.Errorf("axis %d out of range for rank %d", a, rank)
+			}
+			marked[a] = true
+		}
+
+		var shape []int
+		if insert {
+			rest := x.shape
+			for _, m := range marked {
+				if m {
+					shape = append(shape, 1)
+				} else {
+					shape = append(shape, rest[0])
+					rest = rest[1:]
+				}
+			}
+		} else {
+			for i, d := range x.shape {
+				if (marked[i] || len(axes) == 0) && d == 1 {
+					continue
+				}
+				if marked[i] {
+					return nil, fmt.Errorf("cannot squeeze dimension %d of %v", i, x.shape)
+				}
+				shape = append(shape, d)
+			}
+		}
+		return reshaped(x, shape)
+	}
+}
+
+// concatOp joins its inputs along an axis
+func concatOp(n node, inputs []*tensor) (*tensor, error) {
+	first := inputs[0]
+	at, err := axis(n, "axis", 0, len(first.shape))
+	if err != nil {
+		return nil, err
+	}
+
+	shape := append([]int(nil), first.shape...)
+	shape[at] = 0
+	for _, t := range inputs {
+		if t == nil || len(t.shape) != len(first.shape) {
+			return nil, fmt.Errorf("inputs of different ranks")
+		}
+		for d := range shape {
+			if d != at && t.shape[d] != first.shape[d] {
+				return nil, fmt.Errorf("cannot concatenate %v and %v on axis %d", first.shape, t.shape, at)
+			}
+		}
+		shape[at] += t.shape[at]
+	}
+
+	// Each input contributes a block of its axis and those after it per
+	// index of the axes before it
+	outer := 1
+	for _, d := range shape[:at] {
+		outer *= d
+	}
+	out := &tensor{shape: shape, data: make([]float64, 0, (&tensor{shape: shape}).size())}
+	for o := 0; o < outer; o++ {
+		for _, t := range inputs {
+			block := len(t.data) / max(outer, 1)
+			out.data = append(out.data, t.data[o*block:(o+1)*block]...)
+		}
+	}
+	return out, nil
+}
+
+// gatherOp takes the entries at the indices of its second input along an
+// axis, negative indices counting from the end
+func gatherOp(n node, inputs []*tensor) (*tensor, error) {
+	if len(inputs) != 2 || inputs[1] == nil {
+		return nil, fmt.Errorf("expected 2 inputs, got %d", len(inputs))
+	}
+	x, indices := inputs[0], inputs[1]
+	at, err := axis(n, "axis", 0, len(x.shape))
+	if err != nil {
+		return nil, err
+	}
+	return take(x, at, indices)
+}
+
+// arrayFeatureExtractor is the ai.onnx.ml operator taking the columns at
+// the indices of its second input along the last axis
+func arrayFeatureExtractor(n node, inputs []*tensor) (*tensor, error) {
+	if len(inputs) != 2 || inputs[1] == nil {
+		return nil, fmt.Errorf("expected 2 inputs, got %d", len(inputs))
+	}
+	x := inputs[0]
+	if len(x.shape) == 0 {
+		return nil, fmt.Errorf("cannot extract from a scalar")
+	}
+	// The indices always form the last axis of the output
+	flat := &tensor{shape: []int{len(inputs[1].data)}, data: inputs[1].data}
+	return take(x, len(x.shape)-1, flat)
+}
+
+// take gathers x's entries at indices along an axis
+func take(x *tensor, at int, indices *tensor) (*tensor, error) {
+	size := x.shape[at]
+	outer, inner := 1, 1
+	for _, d := range x.shape[:at] {
+		outer *= d
+	}
+	for _, d := range x.shape[at+1:] {
+		inner *= d
+	}
+
+	shape := append(append(append([]int(nil), x.shape[:at]...), indices.shape...), x.shape[at+1:]...)
+	out := &tensor{shape: shape, data: make([]float64, 0, outer*len(indices.data)*inner)}
+	for o := 0; o < outer; o++ {
+		for _, v := range indices.data {
+			i := int(v)
+			if i < 0 {
+				i += size
+			}
+			if i < 0 || i >= size {
+				return nil, fmt.Errorf("index %d out of range for dimension of %d", int(v), size)
+			}
+			start := (o*size + i) * inner
+			out.data = append(out.data, x.data[start:start+inner]...)
+		}
+	}
+	return out, nil
+}
+
+// gemmOp multiplies two matrices; as Gemm it also honours transA, transB,
+// alpha and beta and adds the optional third input
+func gemmOp(gemm bool) op {
+	return func(n node, inputs []*tensor) (*tensor, error) {
+		if len(inputs) < 2 {
+			return nil, fmt.Errorf("expected at least 2 inputs, got %d", len(inputs))
+		}
+		a, b := inputs[0], inputs[1]
+		ar, ac, err := a.matrix()
+		if err != nil {
+			return nil, err
+		}
+		br, bc, err := b.matrix()
+		if err != nil {
+			return nil, err
+		}
+
+		aStride, bStride := ac, bc
+		at := func(i, k int) float64 { return a.data[i*aStride+k] }
+		if gemm && n.attrInt("transA", 0) != 0 {
+			ar, ac = ac, ar
+			at = func(i, k int) float64 { return a.data[k*aStride+i] }
+		}
+		bt := func(k, j int) float64 { return b.data[k*bStride+j] }
+		if gemm && n.attrInt("transB", 0) != 0 {
+			br, bc = bc, br
+			bt = func(k, j int) float64 { return b.data[j*bStride+k] }
+		}
+		if ac != br {
+			return nil, fmt.Errorf("cannot multiply %v by %v", a.shape, b.shape)
+		}
+
+		alpha := 1.0
+		if gemm {
+			alpha = n.attrFloat("alpha", 1)
+		}
+		out := &tensor{shape: []int{ar, bc}, data: make([]float64, ar*bc)}
+		for i := 0; i < ar; i++ {
+			for j := 0; j < bc; j++ {
+				sum := 0.0
+				for k := 0; k < ac; k++ {
+					sum += at(i, k) * bt(k, j)
+				}
+				out.data[i*bc+j] = alpha * sum
+			}
+		}
+
+		if gemm && len(inputs) > 2 && inputs[2] != nil {
+			beta := n.attrFloat("beta", 1)
+			return broadcast(out, inputs[2], func(x, c float64) float64 { return x + beta*c })
+		}
+		return out, nil
+	}
+}
+
+// reduceOp sums or averages over the axes given as attribute or second
+// input, or over every axis when there are none
+func reduceOp(mean bool) op {
+	return func(n node, inputs []*tensor) (*tensor, error) {
+		x := inputs[0]
+		rank := len(x.shape)
+
+		var axes []int64
+		if a, ok := n.attributes["axes"]; ok {
+			axes = a.ints
+		} else if len(inputs) > 1 && inputs[1] != nil {
+			for _, v := range inputs[1].data {
+				axes = append(axes, int64(v))
+			}
+		}
+		reduced := make([]bool, rank)
+		for _, axis := range axes {
+			if axis < 0 {
+				axis += int64(rank)
+			}
+			if axis < 0 || int(axis) >= rank {
+				return nil, fmt.Errorf("axis %d out of range for shape %v", axis, x.shape)
+			}
+			reduced[axis] = true
+		}
+		if len(axes) == 0 {
+			for i := range reduced {
+				reduced[i] = true
+			}
+		}
+
+		keepDims := n.attrInt("keepdims", 1) != 0
+		var shape, kept []int
+		for i, d := range x.shape {
+			switch {
+			case !reduced[i]:
+				shape = append(shape, d)
+				kept = append(kept, d)
+			case keepDims:
+				shape = append(shape, 1)
+				kept = append(kept, 1)
+			default:
+				kept = append(kept, 1)
+			}
+		}
+
+		out := &tensor{shape: shape}
+		out.data = make([]float64, out.size())
+		counts := make([]int, len(out.data))
+		index := make([]int, rank)
+		for _, v := range x.data {
+			o := 0
+			for d := 0; d < rank; d++ {
+				o = o*kept[d] + min(index[d], kept[d]-1)
+			}
+			out.data[o] += v
+			counts[o]++
+
+			for d := rank - 1; d >= 0; d-- {
+				if index[d]++; index[d] < x.shape[d] {
+					break
+				}
+				index[d] = 0
+			}
+		}
+		if mean {
+			for i := range out.data {
+				if counts[i] > 0 {
+					out.data[i] /= float64(counts[i])
+				}
+			}
+		}
+		return out, nil
+	}
+}
+
+// treeNode is one node of a TreeEnsembleRegressor tree
+type treeNode struct {
+	feature int
+	value   float64
+	mode    string
+	yes, no int // indexes of the child nodes
+	targets []treeTarget
+}
+
+type treeTarget struct {
+	id     int
+	weight float64
+}
+
+// treeEnsemble evaluates the ai.onnx.ml TreeEnsembleRegressor operator
+// row by row, which is how isolation forests and gradient boosted trees
+// are exported
+func treeEnsemble(n node, inputs []*tensor) (*tensor, error) {
+	x := inputs[0]
+	rows, cols, err := x.matrix()
+	if err != nil {
+		return nil, err
+	}
+
+	ints := func(name string) []int64 { return n.attributes[name].ints }
+	treeIDs, nodeIDs := ints("nodes_treeids"), ints("nodes_nodeids")
+	features, modes := ints("nodes_featureids"), n.attributes["nodes_modes"].strings
+	values := n.attributes["nodes_values"].floats
+	yesIDs, noIDs := ints("nodes_truenodeids"), ints("nodes_falsenodeids")
+	for _, field := range [][]int64{nodeIDs, features, yesIDs, noIDs} {
+		if len(field) != len(treeIDs) {
+			return nil, fmt.Errorf("inconsistent tree node attributes")
+		}
+	}
+	if len(modes) != len(treeIDs) || len(values) != len(treeIDs) {
+		return nil, fmt.Errorf("inconsistent tree node attributes")
+	}
+
+	// Index nodes by tree and node ID, then link children by position
+	type key struct{ tree, node int64 }
+	positions := make(map[key]int, len(treeIDs))
+	nodes := make([]treeNode, len(treeIDs))
+	for i := range treeIDs {
+		positions[key{treeIDs[i], nodeIDs[i]}] = i
+		nodes[i] = treeNode{feature: int(features[i]), value: values[i], mode: modes[i]}
+		// Leaves carry no feature worth checking; exporters set anything
+		if nodes[i].mode != "LEAF" && (nodes[i].feature < 0 || nodes[i].feature >= cols) {
+			return nil, fmt.Errorf("tree node uses feature %d of %d", nodes[i].feature, cols)
+		}
+	}
+	for i := range nodes {
+		if nodes[i].mode == "LEAF" {
+			continue
+		}
+		yes, ok1 := positions[key{treeIDs[i], yesIDs[i]}]
+		no, ok2 := positions[key{treeIDs[i], noIDs[i]}]
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("tree %d node %d has a missing child", treeIDs[i], nodeIDs[i])
+		}
+		nodes[i].yes, nodes[i].no = yes, no
+	}
+
+	targetTrees, targetNodes := ints("target_treeids"), ints("target_nodeids")
+	targetIDs, weights := ints("target_ids"), n.attributes["target_weights"].floats
+	if len(targetNodes) != len(targetTrees) || len(targetIDs) != len(targetTrees) || len(weights) != len(targetTrees) {
+		return nil, fmt.Errorf("inconsistent tree target attributes")
+	}
+	targets := int(n.attrInt("n_targets", 1))
+	for i := range targetTrees {
+		leaf, ok := positions[key{targetTrees[i], targetNodes[i]}]
+		if !ok || nodes[leaf].mode != "LEAF" || targetIDs[i] < 0 || int(targetIDs[i]) >= targets {
+			return nil, fmt.Errorf("tree %d target node %d is invalid", targetTrees[i], targetNodes[i])
+		}
+		nodes[leaf].targets = append(nodes[leaf].targets, treeTarget{id: int(targetIDs[i]), weight: weights[i]})
+	}
+
+	// Trees start at their lowest node ID
+	roots := make(map[int64]int)
+	for i := range treeIDs {
+		if root, ok := roots[treeIDs[i]]; !ok || nodeIDs[i] < nodeIDs[root] {
+			roots[treeIDs[i]] = i
+		}
+	}
+	ordered := make([]int, 0, len(roots))
+	for _, root := range roots {
+		ordered = append(ordered, root)
+	}
+	sort.Ints(ordered)
+
+	aggregate := n.attributes["aggregate_function"].s
+	if aggregate == "" {
+		aggregate = "SUM"
+	}
+	base := n.attributes["base_values"].floats
+
+	out := &tensor{shape: []int{rows, targets}, data: make([]float64, rows*targets)}
+	for r := 0; r < rows; r++ {
+		row := x.data[r*cols : (r+1)*cols]
+		sums := make([]float64, targets)
+		seen := make([]bool, targets)
+		for _, root := range ordered {
+			leaf, err := walkTree(nodes, root, row)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range nodes[leaf].targets {
+				switch {
+				case !seen[t.id]:
+					sums[t.id] = t.weight
+				case aggregate == "MIN":
+					sums[t.id] = math.Min(sums[t.id], t.weight)
+				case aggregate == "MAX":
+					sums[t.id] = math.Max(sums[t.id], t.weight)
+				default:
+					sums[t.id] += t.weight
+				}
+				seen[t.id] = true
+			}
+		}
+		for t := range sums {
+			if aggregate == "AVERAGE" && len(ordered) > 0 {
+				sums[t] /= float64(len(ordered))
+			}
+			if t < len(base) {
+				sums[t] += base[t]
+			}
+			out.data[r*targets+t] = sums[t]
+		}
+	}
+	return out, nil
+}
+
+// walkTree follows a row from a tree's root to its leaf
+func walkTree(nodes []treeNode, i int, row []float64) (int, error) {
+	for steps := 0; steps <= len(nodes); steps++ {
+		node := nodes[i]
+		if node.mode == "LEAF" {
+			return i, nil
+		}
+		x := row[node.feature]
+		var yes bool
+		switch node.mode {
+		case "BRANCH_LEQ":
+			yes = x <= node.value
+		case "BRANCH_LT":
+			yes = x < node.value
+		case "BRANCH_GTE":
+			yes = x >= node.value
+		case "BRANCH_GT":
+			yes = x > node.value
+		case "BRANCH_EQ":
+			yes = x == node.value
+		case "BRANCH_NEQ":
+			yes = x != node.value
+		default:
+			return 0, fmt.Errorf("unsupported tree node mode %s", node.mode)
+		}
+		if yes {
+			i = node.yes
+		} else {
+			i = node.no
+		}
+	}
+	return 0, fmt.Errorf("tree has a cycle")
+}
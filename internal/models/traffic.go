@@ -12,13 +12,63 @@ type TrafficRequest struct {
 	DestPort    int       `json:"dest_port"`
 	Protocol    string    `json:"protocol"` // TCP, UDP, HTTP, etc.
 	RequestPath string    `json:"request_path"`
+	Method      string    `json:"method,omitempty"` // HTTP request method
 	UserAgent   string    `json:"user_agent"`
+	Referer     string    `json:"referer,omitempty"`
+	HeaderBytes int       `json:"header_bytes,omitempty"` // size of the request headers, 0 when unknown
 	BytesSent   int       `json:"bytes_sent"`
 	BytesRecv   int       `json:"bytes_recv"`
 	StatusCode  int       `json:"status_code"`
 	Duration    int       `json:"duration_ms"` // Connection duration in ms
+	Environment string    `json:"environment,omitempty"` // prod, staging, dc-east, ...
+	TCPFlags    uint8     `json:"tcp_flags,omitempty"`   // TCPFlag* bits, 0 when unknown
+	// TCPFlagsAggregated marks TCPFlags as the OR of a whole flow's
+	// segments, as flow logs record them, rather than one segment's
+	TCPFlagsAggregated bool `json:"tcp_flags_aggregated,omitempty"`
+	// Packets is how many packets a flow record stands for, 0 when the
+	// record is a single request or segment
+	Packets int `json:"packets,omitempty"`
+	TTL         int       `json:"ttl,omitempty"`         // IP TTL / hop limit as received, 0 when unknown
+	// Body transfer rates in bytes/sec, 0 when unknown. Low values on
+	// long-lived connections indicate slow POST (RUDY) or slow-read attacks.
+	RequestBodyRate  float64 `json:"request_body_bps,omitempty"`
+	ResponseReadRate float64 `json:"response_read_bps,omitempty"`
+	DNSQueryName     string  `json:"dns_query_name,omitempty"` // QNAME of DNS queries
+	// Client stack signals, hashed into HeaderFingerprint on ingest when the
+	// producer doesn't supply one
+	HTTPVersion       string   `json:"http_version,omitempty"` // HTTP/1.1, HTTP/2, HTTP/3
+	HeaderOrder       []string `json:"header_order,omitempty"` // request header names in the order sent
+	Accept            string   `json:"accept,omitempty"`
+	AcceptLanguage    string   `json:"accept_language,omitempty"`
+	HeaderFingerprint string   `json:"header_fingerprint,omitempty"`
+	// TLS handshake of the connection, when the producer terminates TLS
+	TLSServerName string `json:"tls_sni,omitempty"`
+	JA3           string `json:"ja3,omitempty"` // MD5 of the ClientHello fields, hex
+	TLSCipher     string `json:"tls_cipher,omitempty"`
+	// Set on ingest when threat intelligence feeds list the source: its
+	// reputation score (0-100) and the feeds listing it
+	Reputation  int      `json:"reputation,omitempty"`
+	ThreatFeeds []string `json:"threat_feeds,omitempty"`
+	// Set on ingest when the source lies in space no internet traffic comes
+	// from: private, multicast, unallocated, ...
+	SourceBogon string `json:"source_bogon,omitempty"`
+	// Where the source is, as the producer or the GeoIP databases know it:
+	// ISO 3166-1 alpha-2 country and autonomous system
+	Country string `json:"country,omitempty"`
+	ASN     uint32 `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
 }
 
+// TCP header flag bits carried in TrafficRequest.TCPFlags
+const (
+	TCPFlagFIN uint8 = 1 << iota
+	TCPFlagSYN
+	TCPFlagRST
+	TCPFlagPSH
+	TCPFlagACK
+	TCPFlagURG
+)
+
 // Metrics represents aggregated traffic metrics for a time window
 type Metrics struct {
 	Timestamp        time.Time         `json:"timestamp"`
@@ -34,6 +84,21 @@ type Metrics struct {
 	ProtocolBreakdown map[string]int   `json:"protocol_breakdown"`
 	StatusCodeDist   map[int]int       `json:"status_code_dist"`
 	AvgConnDuration  float64           `json:"avg_connection_duration"`
+	SourceRates      *SourceRates      `json:"source_rates,omitempty"`
+	CountryBreakdown map[string]int    `json:"country_breakdown,omitempty"` // requests per source country
+	MethodBreakdown  map[string]int    `json:"method_breakdown,omitempty"`  // HTTP requests per method
+	BogonSources     map[string]int    `json:"bogon_sources,omitempty"`     // requests per bogon range class of their source
+	TopASNs          []ASNCount        `json:"top_asns,omitempty"`          // busiest source autonomous systems
+}
+
+// SourceRates is the spread of request rates over sources, in requests per
+// second per source. Many low-rate bots keep P95 low however much they send
+// together; a handful of aggressive clients push it up.
+type SourceRates struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
 }
 
 type IPCount struct {
@@ -42,11 +107,57 @@ type IPCount struct {
 	Percentage float64 `json:"percentage"`
 }
 
+// CountryCount is the traffic from one source country
+type CountryCount struct {
+	Country    string  `json:"country"`
+	Requests   int64   `json:"requests"`
+	Percentage float64 `json:"percentage"`
+}
+
 type PathCount struct {
 	Path  string `json:"path"`
 	Count int    `json:"count"`
 }
 
+// SourcePrefix is a /24 or /16 (/64 or /48 for IPv6) holding many of an
+// attack's sources
+type SourcePrefix struct {
+	Prefix   string `json:"prefix"`
+	Sources  int    `json:"sources"`  // distinct attacking IPs in the prefix
+	Requests int    `json:"requests"` // their requests in the analysis window
+}
+
+// ASNCount is the traffic of one autonomous system
+type ASNCount struct {
+	ASN        uint32  `json:"asn"`
+	Org        string  `json:"org,omitempty"`
+	Sources    int     `json:"sources,omitempty"` // distinct IPs, where counted
+	Requests   int64   `json:"requests"`
+	Percentage float64 `json:"percentage"`
+	Hosting    bool    `json:"hosting,omitempty"` // a hosting or cloud provider's network
+}
+
+// PathLoad is the attack traffic one path received in the analysis window
+type PathLoad struct {
+	Path      string `json:"path"`
+	Requests  int    `json:"requests"`
+	Sources   int    `json:"sources"`     // distinct client IPs
+	PeakPerIP int    `json:"peak_per_ip"` // most requests from a single IP
+}
+
+// RateLimitRecommendation is a per-client rate limit suggested for one path,
+// with ready-to-apply configuration for common proxies
+type RateLimitRecommendation struct {
+	Path          string            `json:"path"`
+	Key           string            `json:"key"` // what requests are counted per, e.g. client_ip
+	RatePerMinute int               `json:"rate_per_minute"`
+	Burst         int               `json:"burst"`
+	ObservedPerIP float64           `json:"observed_per_ip_per_minute"` // mean attacker rate on the path
+	Effective     bool              `json:"effective"`                  // false when attackers already stay under the limit
+	Note          string            `json:"note,omitempty"`
+	Snippets      map[string]string `json:"snippets"` // nginx, haproxy, envoy
+}
+
 // Attack represents a detected attack
 type Attack struct {
 	ID          string    `json:"id"`
@@ -55,24 +166,252 @@ type Attack struct {
 	Confidence  float64   `json:"confidence"` // 0.0 to 1.0
 	StartTime   time.Time `json:"start_time"`
 	EndTime     *time.Time `json:"end_time,omitempty"`
-	SourceIPs   []string  `json:"source_ips"`
+	SourceIPs   []string  `json:"source_ips"` // top sources; samples when SourcePrefixes is set
+	SourcePrefixes []SourcePrefix `json:"source_prefixes,omitempty"` // set when most sources share prefixes
 	TargetIPs   []string  `json:"target_ips"`
+	TargetEndpoint string `json:"target_endpoint,omitempty"` // request path under attack, for application-layer attacks
+	TargetPaths []PathLoad `json:"target_paths,omitempty"` // per-path load of application-layer attacks
+	TargetService string  `json:"target_service,omitempty"` // service group, or port, flooded on the target
+	TargetPorts []int     `json:"target_ports,omitempty"` // destination ports the flooded service was hit on
 	Description string    `json:"description"`
 	Metrics     *Metrics  `json:"metrics,omitempty"`
 	Mitigated   bool      `json:"mitigated"`
+	Environment string    `json:"environment,omitempty"`
+	MergedFrom  []string  `json:"merged_from,omitempty"` // IDs of attacks manually merged into this one
+	SplitFrom   string    `json:"split_from,omitempty"`  // ID of the attack this one was manually split from
+	DetectionMode string  `json:"detection_mode,omitempty"` // full, or approximate when the pipeline was saturated
+	Window      string    `json:"window,omitempty"` // resolution that triggered detection: short, medium or long
+	WindowSeconds int     `json:"window_seconds,omitempty"`
+	Pulse       *PulseWave `json:"pulse,omitempty"` // set once the attack recurs as separate bursts
+	Traffic     *AttackTraffic `json:"traffic,omitempty"` // excess traffic over baseline while the attack ran
+	Cost        *CostEstimate  `json:"cost,omitempty"`
+	Spoofing    *SpoofingEvidence `json:"spoofing,omitempty"` // set when the sources are likely forged
+	SourceReputation []SourceReputation `json:"source_reputation,omitempty"` // the SourceIPs threat intelligence feeds list
+	SourceASNs       []ASNCount         `json:"source_asns,omitempty"`       // autonomous systems the sources are in, busiest first
+	Evidence    *Evidence `json:"evidence,omitempty"` // why the latest detection flagged the attack
+	RateComparison *RateComparison `json:"rate_comparison,omitempty"` // set on rate anomalies: the same time yesterday and last week
+	Vectors     []AttackVector `json:"vectors,omitempty"` // the detections a MULTI_VECTOR attack correlates, most confident first
+	Fingerprint string    `json:"fingerprint,omitempty"` // type, environment, top sources and targets at first detection
+	Detections  int       `json:"detections,omitempty"` // analysis cycles that detected the attack
+	LastDetected *time.Time `json:"last_detected,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"` // from the start to the last detection
+	SilencedBy  string    `json:"silenced_by,omitempty"` // silence holding back the attack's alerts and automatic mitigation
+}
+
+// SpoofingEvidence explains why an attack's source addresses are likely
+// forged. Blocking forged sources is futile, so such attacks are
+// blackholed upstream (RTBH) instead of blocked per source IP.
+type SpoofingEvidence struct {
+	Indicators      []string       `json:"indicators"`             // one line per heuristic that fired
+	PortEntropy     float64        `json:"port_entropy"`           // source port spread, 1.0 is uniform over 0-65535
+	LowPortShare    float64        `json:"low_port_share"`         // packets from source ports below 1024
+	TTLVaryingShare float64        `json:"ttl_varying_share"`      // repeat sources whose TTL keeps changing
+	BogonShare      float64        `json:"bogon_share"`            // packets from reserved or bogon ranges
+	BogonRanges     map[string]int `json:"bogon_ranges,omitempty"` // those packets per range class
+}
+
+// SourceReputation is what threat intelligence feeds know about a source
+type SourceReputation struct {
+	IP    string   `json:"ip"`
+	Score int      `json:"score"` // 0-100, the highest any feed gives it
+	Feeds []string `json:"feeds"`
+}
+
+// SourceProfile is how one source normally behaves, learned from the
+// windows it was active in without an attack under way
+type SourceProfile struct {
+	IP            string      `json:"ip"`
+	Samples       int         `json:"samples"`        // windows learned from
+	RequestRate   float64     `json:"request_rate"`   // requests per minute while active
+	PathDiversity float64     `json:"path_diversity"` // distinct paths per request
+	ErrorRatio    float64     `json:"error_ratio"`    // share of its responses with a 4xx/5xx status
+	ActiveHours   [24]float64 `json:"active_hours"`   // decayed activity per local hour of day
+	FirstSeen     time.Time   `json:"first_seen"`
+	LastSeen      time.Time   `json:"last_seen"` // the last window learned
+}
+
+// Evidence explains why a detector flagged an attack: the measurements it
+// judged, the thresholds they were compared against and the sources and
+// paths that contributed most
+type Evidence struct {
+	Detector   string             `json:"detector"`            // plugin or per-cycle check, e.g. syn_flood
+	Features   map[string]float64 `json:"features"`            // measured over the detection window
+	Checks     []ThresholdCheck   `json:"checks"`              // the comparisons that led to the detection
+	Condition  string             `json:"condition,omitempty"` // the condition of a user-defined rule
+	TopSources []IPCount          `json:"top_sources,omitempty"`
+	TopPaths   []PathCount        `json:"top_paths,omitempty"`
+}
+
+// ThresholdCheck is one comparison of a measurement against a threshold,
+// e.g. syn_packets 1450 > SYNFloodThreshold 1000. Count thresholds are
+// scaled to the detection window.
+type ThresholdCheck struct {
+	Feature   string  `json:"feature"`
+	Value     float64 `json:"value"`
+	Operator  string  `json:"operator"` // >, >=, < or <=
+	Threshold string  `json:"threshold"` // Thresholds field or baseline compared against
+	Limit     float64 `json:"limit"`
+}
+
+// RateComparison puts a rate anomaly next to the same local hour yesterday
+// and a week ago, so an expected weekly peak the baseline hasn't learned
+// can be told from an attack at a glance
+type RateComparison struct {
+	CurrentRPS float64     `json:"current_rps"` // the detection window's rate
+	HourStarts []time.Time `json:"hour_starts"` // hours of the sparklines, oldest first, the current one last
+	Today      []float64   `json:"today"`       // average req/s per hour; the current hour so far
+	Yesterday  RatePeriod  `json:"yesterday"`
+	LastWeek   RatePeriod  `json:"last_week"`
+}
+
+// RatePeriod is the traffic of the hours a rate anomaly is compared with
+type RatePeriod struct {
+	HourStart    time.Time `json:"hour_start"` // the hour matching the current one
+	RPS          float64   `json:"rps"`        // its average req/s
+	DeltaRPS     float64   `json:"delta_rps"`  // current rate minus RPS
+	DeltaPercent *float64  `json:"delta_percent,omitempty"` // unset when the hour had no traffic
+	Sparkline    []float64 `json:"sparkline"`  // average req/s of the hours matching HourStarts
+}
+
+// BlockingRecommendation is how to drop an attack's traffic at the edge
+type BlockingRecommendation struct {
+	Strategy string   `json:"strategy"` // BLOCK the sources, or RTBH to blackhole the victim upstream
+	Targets  []string `json:"targets"`  // source IPs or prefixes for BLOCK, victim prefixes for RTBH
+	Reason   string   `json:"reason"`
+}
+
+// AttackTraffic is the traffic above baseline accumulated over an attack
+type AttackTraffic struct {
+	Requests int64 `json:"requests"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// CostEstimate prices an attack with the configured cost model
+type CostEstimate struct {
+	Currency       string  `json:"currency"`
+	Requests       int64   `json:"requests"`
+	Bytes          int64   `json:"bytes"`
+	Bandwidth      float64 `json:"bandwidth"`
+	Compute        float64 `json:"compute"`
+	AttackCost     float64 `json:"attack_cost"`     // bandwidth + compute
+	MitigationCost float64 `json:"mitigation_cost"` // scrubbing fees
+	Total          float64 `json:"total"`
+}
+
+// PulseWave describes an attack that switches on and off repeatedly
+type PulseWave struct {
+	Bursts        int       `json:"bursts"`
+	PeriodSeconds float64   `json:"period_seconds"` // mean time between burst starts
+	BurstSeconds  float64   `json:"burst_seconds"`  // mean length of a completed burst
+	LastBurst     time.Time `json:"last_burst"`
+}
+
+// AttackVector is one detection correlated into a MULTI_VECTOR attack
+type AttackVector struct {
+	Type        string    `json:"type"`
+	Severity    string    `json:"severity"`
+	Confidence  float64   `json:"confidence"`
+	Description string    `json:"description"`
+	Window      string    `json:"window,omitempty"`
+	Evidence    *Evidence `json:"evidence,omitempty"`
+}
+
+// Types returns the attack's type, or the types of its vectors for a
+// MULTI_VECTOR attack
+func (a Attack) Types() []string {
+	if len(a.Vectors) == 0 {
+		return []string{a.Type}
+	}
+	types := make([]string, len(a.Vectors))
+	for i, vector := range a.Vectors {
+		types[i] = vector.Type
+	}
+	return types
+}
+
+// SeverityRank orders attack severities from LOW (1) to CRITICAL (4); unknown is 0
+func SeverityRank(severity string) int {
+	switch severity {
+	case "LOW":
+		return 1
+	case "MEDIUM":
+		return 2
+	case "HIGH":
+		return 3
+	case "CRITICAL":
+		return 4
+	}
+	return 0
+}
+
+// ShadowAttack is an attack detected with shadow mode thresholds, which
+// raised no alert and was not mitigated
+type ShadowAttack struct {
+	Attack
+	// Whether production detected the same types in the environment in a
+	// cycle while the shadow attack lasted
+	InProduction bool `json:"in_production"`
+}
+
+// AttackDecision records a manual merge or split made by an operator, which
+// automated correlation must respect from then on
+type AttackDecision struct {
+	Action    string    `json:"action"` // MERGE, SPLIT
+	AttackIDs []string  `json:"attack_ids"`
+	Operator  string    `json:"operator,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TimelineEvent is an entry in an attack's incident timeline
+type TimelineEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"` // DETECTED, SCALED, MERGED, SPLIT, ...
+	Message   string    `json:"message"`
+	Actor     string    `json:"actor,omitempty"` // operator or subsystem responsible
 }
 
 // MitigationAction represents a response to an attack
 type MitigationAction struct {
 	ID          string        `json:"id"`
-	Type        string        `json:"type"` // BLOCK, RATE_LIMIT, CHALLENGE, MONITOR
-	Target      string        `json:"target"` // IP or CIDR
+	Type        string        `json:"type"` // BLOCK, RATE_LIMIT, CHALLENGE, MONITOR, RTBH
+	Target      string        `json:"target"` // IP or CIDR; the victim prefix for RTBH
 	Duration    time.Duration `json:"duration"`
 	Reason      string        `json:"reason"`
 	AttackID    string        `json:"attack_id"`
 	AppliedAt   time.Time     `json:"applied_at"`
 	ExpiresAt   time.Time     `json:"expires_at"`
 	Active      bool          `json:"active"`
+	Confidence  float64       `json:"confidence,omitempty"` // of the attack, the highest it was acted on at
+	AttackTypes []string      `json:"attack_types,omitempty"` // the attack's types, or its vectors'
+	AppliedBy   string        `json:"applied_by,omitempty"` // the operator, for actions taken by hand
+	Policy      string        `json:"policy,omitempty"`     // the policy rule that decided it
+	DryRun      bool          `json:"dry_run,omitempty"`    // recorded and reported, but not enforced
+	LiftedBy    string        `json:"lifted_by,omitempty"`  // who lifted it before it expired
+	// AttackEndedAt is set once the attack stopped being detected; backends
+	// lifting their actions when the attack ends no longer enforce it
+	AttackEndedAt *time.Time `json:"attack_ended_at,omitempty"`
+}
+
+// MitigationEvent is an entry of the mitigation audit trail: an action
+// applied, escalated, expired, lifted or failing on an executor
+type MitigationEvent struct {
+	ID        string           `json:"id"`
+	Event     string           `json:"event"`
+	Actor     string           `json:"actor"` // the operator, policy or process behind it
+	Executor  string           `json:"executor,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Action    MitigationAction `json:"action"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// IPListEntry is a CIDR on the allowlist of trusted sources or the
+// denylist of known-bad ones
+type IPListEntry struct {
+	CIDR    string    `json:"cidr"` // a single address is stored as its host prefix
+	Comment string    `json:"comment,omitempty"`
+	AddedBy string    `json:"added_by,omitempty"`
+	AddedAt time.Time `json:"added_at"`
 }
 
 // Alert represents a security alert
@@ -82,7 +421,159 @@ type Alert struct {
 	Title       string    `json:"title"`
 	Message     string    `json:"message"`
 	AttackType  string    `json:"attack_type,omitempty"`
+	Environment string    `json:"environment,omitempty"`
 	SourceIP    string    `json:"source_ip,omitempty"`
 	Timestamp   time.Time `json:"timestamp"`
 	Acknowledged bool     `json:"acknowledged"`
-}
\ No newline at end of file
+	// OPEN, ACKNOWLEDGED by an operator, or RESOLVED by an operator or as
+	// its attack ended
+	Status         string     `json:"status,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Alert statuses
+const (
+	AlertOpen         = "OPEN"
+	AlertAcknowledged = "ACKNOWLEDGED"
+	AlertResolved     = "RESOLVED"
+)
+
+// Silence holds back the alerts, notifications and automatic mitigation of
+// the attacks it matches while it is in force, e.g. during a load test.
+// Matchers left empty match anything; an attack must match all the others.
+type Silence struct {
+	ID          string    `json:"id"`
+	AttackTypes []string  `json:"attack_types,omitempty"`
+	SourceCIDRs []string  `json:"source_cidrs,omitempty"` // every source of the attack is in one of them
+	Targets     []string  `json:"targets,omitempty"`      // IPs or CIDRs every target of the attack is in
+	Environment string    `json:"environment,omitempty"`
+	Comment     string    `json:"comment"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	ExpiredBy   string    `json:"expired_by,omitempty"` // who ended it early
+}
+
+// Silence states
+const (
+	SilencePending = "pending"
+	SilenceActive  = "active"
+	SilenceExpired = "expired"
+)
+
+// State is whether the silence is yet to start, in force or over at now
+func (s Silence) State(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilencePending
+	case now.Before(s.EndsAt):
+		return SilenceActive
+	}
+	return SilenceExpired
+}
+
+// PeriodSummary is traffic rolled up over a local hour or day
+type PeriodSummary struct {
+	Environment       string           `json:"environment"`
+	Period            string           `json:"period"`
+	Start             time.Time        `json:"start"`
+	TimeZone          string           `json:"time_zone"`
+	TotalRequests     int64            `json:"total_requests"`
+	TotalBytes        int64            `json:"total_bytes"`
+	TotalPackets      int64            `json:"total_packets,omitempty"` // of flow records
+	ProtocolBreakdown map[string]int64 `json:"protocol_breakdown"`
+	MethodBreakdown   map[string]int64 `json:"method_breakdown,omitempty"`
+	CountryBreakdown  map[string]int64 `json:"country_breakdown,omitempty"`
+	ASNBreakdown      map[string]int64 `json:"asn_breakdown,omitempty"` // requests per AS number
+	AttackCost        float64          `json:"attack_cost"`
+	MitigationCost    float64          `json:"mitigation_cost"`
+}
+
+// ActionAudit records one invocation of an admin action
+type ActionAudit struct {
+	ID        string            `json:"id"`
+	Action    string            `json:"action"`
+	Params    map[string]string `json:"params,omitempty"`
+	Actor     string            `json:"actor"`
+	Role      string            `json:"role"`
+	Success   bool              `json:"success"`
+	Result    interface{}       `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Notification is what notifiers are told about: an attack starting or
+// ending, with the attack, a change to a mitigation, with the change, or
+// the outcome of a detection validation run, with its report
+type Notification struct {
+	EventID    string            `json:"event_id"`
+	Event      string            `json:"event"` // attack.started, attack.ended, mitigation.<event> or verify.passed/failed
+	Attack     *Attack           `json:"attack,omitempty"`
+	Mitigation *MitigationEvent  `json:"mitigation,omitempty"`
+	Report     *ValidationReport `json:"report,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// ValidationReport is the outcome of a scheduled detection validation run,
+// e.g. simulator verify replaying its attack battery
+type ValidationReport struct {
+	Title   string      `json:"title"`
+	Passed  bool        `json:"passed"`
+	Summary string      `json:"summary"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// AttackID returns the ID of the attack the notification is about, if any
+func (n Notification) AttackID() string {
+	if n.Attack != nil {
+		return n.Attack.ID
+	}
+	if n.Mitigation != nil {
+		return n.Mitigation.Action.AttackID
+	}
+	return ""
+}
+
+// NotificationDelivery is one attempt to notify an external system of an
+// attack or a mitigation
+type NotificationDelivery struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event,omitempty"`
+	AttackID  string    `json:"attack_id"`
+	Severity  string    `json:"severity"`
+	Channel   string    `json:"channel"` // notifier name, e.g. misp
+	Status    string    `json:"status"`  // DELIVERED, SKIPPED, RETRYING or FAILED
+	Attempt   int       `json:"attempt"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RoutedAttack is the route an attack's notifications follow and how far
+// they have escalated along it
+type RoutedAttack struct {
+	AttackID string    `json:"attack_id"`
+	Route    string    `json:"route"`
+	Since    time.Time `json:"since"` // step delays count from here
+	Steps    int       `json:"steps"` // steps notified so far
+	// Notified are the channels told so far, which are told of the end
+	Notified []string `json:"notified"`
+	// Notification is the latest, sent to the steps still to come
+	Notification Notification `json:"notification"`
+}
+
+// DeadLetter is a notification that failed for good, kept whole so it can
+// be delivered again once the destination is fixed. Letters queued before
+// notifications carried events have only the attack, which had started.
+type DeadLetter struct {
+	ID      string `json:"id"`
+	Channel string `json:"channel"`
+	Notification
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
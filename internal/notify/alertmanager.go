@@ -0,0 +1,293 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// DefaultAlertmanagerResend is how often the alerts of active attacks are
+// sent again, as Prometheus does, so Alertmanager keeps them firing
+const DefaultAlertmanagerResend = time.Minute
+
+// AlertmanagerConfig configures alerts posted to Prometheus Alertmanager's
+// v2 API
+type AlertmanagerConfig struct {
+	// URLs of the Alertmanagers, e.g. http://alertmanager:9093; every
+	// member of a cluster is sent every alert
+	URLs []string
+	// Labels are added to every alert, e.g. cluster=eu1
+	Labels map[string]string
+	// Only attacks at or above this severity are alerted
+	MinSeverity string
+	// ResendInterval is how often active attacks are alerted again
+	ResendInterval time.Duration
+	// DashboardURL is the generatorURL of every alert when set
+	DashboardURL string
+}
+
+// AlertmanagerNotifier fires an alert when an attack starts and resolves it
+// when the attack ends. Alertmanager resolves alerts that aren't sent
+// again before their endsAt, so Resend must be called with the active
+// attacks every ResendInterval.
+type AlertmanagerNotifier struct {
+	cfg    AlertmanagerConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	sent map[string]map[string]string // attack ID -> labels last fired
+}
+
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func NewAlertmanagerNotifier(cfg AlertmanagerConfig) (*AlertmanagerNotifier, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, fmt.Errorf("alertmanager: url is required")
+	}
+	for _, u := range cfg.URLs {
+		if err := checkURL(u); err != nil {
+			return nil, fmt.Errorf("alertmanager: %w", err)
+		}
+	}
+	for name := range cfg.Labels {
+		if !labelName.MatchString(name) {
+			return nil, fmt.Errorf("alertmanager: invalid label name %q", name)
+		}
+	}
+	cfg.MinSeverity = strings.ToUpper(cfg.MinSeverity)
+	if cfg.MinSeverity != "" && models.SeverityRank(cfg.MinSeverity) == 0 {
+		return nil, fmt.Errorf("alertmanager: unknown severity %s", cfg.MinSeverity)
+	}
+	if cfg.ResendInterval <= 0 {
+		cfg.ResendInterval = DefaultAlertmanagerResend
+	}
+
+	return &AlertmanagerNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		sent:   make(map[string]map[string]string),
+	}, nil
+}
+
+// ParseLabels parses name=value pairs separated by commas
+func ParseLabels(spec string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("label %q is not name=value", part)
+		}
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+func (a *AlertmanagerNotifier) Name() string {
+	return "alertmanager"
+}
+
+// Subscribes takes attacks starting, escalating and ending
+func (a *AlertmanagerNotifier) Subscribes(event string) bool {
+	return event == EventAttackStarted || event == EventAttackEscalated || event == EventAttackEnded
+}
+
+// ResendInterval is how often Resend should be called
+func (a *AlertmanagerNotifier) ResendInterval() time.Duration {
+	return a.cfg.ResendInterval
+}
+
+// postableAlert is an alert of Alertmanager's v2 API
+type postableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Notify fires the attack's alert as it starts and resolves it as it ends
+func (a *AlertmanagerNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.Attack == nil {
+		return ErrSkipped
+	}
+	attack := *notification.Attack
+	severe := meetsSeverity(attack, a.cfg.MinSeverity)
+
+	now := time.Now()
+	var alerts []postableAlert
+	if notification.Event == EventAttackEnded {
+		end := now
+		if attack.EndTime != nil {
+			end = *attack.EndTime
+		}
+		// Resolve what was fired, even if the attack since fell below the
+		// minimum, and what another node may have fired
+		alerts = a.resolve(attack, end)
+		if labels := a.labels(attack); severe && !hasLabels(alerts, labels) {
+			alerts = append(alerts, a.alert(attack, labels, end))
+		}
+	} else if severe {
+		alerts = a.fire(attack, now)
+	}
+	if len(alerts) == 0 {
+		return ErrSkipped
+	}
+	return a.post(ctx, alerts)
+}
+
+// Resend fires the alerts of the active attacks again, and resolves those
+// of attacks that fell below the minimum severity
+func (a *AlertmanagerNotifier) Resend(ctx context.Context, active []models.Attack) error {
+	now := time.Now()
+	alerts := make([]postableAlert, 0, len(active))
+	firing := make(map[string]bool, len(active))
+	for _, attack := range active {
+		if !meetsSeverity(attack, a.cfg.MinSeverity) {
+			alerts = append(alerts, a.resolve(attack, now)...)
+			continue
+		}
+		firing[attack.ID] = true
+		alerts = append(alerts, a.fire(attack, now)...)
+	}
+
+	// An attack that ended without its alert resolving lapses at its endsAt
+	a.mu.Lock()
+	for id := range a.sent {
+		if !firing[id] {
+			delete(a.sent, id)
+		}
+	}
+	a.mu.Unlock()
+
+	if len(alerts) == 0 {
+		return nil
+	}
+	return a.post(ctx, alerts)
+}
+
+// fire is the firing alert of an attack, lasting until a few resends are
+// missed, after the alert it replaces if its labels changed, e.g. as it
+// escalated
+func (a *AlertmanagerNotifier) fire(attack models.Attack, now time.Time) []postableAlert {
+	labels := a.labels(attack)
+
+	a.mu.Lock()
+	previous, ok := a.sent[attack.ID]
+	a.sent[attack.ID] = labels
+	a.mu.Unlock()
+
+	var alerts []postableAlert
+	if ok && !maps.Equal(previous, labels) {
+		alerts = append(alerts, postableAlert{Labels: previous, StartsAt: attack.StartTime, EndsAt: now})
+	}
+	return append(alerts, a.alert(attack, labels, now.Add(4*a.cfg.ResendInterval)))
+}
+
+// resolve ends the last alert fired for an attack, if any
+func (a *AlertmanagerNotifier) resolve(attack models.Attack, end time.Time) []postableAlert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	labels, ok := a.sent[attack.ID]
+	if !ok {
+		return nil
+	}
+	delete(a.sent, attack.ID)
+	return []postableAlert{{Labels: labels, StartsAt: attack.StartTime, EndsAt: end}}
+}
+
+// labels identify an attack's alert; Alertmanager routes, groups and
+// silences on them
+func (a *AlertmanagerNotifier) labels(attack models.Attack) map[string]string {
+	labels := maps.Clone(a.cfg.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels["alertname"] = "DDoSAttack"
+	labels["severity"] = strings.ToLower(attack.Severity)
+	labels["attack_type"] = attack.Type
+	labels["attack_id"] = attack.ID
+	labels["fingerprint"] = strings.TrimPrefix(incidentKey(attack), "ddos-")
+	if attack.Environment != "" {
+		labels["environment"] = attack.Environment
+	}
+	if targets := chatTargets(attack); targets != "" {
+		labels["targets"] = targets
+	}
+	return labels
+}
+
+func (a *AlertmanagerNotifier) alert(attack models.Attack, labels map[string]string, endsAt time.Time) postableAlert {
+	annotations := map[string]string{
+		"summary":     incidentSummary(attack),
+		"description": attack.Description,
+		"confidence":  fmt.Sprintf("%.2f", attack.Confidence),
+	}
+	if sources := chatSources(attack); sources != "" {
+		annotations["top_sources"] = strings.ReplaceAll(sources, "\n", ", ")
+	}
+	return postableAlert{
+		Labels:       labels,
+		Annotations:  annotations,
+		StartsAt:     attack.StartTime,
+		EndsAt:       endsAt,
+		GeneratorURL: a.cfg.DashboardURL,
+	}
+}
+
+// hasLabels reports whether one of alerts has exactly these labels
+func hasLabels(alerts []postableAlert, labels map[string]string) bool {
+	for _, alert := range alerts {
+		if maps.Equal(alert.Labels, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// post sends alerts to every Alertmanager. Clustered Alertmanagers share
+// them, so one taking them is enough.
+func (a *AlertmanagerNotifier) post(ctx context.Context, alerts []postableAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, u := range a.cfg.URLs {
+		endpoint := strings.TrimSuffix(u, "/") + "/api/v2/alerts"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := a.client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alertmanager %s: %w", u, err))
+			continue
+		}
+		err = statusError("alertmanager "+u, resp)
+		resp.Body.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(a.cfg.URLs) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,411 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Chat services posted to through incoming webhooks
+const (
+	ChatSlack   = "slack"
+	ChatDiscord = "discord"
+)
+
+const (
+	// DefaultChatCooldown is how long a chat channel hears nothing more of
+	// the same event for the same attack
+	DefaultChatCooldown = 10 * time.Minute
+	// DefaultChatMaxPerHour caps the messages posted to a chat channel
+	DefaultChatMaxPerHour = 30
+
+	// chatTopSources is how many sources a message lists
+	chatTopSources = 5
+)
+
+// ChatConfig is one Slack or Discord channel, through its incoming webhook.
+// Routing attacks by severity is a matter of listing a channel per
+// severity band.
+type ChatConfig struct {
+	// Name tells the channel apart in the delivery log, as slack:<name> or
+	// discord:<name>
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Events are the event types posted, or globs such as mitigation.*;
+	// none posts attacks starting, escalating and ending
+	Events []string `yaml:"events"`
+	// Severities posts attacks of exactly these severities; otherwise
+	// MinSeverity posts those at or above it. Mitigation events are posted
+	// regardless.
+	Severities  []string `yaml:"severities"`
+	MinSeverity string   `yaml:"min_severity"`
+	// Cooldown holds back the same event for the same attack, or an attack
+	// with the same fingerprint, so a flapping detector posts it once
+	Cooldown time.Duration `yaml:"cooldown"`
+	// MaxPerHour caps the messages posted to the channel
+	MaxPerHour int `yaml:"max_per_hour"`
+	// DashboardURL is linked from every message when set
+	DashboardURL string `yaml:"-"`
+}
+
+// ChatNotifier posts formatted messages to a Slack or Discord channel
+type ChatNotifier struct {
+	kind    string
+	cfg     ChatConfig
+	client  *http.Client
+	limiter *chatLimiter
+}
+
+func NewChatNotifier(kind string, cfg ChatConfig) (*ChatNotifier, error) {
+	if kind != ChatSlack && kind != ChatDiscord {
+		return nil, fmt.Errorf("unknown chat service %q", kind)
+	}
+	if err := checkURL(cfg.URL); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", kind, cfg.Name, err)
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = []string{EventAttackStarted, EventAttackEscalated, EventAttackEnded}
+	}
+	if err := checkEvents(cfg.Events); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", kind, cfg.Name, err)
+	}
+	cfg.MinSeverity = strings.ToUpper(cfg.MinSeverity)
+	if cfg.MinSeverity != "" && models.SeverityRank(cfg.MinSeverity) == 0 {
+		return nil, fmt.Errorf("%s %s: unknown severity %s", kind, cfg.Name, cfg.MinSeverity)
+	}
+	for i, severity := range cfg.Severities {
+		cfg.Severities[i] = strings.ToUpper(severity)
+		if models.SeverityRank(cfg.Severities[i]) == 0 {
+			return nil, fmt.Errorf("%s %s: unknown severity %s", kind, cfg.Name, severity)
+		}
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultChatCooldown
+	}
+	if cfg.MaxPerHour <= 0 {
+		cfg.MaxPerHour = DefaultChatMaxPerHour
+	}
+
+	return &ChatNotifier{
+		kind:    kind,
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		limiter: &chatLimiter{cooldown: cfg.Cooldown, maxPerHour: cfg.MaxPerHour, last: make(map[string]chatPost)},
+	}, nil
+}
+
+func (c *ChatNotifier) Name() string {
+	return c.kind + ":" + c.cfg.Name
+}
+
+// Subscribes takes the events the channel is routed
+func (c *ChatNotifier) Subscribes(event string) bool {
+	return MatchEvent(c.cfg.Events, event)
+}
+
+// Notify posts the notification as a message, unless the attack's severity
+// isn't routed to the channel or the channel is rate limited
+func (c *ChatNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if attack := notification.Attack; attack != nil {
+		if len(c.cfg.Severities) > 0 && !slices.Contains(c.cfg.Severities, attack.Severity) ||
+			len(c.cfg.Severities) == 0 && !meetsSeverity(*attack, c.cfg.MinSeverity) {
+			return ErrSkipped
+		}
+	}
+
+	suppressed, err := c.limiter.allow(chatKey(notification), notification.EventID, time.Now())
+	if err != nil {
+		return err
+	}
+	message := chatMessageFor(notification, c.cfg.DashboardURL, suppressed)
+
+	var payload any
+	if c.kind == ChatSlack {
+		payload = message.slack()
+	} else {
+		payload = message.discord()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	return statusError(c.Name(), resp)
+}
+
+// chatKey identifies what a message is about for the cooldown: the event
+// and the attack's fingerprint, or the mitigation's target
+func chatKey(notification models.Notification) string {
+	switch {
+	case notification.Attack != nil && notification.Attack.Fingerprint != "":
+		return notification.Event + "|" + notification.Attack.Fingerprint
+	case notification.Attack != nil:
+		return notification.Event + "|" + notification.Attack.ID
+	case notification.Mitigation != nil:
+		return notification.Event + "|" + notification.Mitigation.Action.Target
+	}
+	return notification.Event
+}
+
+// chatPost is a message let through by a chatLimiter
+type chatPost struct {
+	eventID string
+	at      time.Time
+}
+
+// chatLimiter holds back repeats within the cooldown and messages over the
+// hourly cap. A notification already let through is let through again, so
+// retries aren't held back by their own first attempt.
+type chatLimiter struct {
+	cooldown   time.Duration
+	maxPerHour int
+
+	mu         sync.Mutex
+	last       map[string]chatPost // latest post per chatKey
+	hour       []chatPost          // posts in the last hour, oldest first
+	suppressed int                 // messages held back since the last post
+}
+
+// allow reports whether a notification may be posted, with how many were
+// held back since the last post
+func (l *chatLimiter) allow(key, eventID string, now time.Time) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.hour) > 0 && now.Sub(l.hour[0].at) >= time.Hour {
+		l.hour = l.hour[1:]
+	}
+	if slices.ContainsFunc(l.hour, func(post chatPost) bool { return post.eventID == eventID }) {
+		return 0, nil
+	}
+
+	if last, ok := l.last[key]; ok && now.Sub(last.at) < l.cooldown {
+		l.suppressed++
+		return 0, fmt.Errorf("%w: repeated within %s", ErrSkipped, l.cooldown)
+	}
+	if len(l.hour) >= l.maxPerHour {
+		l.suppressed++
+		return 0, fmt.Errorf("%w: over %d messages an hour", ErrSkipped, l.maxPerHour)
+	}
+
+	post := chatPost{eventID: eventID, at: now}
+	l.hour = append(l.hour, post)
+	l.last[key] = post
+	for key, last := range l.last {
+		if now.Sub(last.at) >= l.cooldown {
+			delete(l.last, key)
+		}
+	}
+
+	suppressed := l.suppressed
+	l.suppressed = 0
+	return suppressed, nil
+}
+
+// chatMessage is a message laid out for either chat service
+type chatMessage struct {
+	title       string
+	description string
+	color       int
+	fields      []chatField
+	url         string
+	timestamp   time.Time
+}
+
+type chatField struct {
+	name, value string
+}
+
+// Message colors: severities, attacks ending and mitigations
+var chatColors = map[string]int{
+	"CRITICAL": 0xd00000,
+	"HIGH":     0xff8c00,
+	"MEDIUM":   0xf2c744,
+	"LOW":      0x439fe0,
+	"ended":    0x2eb67d,
+	"action":   0x7d7d7d,
+}
+
+func chatMessageFor(notification models.Notification, dashboardURL string, suppressed int) chatMessage {
+	message := chatMessage{url: dashboardURL, timestamp: notification.Timestamp}
+
+	switch {
+	case notification.Attack != nil:
+		attack := *notification.Attack
+		message.description = attack.Description
+		where := ""
+		if attack.Environment != "" {
+			where = " in " + attack.Environment
+		}
+		switch notification.Event {
+		case EventAttackEnded:
+			message.title = fmt.Sprintf("✅ %s attack%s ended", attack.Type, where)
+			message.color = chatColors["ended"]
+		case EventAttackEscalated:
+			message.title = fmt.Sprintf("📈 %s attack%s escalated to %s", attack.Type, where, attack.Severity)
+			message.color = chatColors[attack.Severity]
+		default:
+			message.title = fmt.Sprintf("🚨 %s %s attack%s", attack.Severity, attack.Type, where)
+			message.color = chatColors[attack.Severity]
+		}
+
+		message.fields = append(message.fields,
+			chatField{"Severity", attack.Severity},
+			chatField{"Confidence", strconv.Itoa(int(attack.Confidence*100+0.5)) + "%"},
+		)
+		if attack.EndTime != nil {
+			message.fields = append(message.fields, chatField{"Lasted", attack.EndTime.Sub(attack.StartTime).Round(time.Second).String()})
+		}
+		if targets := chatTargets(attack); targets != "" {
+			message.fields = append(message.fields, chatField{"Targets", targets})
+		}
+		if sources := chatSources(attack); sources != "" {
+			message.fields = append(message.fields, chatField{"Top sources", sources})
+		}
+		if attack.Mitigated {
+			message.fields = append(message.fields, chatField{"Mitigated", "yes"})
+		}
+		message.fields = append(message.fields, chatField{"Attack", "`" + attack.ID + "`"})
+
+	case notification.Mitigation != nil:
+		event := *notification.Mitigation
+		action := event.Action
+		message.title = fmt.Sprintf("🛡️ %s of %s %s", action.Type, action.Target, event.Event)
+		message.color = chatColors["action"]
+		message.description = action.Reason
+		message.fields = append(message.fields, chatField{"By", event.Actor})
+		if !action.ExpiresAt.IsZero() && event.Event != "lifted" && event.Event != "expired" {
+			message.fields = append(message.fields, chatField{"Expires", action.ExpiresAt.UTC().Format(time.RFC3339)})
+		}
+		if action.DryRun {
+			message.fields = append(message.fields, chatField{"Dry run", "yes"})
+		}
+		if event.Error != "" {
+			message.color = chatColors["CRITICAL"]
+			message.fields = append(message.fields, chatField{"Error", event.Executor + ": " + event.Error})
+		}
+		if action.AttackID != "" {
+			message.fields = append(message.fields, chatField{"Attack", "`" + action.AttackID + "`"})
+		}
+
+	case notification.Report != nil:
+		report := *notification.Report
+		message.title = "✅ " + report.Title
+		message.color = chatColors["ended"]
+		if !report.Passed {
+			message.title = "❌ " + report.Title
+			message.color = chatColors["CRITICAL"]
+		}
+		message.description = "```\n" + report.Summary + "\n```"
+
+	default:
+		message.title = notification.Event
+	}
+
+	if suppressed > 0 {
+		message.fields = append(message.fields, chatField{"Held back", fmt.Sprintf("%d messages since the last one", suppressed)})
+	}
+	return message
+}
+
+// chatTargets lists an attack's targets, with the endpoint under attack
+func chatTargets(attack models.Attack) string {
+	targets := attack.TargetIPs
+	if len(targets) > 3 {
+		targets = append(targets[:3:3], fmt.Sprintf("+%d more", len(attack.TargetIPs)-3))
+	}
+	list := strings.Join(targets, ", ")
+	if attack.TargetEndpoint != "" {
+		list = strings.TrimPrefix(list+" "+attack.TargetEndpoint, " ")
+	}
+	return list
+}
+
+// chatSources lists an attack's busiest sources, with their requests when
+// the detection measured them
+func chatSources(attack models.Attack) string {
+	var sources []string
+	switch {
+	case attack.Evidence != nil && len(attack.Evidence.TopSources) > 0:
+		for _, source := range attack.Evidence.TopSources {
+			sources = append(sources, fmt.Sprintf("%s (%d)", source.IP, source.Count))
+		}
+	case len(attack.SourcePrefixes) > 0:
+		for _, prefix := range attack.SourcePrefixes {
+			sources = append(sources, prefix.Prefix)
+		}
+	default:
+		sources = attack.SourceIPs
+	}
+	if len(sources) > chatTopSources {
+		sources = sources[:chatTopSources]
+	}
+	return strings.Join(sources, "\n")
+}
+
+// slack lays the message out as a Slack attachment, for its color bar
+func (m chatMessage) slack() map[string]any {
+	fields := make([]map[string]any, 0, len(m.fields))
+	for _, field := range m.fields {
+		if field.value != "" {
+			fields = append(fields, map[string]any{"title": field.name, "value": field.value, "short": !strings.Contains(field.value, "\n")})
+		}
+	}
+	attachment := map[string]any{
+		"fallback": m.title,
+		"color":    fmt.Sprintf("#%06x", m.color),
+		"title":    m.title,
+		"text":     m.description,
+		"fields":   fields,
+		"ts":       m.timestamp.Unix(),
+	}
+	if m.url != "" {
+		attachment["title_link"] = m.url
+	}
+	return map[string]any{"text": m.title, "attachments": []any{attachment}}
+}
+
+// discord lays the message out as a Discord embed, which rejects empty
+// descriptions and field values
+func (m chatMessage) discord() map[string]any {
+	fields := make([]map[string]any, 0, len(m.fields))
+	for _, field := range m.fields {
+		if field.value != "" {
+			fields = append(fields, map[string]any{"name": field.name, "value": field.value, "inline": !strings.Contains(field.value, "\n")})
+		}
+	}
+	embed := map[string]any{
+		"title":     m.title,
+		"color":     m.color,
+		"fields":    fields,
+		"timestamp": m.timestamp.UTC().Format(time.RFC3339),
+	}
+	if m.description != "" {
+		embed["description"] = m.description
+	}
+	if m.url != "" {
+		embed["url"] = m.url
+	}
+	return map[string]any{"embeds": []any{embed}}
+}
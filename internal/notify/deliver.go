@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Delivery statuses recorded per attempt
+const (
+	StatusDelivered = "DELIVERED"
+	StatusSkipped   = "SKIPPED"
+	StatusRetrying  = "RETRYING"
+	StatusFailed    = "FAILED"
+)
+
+// ErrSkipped is returned by a notifier that deliberately sent nothing, e.g.
+// because the attack is below its severity threshold
+var ErrSkipped = errors.New("notify: skipped")
+
+// permanentError marks a failure that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure retrying won't fix, such as a rejected
+// request or bad credentials
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// statusError turns an unsuccessful HTTP response into an error, marked
+// permanent when the request was rejected and sending it again won't help
+func statusError(what string, resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err := fmt.Errorf("%s: %s: %s", what, resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+		return Permanent(err)
+	}
+	return err
+}
+
+// RetryPolicy bounds how hard Deliver tries
+type RetryPolicy struct {
+	Attempts int
+	Backoff  time.Duration // before the second attempt, doubling after
+	Timeout  time.Duration // per attempt
+}
+
+// DefaultRetryPolicy tries three times over about six seconds
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: 3, Backoff: 2 * time.Second, Timeout: 30 * time.Second}
+}
+
+// Deliver sends one destination a notification, retrying transient
+// failures, and passes every attempt to record. It returns the last
+// attempt, whose status is DELIVERED, SKIPPED or FAILED; a FAILED delivery
+// belongs in the dead-letter queue.
+func Deliver(ctx context.Context, notifier Notifier, notification models.Notification, policy RetryPolicy, record func(models.NotificationDelivery)) models.NotificationDelivery {
+	severity := ""
+	if notification.Attack != nil {
+		severity = notification.Attack.Severity
+	}
+
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		delivery := models.NotificationDelivery{
+			ID:        uuid.New().String(),
+			Event:     notification.Event,
+			AttackID:  notification.AttackID(),
+			Severity:  severity,
+			Channel:   notifier.Name(),
+			Attempt:   attempt,
+			Timestamp: time.Now(),
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, policy.Timeout)
+		err := notifier.Notify(attemptCtx, notification)
+		cancel()
+		delivery.LatencyMs = time.Since(delivery.Timestamp).Milliseconds()
+
+		switch {
+		case err == nil:
+			delivery.Status = StatusDelivered
+		case errors.Is(err, ErrSkipped):
+			delivery.Status = StatusSkipped
+			if err != ErrSkipped {
+				delivery.Error = err.Error()
+			}
+		case IsPermanent(err) || attempt >= policy.Attempts || ctx.Err() != nil:
+			delivery.Status = StatusFailed
+			delivery.Error = err.Error()
+		default:
+			delivery.Status = StatusRetrying
+			delivery.Error = err.Error()
+		}
+		record(delivery)
+
+		if delivery.Status != StatusRetrying {
+			return delivery
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
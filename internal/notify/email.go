@@ -0,0 +1,391 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// EventDigest is a daily digest, delivered outside of the events notifiers
+// subscribe to
+const EventDigest = "digest"
+
+// EmailConfig configures alert emails sent through an SMTP server
+type EmailConfig struct {
+	// Addr is the SMTP server's host:port; port 465 is spoken over TLS,
+	// others upgrade with STARTTLS when the server offers it
+	Addr     string
+	Username string
+	Password string
+	From     string
+	// Recipients lists who hears of attacks, per severity
+	Recipients map[string][]string
+	// Attacks at or above AlertSeverity are emailed as they start; the
+	// digest covers the rest
+	AlertSeverity string
+	// DashboardURL is linked from every email when set
+	DashboardURL string
+}
+
+// EmailNotifier emails recipients when severe attacks start, and sends
+// them a digest of the day's attacks on request
+type EmailNotifier struct {
+	cfg  EmailConfig
+	host string
+}
+
+func NewEmailNotifier(cfg EmailConfig) (*EmailNotifier, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("email: smtp address must be host:port")
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("email: sender is required")
+	}
+	if len(cfg.Recipients) == 0 {
+		return nil, fmt.Errorf("email: no recipients")
+	}
+	for severity := range cfg.Recipients {
+		if models.SeverityRank(severity) == 0 {
+			return nil, fmt.Errorf("email: unknown severity %s", severity)
+		}
+	}
+	cfg.AlertSeverity = strings.ToUpper(cfg.AlertSeverity)
+	if cfg.AlertSeverity == "" {
+		cfg.AlertSeverity = "CRITICAL"
+	}
+	if models.SeverityRank(cfg.AlertSeverity) == 0 {
+		return nil, fmt.Errorf("email: unknown severity %s", cfg.AlertSeverity)
+	}
+
+	return &EmailNotifier{cfg: cfg, host: host}, nil
+}
+
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Subscribes takes attacks as they start and escalate
+func (e *EmailNotifier) Subscribes(event string) bool {
+	return event == EventAttackStarted || event == EventAttackEscalated
+}
+
+// Notify emails the attack to the recipients of its severity, if it is
+// severe enough to be emailed right away
+func (e *EmailNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.Attack == nil {
+		return ErrSkipped
+	}
+	attack := *notification.Attack
+	to := e.cfg.Recipients[attack.Severity]
+	if !meetsSeverity(attack, e.cfg.AlertSeverity) || len(to) == 0 {
+		return ErrSkipped
+	}
+
+	subject := fmt.Sprintf("[DDoS] %s %s attack", attack.Severity, attack.Type)
+	if attack.Environment != "" {
+		subject += " in " + attack.Environment
+	}
+	if notification.Event == EventAttackEscalated {
+		subject += " escalated"
+	}
+	data := emailAlert{
+		Title:        subject,
+		Attack:       attack,
+		Confidence:   int(attack.Confidence*100 + 0.5),
+		Targets:      chatTargets(attack),
+		Color:        fmt.Sprintf("#%06x", chatColors[attack.Severity]),
+		DashboardURL: e.cfg.DashboardURL,
+	}
+	if sources := chatSources(attack); sources != "" {
+		data.Sources = strings.Split(sources, "\n")
+	}
+	return e.send(ctx, to, subject, alertTemplate, data)
+}
+
+// Digest returns a notifier sending each recipient a digest of the
+// attacks of their severities that started between from and to, so the
+// digest can be delivered with retries like any notification
+func (e *EmailNotifier) Digest(attacks []models.Attack, from, to time.Time) Notifier {
+	return emailDigest{email: e, attacks: attacks, from: from, to: to}
+}
+
+// emailDigest is a pending digest, sent by Notify
+type emailDigest struct {
+	email    *EmailNotifier
+	attacks  []models.Attack
+	from, to time.Time
+}
+
+func (d emailDigest) Name() string {
+	return d.email.Name()
+}
+
+func (d emailDigest) Subscribes(event string) bool {
+	return event == EventDigest
+}
+
+// Notify sends every recipient their digest. Recipients of several
+// severities get one digest covering them all, even when no attack came.
+func (d emailDigest) Notify(ctx context.Context, _ models.Notification) error {
+	severities := make(map[string][]string)
+	for severity, recipients := range d.email.cfg.Recipients {
+		for _, recipient := range recipients {
+			severities[recipient] = append(severities[recipient], severity)
+		}
+	}
+	recipients := make([]string, 0, len(severities))
+	for recipient := range severities {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+
+	var errs []error
+	for _, recipient := range recipients {
+		data := emailDigestData{
+			From:         d.from,
+			To:           d.to,
+			DashboardURL: d.email.cfg.DashboardURL,
+			Counts:       make(map[string]int),
+		}
+		for _, attack := range d.attacks {
+			if !slices.Contains(severities[recipient], attack.Severity) {
+				continue
+			}
+			data.Attacks = append(data.Attacks, digestAttack{
+				Attack:     attack,
+				Started:    attack.StartTime.In(d.from.Location()).Format("15:04"),
+				Color:      fmt.Sprintf("#%06x", chatColors[attack.Severity]),
+				Confidence: int(attack.Confidence*100 + 0.5),
+				Lasted:     digestDuration(attack, d.to),
+			})
+			data.Counts[attack.Severity]++
+		}
+		sort.Slice(data.Attacks, func(i, j int) bool {
+			return data.Attacks[i].StartTime.Before(data.Attacks[j].StartTime)
+		})
+
+		noun := "attacks"
+		if len(data.Attacks) == 1 {
+			noun = "attack"
+		}
+		data.Title = fmt.Sprintf("[DDoS] Daily digest: %d %s since %s", len(data.Attacks), noun, d.from.Format("Mon 2 Jan 15:04"))
+		if err := d.email.send(ctx, []string{recipient}, data.Title, digestTemplate, data); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", recipient, err))
+		}
+	}
+
+	// A retry sends the digest to everyone again, so only retry when
+	// nobody got it
+	if len(errs) > 0 && len(errs) < len(recipients) {
+		return Permanent(errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// digestDuration is how long an attack lasted, or has lasted so far
+func digestDuration(attack models.Attack, now time.Time) string {
+	end := now
+	if attack.EndTime != nil {
+		end = *attack.EndTime
+	}
+	lasted := end.Sub(attack.StartTime).Round(time.Second).String()
+	if attack.EndTime == nil {
+		lasted += " (ongoing)"
+	}
+	return lasted
+}
+
+// send emails an HTML message rendered from a template, with a plain text
+// part for clients that don't show HTML
+func (e *EmailNotifier) send(ctx context.Context, to []string, subject string, tmpl *template.Template, data any) error {
+	var html bytes.Buffer
+	if err := tmpl.Execute(&html, data); err != nil {
+		return Permanent(fmt.Errorf("email: render: %w", err))
+	}
+	message, err := e.message(to, subject, html.String())
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if strings.HasSuffix(e.cfg.Addr, ":465") {
+		conn = tls.Client(conn, &tls.Config{ServerName: e.host})
+	}
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("email: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+			return fmt.Errorf("email: starttls: %w", err)
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.host)); err != nil {
+			return smtpError("auth", err)
+		}
+	}
+	if err := client.Mail(e.cfg.From); err != nil {
+		return smtpError("mail from", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return smtpError("rcpt to "+recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("data", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("data", err)
+	}
+	return client.Quit()
+}
+
+// smtpError marks errors the server answered with a permanent (5xx) reply
+func smtpError(stage string, err error) error {
+	err = fmt.Errorf("email: %s: %w", stage, err)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+// message builds a multipart/alternative email around the HTML body
+func (e *EmailNotifier) message(to []string, subject, html string) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	text, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	text.Write([]byte(subject + "\r\n\r\nThis message is best viewed as HTML."))
+	if e.cfg.DashboardURL != "" {
+		text.Write([]byte(" Dashboard: " + e.cfg.DashboardURL))
+	}
+	htmlPart, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	htmlPart.Write([]byte(html))
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", e.cfg.From},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + uuid.New().String() + "@ddos-dashboard>"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// emailAlert is what alertTemplate renders
+type emailAlert struct {
+	Title        string
+	Attack       models.Attack
+	Confidence   int
+	Targets      string
+	Sources      []string
+	Color        string
+	DashboardURL string
+}
+
+// emailDigestData is what digestTemplate renders
+type emailDigestData struct {
+	Title        string
+	From, To     time.Time
+	Attacks      []digestAttack
+	Counts       map[string]int
+	DashboardURL string
+}
+
+type digestAttack struct {
+	models.Attack
+	Started    string // in the digest's time zone
+	Color      string
+	Confidence int
+	Lasted     string
+}
+
+var alertTemplate = template.Must(template.New("alert").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif; color: #222;">
+<h2 style="border-left: 6px solid {{.Color}}; padding-left: 10px;">{{.Title}}</h2>
+<p>{{.Attack.Description}}</p>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr><th align="left">Severity</th><td>{{.Attack.Severity}}</td></tr>
+<tr><th align="left">Confidence</th><td>{{.Confidence}}%</td></tr>
+<tr><th align="left">Started</th><td>{{.Attack.StartTime.UTC.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+{{if .Targets}}<tr><th align="left">Targets</th><td>{{.Targets}}</td></tr>{{end}}
+{{if .Sources}}<tr><th align="left" valign="top">Top sources</th><td>{{range .Sources}}{{.}}<br>{{end}}</td></tr>{{end}}
+<tr><th align="left">Mitigated</th><td>{{if .Attack.Mitigated}}yes{{else}}no{{end}}</td></tr>
+<tr><th align="left">Attack</th><td><code>{{.Attack.ID}}</code></td></tr>
+</table>
+{{if .DashboardURL}}<p><a href="{{.DashboardURL}}">Open the dashboard</a></p>{{end}}
+</body></html>
+`))
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif; color: #222;">
+<h2>{{.Title}}</h2>
+<p>Attacks started from {{.From.Format "2006-01-02 15:04 MST"}} to {{.To.Format "2006-01-02 15:04 MST"}}{{range $severity, $count := .Counts}} · {{$count}} {{$severity}}{{end}}</p>
+{{if .Attacks}}
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr style="background: #f0f0f0;"><th align="left">Started</th><th align="left">Severity</th><th align="left">Type</th><th align="left">Environment</th><th align="left">Confidence</th><th align="left">Lasted</th><th align="left">Mitigated</th></tr>
+{{range .Attacks}}<tr style="border-top: 1px solid #ddd;">
+<td>{{.Started}}</td>
+<td style="border-left: 6px solid {{.Color}};">{{.Severity}}</td>
+<td>{{.Type}}</td>
+<td>{{.Environment}}</td>
+<td>{{.Confidence}}%</td>
+<td>{{.Lasted}}</td>
+<td>{{if .Mitigated}}yes{{else}}no{{end}}</td>
+</tr>
+{{end}}</table>
+{{else}}<p>No attacks.</p>{{end}}
+{{if .DashboardURL}}<p><a href="{{.DashboardURL}}">Open the dashboard</a></p>{{end}}
+</body></html>
+`))
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// incidentKey deduplicates the incidents of an attack: attacks with the
+// same fingerprint (type, environment, top sources and targets) belong to
+// one incident, so a recurring attack reopens or joins it rather than
+// paging again
+func incidentKey(attack models.Attack) string {
+	if attack.Fingerprint != "" {
+		return "ddos-" + attack.Fingerprint
+	}
+	return "ddos-" + attack.ID
+}
+
+// incidentSummary is the one-line title of an attack's incident
+func incidentSummary(attack models.Attack) string {
+	summary := fmt.Sprintf("%s %s attack", attack.Severity, attack.Type)
+	if attack.Environment != "" {
+		summary += " in " + attack.Environment
+	}
+	if len(attack.TargetIPs) > 0 {
+		summary += " on " + chatTargets(attack)
+	}
+	return summary
+}
+
+// incidentDetails are the attack's particulars attached to its incident
+func incidentDetails(attack models.Attack) map[string]string {
+	details := map[string]string{
+		"attack_id":   attack.ID,
+		"type":        attack.Type,
+		"severity":    attack.Severity,
+		"confidence":  fmt.Sprintf("%.2f", attack.Confidence),
+		"description": attack.Description,
+		"started":     attack.StartTime.UTC().Format("2006-01-02T15:04:05Z"),
+		"mitigated":   fmt.Sprint(attack.Mitigated),
+	}
+	if attack.Environment != "" {
+		details["environment"] = attack.Environment
+	}
+	if targets := chatTargets(attack); targets != "" {
+		details["targets"] = targets
+	}
+	if sources := chatSources(attack); sources != "" {
+		details["top_sources"] = strings.ReplaceAll(sources, "\n", ", ")
+	}
+	if attack.EndTime != nil {
+		details["ended"] = attack.EndTime.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return details
+}
@@ -0,0 +1,237 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// MISPConfig configures event creation on a MISP instance
+type MISPConfig struct {
+	URL    string
+	APIKey string
+	// Only attacks at or above this severity are shared
+	MinSeverity string
+	// MISP distribution level: 0 organisation, 1 community, 2 connected
+	// communities, 3 all communities
+	Distribution int
+	// Tags added to every event, e.g. "tlp:amber"
+	Tags []string
+	// Accept self-signed certificates, common on private MISP instances
+	InsecureSkipVerify bool
+}
+
+// MISPNotifier shares attacks with trusted communities as MISP events
+type MISPNotifier struct {
+	cfg    MISPConfig
+	client *http.Client
+}
+
+func NewMISPNotifier(cfg MISPConfig) (*MISPNotifier, error) {
+	if cfg.URL == "" || cfg.APIKey == "" {
+		return nil, fmt.Errorf("misp: url and api key are required")
+	}
+	if cfg.Distribution < 0 || cfg.Distribution > 3 {
+		return nil, fmt.Errorf("misp: distribution must be between 0 and 3")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &MISPNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}, nil
+}
+
+func (m *MISPNotifier) Name() string {
+	return "misp"
+}
+
+// mispNamespace derives the UUID of an attack's MISP event from the attack
+// ID, so every notification of the attack lands on the same event
+var mispNamespace = uuid.MustParse("8d3f1a6e-2c4b-4e9a-b7d5-61f0c3a9e842")
+
+// MISP analysis levels
+const (
+	mispAnalysisOngoing   = "1"
+	mispAnalysisCompleted = "2"
+)
+
+type mispEvent struct {
+	UUID          string          `json:"uuid"`
+	Info          string          `json:"info"`
+	Date          string          `json:"date"`
+	Distribution  string          `json:"distribution"`
+	ThreatLevelID string          `json:"threat_level_id"`
+	Analysis      string          `json:"analysis"`
+	Attributes    []mispAttribute `json:"Attribute"`
+	Tags          []mispTag       `json:"Tag,omitempty"`
+}
+
+type mispAttribute struct {
+	UUID      string `json:"uuid"`
+	Type      string `json:"type"`
+	Category  string `json:"category"`
+	Value     string `json:"value"`
+	ToIDS     bool   `json:"to_ids"`
+	Comment   string `json:"comment,omitempty"`
+	FirstSeen string `json:"first_seen,omitempty"`
+	LastSeen  string `json:"last_seen,omitempty"`
+}
+
+type mispTag struct {
+	Name string `json:"name"`
+}
+
+// Subscribes takes attacks as they start, escalate and end, keeping each
+// attack's event up to date
+func (m *MISPNotifier) Subscribes(event string) bool {
+	return event == EventAttackStarted || event == EventAttackEscalated || event == EventAttackEnded
+}
+
+// Notify shares the attack as a MISP event if it meets the severity
+// threshold. An attack has one event: it is created the first time and
+// updated after, so escalations, the end of the attack, alerts raised again
+// for it and retries of a create that reached MISP don't add duplicates.
+func (m *MISPNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.Attack == nil {
+		return ErrSkipped
+	}
+	attack := *notification.Attack
+	if !meetsSeverity(attack, m.cfg.MinSeverity) {
+		return ErrSkipped
+	}
+
+	event := m.event(attack)
+	body, err := json.Marshal(map[string]mispEvent{"Event": event})
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.post(ctx, "/events/edit/"+event.UUID, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		defer resp.Body.Close()
+		return statusError("misp: update event", resp)
+	}
+	resp.Body.Close()
+
+	resp, err = m.post(ctx, "/events/add", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return statusError("misp: create event", resp)
+}
+
+func (m *MISPNotifier) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(m.cfg.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", m.cfg.APIKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("misp: %w", err)
+	}
+	return resp, nil
+}
+
+func (m *MISPNotifier) event(attack models.Attack) mispEvent {
+	eventUUID := uuid.NewSHA1(mispNamespace, []byte(attack.ID))
+	firstSeen := attack.StartTime.UTC().Format(time.RFC3339)
+	lastSeen := ""
+	if attack.EndTime != nil {
+		lastSeen = attack.EndTime.UTC().Format(time.RFC3339)
+	}
+
+	attributes := []mispAttribute{
+		{Type: "text", Category: "Other", Value: attack.Type, Comment: "DDoS attack type"},
+		{Type: "float", Category: "Other", Value: strconv.FormatFloat(attack.Confidence, 'f', 2, 64), Comment: "detection confidence"},
+		{Type: "datetime", Category: "Other", Value: firstSeen, Comment: "attack start"},
+	}
+	if lastSeen != "" {
+		attributes = append(attributes, mispAttribute{Type: "datetime", Category: "Other", Value: lastSeen, Comment: "attack end"})
+	}
+
+	for _, ip := range attack.SourceIPs {
+		attributes = append(attributes, mispAttribute{
+			Type:      "ip-src",
+			Category:  "Network activity",
+			Value:     ip,
+			ToIDS:     true,
+			FirstSeen: firstSeen,
+			LastSeen:  lastSeen,
+		})
+	}
+	// Targets are our own assets: context for partners, never IDS signatures
+	for _, ip := range attack.TargetIPs {
+		attributes = append(attributes, mispAttribute{
+			Type:     "ip-dst",
+			Category: "Network activity",
+			Value:    ip,
+			Comment:  "attack target",
+		})
+	}
+	// Updates match attributes by UUID: an address keeps its attribute,
+	// and the type, confidence and times keep theirs as their values change
+	for i, attribute := range attributes {
+		key := attribute.Type + "|" + attribute.Comment
+		if attribute.Category == "Network activity" {
+			key = attribute.Type + "|" + attribute.Value
+		}
+		attributes[i].UUID = uuid.NewSHA1(eventUUID, []byte(key)).String()
+	}
+
+	analysis := mispAnalysisOngoing
+	if attack.EndTime != nil {
+		analysis = mispAnalysisCompleted
+	}
+
+	tags := []mispTag{{Name: "ddos"}}
+	for _, tag := range m.cfg.Tags {
+		tags = append(tags, mispTag{Name: tag})
+	}
+
+	return mispEvent{
+		UUID:          eventUUID.String(),
+		Info:          fmt.Sprintf("%s %s attack: %s", attack.Severity, attack.Type, attack.Description),
+		Date:          attack.StartTime.UTC().Format("2006-01-02"),
+		Distribution:  strconv.Itoa(m.cfg.Distribution),
+		ThreatLevelID: mispThreatLevel(attack.Severity),
+		Analysis:      analysis,
+		Attributes:    attributes,
+		Tags:          tags,
+	}
+}
+
+// mispThreatLevel maps severity onto MISP threat levels (1 high .. 4 undefined)
+func mispThreatLevel(severity string) string {
+	switch severity {
+	case "CRITICAL", "HIGH":
+		return "1"
+	case "MEDIUM":
+		return "2"
+	case "LOW":
+		return "3"
+	}
+	return "4"
+}
@@ -0,0 +1,89 @@
+// Package notify delivers detected attacks and mitigation changes to
+// external systems
+package notify
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Events notifiers are told about; changes to mitigations are
+// "mitigation." followed by the mitigation event, e.g. mitigation.applied
+const (
+	EventAttackStarted   = "attack.started"
+	EventAttackEscalated = "attack.escalated"
+	EventAttackEnded     = "attack.ended"
+
+	// Outcomes of a detection validation run
+	EventVerifyPassed = "verify.passed"
+	EventVerifyFailed = "verify.failed"
+)
+
+// Notifier sends notifications to one destination
+type Notifier interface {
+	Name() string
+	// Subscribes reports whether the destination wants events of a type;
+	// others are not sent to it at all
+	Subscribes(event string) bool
+	Notify(ctx context.Context, notification models.Notification) error
+}
+
+// AttackNotification tells of an attack starting or ending
+func AttackNotification(event string, attack models.Attack) models.Notification {
+	return models.Notification{
+		EventID:   uuid.New().String(),
+		Event:     event,
+		Attack:    &attack,
+		Timestamp: time.Now(),
+	}
+}
+
+// MitigationNotification tells of a change to a mitigation, as recorded on
+// the audit trail
+func MitigationNotification(event models.MitigationEvent) models.Notification {
+	return models.Notification{
+		EventID:    uuid.New().String(),
+		Event:      "mitigation." + event.Event,
+		Mitigation: &event,
+		Timestamp:  event.Timestamp,
+	}
+}
+
+// ReportNotification tells of the outcome of a detection validation run
+func ReportNotification(report models.ValidationReport) models.Notification {
+	event := EventVerifyPassed
+	if !report.Passed {
+		event = EventVerifyFailed
+	}
+	return models.Notification{
+		EventID:   uuid.New().String(),
+		Event:     event,
+		Report:    &report,
+		Timestamp: time.Now(),
+	}
+}
+
+// MatchEvent reports whether an event matches any of the patterns, which
+// are event types or globs such as mitigation.*. No patterns match every
+// event.
+func MatchEvent(patterns []string, event string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, event); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// meetsSeverity reports whether an attack is at least as severe as min.
+// An empty min accepts everything.
+func meetsSeverity(attack models.Attack, min string) bool {
+	return min == "" || models.SeverityRank(attack.Severity) >= models.SeverityRank(min)
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// DefaultOpsgenieURL is the Opsgenie API; EU accounts use
+// https://api.eu.opsgenie.com
+const DefaultOpsgenieURL = "https://api.opsgenie.com"
+
+// OpsgenieConfig configures alerts through the Opsgenie Alert API
+type OpsgenieConfig struct {
+	APIKey string
+	URL    string
+	// Team the alerts are routed to, if not the integration's
+	Team string
+	// Only attacks at or above this severity open alerts
+	MinSeverity string
+	// DashboardURL is attached to every alert when set
+	DashboardURL string
+}
+
+// OpsgenieNotifier opens an alert when an attack starts and closes it when
+// the attack ends
+type OpsgenieNotifier struct {
+	cfg    OpsgenieConfig
+	client *http.Client
+}
+
+func NewOpsgenieNotifier(cfg OpsgenieConfig) (*OpsgenieNotifier, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("opsgenie: api key is required")
+	}
+	if cfg.URL == "" {
+		cfg.URL = DefaultOpsgenieURL
+	}
+	if err := checkURL(cfg.URL); err != nil {
+		return nil, fmt.Errorf("opsgenie: %w", err)
+	}
+	cfg.MinSeverity = strings.ToUpper(cfg.MinSeverity)
+	if cfg.MinSeverity != "" && models.SeverityRank(cfg.MinSeverity) == 0 {
+		return nil, fmt.Errorf("opsgenie: unknown severity %s", cfg.MinSeverity)
+	}
+
+	return &OpsgenieNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (o *OpsgenieNotifier) Name() string {
+	return "opsgenie"
+}
+
+// Subscribes takes attacks starting, escalating and ending
+func (o *OpsgenieNotifier) Subscribes(event string) bool {
+	return event == EventAttackStarted || event == EventAttackEscalated || event == EventAttackEnded
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description,omitempty"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags"`
+	Details     map[string]string   `json:"details"`
+	Entity      string              `json:"entity,omitempty"`
+	Source      string              `json:"source"`
+	Priority    string              `json:"priority"`
+}
+
+type opsgenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Notify creates the attack's alert as it starts or escalates and closes it
+// as it ends, aliased by the attack's fingerprint. Opsgenie folds a
+// repeated alias into the open alert.
+func (o *OpsgenieNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.Attack == nil {
+		return ErrSkipped
+	}
+	attack := *notification.Attack
+	if !meetsSeverity(attack, o.cfg.MinSeverity) {
+		return ErrSkipped
+	}
+	alias := incidentKey(attack)
+	base := strings.TrimSuffix(o.cfg.URL, "/") + "/v2/alerts"
+
+	if notification.Event == EventAttackEnded {
+		body := map[string]string{"source": "ddos-dashboard", "note": "Attack ended after " + digestDuration(attack, time.Now())}
+		status, err := o.post(ctx, base+"/"+url.PathEscape(alias)+"/close?identifierType=alias", body)
+		// An alert closed by hand, or never opened, has nothing to close
+		if status == http.StatusNotFound {
+			return fmt.Errorf("%w: no open alert %s", ErrSkipped, alias)
+		}
+		return err
+	}
+
+	alert := opsgenieAlert{
+		Message:     incidentSummary(attack),
+		Alias:       alias,
+		Description: attack.Description,
+		Tags:        []string{"ddos", strings.ToLower(attack.Type)},
+		Details:     incidentDetails(attack),
+		Entity:      attack.Environment,
+		Source:      "ddos-dashboard",
+		Priority:    opsgeniePriority(attack.Severity),
+	}
+	if o.cfg.Team != "" {
+		alert.Responders = []opsgenieResponder{{Name: o.cfg.Team, Type: "team"}}
+	}
+	if o.cfg.DashboardURL != "" {
+		alert.Details["dashboard"] = o.cfg.DashboardURL
+	}
+	_, err := o.post(ctx, base, alert)
+	return err
+}
+
+// post sends a request to the Alert API, which queues it and answers 202,
+// and returns the answer's status
+func (o *OpsgenieNotifier) post(ctx context.Context, endpoint string, payload any) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "GenieKey "+o.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("opsgenie: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, statusError("opsgenie", resp)
+}
+
+// opsgeniePriority maps severity onto Opsgenie priorities (P1 highest)
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case "CRITICAL":
+		return "P1"
+	case "HIGH":
+		return "P2"
+	case "MEDIUM":
+		return "P3"
+	}
+	return "P4"
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyConfig configures incidents through the PagerDuty Events API v2
+type PagerDutyConfig struct {
+	// RoutingKey is the integration key of the service paged
+	RoutingKey string
+	// URL overrides the Events API endpoint
+	URL string
+	// Only attacks at or above this severity open incidents
+	MinSeverity string
+	// DashboardURL is linked from every incident when set
+	DashboardURL string
+}
+
+// PagerDutyNotifier opens an incident when an attack starts and resolves
+// it when the attack ends
+type PagerDutyNotifier struct {
+	cfg    PagerDutyConfig
+	client *http.Client
+}
+
+func NewPagerDutyNotifier(cfg PagerDutyConfig) (*PagerDutyNotifier, error) {
+	if cfg.RoutingKey == "" {
+		return nil, fmt.Errorf("pagerduty: routing key is required")
+	}
+	if cfg.URL == "" {
+		cfg.URL = DefaultPagerDutyURL
+	}
+	if err := checkURL(cfg.URL); err != nil {
+		return nil, fmt.Errorf("pagerduty: %w", err)
+	}
+	cfg.MinSeverity = strings.ToUpper(cfg.MinSeverity)
+	if cfg.MinSeverity != "" && models.SeverityRank(cfg.MinSeverity) == 0 {
+		return nil, fmt.Errorf("pagerduty: unknown severity %s", cfg.MinSeverity)
+	}
+
+	return &PagerDutyNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *PagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+// Subscribes takes attacks starting, escalating and ending
+func (p *PagerDutyNotifier) Subscribes(event string) bool {
+	return event == EventAttackStarted || event == EventAttackEscalated || event == EventAttackEnded
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // trigger or resolve
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class"`
+	CustomDetails map[string]string `json:"custom_details"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Notify triggers the attack's incident as it starts or escalates and
+// resolves it as it ends, keyed by the attack's fingerprint
+func (p *PagerDutyNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.Attack == nil {
+		return ErrSkipped
+	}
+	attack := *notification.Attack
+	if !meetsSeverity(attack, p.cfg.MinSeverity) {
+		return ErrSkipped
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  p.cfg.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    incidentKey(attack),
+	}
+	if notification.Event == EventAttackEnded {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:       incidentSummary(attack),
+			Source:        "ddos-dashboard",
+			Severity:      pagerDutySeverity(attack.Severity),
+			Timestamp:     attack.StartTime.UTC().Format(time.RFC3339),
+			Component:     strings.Join(attack.TargetIPs, ","),
+			Group:         attack.Environment,
+			Class:         attack.Type,
+			CustomDetails: incidentDetails(attack),
+		}
+		if p.cfg.DashboardURL != "" {
+			event.Links = []pagerDutyLink{{Href: p.cfg.DashboardURL, Text: "DDoS dashboard"}}
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pagerduty: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return statusError("pagerduty: "+event.EventAction, resp)
+}
+
+// pagerDutySeverity maps severity onto PagerDuty's
+func pagerDutySeverity(severity string) string {
+	switch severity {
+	case "CRITICAL":
+		return "critical"
+	case "HIGH":
+		return "error"
+	case "MEDIUM":
+		return "warning"
+	}
+	return "info"
+}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Route sends the attacks it matches to channels in turn. Every condition
+// given must hold; a route without conditions matches every attack.
+type Route struct {
+	Name        string   `yaml:"name" json:"name"`
+	MinSeverity string   `yaml:"min_severity" json:"min_severity,omitempty"`
+	AttackTypes []string `yaml:"attack_types" json:"attack_types,omitempty"`
+	// Environments are the tenants the route is for
+	Environments []string `yaml:"environments" json:"environments,omitempty"`
+	// Steps escalate in order while the attack's alert is unacknowledged
+	Steps []RouteStep `yaml:"steps" json:"steps"`
+}
+
+// RouteStep notifies channels, by their name in the delivery log, once an
+// attack has gone unacknowledged for After
+type RouteStep struct {
+	Channels []string      `yaml:"channels" json:"channels"`
+	After    time.Duration `yaml:"after" json:"after"`
+}
+
+// MarshalJSON writes After as a duration string, which reads back as YAML
+func (s RouteStep) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Channels []string `json:"channels"`
+		After    string   `json:"after"`
+	}{s.Channels, s.After.String()})
+}
+
+// Matches reports whether the route applies to an attack
+func (r Route) Matches(attack models.Attack) bool {
+	if r.MinSeverity != "" && models.SeverityRank(attack.Severity) < models.SeverityRank(r.MinSeverity) {
+		return false
+	}
+	// The attack's own type or any of its vectors
+	if len(r.AttackTypes) > 0 && !slices.ContainsFunc(append(attack.Types(), attack.Type), func(t string) bool {
+		return slices.Contains(r.AttackTypes, t)
+	}) {
+		return false
+	}
+	return len(r.Environments) == 0 || slices.Contains(r.Environments, attack.Environment)
+}
+
+// Channels lists every channel of the route's steps
+func (r Route) Channels() []string {
+	channels := make([]string, 0)
+	for _, step := range r.Steps {
+		for _, channel := range step.Channels {
+			if !slices.Contains(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels
+}
+
+// Routing is the layout of a routing file: routes tried in order, the first
+// matching an attack deciding where it goes
+type Routing struct {
+	Routes []Route `yaml:"routes" json:"routes"`
+}
+
+// Match returns the first route matching an attack, or nil
+func (r Routing) Match(attack models.Attack) *Route {
+	for i := range r.Routes {
+		if r.Routes[i].Matches(attack) {
+			return &r.Routes[i]
+		}
+	}
+	return nil
+}
+
+// LoadRouting reads a YAML or JSON routing file
+func LoadRouting(file string) (Routing, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Routing{}, err
+	}
+	routing, err := ParseRouting(data)
+	if err != nil {
+		return routing, fmt.Errorf("%s: %w", file, err)
+	}
+	return routing, nil
+}
+
+// ParseRouting reads and checks YAML or JSON routes
+func ParseRouting(data []byte) (Routing, error) {
+	var routing Routing
+	if err := yaml.Unmarshal(data, &routing); err != nil {
+		return routing, err
+	}
+
+	names := make(map[string]bool, len(routing.Routes))
+	for i := range routing.Routes {
+		route := &routing.Routes[i]
+		if route.Name == "" {
+			return routing, fmt.Errorf("route %d has no name", i+1)
+		}
+		if names[route.Name] {
+			return routing, fmt.Errorf("route %s is defined twice", route.Name)
+		}
+		names[route.Name] = true
+
+		route.MinSeverity = strings.ToUpper(route.MinSeverity)
+		if route.MinSeverity != "" && models.SeverityRank(route.MinSeverity) == 0 {
+			return routing, fmt.Errorf("route %s: unknown severity %s", route.Name, route.MinSeverity)
+		}
+		for j, attackType := range route.AttackTypes {
+			route.AttackTypes[j] = strings.ToUpper(attackType)
+		}
+		if len(route.Steps) == 0 {
+			return routing, fmt.Errorf("route %s has no steps", route.Name)
+		}
+		for j, step := range route.Steps {
+			if len(step.Channels) == 0 {
+				return routing, fmt.Errorf("route %s: step %d has no channels", route.Name, j+1)
+			}
+			if step.After < 0 {
+				return routing, fmt.Errorf("route %s: step %d has a negative delay", route.Name, j+1)
+			}
+			if j > 0 && step.After < route.Steps[j-1].After {
+				return routing, fmt.Errorf("route %s: step %d comes before step %d", route.Name, j+1, j)
+			}
+		}
+	}
+	return routing, nil
+}
+
+// Check reports channels the routes name that aren't configured
+func (r Routing) Check(notifiers []Notifier) error {
+	for _, route := range r.Routes {
+		for _, channel := range route.Channels() {
+			if !slices.ContainsFunc(notifiers, func(n Notifier) bool { return n.Name() == channel }) {
+				return fmt.Errorf("route %s: channel %s is not configured", route.Name, channel)
+			}
+		}
+	}
+	return nil
+}
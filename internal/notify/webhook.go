@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// WebhookConfig is one webhook destination
+type WebhookConfig struct {
+	// Name tells the webhook apart in the delivery log, as webhook:<name>
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Secret signs every body with HMAC-SHA256
+	Secret string `yaml:"secret"`
+	// Events are the event types sent, or globs such as mitigation.*; none
+	// sends every event
+	Events []string `yaml:"events"`
+	// Only attacks at or above this severity are sent; mitigation events
+	// are sent regardless
+	MinSeverity string `yaml:"min_severity"`
+}
+
+// WebhooksFile is the layout of a webhooks file: generic webhooks, and
+// Slack and Discord incoming webhooks. URLs and secrets written ${VAR} are
+// read from the environment.
+type WebhooksFile struct {
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	Slack    []ChatConfig    `yaml:"slack"`
+	Discord  []ChatConfig    `yaml:"discord"`
+}
+
+// LoadWebhooks reads a YAML or JSON webhooks file
+func LoadWebhooks(file string) (WebhooksFile, error) {
+	var f WebhooksFile
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return f, err
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("%s: %w", file, err)
+	}
+
+	names := make(map[string]bool)
+	unique := func(kind, name string, i int) error {
+		if name == "" {
+			return fmt.Errorf("%s: %s webhook %d has no name", file, kind, i+1)
+		}
+		if names[kind+":"+name] {
+			return fmt.Errorf("%s: %s webhook %s is defined twice", file, kind, name)
+		}
+		names[kind+":"+name] = true
+		return nil
+	}
+	for i := range f.Webhooks {
+		webhook := &f.Webhooks[i]
+		if err := unique("generic", webhook.Name, i); err != nil {
+			return f, err
+		}
+		webhook.URL = os.ExpandEnv(webhook.URL)
+		webhook.Secret = os.ExpandEnv(webhook.Secret)
+	}
+	for kind, chats := range map[string][]ChatConfig{ChatSlack: f.Slack, ChatDiscord: f.Discord} {
+		for i := range chats {
+			if err := unique(kind, chats[i].Name, i); err != nil {
+				return f, err
+			}
+			chats[i].URL = os.ExpandEnv(chats[i].URL)
+		}
+	}
+	return f, nil
+}
+
+// Notifiers sets up a notifier for every webhook in the file; chat
+// messages link to dashboardURL when it is set
+func (f WebhooksFile) Notifiers(dashboardURL string) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0, len(f.Webhooks)+len(f.Slack)+len(f.Discord))
+	for _, cfg := range f.Webhooks {
+		webhook, err := NewWebhookNotifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	chat := func(kind string, chats []ChatConfig) error {
+		for _, cfg := range chats {
+			cfg.DashboardURL = dashboardURL
+			notifier, err := NewChatNotifier(kind, cfg)
+			if err != nil {
+				return err
+			}
+			notifiers = append(notifiers, notifier)
+		}
+		return nil
+	}
+	if err := chat(ChatSlack, f.Slack); err != nil {
+		return nil, err
+	}
+	if err := chat(ChatDiscord, f.Discord); err != nil {
+		return nil, err
+	}
+	return notifiers, nil
+}
+
+// WebhookNotifier POSTs notifications as JSON to a URL
+type WebhookNotifier struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+func NewWebhookNotifier(cfg WebhookConfig) (*WebhookNotifier, error) {
+	if err := checkURL(cfg.URL); err != nil {
+		return nil, fmt.Errorf("webhook %s: %w", cfg.Name, err)
+	}
+	if err := checkEvents(cfg.Events); err != nil {
+		return nil, fmt.Errorf("webhook %s: %w", cfg.Name, err)
+	}
+	cfg.MinSeverity = strings.ToUpper(cfg.MinSeverity)
+	if cfg.MinSeverity != "" && models.SeverityRank(cfg.MinSeverity) == 0 {
+		return nil, fmt.Errorf("webhook %s: unknown severity %s", cfg.Name, cfg.MinSeverity)
+	}
+
+	return &WebhookNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (w *WebhookNotifier) Name() string {
+	return "webhook:" + w.cfg.Name
+}
+
+// Subscribes takes the events the webhook is routed
+func (w *WebhookNotifier) Subscribes(event string) bool {
+	return MatchEvent(w.cfg.Events, event)
+}
+
+// Notify POSTs the notification as it is. X-DDoS-Event-ID stays the same
+// across retries, so receivers can drop duplicates, and with a secret
+// X-DDoS-Signature carries sha256=<hex HMAC-SHA256 of the body>.
+func (w *WebhookNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	if notification.Attack != nil && !meetsSeverity(*notification.Attack, w.cfg.MinSeverity) {
+		return ErrSkipped
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ddos-dashboard-webhook")
+	req.Header.Set("X-DDoS-Event", notification.Event)
+	req.Header.Set("X-DDoS-Event-ID", notification.EventID)
+	if w.cfg.Secret != "" {
+		req.Header.Set("X-DDoS-Signature", "sha256="+Sign(w.cfg.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", w.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+
+	return statusError("webhook "+w.cfg.Name, resp)
+}
+
+// checkURL accepts absolute http and https URLs
+func checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	return nil
+}
+
+// checkEvents accepts event patterns MatchEvent can match with
+func checkEvents(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad event pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of a body under a secret, as receivers
+// compute it to check X-DDoS-Signature
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,318 @@
+// Package proxy is the server's TCP proxy mode. It fronts a backend,
+// reports every connection as traffic once it closes, and keeps a registry
+// of the connections still open with their age and byte progress, so slow
+// ones (Slowloris, slow POST, slow read) can be found and closed before
+// they exhaust the backend's connection slots.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/ingestion"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// Reasons a connection is slow
+const (
+	SlowWrite = "SLOW_WRITE" // the client sends its request too slowly
+	SlowRead  = "SLOW_READ"  // the client reads its response too slowly
+)
+
+// dialTimeout bounds connecting to the backend
+const dialTimeout = 5 * time.Second
+
+// Policy defines when an open connection is slow
+type Policy struct {
+	MinAge       time.Duration // connections younger than this are never slow
+	MinWriteRate float64       // bytes/sec the client must send at, on average
+	MinReadRate  float64       // bytes/sec the client must read at while a response waits
+}
+
+// DefaultPolicy matches the detectors' slow connection thresholds
+func DefaultPolicy() Policy {
+	return Policy{MinAge: 30 * time.Second, MinWriteRate: 100, MinReadRate: 100}
+}
+
+// Connection describes an open proxied connection
+type Connection struct {
+	ID          string    `json:"id"`
+	ClientIP    string    `json:"client_ip"`
+	ClientPort  int       `json:"client_port"`
+	OpenedAt    time.Time `json:"opened_at"`
+	AgeSeconds  float64   `json:"age_seconds"`
+	BytesIn     int64     `json:"bytes_in"`  // from the client
+	BytesOut    int64     `json:"bytes_out"` // to the client
+	WriteRate   float64   `json:"write_bps"` // average bytes/sec from the client
+	ReadRate    float64   `json:"read_bps"`  // average bytes/sec to the client
+	ReadPending bool      `json:"read_pending"`
+	Slow        string    `json:"slow,omitempty"` // SLOW_WRITE or SLOW_READ under the policy asked about
+}
+
+// Proxy forwards TCP connections to a backend
+type Proxy struct {
+	addr        string
+	backend     string
+	environment string
+	sink        ingestion.Sink
+
+	// Sockets opens the listener; nil means plain net.Listen
+	Sockets ingestion.Sockets
+
+	mu    sync.Mutex
+	conns map[string]*conn
+}
+
+// conn is one proxied connection and its progress
+type conn struct {
+	id      string
+	client  net.Conn
+	backend net.Conn
+	opened  time.Time
+
+	in  atomic.Int64
+	out atomic.Int64
+	// pendingSince is when the write to the client in progress started,
+	// in Unix nanoseconds, or 0 when none is
+	pendingSince atomic.Int64
+	closeOnce    sync.Once
+}
+
+func New(addr, backend, environment string, sink ingestion.Sink) *Proxy {
+	return &Proxy{
+		addr:        addr,
+		backend:     backend,
+		environment: environment,
+		sink:        sink,
+		conns:       make(map[string]*conn),
+	}
+}
+
+// Run accepts connections until the context is cancelled
+func (p *Proxy) Run(ctx context.Context) error {
+	var listener net.Listener
+	var err error
+	if p.Sockets == nil {
+		listener, err = net.Listen("tcp", p.addr)
+	} else {
+		listener, err = p.Sockets.Listen("proxy", "tcp", p.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("proxy listen: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	log.Printf("🔀 Proxying %s to %s", p.addr, p.backend)
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("proxy accept: %w", err)
+		}
+		go p.serve(client)
+	}
+}
+
+// serve pipes one client connection to the backend until either side is done
+func (p *Proxy) serve(client net.Conn) {
+	backend, err := net.DialTimeout("tcp", p.backend, dialTimeout)
+	if err != nil {
+		log.Printf("Error connecting to proxy backend %s: %v", p.backend, err)
+		client.Close()
+		return
+	}
+
+	c := &conn{id: uuid.New().String(), client: client, backend: backend, opened: time.Now()}
+	p.mu.Lock()
+	p.conns[c.id] = c
+	p.mu.Unlock()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(&counter{Writer: backend, n: &c.in}, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&counter{Writer: client, n: &c.out, pending: &c.pendingSince}, backend)
+		done <- struct{}{}
+	}()
+
+	// Either side finishing ends the connection
+	<-done
+	c.close()
+	<-done
+
+	p.mu.Lock()
+	delete(p.conns, c.id)
+	p.mu.Unlock()
+
+	if err := p.sink.StoreTraffic(p.traffic(c)); err != nil {
+		log.Printf("Error storing proxied connection: %v", err)
+	}
+}
+
+// traffic describes a finished connection as a traffic record
+func (p *Proxy) traffic(c *conn) models.TrafficRequest {
+	age := time.Since(c.opened)
+	clientIP, clientPort := splitAddr(c.client.RemoteAddr())
+	destIP, destPort := splitAddr(c.client.LocalAddr())
+
+	req := models.TrafficRequest{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		SourceIP:    clientIP,
+		SourcePort:  clientPort,
+		DestIP:      destIP,
+		DestPort:    destPort,
+		Protocol:    "TCP",
+		BytesSent:   int(c.in.Load()),
+		BytesRecv:   int(c.out.Load()),
+		Duration:    int(age.Milliseconds()),
+		Environment: p.environment,
+	}
+	if seconds := age.Seconds(); seconds > 0 {
+		req.RequestBodyRate = float64(req.BytesSent) / seconds
+		req.ResponseReadRate = float64(req.BytesRecv) / seconds
+	}
+	return req
+}
+
+// close closes both sides of a connection
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		c.client.Close()
+		c.backend.Close()
+	})
+}
+
+// describe reports a connection's progress and whether the policy
+// considers it slow
+func (c *conn) describe(now time.Time, policy Policy) Connection {
+	clientIP, clientPort := splitAddr(c.client.RemoteAddr())
+	age := now.Sub(c.opened)
+	info := Connection{
+		ID:          c.id,
+		ClientIP:    clientIP,
+		ClientPort:  clientPort,
+		OpenedAt:    c.opened,
+		AgeSeconds:  age.Seconds(),
+		BytesIn:     c.in.Load(),
+		BytesOut:    c.out.Load(),
+		ReadPending: c.pendingSince.Load() != 0,
+	}
+	if seconds := age.Seconds(); seconds > 0 {
+		info.WriteRate = float64(info.BytesIn) / seconds
+		info.ReadRate = float64(info.BytesOut) / seconds
+	}
+
+	if age >= policy.MinAge {
+		switch {
+		case info.ReadPending && info.ReadRate < policy.MinReadRate:
+			info.Slow = SlowRead
+		case info.WriteRate < policy.MinWriteRate:
+			info.Slow = SlowWrite
+		}
+	}
+	return info
+}
+
+// Connections lists the open connections, oldest first, marking the ones
+// the policy considers slow
+func (p *Proxy) Connections(policy Policy) []Connection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	conns := make([]Connection, 0, len(p.conns))
+	for _, c := range p.conns {
+		conns = append(conns, c.describe(now, policy))
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].OpenedAt.Before(conns[j].OpenedAt) })
+	return conns
+}
+
+// Slow lists the open connections the policy considers slow, oldest first
+func (p *Proxy) Slow(policy Policy) []Connection {
+	slow := make([]Connection, 0)
+	for _, c := range p.Connections(policy) {
+		if c.Slow != "" {
+			slow = append(slow, c)
+		}
+	}
+	return slow
+}
+
+// CloseSlow force-closes every connection the policy considers slow and
+// returns what they were
+func (p *Proxy) CloseSlow(policy Policy) []Connection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	closed := make([]Connection, 0)
+	for _, c := range p.conns {
+		if info := c.describe(now, policy); info.Slow != "" {
+			c.close()
+			closed = append(closed, info)
+		}
+	}
+	return closed
+}
+
+// EnforceSlow closes slow connections every interval until the context is
+// cancelled
+func (p *Proxy) EnforceSlow(ctx context.Context, policy Policy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if closed := p.CloseSlow(policy); len(closed) > 0 {
+				log.Printf("🐌 Closed %d slow proxied connections", len(closed))
+			}
+		}
+	}
+}
+
+// counter counts the bytes written through it and, when pending is set,
+// records when a write started until it returns
+type counter struct {
+	io.Writer
+	n       *atomic.Int64
+	pending *atomic.Int64
+}
+
+func (c *counter) Write(b []byte) (int, error) {
+	if c.pending != nil {
+		c.pending.Store(time.Now().UnixNano())
+		defer c.pending.Store(0)
+	}
+	n, err := c.Writer.Write(b)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// splitAddr returns the IP and port of a TCP address
+func splitAddr(addr net.Addr) (string, int) {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String(), tcp.Port
+	}
+	return addr.String(), 0
+}
@@ -0,0 +1,136 @@
+// Package ratelimit holds clients to token buckets: the dashboard's own API
+// clients, so a flood aimed at the API, ingest included, can't take down
+// the dashboard watching for floods, and the sources the mitigation
+// enforcer rate limits
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys bounds the buckets a limiter keeps at once
+const DefaultMaxKeys = 100000
+
+// ipv6ClientBits is the prefix length IPv6 clients are keyed by. A single
+// host usually holds a whole /64 and can rotate through it at will.
+const ipv6ClientBits = 64
+
+// bucket is one key's token bucket
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+	// limited is set from a key's first rejected request until one is let
+	// through again
+	limited bool
+}
+
+// Decision is a limiter's verdict on one request
+type Decision struct {
+	Allowed bool
+	// Remaining is the whole tokens left in the bucket
+	Remaining int
+	// RetryAfter is when the key has a token again, when not allowed
+	RetryAfter time.Duration
+	// First is set on the first rejection since the key was last let
+	// through, so callers can log a client being limited once
+	First bool
+}
+
+// Limiter holds each key, such as a client address or API principal, to a
+// rate with bursts. Buckets live in memory, per node; when there are more
+// keys than it keeps, the one idle the longest is forgotten.
+type Limiter struct {
+	perMinute int
+	burst     int
+	maxKeys   int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	idle    *list.List // of *bucket, most recently used first
+}
+
+// New holds every key to perMinute requests with bursts of burst, keeping
+// at most maxKeys buckets (0 for DefaultMaxKeys)
+func New(perMinute, burst, maxKeys int) *Limiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return &Limiter{
+		perMinute: perMinute,
+		burst:     burst,
+		maxKeys:   maxKeys,
+		buckets:   make(map[string]*list.Element),
+		idle:      list.New(),
+	}
+}
+
+// Allow takes a token from the key's bucket, if it has one
+func (l *Limiter) Allow(key string, now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perSecond := float64(l.perMinute) / 60
+	var b *bucket
+	if element, ok := l.buckets[key]; ok {
+		b = element.Value.(*bucket)
+		l.idle.MoveToFront(element)
+	} else {
+		for len(l.buckets) >= l.maxKeys {
+			oldest := l.idle.Back()
+			l.idle.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: float64(l.burst), last: now}
+		l.buckets[key] = l.idle.PushFront(b)
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.limited = false
+		return Decision{Allowed: true, Remaining: int(b.tokens)}
+	}
+	first := !b.limited
+	b.limited = true
+	wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	return Decision{RetryAfter: wait, First: first}
+}
+
+// Limits returns the rate and burst keys are held to
+func (l *Limiter) Limits() (perMinute, burst int) {
+	return l.perMinute, l.burst
+}
+
+// Limited counts the keys whose last request was rejected
+func (l *Limiter) Limited() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for _, element := range l.buckets {
+		if element.Value.(*bucket).limited {
+			n++
+		}
+	}
+	return n
+}
+
+// ClientKey is the key a client address is limited by: the address itself
+// for IPv4, its /64 for IPv6, and ip unchanged when it doesn't parse
+func ClientKey(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap().WithZone("")
+	if addr.Is4() {
+		return addr.String()
+	}
+	return netip.PrefixFrom(addr, ipv6ClientBits).Masked().String()
+}
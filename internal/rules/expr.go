@@ -0,0 +1,360 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Lookup returns the value of a variable, or of one key of a map variable
+// such as protocol_counts.UDP. A key missing from a map is 0.
+type Lookup func(name, key string) float64
+
+// Vars declares the variables conditions may use, and whether each is a
+// map that takes a key
+type Vars map[string]bool
+
+// expr is a compiled condition or part of one. Comparisons and logical
+// operators yield 1 for true and 0 for false.
+type expr interface {
+	eval(lookup Lookup) float64
+}
+
+type number float64
+
+func (n number) eval(Lookup) float64 { return float64(n) }
+
+// variable is a scalar, or a map variable with its key
+type variable struct {
+	name, key string
+}
+
+func (v variable) eval(lookup Lookup) float64 { return lookup(v.name, v.key) }
+
+// String renders the variable as written in conditions
+func (v variable) String() string {
+	if v.key == "" {
+		return v.name
+	}
+	if isIdentifier(v.key) {
+		return v.name + "." + v.key
+	}
+	return fmt.Sprintf("%s[%q]", v.name, v.key)
+}
+
+type unary struct {
+	op      string
+	operand expr
+}
+
+func (u unary) eval(lookup Lookup) float64 {
+	x := u.operand.eval(lookup)
+	if u.op == "!" {
+		return truth(x == 0)
+	}
+	return -x
+}
+
+type binary struct {
+	op          string
+	left, right expr
+}
+
+func (b binary) eval(lookup Lookup) float64 {
+	// Logical operators short-circuit
+	switch b.op {
+	case "&&":
+		return truth(b.left.eval(lookup) != 0 && b.right.eval(lookup) != 0)
+	case "||":
+		return truth(b.left.eval(lookup) != 0 || b.right.eval(lookup) != 0)
+	}
+
+	x, y := b.left.eval(lookup), b.right.eval(lookup)
+	switch b.op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		if y == 0 {
+			return 0
+		}
+		return x / y
+	case ">":
+		return truth(x > y)
+	case ">=":
+		return truth(x >= y)
+	case "<":
+		return truth(x < y)
+	case "<=":
+		return truth(x <= y)
+	case "==":
+		return truth(x == y)
+	default: // "!="
+		return truth(x != y)
+	}
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// token is a lexeme of a condition: an operator or punctuation, a number,
+// an identifier or a quoted string
+type token struct {
+	kind  byte // 'o' operator, 'n' number, 'i' identifier, 's' string
+	text  string
+	value float64
+	pos   int
+}
+
+var operators = []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "+", "-", "*", "/", "(", ")", "[", "]", "."}
+
+// tokenize splits a condition into tokens
+func tokenize(src string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			value, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", src[i:j], i)
+			}
+			tokens = append(tokens, token{kind: 'n', text: src[i:j], value: value, pos: i})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: 'i', text: src[i:j], pos: i})
+			i = j
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(src[i+1:], src[i])
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{kind: 's', text: src[i+1 : i+1+j], pos: i})
+			i += j + 2
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: 'o', text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive-descent parser over a condition's tokens. From
+// loosest to tightest binding: ||, &&, !, comparisons, + and -, * and /,
+// unary minus.
+type parser struct {
+	tokens []token
+	pos    int
+	vars   Vars
+	used   []variable
+}
+
+// compile parses a condition, checking its variables against vars. It
+// returns the expression and the variables it uses, in order of appearance.
+func compile(src string, vars Vars) (expr, []variable, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &parser{tokens: tokens, vars: vars}
+	e, err := p.or()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, fmt.Errorf("unexpected %q at %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	return e, p.used, nil
+}
+
+// accept consumes the next token if it is one of the operators
+func (p *parser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != 'o' {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) or() (expr, error) {
+	return p.binaryLevel(p.and, "||")
+}
+
+func (p *parser) and() (expr, error) {
+	return p.binaryLevel(p.not, "&&")
+}
+
+func (p *parser) not() (expr, error) {
+	if _, ok := p.accept("!"); ok {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return unary{"!", operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expr, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept(">=", "<=", "==", "!=", ">", "<"); ok {
+		right, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op, left, right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) sum() (expr, error) {
+	return p.binaryLevel(p.product, "+", "-")
+}
+
+func (p *parser) product() (expr, error) {
+	return p.binaryLevel(p.negation, "*", "/")
+}
+
+// binaryLevel parses a left-associative chain of one precedence level
+func (p *parser) binaryLevel(next func() (expr, error), ops ...string) (expr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op, left, right}
+	}
+}
+
+func (p *parser) negation() (expr, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.negation()
+		if err != nil {
+			return nil, err
+		}
+		return unary{"-", operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case 'n':
+		return number(t.value), nil
+	case 'i':
+		switch t.text {
+		case "true":
+			return number(1), nil
+		case "false":
+			return number(0), nil
+		}
+		return p.variable(t)
+	case 'o':
+		if t.text == "(" {
+			e, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing ) for ( at %d", t.pos)
+			}
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// variable parses a variable and, for a map, its key: map.key or map["key"]
+func (p *parser) variable(name token) (expr, error) {
+	keyed, ok := p.vars[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s at %d", name.text, name.pos)
+	}
+
+	v := variable{name: name.text}
+	if keyed {
+		switch op, _ := p.accept(".", "["); op {
+		case ".":
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != 'i' {
+				return nil, fmt.Errorf("%s. needs a key at %d", name.text, name.pos)
+			}
+			v.key = p.tokens[p.pos].text
+			p.pos++
+		case "[":
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != 's' {
+				return nil, fmt.Errorf("%s[ needs a quoted key at %d", name.text, name.pos)
+			}
+			v.key = p.tokens[p.pos].text
+			p.pos++
+			if _, ok := p.accept("]"); !ok {
+				return nil, fmt.Errorf("missing ] after %s key at %d", name.text, name.pos)
+			}
+		default:
+			return nil, fmt.Errorf("%s needs a key, as in %s.name or %s[\"name\"], at %d", name.text, name.text, name.text, name.pos)
+		}
+	}
+
+	p.used = append(p.used, v)
+	return v, nil
+}
+
+// isIdentifier reports whether s can be written as map.key
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,227 @@
+// Package rules evaluates user-defined detection rules: named conditions
+// over a window's computed metrics, such as
+// "protocol_counts.UDP > 5000 && ip_entropy < 3", read from a YAML or JSON
+// file that is reloaded whenever it changes.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Rule is one rule as written in the rules file
+type Rule struct {
+	Name      string `yaml:"name" json:"name"`
+	Type      string `yaml:"type" json:"type"` // attack type raised; defaults to the name in upper case
+	Condition string `yaml:"condition" json:"condition"`
+	// Severity overrides the one derived from Confidence
+	Severity    string  `yaml:"severity" json:"severity,omitempty"`
+	Confidence  float64 `yaml:"confidence" json:"confidence"` // defaults to 0.8
+	Description string  `yaml:"description" json:"description,omitempty"`
+}
+
+// file is the layout of a rules file
+type file struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// compiled is a rule ready to evaluate
+type compiled struct {
+	Rule
+	condition expr
+	used      []variable
+}
+
+// Match is a rule whose condition held, with the values of the variables
+// the condition uses, e.g. "protocol_counts.UDP=7200, ip_entropy=2.1", and
+// the same values by variable
+type Match struct {
+	Rule
+	Values    string
+	Variables map[string]float64
+}
+
+// Engine holds the rules of a file and reloads them when the file changes.
+// It is safe for concurrent use.
+type Engine struct {
+	path string
+	vars Vars
+
+	mu      sync.RWMutex
+	rules   []compiled
+	modTime time.Time
+	err     error // last failed reload; the previous rules stay in force
+}
+
+// Load reads and compiles a rules file. Conditions may only use vars.
+func Load(path string, vars Vars) (*Engine, error) {
+	e := &Engine{path: path, vars: vars}
+	if _, err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload recompiles the rules if the file has changed since they were
+// loaded, and reports whether it did. A file that fails to read or compile
+// leaves the previous rules in force.
+func (e *Engine) Reload() (bool, error) {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false, e.fail(err)
+	}
+
+	e.mu.RLock()
+	unchanged := info.ModTime().Equal(e.modTime) && e.err == nil
+	e.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, e.fail(err)
+	}
+	rules, err := parse(data, e.vars)
+	if err != nil {
+		return false, e.fail(fmt.Errorf("%s: %w", e.path, err))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules, e.modTime, e.err = rules, info.ModTime(), nil
+	return true, nil
+}
+
+// fail records a failed reload
+func (e *Engine) fail(err error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.err = err
+	return err
+}
+
+// Watch reloads the rules every interval until the context is done
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := e.Reload()
+			if err != nil {
+				log.Printf("⚠️  Keeping previous detection rules: %v", err)
+			} else if reloaded {
+				log.Printf("📜 Reloaded %d detection rules from %s", len(e.Rules()), e.path)
+			}
+		}
+	}
+}
+
+// parse compiles the rules of a YAML or JSON rules file
+func parse(data []byte, vars Vars) ([]compiled, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(f.Rules))
+	rules := make([]compiled, 0, len(f.Rules))
+	for i, rule := range f.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %s is defined twice", rule.Name)
+		}
+		names[rule.Name] = true
+
+		condition, used, err := compile(rule.Condition, vars)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if rule.Type == "" {
+			rule.Type = strings.ToUpper(rule.Name)
+		}
+		if rule.Confidence == 0 {
+			rule.Confidence = 0.8
+		}
+		if rule.Confidence < 0 || rule.Confidence > 1 {
+			return nil, fmt.Errorf("rule %s: confidence %.2f is not between 0 and 1", rule.Name, rule.Confidence)
+		}
+		rule.Severity = strings.ToUpper(rule.Severity)
+		switch rule.Severity {
+		case "", "LOW", "MEDIUM", "HIGH", "CRITICAL":
+		default:
+			return nil, fmt.Errorf("rule %s: unknown severity %s", rule.Name, rule.Severity)
+		}
+
+		rules = append(rules, compiled{Rule: rule, condition: condition, used: used})
+	}
+	return rules, nil
+}
+
+// Rules returns the rules in force
+func (e *Engine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]Rule, len(e.rules))
+	for i, rule := range e.rules {
+		rules[i] = rule.Rule
+	}
+	return rules
+}
+
+// Err returns the error of the last reload, nil if it succeeded
+func (e *Engine) Err() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.err
+}
+
+// Path returns the rules file
+func (e *Engine) Path() string {
+	return e.path
+}
+
+// Match evaluates every rule against a window's variables and returns the
+// rules whose condition holds, in file order
+func (e *Engine) Match(lookup Lookup) []Match {
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	matches := make([]Match, 0)
+	for _, rule := range rules {
+		if rule.condition.eval(lookup) == 0 {
+			continue
+		}
+
+		values := make([]string, 0, len(rule.used))
+		variables := make(map[string]float64, len(rule.used))
+		for _, v := range rule.used {
+			name := v.String()
+			if _, ok := variables[name]; ok {
+				continue
+			}
+			variables[name] = v.eval(lookup)
+			values = append(values, fmt.Sprintf("%s=%.4g", name, variables[name]))
+		}
+		matches = append(matches, Match{Rule: rule.Rule, Values: strings.Join(values, ", "), Variables: variables})
+	}
+	return matches
+}
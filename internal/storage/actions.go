@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lastAlertKey   = "alerts:last"
+	actionAuditKey = "audit:actions"
+
+	// maxActionAudit bounds the admin action audit log
+	maxActionAudit = 1000
+)
+
+// ErrNoAlert is returned when no alert has been published yet
+var ErrNoAlert = errors.New("no alert has been published")
+
+// GetLastAlert returns the most recently published alert
+func (r *RedisClient) GetLastAlert() (*models.Alert, error) {
+	data, err := r.client.Get(r.ctx, r.key(lastAlertKey)).Result()
+	if err == redis.Nil {
+		return nil, ErrNoAlert
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var alert models.Alert
+	if err := json.Unmarshal([]byte(data), &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// FlushMetricsMinute deletes the real-time counters of the minute containing
+// t, returning how many keys were removed
+func (r *RedisClient) FlushMetricsMinute(t time.Time) (int64, error) {
+	key := r.key(fmt.Sprintf(metricsKeyFormat, t.Truncate(time.Minute).Unix()))
+	return r.client.Del(r.ctx, key, key+":unique_ips", key+":ip_counts", key+":path_counts").Result()
+}
+
+// AppendActionAudit records an admin action invocation
+func (r *RedisClient) AppendActionAudit(entry models.ActionAudit) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.LPush(r.ctx, r.key(actionAuditKey), data)
+	pipe.LTrim(r.ctx, r.key(actionAuditKey), 0, maxActionAudit-1)
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// GetActionAudit returns the most recent admin action invocations, newest first
+func (r *RedisClient) GetActionAudit(limit int) ([]models.ActionAudit, error) {
+	results, err := r.client.LRange(r.ctx, r.key(actionAuditKey), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.ActionAudit, 0, len(results))
+	for _, result := range results {
+		var entry models.ActionAudit
+		if err := json.Unmarshal([]byte(result), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
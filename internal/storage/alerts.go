@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	alertsKey     = "alerts:records" // alert ID -> alert
+	alertIndexKey = "alerts:index"   // alert IDs by time raised
+	maxAlerts     = 5000
+
+	silencesKey = "alerts:silences" // silence ID -> silence
+	// silenceRetention is how long expired silences are kept for reference
+	silenceRetention = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrAlertNotFound is returned for an alert that isn't stored, or no longer
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrSilenceNotFound is returned for a silence that isn't stored
+	ErrSilenceNotFound = errors.New("silence not found")
+)
+
+// StoreAlert stores or replaces an alert, keeping the latest maxAlerts
+func (r *RedisClient) StoreAlert(alert models.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, r.key(alertsKey), alert.ID, string(data))
+	pipe.ZAdd(r.ctx, r.key(alertIndexKey), redis.Z{
+		Score:  float64(alert.Timestamp.UnixMilli()),
+		Member: alert.ID,
+	})
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return err
+	}
+
+	count, err := r.client.ZCard(r.ctx, r.key(alertIndexKey)).Result()
+	if err != nil || count <= maxAlerts {
+		return err
+	}
+	oldest, err := r.client.ZRange(r.ctx, r.key(alertIndexKey), 0, count-maxAlerts-1).Result()
+	if err != nil || len(oldest) == 0 {
+		return err
+	}
+	pipe = r.client.TxPipeline()
+	pipe.ZRemRangeByRank(r.ctx, r.key(alertIndexKey), 0, count-maxAlerts-1)
+	pipe.HDel(r.ctx, r.key(alertsKey), oldest...)
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// GetAlert returns a stored alert
+func (r *RedisClient) GetAlert(id string) (*models.Alert, error) {
+	data, err := r.client.HGet(r.ctx, r.key(alertsKey), id).Result()
+	if err == redis.Nil {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var alert models.Alert
+	if err := json.Unmarshal([]byte(data), &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// GetAlerts returns up to limit alerts (0 for all), most recently raised
+// first
+func (r *RedisClient) GetAlerts(limit int) ([]models.Alert, error) {
+	ids, err := r.client.ZRevRange(r.ctx, r.key(alertIndexKey), 0, int64(limit)-1).Result()
+	if err != nil || len(ids) == 0 {
+		return []models.Alert{}, err
+	}
+
+	records, err := r.client.HMGet(r.ctx, r.key(alertsKey), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]models.Alert, 0, len(records))
+	for _, record := range records {
+		data, ok := record.(string)
+		if !ok {
+			continue
+		}
+		var alert models.Alert
+		if err := json.Unmarshal([]byte(data), &alert); err != nil {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// UpdateAlert changes a stored alert with update, retrying if another node
+// changes it meanwhile, and returns the result. An error from update
+// leaves the alert as it was.
+func (r *RedisClient) UpdateAlert(id string, update func(*models.Alert) error) (*models.Alert, error) {
+	key := r.key(alertsKey)
+	var updated models.Alert
+
+	for attempt := 0; attempt < 3; attempt++ {
+		err := r.client.Watch(r.ctx, func(tx *redis.Tx) error {
+			data, err := tx.HGet(r.ctx, key, id).Result()
+			if err == redis.Nil {
+				return ErrAlertNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			updated = models.Alert{}
+			if err := json.Unmarshal([]byte(data), &updated); err != nil {
+				return err
+			}
+			if err := update(&updated); err != nil {
+				return err
+			}
+			changed, err := json.Marshal(updated)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(r.ctx, key, id, string(changed))
+				return nil
+			})
+			return err
+		}, key)
+		if err != redis.TxFailedErr {
+			if err != nil {
+				return nil, err
+			}
+			return &updated, nil
+		}
+	}
+	return nil, redis.TxFailedErr
+}
+
+// StoreSilence stores or replaces a silence. Silences expired for more than
+// silenceRetention are dropped.
+func (r *RedisClient) StoreSilence(silence models.Silence) error {
+	data, err := json.Marshal(silence)
+	if err != nil {
+		return err
+	}
+	if err := r.client.HSet(r.ctx, r.key(silencesKey), silence.ID, string(data)).Err(); err != nil {
+		return err
+	}
+
+	silences, err := r.GetSilences()
+	if err != nil {
+		return err
+	}
+	stale := make([]string, 0)
+	for _, s := range silences {
+		if time.Since(s.EndsAt) > silenceRetention {
+			stale = append(stale, s.ID)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return r.client.HDel(r.ctx, r.key(silencesKey), stale...).Err()
+}
+
+// GetSilence returns a stored silence
+func (r *RedisClient) GetSilence(id string) (*models.Silence, error) {
+	data, err := r.client.HGet(r.ctx, r.key(silencesKey), id).Result()
+	if err == redis.Nil {
+		return nil, ErrSilenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var silence models.Silence
+	if err := json.Unmarshal([]byte(data), &silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// GetSilences returns every stored silence, most recently starting first
+func (r *RedisClient) GetSilences() ([]models.Silence, error) {
+	records, err := r.client.HGetAll(r.ctx, r.key(silencesKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	silences := make([]models.Silence, 0, len(records))
+	for _, data := range records {
+		var silence models.Silence
+		if err := json.Unmarshal([]byte(data), &silence); err != nil {
+			continue
+		}
+		silences = append(silences, silence)
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].StartsAt.After(silences[j].StartsAt)
+	})
+
+	return silences, nil
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	activeAttacksKey   = "attacks:active"
+	attackHistoryKey   = "attacks:history"
+	endedAttacksKey    = "attacks:ended"     // attack ID -> record of an ended attack
+	attackMergedKey    = "attacks:merged"    // merged attack ID -> surviving attack ID
+	attackNoMergeKey   = "attacks:nomerge"   // pairs an operator split apart
+	attackDecisionsKey = "attacks:decisions" // audit log of manual decisions
+	attackTimelineKey  = "attacks:timeline:" // + attack ID
+
+	// maxAttackHistory bounds the ended attacks kept
+	maxAttackHistory = 10000
+)
+
+// ErrAttackNotFound is returned when an attack ID is neither active nor ended
+var ErrAttackNotFound = errors.New("attack not found")
+
+// GetAttack retrieves an active or ended attack, following manual merges
+// to the incident the ID was merged into
+func (r *RedisClient) GetAttack(id string) (*models.Attack, error) {
+	id, err := r.ResolveAttackID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.client.HGet(r.ctx, r.key(activeAttacksKey), id).Result()
+	if errors.Is(err, redis.Nil) {
+		data, err = r.client.HGet(r.ctx, r.key(endedAttacksKey), id).Result()
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrAttackNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attack models.Attack
+	if err := json.Unmarshal([]byte(data), &attack); err != nil {
+		return nil, err
+	}
+
+	return &attack, nil
+}
+
+// ResolveAttackID returns the ID of the incident an attack was merged into,
+// or the ID itself if it was never merged
+func (r *RedisClient) ResolveAttackID(id string) (string, error) {
+	// Bounded so a corrupted redirect cycle can't loop forever
+	for i := 0; i < 16; i++ {
+		next, err := r.client.HGet(r.ctx, r.key(attackMergedKey), id).Result()
+		if errors.Is(err, redis.Nil) {
+			return id, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		id = next
+	}
+
+	return "", fmt.Errorf("merge chain for attack %s is too long", id)
+}
+
+// ApplyMerge stores a manually merged incident, retires the source attack
+// and records the decision
+func (r *RedisClient) ApplyMerge(merged models.Attack, sourceID string, decision models.AttackDecision) error {
+	mergedData, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	decisionData, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, r.key(activeAttacksKey), merged.ID, string(mergedData))
+	pipe.HDel(r.ctx, r.key(activeAttacksKey), sourceID)
+	pipe.HSet(r.ctx, r.key(attackMergedKey), sourceID, merged.ID)
+	// A merge overrides an earlier split of the same pair
+	pipe.SRem(r.ctx, r.key(attackNoMergeKey), pairKey(merged.ID, sourceID))
+	pipe.RPush(r.ctx, r.key(attackDecisionsKey), string(decisionData))
+	_, err = pipe.Exec(r.ctx)
+
+	return err
+}
+
+// ApplySplit stores both halves of a manual split and remembers that the two
+// must not be merged again automatically
+func (r *RedisClient) ApplySplit(remaining, split models.Attack, decision models.AttackDecision) error {
+	remainingData, err := json.Marshal(remaining)
+	if err != nil {
+		return err
+	}
+	splitData, err := json.Marshal(split)
+	if err != nil {
+		return err
+	}
+	decisionData, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, r.key(activeAttacksKey), remaining.ID, string(remainingData))
+	pipe.HSet(r.ctx, r.key(activeAttacksKey), split.ID, string(splitData))
+	pipe.ZAdd(r.ctx, r.key(attackHistoryKey), redis.Z{
+		Score:  float64(split.StartTime.Unix()),
+		Member: split.ID,
+	})
+	pipe.SAdd(r.ctx, r.key(attackNoMergeKey), pairKey(remaining.ID, split.ID))
+	pipe.RPush(r.ctx, r.key(attackDecisionsKey), string(decisionData))
+	_, err = pipe.Exec(r.ctx)
+
+	return err
+}
+
+// IsMergeBlocked reports whether an operator split these attacks apart, in
+// which case correlation must keep them separate
+func (r *RedisClient) IsMergeBlocked(a, b string) (bool, error) {
+	return r.client.SIsMember(r.ctx, r.key(attackNoMergeKey), pairKey(a, b)).Result()
+}
+
+// GetAttackDecisions returns manual merge/split decisions, oldest first
+func (r *RedisClient) GetAttackDecisions() ([]models.AttackDecision, error) {
+	results, err := r.client.LRange(r.ctx, r.key(attackDecisionsKey), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make([]models.AttackDecision, 0, len(results))
+	for _, result := range results {
+		var decision models.AttackDecision
+		if err := json.Unmarshal([]byte(result), &decision); err != nil {
+			continue
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, nil
+}
+
+// AppendTimelineEvent adds an entry to an attack's incident timeline
+func (r *RedisClient) AppendTimelineEvent(attackID string, event models.TimelineEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return r.client.RPush(r.ctx, r.key(attackTimelineKey+attackID), string(data)).Err()
+}
+
+// GetTimeline returns an attack's incident timeline, oldest first
+func (r *RedisClient) GetTimeline(attackID string) ([]models.TimelineEvent, error) {
+	results, err := r.client.LRange(r.ctx, r.key(attackTimelineKey+attackID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.TimelineEvent, 0, len(results))
+	for _, result := range results {
+		var event models.TimelineEvent
+		if err := json.Unmarshal([]byte(result), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// pairKey is order independent so (a, b) and (b, a) are the same pair
+func pairKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// EndAttack moves an attack from the active set into history
+func (r *RedisClient) EndAttack(attack models.Attack) error {
+	data, err := json.Marshal(attack)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HDel(r.ctx, r.key(activeAttacksKey), attack.ID)
+	pipe.HSet(r.ctx, r.key(endedAttacksKey), attack.ID, string(data))
+	pipe.ZAdd(r.ctx, r.key(attackHistoryKey), redis.Z{
+		Score:  float64(attack.StartTime.Unix()),
+		Member: attack.ID,
+	})
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return err
+	}
+
+	return r.trimAttackHistory()
+}
+
+// trimAttackHistory drops the oldest attacks beyond maxAttackHistory
+func (r *RedisClient) trimAttackHistory() error {
+	count, err := r.client.ZCard(r.ctx, r.key(attackHistoryKey)).Result()
+	if err != nil || count <= maxAttackHistory {
+		return err
+	}
+
+	oldest, err := r.client.ZRange(r.ctx, r.key(attackHistoryKey), 0, count-maxAttackHistory-1).Result()
+	if err != nil || len(oldest) == 0 {
+		return err
+	}
+	records, err := r.client.HMGet(r.ctx, r.key(endedAttacksKey), oldest...).Result()
+	if err != nil {
+		return err
+	}
+
+	// Long-running attacks that are still active keep their entries
+	pipe := r.client.TxPipeline()
+	for i, id := range oldest {
+		if records[i] == nil {
+			continue
+		}
+		pipe.ZRem(r.ctx, r.key(attackHistoryKey), id)
+		pipe.HDel(r.ctx, r.key(endedAttacksKey), id)
+		pipe.Del(r.ctx, r.key(attackTimelineKey+id))
+	}
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// GetAttackHistory returns up to limit ended attacks, most recently started first
+func (r *RedisClient) GetAttackHistory(limit int) ([]models.Attack, error) {
+	ids, err := r.client.ZRevRange(r.ctx, r.key(attackHistoryKey), 0, int64(limit)-1).Result()
+	if err != nil || len(ids) == 0 {
+		return []models.Attack{}, err
+	}
+
+	records, err := r.client.HMGet(r.ctx, r.key(endedAttacksKey), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	attacks := make([]models.Attack, 0, len(records))
+	for _, record := range records {
+		// Active attacks are in history too but have no ended record
+		data, ok := record.(string)
+		if !ok {
+			continue
+		}
+		var attack models.Attack
+		if err := json.Unmarshal([]byte(data), &attack); err != nil {
+			continue
+		}
+		attacks = append(attacks, attack)
+	}
+
+	return attacks, nil
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// bogonField prefixes the fields of the per-minute metrics hash counting
+// requests from bogon space by range class
+const bogonField = "bogon:"
+
+// countBogon counts a request tagged as coming from bogon space toward its
+// range class
+func countBogon(ctx context.Context, pipe redis.Pipeliner, key string, req models.TrafficRequest) {
+	if req.SourceBogon != "" {
+		pipe.HIncrBy(ctx, key, bogonField+req.SourceBogon, 1)
+	}
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// SeedMetrics replaces the counters of the minute m.Timestamp falls in so
+// that GetMetrics reads m back, for fixture data. As GetMetrics derives the
+// total from the busiest sources, it is the sum of m.TopIPs; unique sources
+// are counted approximately.
+func (r *RedisClient) SeedMetrics(m models.Metrics) error {
+	minute := m.Timestamp.Truncate(time.Minute).Unix()
+	key := r.key(fmt.Sprintf(metricsKeyFormat, minute))
+
+	fields := map[string]interface{}{
+		"total_bytes": int64(m.BytesPerSec * 60),
+	}
+	total := 0
+	for _, ip := range m.TopIPs {
+		total += ip.Count
+	}
+	fields["total_requests"] = total
+	for protocol, count := range m.ProtocolBreakdown {
+		fields["protocol:"+protocol] = count
+	}
+	for method, count := range m.MethodBreakdown {
+		fields[methodField+method] = count
+	}
+	for status, count := range m.StatusCodeDist {
+		fields[fmt.Sprintf("status:%d", status)] = count
+	}
+	for country, count := range m.CountryBreakdown {
+		fields[countryField+country] = count
+	}
+
+	sources := make([]interface{}, m.UniqueIPs)
+	for i := range sources {
+		sources[i] = fmt.Sprintf("fixture-source-%d", i)
+	}
+	ips := make([]redis.Z, 0, len(m.TopIPs))
+	for _, ip := range m.TopIPs {
+		ips = append(ips, redis.Z{Score: float64(ip.Count), Member: ip.IP})
+	}
+	paths := make([]redis.Z, 0, len(m.TopPaths))
+	for _, path := range m.TopPaths {
+		paths = append(paths, redis.Z{Score: float64(path.Count), Member: path.Path})
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(r.ctx, key, key+":unique_ips", key+":ip_counts", key+":path_counts")
+	pipe.HSet(r.ctx, key, fields)
+	if len(sources) > 0 {
+		pipe.PFAdd(r.ctx, key+":unique_ips", sources...)
+	}
+	if len(ips) > 0 {
+		pipe.ZAdd(r.ctx, key+":ip_counts", ips...)
+	}
+	if len(paths) > 0 {
+		pipe.ZAdd(r.ctx, key+":path_counts", paths...)
+	}
+	for _, k := range []string{key, key + ":unique_ips", key + ":ip_counts", key + ":path_counts"} {
+		pipe.Expire(r.ctx, k, time.Hour)
+	}
+	_, err := pipe.Exec(r.ctx)
+	return err
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Fields of the per-minute metrics and rollup hashes counting requests by
+// source country and autonomous system
+const (
+	countryField = "country:"
+	asnField     = "asn:"
+	// asOrgField names an AS in the per-minute metrics hash
+	asOrgField = "asorg:"
+)
+
+// countLocation counts a request toward its source country and AS in a
+// metrics hash; requests of unknown origin count toward neither
+func countLocation(ctx context.Context, pipe redis.Pipeliner, key string, req models.TrafficRequest) {
+	if req.Country != "" {
+		pipe.HIncrBy(ctx, key, countryField+req.Country, 1)
+	}
+	if req.ASN != 0 {
+		pipe.HIncrBy(ctx, key, fmt.Sprintf("%s%d", asnField, req.ASN), 1)
+	}
+}
+
+// nameASN records the organization of a request's AS in a metrics hash
+func nameASN(ctx context.Context, pipe redis.Pipeliner, key string, req models.TrafficRequest) {
+	if req.ASN != 0 && req.ASOrg != "" {
+		pipe.HSet(ctx, key, fmt.Sprintf("%s%d", asOrgField, req.ASN), req.ASOrg)
+	}
+}
+
+// topASNs orders per-AS request counts busiest first, keeping at most limit
+func topASNs(requests map[uint32]int64, names map[uint32]string, total int64, limit int) []models.ASNCount {
+	asns := make([]models.ASNCount, 0, len(requests))
+	for asn, n := range requests {
+		count := models.ASNCount{ASN: asn, Org: names[asn], Requests: n}
+		if total > 0 {
+			count.Percentage = float64(n) / float64(total) * 100
+		}
+		asns = append(asns, count)
+	}
+	sort.Slice(asns, func(i, j int) bool {
+		if asns[i].Requests != asns[j].Requests {
+			return asns[i].Requests > asns[j].Requests
+		}
+		return asns[i].ASN < asns[j].ASN
+	})
+	if len(asns) > limit {
+		asns = asns[:limit]
+	}
+	return asns
+}
+
+// readASNFields adds the asn: and asorg: fields of a metrics hash
+func readASNFields(data map[string]string, requests map[uint32]int64, names map[uint32]string) {
+	for field, value := range data {
+		if raw, ok := strings.CutPrefix(field, asOrgField); ok {
+			if asn, err := strconv.ParseUint(raw, 10, 32); err == nil {
+				names[uint32(asn)] = value
+			}
+			continue
+		}
+		raw, ok := strings.CutPrefix(field, asnField)
+		if !ok {
+			continue
+		}
+		asn, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			requests[uint32(asn)] += n
+		}
+	}
+}
+
+// GetTopASNs sums the requests per source AS over the last minutes
+// minutes, the current one included, returning the limit busiest and the
+// total requests
+func (r *RedisClient) GetTopASNs(minutes, limit int) ([]models.ASNCount, int64, error) {
+	cmds, err := r.recentMetrics(minutes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(0)
+	requests := make(map[uint32]int64)
+	names := make(map[uint32]string)
+	for _, cmd := range cmds {
+		data := cmd.Val()
+		n, _ := strconv.ParseInt(data["total_requests"], 10, 64)
+		total += n
+		readASNFields(data, requests, names)
+	}
+	return topASNs(requests, names, total, limit), total, nil
+}
+
+// recentMetrics fetches the per-minute metrics hashes of the last minutes minutes
+func (r *RedisClient) recentMetrics(minutes int) ([]*redis.MapStringStringCmd, error) {
+	now := time.Now().Truncate(time.Minute)
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, minutes)
+	for i := 0; i < minutes; i++ {
+		minute := now.Add(-time.Duration(i) * time.Minute).Unix()
+		cmds = append(cmds, pipe.HGetAll(r.ctx, r.key(fmt.Sprintf(metricsKeyFormat, minute))))
+	}
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return cmds, nil
+}
+
+// GetCountryMetrics sums the requests per source country over the last
+// minutes minutes, the current one included, busiest first. Percentages are
+// of all requests, so those of unknown origin make them add up to under 100.
+func (r *RedisClient) GetCountryMetrics(minutes int) ([]models.CountryCount, int64, error) {
+	cmds, err := r.recentMetrics(minutes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(0)
+	requests := make(map[string]int64)
+	for _, cmd := range cmds {
+		for field, value := range cmd.Val() {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			if field == "total_requests" {
+				total += n
+			} else if country, ok := strings.CutPrefix(field, countryField); ok {
+				requests[country] += n
+			}
+		}
+	}
+
+	countries := make([]models.CountryCount, 0, len(requests))
+	for country, n := range requests {
+		count := models.CountryCount{Country: country, Requests: n}
+		if total > 0 {
+			count.Percentage = float64(n) / float64(total) * 100
+		}
+		countries = append(countries, count)
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		if countries[i].Requests != countries[j].Requests {
+			return countries[i].Requests > countries[j].Requests
+		}
+		return countries[i].Country < countries[j].Country
+	})
+	return countries, total, nil
+}
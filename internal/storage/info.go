@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MemoryUsage is how much memory Redis uses, from INFO memory
+type MemoryUsage struct {
+	UsedBytes int64 `json:"used_bytes"`
+	PeakBytes int64 `json:"peak_bytes"`
+	// MaxBytes is the configured maxmemory; 0 means no limit
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// MemoryUsage reports the memory Redis uses. The stores that don't
+// implement INFO memory, like the embedded one, return an error.
+func (r *RedisClient) MemoryUsage() (*MemoryUsage, error) {
+	info, err := r.client.Info(r.ctx, "memory").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]int64)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			fields[name] = n
+		}
+	}
+
+	used, ok := fields["used_memory"]
+	if !ok {
+		return nil, fmt.Errorf("INFO memory has no used_memory")
+	}
+	return &MemoryUsage{
+		UsedBytes: used,
+		PeakBytes: fields["used_memory_peak"],
+		MaxBytes:  fields["maxmemory"],
+	}, nil
+}
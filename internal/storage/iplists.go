@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+)
+
+// ipListKey is the hash of a list's entries by CIDR, e.g.
+// mitigations:iplist:deny. The lists are part of what enforcement points
+// apply, so every change bumps the mitigation serial.
+func ipListKey(list string) string {
+	return "mitigations:iplist:" + list
+}
+
+// StoreIPListEntries adds entries to a list, replacing those with the same CIDR
+func (r *RedisClient) StoreIPListEntries(list string, entries []models.IPListEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, 0, 2*len(entries))
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		values = append(values, entry.CIDR, string(data))
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, r.key(ipListKey(list)), values...)
+	pipe.Incr(r.ctx, r.key(mitigationSerialKey))
+	_, err := pipe.Exec(r.ctx)
+
+	return err
+}
+
+// ReplaceIPList replaces every entry of a list
+func (r *RedisClient) ReplaceIPList(list string, entries []models.IPListEntry) error {
+	pipe := r.client.TxPipeline()
+	pipe.Del(r.ctx, r.key(ipListKey(list)))
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		pipe.HSet(r.ctx, r.key(ipListKey(list)), entry.CIDR, string(data))
+	}
+	pipe.Incr(r.ctx, r.key(mitigationSerialKey))
+	_, err := pipe.Exec(r.ctx)
+
+	return err
+}
+
+// RemoveIPListEntry drops a CIDR from a list, reporting whether it was on it
+func (r *RedisClient) RemoveIPListEntry(list, cidr string) (bool, error) {
+	removed, err := removeAndBumpSerial.Run(r.ctx, r.client, []string{r.key(ipListKey(list)), r.key(mitigationSerialKey)}, cidr).Int()
+	return removed == 1, err
+}
+
+// GetIPList returns a list's entries ordered by CIDR
+func (r *RedisClient) GetIPList(list string) ([]models.IPListEntry, error) {
+	data, err := r.client.HGetAll(r.ctx, r.key(ipListKey(list))).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.IPListEntry, 0, len(data))
+	for _, raw := range data {
+		var entry models.IPListEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CIDR < entries[j].CIDR })
+	return entries, nil
+}
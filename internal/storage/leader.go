@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	leaderKey         = "analysis:leader"
+	detectionStateKey = "detection:state"
+)
+
+// renewLeadership extends the lease only if this node still holds it
+var renewLeadership = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeadership deletes the lease only if this node still holds it
+var releaseLeadership = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// saveDetectionState writes the state only if this node still holds the
+// lease, so a leader that stalled past it can't overwrite its successor's
+var saveDetectionState = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[2], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// ErrNotLeader is returned for a write only the lease holder may make
+var ErrNotLeader = errors.New("this node no longer holds the analysis leader lease")
+
+// AcquireLeadership takes or renews the analysis leader lease. It returns
+// true while nodeID holds the lease; the lease lapses after ttl unless renewed.
+func (r *RedisClient) AcquireLeadership(nodeID string, ttl time.Duration) (bool, error) {
+	renewed, err := renewLeadership.Run(r.ctx, r.client, []string{r.key(leaderKey)}, nodeID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	if renewed == 1 {
+		return true, nil
+	}
+
+	return r.client.SetNX(r.ctx, r.key(leaderKey), nodeID, ttl).Result()
+}
+
+// ReleaseLeadership gives up the lease so a standby can take over immediately
+func (r *RedisClient) ReleaseLeadership(nodeID string) error {
+	return releaseLeadership.Run(r.ctx, r.client, []string{r.key(leaderKey)}, nodeID).Err()
+}
+
+// CurrentLeader returns the node holding the lease, or "" if nobody does
+func (r *RedisClient) CurrentLeader() (string, error) {
+	leader, err := r.client.Get(r.ctx, r.key(leaderKey)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return leader, err
+}
+
+// SaveDetectionState stores the leader's in-memory detection state. It
+// returns ErrNotLeader, storing nothing, once nodeID has lost the lease.
+func (r *RedisClient) SaveDetectionState(nodeID string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	saved, err := saveDetectionState.Run(r.ctx, r.client, []string{r.key(leaderKey), r.key(detectionStateKey)}, nodeID, data).Int()
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// LoadDetectionState reads the last replicated detection state into state.
+// It returns false if no state has been saved yet.
+func (r *RedisClient) LoadDetectionState(state interface{}) (bool, error) {
+	data, err := r.client.Get(r.ctx, r.key(detectionStateKey)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, state)
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	logpushBatchKey = "logpush:batch:"
+
+	// logpushBatchTTL is how long a batch is remembered; Logpush gives up
+	// retrying one well within a day
+	logpushBatchTTL = 24 * time.Hour
+)
+
+// claimLogpushBatch claims a batch that is new or was released after a
+// failure. A claimed batch holds -1; a released one how many of its records
+// were stored, which the script returns. It returns -1 for a batch already
+// claimed.
+var claimLogpushBatch = redis.NewScript(`
+local stored = tonumber(redis.call("GET", KEYS[1]) or "0")
+if stored < 0 then
+	return -1
+end
+redis.call("SET", KEYS[1], "-1", "PX", ARGV[1])
+return stored
+`)
+
+// ClaimLogpushBatch claims a Logpush batch for ingesting, on any node. It
+// returns how many records of the batch an earlier, failed delivery stored,
+// or false if the batch was ingested already or is being ingested.
+func (r *RedisClient) ClaimLogpushBatch(id string) (int, bool, error) {
+	stored, err := claimLogpushBatch.Run(r.ctx, r.client, []string{r.key(logpushBatchKey + id)}, logpushBatchTTL.Milliseconds()).Int()
+	if err != nil {
+		return 0, false, err
+	}
+	if stored < 0 {
+		return 0, false, nil
+	}
+	return stored, true, nil
+}
+
+// ReleaseLogpushBatch hands a batch that failed to ingest back for
+// Logpush's retry, which skips the records stored so far
+func (r *RedisClient) ReleaseLogpushBatch(id string, stored int) error {
+	return r.client.Set(r.ctx, r.key(logpushBatchKey+id), stored, logpushBatchTTL).Err()
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// methodField prefixes the fields of the per-minute metrics and rollup
+// hashes counting HTTP requests by method
+const methodField = "method:"
+
+// countMethod counts a request toward its HTTP method in a metrics hash;
+// requests without one, such as L4 flows, count toward none
+func countMethod(ctx context.Context, pipe redis.Pipeliner, key string, req models.TrafficRequest) {
+	if req.Method != "" {
+		pipe.HIncrBy(ctx, key, methodField+strings.ToUpper(req.Method), 1)
+	}
+}
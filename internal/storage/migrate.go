@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// schemaVersionKey holds the version of the last migration applied
+const schemaVersionKey = "schema:version"
+
+// orphanedAttackAge is how long an active attack must have been silent
+// before the orphaned-attacks migration closes it
+const orphanedAttackAge = time.Hour
+
+// Migration upgrades the stored data layout of one release to the next
+type Migration struct {
+	Version     int    `json:"version"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// run applies the migration, or only counts the changes when dryRun is
+	// set, and returns how many keys or records it changed
+	run func(r *RedisClient, dryRun bool, progress func(done int)) (int, error)
+}
+
+// MigrationResult is the outcome of one migration
+type MigrationResult struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Changed int    `json:"changed"` // keys or records changed, or that would change in a dry run
+}
+
+// migrations in the order they must be applied. Versions only grow; a
+// released migration is never edited, only followed by a new one.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Name:        "prefix_keys",
+		Description: "Move keys written before -redis-key-prefix was set into the prefixed namespace",
+		run: func(r *RedisClient, dryRun bool, progress func(done int)) (int, error) {
+			if r.prefix == "" {
+				return 0, nil
+			}
+			result, err := r.migrateToKeyPrefix(dryRun, progress)
+			if err != nil {
+				return 0, err
+			}
+			return result.Renamed, nil
+		},
+	},
+	{
+		Version:     2,
+		Name:        "close_orphaned_attacks",
+		Description: "Move attacks left in the active set by releases without attack end detection into history",
+		run: func(r *RedisClient, dryRun bool, progress func(done int)) (int, error) {
+			return r.closeOrphanedAttacks(dryRun, progress)
+		},
+	},
+}
+
+// Migrations lists every migration known to this release, oldest first
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestSchemaVersion is the data layout version this release writes
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the version of the stored data layout, 0 for data
+// written before versioning
+func (r *RedisClient) SchemaVersion() (int, error) {
+	version, err := r.client.Get(r.ctx, r.key(schemaVersionKey)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// SetSchemaVersion records the stored data layout version
+func (r *RedisClient) SetSchemaVersion(version int) error {
+	return r.client.Set(r.ctx, r.key(schemaVersionKey), version, 0).Err()
+}
+
+// Migrate applies the pending migrations in order, recording the schema
+// version after each. A dry run changes nothing and reports what each
+// pending migration would change. progress, if set, is called as a
+// migration works through large data sets.
+func (r *RedisClient) Migrate(dryRun bool, progress func(m Migration, done int)) ([]MigrationResult, error) {
+	version, err := r.SchemaVersion()
+	if err != nil {
+		return nil, fmt.Errorf("reading schema version: %w", err)
+	}
+
+	results := make([]MigrationResult, 0)
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+
+		var report func(done int)
+		if progress != nil {
+			report = func(done int) { progress(m, done) }
+		}
+
+		changed, err := m.run(r, dryRun, report)
+		results = append(results, MigrationResult{Version: m.Version, Name: m.Name, Changed: changed})
+		if err != nil {
+			return results, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		if !dryRun {
+			if err := r.SetSchemaVersion(m.Version); err != nil {
+				return results, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// closeOrphanedAttacks ends active attacks that have been silent for
+// orphanedAttackAge. Their end time is their last recorded activity.
+func (r *RedisClient) closeOrphanedAttacks(dryRun bool, progress func(done int)) (int, error) {
+	attacks, err := r.GetActiveAttacks()
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	cutoff := time.Now().Add(-orphanedAttackAge)
+	for i, attack := range attacks {
+		if progress != nil && i > 0 && i%100 == 0 {
+			progress(i)
+		}
+		if attack.EndTime != nil {
+			continue
+		}
+
+		lastActive, err := r.lastActivity(attack)
+		if err != nil {
+			return closed, err
+		}
+		if lastActive.After(cutoff) {
+			continue
+		}
+
+		closed++
+		if dryRun {
+			continue
+		}
+		attack.EndTime = &lastActive
+		if err := r.EndAttack(attack); err != nil {
+			return closed, err
+		}
+	}
+
+	return closed, nil
+}
+
+// lastActivity is the latest of an attack's start, last burst and last
+// timeline event
+func (r *RedisClient) lastActivity(attack models.Attack) (time.Time, error) {
+	last := attack.StartTime
+	if attack.Pulse != nil && attack.Pulse.LastBurst.After(last) {
+		last = attack.Pulse.LastBurst
+	}
+
+	data, err := r.client.LIndex(r.ctx, r.key(attackTimelineKey+attack.ID), -1).Result()
+	if errors.Is(err, redis.Nil) {
+		return last, nil
+	}
+	if err != nil {
+		return last, err
+	}
+
+	var event models.TimelineEvent
+	if err := json.Unmarshal([]byte(data), &event); err == nil && event.Timestamp.After(last) {
+		last = event.Timestamp
+	}
+	return last, nil
+}
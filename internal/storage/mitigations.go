@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	activeMitigationsKey = "mitigations:active"
+	// mitigationSerialKey is bumped on every change to the active set so
+	// external enforcement points can cheaply tell whether to resync
+	mitigationSerialKey = "mitigations:serial"
+	// mitigationKillSwitchKey is set while automatic mitigation is stopped
+	mitigationKillSwitchKey = "mitigations:kill_switch"
+	// mitigationHistoryKey is the audit trail of the mitigations
+	mitigationHistoryKey = "mitigations:history"
+
+	// maxMitigationHistory bounds the mitigation audit trail
+	maxMitigationHistory = 10000
+)
+
+// StoreMitigation adds or updates an active mitigation
+func (r *RedisClient) StoreMitigation(action models.MitigationAction) error {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, r.key(activeMitigationsKey), action.ID, string(data))
+	pipe.Incr(r.ctx, r.key(mitigationSerialKey))
+	_, err = pipe.Exec(r.ctx)
+
+	return err
+}
+
+// removeAndBumpSerial deletes a hash field and, if it was there, bumps the
+// mitigation serial, so no reader sees the set changed under the old serial
+var removeAndBumpSerial = redis.NewScript(`
+if redis.call("HDEL", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("INCR", KEYS[2])
+return 1
+`)
+
+// RemoveMitigation drops a mitigation from the active set
+func (r *RedisClient) RemoveMitigation(id string) error {
+	return removeAndBumpSerial.Run(r.ctx, r.client, []string{r.key(activeMitigationsKey), r.key(mitigationSerialKey)}, id).Err()
+}
+
+// GetActiveMitigations returns unexpired mitigations ordered by target, along
+// with the serial of the set they were read from, read in one transaction.
+// Expired entries are left for the mitigation engine to lift from the
+// executors and remove.
+func (r *RedisClient) GetActiveMitigations() ([]models.MitigationAction, int64, error) {
+	pipe := r.client.TxPipeline()
+	data := pipe.HGetAll(r.ctx, r.key(activeMitigationsKey))
+	serialCmd := pipe.Get(r.ctx, r.key(mitigationSerialKey))
+	if _, err := pipe.Exec(r.ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, err
+	}
+	serial, err := serialCmd.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	all := decodeMitigations(data.Val())
+	actions := make([]models.MitigationAction, 0, len(all))
+	for _, action := range all {
+		if action.ExpiresAt.IsZero() || !now.After(action.ExpiresAt) {
+			actions = append(actions, action)
+		}
+	}
+
+	return actions, serial, nil
+}
+
+// GetMitigations returns every recorded mitigation ordered by target,
+// including expired ones not yet removed
+func (r *RedisClient) GetMitigations() ([]models.MitigationAction, error) {
+	data, err := r.client.HGetAll(r.ctx, r.key(activeMitigationsKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeMitigations(data), nil
+}
+
+// decodeMitigations decodes the active set, ordered by target
+func decodeMitigations(data map[string]string) []models.MitigationAction {
+	actions := make([]models.MitigationAction, 0, len(data))
+	for _, raw := range data {
+		var action models.MitigationAction
+		if err := json.Unmarshal([]byte(raw), &action); err != nil {
+			continue
+		}
+		actions = append(actions, action)
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].Target != actions[j].Target {
+			return actions[i].Target < actions[j].Target
+		}
+		return actions[i].ID < actions[j].ID
+	})
+
+	return actions
+}
+
+// ErrMitigationNotFound is returned when no mitigation has the given ID
+var ErrMitigationNotFound = errors.New("mitigation not found")
+
+// GetMitigation returns one recorded mitigation, expired or not
+func (r *RedisClient) GetMitigation(id string) (*models.MitigationAction, error) {
+	raw, err := r.client.HGet(r.ctx, r.key(activeMitigationsKey), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMitigationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var action models.MitigationAction
+	if err := json.Unmarshal([]byte(raw), &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// MitigationSerial returns the current serial of the active mitigation set
+func (r *RedisClient) MitigationSerial() (int64, error) {
+	serial, err := r.client.Get(r.ctx, r.key(mitigationSerialKey)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return serial, err
+}
+
+// SetMitigationKillSwitch engages or releases the kill switch of automatic
+// mitigation for every node
+func (r *RedisClient) SetMitigationKillSwitch(engaged bool) error {
+	if !engaged {
+		return r.client.Del(r.ctx, r.key(mitigationKillSwitchKey)).Err()
+	}
+	return r.client.Set(r.ctx, r.key(mitigationKillSwitchKey), "1", 0).Err()
+}
+
+// MitigationKillSwitch reports whether the kill switch is engaged
+func (r *RedisClient) MitigationKillSwitch() (bool, error) {
+	n, err := r.client.Exists(r.ctx, r.key(mitigationKillSwitchKey)).Result()
+	return n > 0, err
+}
+
+// AppendMitigationEvent adds an entry to the mitigation audit trail
+func (r *RedisClient) AppendMitigationEvent(event models.MitigationEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.LPush(r.ctx, r.key(mitigationHistoryKey), data)
+	pipe.LTrim(r.ctx, r.key(mitigationHistoryKey), 0, maxMitigationHistory-1)
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// GetMitigationEvents returns up to limit entries of the audit trail,
+// newest first, keeping those match accepts (all when nil)
+func (r *RedisClient) GetMitigationEvents(limit int, match func(models.MitigationEvent) bool) ([]models.MitigationEvent, error) {
+	results, err := r.client.LRange(r.ctx, r.key(mitigationHistoryKey), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.MitigationEvent, 0)
+	for _, result := range results {
+		var event models.MitigationEvent
+		if err := json.Unmarshal([]byte(result), &event); err != nil {
+			continue
+		}
+		if match != nil && !match(event) {
+			continue
+		}
+		events = append(events, event)
+		if len(events) == limit {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+// GetSourceRequests counts the requests from the addresses of a prefix in
+// each minute from..to still held in the real-time counters, by minute
+// Unix time. Minutes without any traffic at all are left out.
+func (r *RedisClient) GetSourceRequests(prefix netip.Prefix, from, to time.Time) (map[int64]int64, error) {
+	counts := make(map[int64]int64)
+	for minute := from.Truncate(time.Minute); !minute.After(to); minute = minute.Add(time.Minute) {
+		key := r.key(fmt.Sprintf(metricsKeyFormat, minute.Unix()))
+		exists, err := r.client.Exists(r.ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			continue
+		}
+
+		var total int64
+		if prefix.IsSingleIP() {
+			score, err := r.client.ZScore(r.ctx, key+":ip_counts", prefix.Addr().String()).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+			total = int64(score)
+		} else {
+			sources, err := r.client.ZRangeWithScores(r.ctx, key+":ip_counts", 0, -1).Result()
+			if err != nil {
+				return nil, err
+			}
+			for _, source := range sources {
+				addr, err := netip.ParseAddr(source.Member.(string))
+				if err == nil && prefix.Contains(addr.Unmap()) {
+					total += int64(source.Score)
+				}
+			}
+		}
+		counts[minute.Unix()] = total
+	}
+	return counts, nil
+}
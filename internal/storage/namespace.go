@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyFamilies matches every key this package writes, without a prefix
+var keyFamilies = []string{
+	"traffic:*",
+	"metrics:*",
+	"attacks:*",
+	"mitigations:*",
+	"alerts:*",
+	"audit:*",
+	"analysis:*",
+	"detection:*",
+	"schema:*",
+	"notifications:*",
+}
+
+// SetKeyPrefix namespaces every key and the alerts channel, e.g.
+// "ddos:prod:", so several deployments or tenants can share one Redis.
+// Must be called before the client is used.
+func (r *RedisClient) SetKeyPrefix(prefix string) error {
+	if strings.ContainsAny(prefix, "*?[]\\") {
+		return fmt.Errorf("key prefix %q must not contain glob characters", prefix)
+	}
+	r.prefix = prefix
+	return nil
+}
+
+// KeyPrefix returns the configured key namespace
+func (r *RedisClient) KeyPrefix() string {
+	return r.prefix
+}
+
+func (r *RedisClient) key(name string) string {
+	return r.prefix + name
+}
+
+// PrefixMigration reports the outcome of MigrateToKeyPrefix
+type PrefixMigration struct {
+	Prefix    string   `json:"prefix"`
+	DryRun    bool     `json:"dry_run"`
+	Renamed   int      `json:"renamed"`             // keys moved (or that would be moved) into the namespace
+	Conflicts []string `json:"conflicts,omitempty"` // unprefixed keys left alone because the prefixed key exists
+}
+
+// MigrateToKeyPrefix moves data written before a key prefix was configured
+// into the namespace. Keys whose prefixed name already exists (e.g. the
+// current minute's counters, written since the restart) are left in place
+// and reported. TTLs are preserved.
+func (r *RedisClient) MigrateToKeyPrefix(dryRun bool) (*PrefixMigration, error) {
+	return r.migrateToKeyPrefix(dryRun, nil)
+}
+
+// migrateToKeyPrefix is MigrateToKeyPrefix, calling progress with the
+// number of keys examined after every batch
+func (r *RedisClient) migrateToKeyPrefix(dryRun bool, progress func(done int)) (*PrefixMigration, error) {
+	if r.prefix == "" {
+		return nil, fmt.Errorf("no key prefix is configured")
+	}
+
+	result := &PrefixMigration{Prefix: r.prefix, DryRun: dryRun}
+	examined := 0
+	for _, family := range keyFamilies {
+		iter := r.client.Scan(r.ctx, 0, family, 1000).Iterator()
+		for iter.Next(r.ctx) {
+			name := iter.Val()
+			if strings.HasPrefix(name, r.prefix) {
+				continue
+			}
+			if examined++; progress != nil && examined%1000 == 0 {
+				progress(examined)
+			}
+
+			if dryRun {
+				exists, err := r.client.Exists(r.ctx, r.key(name)).Result()
+				if err != nil {
+					return result, err
+				}
+				if exists > 0 {
+					result.Conflicts = append(result.Conflicts, name)
+				} else {
+					result.Renamed++
+				}
+				continue
+			}
+
+			renamed, err := r.client.RenameNX(r.ctx, name, r.key(name)).Result()
+			if err != nil {
+				return result, fmt.Errorf("renaming %s: %w", name, err)
+			}
+			if renamed {
+				result.Renamed++
+			} else {
+				result.Conflicts = append(result.Conflicts, name)
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	deliveriesKey  = "notifications:deliveries"
+	deadLettersKey = "notifications:dead_letters"
+	digestKey      = "notifications:digest:"
+	routingKey     = "notifications:routing" // routes as edited through the API
+	routedKey      = "notifications:routed"  // attack ID -> routed attack
+
+	// maxDeliveries bounds the notification delivery log
+	maxDeliveries = 5000
+)
+
+// AppendDelivery records a notification attempt
+func (r *RedisClient) AppendDelivery(delivery models.NotificationDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.LPush(r.ctx, r.key(deliveriesKey), data)
+	pipe.LTrim(r.ctx, r.key(deliveriesKey), 0, maxDeliveries-1)
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// GetDeliveries returns the most recent notification attempts, newest
+// first; a limit of 0 or less returns the whole log
+func (r *RedisClient) GetDeliveries(limit int) ([]models.NotificationDelivery, error) {
+	stop := int64(limit) - 1
+	if limit <= 0 {
+		stop = -1
+	}
+	results, err := r.client.LRange(r.ctx, r.key(deliveriesKey), 0, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.NotificationDelivery, 0, len(results))
+	for _, result := range results {
+		var delivery models.NotificationDelivery
+		if err := json.Unmarshal([]byte(result), &delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// AddDeadLetter queues a notification that failed for good
+func (r *RedisClient) AddDeadLetter(letter models.DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	return r.client.HSet(r.ctx, r.key(deadLettersKey), letter.ID, data).Err()
+}
+
+// GetDeadLetters returns every queued dead letter
+func (r *RedisClient) GetDeadLetters() ([]models.DeadLetter, error) {
+	results, err := r.client.HGetAll(r.ctx, r.key(deadLettersKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]models.DeadLetter, 0, len(results))
+	for _, result := range results {
+		var letter models.DeadLetter
+		if err := json.Unmarshal([]byte(result), &letter); err != nil {
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	// Oldest first, the order they should be retried in
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+
+	return letters, nil
+}
+
+// RemoveDeadLetter drops a dead letter once it was delivered
+func (r *RedisClient) RemoveDeadLetter(id string) error {
+	return r.client.HDel(r.ctx, r.key(deadLettersKey), id).Err()
+}
+
+// CountDeadLetters returns how many notifications are waiting to be redelivered
+func (r *RedisClient) CountDeadLetters() (int64, error) {
+	return r.client.HLen(r.ctx, r.key(deadLettersKey)).Result()
+}
+
+// ClaimDigest reports whether this node is the first to claim the digest
+// of a day, so it is sent once however many nodes run
+func (r *RedisClient) ClaimDigest(day string) (bool, error) {
+	return r.client.SetNX(r.ctx, r.key(digestKey+day), time.Now().Unix(), 48*time.Hour).Result()
+}
+
+// SetRouting stores the notification routes for every node
+func (r *RedisClient) SetRouting(data []byte) error {
+	return r.client.Set(r.ctx, r.key(routingKey), data, 0).Err()
+}
+
+// GetRouting returns the stored notification routes, or nil if there are
+// none
+func (r *RedisClient) GetRouting() ([]byte, error) {
+	data, err := r.client.Get(r.ctx, r.key(routingKey)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// StoreRoutedAttack stores or replaces the routing of an attack
+func (r *RedisClient) StoreRoutedAttack(routed models.RoutedAttack) error {
+	data, err := json.Marshal(routed)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(r.ctx, r.key(routedKey), routed.AttackID, data).Err()
+}
+
+// GetRoutedAttack returns the routing of an attack, or nil if it was not
+// routed
+func (r *RedisClient) GetRoutedAttack(attackID string) (*models.RoutedAttack, error) {
+	data, err := r.client.HGet(r.ctx, r.key(routedKey), attackID).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var routed models.RoutedAttack
+	if err := json.Unmarshal([]byte(data), &routed); err != nil {
+		return nil, err
+	}
+	return &routed, nil
+}
+
+// GetRoutedAttacks returns the routing of every attack still routed
+func (r *RedisClient) GetRoutedAttacks() ([]models.RoutedAttack, error) {
+	results, err := r.client.HGetAll(r.ctx, r.key(routedKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	routed := make([]models.RoutedAttack, 0, len(results))
+	for _, result := range results {
+		var attack models.RoutedAttack
+		if err := json.Unmarshal([]byte(result), &attack); err != nil {
+			continue
+		}
+		routed = append(routed, attack)
+	}
+	return routed, nil
+}
+
+// RemoveRoutedAttack drops the routing of an attack that ended
+func (r *RedisClient) RemoveRoutedAttack(attackID string) error {
+	return r.client.HDel(r.ctx, r.key(routedKey), attackID).Err()
+}
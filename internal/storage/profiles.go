@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// profileTTL is how long a source's profile outlives its last learned
+// window; each learned window extends it
+const profileTTL = 30 * 24 * time.Hour
+
+// profileKey is one source's profile in an environment, e.g.
+// profiles:prod:203.0.113.7
+func profileKey(env, ip string) string {
+	return "profiles:" + rollupEnvironment(env) + ":" + ip
+}
+
+// SourceProfiles returns the profiles of the given sources that have one
+func (r *RedisClient) SourceProfiles(env string, ips []string) (map[string]models.SourceProfile, error) {
+	profiles := make(map[string]models.SourceProfile)
+	if len(ips) == 0 {
+		return profiles, nil
+	}
+
+	keys := make([]string, len(ips))
+	for i, ip := range ips {
+		keys[i] = r.key(profileKey(env, ip))
+	}
+	values, err := r.client.MGet(r.ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var profile models.SourceProfile
+		if err := json.Unmarshal([]byte(data), &profile); err != nil {
+			continue
+		}
+		profiles[ips[i]] = profile
+	}
+	return profiles, nil
+}
+
+// StoreSourceProfiles writes profiles, each expiring profileTTL from now
+func (r *RedisClient) StoreSourceProfiles(env string, profiles []models.SourceProfile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, profile := range profiles {
+		data, err := json.Marshal(profile)
+		if err != nil {
+			return err
+		}
+		pipe.Set(r.ctx, r.key(profileKey(env, profile.IP)), data, profileTTL)
+	}
+	_, err := pipe.Exec(r.ctx)
+	return err
+}
@@ -4,15 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+)
+
+const (
+	trafficKey       = "traffic:requests"
+	metricsKeyFormat = "metrics:%d" // + minute Unix time
+	alertsChannel    = "alerts"
 )
 
 type RedisClient struct {
 	client *redis.Client
 	ctx    context.Context
+	zones  *tz.Zones
+	prefix string // namespace prepended to every key and channel
 }
 
 func NewRedisClient(addr string, password string, db int) (*RedisClient, error) {
@@ -39,7 +51,7 @@ func NewRedisClient(addr string, password string, db int) (*RedisClient, error)
 func (r *RedisClient) StoreTraffic(req models.TrafficRequest) error {
 	// Store in a time-series sorted set
 	timestamp := float64(req.Timestamp.Unix())
-	key := "traffic:requests"
+	key := r.key(trafficKey)
 	
 	data, err := json.Marshal(req)
 	if err != nil {
@@ -59,23 +71,38 @@ func (r *RedisClient) StoreTraffic(req models.TrafficRequest) error {
 	r.client.ZRemRangeByScore(r.ctx, key, "-inf", fmt.Sprintf("%f", fiveMinutesAgo))
 
 	// Update real-time counters
-	r.updateCounters(req)
+	if err := r.updateCounters(req); err != nil {
+		fmt.Printf("Error updating counters: %v\n", err)
+	}
 
 	return nil
 }
 
-// updateCounters updates real-time metrics
-func (r *RedisClient) updateCounters(req models.TrafficRequest) {
-	minute := time.Now().Truncate(time.Minute).Unix()
-	key := fmt.Sprintf("metrics:%d", minute)
+// CountTraffic updates the real-time counters and rollups for a request
+// without keeping the request itself, for servers that analyze traffic in
+// process rather than from the shared sorted set
+func (r *RedisClient) CountTraffic(req models.TrafficRequest) error {
+	return r.updateCounters(req)
+}
+
+// updateCounters updates real-time metrics, in the minute the request was
+// made, so sources delivering in batches, such as flow logs, don't pile a
+// batch into the minute it arrived
+func (r *RedisClient) updateCounters(req models.TrafficRequest) error {
+	at := countedAt(req, time.Now())
+	minute := at.Truncate(time.Minute).Unix()
+	key := r.key(fmt.Sprintf(metricsKeyFormat, minute))
 
 	pipe := r.client.Pipeline()
 
 	// Increment total requests
 	pipe.HIncrBy(r.ctx, key, "total_requests", 1)
 
-	// Increment bytes
+	// Increment bytes and the packets of flow records
 	pipe.HIncrBy(r.ctx, key, "total_bytes", int64(req.BytesSent))
+	if req.Packets > 0 {
+		pipe.HIncrBy(r.ctx, key, "total_packets", int64(req.Packets))
+	}
 
 	// Add unique IP
 	pipe.PFAdd(r.ctx, key+":unique_ips", req.SourceIP)
@@ -89,21 +116,46 @@ func (r *RedisClient) updateCounters(req models.TrafficRequest) {
 	// Increment protocol counter
 	pipe.HIncrBy(r.ctx, key, "protocol:"+req.Protocol, 1)
 
+	// Increment HTTP method counter
+	countMethod(r.ctx, pipe, key, req)
+
+	// Increment bogon source counter
+	countBogon(r.ctx, pipe, key, req)
+
+	// Increment status code counter
+	if req.StatusCode != 0 {
+		pipe.HIncrBy(r.ctx, key, fmt.Sprintf("status:%d", req.StatusCode), 1)
+	}
+
+	// Increment source country and AS counters
+	countLocation(r.ctx, pipe, key, req)
+	nameASN(r.ctx, pipe, key, req)
+
 	// Set expiration (keep for 1 hour)
 	pipe.Expire(r.ctx, key, time.Hour)
 	pipe.Expire(r.ctx, key+":unique_ips", time.Hour)
 	pipe.Expire(r.ctx, key+":ip_counts", time.Hour)
 	pipe.Expire(r.ctx, key+":path_counts", time.Hour)
 
+	// Hourly and daily rollups follow the tenant's local calendar
+	r.updateRollups(pipe, req, at)
+
 	_, err := pipe.Exec(r.ctx)
-	if err != nil {
-		fmt.Printf("Error updating counters: %v\n", err)
+	return err
+}
+
+// countedAt is when a request is counted: its timestamp, unless it has
+// none or claims to be from the future
+func countedAt(req models.TrafficRequest, now time.Time) time.Time {
+	if req.Timestamp.IsZero() || req.Timestamp.After(now) {
+		return now
 	}
+	return req.Timestamp
 }
 
 // GetRecentTraffic retrieves traffic from the last N seconds
 func (r *RedisClient) GetRecentTraffic(seconds int) ([]models.TrafficRequest, error) {
-	key := "traffic:requests"
+	key := r.key(trafficKey)
 	since := time.Now().Add(-time.Duration(seconds) * time.Second).Unix()
 
 	results, err := r.client.ZRangeByScore(r.ctx, key, &redis.ZRangeBy{
@@ -127,10 +179,60 @@ func (r *RedisClient) GetRecentTraffic(seconds int) ([]models.TrafficRequest, er
 	return requests, nil
 }
 
+// sampleTrafficScript returns k, then every k-th request of the window
+// since ARGV[1] with k picked so at most ARGV[2] come back. Requests are
+// read by rank, so the window is never copied out whole.
+var sampleTrafficScript = redis.NewScript(`
+local total = redis.call("ZCARD", KEYS[1])
+local n = redis.call("ZCOUNT", KEYS[1], ARGV[1], "+inf")
+local k = math.max(1, math.ceil(n / tonumber(ARGV[2])))
+if k == 1 then
+	local all = redis.call("ZRANGE", KEYS[1], total - n, total - 1)
+	table.insert(all, 1, 1)
+	return all
+end
+local sample = {k}
+for rank = total - n, total - 1, k do
+	sample[#sample + 1] = redis.call("ZRANGE", KEYS[1], rank, rank)[1]
+end
+return sample
+`)
+
+// GetRecentTrafficSample is GetRecentTraffic reading at most limit requests,
+// every k-th of the window, so only those are sent and decoded. It returns
+// the sample and k, how many requests each sampled one stands for.
+func (r *RedisClient) GetRecentTrafficSample(seconds, limit int) ([]models.TrafficRequest, int, error) {
+	since := time.Now().Add(-time.Duration(seconds) * time.Second).Unix()
+
+	results, err := sampleTrafficScript.Run(r.ctx, r.client, []string{r.key(trafficKey)}, since, limit).Slice()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return nil, 1, nil
+	}
+
+	k, _ := results[0].(int64)
+	requests := make([]models.TrafficRequest, 0, len(results)-1)
+	for _, result := range results[1:] {
+		member, ok := result.(string)
+		if !ok {
+			continue
+		}
+		var req models.TrafficRequest
+		if err := json.Unmarshal([]byte(member), &req); err != nil {
+			continue
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, max(int(k), 1), nil
+}
+
 // GetMetrics retrieves aggregated metrics for a time window
 func (r *RedisClient) GetMetrics(windowStart time.Time) (*models.Metrics, error) {
 	minute := windowStart.Truncate(time.Minute).Unix()
-	key := fmt.Sprintf("metrics:%d", minute)
+	key := r.key(fmt.Sprintf(metricsKeyFormat, minute))
 
 	// Get all metrics
 	metricsData, err := r.client.HGetAll(r.ctx, key).Result()
@@ -178,6 +280,41 @@ func (r *RedisClient) GetMetrics(windowStart time.Time) (*models.Metrics, error)
 		})
 	}
 
+	// Status code and source country distribution
+	statusCodes := make(map[int]int)
+	countries := make(map[string]int)
+	methods := make(map[string]int)
+	bogons := make(map[string]int)
+	for field, value := range metricsData {
+		if country, ok := strings.CutPrefix(field, countryField); ok {
+			countries[country], _ = strconv.Atoi(value)
+			continue
+		}
+		if method, ok := strings.CutPrefix(field, methodField); ok {
+			methods[method], _ = strconv.Atoi(value)
+			continue
+		}
+		if class, ok := strings.CutPrefix(field, bogonField); ok {
+			bogons[class], _ = strconv.Atoi(value)
+			continue
+		}
+		code, ok := strings.CutPrefix(field, "status:")
+		if !ok {
+			continue
+		}
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			continue
+		}
+		count, _ := strconv.Atoi(value)
+		statusCodes[status] = count
+	}
+
+	sourceRates, err := r.sourceRates(key+":ip_counts", 60)
+	if err != nil {
+		return nil, err
+	}
+
 	metrics := &models.Metrics{
 		Timestamp:      windowStart,
 		WindowDuration: 60,
@@ -186,11 +323,61 @@ func (r *RedisClient) GetMetrics(windowStart time.Time) (*models.Metrics, error)
 		RequestsPerSec: float64(totalRequests) / 60.0,
 		TopIPs:         topIPs,
 		TopPaths:       topPaths,
+		StatusCodeDist: statusCodes,
+		SourceRates:    sourceRates,
+	}
+	if len(countries) > 0 {
+		metrics.CountryBreakdown = countries
+	}
+	if len(methods) > 0 {
+		metrics.MethodBreakdown = methods
+	}
+	if len(bogons) > 0 {
+		metrics.BogonSources = bogons
+	}
+
+	// Busiest source autonomous systems
+	asnRequests := make(map[uint32]int64)
+	asnNames := make(map[uint32]string)
+	readASNFields(metricsData, asnRequests, asnNames)
+	if len(asnRequests) > 0 {
+		total, _ := strconv.ParseInt(metricsData["total_requests"], 10, 64)
+		metrics.TopASNs = topASNs(asnRequests, asnNames, total, 10)
 	}
 
 	return metrics, nil
 }
 
+// sourceRates reads the per-source rate percentiles of a window from its
+// per-IP counts, fetching only the sources at those ranks
+func (r *RedisClient) sourceRates(key string, seconds float64) (*models.SourceRates, error) {
+	sources, err := r.client.ZCard(r.ctx, key).Result()
+	if err != nil || sources == 0 {
+		return nil, err
+	}
+
+	percentiles := []float64{0.5, 0.95, 0.99, 1}
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.ZSliceCmd, len(percentiles))
+	for i, p := range percentiles {
+		// The source ranked at p from the bottom, counted from the top
+		rank := sources - int64(math.Ceil(p*float64(sources)))
+		cmds[i] = pipe.ZRevRangeWithScores(r.ctx, key, rank, rank)
+	}
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return nil, err
+	}
+
+	rates := make([]float64, len(cmds))
+	for i, cmd := range cmds {
+		if z := cmd.Val(); len(z) > 0 {
+			rates[i] = z[0].Score / seconds
+		}
+	}
+
+	return &models.SourceRates{P50: rates[0], P95: rates[1], P99: rates[2], Max: rates[3]}, nil
+}
+
 // StoreAttack stores detected attack information
 func (r *RedisClient) StoreAttack(attack models.Attack) error {
 	data, err := json.Marshal(attack)
@@ -198,16 +385,19 @@ func (r *RedisClient) StoreAttack(attack models.Attack) error {
 		return err
 	}
 
-	key := "attacks:active"
+	key := r.key(activeAttacksKey)
 	
-	// Store in hash
-	if err := r.client.HSet(r.ctx, key, attack.ID, string(data)).Err(); err != nil {
+	// Store in hash; a reopened attack leaves history
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, key, attack.ID, string(data))
+	pipe.HDel(r.ctx, r.key(endedAttacksKey), attack.ID)
+	if _, err := pipe.Exec(r.ctx); err != nil {
 		return err
 	}
 
 	// Also add to time-series
 	timestamp := float64(attack.StartTime.Unix())
-	if err := r.client.ZAdd(r.ctx, "attacks:history", redis.Z{
+	if err := r.client.ZAdd(r.ctx, r.key(attackHistoryKey), redis.Z{
 		Score:  timestamp,
 		Member: attack.ID,
 	}).Err(); err != nil {
@@ -219,7 +409,7 @@ func (r *RedisClient) StoreAttack(attack models.Attack) error {
 
 // GetActiveAttacks retrieves currently active attacks
 func (r *RedisClient) GetActiveAttacks() ([]models.Attack, error) {
-	key := "attacks:active"
+	key := r.key(activeAttacksKey)
 	
 	attacksData, err := r.client.HGetAll(r.ctx, key).Result()
 	if err != nil {
@@ -245,7 +435,46 @@ func (r *RedisClient) PublishAlert(alert models.Alert) error {
 		return err
 	}
 
-	return r.client.Publish(r.ctx, "alerts", string(data)).Err()
+	pipe := r.client.Pipeline()
+	pipe.Publish(r.ctx, r.key(alertsChannel), string(data))
+	pipe.Set(r.ctx, r.key(lastAlertKey), data, 0)
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// SubscribeAlerts passes every alert published from now on to handle until
+// ctx is done. The subscription survives reconnects, but alerts published
+// while it was down are not redelivered; messages that don't decode are
+// skipped.
+func (r *RedisClient) SubscribeAlerts(ctx context.Context, handle func(models.Alert)) error {
+	pubsub := r.client.Subscribe(ctx, r.key(alertsChannel))
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribing to alerts: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("alerts subscription closed")
+			}
+			var alert models.Alert
+			if err := json.Unmarshal([]byte(msg.Payload), &alert); err != nil {
+				continue
+			}
+			handle(alert)
+		}
+	}
+}
+
+// AddHook installs a go-redis hook, e.g. for fault injection
+func (r *RedisClient) AddHook(hook redis.Hook) {
+	r.client.AddHook(hook)
 }
 
 // Close closes the Redis connection
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/tz"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	hourlyRollupKey = "metrics:hourly:"
+	dailyRollupKey  = "metrics:daily:"
+
+	// Hourly rollups cover a week so hour-of-week baselines have history
+	hourlyRollupTTL = 8 * 24 * time.Hour
+	dailyRollupTTL  = 90 * 24 * time.Hour
+
+	defaultRollupEnvironment = "default"
+)
+
+// SetZones configures the deployment and per-tenant time zones used for
+// hourly and daily rollups. Without it, rollups use UTC.
+func (r *RedisClient) SetZones(zones *tz.Zones) {
+	r.zones = zones
+}
+
+// Zones returns the configured reporting time zones
+func (r *RedisClient) Zones() *tz.Zones {
+	return r.zones
+}
+
+// updateRollups counts a request in the hour and day of at
+func (r *RedisClient) updateRollups(pipe redis.Pipeliner, req models.TrafficRequest, at time.Time) {
+	env := rollupEnvironment(req.Environment)
+	loc := r.zones.For(env)
+
+	keys := map[string]time.Duration{
+		r.key(hourlyRollupKey + env + ":" + tz.HourKey(at, loc)): hourlyRollupTTL,
+		r.key(dailyRollupKey + env + ":" + tz.DayKey(at, loc)):   dailyRollupTTL,
+	}
+	for key, ttl := range keys {
+		pipe.HIncrBy(r.ctx, key, "total_requests", 1)
+		pipe.HIncrBy(r.ctx, key, "total_bytes", int64(req.BytesSent))
+		if req.Packets > 0 {
+			pipe.HIncrBy(r.ctx, key, "total_packets", int64(req.Packets))
+		}
+		pipe.HIncrBy(r.ctx, key, "protocol:"+req.Protocol, 1)
+		countMethod(r.ctx, pipe, key, req)
+		countLocation(r.ctx, pipe, key, req)
+		pipe.Expire(r.ctx, key, ttl)
+	}
+}
+
+// AddRollupCost adds estimated attack and mitigation spend to the hourly and
+// daily rollups of the environment at t
+func (r *RedisClient) AddRollupCost(environment string, t time.Time, attackCost, mitigationCost float64) error {
+	env := rollupEnvironment(environment)
+	loc := r.zones.For(env)
+
+	keys := map[string]time.Duration{
+		r.key(hourlyRollupKey + env + ":" + tz.HourKey(t, loc)): hourlyRollupTTL,
+		r.key(dailyRollupKey + env + ":" + tz.DayKey(t, loc)):   dailyRollupTTL,
+	}
+
+	pipe := r.client.Pipeline()
+	for key, ttl := range keys {
+		if attackCost != 0 {
+			pipe.HIncrByFloat(r.ctx, key, "attack_cost", attackCost)
+		}
+		if mitigationCost != 0 {
+			pipe.HIncrByFloat(r.ctx, key, "mitigation_cost", mitigationCost)
+		}
+		pipe.Expire(r.ctx, key, ttl)
+	}
+
+	_, err := pipe.Exec(r.ctx)
+	return err
+}
+
+// GetDailySummary returns the rollup of one local day ("2006-01-02") in
+// the environment's time zone
+func (r *RedisClient) GetDailySummary(environment, day string) (*models.PeriodSummary, error) {
+	env := rollupEnvironment(environment)
+	loc := r.zones.For(env)
+
+	midnight, err := tz.ParseDay(day, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day %q: %w", day, err)
+	}
+
+	data, err := r.client.HGetAll(r.ctx, r.key(dailyRollupKey+env+":"+day)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return periodSummary(env, "day", midnight, loc, data), nil
+}
+
+// GetHourlySummaries returns one rollup per local hour of a day. Across DST
+// changes a day has 23 or 25 hours; the repeated hour of a fall-back day
+// shares one bucket.
+func (r *RedisClient) GetHourlySummaries(environment, day string) ([]models.PeriodSummary, error) {
+	env := rollupEnvironment(environment)
+	loc := r.zones.For(env)
+
+	midnight, err := tz.ParseDay(day, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day %q: %w", day, err)
+	}
+
+	hours := tz.HoursOfDay(midnight, loc)
+	seen := make(map[string]bool, len(hours))
+	starts := make([]time.Time, 0, len(hours))
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(hours))
+
+	for _, start := range hours {
+		key := tz.HourKey(start, loc)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		starts = append(starts, start)
+		cmds = append(cmds, pipe.HGetAll(r.ctx, r.key(hourlyRollupKey+env+":"+key)))
+	}
+
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	summaries := make([]models.PeriodSummary, 0, len(cmds))
+	for i, cmd := range cmds {
+		summaries = append(summaries, *periodSummary(env, "hour", starts[i], loc, cmd.Val()))
+	}
+
+	return summaries, nil
+}
+
+func periodSummary(env, period string, start time.Time, loc *time.Location, data map[string]string) *models.PeriodSummary {
+	summary := &models.PeriodSummary{
+		Environment:       env,
+		Period:            period,
+		Start:             start,
+		TimeZone:          loc.String(),
+		ProtocolBreakdown: make(map[string]int64),
+	}
+
+	for field, value := range data {
+		switch field {
+		case "attack_cost":
+			summary.AttackCost, _ = strconv.ParseFloat(value, 64)
+			continue
+		case "mitigation_cost":
+			summary.MitigationCost, _ = strconv.ParseFloat(value, 64)
+			continue
+		}
+
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case field == "total_requests":
+			summary.TotalRequests = n
+		case field == "total_bytes":
+			summary.TotalBytes = n
+		case field == "total_packets":
+			summary.TotalPackets = n
+		case strings.HasPrefix(field, "protocol:"):
+			summary.ProtocolBreakdown[strings.TrimPrefix(field, "protocol:")] = n
+		case strings.HasPrefix(field, methodField):
+			if summary.MethodBreakdown == nil {
+				summary.MethodBreakdown = make(map[string]int64)
+			}
+			summary.MethodBreakdown[strings.TrimPrefix(field, methodField)] = n
+		case strings.HasPrefix(field, countryField):
+			if summary.CountryBreakdown == nil {
+				summary.CountryBreakdown = make(map[string]int64)
+			}
+			summary.CountryBreakdown[strings.TrimPrefix(field, countryField)] = n
+		case strings.HasPrefix(field, asnField):
+			if summary.ASNBreakdown == nil {
+				summary.ASNBreakdown = make(map[string]int64)
+			}
+			summary.ASNBreakdown[strings.TrimPrefix(field, asnField)] = n
+		}
+	}
+
+	return summary
+}
+
+func rollupEnvironment(env string) string {
+	if env == "" {
+		return defaultRollupEnvironment
+	}
+	return env
+}
+
+// GetRecentHourlySummaries returns the rollups of the last n complete local
+// hours, oldest first. Hours without traffic are included with zero counts.
+func (r *RedisClient) GetRecentHourlySummaries(environment string, hours int) ([]models.PeriodSummary, error) {
+	env := rollupEnvironment(environment)
+	loc := r.zones.For(env)
+	current := tz.StartOfHour(time.Now(), loc)
+
+	starts := make([]time.Time, 0, hours)
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, hours)
+	for i := hours; i >= 1; i-- {
+		start := current.Add(-time.Duration(i) * time.Hour)
+		starts = append(starts, start)
+		cmds = append(cmds, pipe.HGetAll(r.ctx, r.key(hourlyRollupKey+env+":"+tz.HourKey(start, loc))))
+	}
+
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	summaries := make([]models.PeriodSummary, 0, len(cmds))
+	for i, cmd := range cmds {
+		summaries = append(summaries, *periodSummary(env, "hour", starts[i], loc, cmd.Val()))
+	}
+
+	return summaries, nil
+}
+
+// GetHourlyRequests returns the request count of the local hours starting
+// at each of starts, zero for hours without traffic or past retention
+func (r *RedisClient) GetHourlyRequests(environment string, starts []time.Time) ([]int64, error) {
+	env := rollupEnvironment(environment)
+	loc := r.zones.For(env)
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(starts))
+	for _, start := range starts {
+		cmds = append(cmds, pipe.HGet(r.ctx, r.key(hourlyRollupKey+env+":"+tz.HourKey(start, loc)), "total_requests"))
+	}
+
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(cmds))
+	for i, cmd := range cmds {
+		counts[i], _ = strconv.ParseInt(cmd.Val(), 10, 64)
+	}
+	return counts, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// revokedSessionKey marks a logged out session until it would have expired
+	revokedSessionKey = "auth:revoked:"
+	// wsTicketKey holds a WebSocket ticket's principal until it is used
+	wsTicketKey = "auth:wsticket:"
+)
+
+// RevokeSession ends a session on every node before it expires
+func (r *RedisClient) RevokeSession(id string, expires time.Time) error {
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(r.ctx, r.key(revokedSessionKey+id), 1, ttl).Err()
+}
+
+// SessionRevoked reports whether a session was logged out
+func (r *RedisClient) SessionRevoked(id string) (bool, error) {
+	n, err := r.client.Exists(r.ctx, r.key(revokedSessionKey+id)).Result()
+	return n > 0, err
+}
+
+// StoreWSTicket keeps the encoded principal of a WebSocket ticket, usable
+// once on any node within ttl
+func (r *RedisClient) StoreWSTicket(ticket string, principal []byte, ttl time.Duration) error {
+	return r.client.Set(r.ctx, r.key(wsTicketKey+ticket), principal, ttl).Err()
+}
+
+// TakeWSTicket returns a ticket's encoded principal and deletes it, or nil
+// for a ticket that is unknown, used or expired
+func (r *RedisClient) TakeWSTicket(ticket string) ([]byte, error) {
+	principal, err := r.client.GetDel(r.ctx, r.key(wsTicketKey+ticket)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return principal, err
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	shadowAttacksKey    = "attacks:shadow"       // attack ID -> shadow mode detection
+	shadowAttackHistory = "attacks:shadow:index" // attack IDs by start time
+	maxShadowAttacks    = 1000
+)
+
+// StoreShadowAttack stores or updates what shadow mode detected, keeping
+// the latest maxShadowAttacks
+func (r *RedisClient) StoreShadowAttack(attack models.ShadowAttack) error {
+	data, err := json.Marshal(attack)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(r.ctx, r.key(shadowAttacksKey), attack.ID, string(data))
+	pipe.ZAdd(r.ctx, r.key(shadowAttackHistory), redis.Z{
+		Score:  float64(attack.StartTime.Unix()),
+		Member: attack.ID,
+	})
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return err
+	}
+
+	count, err := r.client.ZCard(r.ctx, r.key(shadowAttackHistory)).Result()
+	if err != nil || count <= maxShadowAttacks {
+		return err
+	}
+	oldest, err := r.client.ZRange(r.ctx, r.key(shadowAttackHistory), 0, count-maxShadowAttacks-1).Result()
+	if err != nil || len(oldest) == 0 {
+		return err
+	}
+	pipe = r.client.TxPipeline()
+	pipe.ZRemRangeByRank(r.ctx, r.key(shadowAttackHistory), 0, count-maxShadowAttacks-1)
+	pipe.HDel(r.ctx, r.key(shadowAttacksKey), oldest...)
+	_, err = pipe.Exec(r.ctx)
+	return err
+}
+
+// GetShadowAttacks returns up to limit shadow mode detections, most
+// recently started first
+func (r *RedisClient) GetShadowAttacks(limit int) ([]models.ShadowAttack, error) {
+	ids, err := r.client.ZRevRange(r.ctx, r.key(shadowAttackHistory), 0, int64(limit)-1).Result()
+	if err != nil || len(ids) == 0 {
+		return []models.ShadowAttack{}, err
+	}
+
+	records, err := r.client.HMGet(r.ctx, r.key(shadowAttacksKey), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	attacks := make([]models.ShadowAttack, 0, len(records))
+	for _, record := range records {
+		data, ok := record.(string)
+		if !ok {
+			continue
+		}
+		var attack models.ShadowAttack
+		if err := json.Unmarshal([]byte(data), &attack); err != nil {
+			continue
+		}
+		attacks = append(attacks, attack)
+	}
+
+	return attacks, nil
+}
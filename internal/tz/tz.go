@@ -0,0 +1,118 @@
+// Package tz computes reporting window boundaries in the deployment's (or a
+// tenant's) local time zone. time.Truncate works on absolute time, so
+// truncating to an hour or day splits local business days for any zone with
+// a non-UTC offset.
+package tz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dayLayout  = "2006-01-02"
+	hourLayout = "2006-01-02T15"
+)
+
+// Zones maps tenants (environments) to their reporting time zone
+type Zones struct {
+	def     *time.Location
+	tenants map[string]*time.Location
+}
+
+// NewZones builds a zone table from IANA names. An empty default means UTC.
+func NewZones(defaultZone string, tenantZones map[string]string) (*Zones, error) {
+	def := time.UTC
+	if defaultZone != "" {
+		loc, err := time.LoadLocation(defaultZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", defaultZone, err)
+		}
+		def = loc
+	}
+
+	zones := &Zones{
+		def:     def,
+		tenants: make(map[string]*time.Location, len(tenantZones)),
+	}
+	for tenant, name := range tenantZones {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q for %s: %w", name, tenant, err)
+		}
+		zones.tenants[tenant] = loc
+	}
+
+	return zones, nil
+}
+
+// ParseTenantZones reads "tenant=Zone/Name,other=Zone/Name"
+func ParseTenantZones(spec string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tenant, zone, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("tenant time zone %q is not tenant=Zone", part)
+		}
+		result[tenant] = zone
+	}
+	return result, nil
+}
+
+// For returns the zone of a tenant, falling back to the deployment zone. A
+// nil *Zones means UTC everywhere.
+func (z *Zones) For(tenant string) *time.Location {
+	if z == nil {
+		return time.UTC
+	}
+	if loc, ok := z.tenants[tenant]; ok {
+		return loc
+	}
+	return z.def
+}
+
+// StartOfHour returns the start of the local hour containing t
+func StartOfHour(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+}
+
+// StartOfDay returns local midnight of the day containing t. Days are not
+// always 24h long: DST transitions make them 23 or 25 hours.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// DayKey identifies the local day containing t, e.g. "2026-10-15"
+func DayKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(dayLayout)
+}
+
+// HourKey identifies the local hour containing t, e.g. "2026-10-15T09"
+func HourKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(hourLayout)
+}
+
+// ParseDay parses a DayKey in the given zone, returning local midnight
+func ParseDay(day string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation(dayLayout, day, loc)
+}
+
+// HoursOfDay returns the start of every local hour in the day beginning at
+// midnight, which is 23 or 25 entries across DST changes
+func HoursOfDay(midnight time.Time, loc *time.Location) []time.Time {
+	// +36h always lands inside the next local day
+	next := StartOfDay(midnight.Add(36*time.Hour), loc)
+
+	hours := make([]time.Time, 0, 25)
+	for t := midnight; t.Before(next); t = t.Add(time.Hour) {
+		hours = append(hours, t)
+	}
+	return hours
+}
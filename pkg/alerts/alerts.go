@@ -0,0 +1,67 @@
+// Package alerts subscribes to the alerts a dashboard server publishes on
+// Redis pub/sub. It is the supported way for programs outside this
+// repository to react to detections; cmd/alertbridge is built on the same
+// subscription.
+//
+// Pub/sub delivers at most once: alerts published while no subscriber is
+// connected are not redelivered. Last returns the most recent alert, so a
+// consumer can tell what it missed since it last ran.
+package alerts
+
+import (
+	"context"
+
+	"github.com/nshruti113/ddos-detection-dashboard/internal/models"
+	"github.com/nshruti113/ddos-detection-dashboard/internal/storage"
+)
+
+// Alert is an alert as published by the server
+type Alert = models.Alert
+
+// ErrNoAlert is returned by Last when no alert has been published yet
+var ErrNoAlert = storage.ErrNoAlert
+
+// Config locates the Redis instance the server publishes to
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix is the server's -redis-key-prefix, which also namespaces
+	// the alerts channel
+	KeyPrefix string
+}
+
+// Subscriber receives the alerts of one deployment
+type Subscriber struct {
+	redis *storage.RedisClient
+}
+
+// NewSubscriber connects to the deployment's Redis
+func NewSubscriber(cfg Config) (*Subscriber, error) {
+	client, err := storage.NewRedisClient(cfg.Addr, cfg.Password, cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SetKeyPrefix(cfg.KeyPrefix); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Subscriber{redis: client}, nil
+}
+
+// Run calls handle for every alert published until ctx is done. Alerts are
+// handled one at a time in the order they were published; a slow handler
+// delays the ones after it.
+func (s *Subscriber) Run(ctx context.Context, handle func(Alert)) error {
+	return s.redis.SubscribeAlerts(ctx, handle)
+}
+
+// Last returns the most recently published alert
+func (s *Subscriber) Last() (*Alert, error) {
+	return s.redis.GetLastAlert()
+}
+
+// Close disconnects from Redis
+func (s *Subscriber) Close() error {
+	return s.redis.Close()
+}
@@ -39,6 +39,6 @@ Write-Host "`n✅ Setup complete!" -ForegroundColor Green
 Write-Host "`nNext steps:" -ForegroundColor Cyan
 Write-Host "1. Copy the source files into the created directories"
 Write-Host "2. Start Redis: docker run -d -p 6379:6379 redis:latest"
-Write-Host "3. Run server: go run cmd\server\main.go"
-Write-Host "4. Run simulator: go run cmd\simulator\main.go"
+Write-Host "3. Run server: go run .\cmd\server -auth-required=false"
+Write-Host "4. Run simulator: go run .\cmd\simulator"
 Write-Host "5. Open browser: http://localhost:8080"
\ No newline at end of file
@@ -0,0 +1,9 @@
+// Package web holds the dashboard UI so bundle builds can serve it from the binary
+package web
+
+import "embed"
+
+// Files contains index.html
+//
+//go:embed index.html
+var Files embed.FS